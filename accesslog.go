@@ -0,0 +1,248 @@
+// author: Gary A. Stafford
+// site: https://programmaticponderings.com
+// license: MIT License
+// purpose: NLP microservices: nlp-client access log sinks
+// modified: 2026-10-14
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"path"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/labstack/echo/v4"
+	"github.com/labstack/echo/v4/middleware"
+	gbytes "github.com/labstack/gommon/bytes"
+)
+
+var (
+	// e.g. file:///var/log/nlp-client/access.log or s3://bucket/nlp-client; unset to disable
+	accessLog               = getEnv("ACCESS_LOG", "")
+	accessLogFormat         = getEnv("ACCESS_LOG_FORMAT", "json") // or "clf"
+	accessLogMaxSize        = getEnv("ACCESS_LOG_MAX_SIZE", "100M")
+	accessLogMaxBackups     = getEnv("ACCESS_LOG_MAX_BACKUPS", "5")
+	accessLogUploadInterval = getEnv("ACCESS_LOG_UPLOAD_INTERVAL", "5m")
+)
+
+// combined log format, with the request ID appended
+const clfFormat = `${remote_ip} - - [${time_custom}] "${method} ${uri} ${protocol}" ${status} ${bytes_out} ` +
+	`"${referer}" "${user_agent}" ${id}` + "\n"
+
+const clfTimeFormat = "02/Jan/2006:15:04:05 -0700"
+
+// accessLogMiddleware writes an access log line per request to ACCESS_LOG,
+// in addition to the logger middleware's output on stdout.
+func accessLogMiddleware() echo.MiddlewareFunc {
+	if accessLog == "" {
+		return func(next echo.HandlerFunc) echo.HandlerFunc { return next }
+	}
+	sink, err := newLogSink(accessLog)
+	if err != nil {
+		e.Logger.Errorf("ignoring ACCESS_LOG: %v", err)
+		return func(next echo.HandlerFunc) echo.HandlerFunc { return next }
+	}
+
+	return middleware.LoggerWithConfig(accessLogConfig(accessLogFormat, sink))
+}
+
+func accessLogConfig(format string, output io.Writer) middleware.LoggerConfig {
+	cfg := middleware.LoggerConfig{Format: middleware.DefaultLoggerConfig.Format, Output: output}
+	if format == "clf" {
+		cfg.Format = clfFormat
+		cfg.CustomTimeFormat = clfTimeFormat
+	}
+
+	return cfg
+}
+
+// newLogSink opens a file:// or s3:// sink of log lines, rotated and uploaded
+// with the ACCESS_LOG_* settings.
+func newLogSink(sink string) (io.Writer, error) {
+	u, err := url.Parse(sink)
+	if err != nil {
+		return nil, err
+	}
+	maxSize, err := gbytes.Parse(accessLogMaxSize)
+	if err != nil {
+		return nil, fmt.Errorf("invalid ACCESS_LOG_MAX_SIZE: %s", accessLogMaxSize)
+	}
+
+	switch u.Scheme {
+	case "file":
+		return newRotatingFile(u.Path, maxSize, envInt(accessLogMaxBackups, 5)), nil
+	case "s3":
+		ctx := context.Background()
+		cfg, err := config.LoadDefaultConfig(ctx)
+		if err != nil {
+			return nil, err
+		}
+		client := s3.NewFromConfig(cfg)
+		upload := func(ctx context.Context, key string, body []byte) error {
+			_, err := client.PutObject(ctx, &s3.PutObjectInput{
+				Bucket: aws.String(u.Host),
+				Key:    aws.String(key),
+				Body:   bytes.NewReader(body),
+			})
+			return err
+		}
+		s := newS3Sink(upload, strings.Trim(u.Path, "/"), maxSize)
+		go s.run(ctx, envDuration(accessLogUploadInterval, 5*time.Minute))
+		return s, nil
+	}
+
+	return nil, fmt.Errorf("unsupported log sink: %s", sink)
+}
+
+// rotatingFile is an append-only log file, rotated to path.1, path.2, ...
+// once it would grow past maxSize.
+type rotatingFile struct {
+	mu         sync.Mutex
+	path       string
+	maxSize    int64
+	maxBackups int
+	file       *os.File
+	size       int64
+}
+
+func newRotatingFile(path string, maxSize int64, maxBackups int) *rotatingFile {
+	return &rotatingFile{path: path, maxSize: maxSize, maxBackups: maxBackups}
+}
+
+func (f *rotatingFile) Write(p []byte) (int, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.file == nil {
+		if err := f.open(); err != nil {
+			return 0, err
+		}
+	}
+	if f.maxSize > 0 && f.size > 0 && f.size+int64(len(p)) > f.maxSize {
+		if err := f.rotate(); err != nil {
+			return 0, err
+		}
+	}
+	n, err := f.file.Write(p)
+	f.size += int64(n)
+
+	return n, err
+}
+
+func (f *rotatingFile) open() error {
+	file, err := os.OpenFile(f.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return err
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return err
+	}
+	f.file, f.size = file, info.Size()
+
+	return nil
+}
+
+func (f *rotatingFile) rotate() error {
+	if err := f.file.Close(); err != nil {
+		return err
+	}
+	f.file = nil
+
+	if f.maxBackups < 1 {
+		if err := os.Remove(f.path); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		return f.open()
+	}
+	for i := f.maxBackups - 1; i >= 1; i-- {
+		if err := os.Rename(f.backup(i), f.backup(i+1)); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+	if err := os.Rename(f.path, f.backup(1)); err != nil {
+		return err
+	}
+
+	return f.open()
+}
+
+func (f *rotatingFile) backup(i int) string {
+	return f.path + "." + strconv.Itoa(i)
+}
+
+// s3Sink buffers log lines and uploads them as a new object on every
+// interval, or sooner once the buffer reaches maxSize.
+type s3Sink struct {
+	mu      sync.Mutex
+	buf     bytes.Buffer
+	upload  func(ctx context.Context, key string, body []byte) error
+	prefix  string
+	maxSize int64
+}
+
+func newS3Sink(upload func(ctx context.Context, key string, body []byte) error, prefix string, maxSize int64) *s3Sink {
+	return &s3Sink{upload: upload, prefix: prefix, maxSize: maxSize}
+}
+
+func (s *s3Sink) Write(p []byte) (int, error) {
+	s.mu.Lock()
+	n, _ := s.buf.Write(p)
+	full := s.maxSize > 0 && int64(s.buf.Len()) >= s.maxSize
+	s.mu.Unlock()
+
+	if full {
+		go s.flush(context.Background())
+	}
+
+	return n, nil
+}
+
+func (s *s3Sink) run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			s.flush(context.Background())
+			return
+		case <-ticker.C:
+			s.flush(ctx)
+		}
+	}
+}
+
+func (s *s3Sink) flush(ctx context.Context) {
+	s.mu.Lock()
+	if s.buf.Len() == 0 {
+		s.mu.Unlock()
+		return
+	}
+	body := append([]byte(nil), s.buf.Bytes()...)
+	s.buf.Reset()
+	s.mu.Unlock()
+
+	if err := s.upload(ctx, s.key(time.Now()), body); err != nil {
+		e.Logger.Errorf("access log upload failed: %v", err)
+	}
+}
+
+// key names each upload by time, and by host so replicas do not collide.
+func (s *s3Sink) key(now time.Time) string {
+	host, _ := os.Hostname()
+	name := fmt.Sprintf("%s-%s.log", now.UTC().Format("150405.000000000"), host)
+
+	return path.Join(s.prefix, now.UTC().Format("2006/01/02"), name)
+}
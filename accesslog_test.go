@@ -0,0 +1,80 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/labstack/echo/v4/middleware"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRotatingFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "access.log")
+	f := newRotatingFile(path, 10, 2)
+
+	for _, line := range []string{"one\n", "two\n", "three\n", "four\n", "five\n"} {
+		_, err := f.Write([]byte(line))
+		assert.NoError(t, err)
+	}
+	defer f.file.Close()
+
+	read := func(name string) string {
+		data, _ := ioutil.ReadFile(name)
+		return string(data)
+	}
+	assert.Equal(t, "four\nfive\n", read(path))
+	assert.Equal(t, "three\n", read(path+".1"))
+	assert.Equal(t, "one\ntwo\n", read(path+".2"))
+	assert.NoFileExists(t, path+".3")
+}
+
+func TestS3Sink(t *testing.T) {
+	uploads := map[string]string{}
+	s := newS3Sink(func(ctx context.Context, key string, body []byte) error {
+		uploads[key] = string(body)
+		return nil
+	}, "nlp-client", 0)
+
+	_, _ = s.Write([]byte("one\n"))
+	_, _ = s.Write([]byte("two\n"))
+	s.flush(context.Background())
+	// nothing new to upload
+	s.flush(context.Background())
+
+	if assert.Len(t, uploads, 1) {
+		for key, body := range uploads {
+			assert.Regexp(t, `^nlp-client/\d{4}/\d{2}/\d{2}/\d{6}\.\d{9}-.+\.log$`, key)
+			assert.Equal(t, "one\ntwo\n", body)
+		}
+	}
+}
+
+func TestS3SinkKey(t *testing.T) {
+	s := newS3Sink(nil, "", 0)
+	key := s.key(time.Date(2026, 10, 14, 9, 30, 0, 0, time.UTC))
+	assert.Regexp(t, `^2026/10/14/093000\.000000000-`, key)
+}
+
+func TestAccessLogCLF(t *testing.T) {
+	out := new(bytes.Buffer)
+	handler := middleware.LoggerWithConfig(accessLogConfig("clf", out))(func(c echo.Context) error {
+		return c.String(http.StatusOK, "ok")
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/health", nil)
+	req.RemoteAddr = "10.0.0.1:1234"
+	req.Header.Set("User-Agent", "curl/7.79")
+	if assert.NoError(t, handler(e.NewContext(req, httptest.NewRecorder()))) {
+		assert.Regexp(t, regexp.MustCompile(
+			`^10\.0\.0\.1 - - \[\d{2}/\w{3}/\d{4}:\d{2}:\d{2}:\d{2} [+-]\d{4}\] "GET /health HTTP/1\.1" 200 2 "" "curl/7\.79" \n$`),
+			out.String())
+	}
+}
@@ -0,0 +1,107 @@
+// author: Gary A. Stafford
+// site: https://programmaticponderings.com
+// license: MIT License
+// purpose: NLP microservices: nlp-client admin and debug endpoints
+// modified: 2026-10-14
+
+package main
+
+import (
+	"net/http"
+	"net/http/pprof"
+	"runtime"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/labstack/echo/v4/middleware"
+)
+
+// set at build time, e.g. -ldflags "-X main.version=1.2.0 -X main.commit=$(git rev-parse HEAD)"
+var (
+	version   = "dev"
+	commit    = "unknown"
+	buildDate = "unknown"
+)
+
+var (
+	// comma-separated keys for the admin routes, which are closed until one is set
+	adminAPIKey = getEnv("ADMIN_API_KEY", "")
+	adminKeys   = newSecret(adminAPIKey)
+)
+
+type buildInfo struct {
+	Version   string `json:"version"`
+	Commit    string `json:"commit"`
+	BuildDate string `json:"build_date"`
+	GoVersion string `json:"go_version"`
+}
+
+func currentBuildInfo() buildInfo {
+	return buildInfo{version, commit, buildDate, runtime.Version()}
+}
+
+func adminAuth() echo.MiddlewareFunc {
+	return middleware.KeyAuthWithConfig(middleware.KeyAuthConfig{
+		KeyLookup: "header:X-API-Key",
+		Validator: func(key string, c echo.Context) (bool, error) {
+			return adminKeys.Matches(key), nil
+		},
+	})
+}
+
+func adminRoutes() []route {
+	return []route{
+		{http.MethodGet, "/debug/pprof/", echo.WrapHandler(http.HandlerFunc(pprof.Index)), groupAdmin},
+		{http.MethodGet, "/debug/pprof/cmdline", echo.WrapHandler(http.HandlerFunc(pprof.Cmdline)), groupAdmin},
+		{http.MethodGet, "/debug/pprof/profile", echo.WrapHandler(http.HandlerFunc(pprof.Profile)), groupAdmin},
+		{http.MethodGet, "/debug/pprof/symbol", echo.WrapHandler(http.HandlerFunc(pprof.Symbol)), groupAdmin},
+		{http.MethodPost, "/debug/pprof/symbol", echo.WrapHandler(http.HandlerFunc(pprof.Symbol)), groupAdmin},
+		{http.MethodGet, "/debug/pprof/trace", echo.WrapHandler(http.HandlerFunc(pprof.Trace)), groupAdmin},
+		// pprof.Index serves the named profiles, e.g. heap and goroutine
+		{http.MethodGet, "/debug/pprof/*", echo.WrapHandler(http.HandlerFunc(pprof.Index)), groupAdmin},
+		{http.MethodGet, "/debug/runtime", getRuntime, groupAdmin},
+		{http.MethodGet, "/admin/logging", getLogging, groupAdmin},
+		{http.MethodPut, "/admin/logging", putLogging, groupAdmin},
+		{http.MethodGet, "/admin/faults", getFaults, groupAdmin},
+		{http.MethodPut, "/admin/faults/:upstream", putFault, groupAdmin},
+		{http.MethodDelete, "/admin/faults/:upstream", deleteFault, groupAdmin},
+		{http.MethodPost, "/admin/records/decrypt", postDecryptRecord, groupAdmin},
+		{http.MethodGet, "/admin/feedback/export", getFeedbackExport, groupAdmin},
+		{http.MethodGet, "/admin/reviews", getReviews, groupAdmin},
+		{http.MethodPost, "/admin/reviews/:id", postReview, groupAdmin},
+	}
+}
+
+func getVersion(c echo.Context) error {
+	return c.JSON(http.StatusOK, currentBuildInfo())
+}
+
+func getRuntime(c echo.Context) error {
+	var mem runtime.MemStats
+	runtime.ReadMemStats(&mem)
+
+	var lastGC *time.Time
+	if mem.LastGC > 0 {
+		last := time.Unix(0, int64(mem.LastGC)).UTC()
+		lastGC = &last
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"build":      currentBuildInfo(),
+		"goroutines": runtime.NumGoroutine(),
+		"cpus":       runtime.NumCPU(),
+		"gc": map[string]interface{}{
+			"count":          mem.NumGC,
+			"pause_total_ns": mem.PauseTotalNs,
+			"last":           lastGC,
+			"cpu_fraction":   mem.GCCPUFraction,
+			"next_target":    mem.NextGC,
+		},
+		"memory": map[string]interface{}{
+			"heap_alloc":   mem.HeapAlloc,
+			"heap_inuse":   mem.HeapInuse,
+			"heap_objects": mem.HeapObjects,
+			"sys":          mem.Sys,
+		},
+	})
+}
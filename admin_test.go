@@ -0,0 +1,71 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"runtime"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGetVersion(t *testing.T) {
+	req := httptest.NewRequest(http.MethodGet, "/version", nil)
+	w := httptest.NewRecorder()
+
+	if assert.NoError(t, getVersion(e.NewContext(req, w))) {
+		var info buildInfo
+		if assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &info)) {
+			assert.Equal(t, "dev", info.Version)
+			assert.Equal(t, runtime.Version(), info.GoVersion)
+		}
+	}
+}
+
+func TestGetRuntime(t *testing.T) {
+	req := httptest.NewRequest(http.MethodGet, "/debug/runtime", nil)
+	w := httptest.NewRecorder()
+
+	if assert.NoError(t, getRuntime(e.NewContext(req, w))) {
+		var stats struct {
+			Goroutines int `json:"goroutines"`
+		}
+		if assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &stats)) {
+			assert.Greater(t, stats.Goroutines, 0)
+		}
+	}
+}
+
+func TestAdminRoutes(t *testing.T) {
+	defer func(key string) { adminKeys.Set(key) }(adminKeys.Get())
+
+	admin := echo.New()
+	admin.HTTPErrorHandler = httpErrorHandler
+	for _, r := range adminRoutes() {
+		admin.Add(r.method, r.path, r.handler, adminAuth())
+	}
+
+	// closed while no admin key is configured
+	adminKeys.Set("")
+	req := httptest.NewRequest(http.MethodGet, "/debug/pprof/goroutine", nil)
+	req.Header.Set("X-API-Key", "")
+	w := httptest.NewRecorder()
+	admin.ServeHTTP(w, req)
+	assert.NotEqual(t, http.StatusOK, w.Code)
+
+	adminKeys.Set("admin")
+	req = httptest.NewRequest(http.MethodGet, "/debug/pprof/goroutine?debug=1", nil)
+	req.Header.Set("X-API-Key", "admin")
+	w = httptest.NewRecorder()
+	admin.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), "goroutine")
+
+	req = httptest.NewRequest(http.MethodGet, "/debug/runtime", nil)
+	req.Header.Set("X-API-Key", "wrong")
+	w = httptest.NewRecorder()
+	admin.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+}
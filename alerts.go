@@ -0,0 +1,286 @@
+// author: Gary A. Stafford
+// site: https://programmaticponderings.com
+// license: MIT License
+// purpose: NLP microservices: nlp-client alerting rules on analysis results
+// modified: 2026-10-14
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/labstack/echo/v4"
+)
+
+var (
+	// semicolon-separated rules, each "name: subject op value [when Header=value]", e.g.
+	// "people: entities.PERSON > 5; not-english: language != en when X-Channel=support"
+	alertRules = getEnv("ALERT_RULES", "")
+	// Slack-compatible incoming webhook notified of every match; unset to only count them
+	alertWebhookURL     = getEnv("ALERT_WEBHOOK_URL", "")
+	alertWebhookTimeout = getEnv("ALERT_WEBHOOK_TIMEOUT", "5s")
+	// "on" to evaluate the rules over the text of /record requests and store the
+	// names of those matched in an "alerts" field of the record
+	alertRecord = getEnv("ALERT_RECORD", "off")
+
+	alerts      = loadAlertRules()
+	alertNotify = alertWebhook(alertWebhookURL)
+)
+
+// alertRule compares a measure of one analysis's result with a value: the
+// number of tokens, sentences, keywords, or entities (of one label, for
+// entities.LABEL), or the detected language.
+type alertRule struct {
+	name     string
+	analysis string
+	label    string
+	op       string
+	value    string
+	number   float64
+	// the rule only applies to requests with this header value, when set
+	header      string
+	headerValue string
+}
+
+// alertEvent is posted to the webhook. Slack renders the text and ignores
+// the other fields, which are there for other receivers.
+type alertEvent struct {
+	Text     string    `json:"text"`
+	Event    string    `json:"event"`
+	Rule     string    `json:"rule"`
+	Analysis string    `json:"analysis"`
+	Value    string    `json:"value"`
+	Time     time.Time `json:"time"`
+}
+
+func parseAlertRule(entry string) (alertRule, error) {
+	parts := strings.SplitN(entry, ":", 2)
+	fields := []string{}
+	if len(parts) == 2 {
+		fields = strings.Fields(parts[1])
+	}
+	if len(fields) != 3 && !(len(fields) == 5 && fields[3] == "when") {
+		return alertRule{}, fmt.Errorf("invalid alert rule %q, want name: subject op value [when Header=value]", entry)
+	}
+
+	rule := alertRule{name: strings.TrimSpace(parts[0]), op: fields[1], value: fields[2]}
+	subject := strings.SplitN(fields[0], ".", 2)
+	rule.analysis = subject[0]
+	if len(subject) == 2 {
+		rule.label = subject[1]
+	}
+	switch {
+	case rule.name == "":
+		return alertRule{}, fmt.Errorf("alert rule %q has no name", entry)
+	case rule.analysis == "language" && rule.label == "":
+		if rule.op != "==" && rule.op != "!=" {
+			return alertRule{}, fmt.Errorf("alert rule %s compares language with %q, want == or !=", rule.name, rule.op)
+		}
+	case rule.analysis == "entities" || (rule.label == "" && (rule.analysis == "tokens" || rule.analysis == "sentences" || rule.analysis == "keywords")):
+		switch rule.op {
+		case ">", ">=", "<", "<=", "==", "!=":
+		default:
+			return alertRule{}, fmt.Errorf("alert rule %s has unknown operator %q", rule.name, rule.op)
+		}
+		number, err := strconv.ParseFloat(rule.value, 64)
+		if err != nil {
+			return alertRule{}, fmt.Errorf("alert rule %s compares a count with %q", rule.name, rule.value)
+		}
+		rule.number = number
+	default:
+		return alertRule{}, fmt.Errorf("alert rule %s has unknown subject %q", rule.name, fields[0])
+	}
+	if len(fields) == 5 {
+		scope := strings.SplitN(fields[4], "=", 2)
+		if len(scope) != 2 || scope[0] == "" {
+			return alertRule{}, fmt.Errorf("alert rule %s has invalid condition %q, want Header=value", rule.name, fields[4])
+		}
+		rule.header, rule.headerValue = scope[0], scope[1]
+	}
+
+	return rule, nil
+}
+
+func parseAlertRules(config string) ([]alertRule, error) {
+	var rules []alertRule
+	for _, entry := range strings.Split(config, ";") {
+		if strings.TrimSpace(entry) == "" {
+			continue
+		}
+		rule, err := parseAlertRule(entry)
+		if err != nil {
+			return nil, err
+		}
+		rules = append(rules, rule)
+	}
+
+	return rules, nil
+}
+
+func loadAlertRules() []alertRule {
+	rules, err := parseAlertRules(alertRules)
+	if err != nil {
+		e.Logger.Errorf("ignoring ALERT_RULES: %v", err)
+		return nil
+	}
+
+	return rules
+}
+
+// applies reports whether a rule applies to a request with header.
+func (r alertRule) applies(header http.Header) bool {
+	return r.header == "" || header.Get(r.header) == r.headerValue
+}
+
+// measure returns the measure of an upstream result the rule compares.
+func (r alertRule) measure(body json.RawMessage) (string, error) {
+	if r.analysis == "language" {
+		var language struct {
+			Language string `json:"language"`
+		}
+		err := json.Unmarshal(body, &language)
+		return language.Language, err
+	}
+	if r.label == "" {
+		var raw []json.RawMessage
+		err := json.Unmarshal(body, &raw)
+		return strconv.Itoa(len(raw)), err
+	}
+	var items []struct {
+		Label string `json:"label"`
+	}
+	if err := json.Unmarshal(body, &items); err != nil {
+		return "", err
+	}
+	count := 0
+	for _, item := range items {
+		if item.Label == r.label {
+			count++
+		}
+	}
+
+	return strconv.Itoa(count), nil
+}
+
+func (r alertRule) matches(measured string) bool {
+	if r.analysis == "language" {
+		return (measured == r.value) == (r.op == "==")
+	}
+	count, _ := strconv.ParseFloat(measured, 64)
+	switch r.op {
+	case ">":
+		return count > r.number
+	case ">=":
+		return count >= r.number
+	case "<":
+		return count < r.number
+	case "<=":
+		return count <= r.number
+	case "==":
+		return count == r.number
+	}
+
+	return count != r.number
+}
+
+// checkAlerts evaluates the rules over the successful upstream results of a
+// request, keyed by analysis, notifying each match, and returns the names of
+// the rules matched.
+func checkAlerts(header http.Header, results map[string]json.RawMessage) []string {
+	matched := []string{}
+	for _, rule := range alerts {
+		body, ok := results[rule.analysis]
+		if !ok || !rule.applies(header) {
+			continue
+		}
+		measured, err := rule.measure(body)
+		if err != nil || !rule.matches(measured) {
+			continue
+		}
+		matched = append(matched, rule.name)
+		metrics.Count("alerts_matched_total", 1, map[string]string{"rule": rule.name})
+		if alertNotify == nil {
+			continue
+		}
+		go notifyAlert(alertNotify, alertEvent{
+			Text:     fmt.Sprintf("nlp-client: alert %s matched, %s %s", rule.name, rule.subject(), measured),
+			Event:    "analysis_alert",
+			Rule:     rule.name,
+			Analysis: rule.analysis,
+			Value:    measured,
+			Time:     time.Now().UTC(),
+		})
+	}
+
+	return matched
+}
+
+func (r alertRule) subject() string {
+	if r.label != "" {
+		return r.analysis + "." + r.label
+	}
+
+	return r.analysis
+}
+
+func notifyAlert(notify func(ctx context.Context, event alertEvent) error, event alertEvent) {
+	ctx, cancel := context.WithTimeout(context.Background(), envDuration(alertWebhookTimeout, 5*time.Second))
+	defer cancel()
+	if err := notify(ctx, event); err != nil {
+		e.Logger.Errorf("alert webhook failed: %v", err)
+	}
+}
+
+func alertWebhook(url string) func(ctx context.Context, event alertEvent) error {
+	if url == "" {
+		return nil
+	}
+
+	return func(ctx context.Context, event alertEvent) error {
+		return sendWebhook(ctx, url, event)
+	}
+}
+
+// alertedResults returns the successful results of a multi-analysis request, for checkAlerts.
+func alertedResults(results map[string]analysisResult) map[string]json.RawMessage {
+	bodies := make(map[string]json.RawMessage, len(results))
+	for name, result := range results {
+		if result.Error == nil {
+			bodies[name] = result.Result
+		}
+	}
+
+	return bodies
+}
+
+// tagRecord analyzes the text of a /record request with the analyses the
+// rules applying to it compare, and stores the names of the rules matched in
+// the "alerts" field of the record.
+func tagRecord(c echo.Context, r *record) error {
+	req := c.Request()
+	needed := map[string]bool{}
+	for _, rule := range alerts {
+		if rule.applies(req.Header) {
+			needed[rule.analysis] = true
+		}
+	}
+	if len(needed) == 0 {
+		return nil
+	}
+	analyses := make([]string, 0, len(needed))
+	for name := range needed {
+		analyses = append(analyses, name)
+	}
+	sort.Strings(analyses)
+
+	results := runAnalyses(req.Context(), r.text, analyses, req.Header.Get("X-API-Key"))
+
+	return r.set("alerts", checkAlerts(req.Header, alertedResults(results)))
+}
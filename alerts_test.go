@@ -0,0 +1,102 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/garystafford/nlp-client/mockupstream"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseAlertRules(t *testing.T) {
+	rules, err := parseAlertRules("people: entities.PERSON > 5; not-english: language != en when X-Channel=support")
+	if assert.NoError(t, err) {
+		assert.Equal(t, []alertRule{
+			{name: "people", analysis: "entities", label: "PERSON", op: ">", value: "5", number: 5},
+			{name: "not-english", analysis: "language", op: "!=", value: "en", header: "X-Channel", headerValue: "support"},
+		}, rules)
+	}
+
+	for _, config := range []string{
+		"people entities > 5",
+		"people: entities >",
+		"people: entities ~ 5",
+		"people: entities > many",
+		"english: language > en",
+		"long: tokens.NOUN > 5",
+		"long: words > 5",
+		"long: tokens > 5 if X-Channel=support",
+		"long: tokens > 5 when X-Channel",
+	} {
+		_, err := parseAlertRules(config)
+		assert.Error(t, err, config)
+	}
+}
+
+// captureAlerts replaces the alert webhook with one sending its events to the returned channel.
+func captureAlerts(t *testing.T, config string) <-chan alertEvent {
+	rules, err := parseAlertRules(config)
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	events := make(chan alertEvent, 10)
+	restoreAlerts, restoreNotify := alerts, alertNotify
+	t.Cleanup(func() { alerts, alertNotify = restoreAlerts, restoreNotify })
+	alerts = rules
+	alertNotify = func(ctx context.Context, event alertEvent) error {
+		events <- event
+		return nil
+	}
+
+	return events
+}
+
+func TestCheckAlerts(t *testing.T) {
+	events := captureAlerts(t, "people: entities.PERSON >= 2; long: tokens > 3; not-english: language != en when X-Channel=support")
+
+	header := http.Header{}
+	header.Set("X-Channel", "support")
+	matched := checkAlerts(header, map[string]json.RawMessage{
+		"entities": json.RawMessage(`[{"text":"Marie Curie","label":"PERSON"},{"text":"Paris","label":"GPE"},{"text":"Pierre","label":"PERSON"}]`),
+		"tokens":   json.RawMessage(`["Marie","Curie"]`),
+		"language": json.RawMessage(`{"language":"fr","probability":0.98}`),
+	})
+	assert.Equal(t, []string{"people", "not-english"}, matched)
+
+	select {
+	case event := <-events:
+		assert.Equal(t, "analysis_alert", event.Event)
+		assert.Contains(t, []string{"people", "not-english"}, event.Rule)
+	case <-time.After(time.Second):
+		t.Fatal("no alert notified")
+	}
+
+	matched = checkAlerts(http.Header{}, map[string]json.RawMessage{"language": json.RawMessage(`{"language":"fr"}`)})
+	assert.Empty(t, matched, "rules scoped to a header value only apply to requests with it")
+}
+
+func TestTagRecord(t *testing.T) {
+	captureAlerts(t, "people: entities.PERSON > 0")
+	var records []map[string]interface{}
+	_, dynamo := newModerationUpstreams(t, &records)
+	defer dynamo.Close()
+	prose := mockupstream.NewProse()
+	defer prose.Close()
+	defer func(proseURL, dynamoURL, mode string) {
+		urlProse, urlDynamo, alertRecord = proseURL, dynamoURL, mode
+	}(urlProse, urlDynamo, alertRecord)
+	urlProse, urlDynamo = prose.URL, dynamo.URL
+	alertRecord = "on"
+
+	req := httptest.NewRequest(http.MethodPost, "/record", strings.NewReader(`{"text": "Marie Curie", "source": "web"}`))
+	c := e.NewContext(req, httptest.NewRecorder())
+	c.SetPath("/record")
+	if assert.NoError(t, putDynamo(c)) && assert.Len(t, records, 1) {
+		assert.Equal(t, map[string]interface{}{"text": "Marie Curie", "source": "web", "alerts": []interface{}{"people"}}, records[0])
+	}
+}
@@ -0,0 +1,344 @@
+// author: Gary A. Stafford
+// site: https://programmaticponderings.com
+// license: MIT License
+// purpose: NLP microservices: nlp-client multi-analysis requests
+// modified: 2026-10-14
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/labstack/echo/v4"
+)
+
+// Overall status of a multi-analysis request.
+const (
+	analysesOK      = "ok"
+	analysesPartial = "partial"
+	analysesFailed  = "failed"
+)
+
+// analysisResult is the outcome of one analysis of a multi-analysis request;
+// a failed analysis reports its error inline instead of failing the request.
+type analysisResult struct {
+	Status int             `json:"status"`
+	Result json.RawMessage `json:"result,omitempty"`
+	Error  *apiError       `json:"error,omitempty"`
+	meta   *upstreamMeta
+}
+
+// analysisEndpoint returns the upstream URL serving the named analysis.
+func analysisEndpoint(name string) (string, bool) {
+	switch name {
+	case "keywords":
+		return urlRake + "/keywords", true
+	case "tokens":
+		return urlProse + "/tokens", true
+	case "entities":
+		return urlProse + "/entities", true
+	case "sentences":
+		return urlProse + "/sentences", true
+	case "language":
+		return urlLang + "/language", true
+	}
+
+	return "", false
+}
+
+// output formats of the multi-analysis routes
+const (
+	formatJSON   = "json"
+	formatHTML   = "html"
+	formatReport = "report"
+)
+
+// parseFormat returns the ?format= a request asks for, JSON by default.
+func parseFormat(format string) (string, error) {
+	switch format {
+	case "", formatJSON:
+		return formatJSON, nil
+	case formatHTML, formatReport:
+		return format, nil
+	}
+
+	return "", fmt.Errorf("unknown format %q, want json, html, or report", format)
+}
+
+func parseAnalyses(value string) ([]string, error) {
+	var analyses []string
+	for _, name := range strings.Split(value, ",") {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+		if _, ok := analysisEndpoint(name); !ok {
+			return nil, fmt.Errorf("unknown analysis: %s", name)
+		}
+		analyses = append(analyses, name)
+	}
+	if len(analyses) == 0 {
+		return nil, fmt.Errorf("at least one analysis is required")
+	}
+
+	return analyses, nil
+}
+
+// postUpstream sends a text to an upstream analysis and returns the raw JSON response.
+func postUpstream(ctx context.Context, url, text, key string) (json.RawMessage, error) {
+	return postUpstreamJSON(ctx, url, struct {
+		Text string `json:"text"`
+	}{text}, key)
+}
+
+// postUpstreamJSON posts payload to an upstream as JSON and returns its response body.
+func postUpstreamJSON(ctx context.Context, url string, payload interface{}, key string) (json.RawMessage, error) {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return nil, err
+	}
+
+	// a bytes.Reader body sets ContentLength and GetBody, so upstreams that
+	// reject chunked requests accept it and the failover can replay it
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", echo.MIMEApplicationJSON)
+	req.Header.Set("X-API-Key", key)
+	setDeadlineHeader(req)
+	setBaggageHeader(req)
+	setForwardedHeaders(req)
+
+	resp, err := upstreamClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer func(Body io.ReadCloser) {
+		err := Body.Close()
+		if err != nil {
+			e.Logger.Error(err)
+		}
+	}(resp.Body)
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return nil, newUpstreamStatusError(resp)
+	}
+
+	return ioutil.ReadAll(resp.Body)
+}
+
+// runAnalyses runs each analysis of text in turn, recording failures per
+// analysis. Each analysis gets a share of the remaining deadline, and those
+// left once it has passed fail without calling their upstream.
+func runAnalyses(ctx context.Context, text string, analyses []string, key string) map[string]analysisResult {
+	return runAnalysesConcurrently(ctx, text, analyses, key, 1)
+}
+
+func runAnalysis(ctx context.Context, name, text, key string) analysisResult {
+	url, _ := analysisEndpoint(name)
+	return runAnalysisWith(ctx, name, providerFor(name), url, text, key)
+}
+
+// runAnalysisWith runs an analysis of text with the provider answering it at url.
+func runAnalysisWith(ctx context.Context, name, provider, url, text, key string) analysisResult {
+	text, err := transformText("/"+name, text)
+	if err != nil {
+		return failedAnalysis(validationError(err.Error()))
+	}
+	start := time.Now()
+	body, err := postUpstream(ctx, url, text, key)
+	if err != nil {
+		result := failedAnalysis(upstreamError(err))
+		result.meta = newUpstreamMeta(name, provider, start, upstreamStatus(err))
+		result.meta.Transforms = requestTransformsFor("/" + name)
+		result.meta.Region = servedRegion(url)
+		return result
+	}
+	meta := newUpstreamMeta(name, provider, start, http.StatusOK)
+	meta.Transforms = requestTransformsFor("/" + name)
+	meta.Region = servedRegion(url)
+	if body, err = transformResponse("/"+name, body); err != nil {
+		result := failedAnalysis(internalError(err))
+		result.meta = meta
+		return result
+	}
+
+	return analysisResult{Status: http.StatusOK, Result: body, meta: meta}
+}
+
+func failedAnalysis(err *apiError) analysisResult {
+	return analysisResult{Status: err.Status, Error: err}
+}
+
+// analysesResponse returns the overall status of the analyses of a request,
+// and the HTTP status to answer with: 207 when only some succeed, and the
+// status of the first when none do.
+func analysesResponse(results map[string]analysisResult, analyses []string) (string, int) {
+	status := analysesStatus(results)
+	switch status {
+	case analysesPartial:
+		return status, http.StatusMultiStatus
+	case analysesFailed:
+		return status, results[analyses[0]].Status
+	}
+
+	return status, http.StatusOK
+}
+
+func analysesStatus(results map[string]analysisResult) string {
+	failed := 0
+	for _, result := range results {
+		if result.Error != nil {
+			failed++
+		}
+	}
+
+	switch failed {
+	case 0:
+		return analysesOK
+	case len(results):
+		return analysesFailed
+	default:
+		return analysesPartial
+	}
+}
+
+// postAnalyze runs several analyses of one text. It answers 200 when every
+// analysis succeeds, 207 Multi-Status when only some do, and the status of
+// the first failed analysis when none do; the body always holds every section.
+func postAnalyze(c echo.Context) error {
+	analyses, err := parseAnalyses(c.QueryParam("analyses"))
+	if err != nil {
+		return validationError(err.Error())
+	}
+	var body struct {
+		Text string `json:"text"`
+	}
+	if err := json.NewDecoder(c.Request().Body).Decode(&body); err != nil {
+		return validationError(err.Error())
+	}
+
+	return respondAnalyses(c, body.Text, analyses, "")
+}
+
+// finishResult calibrates, queues for review, filters, normalizes, merges
+// with the tenant's local entities, and enriches a successful analysis result, as
+// respondResult does the result of a single-analysis route.
+func finishResult(c echo.Context, name, text, version string, filter *resultFilter, enrich *enrichment, result *analysisResult) error {
+	route := "/" + name
+	body, calibration, err := calibrate(route, result.Result)
+	if err != nil {
+		return err
+	}
+	queueReview(c, route, text, body)
+	if calibration != "" && result.meta != nil {
+		result.meta.Calibration = calibration
+	}
+	if filter != nil {
+		if body, err = filter.apply(route, body); err != nil {
+			return err
+		}
+	}
+	if version == schemaV2 {
+		if body, err = normalizeResult(route, text, body); err != nil {
+			return err
+		}
+	}
+	if body, err = mergeLocalEntities(c.Request().Header.Get(tenantHeader), route, version, text, body); err != nil {
+		return err
+	}
+	if enrich.applies(route) {
+		if body, err = enrich.apply(c.Request().Context(), route, text, body); err != nil {
+			return err
+		}
+	}
+	result.Result = body
+
+	return nil
+}
+
+// respondAnalyses runs the analyses of text and writes every section, along
+// with the text itself when it was extracted rather than sent by the caller,
+// or, with ?format=html, the text with its keywords and entities marked, or,
+// with ?format=report, a summary for people to read.
+func respondAnalyses(c echo.Context, text string, analyses []string, extracted string) error {
+	version, err := schemaVersion(c)
+	if err != nil {
+		return validationError(err.Error())
+	}
+	filter, err := parseResultFilter(c)
+	if err != nil {
+		return validationError(err.Error())
+	}
+	enrich, err := parseEnrich(c)
+	if err != nil {
+		return validationError(err.Error())
+	}
+	format, err := parseFormat(c.QueryParam("format"))
+	if err != nil {
+		return validationError(err.Error())
+	}
+	style, err := parseReportStyle(c.QueryParam("report"))
+	if err != nil {
+		return validationError(err.Error())
+	}
+	concurrency, err := parseConcurrency(c)
+	if err != nil {
+		return validationError(err.Error())
+	}
+	shape, err := templateFor(c, "/analyze")
+	if err != nil {
+		return err
+	}
+	results := runAnalysesConcurrently(c.Request().Context(), text, analyses, c.Request().Header.Get("X-API-Key"), concurrency)
+	checkAlerts(c.Request().Header, alertedResults(results))
+	for name, result := range results {
+		if result.Error != nil {
+			continue
+		}
+		if err := finishResult(c, name, text, version, filter, enrich, &result); err != nil {
+			results[name] = failedAnalysis(internalError(err))
+			continue
+		}
+		results[name] = result
+	}
+	status, code := analysesResponse(results, analyses)
+	if shape != nil {
+		// the results of the analyses that succeeded, by name
+		shaped := map[string]interface{}{}
+		for name, result := range results {
+			if result.Error == nil {
+				var value interface{}
+				_ = json.Unmarshal(result.Result, &value)
+				shaped[name] = value
+			}
+		}
+		return respondTemplate(c, code, shape, shaped)
+	}
+	switch format {
+	case formatHTML:
+		return c.HTML(code, highlightHTML(text, results))
+	case formatReport:
+		return c.Blob(code, reportContentTypes[style], []byte(renderReport(style, analyses, results)))
+	}
+	var meta *responseMeta
+	if wantsMeta(c) || version == schemaV2 {
+		meta = analysesMeta(text, analyses, results)
+	}
+
+	return c.JSON(code, struct {
+		Text     string                    `json:"text,omitempty"`
+		Status   string                    `json:"status"`
+		Analyses map[string]analysisResult `json:"analyses"`
+		Meta     *responseMeta             `json:"_meta,omitempty"`
+	}{extracted, status, results, meta})
+}
@@ -0,0 +1,78 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseAnalyses(t *testing.T) {
+	analyses, err := parseAnalyses("keywords, language")
+	if assert.NoError(t, err) {
+		assert.Equal(t, []string{"keywords", "language"}, analyses)
+	}
+
+	_, err = parseAnalyses("keywords,sentiment")
+	assert.EqualError(t, err, "unknown analysis: sentiment")
+
+	_, err = parseAnalyses("")
+	assert.Error(t, err)
+}
+
+func TestPostUpstreamLength(t *testing.T) {
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, int64(len(`{"text":"Marie Curie"}`)), r.ContentLength, "the body is sent with its length")
+		assert.Empty(t, r.TransferEncoding, "the body is not chunked")
+		w.Write([]byte(`{}`))
+	}))
+	defer upstream.Close()
+
+	_, err := postUpstream(context.Background(), upstream.URL, "Marie Curie", "key")
+	assert.NoError(t, err)
+}
+
+func TestAnalysesStatus(t *testing.T) {
+	ok := analysisResult{Status: http.StatusOK}
+	failed := failedAnalysis(internalError(nil))
+
+	assert.Equal(t, analysesOK, analysesStatus(map[string]analysisResult{"keywords": ok}))
+	assert.Equal(t, analysesPartial, analysesStatus(map[string]analysisResult{"keywords": ok, "entities": failed}))
+	assert.Equal(t, analysesFailed, analysesStatus(map[string]analysisResult{"entities": failed}))
+}
+
+func TestPostAnalyzePartial(t *testing.T) {
+	upstream := newBatchUpstream(t)
+	defer upstream.Close()
+	defer func(rake, prose string) { urlRake, urlProse = rake, prose }(urlRake, urlProse)
+	urlRake = upstream.URL
+	urlProse = "http://127.0.0.1:1"
+
+	req := httptest.NewRequest(http.MethodPost, "/analyze?analyses=keywords,entities", strings.NewReader(`{"text": "curie"}`))
+	w := httptest.NewRecorder()
+	c := e.NewContext(req, w)
+
+	if assert.NoError(t, postAnalyze(c)) {
+		assert.Equal(t, http.StatusMultiStatus, w.Code)
+		assert.JSONEq(t, `{"status": "partial", "analyses": {
+			"keywords": {"status": 200, "result": ["CURIE"]},
+			"entities": {"status": 502, "error": {"code": "UPSTREAM_UNAVAILABLE",
+				"message": "upstream service unavailable", "retryable": true}}}}`, w.Body.String())
+	}
+}
+
+func TestPostAnalyzeFailed(t *testing.T) {
+	defer func(url string) { urlLang = url }(urlLang)
+	urlLang = "http://127.0.0.1:1"
+
+	req := httptest.NewRequest(http.MethodPost, "/analyze?analyses=language", strings.NewReader(`{"text": "curie"}`))
+	w := httptest.NewRecorder()
+	c := e.NewContext(req, w)
+
+	if assert.NoError(t, postAnalyze(c)) {
+		assert.Equal(t, http.StatusBadGateway, w.Code)
+	}
+}
@@ -0,0 +1,171 @@
+// author: Gary A. Stafford
+// site: https://programmaticponderings.com
+// license: MIT License
+// purpose: NLP microservices: nlp-client anonymized records
+// modified: 2026-10-14
+
+package main
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/labstack/echo/v4"
+)
+
+var (
+	// comma-separated scope=mode anonymizations of stored records, where scope is
+	// a tenant, a route storing records (/record, /analyze/email), or * for all
+	// others, and mode is "hash" to replace the text by its salted hash,
+	// "discard" to drop it, or "off"; e.g. "acme=discard,/analyze/email=hash"
+	recordAnonymize = getEnv("RECORD_ANONYMIZE", "")
+	// secret salt of the "hash" mode, so hashes cannot be matched to guessed texts
+	recordHashSalt = getEnv("RECORD_HASH_SALT", "")
+
+	anonymize map[string]string
+)
+
+// anonymizedAnalyses derive the features stored in place of the text.
+var anonymizedAnalyses = []string{"entities", "keywords", "language"}
+
+// recordFeatures are what is stored of the text of an anonymized record.
+type recordFeatures struct {
+	Keywords []string       `json:"keywords"`
+	Entities map[string]int `json:"entities"`
+	Language string         `json:"language"`
+	Length   int            `json:"length"`
+}
+
+func parseAnonymize(config string) (map[string]string, error) {
+	modes := map[string]string{}
+	for _, entry := range splitList(config) {
+		parts := strings.SplitN(entry, "=", 2)
+		if len(parts) != 2 || parts[0] == "" {
+			return nil, fmt.Errorf("invalid anonymization %q, want scope=mode", entry)
+		}
+		switch parts[1] {
+		case "off", "hash", "discard":
+		default:
+			return nil, fmt.Errorf("anonymization %s has unknown mode %q, want off, hash, or discard", parts[0], parts[1])
+		}
+		modes[parts[0]] = parts[1]
+	}
+
+	return modes, nil
+}
+
+// loadAnonymize parses RECORD_ANONYMIZE. Like the residency, a configuration
+// that cannot be parsed stops the client from starting, so raw text is never
+// stored by mistake.
+func loadAnonymize() error {
+	modes, err := parseAnonymize(recordAnonymize)
+	if err != nil {
+		return fmt.Errorf("RECORD_ANONYMIZE: %w", err)
+	}
+	for _, mode := range modes {
+		if mode == "hash" && recordHashSalt == "" {
+			return errors.New("RECORD_HASH_SALT is required with RECORD_ANONYMIZE hash mode")
+		}
+	}
+	anonymize = modes
+
+	return nil
+}
+
+// anonymizeMode returns the anonymization of a record stored by route for a
+// request with header: that of its tenant, else of the route, else of *.
+func anonymizeMode(header http.Header, route string) string {
+	if tenant := header.Get(tenantHeader); tenant != "" {
+		if mode, ok := anonymize[tenant]; ok {
+			return mode
+		}
+	}
+	if mode, ok := anonymize[route]; ok {
+		return mode
+	}
+	if mode, ok := anonymize["*"]; ok {
+		return mode
+	}
+
+	return "off"
+}
+
+// textHash returns the salted hash stored in place of a text.
+func textHash(text string) string {
+	mac := hmac.New(sha256.New, []byte(recordHashSalt))
+	mac.Write([]byte(text))
+
+	return "hmac-sha256:" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// deriveFeatures returns the features of a text from the results of the
+// anonymized analyses. A record is not stored without them, since nothing
+// else of its text is.
+func deriveFeatures(text string, results map[string]analysisResult) (recordFeatures, error) {
+	for _, name := range anonymizedAnalyses {
+		if err := results[name].Error; err != nil {
+			return recordFeatures{}, err
+		}
+	}
+
+	features := recordFeatures{Keywords: []string{}, Entities: map[string]int{}, Length: utf8.RuneCountInString(text)}
+	var keywords []struct {
+		Candidate string `json:"candidate"`
+	}
+	if err := json.Unmarshal(results["keywords"].Result, &keywords); err != nil {
+		return recordFeatures{}, internalError(err)
+	}
+	for _, keyword := range keywords {
+		features.Keywords = append(features.Keywords, keyword.Candidate)
+	}
+	var entities []struct {
+		Label string `json:"label"`
+	}
+	if err := json.Unmarshal(results["entities"].Result, &entities); err != nil {
+		return recordFeatures{}, internalError(err)
+	}
+	for _, entity := range entities {
+		features.Entities[entity.Label]++
+	}
+	var language struct {
+		Language string `json:"language"`
+	}
+	if err := json.Unmarshal(results["language"].Result, &language); err != nil {
+		return recordFeatures{}, internalError(err)
+	}
+	features.Language = language.Language
+
+	return features, nil
+}
+
+// anonymizeRecord stores the features of a record's text in its "features"
+// field, and replaces the text by its salted hash or drops it.
+func anonymizeRecord(c echo.Context, r *record) error {
+	req := c.Request()
+	mode := anonymizeMode(req.Header, routePath(c))
+	if mode == "off" {
+		return nil
+	}
+
+	results := runAnalyses(req.Context(), r.text, anonymizedAnalyses, req.Header.Get("X-API-Key"))
+	features, err := deriveFeatures(r.text, results)
+	if err != nil {
+		return err
+	}
+	if err := r.set("features", features); err != nil {
+		return err
+	}
+	if mode == "discard" {
+		delete(r.fields, "text")
+		return nil
+	}
+
+	return r.set("text", textHash(r.text))
+}
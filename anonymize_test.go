@@ -0,0 +1,156 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/garystafford/nlp-client/mockupstream"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseAnonymize(t *testing.T) {
+	modes, err := parseAnonymize("acme=discard, /record=hash, *=off")
+	if assert.NoError(t, err) {
+		assert.Equal(t, map[string]string{"acme": "discard", "/record": "hash", "*": "off"}, modes)
+	}
+
+	for _, config := range []string{"acme", "=hash", "acme=drop"} {
+		_, err := parseAnonymize(config)
+		assert.Error(t, err, config)
+	}
+}
+
+func TestLoadAnonymizeRequiresSalt(t *testing.T) {
+	defer func(config, salt string, modes map[string]string) {
+		recordAnonymize, recordHashSalt, anonymize = config, salt, modes
+	}(recordAnonymize, recordHashSalt, anonymize)
+	recordAnonymize, recordHashSalt = "*=hash", ""
+	assert.Error(t, loadAnonymize())
+
+	recordHashSalt = "pepper"
+	assert.NoError(t, loadAnonymize())
+}
+
+func TestAnonymizeMode(t *testing.T) {
+	defer func(modes map[string]string) { anonymize = modes }(anonymize)
+	anonymize = map[string]string{"acme": "discard", "/analyze/email": "hash", "*": "off"}
+
+	header := http.Header{}
+	assert.Equal(t, "off", anonymizeMode(header, "/record"))
+	assert.Equal(t, "hash", anonymizeMode(header, "/analyze/email"))
+	header.Set(tenantHeader, "acme")
+	assert.Equal(t, "discard", anonymizeMode(header, "/analyze/email"), "the tenant's mode comes first")
+	header.Set(tenantHeader, "globex")
+	assert.Equal(t, "hash", anonymizeMode(header, "/analyze/email"))
+}
+
+func TestPutDynamoAnonymized(t *testing.T) {
+	rake, prose, lang := mockupstream.NewRake(), mockupstream.NewProse(), mockupstream.NewLang()
+	defer rake.Close()
+	defer prose.Close()
+	defer lang.Close()
+	var records []map[string]interface{}
+	_, dynamo := newModerationUpstreams(t, &records)
+	defer dynamo.Close()
+	defer func(r, p, l, d, salt string, modes map[string]string) {
+		urlRake, urlProse, urlLang, urlDynamo, recordHashSalt, anonymize = r, p, l, d, salt, modes
+	}(urlRake, urlProse, urlLang, urlDynamo, recordHashSalt, anonymize)
+	urlRake, urlProse, urlLang, urlDynamo = rake.URL, prose.URL, lang.URL, dynamo.URL
+	recordHashSalt = "pepper"
+	anonymize = map[string]string{"acme": "discard", "*": "hash"}
+
+	record := func(tenant string) {
+		req := httptest.NewRequest(http.MethodPost, "/record", strings.NewReader(`{"text": "Marie Curie won the Nobel Prize.", "id": 7}`))
+		req.Header.Set(tenantHeader, tenant)
+		c := e.NewContext(req, httptest.NewRecorder())
+		c.SetPath("/record")
+		assert.NoError(t, putDynamo(c))
+	}
+
+	record("globex")
+	record("acme")
+	if assert.Len(t, records, 2) {
+		assert.Equal(t, textHash("Marie Curie won the Nobel Prize."), records[0]["text"])
+		assert.True(t, strings.HasPrefix(records[0]["text"].(string), "hmac-sha256:"))
+		assert.Equal(t, map[string]interface{}{
+			"keywords": []interface{}{"nobel prize", "marie curie"},
+			"entities": map[string]interface{}{"PERSON": float64(1)},
+			"language": "en",
+			"length":   float64(32),
+		}, records[0]["features"])
+		assert.Equal(t, float64(7), records[0]["id"])
+
+		assert.NotContains(t, records[1], "text")
+		assert.Contains(t, records[1], "features")
+	}
+}
+
+func TestPutDynamoAnonymizedVersioned(t *testing.T) {
+	rake, prose, lang := mockupstream.NewRake(), mockupstream.NewProse(), mockupstream.NewLang()
+	defer rake.Close()
+	defer prose.Close()
+	defer lang.Close()
+	var records []map[string]interface{}
+	_, dynamo := newModerationUpstreams(t, &records)
+	defer dynamo.Close()
+	defer func(r, p, l, d string, modes map[string]string) {
+		urlRake, urlProse, urlLang, urlDynamo, anonymize = r, p, l, d, modes
+	}(urlRake, urlProse, urlLang, urlDynamo, anonymize)
+	urlRake, urlProse, urlLang, urlDynamo = rake.URL, prose.URL, lang.URL, dynamo.URL
+	anonymize = map[string]string{"/record": "discard", "*": "off"}
+
+	req := httptest.NewRequest(http.MethodPost, "/v1/record", strings.NewReader(`{"text": "Marie Curie won the Nobel Prize."}`))
+	c := e.NewContext(req, httptest.NewRecorder())
+	c.SetPath("/v1/record")
+	assert.NoError(t, putDynamo(c))
+	if assert.Len(t, records, 1) {
+		assert.NotContains(t, records[0], "text", "the /record scope covers its versioned path")
+		assert.Contains(t, records[0], "features")
+	}
+}
+
+func TestPutDynamoAnonymizedFailsWithoutFeatures(t *testing.T) {
+	var records []map[string]interface{}
+	_, dynamo := newModerationUpstreams(t, &records)
+	defer dynamo.Close()
+	down := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadGateway)
+	}))
+	defer down.Close()
+	defer func(r, p, l, d string, modes map[string]string) {
+		urlRake, urlProse, urlLang, urlDynamo, anonymize = r, p, l, d, modes
+	}(urlRake, urlProse, urlLang, urlDynamo, anonymize)
+	urlRake, urlProse, urlLang, urlDynamo = down.URL, down.URL, down.URL, dynamo.URL
+	anonymize = map[string]string{"*": "discard"}
+
+	req := httptest.NewRequest(http.MethodPost, "/record", strings.NewReader(`{"text": "Marie Curie"}`))
+	c := e.NewContext(req, httptest.NewRecorder())
+	c.SetPath("/record")
+	assert.Error(t, putDynamo(c))
+	assert.Empty(t, records, "a record is not stored without its features")
+}
+
+func TestPostAnalyzeEmailAnonymized(t *testing.T) {
+	rake, prose, lang := mockupstream.NewRake(), mockupstream.NewProse(), mockupstream.NewLang()
+	defer rake.Close()
+	defer prose.Close()
+	defer lang.Close()
+	var records []map[string]interface{}
+	_, dynamo := newModerationUpstreams(t, &records)
+	defer dynamo.Close()
+	defer func(r, p, l, d string, modes map[string]string) {
+		urlRake, urlProse, urlLang, urlDynamo, anonymize = r, p, l, d, modes
+	}(urlRake, urlProse, urlLang, urlDynamo, anonymize)
+	urlRake, urlProse, urlLang, urlDynamo = rake.URL, prose.URL, lang.URL, dynamo.URL
+	anonymize = map[string]string{"/analyze/email": "discard"}
+
+	req := httptest.NewRequest(http.MethodPost, "/analyze/email?analyses=language&record=true", strings.NewReader(multipartEmail))
+	c := e.NewContext(req, httptest.NewRecorder())
+	c.SetPath("/analyze/email")
+	if assert.NoError(t, postAnalyzeEmail(c)) && assert.Len(t, records, 1) {
+		assert.NotContains(t, records[0], "text")
+		assert.Contains(t, records[0], "features")
+	}
+}
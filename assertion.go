@@ -0,0 +1,255 @@
+// author: Gary A. Stafford
+// site: https://programmaticponderings.com
+// license: MIT License
+// purpose: NLP microservices: nlp-client negation and speculation detection
+// modified: 2026-10-14
+
+package main
+
+import (
+	"encoding/json"
+	"regexp"
+	"sort"
+	"strings"
+	"unicode/utf8"
+)
+
+var (
+	// most words between a cue and an entity it applies to
+	assertionWindow = getEnv("ASSERTION_WINDOW", "6")
+	// comma-separated cues to add to the built-in ones, e.g. "no sign of"
+	negationCues    = getEnv("NEGATION_CUES", "")
+	speculationCues = getEnv("SPECULATION_CUES", "")
+)
+
+const (
+	assertionNegated     = "negated"
+	assertionSpeculative = "speculative"
+)
+
+// assertionCues are, after NegEx, the phrases that negate or hedge the
+// entities after them (pre) or, less often, before them (post).
+var assertionCues = []assertionCue{
+	{assertionNegated, false, "no"}, {assertionNegated, false, "not"}, {assertionNegated, false, "without"},
+	{assertionNegated, false, "never"}, {assertionNegated, false, "denies"}, {assertionNegated, false, "denied"},
+	{assertionNegated, false, "no evidence of"}, {assertionNegated, false, "no signs of"},
+	{assertionNegated, false, "negative for"}, {assertionNegated, false, "free of"},
+	{assertionNegated, false, "absence of"}, {assertionNegated, false, "ruled out"},
+	{assertionNegated, true, "was ruled out"}, {assertionNegated, true, "is ruled out"},
+	{assertionNegated, true, "was not found"}, {assertionNegated, true, "is absent"}, {assertionNegated, true, "was absent"},
+	{assertionSpeculative, false, "possible"}, {assertionSpeculative, false, "possibly"},
+	{assertionSpeculative, false, "probable"}, {assertionSpeculative, false, "probably"},
+	{assertionSpeculative, false, "may"}, {assertionSpeculative, false, "might"}, {assertionSpeculative, false, "could"},
+	{assertionSpeculative, false, "suspected"}, {assertionSpeculative, false, "suspicious for"},
+	{assertionSpeculative, false, "likely"}, {assertionSpeculative, false, "questionable"},
+	{assertionSpeculative, false, "cannot rule out"}, {assertionSpeculative, false, "rule out"},
+	{assertionSpeculative, false, "presumed"}, {assertionSpeculative, false, "alleged"},
+	{assertionSpeculative, false, "allegedly"}, {assertionSpeculative, false, "reportedly"},
+	{assertionSpeculative, true, "is suspected"}, {assertionSpeculative, true, "was suspected"},
+	{assertionSpeculative, true, "cannot be excluded"}, {assertionSpeculative, true, "is possible"},
+	{assertionSpeculative, true, "is likely"},
+}
+
+// pseudoCues contain a cue without negating or hedging anything.
+var pseudoCues = []string{"no doubt", "no change", "no increase", "not only", "not necessarily", "without fail", "could not rule out"}
+
+// assertionTerminators end the scope of a cue, as does the end of a clause.
+var assertionTerminators = map[string]bool{
+	"but": true, "however": true, "although": true, "though": true, "except": true, "yet": true, "which": true, "whereas": true,
+}
+
+// assertionWords splits text into words, and the punctuation that ends a clause.
+var assertionWords = regexp.MustCompile(`[\p{L}\p{N}'/-]+|[.;:!?\n]`)
+
+type assertionCue struct {
+	status string
+	post   bool
+	phrase string
+}
+
+type assertionWord struct {
+	text       string
+	start, end int
+}
+
+// entityAssertion is the status of an entity a cue negates or hedges.
+type entityAssertion struct {
+	Status string `json:"status"`
+	Cue    string `json:"cue"`
+}
+
+// cueMatch is a cue found in the words of a text, from word start to end.
+type cueMatch struct {
+	assertionCue
+	start, end int
+}
+
+func configuredCues() []assertionCue {
+	cues := append([]assertionCue{}, assertionCues...)
+	for _, cue := range splitList(negationCues) {
+		cues = append(cues, assertionCue{assertionNegated, false, strings.ToLower(cue)})
+	}
+	for _, cue := range splitList(speculationCues) {
+		cues = append(cues, assertionCue{assertionSpeculative, false, strings.ToLower(cue)})
+	}
+	// the longest cue at each word wins, so "cannot rule out" hedges rather than "rule out"
+	sort.SliceStable(cues, func(i, j int) bool {
+		return len(strings.Fields(cues[i].phrase)) > len(strings.Fields(cues[j].phrase))
+	})
+
+	return cues
+}
+
+func wordsOf(text string) []assertionWord {
+	var words []assertionWord
+	for _, loc := range assertionWords.FindAllStringIndex(text, -1) {
+		words = append(words, assertionWord{
+			text:  strings.ToLower(text[loc[0]:loc[1]]),
+			start: utf8.RuneCountInString(text[:loc[0]]),
+			end:   utf8.RuneCountInString(text[:loc[1]]),
+		})
+	}
+
+	return words
+}
+
+// phraseAt reports whether the phrase starts at word i, returning the word after it.
+func phraseAt(words []assertionWord, i int, phrase string) (int, bool) {
+	for _, part := range strings.Fields(phrase) {
+		if i >= len(words) || words[i].text != part {
+			return 0, false
+		}
+		i++
+	}
+
+	return i, true
+}
+
+// findCues finds the cues of text, skipping the pseudo-cues that contain them.
+func findCues(words []assertionWord) []cueMatch {
+	cues := configuredCues()
+	var found []cueMatch
+	for i := 0; i < len(words); {
+		skip := 0
+		for _, pseudo := range pseudoCues {
+			if end, ok := phraseAt(words, i, pseudo); ok {
+				skip = end - i
+				break
+			}
+		}
+		if skip > 0 {
+			i += skip
+			continue
+		}
+		matched := false
+		for _, cue := range cues {
+			if end, ok := phraseAt(words, i, cue.phrase); ok {
+				found = append(found, cueMatch{cue, i, end})
+				i, matched = end, true
+				break
+			}
+		}
+		if !matched {
+			i++
+		}
+	}
+
+	return found
+}
+
+// assertEntity returns what the nearest cue in the same clause, within the
+// window, says of an entity spanning words first to last.
+func assertEntity(words []assertionWord, cues []cueMatch, first, last, window int) *entityAssertion {
+	// whether a clause ends between words from and to
+	terminated := func(from, to int) bool {
+		for i := from; i < to; i++ {
+			if text := words[i].text; assertionTerminators[text] || len(text) == 1 && strings.ContainsAny(text, ".;:!?\n") {
+				return true
+			}
+		}
+		return false
+	}
+	var nearest *cueMatch
+	distance := 0
+	for i := range cues {
+		cue := &cues[i]
+		var d int
+		switch {
+		case !cue.post && cue.end <= first:
+			d = first - cue.end
+			if terminated(cue.end, first) {
+				continue
+			}
+		case cue.post && cue.start > last:
+			d = cue.start - last - 1
+			if terminated(last+1, cue.start) {
+				continue
+			}
+		default:
+			continue
+		}
+		if d <= window && (nearest == nil || d < distance || d == distance && cue.status == assertionNegated) {
+			nearest, distance = cue, d
+		}
+	}
+	if nearest == nil {
+		return nil
+	}
+
+	return &entityAssertion{nearest.status, nearest.phrase}
+}
+
+// assertEntities tags the entities of a result, v1 or v2, that a cue near them
+// negates or hedges with their "assertion"; entities stated as fact are left
+// as they were.
+func assertEntities(route, text string, body json.RawMessage) (json.RawMessage, error) {
+	if route != "/entities" {
+		return body, nil
+	}
+	var items []json.RawMessage
+	if err := json.Unmarshal(body, &items); err != nil {
+		return nil, err
+	}
+	words := wordsOf(text)
+	cues := findCues(words)
+	if len(cues) == 0 {
+		return body, nil
+	}
+	window := envInt(assertionWindow, 6)
+
+	var err error
+	loc := &locator{text: foldRunes(text)}
+	for i, raw := range items {
+		var entity v2Item
+		if err := json.Unmarshal(raw, &entity); err != nil {
+			return nil, err
+		}
+		s := entity.span
+		if s.Offset == nil {
+			s = loc.locate(entity.Text)
+		}
+		if s.Offset == nil {
+			continue
+		}
+		start, end := *s.Offset, *s.Offset+s.Length
+		first, last := -1, -1
+		for w, word := range words {
+			if word.end > start && word.start < end {
+				if first < 0 {
+					first = w
+				}
+				last = w
+			}
+		}
+		if first < 0 {
+			continue
+		}
+		if assertion := assertEntity(words, cues, first, last, window); assertion != nil {
+			if items[i], err = setItemFields(raw, itemField{"assertion", assertion}); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	return json.Marshal(items)
+}
@@ -0,0 +1,71 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/garystafford/nlp-client/mockupstream"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFindCues(t *testing.T) {
+	cues := findCues(wordsOf("We cannot rule out sepsis, but there is no doubt of a fever"))
+	if assert.Len(t, cues, 1, "no doubt is a pseudo-cue") {
+		assert.Equal(t, "cannot rule out", cues[0].phrase, "the longest cue wins")
+		assert.Equal(t, assertionSpeculative, cues[0].status)
+	}
+
+	defer func(cues string) { negationCues = cues }(negationCues)
+	negationCues = "Nothing Like"
+	cues = findCues(wordsOf("nothing like a merger"))
+	if assert.Len(t, cues, 1) {
+		assert.Equal(t, assertionCue{assertionNegated, false, "nothing like"}, cues[0].assertionCue)
+	}
+}
+
+func TestAssertEntities(t *testing.T) {
+	text := "Patient denies chest pain but reports nausea. Pneumonia was ruled out; possible Lyme disease."
+	body := `[{"text":"chest pain","label":"SYMPTOM"},{"text":"nausea","label":"SYMPTOM"},` +
+		`{"text":"Pneumonia","label":"DISEASE"},{"text":"Lyme disease","label":"DISEASE"}]`
+	asserted, err := assertEntities("/entities", text, []byte(body))
+	if assert.NoError(t, err) {
+		assert.JSONEq(t, `[{"text":"chest pain","label":"SYMPTOM","assertion":{"status":"negated","cue":"denies"}},`+
+			`{"text":"nausea","label":"SYMPTOM"},`+
+			`{"text":"Pneumonia","label":"DISEASE","assertion":{"status":"negated","cue":"was ruled out"}},`+
+			`{"text":"Lyme disease","label":"DISEASE","assertion":{"status":"speculative","cue":"possible"}}]`, string(asserted),
+			"a terminator ends the scope of a cue")
+	}
+
+	// v2 entities are placed by their offsets, not their text
+	text = "Paris is lovely. No trip to Paris this year."
+	asserted, err = assertEntities("/entities", text, []byte(`[{"text":"Paris","label":"GPE","offset":28,"length":5}]`))
+	if assert.NoError(t, err) {
+		assert.JSONEq(t, `[{"text":"Paris","label":"GPE","offset":28,"length":5,"assertion":{"status":"negated","cue":"no"}}]`, string(asserted))
+	}
+
+	defer func(window string) { assertionWindow = window }(assertionWindow)
+	assertionWindow = "2"
+	body = `[{"text":"Berlin","label":"GPE"}]`
+	asserted, err = assertEntities("/entities", "We never went with our friends to Berlin", []byte(body))
+	if assert.NoError(t, err) {
+		assert.JSONEq(t, body, string(asserted), "a cue beyond the window does not apply")
+	}
+}
+
+func TestGetEntitiesAssertion(t *testing.T) {
+	prose := mockupstream.NewProse()
+	defer prose.Close()
+	defer func(url string) { urlProse = url }(urlProse)
+	urlProse = prose.URL
+	prose.SetFixture("/entities", mockupstream.Fixture{Status: http.StatusOK, Body: `[{"text":"Acme","label":"ORG"},{"text":"Globex","label":"ORG"}]`})
+
+	req := httptest.NewRequest(http.MethodPost, "/entities?enrich=assertion", strings.NewReader(`{"text": "Acme may acquire Globex"}`))
+	w := httptest.NewRecorder()
+	c := e.NewContext(req, w)
+	c.SetPath("/entities")
+	if assert.NoError(t, getEntities(c)) {
+		assert.JSONEq(t, `[{"text":"Acme","label":"ORG"},{"text":"Globex","label":"ORG","assertion":{"status":"speculative","cue":"may"}}]`, w.Body.String())
+	}
+}
@@ -0,0 +1,372 @@
+// author: Gary A. Stafford
+// site: https://programmaticponderings.com
+// license: MIT License
+// purpose: NLP microservices: nlp-client speech-to-text ingestion
+// modified: 2026-10-14
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"mime"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/transcribe"
+	"github.com/aws/aws-sdk-go-v2/service/transcribe/types"
+	"github.com/labstack/echo/v4"
+)
+
+var (
+	// "http" for the transcription service at TRANSCRIBE_ENDPOINT, "transcribe" for AWS Transcribe; unset disables /analyze/audio
+	transcribeBackend = getEnv("TRANSCRIBE_BACKEND", "")
+	// transcription service answering POST /transcribe, given the audio as the body, with {"text": "..."}
+	urlTranscribe = upstreamURL(getEnv("TRANSCRIBE_ENDPOINT", ""))
+	// bucket uploads are staged in for AWS Transcribe, which only reads media from S3
+	transcribeBucket       = getEnv("TRANSCRIBE_BUCKET", "")
+	transcribeLanguage     = getEnv("TRANSCRIBE_LANGUAGE", "") // e.g. "en-US"; unset identifies the language
+	transcribePollInterval = getEnv("TRANSCRIBE_POLL_INTERVAL", "2s")
+	// maximum bytes of audio downloaded from a pre-signed URL
+	transcribeMaxAudioBytes = getEnv("TRANSCRIBE_MAX_AUDIO_BYTES", "104857600")
+	// how long downloading the audio of a pre-signed URL, or a finished job's transcript, may take
+	transcribeFetchTimeout = getEnv("TRANSCRIBE_FETCH_TIMEOUT", "5m")
+
+	transcribeClient = &http.Client{Timeout: envDuration(transcribeFetchTimeout, 5*time.Minute)}
+
+	transcriberOnce sync.Once
+	transcriber     transcribeFunc
+	transcriberErr  error
+)
+
+// audioFormats maps the accepted audio types to their AWS Transcribe media formats.
+var audioFormats = map[string]string{
+	"audio/mpeg":      "mp3",
+	"audio/mp4":       "mp4",
+	"video/mp4":       "mp4",
+	"audio/wav":       "wav",
+	"audio/wave":      "wav",
+	"audio/x-wav":     "wav",
+	"audio/flac":      "flac",
+	"audio/ogg":       "ogg",
+	"application/ogg": "ogg",
+	"audio/webm":      "webm",
+	"video/webm":      "webm",
+	"audio/amr":       "amr",
+}
+
+// audioSource is either uploaded audio or the S3 object a pre-signed URL points to.
+type audioSource struct {
+	audio       []byte
+	contentType string
+	url         *url.URL
+	s3URI       string
+}
+
+// transcribeFunc returns the transcript of an audio source.
+type transcribeFunc func(ctx context.Context, src audioSource) (string, error)
+
+func loadTranscriber() (transcribeFunc, error) {
+	transcriberOnce.Do(func() {
+		transcriber, transcriberErr = newTranscriber(transcribeBackend)
+	})
+
+	return transcriber, transcriberErr
+}
+
+func newTranscriber(backend string) (transcribeFunc, error) {
+	switch backend {
+	case "http":
+		if urlTranscribe == "" {
+			return nil, errors.New("TRANSCRIBE_ENDPOINT is required by the http backend")
+		}
+		return httpTranscriber(urlTranscribe), nil
+	case "transcribe":
+		cfg, err := config.LoadDefaultConfig(context.Background())
+		if err != nil {
+			return nil, err
+		}
+		client := s3.NewFromConfig(cfg)
+		upload := func(ctx context.Context, key string, audio []byte) (string, error) {
+			if transcribeBucket == "" {
+				return "", errors.New("TRANSCRIBE_BUCKET is required to transcribe uploads")
+			}
+			_, err := client.PutObject(ctx, &s3.PutObjectInput{
+				Bucket: aws.String(transcribeBucket),
+				Key:    aws.String(key),
+				Body:   bytes.NewReader(audio),
+			})
+			return "s3://" + transcribeBucket + "/" + key, err
+		}
+		return awsTranscriber(transcribe.NewFromConfig(cfg), upload, envDuration(transcribePollInterval, 2*time.Second)), nil
+	}
+
+	return nil, fmt.Errorf("unsupported TRANSCRIBE_BACKEND: %q", backend)
+}
+
+func httpTranscriber(endpoint string) transcribeFunc {
+	return func(ctx context.Context, src audioSource) (string, error) {
+		if src.url != nil {
+			var err error
+			if src.audio, src.contentType, err = fetchAudio(ctx, src.url); err != nil {
+				return "", err
+			}
+		}
+		req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint+"/transcribe", bytes.NewReader(src.audio))
+		if err != nil {
+			return "", err
+		}
+		req.Header.Set(echo.HeaderContentType, src.contentType)
+		req.Header.Set("X-API-Key", apiKeys.Primary())
+		setDeadlineHeader(req)
+		setBaggageHeader(req)
+		setForwardedHeaders(req)
+
+		resp, err := upstreamClient.Do(req)
+		if err != nil {
+			return "", err
+		}
+		defer func(Body io.ReadCloser) {
+			err := Body.Close()
+			if err != nil {
+				e.Logger.Error(err)
+			}
+		}(resp.Body)
+		if resp.StatusCode < 200 || resp.StatusCode > 299 {
+			return "", newUpstreamStatusError(resp)
+		}
+
+		var result struct {
+			Text string `json:"text"`
+		}
+		if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+			return "", err
+		}
+		return result.Text, nil
+	}
+}
+
+// fetchAudio downloads the audio a pre-signed S3 URL points to.
+func fetchAudio(ctx context.Context, u *url.URL) ([]byte, string, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+	if err != nil {
+		return nil, "", err
+	}
+	resp, err := transcribeClient.Do(req)
+	if err != nil {
+		return nil, "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, "", newUpstreamStatusError(resp)
+	}
+	limit := int64(envInt(transcribeMaxAudioBytes, 100<<20))
+	audio, err := ioutil.ReadAll(io.LimitReader(resp.Body, limit+1))
+	if err != nil {
+		return nil, "", err
+	}
+	if int64(len(audio)) > limit {
+		return nil, "", errors.New("audio is too large")
+	}
+	contentType, _, _ := mime.ParseMediaType(resp.Header.Get(echo.HeaderContentType))
+	if _, ok := audioFormats[contentType]; !ok {
+		contentType, _, _ = mime.ParseMediaType(http.DetectContentType(audio))
+	}
+	if _, ok := audioFormats[contentType]; !ok {
+		return nil, "", fmt.Errorf("unsupported audio type: %s", contentType)
+	}
+
+	return audio, contentType, nil
+}
+
+// transcribeAPI is the part of the AWS Transcribe client the backend uses.
+type transcribeAPI interface {
+	StartTranscriptionJob(ctx context.Context, params *transcribe.StartTranscriptionJobInput, optFns ...func(*transcribe.Options)) (*transcribe.StartTranscriptionJobOutput, error)
+	GetTranscriptionJob(ctx context.Context, params *transcribe.GetTranscriptionJobInput, optFns ...func(*transcribe.Options)) (*transcribe.GetTranscriptionJobOutput, error)
+}
+
+// awsTranscriber runs an AWS Transcribe job on the audio, staging uploads in
+// S3 first, and polls it until it completes or the request's deadline passes.
+func awsTranscriber(client transcribeAPI, upload func(ctx context.Context, key string, audio []byte) (string, error), interval time.Duration) transcribeFunc {
+	return func(ctx context.Context, src audioSource) (string, error) {
+		id := make([]byte, 16)
+		if _, err := rand.Read(id); err != nil {
+			return "", err
+		}
+		job := "nlp-client-" + hex.EncodeToString(id)
+
+		uri, format := src.s3URI, ""
+		if uri == "" {
+			var err error
+			if uri, err = upload(ctx, "transcribe/"+job, src.audio); err != nil {
+				return "", err
+			}
+			format = audioFormats[src.contentType]
+		}
+		input := &transcribe.StartTranscriptionJobInput{
+			TranscriptionJobName: aws.String(job),
+			Media:                &types.Media{MediaFileUri: aws.String(uri)},
+			MediaFormat:          types.MediaFormat(format),
+		}
+		if transcribeLanguage != "" {
+			input.LanguageCode = types.LanguageCode(transcribeLanguage)
+		} else {
+			input.IdentifyLanguage = aws.Bool(true)
+		}
+		if _, err := client.StartTranscriptionJob(ctx, input); err != nil {
+			return "", err
+		}
+
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			out, err := client.GetTranscriptionJob(ctx, &transcribe.GetTranscriptionJobInput{TranscriptionJobName: aws.String(job)})
+			if err != nil {
+				return "", err
+			}
+			switch j := out.TranscriptionJob; j.TranscriptionJobStatus {
+			case types.TranscriptionJobStatusCompleted:
+				return fetchTranscript(ctx, aws.ToString(j.Transcript.TranscriptFileUri))
+			case types.TranscriptionJobStatusFailed:
+				return "", fmt.Errorf("transcription job %s failed: %s", job, aws.ToString(j.FailureReason))
+			}
+			select {
+			case <-ctx.Done():
+				return "", ctx.Err()
+			case <-ticker.C:
+			}
+		}
+	}
+}
+
+// fetchTranscript returns the text of a completed AWS Transcribe job's transcript file.
+func fetchTranscript(ctx context.Context, uri string) (string, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, uri, nil)
+	if err != nil {
+		return "", err
+	}
+	resp, err := transcribeClient.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", newUpstreamStatusError(resp)
+	}
+
+	var transcript struct {
+		Results struct {
+			Transcripts []struct {
+				Transcript string `json:"transcript"`
+			} `json:"transcripts"`
+		} `json:"results"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&transcript); err != nil {
+		return "", err
+	}
+	var texts []string
+	for _, t := range transcript.Results.Transcripts {
+		texts = append(texts, t.Transcript)
+	}
+
+	return strings.Join(texts, "\n"), nil
+}
+
+// s3URIFromURL returns the s3:// URI of a pre-signed S3 URL, in either the
+// virtual-hosted or the path style, or an error for any other URL.
+func s3URIFromURL(u *url.URL) (string, error) {
+	host := u.Hostname()
+	if u.Scheme != "https" || !strings.HasSuffix(host, ".amazonaws.com") {
+		return "", errors.New("url must be a pre-signed https S3 URL")
+	}
+	path := strings.TrimPrefix(u.Path, "/")
+	labels := strings.Split(strings.TrimSuffix(host, ".amazonaws.com"), ".")
+	for i, label := range labels {
+		if label != "s3" && !strings.HasPrefix(label, "s3-") {
+			continue
+		}
+		if i > 0 {
+			// virtual-hosted style: bucket.s3.region.amazonaws.com/key
+			return "s3://" + strings.Join(labels[:i], ".") + "/" + path, nil
+		}
+		// path style: s3.region.amazonaws.com/bucket/key
+		if parts := strings.SplitN(path, "/", 2); len(parts) == 2 && parts[0] != "" && parts[1] != "" {
+			return "s3://" + parts[0] + "/" + parts[1], nil
+		}
+		break
+	}
+
+	return "", errors.New("url must be a pre-signed https S3 URL")
+}
+
+// readAudio returns the audio of an /analyze/audio request: a JSON body with
+// the pre-signed S3 URL of the audio, or the audio itself, uploaded as for
+// /analyze/image.
+func readAudio(c echo.Context) (audioSource, error) {
+	contentType, _, _ := mime.ParseMediaType(c.Request().Header.Get(echo.HeaderContentType))
+	if contentType != echo.MIMEApplicationJSON {
+		audio, contentType, err := readUpload(c, "audio", audioFormats)
+		return audioSource{audio: audio, contentType: contentType}, err
+	}
+
+	var body struct {
+		URL string `json:"url"`
+	}
+	if err := json.NewDecoder(c.Request().Body).Decode(&body); err != nil {
+		return audioSource{}, err
+	}
+	u, err := url.Parse(body.URL)
+	if err != nil {
+		return audioSource{}, err
+	}
+	// only S3 URLs are fetched, so the client cannot be made to request arbitrary hosts
+	uri, err := s3URIFromURL(u)
+	if err != nil {
+		return audioSource{}, err
+	}
+
+	return audioSource{url: u, s3URI: uri}, nil
+}
+
+// postAnalyzeAudio transcribes uploaded audio, or the audio at a pre-signed
+// S3 URL, then runs the requested analyses on the transcript as /analyze would.
+func postAnalyzeAudio(c echo.Context) error {
+	analyses, err := parseAnalyses(c.QueryParam("analyses"))
+	if err != nil {
+		return validationError(err.Error())
+	}
+	if transcribeBackend == "" {
+		return newAPIError(http.StatusNotFound, codeNotFound, "transcription is not configured")
+	}
+	transcribeAudio, err := loadTranscriber()
+	if err != nil {
+		return internalError(err)
+	}
+
+	src, err := readAudio(c)
+	if err != nil {
+		return validationError(err.Error())
+	}
+	transcript, err := transcribeAudio(c.Request().Context(), src)
+	if err != nil {
+		return upstreamError(err)
+	}
+	if strings.TrimSpace(transcript) == "" {
+		return newAPIError(http.StatusUnprocessableEntity, codeValidationFailed, "no speech found in audio")
+	}
+
+	return respondAnalyses(c, transcript, analyses, transcript)
+}
@@ -0,0 +1,146 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/transcribe"
+	"github.com/aws/aws-sdk-go-v2/service/transcribe/types"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+)
+
+// a RIFF WAVE header is enough for content sniffing
+var wavAudio = []byte("RIFF\x24\x00\x00\x00WAVEfmt ")
+
+func TestS3URIFromURL(t *testing.T) {
+	tests := []struct {
+		url      string
+		expected string
+	}{
+		{"https://audio.s3.us-east-1.amazonaws.com/calls/1.wav?X-Amz-Signature=abc", "s3://audio/calls/1.wav"},
+		{"https://my.audio.s3.amazonaws.com/1.wav", "s3://my.audio/1.wav"},
+		{"https://s3.eu-west-1.amazonaws.com/audio/calls/1.wav", "s3://audio/calls/1.wav"},
+		{"https://audio.s3-eu-west-1.amazonaws.com/1.wav", "s3://audio/1.wav"},
+		{"http://audio.s3.amazonaws.com/1.wav", ""},
+		{"https://169.254.169.254/latest/meta-data", ""},
+		{"https://example.com/audio.s3.amazonaws.com/1.wav", ""},
+		{"https://s3.amazonaws.com/audio", ""},
+	}
+	for _, tt := range tests {
+		u, _ := url.Parse(tt.url)
+		uri, err := s3URIFromURL(u)
+		if tt.expected == "" {
+			assert.Error(t, err, tt.url)
+			continue
+		}
+		if assert.NoError(t, err, tt.url) {
+			assert.Equal(t, tt.expected, uri)
+		}
+	}
+}
+
+type fakeTranscribe struct {
+	started *transcribe.StartTranscriptionJobInput
+	polls   int
+	fileURI string
+}
+
+func (f *fakeTranscribe) StartTranscriptionJob(_ context.Context, params *transcribe.StartTranscriptionJobInput, _ ...func(*transcribe.Options)) (*transcribe.StartTranscriptionJobOutput, error) {
+	f.started = params
+	return &transcribe.StartTranscriptionJobOutput{}, nil
+}
+
+func (f *fakeTranscribe) GetTranscriptionJob(_ context.Context, params *transcribe.GetTranscriptionJobInput, _ ...func(*transcribe.Options)) (*transcribe.GetTranscriptionJobOutput, error) {
+	f.polls++
+	job := &types.TranscriptionJob{TranscriptionJobStatus: types.TranscriptionJobStatusInProgress}
+	if f.polls > 2 {
+		job.TranscriptionJobStatus = types.TranscriptionJobStatusCompleted
+		job.Transcript = &types.Transcript{TranscriptFileUri: aws.String(f.fileURI)}
+	}
+	return &transcribe.GetTranscriptionJobOutput{TranscriptionJob: job}, nil
+}
+
+func TestAWSTranscriber(t *testing.T) {
+	files := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`{"results": {"transcripts": [{"transcript": "Marie Curie won the Nobel Prize."}]}}`))
+	}))
+	defer files.Close()
+	client := &fakeTranscribe{fileURI: files.URL}
+	var uploaded []byte
+	upload := func(_ context.Context, key string, audio []byte) (string, error) {
+		uploaded = audio
+		return "s3://staging/" + key, nil
+	}
+
+	text, err := awsTranscriber(client, upload, time.Millisecond)(context.Background(), audioSource{audio: wavAudio, contentType: "audio/wave"})
+	if assert.NoError(t, err) {
+		assert.Equal(t, "Marie Curie won the Nobel Prize.", text)
+		assert.Equal(t, wavAudio, uploaded)
+		assert.Equal(t, "s3://staging/transcribe/"+*client.started.TranscriptionJobName, *client.started.Media.MediaFileUri)
+		assert.Equal(t, types.MediaFormatWav, client.started.MediaFormat)
+		assert.Equal(t, 3, client.polls)
+	}
+
+	// S3 objects are passed on without staging
+	uploaded = nil
+	client.polls = 0
+	_, err = awsTranscriber(client, upload, time.Millisecond)(context.Background(), audioSource{s3URI: "s3://audio/1.wav"})
+	if assert.NoError(t, err) {
+		assert.Nil(t, uploaded)
+		assert.Equal(t, "s3://audio/1.wav", *client.started.Media.MediaFileUri)
+	}
+}
+
+func TestPostAnalyzeAudio(t *testing.T) {
+	transcriptions := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		assert.Equal(t, "/transcribe", r.URL.Path)
+		assert.Equal(t, "audio/wave", r.Header.Get(echo.HeaderContentType))
+		assert.Equal(t, wavAudio, body)
+		_, _ = w.Write([]byte(`{"text": "curie"}`))
+	}))
+	defer transcriptions.Close()
+	upstream := newBatchUpstream(t)
+	defer upstream.Close()
+	defer func(backend, transcribeURL, rake string) {
+		transcribeBackend, urlTranscribe, urlRake = backend, transcribeURL, rake
+		transcriberOnce = sync.Once{}
+	}(transcribeBackend, urlTranscribe, urlRake)
+	transcribeBackend = "http"
+	urlTranscribe = transcriptions.URL
+	urlRake = upstream.URL
+	transcriberOnce = sync.Once{}
+
+	req := httptest.NewRequest(http.MethodPost, "/analyze/audio?analyses=keywords", bytes.NewReader(wavAudio))
+	w := httptest.NewRecorder()
+	if assert.NoError(t, postAnalyzeAudio(e.NewContext(req, w))) {
+		var body struct {
+			Text     string `json:"text"`
+			Analyses map[string]struct {
+				Result []string `json:"result"`
+			} `json:"analyses"`
+		}
+		if assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &body)) {
+			assert.Equal(t, "curie", body.Text)
+			assert.Equal(t, []string{"CURIE"}, body.Analyses["keywords"].Result)
+		}
+	}
+
+	req = httptest.NewRequest(http.MethodPost, "/analyze/audio?analyses=keywords", strings.NewReader(`{"url": "http://10.0.0.1/audio.wav"}`))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	err := postAnalyzeAudio(e.NewContext(req, httptest.NewRecorder()))
+	if assert.Error(t, err) {
+		assert.Equal(t, http.StatusBadRequest, err.(*apiError).Status)
+	}
+}
@@ -0,0 +1,66 @@
+// author: Gary A. Stafford
+// site: https://programmaticponderings.com
+// license: MIT License
+// purpose: NLP microservices: nlp-client autoscaling hints
+// modified: 2026-10-14
+
+package main
+
+import (
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+)
+
+// upstreamLoad is the load on one upstream. Saturation is the calls in
+// flight and queued over the concurrency cap, so above 1 calls are waiting;
+// it is 0 for upstreams without a cap.
+type upstreamLoad struct {
+	InFlight   int     `json:"in_flight"`
+	Queued     int     `json:"queued"`
+	Limit      int     `json:"limit"`
+	Backlog    int     `json:"backlog"`
+	Saturation float64 `json:"saturation"`
+}
+
+// autoscalingHints is shaped for KEDA's metrics-api scaler, e.g. a
+// valueLocation of "queued" or "upstreams.rake.saturation".
+type autoscalingHints struct {
+	InFlight         int                     `json:"in_flight"`
+	Queued           int                     `json:"queued"`
+	Saturation       float64                 `json:"saturation"`
+	BatchConcurrency int                     `json:"batch_concurrency"`
+	Upstreams        map[string]upstreamLoad `json:"upstreams"`
+}
+
+func (q *upstreamQueue) load() upstreamLoad {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	load := upstreamLoad{InFlight: q.inFlight, Queued: q.waiting.Len(), Limit: q.limit, Backlog: q.backlog}
+	if q.limit > 0 {
+		load.Saturation = float64(load.InFlight+load.Queued) / float64(q.limit)
+	}
+
+	return load
+}
+
+// hints reports the load on every named upstream, including those not yet called.
+func (s *queueSet) hints(names map[string]string) autoscalingHints {
+	hints := autoscalingHints{BatchConcurrency: envInt(batchConcurrency, 1), Upstreams: map[string]upstreamLoad{}}
+	for name := range names {
+		load := s.queue(name).load()
+		hints.Upstreams[name] = load
+		hints.InFlight += load.InFlight
+		hints.Queued += load.Queued
+		if load.Saturation > hints.Saturation {
+			hints.Saturation = load.Saturation
+		}
+	}
+
+	return hints
+}
+
+func getAutoscaling(c echo.Context) error {
+	return c.JSON(http.StatusOK, upstreamQueues.hints(namedUpstreams()))
+}
@@ -0,0 +1,43 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestQueueSetHints(t *testing.T) {
+	queues := newQueueSet(2, 10)
+	rake := queues.queue("rake")
+	for i := 0; i < 2; i++ {
+		assert.NoError(t, rake.acquire(context.Background()))
+	}
+	rake.waiting.PushBack(make(chan struct{}))
+
+	hints := queues.hints(map[string]string{"rake": "", "prose": ""})
+	assert.Equal(t, 2, hints.InFlight)
+	assert.Equal(t, 1, hints.Queued)
+	assert.Equal(t, 1.5, hints.Saturation)
+	assert.Equal(t, upstreamLoad{InFlight: 2, Queued: 1, Limit: 2, Backlog: 10, Saturation: 1.5}, hints.Upstreams["rake"])
+	assert.Equal(t, upstreamLoad{Limit: 2, Backlog: 10}, hints.Upstreams["prose"])
+}
+
+func TestGetAutoscaling(t *testing.T) {
+	req := httptest.NewRequest(http.MethodGet, "/autoscaling", nil)
+	w := httptest.NewRecorder()
+	c := e.NewContext(req, w)
+
+	if assert.NoError(t, getAutoscaling(c)) {
+		assert.Equal(t, http.StatusOK, w.Code)
+		var hints autoscalingHints
+		if assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &hints)) {
+			for _, name := range []string{"rake", "prose", "lang", "dynamo"} {
+				assert.Contains(t, hints.Upstreams, name)
+			}
+		}
+	}
+}
@@ -0,0 +1,82 @@
+// author: Gary A. Stafford
+// site: https://programmaticponderings.com
+// license: MIT License
+// purpose: NLP microservices: nlp-client job correlation via OpenTelemetry baggage
+// modified: 2026-10-14
+
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+	"go.opentelemetry.io/otel/baggage"
+	"go.opentelemetry.io/otel/propagation"
+)
+
+const (
+	baggageJobID = "job.id"
+	headerJobID  = "X-Job-ID"
+)
+
+var baggagePropagator = propagation.Baggage{}
+
+// extractBaggage carries the W3C baggage of the inbound request on its
+// context, so every upstream call made for the request passes it on.
+func extractBaggage() echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			req := c.Request()
+			ctx := baggagePropagator.Extract(req.Context(), propagation.HeaderCarrier(req.Header))
+			c.SetRequest(req.WithContext(ctx))
+
+			return next(c)
+		}
+	}
+}
+
+// withJobID returns ctx with a job ID in its baggage, keeping the caller's
+// job ID if it already sent one, so the fanout of a multi-document job can be
+// correlated across services.
+func withJobID(ctx context.Context) (context.Context, string, error) {
+	if id := jobID(ctx); id != "" {
+		return ctx, id, nil
+	}
+
+	id, err := newJobID()
+	if err != nil {
+		return ctx, "", err
+	}
+	member, err := baggage.NewMember(baggageJobID, id)
+	if err != nil {
+		return ctx, "", err
+	}
+	bag, err := baggage.FromContext(ctx).SetMember(member)
+	if err != nil {
+		return ctx, "", err
+	}
+
+	return baggage.ContextWithBaggage(ctx, bag), id, nil
+}
+
+// jobID returns the job ID in the baggage of ctx, if any.
+func jobID(ctx context.Context) string {
+	return baggage.FromContext(ctx).Member(baggageJobID).Value()
+}
+
+func newJobID() (string, error) {
+	id := make([]byte, 16)
+	if _, err := rand.Read(id); err != nil {
+		return "", err
+	}
+
+	return hex.EncodeToString(id), nil
+}
+
+// setBaggageHeader passes the baggage of an upstream request's context on to the upstream.
+func setBaggageHeader(req *http.Request) {
+	baggagePropagator.Inject(req.Context(), propagation.HeaderCarrier(req.Header))
+}
@@ -0,0 +1,65 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/otel/baggage"
+)
+
+func TestWithJobID(t *testing.T) {
+	ctx, id, err := withJobID(context.Background())
+	if assert.NoError(t, err) {
+		assert.Len(t, id, 32)
+		assert.Equal(t, id, baggage.FromContext(ctx).Member(baggageJobID).Value())
+	}
+
+	bag, _ := baggage.Parse("job.id=nightly,tenant=acme")
+	_, id, err = withJobID(baggage.ContextWithBaggage(context.Background(), bag))
+	if assert.NoError(t, err) {
+		assert.Equal(t, "nightly", id)
+	}
+}
+
+func TestExtractBaggage(t *testing.T) {
+	req := httptest.NewRequest(http.MethodPost, "/keywords", nil)
+	req.Header.Set("baggage", "tenant=acme")
+
+	var upstream *http.Request
+	handler := extractBaggage()(func(c echo.Context) error {
+		upstream, _ = http.NewRequestWithContext(c.Request().Context(), http.MethodPost, "http://upstream/keywords", nil)
+		setBaggageHeader(upstream)
+		return nil
+	})
+	if assert.NoError(t, handler(e.NewContext(req, httptest.NewRecorder()))) {
+		assert.Equal(t, "tenant=acme", upstream.Header.Get("baggage"))
+	}
+}
+
+func TestPostBatchJobID(t *testing.T) {
+	var jobIDs []string
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		bag, _ := baggage.Parse(r.Header.Get("baggage"))
+		jobIDs = append(jobIDs, bag.Member(baggageJobID).Value())
+		_, _ = w.Write([]byte(`[]`))
+	}))
+	defer upstream.Close()
+	defer func(url, concurrency string) { urlRake, batchConcurrency = url, concurrency }(urlRake, batchConcurrency)
+	urlRake = upstream.URL
+	batchConcurrency = "1"
+
+	body := `{"documents": [{"id": "a", "text": "one"}, {"id": "b", "text": "two"}]}`
+	req := httptest.NewRequest(http.MethodPost, "/batch?analyses=keywords", strings.NewReader(body))
+	w := httptest.NewRecorder()
+
+	if assert.NoError(t, postBatch(e.NewContext(req, w))) {
+		jobID := w.Header().Get(headerJobID)
+		assert.NotEmpty(t, jobID)
+		assert.Equal(t, []string{jobID, jobID}, jobIDs)
+	}
+}
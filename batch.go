@@ -0,0 +1,198 @@
+// author: Gary A. Stafford
+// site: https://programmaticponderings.com
+// license: MIT License
+// purpose: NLP microservices: nlp-client batch analysis
+// modified: 2026-10-14
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/labstack/echo/v4"
+)
+
+const mimeApplicationNDJSON = "application/x-ndjson"
+
+var batchConcurrency = getEnv("BATCH_CONCURRENCY", "4")
+
+type batchDocument struct {
+	ID   string `json:"id"`
+	Text string `json:"text"`
+}
+
+type batchResult struct {
+	ID       string                    `json:"id"`
+	Status   string                    `json:"status,omitempty"`
+	Analyses map[string]analysisResult `json:"analyses,omitempty"`
+	Error    *apiError                 `json:"error,omitempty"`
+	Meta     *responseMeta             `json:"_meta,omitempty"`
+	index    int
+}
+
+type batchJob struct {
+	index int
+	doc   batchDocument
+}
+
+func analyzeDocument(ctx context.Context, doc batchDocument, analyses []string, key string, meta bool) batchResult {
+	results := runAnalyses(ctx, doc.Text, analyses, key)
+	result := batchResult{ID: doc.ID, Status: analysesStatus(results), Analyses: results}
+	if meta {
+		result.Meta = analysesMeta(doc.Text, analyses, results)
+	}
+
+	return result
+}
+
+// decodeDocuments streams the documents array of a batch request body, so
+// only the documents currently in flight are held in memory.
+func decodeDocuments(r io.Reader, jobs chan<- batchJob) error {
+	defer close(jobs)
+
+	dec := json.NewDecoder(r)
+	if err := expectDelim(dec, '{'); err != nil {
+		return err
+	}
+	for dec.More() {
+		tok, err := dec.Token()
+		if err != nil {
+			return err
+		}
+		if tok != "documents" {
+			var skip json.RawMessage
+			if err := dec.Decode(&skip); err != nil {
+				return err
+			}
+			continue
+		}
+		if err := expectDelim(dec, '['); err != nil {
+			return err
+		}
+		for index := 0; dec.More(); index++ {
+			var doc batchDocument
+			if err := dec.Decode(&doc); err != nil {
+				return err
+			}
+			jobs <- batchJob{index, doc}
+		}
+		if err := expectDelim(dec, ']'); err != nil {
+			return err
+		}
+	}
+
+	return expectDelim(dec, '}')
+}
+
+func expectDelim(dec *json.Decoder, delim json.Delim) error {
+	tok, err := dec.Token()
+	if err != nil {
+		return err
+	}
+	if tok != delim {
+		return fmt.Errorf("expected %q in batch request", delim)
+	}
+
+	return nil
+}
+
+func postBatch(c echo.Context) error {
+	analyses, err := parseAnalyses(c.QueryParam("analyses"))
+	if err != nil {
+		return validationError(err.Error())
+	}
+	workers, err := strconv.Atoi(batchConcurrency)
+	if err != nil || workers < 1 {
+		workers = 1
+	}
+
+	ctx, jobID, err := withJobID(c.Request().Context())
+	if err != nil {
+		return internalError(err)
+	}
+	c.Response().Header().Set(headerJobID, jobID)
+	key := c.Request().Header.Get("X-API-Key")
+	meta := wantsMeta(c)
+	jobs := make(chan batchJob, workers)
+	results := make(chan batchResult, workers)
+
+	decodeErr := make(chan error, 1)
+	go func() {
+		decodeErr <- decodeDocuments(c.Request().Body, jobs)
+	}()
+
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for job := range jobs {
+				result := analyzeDocument(ctx, job.doc, analyses, key, meta)
+				checkAlerts(c.Request().Header, alertedResults(result.Analyses))
+				result.index = job.index
+				results <- result
+			}
+		}()
+	}
+	go func() {
+		wg.Wait()
+		close(results)
+	}()
+
+	accept := c.Request().Header.Get(echo.HeaderAccept)
+	if strings.Contains(accept, mimeApplicationNDJSON) {
+		return streamBatch(c, results, decodeErr)
+	}
+
+	var collected []batchResult
+	for result := range results {
+		collected = append(collected, result)
+	}
+	if err := <-decodeErr; err != nil {
+		return validationError(err.Error())
+	}
+	ordered := make([]batchResult, len(collected))
+	for _, result := range collected {
+		ordered[result.index] = result
+	}
+	if format := exportFormat(accept); format != "" {
+		return exportBatch(c, format, ordered)
+	}
+
+	return c.JSON(http.StatusOK, struct {
+		Results []batchResult `json:"results"`
+	}{ordered})
+}
+
+// streamBatch writes each result as a line of NDJSON as soon as its document completes.
+func streamBatch(c echo.Context, results <-chan batchResult, decodeErr <-chan error) error {
+	// keep the workers unblocked if the client goes away mid-stream
+	defer func() {
+		for range results {
+		}
+	}()
+
+	res := c.Response()
+	res.Header().Set(echo.HeaderContentType, mimeApplicationNDJSON)
+	res.WriteHeader(http.StatusOK)
+
+	enc := json.NewEncoder(res)
+	for result := range results {
+		if err := enc.Encode(result); err != nil {
+			return err
+		}
+		res.Flush()
+	}
+	if err := <-decodeErr; err != nil {
+		return enc.Encode(batchResult{Error: validationError(err.Error())})
+	}
+
+	return nil
+}
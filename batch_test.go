@@ -0,0 +1,84 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+)
+
+func newBatchUpstream(t *testing.T) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body struct {
+			Text string `json:"text"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			t.Error(err)
+		}
+		w.Header().Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		_ = json.NewEncoder(w).Encode([]string{strings.ToUpper(body.Text)})
+	}))
+}
+
+func TestPostBatch(t *testing.T) {
+	upstream := newBatchUpstream(t)
+	defer upstream.Close()
+	defer func(url string) { urlRake = url }(urlRake)
+	urlRake = upstream.URL
+
+	body := `{"documents": [{"id": "a", "text": "one"}, {"id": "b", "text": "two"}, {"id": "c", "text": "three"}]}`
+	req := httptest.NewRequest(http.MethodPost, "/batch?analyses=keywords", strings.NewReader(body))
+	w := httptest.NewRecorder()
+	c := e.NewContext(req, w)
+
+	if assert.NoError(t, postBatch(c)) {
+		assert.Equal(t, http.StatusOK, w.Code)
+		expected := `{"results":[` +
+			`{"id":"a","status":"ok","analyses":{"keywords":{"status":200,"result":["ONE"]}}},` +
+			`{"id":"b","status":"ok","analyses":{"keywords":{"status":200,"result":["TWO"]}}},` +
+			`{"id":"c","status":"ok","analyses":{"keywords":{"status":200,"result":["THREE"]}}}]}`
+		assert.JSONEq(t, expected, w.Body.String())
+	}
+}
+
+func TestPostBatchNDJSON(t *testing.T) {
+	upstream := newBatchUpstream(t)
+	defer upstream.Close()
+	defer func(url string) { urlRake = url }(urlRake)
+	urlRake = upstream.URL
+
+	body := `{"documents": [{"id": "a", "text": "one"}, {"id": "b", "text": "two"}]}`
+	req := httptest.NewRequest(http.MethodPost, "/batch?analyses=keywords", strings.NewReader(body))
+	req.Header.Set(echo.HeaderAccept, mimeApplicationNDJSON)
+	w := httptest.NewRecorder()
+	c := e.NewContext(req, w)
+
+	if assert.NoError(t, postBatch(c)) {
+		assert.Equal(t, mimeApplicationNDJSON, w.Header().Get(echo.HeaderContentType))
+		ids := map[string]string{}
+		scanner := bufio.NewScanner(w.Body)
+		for scanner.Scan() {
+			var result batchResult
+			if assert.NoError(t, json.Unmarshal(scanner.Bytes(), &result)) {
+				ids[result.ID] = string(result.Analyses["keywords"].Result)
+			}
+		}
+		assert.Equal(t, map[string]string{"a": `["ONE"]`, "b": `["TWO"]`}, ids)
+	}
+}
+
+func TestPostBatchInvalidBody(t *testing.T) {
+	req := httptest.NewRequest(http.MethodPost, "/batch?analyses=keywords", strings.NewReader(`[]`))
+	w := httptest.NewRecorder()
+	c := e.NewContext(req, w)
+
+	err := postBatch(c)
+	if assert.Error(t, err) {
+		assert.Equal(t, http.StatusBadRequest, err.(*apiError).Status)
+	}
+}
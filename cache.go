@@ -0,0 +1,238 @@
+// author: Gary A. Stafford
+// site: https://programmaticponderings.com
+// license: MIT License
+// purpose: NLP microservices: nlp-client analysis cache
+// modified: 2026-10-14
+
+package main
+
+import (
+	"container/list"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/labstack/echo/v4"
+)
+
+const (
+	headerETag = "ETag"
+
+	cacheContextKey = "cache"
+)
+
+var (
+	// how long an analysis is cached for; 0, the default, disables caching
+	cacheTTL        = getEnv("CACHE_TTL", "0")
+	cacheMaxEntries = getEnv("CACHE_MAX_ENTRIES", "10000")
+	// memory, or redis to share the cache between instances
+	cacheStore    = getEnv("CACHE_STORE", "memory")
+	analysisCache = loadAnalysisCache()
+)
+
+// cachedRoutes are the analysis routes whose results depend only on their text.
+var cachedRoutes = map[string]bool{
+	"/keywords":  true,
+	"/tokens":    true,
+	"/entities":  true,
+	"/sentences": true,
+	"/language":  true,
+}
+
+type cacheEntry struct {
+	key     string
+	body    []byte
+	status  int
+	expires time.Time
+}
+
+// remoteCacheEntry is a cache entry as stored in Redis.
+type remoteCacheEntry struct {
+	Body   json.RawMessage `json:"body"`
+	Status int             `json:"status"`
+}
+
+// responseCache holds upstream results, after their response transforms, for
+// a fixed time, evicting the least recently used once full. With a remote
+// store, entries are shared through it, and looked up there on a local miss.
+type responseCache struct {
+	mu      sync.Mutex
+	ttl     time.Duration
+	max     int
+	entries map[string]*list.Element
+	order   *list.List
+	now     func() time.Time
+	remote  *redisBackend
+}
+
+// cacheMiddleware opts the routes of a group into the response cache; without
+// it in the chain, every request of the group reaches the upstream. The lookup
+// itself runs in the handler, after the request transforms, so the position
+// of the middleware in the chain does not matter.
+func cacheMiddleware() echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			c.Set(cacheContextKey, true)
+			return next(c)
+		}
+	}
+}
+
+// newResponseCache returns nil, disabling caching, when ttl is not positive.
+func newResponseCache(ttl time.Duration, max int) *responseCache {
+	if ttl <= 0 {
+		return nil
+	}
+
+	return &responseCache{ttl: ttl, max: max, entries: map[string]*list.Element{}, order: list.New(), now: time.Now}
+}
+
+func loadAnalysisCache() *responseCache {
+	cache := newResponseCache(envDuration(cacheTTL, 0), envInt(cacheMaxEntries, 10000))
+	if cache != nil && cacheStore == "redis" {
+		if cache.remote = sharedRedisBackend(); cache.remote == nil {
+			e.Logger.Errorf("CACHE_STORE is redis but REDIS_ADDRS is not set, caching locally")
+		}
+	}
+
+	return cache
+}
+
+func (r *responseCache) get(ctx context.Context, key string) (*cacheEntry, bool) {
+	if entry, ok := r.getLocal(key); ok {
+		return entry, true
+	}
+	if r.remote == nil {
+		return nil, false
+	}
+
+	var remote remoteCacheEntry
+	err := r.remote.do(ctx, func(ctx context.Context) error {
+		data, err := r.remote.client.Get(ctx, r.remote.key("cache:"+key)).Bytes()
+		if err != nil {
+			return err
+		}
+		return json.Unmarshal(data, &remote)
+	})
+	if err != nil {
+		return nil, false
+	}
+
+	return r.setLocal(key, remote.Body, remote.Status), true
+}
+
+func (r *responseCache) getLocal(key string) (*cacheEntry, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	el, ok := r.entries[key]
+	if !ok {
+		return nil, false
+	}
+	entry := el.Value.(*cacheEntry)
+	if !r.now().Before(entry.expires) {
+		r.order.Remove(el)
+		delete(r.entries, key)
+		return nil, false
+	}
+	r.order.MoveToFront(el)
+
+	return entry, true
+}
+
+func (r *responseCache) set(ctx context.Context, key string, body []byte, status int) {
+	r.setLocal(key, body, status)
+	if r.remote == nil {
+		return
+	}
+
+	data, err := json.Marshal(remoteCacheEntry{body, status})
+	if err != nil {
+		return
+	}
+	// a failed write only costs the other instances a miss
+	_ = r.remote.do(ctx, func(ctx context.Context) error {
+		return r.remote.client.Set(ctx, r.remote.key("cache:"+key), data, r.ttl).Err()
+	})
+}
+
+func (r *responseCache) setLocal(key string, body []byte, status int) *cacheEntry {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	entry := &cacheEntry{key, body, status, r.now().Add(r.ttl)}
+	if el, ok := r.entries[key]; ok {
+		el.Value = entry
+		r.order.MoveToFront(el)
+		return entry
+	}
+	r.entries[key] = r.order.PushFront(entry)
+	for r.max > 0 && r.order.Len() > r.max {
+		oldest := r.order.Back()
+		r.order.Remove(oldest)
+		delete(r.entries, oldest.Value.(*cacheEntry).key)
+	}
+
+	return entry
+}
+
+func hashHex(parts ...string) string {
+	sum := sha256.Sum256([]byte(strings.Join(parts, "\x00")))
+	return hex.EncodeToString(sum[:])
+}
+
+// cacheKey identifies the upstream result of a route for a text.
+func cacheKey(route, text string) string {
+	return hashHex(route, text)
+}
+
+// analysisETag is the hash of the text, then of the analysis version: the
+// client version, the route's transforms, and the representation asked for,
+// so a new release or configuration changes every tag.
+func analysisETag(c echo.Context, route, schema, text string) string {
+	analysisVersion := hashHex(
+		version, route, schema,
+		strings.Join(requestTransformsFor(route), ","),
+		strings.Join(routeTransforms(responseTransformRoutes, route), ","),
+		c.QueryParam("offset"),
+		strconv.FormatBool(wantsMeta(c)),
+		confidenceCalibration,
+		c.QueryParam("enrich"),
+		c.QueryParam("glossary"),
+		c.QueryParam("format"),
+		responseNaming(c),
+		glossaries.digest(c.Request().Header.Get(tenantHeader)),
+		gazetteers.digest(c.Request().Header.Get(tenantHeader)),
+		patterns.digest(c.Request().Header.Get(tenantHeader)),
+		c.QueryParam("template"),
+		templates.digest(c.Request().Header.Get(tenantHeader)),
+		localEntityOverlap,
+	)
+	for _, name := range filterParams {
+		analysisVersion = hashHex(analysisVersion, c.QueryParam(name))
+	}
+
+	return `"` + hashHex(text)[:16] + "-" + analysisVersion[:8] + `"`
+}
+
+// notModified reports whether If-None-Match lists the ETag, by weak comparison.
+func notModified(req *http.Request, etag string) bool {
+	header := req.Header.Get("If-None-Match")
+	if header == "" {
+		return false
+	}
+	for _, tag := range strings.Split(header, ",") {
+		tag = strings.TrimPrefix(strings.TrimSpace(tag), "W/")
+		if tag == "*" || tag == etag {
+			return true
+		}
+	}
+
+	return false
+}
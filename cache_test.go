@@ -0,0 +1,101 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/garystafford/nlp-client/mockupstream"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestResponseCache(t *testing.T) {
+	assert.Nil(t, newResponseCache(0, 10))
+
+	now := time.Date(2026, 10, 14, 12, 0, 0, 0, time.UTC)
+	ctx := context.Background()
+	cache := newResponseCache(time.Minute, 2)
+	cache.now = func() time.Time { return now }
+
+	cache.set(ctx, "a", []byte(`"a"`), http.StatusOK)
+	cache.set(ctx, "b", []byte(`"b"`), http.StatusOK)
+	_, _ = cache.get(ctx, "a")
+	cache.set(ctx, "c", []byte(`"c"`), http.StatusOK)
+
+	_, ok := cache.get(ctx, "b")
+	assert.False(t, ok, "least recently used entry is evicted")
+	entry, ok := cache.get(ctx, "a")
+	if assert.True(t, ok) {
+		assert.Equal(t, `"a"`, string(entry.body))
+	}
+
+	now = now.Add(time.Minute)
+	_, ok = cache.get(ctx, "c")
+	assert.False(t, ok, "expired entry is dropped")
+}
+
+func TestNotModified(t *testing.T) {
+	tests := []struct {
+		header   string
+		expected bool
+	}{
+		{"", false},
+		{`"abc"`, true},
+		{`W/"abc"`, true},
+		{`"xyz", "abc"`, true},
+		{`"xyz"`, false},
+		{"*", true},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest(http.MethodPost, "/keywords", nil)
+		req.Header.Set("If-None-Match", tt.header)
+		assert.Equal(t, tt.expected, notModified(req, `"abc"`), tt.header)
+	}
+}
+
+func TestGetKeywordsCached(t *testing.T) {
+	rake := mockupstream.NewRake()
+	defer rake.Close()
+	defer func(url string) { urlRake = url }(urlRake)
+	urlRake = rake.URL
+	defer func(cache *responseCache) { analysisCache = cache }(analysisCache)
+	analysisCache = newResponseCache(time.Minute, 10)
+
+	call := func(etag string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/keywords", strings.NewReader(`{"text": "The Nobel Prize"}`))
+		req.Header.Set("If-None-Match", etag)
+		w := httptest.NewRecorder()
+		c := e.NewContext(req, w)
+		c.SetPath("/keywords")
+		assert.NoError(t, cacheMiddleware()(getKeywords)(c))
+		return w
+	}
+
+	first := call("")
+	etag := first.Header().Get(headerETag)
+	assert.Equal(t, http.StatusOK, first.Code)
+	assert.NotEmpty(t, etag)
+
+	// the second call is answered from the cache, not the changed upstream
+	rake.SetFixture("/keywords", mockupstream.Fixture{Status: http.StatusOK, Body: `[]`})
+	second := call("")
+	assert.Equal(t, first.Body.String(), second.Body.String())
+	assert.Equal(t, etag, second.Header().Get(headerETag))
+
+	revalidated := call(etag)
+	assert.Equal(t, http.StatusNotModified, revalidated.Code)
+	assert.Empty(t, revalidated.Body.String())
+
+	// a chain without the cache middleware always reaches the upstream
+	req := httptest.NewRequest(http.MethodPost, "/keywords", strings.NewReader(`{"text": "The Nobel Prize"}`))
+	w := httptest.NewRecorder()
+	c := e.NewContext(req, w)
+	c.SetPath("/keywords")
+	if assert.NoError(t, getKeywords(c)) {
+		assert.JSONEq(t, `[]`, w.Body.String())
+		assert.Empty(t, w.Header().Get(headerETag))
+	}
+}
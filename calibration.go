@@ -0,0 +1,176 @@
+// author: Gary A. Stafford
+// site: https://programmaticponderings.com
+// license: MIT License
+// purpose: NLP microservices: nlp-client confidence calibration
+// modified: 2026-10-14
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// semicolon-separated curves, each "provider.field=x:y x:y ...", mapping the
+// scores a provider reports in field onto confidences between 0 and 1, e.g.
+// "rake-app.score=0:0 4:0.5 16:1; lang-app.probability=0:0 0.9:0.5 1:1"
+var confidenceCalibration = getEnv("CONFIDENCE_CALIBRATION", "")
+
+var calibrations = loadCalibrations()
+
+// calibration maps a provider's scores onto confidences by linear
+// interpolation between points, clamped to the first and last point.
+type calibration struct {
+	provider string
+	field    string
+	xs, ys   []float64
+	// identifies the curve, so callers can tell results calibrated differently apart
+	id string
+}
+
+func parseCalibration(entry string) (calibration, error) {
+	parts := strings.SplitN(entry, "=", 2)
+	target := strings.SplitN(strings.TrimSpace(parts[0]), ".", 2)
+	if len(parts) != 2 || len(target) != 2 || target[0] == "" || target[1] == "" {
+		return calibration{}, fmt.Errorf("invalid calibration %q, want provider.field=x:y x:y ...", entry)
+	}
+
+	cal := calibration{provider: target[0], field: target[1]}
+	points := strings.Fields(parts[1])
+	if len(points) < 2 {
+		return calibration{}, fmt.Errorf("calibration %s.%s needs at least two points", cal.provider, cal.field)
+	}
+	for _, point := range points {
+		xy := strings.SplitN(point, ":", 2)
+		if len(xy) != 2 {
+			return calibration{}, fmt.Errorf("calibration %s.%s has invalid point %q, want x:y", cal.provider, cal.field, point)
+		}
+		x, errX := strconv.ParseFloat(xy[0], 64)
+		y, errY := strconv.ParseFloat(xy[1], 64)
+		switch {
+		case errX != nil || errY != nil:
+			return calibration{}, fmt.Errorf("calibration %s.%s has invalid point %q, want x:y", cal.provider, cal.field, point)
+		case y < 0 || y > 1:
+			return calibration{}, fmt.Errorf("calibration %s.%s maps onto %v, want between 0 and 1", cal.provider, cal.field, y)
+		case len(cal.xs) > 0 && x <= cal.xs[len(cal.xs)-1]:
+			return calibration{}, fmt.Errorf("calibration %s.%s points must increase in x", cal.provider, cal.field)
+		}
+		cal.xs, cal.ys = append(cal.xs, x), append(cal.ys, y)
+	}
+	cal.id = cal.provider + "." + cal.field + "@" + hashHex(points...)[:8]
+
+	return cal, nil
+}
+
+func parseCalibrations(config string) (map[string]calibration, error) {
+	cals := map[string]calibration{}
+	for _, entry := range strings.Split(config, ";") {
+		if strings.TrimSpace(entry) == "" {
+			continue
+		}
+		cal, err := parseCalibration(entry)
+		if err != nil {
+			return nil, err
+		}
+		if _, ok := cals[cal.provider]; ok {
+			return nil, fmt.Errorf("provider %s is calibrated twice", cal.provider)
+		}
+		cals[cal.provider] = cal
+	}
+
+	return cals, nil
+}
+
+func loadCalibrations() map[string]calibration {
+	cals, err := parseCalibrations(confidenceCalibration)
+	if err != nil {
+		e.Logger.Errorf("ignoring CONFIDENCE_CALIBRATION: %v", err)
+		return nil
+	}
+
+	return cals
+}
+
+// confidence returns the calibrated confidence of a score.
+func (cal calibration) confidence(score float64) float64 {
+	last := len(cal.xs) - 1
+	switch {
+	case score <= cal.xs[0]:
+		return cal.ys[0]
+	case score >= cal.xs[last]:
+		return cal.ys[last]
+	}
+	i := 1
+	for score > cal.xs[i] {
+		i++
+	}
+	fraction := (score - cal.xs[i-1]) / (cal.xs[i] - cal.xs[i-1])
+
+	return cal.ys[i-1] + fraction*(cal.ys[i]-cal.ys[i-1])
+}
+
+// calibrateItem sets the confidence of an upstream result object from its
+// calibrated field, reporting whether it had one.
+func (cal calibration) calibrateItem(item map[string]json.RawMessage) (bool, error) {
+	var score float64
+	raw, ok := item[cal.field]
+	if !ok || json.Unmarshal(raw, &score) != nil {
+		return false, nil
+	}
+	confidence, err := json.Marshal(cal.confidence(score))
+	if err != nil {
+		return false, err
+	}
+	item["confidence"] = confidence
+
+	return true, nil
+}
+
+// calibrate adds a calibrated "confidence" to the result of an analysis
+// route, or to each of its items, when its provider has a calibration, and
+// returns the id of the calibration applied, if any.
+func calibrate(route string, body []byte) ([]byte, string, error) {
+	cal, ok := calibrations[providerFor(route)]
+	if !ok {
+		return body, "", nil
+	}
+
+	var item map[string]json.RawMessage
+	if json.Unmarshal(body, &item) == nil {
+		calibrated, err := cal.calibrateItem(item)
+		if err != nil || !calibrated {
+			return body, "", err
+		}
+		body, err = json.Marshal(item)
+		return body, cal.id, err
+	}
+	var items []json.RawMessage
+	if err := json.Unmarshal(body, &items); err != nil {
+		return nil, "", err
+	}
+	applied := false
+	for i, raw := range items {
+		var fields map[string]json.RawMessage
+		if json.Unmarshal(raw, &fields) != nil {
+			continue
+		}
+		calibrated, err := cal.calibrateItem(fields)
+		if err != nil {
+			return nil, "", err
+		}
+		if calibrated {
+			if items[i], err = json.Marshal(fields); err != nil {
+				return nil, "", err
+			}
+			applied = true
+		}
+	}
+	if !applied {
+		return body, "", nil
+	}
+	body, err := json.Marshal(items)
+
+	return body, cal.id, err
+}
@@ -0,0 +1,92 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/garystafford/nlp-client/mockupstream"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseCalibrations(t *testing.T) {
+	cals, err := parseCalibrations("rake-app.score=0:0 4:0.5 16:1; lang-app.probability=0:0 1:1")
+	if assert.NoError(t, err) && assert.Len(t, cals, 2) {
+		assert.Equal(t, "score", cals["rake-app"].field)
+		assert.Equal(t, []float64{0, 4, 16}, cals["rake-app"].xs)
+		assert.True(t, strings.HasPrefix(cals["rake-app"].id, "rake-app.score@"))
+	}
+
+	for _, config := range []string{
+		"rake-app=0:0 1:1",
+		"rake-app.score=0:0",
+		"rake-app.score=0:0 1:x",
+		"rake-app.score=0:0 1:2",
+		"rake-app.score=1:0 1:1",
+		"rake-app.score=0:0 1:1; rake-app.rank=0:0 1:1",
+	} {
+		_, err := parseCalibrations(config)
+		assert.Error(t, err, config)
+	}
+}
+
+func TestCalibrationConfidence(t *testing.T) {
+	cal, err := parseCalibration("rake-app.score=0:0 4:0.5 16:1")
+	if assert.NoError(t, err) {
+		assert.Equal(t, 0.0, cal.confidence(-1))
+		assert.Equal(t, 0.25, cal.confidence(2))
+		assert.Equal(t, 0.5, cal.confidence(4))
+		assert.Equal(t, 0.75, cal.confidence(10))
+		assert.Equal(t, 1.0, cal.confidence(40))
+	}
+}
+
+func TestCalibrate(t *testing.T) {
+	defer func(cals map[string]calibration) { calibrations = cals }(calibrations)
+	calibrations, _ = parseCalibrations("rake-app.score=0:0 8:1; lang-app.probability=0.5:0 1:1")
+
+	body, id, err := calibrate("/keywords", []byte(`[{"candidate":"nobel prize","score":4},{"candidate":"radium"}]`))
+	if assert.NoError(t, err) {
+		assert.JSONEq(t, `[{"candidate":"nobel prize","score":4,"confidence":0.5},{"candidate":"radium"}]`, string(body))
+		assert.Equal(t, calibrations["rake-app"].id, id)
+	}
+	body, id, err = calibrate("/language", []byte(`{"language":"en","probability":0.75}`))
+	if assert.NoError(t, err) {
+		assert.JSONEq(t, `{"language":"en","probability":0.75,"confidence":0.5}`, string(body))
+		assert.Equal(t, calibrations["lang-app"].id, id)
+	}
+	tokens := []byte(`["The","Nobel"]`)
+	body, id, err = calibrate("/tokens", tokens)
+	if assert.NoError(t, err) {
+		assert.Equal(t, tokens, body)
+		assert.Empty(t, id)
+	}
+}
+
+func TestGetLanguageCalibrated(t *testing.T) {
+	lang := mockupstream.NewLang()
+	defer lang.Close()
+	defer func(url string, cals map[string]calibration) { urlLang, calibrations = url, cals }(urlLang, calibrations)
+	urlLang = lang.URL
+	calibrations, _ = parseCalibrations("lang-app.probability=0.9:0 1:1")
+
+	req := httptest.NewRequest(http.MethodPost, "/language", strings.NewReader(`{"text": "The Nobel Prize"}`))
+	req.Header.Set(headerIncludeMeta, "true")
+	w := httptest.NewRecorder()
+	c := e.NewContext(req, w)
+	c.SetPath("/language")
+	if assert.NoError(t, getLanguage(c)) {
+		var body struct {
+			Result struct {
+				Confidence float64 `json:"confidence"`
+			} `json:"result"`
+			Meta responseMeta `json:"_meta"`
+		}
+		if assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &body)) {
+			assert.InDelta(t, 0.9, body.Result.Confidence, 1e-9)
+			assert.Equal(t, calibrations["lang-app"].id, body.Meta.Upstreams[0].Calibration)
+		}
+	}
+}
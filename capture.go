@@ -0,0 +1,153 @@
+// author: Gary A. Stafford
+// site: https://programmaticponderings.com
+// license: MIT License
+// purpose: NLP microservices: nlp-client traffic capture
+// modified: 2026-10-14
+
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"math/rand"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/labstack/echo/v4"
+)
+
+var (
+	// e.g. file:///var/log/nlp-client/capture.jsonl or s3://bucket/captures; unset to disable
+	captureSink         = getEnv("CAPTURE", "")
+	captureSampleRate   = getEnv("CAPTURE_SAMPLE_RATE", "0.01")
+	captureMaxBodyBytes = getEnv("CAPTURE_MAX_BODY_BYTES", "65536")
+)
+
+// only these request headers are captured; credentials never are
+var capturedHeaders = []string{echo.HeaderContentType, echo.HeaderAccept, headerIncludeMeta}
+
+// capturedExchange is one sanitized request and response pair, written as a line of JSON.
+type capturedExchange struct {
+	Time       time.Time         `json:"time"`
+	Method     string            `json:"method"`
+	URI        string            `json:"uri"`
+	Headers    map[string]string `json:"headers,omitempty"`
+	Request    string            `json:"request,omitempty"`
+	Status     int               `json:"status"`
+	Response   string            `json:"response,omitempty"`
+	DurationMS float64           `json:"duration_ms"`
+	// truncated exchanges are kept for inspection but not replayed
+	Truncated bool `json:"truncated,omitempty"`
+}
+
+// captureWriter copies up to limit bytes of a response as it is written.
+type captureWriter struct {
+	http.ResponseWriter
+	body      bytes.Buffer
+	limit     int
+	truncated bool
+}
+
+func (w *captureWriter) Write(p []byte) (int, error) {
+	if room := w.limit - w.body.Len(); room > 0 {
+		if len(p) > room {
+			w.body.Write(p[:room])
+			w.truncated = true
+		} else {
+			w.body.Write(p)
+		}
+	} else if len(p) > 0 {
+		w.truncated = true
+	}
+
+	return w.ResponseWriter.Write(p)
+}
+
+func (w *captureWriter) Flush() {
+	if flusher, ok := w.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+func captureMiddleware() echo.MiddlewareFunc {
+	if captureSink == "" {
+		return func(next echo.HandlerFunc) echo.HandlerFunc { return next }
+	}
+	sink, err := newLogSink(captureSink)
+	if err != nil {
+		e.Logger.Errorf("ignoring CAPTURE: %v", err)
+		return func(next echo.HandlerFunc) echo.HandlerFunc { return next }
+	}
+
+	return newCapture(sink, parseSampleRate(captureSampleRate), envInt(captureMaxBodyBytes, 65536))
+}
+
+// newCapture records a sample of request and response pairs to sink, with
+// PII redacted from both bodies, for replay against another environment.
+func newCapture(sink io.Writer, rate float64, maxBytes int) echo.MiddlewareFunc {
+	var mu sync.Mutex
+
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			req := c.Request()
+			if rate <= 0 || rand.Float64() >= rate {
+				return next(c)
+			}
+
+			exchange := capturedExchange{Time: time.Now().UTC(), Method: req.Method, URI: req.RequestURI}
+			for _, name := range capturedHeaders {
+				if value := req.Header.Get(name); value != "" {
+					if exchange.Headers == nil {
+						exchange.Headers = map[string]string{}
+					}
+					exchange.Headers[name] = value
+				}
+			}
+			if req.Body != nil {
+				prefix, err := ioutil.ReadAll(io.LimitReader(req.Body, int64(maxBytes)+1))
+				if err != nil {
+					return err
+				}
+				req.Body = struct {
+					io.Reader
+					io.Closer
+				}{io.MultiReader(bytes.NewReader(prefix), req.Body), req.Body}
+				if len(prefix) > maxBytes {
+					prefix, exchange.Truncated = prefix[:maxBytes], true
+				}
+				exchange.Request = redactPII(string(prefix))
+			}
+
+			writer := &captureWriter{ResponseWriter: c.Response().Writer, limit: maxBytes}
+			c.Response().Writer = writer
+			defer func() { c.Response().Writer = writer.ResponseWriter }()
+
+			start := time.Now()
+			err := next(c)
+			if err != nil {
+				// render the error now, so its body is captured too
+				c.Error(err)
+			}
+
+			exchange.Status = c.Response().Status
+			exchange.Response = redactPII(writer.body.String())
+			exchange.Truncated = exchange.Truncated || writer.truncated
+			exchange.DurationMS = float64(time.Since(start)) / float64(time.Millisecond)
+
+			line, jsonErr := json.Marshal(exchange)
+			if jsonErr == nil {
+				mu.Lock()
+				_, jsonErr = sink.Write(append(line, '\n'))
+				mu.Unlock()
+			}
+			if jsonErr != nil {
+				e.Logger.Errorf("traffic capture failed: %v", jsonErr)
+			}
+
+			return err
+		}
+	}
+}
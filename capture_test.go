@@ -0,0 +1,62 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCapture(t *testing.T) {
+	sink := new(bytes.Buffer)
+	handler := newCapture(sink, 1, 1024)(func(c echo.Context) error {
+		body, _ := ioutil.ReadAll(c.Request().Body)
+		return c.JSONBlob(http.StatusOK, body)
+	})
+
+	req := httptest.NewRequest(http.MethodPost, "/keywords?lang=en", strings.NewReader(`{"text": "mail marie@curie.fr"}`))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	req.Header.Set("X-API-Key", "secret")
+	w := httptest.NewRecorder()
+
+	if assert.NoError(t, handler(e.NewContext(req, w))) {
+		// the caller still gets the unredacted response
+		assert.Equal(t, `{"text": "mail marie@curie.fr"}`, w.Body.String())
+
+		var exchange capturedExchange
+		if assert.NoError(t, json.Unmarshal(sink.Bytes(), &exchange)) {
+			assert.Equal(t, "/keywords?lang=en", exchange.URI)
+			assert.Equal(t, map[string]string{echo.HeaderContentType: echo.MIMEApplicationJSON}, exchange.Headers)
+			assert.Equal(t, `{"text": "mail [REDACTED]"}`, exchange.Request)
+			assert.Equal(t, `{"text": "mail [REDACTED]"}`, exchange.Response)
+			assert.Equal(t, http.StatusOK, exchange.Status)
+			assert.False(t, exchange.Truncated)
+		}
+	}
+}
+
+func TestCaptureErrorAndTruncation(t *testing.T) {
+	defer func(h echo.HTTPErrorHandler) { e.HTTPErrorHandler = h }(e.HTTPErrorHandler)
+	e.HTTPErrorHandler = httpErrorHandler
+
+	sink := new(bytes.Buffer)
+	handler := newCapture(sink, 1, 8)(func(c echo.Context) error {
+		return validationError("text is required")
+	})
+
+	req := httptest.NewRequest(http.MethodPost, "/keywords", strings.NewReader(`{"text": ""}`))
+	assert.Error(t, handler(e.NewContext(req, httptest.NewRecorder())))
+
+	var exchange capturedExchange
+	if assert.NoError(t, json.Unmarshal(sink.Bytes(), &exchange)) {
+		assert.Equal(t, http.StatusBadRequest, exchange.Status)
+		assert.Equal(t, `{"text":`, exchange.Request)
+		assert.True(t, exchange.Truncated)
+	}
+}
@@ -0,0 +1,207 @@
+// author: Gary A. Stafford
+// site: https://programmaticponderings.com
+// license: MIT License
+// purpose: NLP microservices: nlp-client document comparison
+// modified: 2026-10-14
+
+package main
+
+import (
+	"encoding/json"
+	"math"
+	"net/http"
+	"strings"
+	"sync"
+	"unicode"
+
+	"github.com/labstack/echo/v4"
+)
+
+var (
+	// words per shingle the textual overlap is measured in
+	compareShingleSize = getEnv("COMPARE_SHINGLE_SIZE", "3")
+	// least word similarity of two sentences to align them
+	compareAlignThreshold = getEnv("COMPARE_ALIGN_THRESHOLD", "0.5")
+)
+
+// compareAnalyses are run on both documents.
+var compareAnalyses = []string{"entities", "keywords", "sentences"}
+
+// textOverlap is the share, in percent, of the shingles of the documents found
+// in both, of those of either, and of each found in the other.
+type textOverlap struct {
+	Jaccard     float64 `json:"jaccard"`
+	LeftInRight float64 `json:"left_in_right"`
+	RightInLeft float64 `json:"right_in_left"`
+}
+
+type sentencePair struct {
+	Left       int     `json:"left"`
+	Right      int     `json:"right"`
+	LeftText   string  `json:"left_text"`
+	RightText  string  `json:"right_text"`
+	Similarity float64 `json:"similarity"`
+}
+
+type comparison struct {
+	Left           string          `json:"left,omitempty"`
+	Right          string          `json:"right,omitempty"`
+	SharedEntities []evaluatedItem `json:"shared_entities"`
+	SharedKeywords []string        `json:"shared_keywords"`
+	Overlap        textOverlap     `json:"overlap"`
+	Alignment      []sentencePair  `json:"alignment"`
+}
+
+// compareWords splits text into lowercased words.
+func compareWords(text string) []string {
+	return strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
+
+// shingles returns the distinct runs of size words of text; texts shorter
+// than size are one shingle.
+func shingles(text string, size int) map[string]bool {
+	words := compareWords(text)
+	set := map[string]bool{}
+	if len(words) > 0 && len(words) < size {
+		set[strings.Join(words, " ")] = true
+	}
+	for i := 0; i+size <= len(words); i++ {
+		set[strings.Join(words[i:i+size], " ")] = true
+	}
+
+	return set
+}
+
+func percent(n, d int) float64 {
+	if d == 0 {
+		return 0
+	}
+
+	return math.Round(float64(n)/float64(d)*1e4) / 100
+}
+
+func overlapOf(left, right map[string]bool) textOverlap {
+	shared := 0
+	for shingle := range left {
+		if right[shingle] {
+			shared++
+		}
+	}
+
+	return textOverlap{
+		Jaccard:     percent(shared, len(left)+len(right)-shared),
+		LeftInRight: percent(shared, len(left)),
+		RightInLeft: percent(shared, len(right)),
+	}
+}
+
+// sharedItems returns the items of a result of the left document that are
+// also in that of the right, matched as ensembles match them.
+func sharedItems(left, right json.RawMessage) []evaluatedItem {
+	shared := []evaluatedItem{}
+	var leftItems, rightItems []json.RawMessage
+	if json.Unmarshal(left, &leftItems) != nil || json.Unmarshal(right, &rightItems) != nil {
+		return shared
+	}
+	inRight := map[string]bool{}
+	for _, raw := range rightItems {
+		if key, _, _, ok := matchedItem(raw); ok {
+			inRight[key] = true
+		}
+	}
+	for _, raw := range leftItems {
+		if key, text, label, ok := matchedItem(raw); ok && inRight[key] {
+			shared = append(shared, evaluatedItem{text, label})
+			delete(inRight, key)
+		}
+	}
+
+	return shared
+}
+
+// alignSentences pairs each sentence of the left document with the most
+// similar sentence of the right, by the Jaccard similarity of their words,
+// when that is at least threshold.
+func alignSentences(left, right []string, threshold float64) []sentencePair {
+	wordSets := func(sentences []string) []map[string]bool {
+		sets := make([]map[string]bool, len(sentences))
+		for i, sentence := range sentences {
+			sets[i] = shingles(sentence, 1)
+		}
+		return sets
+	}
+	leftWords, rightWords := wordSets(left), wordSets(right)
+
+	pairs := []sentencePair{}
+	for i := range leftWords {
+		best, similarity := -1, 0.0
+		for j := range rightWords {
+			if s := overlapOf(leftWords[i], rightWords[j]).Jaccard / 100; s > similarity {
+				best, similarity = j, s
+			}
+		}
+		if best >= 0 && similarity >= threshold {
+			pairs = append(pairs, sentencePair{i, best, left[i], right[best], similarity})
+		}
+	}
+
+	return pairs
+}
+
+// postCompareDocs compares two documents for overlap: the entities and
+// keywords they share, how much of their text is shared, and which of their
+// sentences correspond.
+func postCompareDocs(c echo.Context) error {
+	var request struct {
+		Documents []batchDocument `json:"documents"`
+	}
+	if err := json.NewDecoder(c.Request().Body).Decode(&request); err != nil {
+		return validationError(err.Error())
+	}
+	if len(request.Documents) != 2 {
+		return validationError("exactly two documents are required")
+	}
+
+	ctx, key := c.Request().Context(), c.Request().Header.Get("X-API-Key")
+	results := make([]map[string]analysisResult, 2)
+	var wg sync.WaitGroup
+	for i, doc := range request.Documents {
+		wg.Add(1)
+		go func(i int, text string) {
+			defer wg.Done()
+			results[i] = runAnalyses(ctx, text, compareAnalyses, key)
+		}(i, doc.Text)
+	}
+	wg.Wait()
+	for _, name := range compareAnalyses {
+		for _, result := range results {
+			if result[name].Error != nil {
+				return result[name].Error
+			}
+		}
+	}
+
+	var leftSentences, rightSentences []string
+	if err := json.Unmarshal(results[0]["sentences"].Result, &leftSentences); err != nil {
+		return internalError(err)
+	}
+	if err := json.Unmarshal(results[1]["sentences"].Result, &rightSentences); err != nil {
+		return internalError(err)
+	}
+	keywords := []string{}
+	for _, item := range sharedItems(results[0]["keywords"].Result, results[1]["keywords"].Result) {
+		keywords = append(keywords, item.Text)
+	}
+	size := envInt(compareShingleSize, 3)
+
+	return c.JSON(http.StatusOK, comparison{
+		Left:           request.Documents[0].ID,
+		Right:          request.Documents[1].ID,
+		SharedEntities: sharedItems(results[0]["entities"].Result, results[1]["entities"].Result),
+		SharedKeywords: keywords,
+		Overlap:        overlapOf(shingles(request.Documents[0].Text, size), shingles(request.Documents[1].Text, size)),
+		Alignment:      alignSentences(leftSentences, rightSentences, envFloat(compareAlignThreshold, 0.5)),
+	})
+}
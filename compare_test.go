@@ -0,0 +1,64 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/garystafford/nlp-client/mockupstream"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestOverlapOf(t *testing.T) {
+	left := shingles("Marie Curie won the Nobel Prize in 1903.", 3)
+	right := shingles("In 1903, Marie Curie won the Nobel Prize!", 3)
+	assert.Len(t, left, 6)
+
+	overlap := overlapOf(left, right)
+	assert.Equal(t, textOverlap{Jaccard: 50, LeftInRight: 66.67, RightInLeft: 66.67}, overlap)
+	assert.Equal(t, textOverlap{}, overlapOf(shingles("", 3), shingles("radium", 3)))
+	assert.Len(t, shingles("radium", 3), 1, "a text shorter than a shingle is one")
+}
+
+func TestAlignSentences(t *testing.T) {
+	left := []string{"Marie Curie won the Nobel Prize.", "She was born in Warsaw."}
+	right := []string{"Radium glows.", "Curie won the Nobel Prize in 1903."}
+
+	pairs := alignSentences(left, right, 0.5)
+	if assert.Len(t, pairs, 1) {
+		assert.Equal(t, 0, pairs[0].Left)
+		assert.Equal(t, 1, pairs[0].Right)
+		assert.Equal(t, 0.625, pairs[0].Similarity)
+	}
+}
+
+func TestPostCompareDocs(t *testing.T) {
+	rake, prose := mockupstream.NewRake(), mockupstream.NewProse()
+	defer rake.Close()
+	defer prose.Close()
+	defer func(r, p string) { urlRake, urlProse = r, p }(urlRake, urlProse)
+	urlRake, urlProse = rake.URL, prose.URL
+
+	post := func(body string) (*httptest.ResponseRecorder, error) {
+		req := httptest.NewRequest(http.MethodPost, "/compare-docs", strings.NewReader(body))
+		w := httptest.NewRecorder()
+		return w, postCompareDocs(e.NewContext(req, w))
+	}
+
+	w, err := post(`{"documents": [{"id": "a", "text": "Marie Curie won the Nobel Prize."}, {"id": "b", "text": "Marie Curie won a Nobel Prize."}]}`)
+	if assert.NoError(t, err) {
+		var body comparison
+		if assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &body)) {
+			assert.Equal(t, "a", body.Left)
+			assert.Equal(t, []evaluatedItem{{"Marie Curie", "PERSON"}}, body.SharedEntities)
+			assert.Equal(t, []string{"nobel prize", "marie curie"}, body.SharedKeywords)
+			assert.Equal(t, 25.0, body.Overlap.LeftInRight)
+			assert.Len(t, body.Alignment, 1)
+		}
+	}
+
+	_, err = post(`{"documents": [{"text": "Marie Curie"}]}`)
+	assert.Error(t, err)
+}
@@ -0,0 +1,318 @@
+// author: Gary A. Stafford
+// site: https://programmaticponderings.com
+// license: MIT License
+// purpose: NLP microservices: nlp-client corpus statistics
+// modified: 2026-10-14
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"unicode/utf8"
+
+	"github.com/labstack/echo/v4"
+)
+
+var (
+	// entities and keywords listed in corpus statistics, unless a request asks for fewer or more
+	corpusTopTerms = getEnv("CORPUS_TOP_TERMS", "20")
+	// upper bounds, in characters, of the document length histogram buckets
+	corpusLengthBuckets = getEnv("CORPUS_LENGTH_BUCKETS", "100,500,1000,5000,10000")
+	corpusLengthBounds  = parseLengthBuckets(corpusLengthBuckets)
+)
+
+// corpusAnalyses are the analyses run over each document of a corpus.
+var corpusAnalyses = []string{"entities", "keywords", "language"}
+
+type corpusEntity struct {
+	Text      string `json:"text"`
+	Label     string `json:"label"`
+	Count     int    `json:"count"`
+	Documents int    `json:"documents"`
+}
+
+type corpusKeyword struct {
+	Text      string  `json:"text"`
+	TFIDF     float64 `json:"tfidf"`
+	Documents int     `json:"documents"`
+}
+
+type lengthBucket struct {
+	// LE is the bucket's inclusive upper bound, nil for the last, unbounded bucket
+	LE    *int `json:"le"`
+	Count int  `json:"count"`
+}
+
+type corpusStats struct {
+	Documents int              `json:"documents"`
+	Failed    int              `json:"failed"`
+	Entities  []corpusEntity   `json:"entities"`
+	Keywords  []corpusKeyword  `json:"keywords"`
+	Languages map[string]int   `json:"languages"`
+	Lengths   corpusLengthStat `json:"lengths"`
+}
+
+type corpusLengthStat struct {
+	Min     int            `json:"min"`
+	Max     int            `json:"max"`
+	Mean    float64        `json:"mean"`
+	Buckets []lengthBucket `json:"buckets"`
+}
+
+// documentStats is what one document contributes to its corpus.
+type documentStats struct {
+	length   int
+	language string
+	entities []v2Item
+	// occurrences of each keyword in the document
+	keywords map[string]int
+	failed   bool
+}
+
+func analyzeCorpusDocument(ctx context.Context, doc batchDocument, key string) documentStats {
+	stats := documentStats{length: utf8.RuneCountInString(doc.Text), keywords: map[string]int{}}
+	for name, result := range runAnalyses(ctx, doc.Text, corpusAnalyses, key) {
+		if result.Error != nil {
+			stats.failed = true
+			continue
+		}
+		body, err := normalizeResult("/"+name, doc.Text, result.Result)
+		if err != nil {
+			stats.failed = true
+			continue
+		}
+		switch name {
+		case "entities":
+			err = json.Unmarshal(body, &stats.entities)
+		case "keywords":
+			var keywords []v2Keyword
+			err = json.Unmarshal(body, &keywords)
+			for _, keyword := range keywords {
+				// a keyword RAKE found occurs at least once, even where its text was rewritten
+				if n := len(keyword.Offsets); n > 0 {
+					stats.keywords[strings.ToLower(keyword.Text)] += n
+				} else {
+					stats.keywords[strings.ToLower(keyword.Text)]++
+				}
+			}
+		case "language":
+			var language struct {
+				Language string `json:"language"`
+			}
+			err = json.Unmarshal(body, &language)
+			stats.language = language.Language
+		}
+		if err != nil {
+			stats.failed = true
+		}
+	}
+
+	return stats
+}
+
+type entityKey struct {
+	text, label string
+}
+
+// corpusAggregate accumulates the statistics of a corpus one document at a time.
+type corpusAggregate struct {
+	documents     int
+	failed        int
+	entityCount   map[entityKey]int
+	entityDocs    map[entityKey]int
+	keywordFreq   map[string]int
+	keywordDocs   map[string]int
+	languages     map[string]int
+	bounds        []int
+	buckets       []int
+	lengthTotal   int
+	lengthMinimum int
+	lengthMaximum int
+}
+
+func newCorpusAggregate(bounds []int) *corpusAggregate {
+	return &corpusAggregate{
+		entityCount: map[entityKey]int{},
+		entityDocs:  map[entityKey]int{},
+		keywordFreq: map[string]int{},
+		keywordDocs: map[string]int{},
+		languages:   map[string]int{},
+		bounds:      bounds,
+		buckets:     make([]int, len(bounds)+1),
+	}
+}
+
+func (a *corpusAggregate) add(doc documentStats) {
+	a.documents++
+	if doc.failed {
+		a.failed++
+	}
+
+	seen := map[entityKey]bool{}
+	for _, entity := range doc.entities {
+		key := entityKey{entity.Text, entity.Label}
+		a.entityCount[key]++
+		if !seen[key] {
+			seen[key] = true
+			a.entityDocs[key]++
+		}
+	}
+	for keyword, n := range doc.keywords {
+		a.keywordFreq[keyword] += n
+		a.keywordDocs[keyword]++
+	}
+	if doc.language != "" {
+		a.languages[doc.language]++
+	}
+
+	bucket := sort.SearchInts(a.bounds, doc.length)
+	a.buckets[bucket]++
+	a.lengthTotal += doc.length
+	if a.documents == 1 || doc.length < a.lengthMinimum {
+		a.lengthMinimum = doc.length
+	}
+	if doc.length > a.lengthMaximum {
+		a.lengthMaximum = doc.length
+	}
+}
+
+// idf is the smoothed inverse document frequency of a keyword found in df of
+// the corpus's documents, so a keyword in every document still weighs something.
+func (a *corpusAggregate) idf(df int) float64 {
+	return math.Log(float64(1+a.documents)/float64(1+df)) + 1
+}
+
+// stats returns the aggregate statistics, with the top entities by count and
+// the top keywords by TF-IDF.
+func (a *corpusAggregate) stats(top int) corpusStats {
+	entities := make([]corpusEntity, 0, len(a.entityCount))
+	for key, count := range a.entityCount {
+		entities = append(entities, corpusEntity{Text: key.text, Label: key.label, Count: count, Documents: a.entityDocs[key]})
+	}
+	sort.Slice(entities, func(i, j int) bool {
+		if entities[i].Count != entities[j].Count {
+			return entities[i].Count > entities[j].Count
+		}
+		if entities[i].Text != entities[j].Text {
+			return entities[i].Text < entities[j].Text
+		}
+		return entities[i].Label < entities[j].Label
+	})
+	if len(entities) > top {
+		entities = entities[:top]
+	}
+
+	keywords := make([]corpusKeyword, 0, len(a.keywordFreq))
+	for keyword, freq := range a.keywordFreq {
+		df := a.keywordDocs[keyword]
+		weight := math.Round(float64(freq)*a.idf(df)*1e4) / 1e4
+		keywords = append(keywords, corpusKeyword{Text: keyword, TFIDF: weight, Documents: df})
+	}
+	sort.Slice(keywords, func(i, j int) bool {
+		if keywords[i].TFIDF != keywords[j].TFIDF {
+			return keywords[i].TFIDF > keywords[j].TFIDF
+		}
+		return keywords[i].Text < keywords[j].Text
+	})
+	if len(keywords) > top {
+		keywords = keywords[:top]
+	}
+
+	lengths := corpusLengthStat{Min: a.lengthMinimum, Max: a.lengthMaximum, Buckets: make([]lengthBucket, len(a.buckets))}
+	if a.documents > 0 {
+		lengths.Mean = math.Round(float64(a.lengthTotal)/float64(a.documents)*100) / 100
+	}
+	for i, count := range a.buckets {
+		lengths.Buckets[i].Count = count
+		if i < len(a.bounds) {
+			lengths.Buckets[i].LE = &a.bounds[i]
+		}
+	}
+
+	return corpusStats{
+		Documents: a.documents,
+		Failed:    a.failed,
+		Entities:  entities,
+		Keywords:  keywords,
+		Languages: a.languages,
+		Lengths:   lengths,
+	}
+}
+
+// parseLengthBuckets parses the ascending histogram bounds, skipping any that are invalid.
+func parseLengthBuckets(config string) []int {
+	var bounds []int
+	for _, value := range splitList(config) {
+		bound, err := strconv.Atoi(value)
+		if err != nil || bound < 0 || (len(bounds) > 0 && bound <= bounds[len(bounds)-1]) {
+			e.Logger.Errorf("ignoring corpus length bucket %q", value)
+			continue
+		}
+		bounds = append(bounds, bound)
+	}
+
+	return bounds
+}
+
+// postCorpusStats analyzes the documents of a corpus, as sent to /batch, and
+// answers with their aggregate statistics rather than the result of each.
+func postCorpusStats(c echo.Context) error {
+	top := envInt(corpusTopTerms, 20)
+	if value := c.QueryParam("top"); value != "" {
+		n, err := strconv.Atoi(value)
+		if err != nil || n < 1 {
+			return validationError("invalid top query parameter")
+		}
+		top = n
+	}
+	workers, err := strconv.Atoi(batchConcurrency)
+	if err != nil || workers < 1 {
+		workers = 1
+	}
+
+	ctx, jobID, err := withJobID(c.Request().Context())
+	if err != nil {
+		return internalError(err)
+	}
+	c.Response().Header().Set(headerJobID, jobID)
+	key := c.Request().Header.Get("X-API-Key")
+	jobs := make(chan batchJob, workers)
+	results := make(chan documentStats, workers)
+
+	decodeErr := make(chan error, 1)
+	go func() {
+		decodeErr <- decodeDocuments(c.Request().Body, jobs)
+	}()
+
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for job := range jobs {
+				results <- analyzeCorpusDocument(ctx, job.doc, key)
+			}
+		}()
+	}
+	go func() {
+		wg.Wait()
+		close(results)
+	}()
+
+	aggregate := newCorpusAggregate(corpusLengthBounds)
+	for result := range results {
+		aggregate.add(result)
+	}
+	if err := <-decodeErr; err != nil {
+		return validationError(err.Error())
+	}
+
+	return c.JSON(http.StatusOK, aggregate.stats(top))
+}
@@ -0,0 +1,72 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/garystafford/nlp-client/mockupstream"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCorpusAggregateTFIDF(t *testing.T) {
+	aggregate := newCorpusAggregate(nil)
+	aggregate.add(documentStats{keywords: map[string]int{"radium": 2, "prize": 1}})
+	aggregate.add(documentStats{keywords: map[string]int{"prize": 1}})
+
+	stats := aggregate.stats(1)
+	assert.Equal(t, []corpusKeyword{{Text: "radium", TFIDF: 2.8109, Documents: 1}}, stats.Keywords,
+		"a keyword in fewer documents weighs more")
+}
+
+func TestParseLengthBuckets(t *testing.T) {
+	assert.Equal(t, []int{10, 100}, parseLengthBuckets("10, x, 5, 100"))
+}
+
+func TestPostCorpusStats(t *testing.T) {
+	rake := mockupstream.NewRake()
+	defer rake.Close()
+	prose := mockupstream.NewProse()
+	defer prose.Close()
+	lang := mockupstream.NewLang()
+	defer lang.Close()
+	defer func(rakeURL, proseURL, langURL string) {
+		urlRake, urlProse, urlLang = rakeURL, proseURL, langURL
+	}(urlRake, urlProse, urlLang)
+	urlRake, urlProse, urlLang = rake.URL, prose.URL, lang.URL
+	defer func(bounds []int) { corpusLengthBounds = bounds }(corpusLengthBounds)
+	corpusLengthBounds = []int{20, 100}
+
+	body := `{"documents": [{"id": "a", "text": "Marie Curie won the Nobel Prize, Marie Curie said."}, {"id": "b", "text": "Marie Curie"}]}`
+	req := httptest.NewRequest(http.MethodPost, "/corpus/stats", strings.NewReader(body))
+	w := httptest.NewRecorder()
+
+	if assert.NoError(t, postCorpusStats(e.NewContext(req, w))) {
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.NotEmpty(t, w.Header().Get(headerJobID))
+		assert.JSONEq(t, `{
+			"documents": 2,
+			"failed": 0,
+			"entities": [{"text": "Marie Curie", "label": "PERSON", "count": 2, "documents": 2}],
+			"keywords": [
+				{"text": "marie curie", "tfidf": 3, "documents": 2},
+				{"text": "nobel prize", "tfidf": 2, "documents": 2}
+			],
+			"languages": {"en": 2},
+			"lengths": {"min": 11, "max": 50, "mean": 30.5, "buckets": [
+				{"le": 20, "count": 1}, {"le": 100, "count": 1}, {"le": null, "count": 0}
+			]}
+		}`, w.Body.String())
+	}
+}
+
+func TestPostCorpusStatsInvalidTop(t *testing.T) {
+	req := httptest.NewRequest(http.MethodPost, "/corpus/stats?top=0", strings.NewReader(`{"documents": []}`))
+	w := httptest.NewRecorder()
+
+	err := postCorpusStats(e.NewContext(req, w))
+	if assert.Error(t, err) {
+		assert.Equal(t, codeValidationFailed, err.(*apiError).Code)
+	}
+}
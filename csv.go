@@ -0,0 +1,167 @@
+// author: Gary A. Stafford
+// site: https://programmaticponderings.com
+// license: MIT License
+// purpose: NLP microservices: nlp-client CSV column analysis
+// modified: 2026-10-14
+
+package main
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/labstack/echo/v4"
+)
+
+const mimeTextCSV = "text/csv"
+
+// number of keywords written to the keywords column of each row
+var csvTopKeywords = getEnv("CSV_TOP_KEYWORDS", "5")
+
+// csvColumns names the columns each analysis adds to a row.
+var csvColumns = map[string]string{
+	"language":  "language",
+	"keywords":  "keywords",
+	"entities":  "entity_count",
+	"tokens":    "token_count",
+	"sentences": "sentence_count",
+}
+
+var csvFormats = map[string]string{mimeTextCSV: "csv", "text/plain": "csv", "application/csv": "csv"}
+
+// csvColumn returns the index of the column selected by name, or by zero-based index.
+func csvColumn(header []string, selector string) (int, error) {
+	for i, name := range header {
+		if strings.EqualFold(strings.TrimSpace(name), selector) {
+			return i, nil
+		}
+	}
+	if i, err := strconv.Atoi(selector); err == nil && i >= 0 && i < len(header) {
+		return i, nil
+	}
+
+	return 0, fmt.Errorf("unknown column: %q", selector)
+}
+
+// csvValue summarizes an analysis result as a single cell.
+func csvValue(analysis string, result json.RawMessage) string {
+	switch analysis {
+	case "language":
+		var language struct {
+			Language string `json:"language"`
+		}
+		_ = json.Unmarshal(result, &language)
+		return language.Language
+	case "keywords":
+		var keywords []struct {
+			Candidate string  `json:"candidate"`
+			Score     float64 `json:"score"`
+		}
+		_ = json.Unmarshal(result, &keywords)
+		sort.SliceStable(keywords, func(i, j int) bool { return keywords[i].Score > keywords[j].Score })
+		var top []string
+		for i := 0; i < len(keywords) && i < envInt(csvTopKeywords, 5); i++ {
+			top = append(top, keywords[i].Candidate)
+		}
+		return strings.Join(top, "; ")
+	}
+
+	var items []json.RawMessage
+	_ = json.Unmarshal(result, &items)
+	return strconv.Itoa(len(items))
+}
+
+// augmentRow appends a cell for each analysis to row, and an errors cell
+// listing the code of each analysis that failed.
+func augmentRow(row []string, analyses []string, results map[string]analysisResult) []string {
+	var failed []string
+	for _, name := range analyses {
+		result := results[name]
+		if result.Error != nil {
+			failed = append(failed, name+":"+result.Error.Code)
+			row = append(row, "")
+			continue
+		}
+		row = append(row, csvValue(name, result.Result))
+	}
+
+	return append(row, strings.Join(failed, "; "))
+}
+
+// postAnalyzeCSV runs the analyses on the selected text column of every row
+// of an uploaded CSV, BATCH_CONCURRENCY rows at a time, and returns the CSV
+// with a column added for each analysis and an errors column.
+func postAnalyzeCSV(c echo.Context) error {
+	analyses, err := parseAnalyses(c.QueryParam("analyses"))
+	if err != nil {
+		return validationError(err.Error())
+	}
+	data, _, err := readUpload(c, "file", csvFormats)
+	if err != nil {
+		return validationError(err.Error())
+	}
+	rows, err := csv.NewReader(bytes.NewReader(data)).ReadAll()
+	if err != nil {
+		return validationError(err.Error())
+	}
+	if len(rows) == 0 {
+		return validationError("CSV has no header row")
+	}
+	column, err := csvColumn(rows[0], c.QueryParam("column"))
+	if err != nil {
+		return validationError(err.Error())
+	}
+
+	header := append([]string{}, rows[0]...)
+	for _, name := range analyses {
+		header = append(header, csvColumns[name])
+	}
+	out := [][]string{append(header, "errors")}
+
+	workers := envInt(batchConcurrency, 1)
+	if workers < 1 {
+		workers = 1
+	}
+	key := c.Request().Header.Get("X-API-Key")
+	augmented := make([][]string, len(rows)-1)
+	indexes := make(chan int)
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range indexes {
+				row := rows[i+1]
+				var text string
+				if column < len(row) {
+					text = row[column]
+				}
+				results := runAnalyses(c.Request().Context(), text, analyses, key)
+				augmented[i] = augmentRow(append([]string{}, row...), analyses, results)
+			}
+		}()
+	}
+	for i := range augmented {
+		indexes <- i
+	}
+	close(indexes)
+	wg.Wait()
+
+	res := c.Response()
+	res.Header().Set(echo.HeaderContentType, mimeTextCSV+"; charset=utf-8")
+	res.Header().Set(echo.HeaderContentDisposition, `attachment; filename="analyzed.csv"`)
+	res.WriteHeader(http.StatusOK)
+	w := csv.NewWriter(res)
+	if err := w.WriteAll(append(out, augmented...)); err != nil {
+		return err
+	}
+
+	return nil
+}
@@ -0,0 +1,74 @@
+package main
+
+import (
+	"encoding/csv"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/garystafford/nlp-client/mockupstream"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCSVColumn(t *testing.T) {
+	header := []string{"id", "Review ", "rating"}
+
+	i, err := csvColumn(header, "review")
+	if assert.NoError(t, err) {
+		assert.Equal(t, 1, i)
+	}
+	i, err = csvColumn(header, "2")
+	if assert.NoError(t, err) {
+		assert.Equal(t, 2, i)
+	}
+	_, err = csvColumn(header, "3")
+	assert.Error(t, err)
+	_, err = csvColumn(header, "comment")
+	assert.Error(t, err)
+}
+
+func TestCSVValue(t *testing.T) {
+	defer func(top string) { csvTopKeywords = top }(csvTopKeywords)
+	csvTopKeywords = "2"
+
+	keywords := `[{"candidate":"radium","score":1},{"candidate":"nobel prize","score":4},{"candidate":"marie curie","score":4}]`
+	assert.Equal(t, "nobel prize; marie curie", csvValue("keywords", []byte(keywords)))
+	assert.Equal(t, "en", csvValue("language", []byte(mockupstream.LangFixtures["/language"].Body)))
+	assert.Equal(t, "1", csvValue("entities", []byte(mockupstream.ProseFixtures["/entities"].Body)))
+	assert.Equal(t, "5", csvValue("tokens", []byte(mockupstream.ProseFixtures["/tokens"].Body)))
+}
+
+func TestPostAnalyzeCSV(t *testing.T) {
+	rake := mockupstream.NewRake()
+	defer rake.Close()
+	lang := mockupstream.NewLang()
+	defer lang.Close()
+	defer func(rakeURL, langURL string) { urlRake, urlLang = rakeURL, langURL }(urlRake, urlLang)
+	urlRake = rake.URL
+	urlLang = lang.URL
+	lang.SetFixture("/language", mockupstream.Fixture{Status: http.StatusInternalServerError})
+
+	body := "id,review\n1,Marie Curie won the Nobel Prize\n2,\"Radium, polonium\"\n3,Pierre\n"
+	req := httptest.NewRequest(http.MethodPost, "/analyze/csv?analyses=keywords,language&column=review", strings.NewReader(body))
+	req.Header.Set("Content-Type", "text/csv")
+	w := httptest.NewRecorder()
+
+	if assert.NoError(t, postAnalyzeCSV(e.NewContext(req, w))) {
+		assert.Equal(t, `attachment; filename="analyzed.csv"`, w.Header().Get("Content-Disposition"))
+		rows, err := csv.NewReader(w.Body).ReadAll()
+		if assert.NoError(t, err) && assert.Len(t, rows, 4) {
+			assert.Equal(t, []string{"id", "review", "keywords", "language", "errors"}, rows[0])
+			assert.Equal(t, []string{"2", "Radium, polonium", "nobel prize; marie curie", "", "language:UPSTREAM_ERROR"}, rows[2])
+			assert.Equal(t, "3", rows[3][0])
+		}
+	}
+}
+
+func TestPostAnalyzeCSVUnknownColumn(t *testing.T) {
+	req := httptest.NewRequest(http.MethodPost, "/analyze/csv?analyses=keywords&column=comment", strings.NewReader("id,review\n1,text\n"))
+	err := postAnalyzeCSV(e.NewContext(req, httptest.NewRecorder()))
+	if assert.Error(t, err) {
+		assert.Equal(t, http.StatusBadRequest, err.(*apiError).Status)
+	}
+}
@@ -0,0 +1,130 @@
+// author: Gary A. Stafford
+// site: https://programmaticponderings.com
+// license: MIT License
+// purpose: NLP microservices: nlp-client request deadline propagation
+// modified: 2026-10-14
+
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/labstack/echo/v4"
+)
+
+const (
+	// absolute RFC 3339 deadline, e.g. "2026-10-14T12:00:00.5Z"
+	headerRequestDeadline = "X-Request-Deadline"
+	// relative gRPC-style timeout, e.g. "1500m" for 1.5 seconds
+	headerGRPCTimeout = "Grpc-Timeout"
+)
+
+var errDeadlineExceeded = errors.New("request deadline exceeded")
+
+var grpcTimeoutUnits = map[byte]time.Duration{
+	'H': time.Hour,
+	'M': time.Minute,
+	'S': time.Second,
+	'm': time.Millisecond,
+	'u': time.Microsecond,
+	'n': time.Nanosecond,
+}
+
+func deadlineExceededError() *apiError {
+	err := newAPIError(http.StatusGatewayTimeout, codeDeadlineExceeded, errDeadlineExceeded.Error())
+	// the same deadline cannot be met by retrying
+	err.Retryable = false
+
+	return err.withCause(errDeadlineExceeded)
+}
+
+// parseRequestDeadline returns the caller's deadline from either header,
+// preferring the absolute X-Request-Deadline.
+func parseRequestDeadline(header http.Header, now time.Time) (time.Time, bool, error) {
+	if value := header.Get(headerRequestDeadline); value != "" {
+		deadline, err := time.Parse(time.RFC3339Nano, value)
+		if err != nil {
+			return time.Time{}, false, fmt.Errorf("invalid %s header: %s", headerRequestDeadline, value)
+		}
+		return deadline, true, nil
+	}
+	if value := header.Get(headerGRPCTimeout); value != "" {
+		timeout, err := parseGRPCTimeout(value)
+		if err != nil {
+			return time.Time{}, false, err
+		}
+		return now.Add(timeout), true, nil
+	}
+
+	return time.Time{}, false, nil
+}
+
+// parseGRPCTimeout parses a timeout of at most eight digits followed by a unit.
+func parseGRPCTimeout(value string) (time.Duration, error) {
+	if len(value) < 2 || len(value) > 9 {
+		return 0, fmt.Errorf("invalid %s header: %s", headerGRPCTimeout, value)
+	}
+	unit, ok := grpcTimeoutUnits[value[len(value)-1]]
+	n, err := strconv.ParseInt(value[:len(value)-1], 10, 64)
+	if !ok || err != nil || n < 0 {
+		return 0, fmt.Errorf("invalid %s header: %s", headerGRPCTimeout, value)
+	}
+
+	return time.Duration(n) * unit, nil
+}
+
+// requestDeadline applies the caller's deadline to the request context, which
+// only ever shortens the route timeout, and rejects requests already past it.
+func requestDeadline() echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			deadline, ok, err := parseRequestDeadline(c.Request().Header, time.Now())
+			if err != nil {
+				return validationError(err.Error())
+			}
+			if !ok {
+				return next(c)
+			}
+			if !time.Now().Before(deadline) {
+				return deadlineExceededError()
+			}
+
+			ctx, cancel := context.WithDeadline(c.Request().Context(), deadline)
+			defer cancel()
+			c.SetRequest(c.Request().WithContext(ctx))
+
+			return next(c)
+		}
+	}
+}
+
+// stepContext gives the next of remaining sequential steps an equal share of
+// the time left before the deadline of ctx, so one slow step cannot starve
+// the rest; time a step leaves unused passes on to the steps after it.
+func stepContext(ctx context.Context, remaining int) (context.Context, context.CancelFunc, error) {
+	deadline, ok := ctx.Deadline()
+	if !ok || remaining < 1 {
+		return ctx, func() {}, nil
+	}
+	budget := time.Until(deadline)
+	if budget <= 0 {
+		return nil, nil, errDeadlineExceeded
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, budget/time.Duration(remaining))
+	return ctx, cancel, nil
+}
+
+// setDeadlineHeader passes the deadline of an upstream request on to the
+// upstream as an absolute X-Request-Deadline.
+func setDeadlineHeader(req *http.Request) {
+	req.Header.Del(headerGRPCTimeout)
+	if deadline, ok := req.Context().Deadline(); ok {
+		req.Header.Set(headerRequestDeadline, deadline.UTC().Format(time.RFC3339Nano))
+	}
+}
@@ -0,0 +1,120 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseGRPCTimeout(t *testing.T) {
+	timeout, err := parseGRPCTimeout("1500m")
+	if assert.NoError(t, err) {
+		assert.Equal(t, 1500*time.Millisecond, timeout)
+	}
+	timeout, err = parseGRPCTimeout("2S")
+	if assert.NoError(t, err) {
+		assert.Equal(t, 2*time.Second, timeout)
+	}
+
+	for _, value := range []string{"", "S", "10x", "123456789S", "-1S"} {
+		_, err := parseGRPCTimeout(value)
+		assert.Error(t, err, value)
+	}
+}
+
+func TestParseRequestDeadline(t *testing.T) {
+	now := time.Date(2026, 10, 14, 12, 0, 0, 0, time.UTC)
+
+	header := http.Header{}
+	_, ok, err := parseRequestDeadline(header, now)
+	assert.NoError(t, err)
+	assert.False(t, ok)
+
+	header.Set(headerGRPCTimeout, "2S")
+	deadline, ok, err := parseRequestDeadline(header, now)
+	if assert.NoError(t, err) && assert.True(t, ok) {
+		assert.Equal(t, now.Add(2*time.Second), deadline)
+	}
+
+	// the absolute deadline wins over the relative timeout
+	header.Set(headerRequestDeadline, "2026-10-14T12:00:00.5Z")
+	deadline, ok, err = parseRequestDeadline(header, now)
+	if assert.NoError(t, err) && assert.True(t, ok) {
+		assert.Equal(t, now.Add(500*time.Millisecond), deadline)
+	}
+
+	header.Set(headerRequestDeadline, "tomorrow")
+	_, _, err = parseRequestDeadline(header, now)
+	assert.Error(t, err)
+}
+
+func TestRequestDeadline(t *testing.T) {
+	var deadline time.Time
+	handler := requestDeadline()(func(c echo.Context) error {
+		deadline, _ = c.Request().Context().Deadline()
+		return c.NoContent(http.StatusOK)
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/keywords", nil)
+	req.Header.Set(headerGRPCTimeout, "2S")
+	if assert.NoError(t, handler(e.NewContext(req, httptest.NewRecorder()))) {
+		assert.WithinDuration(t, time.Now().Add(2*time.Second), deadline, time.Second)
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/keywords", nil)
+	req.Header.Set(headerRequestDeadline, time.Now().Add(-time.Second).Format(time.RFC3339Nano))
+	err := handler(e.NewContext(req, httptest.NewRecorder()))
+	if assert.Error(t, err) {
+		assert.Equal(t, codeDeadlineExceeded, err.(*apiError).Code)
+		assert.False(t, err.(*apiError).Retryable)
+	}
+}
+
+func TestStepContext(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	stepCtx, stepCancel, err := stepContext(ctx, 3)
+	if assert.NoError(t, err) {
+		defer stepCancel()
+		deadline, _ := stepCtx.Deadline()
+		assert.WithinDuration(t, time.Now().Add(time.Second), deadline, 100*time.Millisecond)
+	}
+
+	expired, expiredCancel := context.WithDeadline(context.Background(), time.Now().Add(-time.Second))
+	defer expiredCancel()
+	_, _, err = stepContext(expired, 1)
+	assert.Equal(t, errDeadlineExceeded, err)
+}
+
+func TestRunAnalysesDeadlineExceeded(t *testing.T) {
+	upstream := newBatchUpstream(t)
+	defer upstream.Close()
+	defer func(url string) { urlRake = url }(urlRake)
+	urlRake = upstream.URL
+
+	ctx, cancel := context.WithDeadline(context.Background(), time.Now().Add(-time.Second))
+	defer cancel()
+
+	results := runAnalyses(ctx, "curie", []string{"keywords"}, "")
+	if assert.NotNil(t, results["keywords"].Error) {
+		assert.Equal(t, codeDeadlineExceeded, results["keywords"].Error.Code)
+	}
+}
+
+func TestSetDeadlineHeader(t *testing.T) {
+	deadline := time.Date(2026, 10, 14, 12, 0, 0, 0, time.UTC)
+	ctx, cancel := context.WithDeadline(context.Background(), deadline)
+	defer cancel()
+
+	req, _ := http.NewRequestWithContext(ctx, http.MethodPost, "http://upstream/keywords", nil)
+	req.Header.Set(headerGRPCTimeout, "2S")
+	setDeadlineHeader(req)
+	assert.Equal(t, "2026-10-14T12:00:00Z", req.Header.Get(headerRequestDeadline))
+	assert.Empty(t, req.Header.Get(headerGRPCTimeout))
+}
@@ -0,0 +1,105 @@
+// author: Gary A. Stafford
+// site: https://programmaticponderings.com
+// license: MIT License
+// purpose: NLP microservices: nlp-client route deprecation
+// modified: 2026-10-14
+
+package main
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/labstack/gommon/log"
+)
+
+const (
+	headerDeprecation = "Deprecation"
+	headerSunset      = "Sunset"
+)
+
+// deprecation marks a route for retirement. Since is when it was deprecated,
+// Sunset, if set, when it will stop being served, and Successor, if set, the
+// route that replaces it.
+type deprecation struct {
+	Since     time.Time
+	Sunset    time.Time
+	Successor string
+}
+
+// deprecations lists the deprecated routes of the registry, by routeKey, e.g.
+//
+//	routeKey(http.MethodPost, "/keywords"): {Since: time.Date(2026, 10, 1, 0, 0, 0, 0, time.UTC)},
+var deprecations = map[string]deprecation{}
+
+func routeKey(method, path string) string {
+	return method + " " + path
+}
+
+// headers sets the Deprecation (RFC 9745), Sunset (RFC 8594), and successor
+// Link headers of a deprecated route.
+func (d deprecation) headers(h http.Header) {
+	h.Set(headerDeprecation, "@"+strconv.FormatInt(d.Since.Unix(), 10))
+	if !d.Sunset.IsZero() {
+		h.Set(headerSunset, d.Sunset.UTC().Format(http.TimeFormat))
+	}
+	if d.Successor != "" {
+		h.Add("Link", "<"+d.Successor+`>; rel="successor-version"`)
+	}
+}
+
+// deprecated emits the deprecation headers of a route and logs who still
+// calls it, by API key fingerprint and client address, so its remaining
+// callers can be found before it is retired.
+func deprecated(d deprecation) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			d.headers(c.Response().Header())
+
+			caller := ""
+			if key := c.Request().Header.Get("X-API-Key"); key != "" {
+				caller = keyID(key)
+			}
+			e.Logger.Warnj(log.JSON{
+				"message":   "deprecated route called",
+				"method":    c.Request().Method,
+				"path":      c.Path(),
+				"key_id":    caller,
+				"client_ip": c.RealIP(),
+			})
+			metrics.Count("deprecated_requests_total", 1, map[string]string{"route": c.Path()})
+
+			return next(c)
+		}
+	}
+}
+
+// routeMiddleware returns the chain of a route: its group's chain, led by
+// the deprecation headers if the route is deprecated, by the query text of a
+// GET variant, by the text of a /simple route, and by the checks of the
+// integrations' own credentials.
+func routeMiddleware(r route, chains map[string][]echo.MiddlewareFunc) []echo.MiddlewareFunc {
+	var lead []echo.MiddlewareFunc
+	if d, ok := deprecations[routeKey(r.method, r.path)]; ok {
+		lead = append(lead, deprecated(d))
+	}
+	if isQueryVariant(r) {
+		lead = append(lead, queryText())
+	}
+	if isSimpleRoute(r) {
+		lead = append(lead, simpleText())
+	}
+	if isSlackRoute(r) {
+		lead = append(lead, verifySlack())
+	}
+	if isGitHubRoute(r) {
+		lead = append(lead, verifyGitHub())
+	}
+	if isInboundRoute(r) {
+		lead = append(lead, verifyInbound())
+	}
+
+	return append(lead, chains[r.group]...)
+}
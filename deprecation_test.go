@@ -0,0 +1,45 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDeprecatedHeaders(t *testing.T) {
+	d := deprecation{
+		Since:     time.Date(2026, 10, 1, 0, 0, 0, 0, time.UTC),
+		Sunset:    time.Date(2027, 4, 1, 0, 0, 0, 0, time.UTC),
+		Successor: "/v2/tokens",
+	}
+	req := httptest.NewRequest(http.MethodPost, "/tokens", nil)
+	req.Header.Set("X-API-Key", "secret")
+	w := httptest.NewRecorder()
+	c := e.NewContext(req, w)
+	c.SetPath("/tokens")
+
+	handler := deprecated(d)(func(c echo.Context) error {
+		return c.NoContent(http.StatusOK)
+	})
+	if assert.NoError(t, handler(c)) {
+		assert.Equal(t, "@1790812800", w.Header().Get(headerDeprecation))
+		assert.Equal(t, "Thu, 01 Apr 2027 00:00:00 GMT", w.Header().Get(headerSunset))
+		assert.Equal(t, `</v2/tokens>; rel="successor-version"`, w.Header().Get("Link"))
+	}
+}
+
+func TestRouteMiddleware(t *testing.T) {
+	defer func(d map[string]deprecation) { deprecations = d }(deprecations)
+	deprecations = map[string]deprecation{
+		routeKey(http.MethodPost, "/tokens"): {Since: time.Now()},
+	}
+	chains := map[string][]echo.MiddlewareFunc{groupAPI: {apiKeyAuth()}}
+
+	assert.Len(t, routeMiddleware(route{http.MethodPost, "/tokens", getTokens, groupAPI}, chains), 2)
+	assert.Len(t, routeMiddleware(route{http.MethodPost, "/entities", getEntities, groupAPI}, chains), 1)
+	assert.Len(t, chains[groupAPI], 1)
+}
@@ -0,0 +1,268 @@
+// author: Gary A. Stafford
+// site: https://programmaticponderings.com
+// license: MIT License
+// purpose: NLP microservices: nlp-client email parsing and analysis
+// modified: 2026-10-14
+
+package main
+
+import (
+	"bufio"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net/http"
+	"net/mail"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+	"golang.org/x/text/encoding/htmlindex"
+)
+
+// redact PII from emails before they are stored with ?record=true
+var emailRedactPII = getEnv("EMAIL_REDACT_PII", "true")
+
+// email is the parsed content of an RFC 5322 message.
+type email struct {
+	From    string
+	To      string
+	Date    string
+	Subject string
+	Body    string
+}
+
+// emailSection is the analysis of one part of an email.
+type emailSection struct {
+	Text     string                    `json:"text"`
+	Status   string                    `json:"status"`
+	Analyses map[string]analysisResult `json:"analyses"`
+	Meta     *responseMeta             `json:"_meta,omitempty"`
+}
+
+var (
+	// "On Mon, 12 Oct 2026 at 09:14, Marie Curie <marie@example.com> wrote:"
+	replyAttribution = regexp.MustCompile(`(?i)^\s*on\s.+\swrote:\s*$`)
+	// Outlook's separators above the quoted message
+	replySeparator = regexp.MustCompile(`(?i)^\s*(-{2,}\s*original message\s*-{2,}|_{10,})\s*$`)
+)
+
+var wordDecoder = &mime.WordDecoder{CharsetReader: charsetReader}
+
+func charsetReader(charset string, input io.Reader) (io.Reader, error) {
+	enc, err := htmlindex.Get(charset)
+	if err != nil {
+		return nil, fmt.Errorf("unsupported charset: %s", charset)
+	}
+
+	return enc.NewDecoder().Reader(input), nil
+}
+
+// parseEmail reads the headers of a message and the text of its body,
+// preferring the text/plain alternative to text/html, with quoted replies removed.
+func parseEmail(r io.Reader) (*email, error) {
+	msg, err := mail.ReadMessage(r)
+	if err != nil {
+		return nil, err
+	}
+	decode := func(name string) string {
+		value := msg.Header.Get(name)
+		if decoded, err := wordDecoder.DecodeHeader(value); err == nil {
+			return decoded
+		}
+		return value
+	}
+
+	body, err := partText(msg.Header, msg.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	return &email{
+		From:    decode("From"),
+		To:      decode("To"),
+		Date:    msg.Header.Get("Date"),
+		Subject: decode("Subject"),
+		Body:    stripQuotedReplies(body),
+	}, nil
+}
+
+// header is either a message's header or a MIME part's.
+type header interface {
+	Get(key string) string
+}
+
+// partText returns the text of a message part, walking multipart bodies for
+// their best text alternative and decoding transfer encodings and charsets.
+func partText(h header, body io.Reader) (string, error) {
+	mediaType, params, err := mime.ParseMediaType(h.Get("Content-Type"))
+	if err != nil {
+		mediaType, params = "text/plain", map[string]string{}
+	}
+
+	switch strings.ToLower(h.Get("Content-Transfer-Encoding")) {
+	case "quoted-printable":
+		body = quotedprintable.NewReader(body)
+	case "base64":
+		body = base64.NewDecoder(base64.StdEncoding, newlineStripper{body})
+	}
+
+	if strings.HasPrefix(mediaType, "multipart/") {
+		return multipartText(mediaType, params["boundary"], body)
+	}
+	if mediaType != "text/plain" && mediaType != "text/html" {
+		return "", nil
+	}
+
+	if charset := params["charset"]; charset != "" && !strings.EqualFold(charset, "utf-8") && !strings.EqualFold(charset, "us-ascii") {
+		if body, err = charsetReader(charset, body); err != nil {
+			return "", err
+		}
+	}
+	data, err := ioutil.ReadAll(body)
+	if err != nil {
+		return "", err
+	}
+	if mediaType == "text/html" {
+		return stripHTML(string(data))
+	}
+
+	return string(data), nil
+}
+
+func multipartText(mediaType, boundary string, body io.Reader) (string, error) {
+	reader := multipart.NewReader(body, boundary)
+	var plain, html string
+	var texts []string
+	for {
+		part, err := reader.NextPart()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return "", err
+		}
+		// attachments are not part of the message text
+		if disposition, _, _ := mime.ParseMediaType(part.Header.Get("Content-Disposition")); disposition == "attachment" {
+			continue
+		}
+		text, err := partText(part.Header, part)
+		if err != nil {
+			return "", err
+		}
+		partType, _, _ := mime.ParseMediaType(part.Header.Get("Content-Type"))
+		switch {
+		case mediaType == "multipart/alternative" && (partType == "text/plain" || partType == ""):
+			plain = text
+		case mediaType == "multipart/alternative" && partType == "text/html":
+			html = text
+		case text != "":
+			texts = append(texts, text)
+		}
+	}
+
+	if mediaType == "multipart/alternative" {
+		if plain != "" {
+			return plain, nil
+		}
+		return html, nil
+	}
+
+	return strings.Join(texts, "\n\n"), nil
+}
+
+// newlineStripper drops the line breaks of a base64 body, which the decoder does not expect.
+type newlineStripper struct {
+	r io.Reader
+}
+
+func (s newlineStripper) Read(p []byte) (int, error) {
+	n, err := s.r.Read(p)
+	kept := 0
+	for _, b := range p[:n] {
+		if b != '\r' && b != '\n' {
+			p[kept] = b
+			kept++
+		}
+	}
+
+	return kept, err
+}
+
+// stripQuotedReplies removes the quoted text of earlier messages: ">"
+// quoted lines, and everything from a reply attribution or an Outlook
+// "Original Message" separator onwards.
+func stripQuotedReplies(body string) string {
+	var lines []string
+	scanner := bufio.NewScanner(strings.NewReader(body))
+	scanner.Buffer(make([]byte, 64*1024), len(body)+1)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if replyAttribution.MatchString(line) || replySeparator.MatchString(line) {
+			break
+		}
+		if strings.HasPrefix(strings.TrimSpace(line), ">") {
+			continue
+		}
+		lines = append(lines, line)
+	}
+
+	return strings.TrimSpace(strings.Join(lines, "\n"))
+}
+
+func analyzeSection(c echo.Context, text string, analyses []string) emailSection {
+	results := runAnalyses(c.Request().Context(), text, analyses, c.Request().Header.Get("X-API-Key"))
+	section := emailSection{Text: text, Status: analysesStatus(results), Analyses: results}
+	if wantsMeta(c) {
+		section.Meta = analysesMeta(text, analyses, results)
+	}
+
+	return section
+}
+
+// postAnalyzeEmail parses an uploaded RFC 5322 message and runs the requested
+// analyses on its subject and body separately, optionally storing the
+// message, with PII redacted, through /record.
+func postAnalyzeEmail(c echo.Context) error {
+	analyses, err := parseAnalyses(c.QueryParam("analyses"))
+	if err != nil {
+		return validationError(err.Error())
+	}
+	msg, err := parseEmail(c.Request().Body)
+	if err != nil {
+		return validationError(err.Error())
+	}
+
+	sections := map[string]emailSection{}
+	if msg.Subject != "" {
+		sections["subject"] = analyzeSection(c, msg.Subject, analyses)
+	}
+	if msg.Body != "" {
+		sections["body"] = analyzeSection(c, msg.Body, analyses)
+	}
+
+	recorded := false
+	if record, _ := strconv.ParseBool(c.QueryParam("record")); record {
+		text := strings.TrimSpace(msg.Subject + "\n\n" + msg.Body)
+		if redact, _ := strconv.ParseBool(emailRedactPII); redact {
+			text = redactPII(text)
+		}
+		if err := storeRecord(c, text, nil); err != nil {
+			return err
+		}
+		recorded = true
+	}
+
+	return c.JSON(http.StatusOK, struct {
+		From     string                  `json:"from,omitempty"`
+		To       string                  `json:"to,omitempty"`
+		Date     string                  `json:"date,omitempty"`
+		Sections map[string]emailSection `json:"sections"`
+		Recorded bool                    `json:"recorded"`
+	}{msg.From, msg.To, msg.Date, sections, recorded})
+}
@@ -0,0 +1,106 @@
+package main
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const multipartEmail = "From: =?UTF-8?Q?Marie_Curie?= <marie@example.com>\r\n" +
+	"To: pierre@example.com\r\n" +
+	"Date: Mon, 12 Oct 2026 09:14:00 +0000\r\n" +
+	"Subject: =?ISO-8859-1?Q?Radium_=E9chantillons?=\r\n" +
+	"MIME-Version: 1.0\r\n" +
+	"Content-Type: multipart/mixed; boundary=outer\r\n" +
+	"\r\n" +
+	"--outer\r\n" +
+	"Content-Type: multipart/alternative; boundary=inner\r\n" +
+	"\r\n" +
+	"--inner\r\n" +
+	"Content-Type: text/plain; charset=iso-8859-1\r\n" +
+	"Content-Transfer-Encoding: quoted-printable\r\n" +
+	"\r\n" +
+	"Pierre, the samples arrive Friday. Call me at 555-867-5309.=E9\r\n" +
+	"\r\n" +
+	"On Sun, 11 Oct 2026 at 18:02, Pierre Curie <pierre@example.com> wrote:\r\n" +
+	"> When do the samples arrive?\r\n" +
+	"--inner\r\n" +
+	"Content-Type: text/html\r\n" +
+	"\r\n" +
+	"<p>Pierre, the samples arrive <b>Friday</b>.</p>\r\n" +
+	"--inner--\r\n" +
+	"--outer\r\n" +
+	"Content-Type: application/pdf\r\n" +
+	"Content-Disposition: attachment; filename=manifest.pdf\r\n" +
+	"Content-Transfer-Encoding: base64\r\n" +
+	"\r\n" +
+	"JVBERi0xLjQK\r\n" +
+	"--outer--\r\n"
+
+func TestParseEmail(t *testing.T) {
+	msg, err := parseEmail(strings.NewReader(multipartEmail))
+	if assert.NoError(t, err) {
+		assert.Equal(t, "Marie Curie <marie@example.com>", msg.From)
+		assert.Equal(t, "Radium échantillons", msg.Subject)
+		assert.Equal(t, "Pierre, the samples arrive Friday. Call me at 555-867-5309.é", msg.Body)
+	}
+}
+
+func TestParseEmailHTMLOnly(t *testing.T) {
+	raw := "Subject: Hello\r\n" +
+		"Content-Type: text/html; charset=utf-8\r\n" +
+		"Content-Transfer-Encoding: base64\r\n" +
+		"\r\n" +
+		"PHA+UGllcnJlLCB0aGUgc2FtcGxlcyBh\r\ncnJpdmUgRnJpZGF5LjwvcD4=\r\n"
+
+	msg, err := parseEmail(strings.NewReader(raw))
+	if assert.NoError(t, err) {
+		assert.Equal(t, "Pierre, the samples arrive Friday.", msg.Body)
+	}
+}
+
+func TestStripQuotedReplies(t *testing.T) {
+	body := "Thanks, see below.\n> earlier\n\nMarie\n\n-----Original Message-----\nFrom: Pierre\nSent: Sunday"
+
+	assert.Equal(t, "Thanks, see below.\n\nMarie", stripQuotedReplies(body))
+}
+
+func TestPostAnalyzeEmail(t *testing.T) {
+	upstream := newBatchUpstream(t)
+	defer upstream.Close()
+	var recorded string
+	dynamo := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		recorded = string(body)
+	}))
+	defer dynamo.Close()
+	defer func(rake, dynamoURL string) { urlRake, urlDynamo = rake, dynamoURL }(urlRake, urlDynamo)
+	urlRake = upstream.URL
+	urlDynamo = dynamo.URL
+
+	req := httptest.NewRequest(http.MethodPost, "/analyze/email?analyses=keywords&record=true", strings.NewReader(multipartEmail))
+	w := httptest.NewRecorder()
+
+	if assert.NoError(t, postAnalyzeEmail(e.NewContext(req, w))) {
+		var body struct {
+			Sections map[string]struct {
+				Analyses map[string]struct {
+					Result []string `json:"result"`
+				} `json:"analyses"`
+			} `json:"sections"`
+			Recorded bool `json:"recorded"`
+		}
+		if assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &body)) {
+			assert.Equal(t, []string{"RADIUM ÉCHANTILLONS"}, body.Sections["subject"].Analyses["keywords"].Result)
+			assert.Len(t, body.Sections["body"].Analyses["keywords"].Result, 1)
+			assert.True(t, body.Recorded)
+		}
+		assert.Contains(t, recorded, "Call me at [REDACTED]")
+		assert.NotContains(t, recorded, "555-867-5309")
+	}
+}
@@ -0,0 +1,104 @@
+// author: Gary A. Stafford
+// site: https://programmaticponderings.com
+// license: MIT License
+// purpose: NLP microservices: nlp-client emotion classification
+// modified: 2026-10-14
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+)
+
+var (
+	// emotion backend answering POST /emotions with scores by emotion; unset disables /emotions
+	urlEmotions = upstreamURL(getEnv("EMOTION_ENDPOINT", ""))
+	// comma-separated emotions returned, each scored 0 when the backend does not score it
+	emotionLabels = getEnv("EMOTION_LABELS", "joy,anger,fear,sadness,surprise,disgust")
+)
+
+type emotionScore struct {
+	Label string  `json:"label"`
+	Score float64 `json:"score"`
+}
+
+type emotionsResult struct {
+	Emotions []emotionScore `json:"emotions"`
+	// the emotion with the highest score, empty when none scores above 0
+	Dominant string `json:"dominant"`
+}
+
+func emotionsNotConfigured() *apiError {
+	return newAPIError(http.StatusNotFound, codeNotFound, "emotion classification is not configured")
+}
+
+// parseEmotions reads the scores of a backend answering with an object of
+// scores by emotion, {"joy": 0.9}, or with a list of them, as classification
+// pipelines do, [{"label": "joy", "score": 0.9}], by lowercased emotion.
+func parseEmotions(body json.RawMessage) (map[string]float64, error) {
+	var list []emotionScore
+	if err := json.Unmarshal(body, &list); err == nil {
+		scores := map[string]float64{}
+		for _, score := range list {
+			scores[strings.ToLower(score.Label)] = score.Score
+		}
+		return scores, nil
+	}
+	var object map[string]float64
+	if err := json.Unmarshal(body, &object); err != nil {
+		return nil, fmt.Errorf("emotion backend answered with neither a list nor an object of scores")
+	}
+	scores := make(map[string]float64, len(object))
+	for label, score := range object {
+		scores[strings.ToLower(label)] = score
+	}
+
+	return scores, nil
+}
+
+// classifyEmotions scores the emotions of EMOTION_LABELS, highest first, and
+// leaves out those the backend scores that are not among them.
+func classifyEmotions(scores map[string]float64) emotionsResult {
+	result := emotionsResult{Emotions: []emotionScore{}}
+	for _, label := range splitList(emotionLabels) {
+		label = strings.ToLower(label)
+		result.Emotions = append(result.Emotions, emotionScore{label, scores[label]})
+	}
+	sort.SliceStable(result.Emotions, func(i, j int) bool { return result.Emotions[i].Score > result.Emotions[j].Score })
+	if len(result.Emotions) > 0 && result.Emotions[0].Score > 0 {
+		result.Dominant = result.Emotions[0].Label
+	}
+
+	return result
+}
+
+// postEmotions scores a text across the emotion taxonomy with the backend.
+func postEmotions(c echo.Context) error {
+	if urlEmotions == "" {
+		return emotionsNotConfigured()
+	}
+	var body struct {
+		Text string `json:"text"`
+	}
+	if err := json.NewDecoder(c.Request().Body).Decode(&body); err != nil {
+		return validationError(err.Error())
+	}
+
+	req := c.Request()
+	answer, err := postUpstream(req.Context(), urlEmotions+"/emotions", body.Text, req.Header.Get("X-API-Key"))
+	if err != nil {
+		return upstreamError(err)
+	}
+	scores, err := parseEmotions(answer)
+	if err != nil {
+		return upstreamError(err)
+	}
+
+	return c.JSON(http.StatusOK, classifyEmotions(scores))
+}
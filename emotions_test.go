@@ -0,0 +1,57 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseEmotions(t *testing.T) {
+	scores, err := parseEmotions([]byte(`[{"label":"Anger","score":0.7},{"label":"joy","score":0.1}]`))
+	if assert.NoError(t, err) {
+		assert.Equal(t, map[string]float64{"anger": 0.7, "joy": 0.1}, scores)
+	}
+	scores, err = parseEmotions([]byte(`{"fear": 0.4}`))
+	if assert.NoError(t, err) {
+		assert.Equal(t, map[string]float64{"fear": 0.4}, scores)
+	}
+	_, err = parseEmotions([]byte(`"angry"`))
+	assert.Error(t, err)
+}
+
+func TestClassifyEmotions(t *testing.T) {
+	defer func(labels string) { emotionLabels = labels }(emotionLabels)
+	emotionLabels = "joy, anger, fear"
+	result := classifyEmotions(map[string]float64{"anger": 0.7, "joy": 0.1, "love": 0.9})
+	assert.Equal(t, emotionsResult{[]emotionScore{{"anger", 0.7}, {"joy", 0.1}, {"fear", 0}}, "anger"}, result,
+		"emotions outside the taxonomy are left out")
+	assert.Empty(t, classifyEmotions(nil).Dominant)
+}
+
+func TestPostEmotions(t *testing.T) {
+	post := func() (*httptest.ResponseRecorder, error) {
+		req := httptest.NewRequest(http.MethodPost, "/emotions", strings.NewReader(`{"text": "My order never arrived!"}`))
+		w := httptest.NewRecorder()
+		return w, postEmotions(e.NewContext(req, w))
+	}
+	_, err := post()
+	assert.Equal(t, emotionsNotConfigured(), err)
+
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/emotions", r.URL.Path)
+		_, _ = w.Write([]byte(`{"anger": 0.8, "sadness": 0.3}`))
+	}))
+	defer backend.Close()
+	defer func(url string) { urlEmotions = url }(urlEmotions)
+	urlEmotions = backend.URL
+
+	w, err := post()
+	if assert.NoError(t, err) {
+		assert.JSONEq(t, `{"emotions": [{"label": "anger", "score": 0.8}, {"label": "sadness", "score": 0.3},
+			{"label": "joy", "score": 0}, {"label": "fear", "score": 0}, {"label": "surprise", "score": 0},
+			{"label": "disgust", "score": 0}], "dominant": "anger"}`, w.Body.String())
+	}
+}
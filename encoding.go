@@ -0,0 +1,255 @@
+// author: Gary A. Stafford
+// site: https://programmaticponderings.com
+// license: MIT License
+// purpose: NLP microservices: nlp-client shared JSON response writer
+// modified: 2026-10-14
+
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+)
+
+// answer GET requests with a ?callback= as JSONP, for legacy pages that embed
+// responses with a script tag; off by default, as any page can then read the
+// responses its visitors can
+var jsonpEnabled = getEnv("JSONP_ENABLED", "false")
+
+const mimeApplicationJavaScript = "application/javascript; charset=UTF-8"
+
+// callbackName matches the JavaScript identifiers, or dotted paths of them, a JSONP callback may be named.
+var callbackName = regexp.MustCompile(`^[A-Za-z_$][A-Za-z0-9_$]*(\.[A-Za-z_$][A-Za-z0-9_$]*)*$`)
+
+// jsonEncoding is how a request's JSON response is written.
+type jsonEncoding struct {
+	// renames keys, or nil to keep them as they are
+	rename func(string) string
+	// true to indent, false to compact, or nil to keep the handler's
+	pretty *bool
+	// the JSONP callback to wrap the response in, if any
+	callback string
+}
+
+// parsePretty returns what ?pretty= asks for; a bare ?pretty indents, as echo's own does.
+func parsePretty(c echo.Context) (*bool, error) {
+	values, ok := c.QueryParams()["pretty"]
+	if !ok {
+		return nil, nil
+	}
+	pretty := true
+	if len(values) > 0 && values[0] != "" {
+		var err error
+		if pretty, err = strconv.ParseBool(values[0]); err != nil {
+			return nil, fmt.Errorf("invalid pretty: %s", values[0])
+		}
+	}
+
+	return &pretty, nil
+}
+
+// requestEncoding returns the encoding a request asks for with ?naming=,
+// ?pretty=, and ?callback=.
+func requestEncoding(c echo.Context) (jsonEncoding, error) {
+	var encoding jsonEncoding
+	naming, err := parseNaming(c.QueryParam("naming"))
+	if err != nil {
+		return encoding, err
+	}
+	if naming != "" {
+		c.Set(namingContextKey, naming)
+	}
+	switch responseNaming(c) {
+	case namingCamel:
+		encoding.rename = toCamel
+	case namingSnake:
+		encoding.rename = toSnake
+	}
+	if encoding.pretty, err = parsePretty(c); err != nil {
+		return encoding, err
+	}
+	if callback := c.QueryParam("callback"); callback != "" && envBool(jsonpEnabled) && c.Request().Method == http.MethodGet {
+		if !callbackName.MatchString(callback) {
+			return encoding, fmt.Errorf("invalid callback: %s", callback)
+		}
+		encoding.callback = callback
+	}
+
+	return encoding, nil
+}
+
+// encode rewrites a JSON document in the encoding, or returns it as it is
+// if it does not parse.
+func (enc jsonEncoding) encode(body []byte) []byte {
+	encoded := body
+	if enc.rename != nil {
+		if renamed, err := renameKeys(body, enc.rename); err == nil {
+			encoded = renamed
+		}
+	}
+	var b bytes.Buffer
+	switch {
+	case enc.pretty != nil && *enc.pretty:
+		if json.Indent(&b, encoded, "", "  ") == nil {
+			encoded = b.Bytes()
+		}
+	case enc.pretty != nil || enc.rename != nil:
+		if json.Compact(&b, encoded) == nil {
+			encoded = b.Bytes()
+		}
+	}
+	encoded = bytes.TrimRight(encoded, "\n")
+	if enc.callback != "" {
+		// the comment keeps a callback from being read as the start of a
+		// file by content sniffing, as in Rosetta Flash
+		return []byte(fmt.Sprintf("/**/ %s(%s);\n", enc.callback, encoded))
+	}
+
+	return append(encoded, '\n')
+}
+
+// jsonWriter writes the JSON and NDJSON responses of a handler in a
+// request's encoding: JSON is buffered until the handler completes, NDJSON is
+// rewritten a line at a time so streams still flush as they go, and any other
+// content type passes through.
+type jsonWriter struct {
+	http.ResponseWriter
+	encoding jsonEncoding
+	// "json", "ndjson", or empty to pass through, decided at WriteHeader
+	mode string
+	body bytes.Buffer
+}
+
+func (w *jsonWriter) WriteHeader(code int) {
+	contentType, _, _ := mime.ParseMediaType(w.Header().Get(echo.HeaderContentType))
+	switch {
+	case contentType == mimeApplicationNDJSON && w.encoding.rename != nil:
+		w.mode = "ndjson"
+	case contentType == echo.MIMEApplicationJSON || strings.HasSuffix(contentType, "+json"):
+		w.mode = "json"
+		w.Header().Del(echo.HeaderContentLength)
+		if w.encoding.callback != "" {
+			w.Header().Set(echo.HeaderContentType, mimeApplicationJavaScript)
+			w.Header().Set(echo.HeaderXContentTypeOptions, "nosniff")
+		}
+	}
+	w.ResponseWriter.WriteHeader(code)
+}
+
+func (w *jsonWriter) Write(p []byte) (int, error) {
+	if w.mode == "" {
+		return w.ResponseWriter.Write(p)
+	}
+	w.body.Write(p)
+	if w.mode == "ndjson" {
+		return len(p), w.writeLines(false)
+	}
+
+	return len(p), nil
+}
+
+// writeLines renames and writes the complete lines buffered, or every line at the end.
+func (w *jsonWriter) writeLines(end bool) error {
+	for {
+		line, err := w.body.ReadBytes('\n')
+		if err == io.EOF && !end {
+			// keep the partial line for the next write
+			rest := append([]byte{}, line...)
+			w.body.Reset()
+			w.body.Write(rest)
+			return nil
+		}
+		if len(bytes.TrimSpace(line)) > 0 {
+			newline := bytes.HasSuffix(line, []byte("\n"))
+			if renamed, renameErr := renameKeys(line, w.encoding.rename); renameErr == nil {
+				line = renamed
+				if newline {
+					line = append(line, '\n')
+				}
+			}
+			if _, writeErr := w.ResponseWriter.Write(line); writeErr != nil {
+				return writeErr
+			}
+		}
+		if err == io.EOF {
+			return nil
+		}
+	}
+}
+
+func (w *jsonWriter) Flush() {
+	if w.mode == "ndjson" {
+		_ = w.writeLines(false)
+	}
+	if w.mode == "json" {
+		return
+	}
+	if flusher, ok := w.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+// finish writes the buffered JSON in the encoding.
+func (w *jsonWriter) finish() error {
+	switch w.mode {
+	case "ndjson":
+		return w.writeLines(true)
+	case "json":
+		if w.body.Len() == 0 {
+			return nil
+		}
+		_, err := w.ResponseWriter.Write(w.encoding.encode(w.body.Bytes()))
+		return err
+	}
+
+	return nil
+}
+
+// responseEncoding writes JSON responses renamed to the casing of the
+// request's ?naming= or of JSON_NAMING, indented or compacted as ?pretty=
+// asks, and wrapped in the ?callback= of a JSONP request.
+func responseEncoding() echo.MiddlewareFunc {
+	if _, err := parseNaming(jsonNaming); err != nil {
+		e.Logger.Errorf("ignoring JSON_NAMING: %v", err)
+		jsonNaming = ""
+	}
+
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			if strings.HasPrefix(unversioned(c.Path()), "/integrations/") {
+				// integrations answer in their platform's schema, whatever the naming
+				return next(c)
+			}
+			encoding, err := requestEncoding(c)
+			if err != nil {
+				return validationError(err.Error())
+			}
+			if encoding.rename == nil && encoding.pretty == nil && encoding.callback == "" {
+				return next(c)
+			}
+
+			writer := &jsonWriter{ResponseWriter: c.Response().Writer, encoding: encoding}
+			c.Response().Writer = writer
+			defer func() { c.Response().Writer = writer.ResponseWriter }()
+			err = next(c)
+			if err != nil {
+				// render the error now, so it is encoded too
+				c.Error(err)
+			}
+			if finishErr := writer.finish(); finishErr != nil {
+				e.Logger.Error(finishErr)
+			}
+
+			return err
+		}
+	}
+}
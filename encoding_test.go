@@ -0,0 +1,142 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestResponseEncodingNaming(t *testing.T) {
+	handler := responseEncoding()(func(c echo.Context) error {
+		return c.JSON(http.StatusOK, map[string]interface{}{"failed_analyses": []string{"summary_text"}})
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/?naming=camelCase", nil)
+	w := httptest.NewRecorder()
+	if assert.NoError(t, handler(e.NewContext(req, w))) {
+		assert.Equal(t, `{"failedAnalyses":["summary_text"]}`+"\n", w.Body.String())
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/", nil)
+	w = httptest.NewRecorder()
+	if assert.NoError(t, handler(e.NewContext(req, w))) {
+		assert.Equal(t, `{"failed_analyses":["summary_text"]}`+"\n", w.Body.String(), "keys are as they are by default")
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/?naming=kebab", nil)
+	err := handler(e.NewContext(req, httptest.NewRecorder()))
+	if assert.Error(t, err) {
+		assert.Equal(t, http.StatusBadRequest, err.(*apiError).Status)
+	}
+}
+
+func TestResponseEncodingNamingGlobal(t *testing.T) {
+	defer func(naming string) { jsonNaming = naming }(jsonNaming)
+	jsonNaming = namingCamel
+	defer func(h echo.HTTPErrorHandler) { e.HTTPErrorHandler = h }(e.HTTPErrorHandler)
+	e.HTTPErrorHandler = httpErrorHandler
+	handler := responseEncoding()(func(c echo.Context) error {
+		return newAPIError(http.StatusNotFound, codeNotFound, "not found")
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set(echo.HeaderXRequestID, "r1")
+	w := httptest.NewRecorder()
+	c := e.NewContext(req, w)
+	c.Response().Header().Set(echo.HeaderXRequestID, "r1")
+	assert.Error(t, handler(c))
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	assert.JSONEq(t, `{"code":"NOT_FOUND","message":"not found","requestId":"r1","retryable":false}`, w.Body.String(),
+		"errors are renamed too")
+}
+
+func TestResponseEncodingNamingNDJSON(t *testing.T) {
+	handler := responseEncoding()(func(c echo.Context) error {
+		res := c.Response()
+		res.Header().Set(echo.HeaderContentType, mimeApplicationNDJSON)
+		res.WriteHeader(http.StatusOK)
+		_, _ = res.Write([]byte("{\"token_count\":1}\n{\"token_"))
+		res.Flush()
+		_, _ = res.Write([]byte("count\":2}\n"))
+		return nil
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/?naming=camelCase", nil)
+	w := httptest.NewRecorder()
+	if assert.NoError(t, handler(e.NewContext(req, w))) {
+		assert.Equal(t, "{\"tokenCount\":1}\n{\"tokenCount\":2}\n", w.Body.String())
+	}
+}
+
+func TestResponseEncodingNamingPretty(t *testing.T) {
+	handler := responseEncoding()(func(c echo.Context) error {
+		return c.JSON(http.StatusOK, map[string]int{"token_count": 1})
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/?naming=camelCase&pretty", nil)
+	w := httptest.NewRecorder()
+	if assert.NoError(t, handler(e.NewContext(req, w))) {
+		assert.Equal(t, "{\n  \"tokenCount\": 1\n}\n", w.Body.String())
+	}
+}
+
+func TestResponseEncodingPretty(t *testing.T) {
+	handler := responseEncoding()(func(c echo.Context) error {
+		return c.JSONBlob(http.StatusOK, []byte(`{"text": "Curie"}`))
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/?pretty=true", nil)
+	w := httptest.NewRecorder()
+	if assert.NoError(t, handler(e.NewContext(req, w))) {
+		assert.Equal(t, "{\n  \"text\": \"Curie\"\n}\n", w.Body.String(), "blobs passed through from upstreams are indented too")
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/?pretty=false", nil)
+	w = httptest.NewRecorder()
+	if assert.NoError(t, handler(e.NewContext(req, w))) {
+		assert.Equal(t, `{"text":"Curie"}`+"\n", w.Body.String())
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/?pretty=maybe", nil)
+	err := handler(e.NewContext(req, httptest.NewRecorder()))
+	if assert.Error(t, err) {
+		assert.Equal(t, http.StatusBadRequest, err.(*apiError).Status)
+	}
+}
+
+func TestResponseEncodingJSONP(t *testing.T) {
+	defer func(enabled string) { jsonpEnabled = enabled }(jsonpEnabled)
+	handler := responseEncoding()(func(c echo.Context) error {
+		return c.JSON(http.StatusOK, map[string]string{"text": "Curie"})
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/?callback=app.show", nil)
+	w := httptest.NewRecorder()
+	if assert.NoError(t, handler(e.NewContext(req, w))) {
+		assert.Equal(t, echo.MIMEApplicationJSONCharsetUTF8, w.Header().Get(echo.HeaderContentType), "JSONP is off by default")
+		assert.Equal(t, `{"text":"Curie"}`+"\n", w.Body.String())
+	}
+
+	jsonpEnabled = "true"
+	w = httptest.NewRecorder()
+	if assert.NoError(t, handler(e.NewContext(req, w))) {
+		assert.Equal(t, mimeApplicationJavaScript, w.Header().Get(echo.HeaderContentType))
+		assert.Equal(t, "nosniff", w.Header().Get(echo.HeaderXContentTypeOptions))
+		assert.Equal(t, `/**/ app.show({"text":"Curie"});`+"\n", w.Body.String())
+	}
+
+	req = httptest.NewRequest(http.MethodPost, "/?callback=show", nil)
+	w = httptest.NewRecorder()
+	if assert.NoError(t, handler(e.NewContext(req, w))) {
+		assert.Equal(t, `{"text":"Curie"}`+"\n", w.Body.String(), "only GET requests are answered as JSONP")
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/?callback=alert(1)", nil)
+	err := handler(e.NewContext(req, httptest.NewRecorder()))
+	if assert.Error(t, err) {
+		assert.Equal(t, http.StatusBadRequest, err.(*apiError).Status)
+	}
+}
@@ -0,0 +1,275 @@
+// author: Gary A. Stafford
+// site: https://programmaticponderings.com
+// license: MIT License
+// purpose: NLP microservices: nlp-client field-level encryption of records
+// modified: 2026-10-14
+
+package main
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/kms"
+	"github.com/aws/aws-sdk-go-v2/service/kms/types"
+	"github.com/labstack/echo/v4"
+)
+
+var (
+	// "off", "local" to wrap each record's data key with RECORD_ENCRYPTION_KEY,
+	// or "kms" to have AWS KMS issue and unwrap the data keys
+	recordEncryption = getEnv("RECORD_ENCRYPTION", "off")
+	// base64 256-bit key of the "local" mode
+	recordEncryptionKey = getEnv("RECORD_ENCRYPTION_KEY", "")
+	// KMS key ID, ARN, or alias of the "kms" mode
+	recordKMSKeyID = getEnv("RECORD_KMS_KEY_ID", "")
+	// comma-separated record fields stored encrypted
+	recordEncryptedFields = getEnv("RECORD_ENCRYPTED_FIELDS", "text")
+
+	recordKeysOnce sync.Once
+	recordKeys     keyWrapper
+	recordKeysErr  error
+)
+
+// keyWrapper issues the data key each record is encrypted with, and unwraps
+// the stored data keys to decrypt records.
+type keyWrapper interface {
+	// dataKey returns a new data key, in plaintext and wrapped for storage.
+	dataKey(ctx context.Context) ([]byte, []byte, error)
+	unwrap(ctx context.Context, wrapped []byte) ([]byte, error)
+	keyID() string
+}
+
+// localKeys wraps data keys with a key held by the client.
+type localKeys struct {
+	aead cipher.AEAD
+}
+
+func newLocalKeys(encoded string) (*localKeys, error) {
+	key, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil || len(key) != 32 {
+		return nil, errors.New("RECORD_ENCRYPTION_KEY must be a base64 256-bit key")
+	}
+	aead, err := newAEAD(key)
+	if err != nil {
+		return nil, err
+	}
+
+	return &localKeys{aead}, nil
+}
+
+func (k *localKeys) dataKey(ctx context.Context) ([]byte, []byte, error) {
+	key := make([]byte, 32)
+	if _, err := rand.Read(key); err != nil {
+		return nil, nil, err
+	}
+	wrapped, err := seal(k.aead, key, nil)
+
+	return key, wrapped, err
+}
+
+func (k *localKeys) unwrap(ctx context.Context, wrapped []byte) ([]byte, error) {
+	return open(k.aead, wrapped, nil)
+}
+
+func (k *localKeys) keyID() string {
+	return "local"
+}
+
+// kmsAPI is the part of the KMS client used, so tests can fake it.
+type kmsAPI interface {
+	GenerateDataKey(ctx context.Context, params *kms.GenerateDataKeyInput, optFns ...func(*kms.Options)) (*kms.GenerateDataKeyOutput, error)
+	Decrypt(ctx context.Context, params *kms.DecryptInput, optFns ...func(*kms.Options)) (*kms.DecryptOutput, error)
+}
+
+// kmsKeys has AWS KMS issue and unwrap the data keys, so the key wrapping
+// them never leaves KMS.
+type kmsKeys struct {
+	client kmsAPI
+	key    string
+}
+
+func (k *kmsKeys) dataKey(ctx context.Context) ([]byte, []byte, error) {
+	out, err := k.client.GenerateDataKey(ctx, &kms.GenerateDataKeyInput{KeyId: &k.key, KeySpec: types.DataKeySpecAes256})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return out.Plaintext, out.CiphertextBlob, nil
+}
+
+func (k *kmsKeys) unwrap(ctx context.Context, wrapped []byte) ([]byte, error) {
+	out, err := k.client.Decrypt(ctx, &kms.DecryptInput{CiphertextBlob: wrapped, KeyId: &k.key})
+	if err != nil {
+		return nil, err
+	}
+
+	return out.Plaintext, nil
+}
+
+func (k *kmsKeys) keyID() string {
+	return k.key
+}
+
+// loadRecordKeys returns the key wrapper of RECORD_ENCRYPTION, created on first use.
+func loadRecordKeys() (keyWrapper, error) {
+	recordKeysOnce.Do(func() {
+		switch recordEncryption {
+		case "local":
+			recordKeys, recordKeysErr = newLocalKeys(recordEncryptionKey)
+		case "kms":
+			if recordKMSKeyID == "" {
+				recordKeysErr = errors.New("RECORD_KMS_KEY_ID is required with RECORD_ENCRYPTION=kms")
+				return
+			}
+			cfg, err := config.LoadDefaultConfig(context.Background())
+			if err != nil {
+				recordKeysErr = err
+				return
+			}
+			recordKeys = &kmsKeys{client: kms.NewFromConfig(cfg), key: recordKMSKeyID}
+		default:
+			recordKeysErr = fmt.Errorf("unsupported RECORD_ENCRYPTION: %s", recordEncryption)
+		}
+	})
+
+	return recordKeys, recordKeysErr
+}
+
+func newAEAD(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+
+	return cipher.NewGCM(block)
+}
+
+// seal encrypts plaintext with a random nonce, which it prefixes to the ciphertext.
+func seal(aead cipher.AEAD, plaintext, additional []byte) ([]byte, error) {
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+
+	return aead.Seal(nonce, nonce, plaintext, additional), nil
+}
+
+func open(aead cipher.AEAD, sealed, additional []byte) ([]byte, error) {
+	if len(sealed) < aead.NonceSize() {
+		return nil, errors.New("ciphertext too short")
+	}
+
+	return aead.Open(nil, sealed[:aead.NonceSize()], sealed[aead.NonceSize():], additional)
+}
+
+// recordEnvelope is stored in a record's "encryption" field, with what is
+// needed to decrypt its encrypted fields.
+type recordEnvelope struct {
+	KeyID string `json:"key_id"`
+	// the record's data key, wrapped by the key
+	DataKey []byte   `json:"data_key"`
+	Fields  []string `json:"fields"`
+}
+
+// encryptRecord replaces each encrypted field of a record by its JSON value
+// sealed under a data key of the record's own, bound to the field's name.
+func encryptRecord(c echo.Context, r *record) error {
+	keys, err := loadRecordKeys()
+	if err != nil {
+		return internalError(err)
+	}
+	key, wrapped, err := keys.dataKey(c.Request().Context())
+	if err != nil {
+		return upstreamError(err)
+	}
+	aead, err := newAEAD(key)
+	if err != nil {
+		return internalError(err)
+	}
+
+	envelope := recordEnvelope{KeyID: keys.keyID(), DataKey: wrapped, Fields: []string{}}
+	for _, name := range splitList(recordEncryptedFields) {
+		raw, ok := r.fields[name]
+		if !ok {
+			continue
+		}
+		sealed, err := seal(aead, raw, []byte(name))
+		if err != nil {
+			return internalError(err)
+		}
+		if err := r.set(name, sealed); err != nil {
+			return err
+		}
+		envelope.Fields = append(envelope.Fields, name)
+	}
+
+	return r.set("encryption", envelope)
+}
+
+// decryptRecord restores the encrypted fields of a stored record.
+func decryptRecord(ctx context.Context, keys keyWrapper, fields map[string]json.RawMessage) error {
+	var envelope recordEnvelope
+	raw, ok := fields["encryption"]
+	if !ok {
+		return nil
+	}
+	if err := json.Unmarshal(raw, &envelope); err != nil {
+		return err
+	}
+	if envelope.KeyID != keys.keyID() {
+		return fmt.Errorf("record encrypted under key %s", envelope.KeyID)
+	}
+	key, err := keys.unwrap(ctx, envelope.DataKey)
+	if err != nil {
+		return err
+	}
+	aead, err := newAEAD(key)
+	if err != nil {
+		return err
+	}
+	for _, name := range envelope.Fields {
+		var sealed []byte
+		if err := json.Unmarshal(fields[name], &sealed); err != nil {
+			return err
+		}
+		plaintext, err := open(aead, sealed, []byte(name))
+		if err != nil {
+			return fmt.Errorf("decrypting %s: %w", name, err)
+		}
+		fields[name] = plaintext
+	}
+	delete(fields, "encryption")
+
+	return nil
+}
+
+// postDecryptRecord decrypts a stored record, as read from the table, for
+// the holders of an admin key.
+func postDecryptRecord(c echo.Context) error {
+	if recordEncryption == "off" {
+		return newAPIError(http.StatusNotFound, codeNotFound, "record encryption is not configured")
+	}
+	keys, err := loadRecordKeys()
+	if err != nil {
+		return internalError(err)
+	}
+	var fields map[string]json.RawMessage
+	if err := json.NewDecoder(c.Request().Body).Decode(&fields); err != nil {
+		return validationError(err.Error())
+	}
+	if err := decryptRecord(c.Request().Context(), keys, fields); err != nil {
+		return validationError(err.Error())
+	}
+
+	return c.JSON(http.StatusOK, fields)
+}
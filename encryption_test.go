@@ -0,0 +1,97 @@
+package main
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/service/kms"
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeKMS wraps data keys with a local key, as KMS would with its own.
+type fakeKMS struct {
+	keys *localKeys
+}
+
+func (f *fakeKMS) GenerateDataKey(ctx context.Context, params *kms.GenerateDataKeyInput, optFns ...func(*kms.Options)) (*kms.GenerateDataKeyOutput, error) {
+	plaintext, wrapped, err := f.keys.dataKey(ctx)
+	return &kms.GenerateDataKeyOutput{Plaintext: plaintext, CiphertextBlob: wrapped, KeyId: params.KeyId}, err
+}
+
+func (f *fakeKMS) Decrypt(ctx context.Context, params *kms.DecryptInput, optFns ...func(*kms.Options)) (*kms.DecryptOutput, error) {
+	plaintext, err := f.keys.unwrap(ctx, params.CiphertextBlob)
+	return &kms.DecryptOutput{Plaintext: plaintext}, err
+}
+
+func testKey() string {
+	return base64.StdEncoding.EncodeToString([]byte(strings.Repeat("k", 32)))
+}
+
+// useRecordKeys encrypts records with keys for the rest of the test.
+func useRecordKeys(t *testing.T, mode string, keys keyWrapper) {
+	restoreMode, restoreKeys := recordEncryption, recordKeys
+	t.Cleanup(func() { recordEncryption, recordKeys = restoreMode, restoreKeys })
+	recordEncryption = mode
+	recordKeysOnce.Do(func() {})
+	recordKeys = keys
+}
+
+func TestNewLocalKeys(t *testing.T) {
+	_, err := newLocalKeys("c2hvcnQ=")
+	assert.Error(t, err)
+	_, err = newLocalKeys(testKey())
+	assert.NoError(t, err)
+}
+
+func TestPutDynamoEncryption(t *testing.T) {
+	local, err := newLocalKeys(testKey())
+	if !assert.NoError(t, err) {
+		return
+	}
+	for _, keys := range []keyWrapper{local, &kmsKeys{client: &fakeKMS{local}, key: "alias/records"}} {
+		useRecordKeys(t, "local", keys)
+		var records []map[string]interface{}
+		_, dynamo := newModerationUpstreams(t, &records)
+		defer func(url string) { urlDynamo = url }(urlDynamo)
+		urlDynamo = dynamo.URL
+
+		req := httptest.NewRequest(http.MethodPost, "/record", strings.NewReader(`{"text": "Marie Curie, 1 Rue Pierre", "id": 7}`))
+		c := e.NewContext(req, httptest.NewRecorder())
+		c.SetPath("/record")
+		assert.NoError(t, putDynamo(c))
+		dynamo.Close()
+		if !assert.Len(t, records, 1) {
+			return
+		}
+		stored := records[0]
+		assert.NotContains(t, stored["text"], "Curie", "the text is stored encrypted")
+		assert.Equal(t, float64(7), stored["id"], "other fields are stored as sent")
+
+		body, _ := json.Marshal(stored)
+		w := httptest.NewRecorder()
+		if assert.NoError(t, postDecryptRecord(e.NewContext(httptest.NewRequest(http.MethodPost, "/admin/records/decrypt", strings.NewReader(string(body))), w))) {
+			assert.JSONEq(t, `{"text": "Marie Curie, 1 Rue Pierre", "id": 7}`, w.Body.String())
+		}
+	}
+}
+
+func TestDecryptRecordTampered(t *testing.T) {
+	keys, _ := newLocalKeys(testKey())
+	useRecordKeys(t, "local", keys)
+
+	r := &record{fields: map[string]json.RawMessage{"text": json.RawMessage(`"secret"`), "note": json.RawMessage(`"other"`)}}
+	defer func(fields string) { recordEncryptedFields = fields }(recordEncryptedFields)
+	recordEncryptedFields = "text,note"
+	c := e.NewContext(httptest.NewRequest(http.MethodPost, "/record", nil), httptest.NewRecorder())
+	if !assert.NoError(t, encryptRecord(c, r)) {
+		return
+	}
+	r.fields["text"], r.fields["note"] = r.fields["note"], r.fields["text"]
+
+	assert.Error(t, decryptRecord(context.Background(), keys, r.fields), "fields are bound to their names")
+}
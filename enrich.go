@@ -0,0 +1,147 @@
+// author: Gary A. Stafford
+// site: https://programmaticponderings.com
+// license: MIT License
+// purpose: NLP microservices: nlp-client result enrichment
+// modified: 2026-10-14
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/labstack/echo/v4"
+)
+
+// enrichment is what ?enrich= asks to add to the items of a result: the
+// places of entities, geocoded, glossary matches of the tenant's glossary,
+// annotated or, with ?glossary=expand, expanded, and whether entities are
+// negated or hedged.
+type enrichment struct {
+	geo       bool
+	glossary  bool
+	expand    bool
+	assertion bool
+	tenant    string
+}
+
+// parseEnrich returns the enrichment a request asks for, or nil for none.
+func parseEnrich(c echo.Context) (*enrichment, error) {
+	en := &enrichment{tenant: c.Request().Header.Get(tenantHeader)}
+	for _, name := range splitList(c.QueryParam("enrich")) {
+		switch name {
+		case "geo":
+			if geocodeBackend == "" {
+				return nil, fmt.Errorf("geocoding is not configured")
+			}
+			en.geo = true
+		case "glossary":
+			en.glossary = true
+		case "assertion":
+			en.assertion = true
+		default:
+			return nil, fmt.Errorf("unknown enrichment: %s", name)
+		}
+	}
+	switch mode := c.QueryParam("glossary"); mode {
+	case "", "annotate":
+	case "expand":
+		en.expand = true
+	default:
+		return nil, fmt.Errorf("unknown glossary mode %q, want annotate or expand", mode)
+	}
+	if !en.geo && !en.glossary && !en.assertion {
+		return nil, nil
+	}
+
+	return en, nil
+}
+
+// applies reports whether any of the enrichment applies to the results of route.
+func (en *enrichment) applies(route string) bool {
+	return en != nil && (en.geo && enrichedRoutes[route] || en.glossary && glossaryRoutes[route] || en.assertion && route == "/entities")
+}
+
+// apply enriches a v1 or v2 result of route for text. Assertions go first,
+// while entities still have the text they were found as, and glossary
+// expansion next, so places are geocoded by their expanded names.
+func (en *enrichment) apply(ctx context.Context, route, text string, body json.RawMessage) (json.RawMessage, error) {
+	var err error
+	if en.assertion {
+		if body, err = assertEntities(route, text, body); err != nil {
+			return nil, err
+		}
+	}
+	if en.glossary {
+		if body, err = glossaries.apply(en.tenant, route, body, en.expand); err != nil {
+			return nil, err
+		}
+	}
+	if en.geo {
+		if body, err = enrichEntities(ctx, route, body); err != nil {
+			return nil, err
+		}
+	}
+
+	return body, nil
+}
+
+// itemField is a field set on a result item.
+type itemField struct {
+	name  string
+	value interface{}
+}
+
+// setItemFields sets fields of a JSON object, replacing those it has in place
+// and adding the others after them, so the rest of the item keeps the order
+// the upstream wrote it in.
+func setItemFields(raw json.RawMessage, fields ...itemField) (json.RawMessage, error) {
+	dec := json.NewDecoder(bytes.NewReader(raw))
+	if token, err := dec.Token(); err != nil || token != json.Delim('{') {
+		return nil, fmt.Errorf("result item is not an object")
+	}
+	var names []string
+	values := map[string]json.RawMessage{}
+	for dec.More() {
+		token, err := dec.Token()
+		if err != nil {
+			return nil, err
+		}
+		name, _ := token.(string)
+		var value json.RawMessage
+		if err := dec.Decode(&value); err != nil {
+			return nil, err
+		}
+		if _, seen := values[name]; !seen {
+			names = append(names, name)
+		}
+		values[name] = value
+	}
+	for _, field := range fields {
+		value, err := json.Marshal(field.value)
+		if err != nil {
+			return nil, err
+		}
+		if _, seen := values[field.name]; !seen {
+			names = append(names, field.name)
+		}
+		values[field.name] = value
+	}
+
+	var buf bytes.Buffer
+	buf.WriteByte('{')
+	for i, name := range names {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		key, _ := json.Marshal(name)
+		buf.Write(key)
+		buf.WriteByte(':')
+		buf.Write(values[name])
+	}
+	buf.WriteByte('}')
+
+	return buf.Bytes(), nil
+}
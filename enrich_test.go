@@ -0,0 +1,39 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseEnrich(t *testing.T) {
+	parse := func(query string) (*enrichment, error) {
+		req := httptest.NewRequest(http.MethodPost, "/entities?"+query, nil)
+		return parseEnrich(e.NewContext(req, httptest.NewRecorder()))
+	}
+
+	en, err := parse("")
+	assert.NoError(t, err)
+	assert.Nil(t, en)
+	en, err = parse("enrich=glossary&glossary=expand")
+	if assert.NoError(t, err) {
+		assert.True(t, en.glossary && en.expand)
+		assert.True(t, en.applies("/tokens"))
+		assert.False(t, en.applies("/keywords"))
+	}
+	_, err = parse("enrich=geo")
+	assert.Error(t, err, "geocoding is not configured")
+	_, err = parse("enrich=glossary&glossary=replace")
+	assert.Error(t, err)
+}
+
+func TestSetItemFields(t *testing.T) {
+	item, err := setItemFields([]byte(`{"text": "NLP", "label": "ORG"}`), itemField{"text", "NER"}, itemField{"original", "NLP"})
+	if assert.NoError(t, err) {
+		assert.Equal(t, `{"text":"NER","label":"ORG","original":"NLP"}`, string(item))
+	}
+	_, err = setItemFields([]byte(`"NLP"`), itemField{"text", "NER"})
+	assert.Error(t, err)
+}
@@ -0,0 +1,227 @@
+// author: Gary A. Stafford
+// site: https://programmaticponderings.com
+// license: MIT License
+// purpose: NLP microservices: nlp-client ensembles of providers
+// modified: 2026-10-14
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/labstack/echo/v4"
+)
+
+var (
+	// semicolon-separated analyses, each "analysis: name=url, ...", naming the
+	// providers that answer it alongside its default upstream, with the same API, e.g.
+	// "entities: spacy=http://spacy:8080, flair=http://flair:8080; keywords: yake=http://yake:8080"
+	ensembleProviders = getEnv("ENSEMBLE_PROVIDERS", "")
+	// "union", "intersection", or "vote" for the items most providers agree on,
+	// unless a request asks for another with ?strategy=
+	ensembleStrategy = getEnv("ENSEMBLE_STRATEGY", "vote")
+
+	ensembles = loadEnsembles()
+)
+
+// ensembleAnalyses are the analyses whose items can be matched across providers.
+var ensembleAnalyses = map[string]bool{"entities": true, "keywords": true}
+
+type ensembleProvider struct {
+	name string
+	url  string
+}
+
+// ensembleItem is an item found by one or more providers, with the share of
+// the providers that answered which found it.
+type ensembleItem struct {
+	Text      string   `json:"text"`
+	Label     string   `json:"label,omitempty"`
+	Agreement float64  `json:"agreement"`
+	Providers []string `json:"providers"`
+}
+
+type ensembleStatus struct {
+	Provider string    `json:"provider"`
+	Status   int       `json:"status"`
+	Error    *apiError `json:"error,omitempty"`
+}
+
+func parseEnsembles(config string) (map[string][]ensembleProvider, error) {
+	parsed := map[string][]ensembleProvider{}
+	for _, entry := range strings.Split(config, ";") {
+		if strings.TrimSpace(entry) == "" {
+			continue
+		}
+		parts := strings.SplitN(entry, ":", 2)
+		analysis := strings.TrimSpace(parts[0])
+		if len(parts) != 2 {
+			return nil, fmt.Errorf("invalid ensemble %q, want analysis: name=url, ...", entry)
+		}
+		if !ensembleAnalyses[analysis] {
+			return nil, fmt.Errorf("analysis %s cannot be ensembled", analysis)
+		}
+		for _, provider := range splitList(parts[1]) {
+			nameURL := strings.SplitN(provider, "=", 2)
+			if len(nameURL) != 2 || nameURL[0] == "" || nameURL[1] == "" {
+				return nil, fmt.Errorf("invalid ensemble provider %q, want name=url", provider)
+			}
+			parsed[analysis] = append(parsed[analysis], ensembleProvider{nameURL[0], upstreamURL(nameURL[1])})
+		}
+	}
+
+	return parsed, nil
+}
+
+func loadEnsembles() map[string][]ensembleProvider {
+	parsed, err := parseEnsembles(ensembleProviders)
+	if err != nil {
+		e.Logger.Errorf("ignoring ENSEMBLE_PROVIDERS: %v", err)
+		return nil
+	}
+
+	return parsed
+}
+
+// matchedItem returns the text and label of a result item, and the key items
+// are matched across results by: the text regardless of case, and the label.
+func matchedItem(raw json.RawMessage) (string, string, string, bool) {
+	var item interface{}
+	if json.Unmarshal(raw, &item) != nil {
+		return "", "", "", false
+	}
+	text, ok := itemText(item)
+	if !ok {
+		return "", "", "", false
+	}
+	var label string
+	if fields, isObject := item.(map[string]interface{}); isObject {
+		label, _ = fields["label"].(string)
+	}
+
+	return strings.ToLower(text) + "\x00" + label, text, label, true
+}
+
+// mergeEnsemble matches the items each provider found, by text regardless of
+// case and, for entities, label, and keeps those the strategy accepts, in
+// order of agreement.
+func mergeEnsemble(strategy string, providers []string, results [][]json.RawMessage) []ensembleItem {
+	var order []string
+	items := map[string]*ensembleItem{}
+	for i, result := range results {
+		found := map[string]bool{}
+		for _, raw := range result {
+			key, text, label, ok := matchedItem(raw)
+			if !ok || found[key] {
+				continue
+			}
+			found[key] = true
+			if items[key] == nil {
+				items[key] = &ensembleItem{Text: text, Label: label, Providers: []string{}}
+				order = append(order, key)
+			}
+			items[key].Providers = append(items[key].Providers, providers[i])
+		}
+	}
+
+	merged := []ensembleItem{}
+	for _, key := range order {
+		item := items[key]
+		item.Agreement = float64(len(item.Providers)) / float64(len(results))
+		switch {
+		case strategy == "intersection" && len(item.Providers) < len(results):
+		case strategy == "vote" && item.Agreement <= 0.5:
+		default:
+			merged = append(merged, *item)
+		}
+	}
+	sort.SliceStable(merged, func(i, j int) bool { return merged[i].Agreement > merged[j].Agreement })
+
+	return merged
+}
+
+// postEnsemble runs an analysis of a text with its default upstream and every
+// ensemble provider of it at once, and merges their items. Providers that fail
+// are left out of the agreement, and reported with a 207.
+func postEnsemble(c echo.Context) error {
+	analysis := c.Param("analysis")
+	providers, ok := ensembles[analysis]
+	if !ok {
+		return newAPIError(http.StatusNotFound, codeNotFound, fmt.Sprintf("no ensemble is configured for %s", analysis))
+	}
+	strategy := c.QueryParam("strategy")
+	if strategy == "" {
+		strategy = ensembleStrategy
+	}
+	switch strategy {
+	case "union", "intersection", "vote":
+	default:
+		return validationError(fmt.Sprintf("unknown strategy %q, want union, intersection, or vote", strategy))
+	}
+	var body struct {
+		Text string `json:"text"`
+	}
+	if err := json.NewDecoder(c.Request().Body).Decode(&body); err != nil {
+		return validationError(err.Error())
+	}
+
+	// the default upstream's endpoint, then each provider's at the same path
+	endpoint, _ := analysisEndpoint(analysis)
+	names, endpoints := []string{providerFor(analysis)}, []string{endpoint}
+	for _, provider := range providers {
+		names = append(names, provider.name)
+		endpoints = append(endpoints, provider.url+"/"+analysis)
+	}
+	results := make([]analysisResult, len(endpoints))
+	ctx, key := c.Request().Context(), c.Request().Header.Get("X-API-Key")
+	var wg sync.WaitGroup
+	for i := range endpoints {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			results[i] = runAnalysisWith(ctx, analysis, names[i], endpoints[i], body.Text, key)
+		}(i)
+	}
+	wg.Wait()
+
+	statuses := make([]ensembleStatus, len(results))
+	var answered []string
+	var items [][]json.RawMessage
+	var failed *apiError
+	for i, result := range results {
+		statuses[i] = ensembleStatus{names[i], result.Status, result.Error}
+		var list []json.RawMessage
+		if result.Error == nil {
+			if err := json.Unmarshal(result.Result, &list); err != nil {
+				result.Error = internalError(err)
+				statuses[i] = ensembleStatus{names[i], result.Error.Status, result.Error}
+			}
+		}
+		if result.Error != nil {
+			if failed == nil {
+				failed = result.Error
+			}
+			continue
+		}
+		answered = append(answered, names[i])
+		items = append(items, list)
+	}
+	if len(answered) == 0 {
+		return failed
+	}
+	code := http.StatusOK
+	if failed != nil {
+		code = http.StatusMultiStatus
+	}
+
+	return c.JSON(code, struct {
+		Strategy  string           `json:"strategy"`
+		Providers []ensembleStatus `json:"providers"`
+		Result    []ensembleItem   `json:"result"`
+	}{strategy, statuses, mergeEnsemble(strategy, answered, items)})
+}
@@ -0,0 +1,92 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/garystafford/nlp-client/mockupstream"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseEnsembles(t *testing.T) {
+	parsed, err := parseEnsembles("entities: spacy=http://spacy:8080, flair=http://flair:8080; keywords: yake=http://yake:8080")
+	if assert.NoError(t, err) {
+		assert.Len(t, parsed["entities"], 2)
+		assert.Equal(t, "flair", parsed["entities"][1].name)
+		assert.Equal(t, "yake", parsed["keywords"][0].name)
+	}
+
+	for _, config := range []string{"entities", "language: cld=http://cld:8080", "entities: http://spacy:8080"} {
+		_, err := parseEnsembles(config)
+		assert.Error(t, err, config)
+	}
+}
+
+func TestMergeEnsemble(t *testing.T) {
+	providers := []string{"prose-app", "spacy", "flair"}
+	results := [][]json.RawMessage{
+		{json.RawMessage(`{"text":"Marie Curie","label":"PERSON"}`), json.RawMessage(`{"text":"Paris","label":"GPE"}`)},
+		{json.RawMessage(`{"text":"marie curie","label":"PERSON"}`), json.RawMessage(`{"text":"Paris","label":"PERSON"}`)},
+		{json.RawMessage(`{"text":"Marie Curie","label":"PERSON"}`), json.RawMessage(`{"text":"Paris","label":"GPE"}`)},
+	}
+
+	union := mergeEnsemble("union", providers, results)
+	if assert.Len(t, union, 3) {
+		assert.Equal(t, ensembleItem{"Marie Curie", "PERSON", 1, providers}, union[0])
+		assert.Equal(t, "Paris", union[1].Text)
+		assert.Equal(t, []string{"prose-app", "flair"}, union[1].Providers)
+		assert.InDelta(t, 1.0/3, union[2].Agreement, 1e-9)
+	}
+	assert.Len(t, mergeEnsemble("vote", providers, results), 2)
+	assert.Len(t, mergeEnsemble("intersection", providers, results), 1)
+}
+
+func TestPostEnsemble(t *testing.T) {
+	prose := mockupstream.NewProse()
+	defer prose.Close()
+	spacy := mockupstream.NewProse()
+	defer spacy.Close()
+	spacy.SetFixture("/entities", mockupstream.Fixture{Status: http.StatusOK,
+		Body: `[{"text":"Marie Curie","label":"PERSON"},{"text":"Warsaw","label":"GPE"}]`})
+	down := mockupstream.New(nil)
+	defer down.Close()
+	defer func(url string, parsed map[string][]ensembleProvider) { urlProse, ensembles = url, parsed }(urlProse, ensembles)
+	urlProse = prose.URL
+	ensembles = map[string][]ensembleProvider{"entities": {{"spacy", spacy.URL}, {"flair", down.URL}}}
+
+	post := func(target string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, target, strings.NewReader(`{"text": "Marie Curie was born in Warsaw."}`))
+		w := httptest.NewRecorder()
+		c := e.NewContext(req, w)
+		c.SetPath("/ensemble/:analysis")
+		c.SetParamNames("analysis")
+		c.SetParamValues(strings.SplitN(strings.TrimPrefix(target, "/ensemble/"), "?", 2)[0])
+		if err := postEnsemble(c); err != nil {
+			httpErrorHandler(err, c)
+		}
+		return w
+	}
+
+	w := post("/ensemble/entities?strategy=intersection")
+	assert.Equal(t, http.StatusMultiStatus, w.Code, "the failed provider is reported")
+	var body struct {
+		Strategy  string           `json:"strategy"`
+		Providers []ensembleStatus `json:"providers"`
+		Result    []ensembleItem   `json:"result"`
+	}
+	if assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &body)) {
+		assert.Equal(t, "intersection", body.Strategy)
+		assert.Equal(t, []ensembleItem{{"Marie Curie", "PERSON", 1, []string{"prose-app", "spacy"}}}, body.Result)
+		if assert.Len(t, body.Providers, 3) {
+			assert.Equal(t, http.StatusOK, body.Providers[0].Status)
+			assert.Equal(t, "flair", body.Providers[2].Provider)
+			assert.NotNil(t, body.Providers[2].Error)
+		}
+	}
+
+	assert.Equal(t, http.StatusNotFound, post("/ensemble/keywords").Code)
+	assert.Equal(t, http.StatusBadRequest, post("/ensemble/entities?strategy=best").Code)
+}
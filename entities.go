@@ -0,0 +1,157 @@
+// author: Gary A. Stafford
+// site: https://programmaticponderings.com
+// license: MIT License
+// purpose: NLP microservices: nlp-client local entities merged with upstream entities
+// modified: 2026-10-14
+
+package main
+
+import (
+	"encoding/json"
+	"sort"
+)
+
+// of a local entity and an upstream entity that overlap, which is kept:
+// "local", "upstream", or "longest", with local entities kept on a tie
+var localEntityOverlap = getEnv("LOCAL_ENTITY_OVERLAP", "local")
+
+// localEntities reports whether results of route are merged with entities the
+// tenant's gazetteer or patterns find.
+func localEntities(tenant, route string) bool {
+	return route == "/entities" && (gazetteers.get(tenant) != nil || patterns.get(tenant) != nil)
+}
+
+// resolveLocal keeps, of overlapping local matches, the one of highest
+// priority, then the longest, then the first, and returns them in the order
+// of the text.
+func resolveLocal(found []localMatch) []localMatch {
+	sort.SliceStable(found, func(i, j int) bool {
+		a, b := found[i], found[j]
+		if a.priority != b.priority {
+			return a.priority > b.priority
+		}
+		if a.end-a.start != b.end-b.start {
+			return a.end-a.start > b.end-b.start
+		}
+		return a.start < b.start
+	})
+	var kept []localMatch
+	for _, m := range found {
+		overlaps := false
+		for _, k := range kept {
+			overlaps = overlaps || m.start < k.end && k.start < m.end
+		}
+		if !overlaps {
+			kept = append(kept, m)
+		}
+	}
+	sort.Slice(kept, func(i, j int) bool { return kept[i].start < kept[j].start })
+
+	return kept
+}
+
+// mergeLocalEntities adds the entities the tenant's gazetteer and patterns
+// find in text to an entities result, v1 or v2, each with its label and the
+// "source" that found it. Where a local and an upstream entity overlap,
+// LOCAL_ENTITY_OVERLAP decides which is kept; the result is in the order of
+// the text.
+func mergeLocalEntities(tenant, route, version, text string, body json.RawMessage) (json.RawMessage, error) {
+	if !localEntities(tenant, route) {
+		return body, nil
+	}
+	var found []localMatch
+	if g := gazetteers.get(tenant); g != nil {
+		found = append(found, g.find(text)...)
+	}
+	if p := patterns.get(tenant); p != nil {
+		found = append(found, p.find(text)...)
+	}
+	matches := resolveLocal(found)
+	if len(matches) == 0 {
+		return body, nil
+	}
+	var items []json.RawMessage
+	if err := json.Unmarshal(body, &items); err != nil {
+		return nil, err
+	}
+
+	type positioned struct {
+		start, end int
+		raw        json.RawMessage
+	}
+	var upstream []positioned
+	// v2 entities carry their offsets; v1 entities are found in the text as normalization finds them
+	loc := &locator{text: foldRunes(text)}
+	for _, raw := range items {
+		var entity v2Item
+		if err := json.Unmarshal(raw, &entity); err != nil {
+			return nil, err
+		}
+		if version != schemaV2 {
+			entity.span = loc.locate(entity.Text)
+		}
+		p := positioned{-1, -1, raw}
+		if entity.Offset != nil {
+			p.start, p.end = *entity.Offset, *entity.Offset+entity.Length
+		}
+		upstream = append(upstream, p)
+	}
+
+	// which of each overlapping local and upstream entity is kept
+	dropped := make([]bool, len(upstream))
+	var merged []positioned
+	runes := []rune(text)
+	for _, m := range matches {
+		keep := true
+		var overlapping []int
+		for i, u := range upstream {
+			if u.start < m.end && m.start < u.end {
+				overlapping = append(overlapping, i)
+				switch localEntityOverlap {
+				case "upstream":
+					keep = false
+				case "longest":
+					keep = keep && m.end-m.start >= u.end-u.start
+				}
+			}
+		}
+		if !keep {
+			continue
+		}
+		for _, i := range overlapping {
+			dropped[i] = true
+		}
+		entity := struct {
+			Text  string `json:"text"`
+			Label string `json:"label"`
+			*span
+			Source string `json:"source"`
+		}{Text: string(runes[m.start:m.end]), Label: m.label, Source: m.source}
+		if version == schemaV2 {
+			offset := m.start
+			entity.span = &span{Offset: &offset, Length: m.end - m.start}
+		}
+		raw, err := json.Marshal(entity)
+		if err != nil {
+			return nil, err
+		}
+		merged = append(merged, positioned{m.start, m.end, raw})
+	}
+	for i, u := range upstream {
+		if !dropped[i] {
+			merged = append(merged, u)
+		}
+	}
+	// entities not found in the text keep their place at the end
+	sort.SliceStable(merged, func(i, j int) bool {
+		a, b := merged[i].start, merged[j].start
+		return a >= 0 && (b < 0 || a < b)
+	})
+
+	result := make([]json.RawMessage, len(merged))
+	for i, p := range merged {
+		result[i] = p.raw
+	}
+
+	return json.Marshal(result)
+}
@@ -0,0 +1,38 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestResolveLocal(t *testing.T) {
+	assert.Equal(t, []localMatch{
+		{0, 8, "TICKET", "pattern", 1},
+		{10, 20, "PRODUCT", "gazetteer", 0},
+	}, resolveLocal([]localMatch{
+		{0, 12, "PRODUCT", "gazetteer", 0},
+		{10, 20, "PRODUCT", "gazetteer", 0},
+		{0, 8, "TICKET", "pattern", 1},
+		{12, 16, "ORDER_ID", "pattern", 0},
+	}), "of overlapping matches, the highest priority, then the longest, wins")
+}
+
+func TestMergeLocalEntitiesOverlap(t *testing.T) {
+	assert.NoError(t, uploadPatterns(t, "acme", `{"rules": [{"label": "TICKET", "pattern": "OPS-\\d+"}]}`))
+	text := "See OPS-42 today"
+	upstream := []byte(`[{"text":"OPS-42 today","label":"DATE"}]`)
+	defer func(overlap string) { localEntityOverlap = overlap }(localEntityOverlap)
+
+	for overlap, want := range map[string]string{
+		"local":    `[{"text":"OPS-42","label":"TICKET","source":"pattern"}]`,
+		"upstream": `[{"text":"OPS-42 today","label":"DATE"}]`,
+		"longest":  `[{"text":"OPS-42 today","label":"DATE"}]`,
+	} {
+		localEntityOverlap = overlap
+		merged, err := mergeLocalEntities("acme", "/entities", "", text, upstream)
+		if assert.NoError(t, err) {
+			assert.JSONEq(t, want, string(merged), overlap)
+		}
+	}
+}
@@ -0,0 +1,311 @@
+// author: Gary A. Stafford
+// site: https://programmaticponderings.com
+// license: MIT License
+// purpose: NLP microservices: nlp-client structured errors
+// modified: 2026-10-14
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+)
+
+// Machine-readable error codes returned in every error response.
+const (
+	codeValidationFailed    = "VALIDATION_FAILED"
+	codeUnauthorized        = "UNAUTHORIZED"
+	codeForbidden           = "FORBIDDEN"
+	codeNotFound            = "NOT_FOUND"
+	codeMethodNotAllowed    = "METHOD_NOT_ALLOWED"
+	codeConflict            = "CONFLICT"
+	codePayloadTooLarge     = "PAYLOAD_TOO_LARGE"
+	codeRateLimited         = "RATE_LIMITED"
+	codeQuotaExceeded       = "QUOTA_EXCEEDED"
+	codeOverloaded          = "OVERLOADED"
+	codeUpstreamUnavailable = "UPSTREAM_UNAVAILABLE"
+	codeUpstreamTimeout     = "UPSTREAM_TIMEOUT"
+	codeUpstreamError       = "UPSTREAM_ERROR"
+	codeDeadlineExceeded    = "DEADLINE_EXCEEDED"
+	codeContentRejected     = "CONTENT_REJECTED"
+	codeResidencyViolation  = "RESIDENCY_VIOLATION"
+	codeInternalError       = "INTERNAL_ERROR"
+)
+
+// apiError is the structured error body returned to callers. The cause is
+// logged but never serialized, so internal addresses do not leak.
+type apiError struct {
+	Status    int    `json:"-"`
+	Code      string `json:"code"`
+	Message   string `json:"message"`
+	RequestID string `json:"request_id,omitempty"`
+	Retryable bool   `json:"retryable"`
+	cause     error
+}
+
+func newAPIError(status int, code, message string) *apiError {
+	return &apiError{Status: status, Code: code, Message: message, Retryable: isRetryable(status)}
+}
+
+func (err *apiError) Error() string {
+	return fmt.Sprintf("%s: %s", err.Code, err.Message)
+}
+
+func (err *apiError) Unwrap() error {
+	return err.cause
+}
+
+func (err *apiError) withCause(cause error) *apiError {
+	err.cause = cause
+	return err
+}
+
+func validationError(message string) *apiError {
+	return newAPIError(http.StatusBadRequest, codeValidationFailed, message)
+}
+
+func internalError(cause error) *apiError {
+	return newAPIError(http.StatusInternalServerError, codeInternalError, "internal error").withCause(cause)
+}
+
+const mimeApplicationProblemJSON = "application/problem+json"
+
+var (
+	// "json" for the native error body or "problem" for RFC 7807; callers may
+	// also ask for either with the Accept header
+	errorFormat      = getEnv("ERROR_FORMAT", "json")
+	errorTypeBaseURI = getEnv("ERROR_TYPE_BASE_URI", "https://nlp.example.com/problems/")
+)
+
+// problem is an RFC 7807 problem details body, extended with the native error fields.
+type problem struct {
+	Type      string `json:"type"`
+	Title     string `json:"title"`
+	Status    int    `json:"status"`
+	Detail    string `json:"detail"`
+	Instance  string `json:"instance,omitempty"`
+	Code      string `json:"code"`
+	RequestID string `json:"request_id,omitempty"`
+	Retryable bool   `json:"retryable"`
+}
+
+func newProblem(ae apiError, instance string) problem {
+	return problem{
+		Type:      errorTypeBaseURI + strings.ToLower(strings.ReplaceAll(ae.Code, "_", "-")),
+		Title:     http.StatusText(ae.Status),
+		Status:    ae.Status,
+		Detail:    ae.Message,
+		Instance:  instance,
+		Code:      ae.Code,
+		RequestID: ae.RequestID,
+		Retryable: ae.Retryable,
+	}
+}
+
+// wantsProblem reports whether the error should be rendered as problem+json.
+func wantsProblem(accept string) bool {
+	if strings.Contains(accept, mimeApplicationProblemJSON) {
+		return true
+	}
+	if strings.Contains(accept, echo.MIMEApplicationJSON) {
+		return false
+	}
+
+	return errorFormat == "problem"
+}
+
+// maximum bytes of an upstream error body read for its message
+const maxUpstreamErrorBody = 64 << 10
+
+// upstreamStatusError is returned when an upstream answers with a non-2xx status.
+type upstreamStatusError struct {
+	status int
+	body   []byte
+}
+
+func (err *upstreamStatusError) Error() string {
+	return fmt.Sprintf("upstream returned %d", err.status)
+}
+
+func newUpstreamStatusError(resp *http.Response) *upstreamStatusError {
+	body, _ := ioutil.ReadAll(io.LimitReader(resp.Body, maxUpstreamErrorBody))
+	return &upstreamStatusError{status: resp.StatusCode, body: body}
+}
+
+// maps upstream statuses, exactly or by class (e.g. 4xx), to the status returned to the caller
+var upstreamStatusMap = getEnv("UPSTREAM_STATUS_MAP",
+	"400=400,401=401,403=403,404=404,413=413,422=422,429=429,4xx=400,503=503,504=504,5xx=502")
+
+// mappedStatus returns the caller-facing status for an upstream status.
+func mappedStatus(config string, status int) int {
+	mapping := map[string]int{}
+	for _, entry := range strings.Split(config, ",") {
+		parts := strings.SplitN(strings.TrimSpace(entry), "=", 2)
+		if len(parts) != 2 {
+			continue
+		}
+		if to, err := strconv.Atoi(parts[1]); err == nil {
+			mapping[strings.ToLower(parts[0])] = to
+		}
+	}
+
+	if to, ok := mapping[strconv.Itoa(status)]; ok {
+		return to
+	}
+	if to, ok := mapping[fmt.Sprintf("%dxx", status/100)]; ok {
+		return to
+	}
+
+	return http.StatusBadGateway
+}
+
+// upstreamMessage returns the message of an upstream error body, if it has one.
+func upstreamMessage(body []byte) string {
+	var fields struct {
+		Message string `json:"message"`
+	}
+	if err := json.Unmarshal(body, &fields); err != nil {
+		return ""
+	}
+
+	return fields.Message
+}
+
+func mapUpstreamStatus(err *upstreamStatusError) *apiError {
+	status := mappedStatus(upstreamStatusMap, err.status)
+	switch {
+	case status < http.StatusInternalServerError:
+		// client errors propagate with the upstream's own message
+		message := upstreamMessage(err.body)
+		if message == "" {
+			message = http.StatusText(status)
+		}
+		return newAPIError(status, codeForStatus(status), message).withCause(err)
+	case status == http.StatusServiceUnavailable:
+		return newAPIError(status, codeUpstreamUnavailable, "upstream service unavailable").withCause(err)
+	case status == http.StatusGatewayTimeout:
+		return newAPIError(status, codeUpstreamTimeout, "upstream service timed out").withCause(err)
+	default:
+		return newAPIError(status, codeUpstreamError, "upstream service returned an error").withCause(err)
+	}
+}
+
+// upstreamError classifies a failed upstream call without exposing its address.
+func upstreamError(err error) *apiError {
+	var statusErr *upstreamStatusError
+	switch {
+	case errors.Is(err, context.DeadlineExceeded):
+		return newAPIError(http.StatusGatewayTimeout, codeUpstreamTimeout, "upstream service timed out").withCause(err)
+	case errors.Is(err, errUpstreamQueueFull):
+		return newAPIError(http.StatusServiceUnavailable, codeOverloaded, "upstream service overloaded").withCause(err)
+	case errors.As(err, &statusErr):
+		return mapUpstreamStatus(statusErr)
+	default:
+		return newAPIError(http.StatusBadGateway, codeUpstreamUnavailable, "upstream service unavailable").withCause(err)
+	}
+}
+
+func isRetryable(status int) bool {
+	switch status {
+	case http.StatusTooManyRequests, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	}
+
+	return false
+}
+
+func codeForStatus(status int) string {
+	switch status {
+	case http.StatusBadRequest, http.StatusUnprocessableEntity:
+		return codeValidationFailed
+	case http.StatusUnauthorized:
+		return codeUnauthorized
+	case http.StatusForbidden:
+		return codeForbidden
+	case http.StatusNotFound:
+		return codeNotFound
+	case http.StatusMethodNotAllowed:
+		return codeMethodNotAllowed
+	case http.StatusConflict:
+		return codeConflict
+	case http.StatusRequestEntityTooLarge:
+		return codePayloadTooLarge
+	case http.StatusTooManyRequests:
+		return codeRateLimited
+	case http.StatusServiceUnavailable:
+		return codeOverloaded
+	case http.StatusBadGateway:
+		return codeUpstreamError
+	case http.StatusGatewayTimeout:
+		return codeUpstreamTimeout
+	}
+
+	return codeInternalError
+}
+
+// toAPIError converts any handler or middleware error into an apiError.
+func toAPIError(err error) *apiError {
+	var ae *apiError
+	if errors.As(err, &ae) {
+		return ae
+	}
+
+	var he *echo.HTTPError
+	if errors.As(err, &he) {
+		message, ok := he.Message.(string)
+		// only plain string messages are safe to return; anything else may wrap internal errors
+		if !ok || he.Code >= http.StatusInternalServerError {
+			message = http.StatusText(he.Code)
+		}
+		return newAPIError(he.Code, codeForStatus(he.Code), message).withCause(err)
+	}
+
+	return internalError(err)
+}
+
+// requestID returns the ID of a request, as generated by the request_id
+// middleware or sent by the caller.
+func requestID(c echo.Context) string {
+	if id := c.Response().Header().Get(echo.HeaderXRequestID); id != "" {
+		return id
+	}
+
+	return c.Request().Header.Get(echo.HeaderXRequestID)
+}
+
+// httpErrorHandler renders every error as a structured JSON body.
+func httpErrorHandler(err error, c echo.Context) {
+	if c.Response().Committed {
+		return
+	}
+
+	ae := *toAPIError(err)
+	ae.RequestID = requestID(c)
+	if ae.Status >= http.StatusInternalServerError {
+		e.Logger.Errorf("request %s failed: %v", ae.RequestID, err)
+	}
+
+	switch {
+	case c.Request().Method == http.MethodHead:
+		err = c.NoContent(ae.Status)
+	case wantsProblem(c.Request().Header.Get(echo.HeaderAccept)):
+		var body []byte
+		if body, err = json.Marshal(newProblem(ae, c.Request().URL.Path)); err == nil {
+			err = c.Blob(ae.Status, mimeApplicationProblemJSON, body)
+		}
+	default:
+		err = c.JSON(ae.Status, ae)
+	}
+	if err != nil {
+		e.Logger.Error(err)
+	}
+}
@@ -0,0 +1,128 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestToAPIError(t *testing.T) {
+	ae := toAPIError(echo.NewHTTPError(http.StatusUnauthorized, "invalid key"))
+	assert.Equal(t, &apiError{Status: 401, Code: codeUnauthorized, Message: "invalid key"}, ae.withCause(nil))
+
+	ae = toAPIError(echo.NewHTTPError(http.StatusInternalServerError, errors.New("dial tcp 10.0.0.1:8081")))
+	assert.Equal(t, "Internal Server Error", ae.Message)
+
+	ae = toAPIError(errors.New("boom"))
+	assert.Equal(t, codeInternalError, ae.Code)
+	assert.False(t, ae.Retryable)
+}
+
+func TestUpstreamError(t *testing.T) {
+	ae := upstreamError(fmt.Errorf(`Post "http://10.0.0.1:8081/keywords": %w`, context.DeadlineExceeded))
+	assert.Equal(t, http.StatusGatewayTimeout, ae.Status)
+	assert.Equal(t, codeUpstreamTimeout, ae.Code)
+	assert.True(t, ae.Retryable)
+	assert.NotContains(t, ae.Message, "10.0.0.1")
+
+	ae = upstreamError(&upstreamStatusError{status: http.StatusInternalServerError})
+	assert.Equal(t, codeUpstreamError, ae.Code)
+}
+
+func TestHTTPErrorHandler(t *testing.T) {
+	req := httptest.NewRequest(http.MethodPost, "/keywords", nil)
+	req.Header.Set(echo.HeaderXRequestID, "abc123")
+	w := httptest.NewRecorder()
+	c := e.NewContext(req, w)
+
+	httpErrorHandler(upstreamError(errors.New("dial tcp [::1]:8081: connect: connection refused")), c)
+
+	assert.Equal(t, http.StatusBadGateway, w.Code)
+	assert.JSONEq(t, `{"code": "UPSTREAM_UNAVAILABLE", "message": "upstream service unavailable",
+		"request_id": "abc123", "retryable": true}`, w.Body.String())
+}
+
+func TestWantsProblem(t *testing.T) {
+	defer func(format string) { errorFormat = format }(errorFormat)
+
+	errorFormat = "json"
+	assert.False(t, wantsProblem(""))
+	assert.True(t, wantsProblem("application/problem+json"))
+
+	errorFormat = "problem"
+	assert.True(t, wantsProblem("*/*"))
+	assert.False(t, wantsProblem("application/json"))
+}
+
+func TestHTTPErrorHandlerProblem(t *testing.T) {
+	req := httptest.NewRequest(http.MethodPost, "/keywords", nil)
+	req.Header.Set(echo.HeaderAccept, mimeApplicationProblemJSON)
+	req.Header.Set(echo.HeaderXRequestID, "abc123")
+	w := httptest.NewRecorder()
+	c := e.NewContext(req, w)
+
+	httpErrorHandler(validationError("text is required"), c)
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Equal(t, mimeApplicationProblemJSON, w.Header().Get(echo.HeaderContentType))
+	assert.JSONEq(t, `{"type": "https://nlp.example.com/problems/validation-failed", "title": "Bad Request",
+		"status": 400, "detail": "text is required", "instance": "/keywords",
+		"code": "VALIDATION_FAILED", "request_id": "abc123", "retryable": false}`, w.Body.String())
+}
+
+func TestMappedStatus(t *testing.T) {
+	config := "400=400,422=422,4xx=400,503=503,5xx=502"
+
+	assert.Equal(t, 422, mappedStatus(config, 422))
+	assert.Equal(t, 400, mappedStatus(config, 418))
+	assert.Equal(t, 503, mappedStatus(config, 503))
+	assert.Equal(t, 502, mappedStatus(config, 500))
+	assert.Equal(t, 502, mappedStatus("", 404))
+}
+
+func TestGetKeywordsUpstreamClientError(t *testing.T) {
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		w.WriteHeader(http.StatusUnprocessableEntity)
+		_, _ = w.Write([]byte(`{"message": "text must not be empty"}`))
+	}))
+	defer upstream.Close()
+	defer func(url string) { urlRake = url }(urlRake)
+	urlRake = upstream.URL
+
+	req := httptest.NewRequest(http.MethodPost, "/keywords", nil)
+	c := e.NewContext(req, httptest.NewRecorder())
+
+	err := getKeywords(c)
+	if assert.Error(t, err) {
+		ae := err.(*apiError)
+		assert.Equal(t, http.StatusUnprocessableEntity, ae.Status)
+		assert.Equal(t, codeValidationFailed, ae.Code)
+		assert.Equal(t, "text must not be empty", ae.Message)
+	}
+}
+
+func TestGetKeywordsUpstreamServerError(t *testing.T) {
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "panic at 10.0.0.1", http.StatusInternalServerError)
+	}))
+	defer upstream.Close()
+	defer func(url string) { urlRake = url }(urlRake)
+	urlRake = upstream.URL
+
+	req := httptest.NewRequest(http.MethodPost, "/keywords", nil)
+	c := e.NewContext(req, httptest.NewRecorder())
+
+	err := getKeywords(c)
+	if assert.Error(t, err) {
+		ae := err.(*apiError)
+		assert.Equal(t, http.StatusBadGateway, ae.Status)
+		assert.Equal(t, "upstream service returned an error", ae.Message)
+	}
+}
@@ -0,0 +1,248 @@
+// author: Gary A. Stafford
+// site: https://programmaticponderings.com
+// license: MIT License
+// purpose: NLP microservices: nlp-client evaluation against gold annotations
+// modified: 2026-10-14
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"sync"
+
+	"github.com/labstack/echo/v4"
+)
+
+// most documents one evaluation may score
+var evaluateMaxDocuments = getEnv("EVALUATE_MAX_DOCUMENTS", "1000")
+
+// evaluationDocument is a text with the items a provider should find in it:
+// entities, as {"text", "label"}, or keywords, as strings.
+type evaluationDocument struct {
+	ID   string            `json:"id"`
+	Text string            `json:"text"`
+	Gold []json.RawMessage `json:"gold"`
+}
+
+type evaluatedItem struct {
+	Text  string `json:"text"`
+	Label string `json:"label,omitempty"`
+}
+
+// evaluationScores counts the items a provider found that are gold (true
+// positives), that are not (false positives), and the gold it missed (false
+// negatives). Precision without any items found, and recall without any gold,
+// are 1, since nothing was wrong.
+type evaluationScores struct {
+	TruePositives  int     `json:"true_positives"`
+	FalsePositives int     `json:"false_positives"`
+	FalseNegatives int     `json:"false_negatives"`
+	Precision      float64 `json:"precision"`
+	Recall         float64 `json:"recall"`
+	F1             float64 `json:"f1"`
+}
+
+type documentEvaluation struct {
+	ID string `json:"id"`
+	*evaluationScores
+	Missed   []evaluatedItem `json:"missed,omitempty"`
+	Spurious []evaluatedItem `json:"spurious,omitempty"`
+	Error    *apiError       `json:"error,omitempty"`
+	labels   map[string]*evaluationScores
+}
+
+func (s *evaluationScores) add(other evaluationScores) {
+	s.TruePositives += other.TruePositives
+	s.FalsePositives += other.FalsePositives
+	s.FalseNegatives += other.FalseNegatives
+}
+
+// score computes the precision, recall, and F1 of the counts, rounded for the response.
+func (s *evaluationScores) score() {
+	ratio := func(n, d int) float64 {
+		if d == 0 {
+			return 1
+		}
+		return float64(n) / float64(d)
+	}
+	precision := ratio(s.TruePositives, s.TruePositives+s.FalsePositives)
+	recall := ratio(s.TruePositives, s.TruePositives+s.FalseNegatives)
+	f1 := 0.0
+	if precision+recall > 0 {
+		f1 = 2 * precision * recall / (precision + recall)
+	}
+	s.Precision = math.Round(precision*1e4) / 1e4
+	s.Recall = math.Round(recall*1e4) / 1e4
+	s.F1 = math.Round(f1*1e4) / 1e4
+}
+
+// compareItems matches the items a provider found to the gold items, as
+// ensembles do, counting each distinct item once.
+func compareItems(id string, found, gold []json.RawMessage) documentEvaluation {
+	eval := documentEvaluation{ID: id, evaluationScores: &evaluationScores{}, labels: map[string]*evaluationScores{}}
+	label := func(name string) *evaluationScores {
+		if eval.labels[name] == nil {
+			eval.labels[name] = &evaluationScores{}
+		}
+		return eval.labels[name]
+	}
+	golden := map[string]evaluatedItem{}
+	for _, raw := range gold {
+		if key, text, name, ok := matchedItem(raw); ok {
+			golden[key] = evaluatedItem{text, name}
+		}
+	}
+	matched := map[string]bool{}
+	for _, raw := range found {
+		key, text, name, ok := matchedItem(raw)
+		if !ok || matched[key] {
+			continue
+		}
+		matched[key] = true
+		if _, ok := golden[key]; ok {
+			eval.TruePositives++
+			label(name).TruePositives++
+			continue
+		}
+		eval.FalsePositives++
+		label(name).FalsePositives++
+		eval.Spurious = append(eval.Spurious, evaluatedItem{text, name})
+	}
+	for _, raw := range gold {
+		key, _, _, ok := matchedItem(raw)
+		if !ok || matched[key] {
+			continue
+		}
+		matched[key] = true
+		item := golden[key]
+		eval.FalseNegatives++
+		label(item.Label).FalseNegatives++
+		eval.Missed = append(eval.Missed, item)
+	}
+	eval.score()
+
+	return eval
+}
+
+// evaluateDocument runs the analysis of a document, as its route would answer
+// it, with the request's result filters, and compares it with the gold.
+func evaluateDocument(ctx context.Context, analysis string, doc evaluationDocument, filter *resultFilter, key string) documentEvaluation {
+	result := runAnalysis(ctx, analysis, doc.Text, key)
+	if result.Error == nil {
+		if body, _, err := calibrate("/"+analysis, result.Result); err != nil {
+			result.Error = internalError(err)
+		} else {
+			result.Result = body
+		}
+	}
+	if result.Error == nil && filter != nil {
+		if body, err := filter.apply("/"+analysis, result.Result); err != nil {
+			result.Error = internalError(err)
+		} else {
+			result.Result = body
+		}
+	}
+	var found []json.RawMessage
+	if result.Error == nil {
+		if err := json.Unmarshal(result.Result, &found); err != nil {
+			result.Error = internalError(err)
+		}
+	}
+	if result.Error != nil {
+		return documentEvaluation{ID: doc.ID, Error: result.Error}
+	}
+
+	return compareItems(doc.ID, found, doc.Gold)
+}
+
+// postEvaluate scores the configured entity or keyword provider against gold
+// annotations: per document, and micro-averaged over the documents analyzed
+// and, for entities, by label.
+func postEvaluate(c echo.Context) error {
+	analysis := c.QueryParam("analysis")
+	if !ensembleAnalyses[analysis] {
+		return validationError("analysis must be entities or keywords")
+	}
+	filter, err := parseResultFilter(c)
+	if err != nil {
+		return validationError(err.Error())
+	}
+	var request struct {
+		Documents []evaluationDocument `json:"documents"`
+	}
+	if err := json.NewDecoder(c.Request().Body).Decode(&request); err != nil {
+		return validationError(err.Error())
+	}
+	switch max := envInt(evaluateMaxDocuments, 1000); {
+	case len(request.Documents) == 0:
+		return validationError("documents must not be empty")
+	case len(request.Documents) > max:
+		return newAPIError(http.StatusRequestEntityTooLarge, codePayloadTooLarge,
+			fmt.Sprintf("more than %d documents", max))
+	}
+	workers, err := strconv.Atoi(batchConcurrency)
+	if err != nil || workers < 1 {
+		workers = 1
+	}
+
+	ctx, key := c.Request().Context(), c.Request().Header.Get("X-API-Key")
+	evals := make([]documentEvaluation, len(request.Documents))
+	next := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range next {
+				evals[i] = evaluateDocument(ctx, analysis, request.Documents[i], filter, key)
+			}
+		}()
+	}
+	for i := range request.Documents {
+		next <- i
+	}
+	close(next)
+	wg.Wait()
+
+	total := evaluationScores{}
+	labels := map[string]*evaluationScores{}
+	failed := 0
+	for _, eval := range evals {
+		if eval.Error != nil {
+			failed++
+			continue
+		}
+		total.add(*eval.evaluationScores)
+		for name, scores := range eval.labels {
+			if name == "" {
+				continue
+			}
+			if labels[name] == nil {
+				labels[name] = &evaluationScores{}
+			}
+			labels[name].add(*scores)
+		}
+	}
+	if failed == len(evals) {
+		return evals[0].Error
+	}
+	total.score()
+	for _, scores := range labels {
+		scores.score()
+	}
+
+	return c.JSON(http.StatusOK, struct {
+		Analysis  string                       `json:"analysis"`
+		Provider  string                       `json:"provider"`
+		Documents int                          `json:"documents"`
+		Failed    int                          `json:"failed"`
+		Scores    evaluationScores             `json:"scores"`
+		Labels    map[string]*evaluationScores `json:"labels,omitempty"`
+		Results   []documentEvaluation         `json:"results"`
+	}{analysis, providerFor(analysis), len(evals), failed, total, labels, evals})
+}
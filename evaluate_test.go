@@ -0,0 +1,81 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/garystafford/nlp-client/mockupstream"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCompareItems(t *testing.T) {
+	found := []json.RawMessage{
+		json.RawMessage(`{"text":"Marie Curie","label":"PERSON"}`),
+		json.RawMessage(`{"text":"Paris","label":"PERSON"}`),
+		json.RawMessage(`{"text":"marie curie","label":"PERSON"}`),
+	}
+	gold := []json.RawMessage{
+		json.RawMessage(`{"text":"Marie Curie","label":"PERSON"}`),
+		json.RawMessage(`{"text":"Paris","label":"GPE"}`),
+	}
+
+	eval := compareItems("1", found, gold)
+	assert.Equal(t, evaluationScores{1, 1, 1, 0.5, 0.5, 0.5}, *eval.evaluationScores)
+	assert.Equal(t, []evaluatedItem{{"Paris", "GPE"}}, eval.Missed)
+	assert.Equal(t, []evaluatedItem{{"Paris", "PERSON"}}, eval.Spurious)
+	assert.Equal(t, 1, eval.labels["PERSON"].TruePositives)
+	assert.Equal(t, 1, eval.labels["GPE"].FalseNegatives)
+
+	empty := compareItems("2", nil, nil)
+	assert.Equal(t, 1.0, empty.F1, "nothing to find and nothing found is a perfect score")
+}
+
+func TestPostEvaluate(t *testing.T) {
+	rake := mockupstream.NewRake()
+	defer rake.Close()
+	defer func(url string) { urlRake = url }(urlRake)
+	urlRake = rake.URL
+
+	post := func(target, body string) (*httptest.ResponseRecorder, error) {
+		req := httptest.NewRequest(http.MethodPost, target, strings.NewReader(body))
+		w := httptest.NewRecorder()
+		c := e.NewContext(req, w)
+		c.SetPath("/evaluate")
+		return w, postEvaluate(c)
+	}
+
+	w, err := post("/evaluate?analysis=keywords", `{"documents": [
+		{"id": "a", "text": "Marie Curie won the Nobel Prize.", "gold": ["Nobel Prize", "radium"]},
+		{"id": "b", "text": "Marie Curie won the Nobel Prize.", "gold": ["nobel prize", "marie curie"]}
+	]}`)
+	if assert.NoError(t, err) {
+		var body struct {
+			Provider  string                 `json:"provider"`
+			Documents int                    `json:"documents"`
+			Scores    evaluationScores       `json:"scores"`
+			Labels    map[string]interface{} `json:"labels"`
+			Results   []struct {
+				F1     float64         `json:"f1"`
+				Missed []evaluatedItem `json:"missed"`
+			} `json:"results"`
+		}
+		if assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &body)) {
+			assert.Equal(t, "rake-app", body.Provider)
+			assert.Equal(t, 2, body.Documents)
+			assert.Equal(t, evaluationScores{3, 1, 1, 0.75, 0.75, 0.75}, body.Scores)
+			assert.Empty(t, body.Labels, "keywords have no labels")
+			if assert.Len(t, body.Results, 2) {
+				assert.Equal(t, []evaluatedItem{{Text: "radium"}}, body.Results[0].Missed)
+				assert.Equal(t, 1.0, body.Results[1].F1)
+			}
+		}
+	}
+
+	_, err = post("/evaluate?analysis=language", `{"documents": [{"text": "Marie Curie"}]}`)
+	assert.Error(t, err)
+	_, err = post("/evaluate?analysis=entities", `{"documents": []}`)
+	assert.Error(t, err)
+}
@@ -0,0 +1,216 @@
+// author: Gary A. Stafford
+// site: https://programmaticponderings.com
+// license: MIT License
+// purpose: NLP microservices: nlp-client Avro and Parquet batch exports
+// modified: 2026-10-14
+
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+	"github.com/linkedin/goavro/v2"
+	"github.com/xitongsys/parquet-go/writer"
+)
+
+const (
+	mimeAvro    = "application/avro"
+	mimeParquet = "application/vnd.apache.parquet"
+)
+
+// exportSchema is the Avro schema of a batch export, one record a document.
+// Fields are only ever added, with defaults, so tables defined over earlier
+// exports keep reading later ones.
+const exportSchema = `{
+  "type": "record", "name": "BatchResult", "namespace": "nlpclient",
+  "fields": [
+    {"name": "id", "type": "string"},
+    {"name": "status", "type": "string"},
+    {"name": "error", "type": ["null", "string"], "default": null},
+    {"name": "language", "type": ["null", "string"], "default": null},
+    {"name": "language_probability", "type": ["null", "double"], "default": null},
+    {"name": "keywords", "type": {"type": "array", "items": {"type": "record", "name": "Keyword",
+      "fields": [{"name": "text", "type": "string"}, {"name": "score", "type": "double"}]}}, "default": []},
+    {"name": "entities", "type": {"type": "array", "items": {"type": "record", "name": "Entity",
+      "fields": [{"name": "text", "type": "string"}, {"name": "label", "type": "string"}]}}, "default": []},
+    {"name": "token_count", "type": ["null", "long"], "default": null},
+    {"name": "sentence_count", "type": ["null", "long"], "default": null},
+    {"name": "failed_analyses", "type": {"type": "array", "items": "string"}, "default": []}
+  ]
+}`
+
+// exportRow is a batch result in the columns of exportSchema; the analyses
+// not asked for are null or empty.
+type exportRow struct {
+	ID                  string           `parquet:"name=id, type=BYTE_ARRAY, convertedtype=UTF8"`
+	Status              string           `parquet:"name=status, type=BYTE_ARRAY, convertedtype=UTF8"`
+	Error               *string          `parquet:"name=error, type=BYTE_ARRAY, convertedtype=UTF8, repetitiontype=OPTIONAL"`
+	Language            *string          `parquet:"name=language, type=BYTE_ARRAY, convertedtype=UTF8, repetitiontype=OPTIONAL"`
+	LanguageProbability *float64         `parquet:"name=language_probability, type=DOUBLE, repetitiontype=OPTIONAL"`
+	Keywords            []*exportKeyword `parquet:"name=keywords, type=LIST"`
+	Entities            []*exportEntity  `parquet:"name=entities, type=LIST"`
+	TokenCount          *int64           `parquet:"name=token_count, type=INT64, repetitiontype=OPTIONAL"`
+	SentenceCount       *int64           `parquet:"name=sentence_count, type=INT64, repetitiontype=OPTIONAL"`
+	FailedAnalyses      []string         `parquet:"name=failed_analyses, type=LIST, valuetype=BYTE_ARRAY, valueconvertedtype=UTF8"`
+}
+
+type exportKeyword struct {
+	Text  string  `parquet:"name=text, type=BYTE_ARRAY, convertedtype=UTF8"`
+	Score float64 `parquet:"name=score, type=DOUBLE"`
+}
+
+type exportEntity struct {
+	Text  string `parquet:"name=text, type=BYTE_ARRAY, convertedtype=UTF8"`
+	Label string `parquet:"name=label, type=BYTE_ARRAY, convertedtype=UTF8"`
+}
+
+// exportFormat returns the MIME type of the export a batch request's Accept
+// header asks for, or empty for JSON.
+func exportFormat(accept string) string {
+	for _, mime := range []string{mimeAvro, mimeParquet} {
+		if strings.Contains(accept, mime) {
+			return mime
+		}
+	}
+
+	return ""
+}
+
+// exportRowOf flattens a batch result into the columns of an export.
+func exportRowOf(result batchResult) exportRow {
+	row := exportRow{ID: result.ID, Status: result.Status, Keywords: []*exportKeyword{}, Entities: []*exportEntity{}, FailedAnalyses: []string{}}
+	if result.Error != nil {
+		row.Error = &result.Error.Message
+	}
+	for name, analysis := range result.Analyses {
+		if analysis.Error != nil {
+			row.FailedAnalyses = append(row.FailedAnalyses, name)
+			continue
+		}
+		switch name {
+		case "language":
+			var language struct {
+				Language    string   `json:"language"`
+				Probability *float64 `json:"probability"`
+			}
+			_ = json.Unmarshal(analysis.Result, &language)
+			row.Language, row.LanguageProbability = &language.Language, language.Probability
+		case "keywords":
+			var items []interface{}
+			_ = json.Unmarshal(analysis.Result, &items)
+			for _, item := range items {
+				keyword := &exportKeyword{}
+				keyword.Text, _ = itemText(item)
+				if fields, ok := item.(map[string]interface{}); ok {
+					keyword.Score, _ = fields["score"].(float64)
+				}
+				row.Keywords = append(row.Keywords, keyword)
+			}
+		case "entities":
+			var items []v2Item
+			_ = json.Unmarshal(analysis.Result, &items)
+			for _, item := range items {
+				row.Entities = append(row.Entities, &exportEntity{item.Text, item.Label})
+			}
+		case "tokens", "sentences":
+			var items []json.RawMessage
+			_ = json.Unmarshal(analysis.Result, &items)
+			count := int64(len(items))
+			if name == "tokens" {
+				row.TokenCount = &count
+			} else {
+				row.SentenceCount = &count
+			}
+		}
+	}
+	sort.Strings(row.FailedAnalyses)
+
+	return row
+}
+
+// writeAvro writes rows as an Avro object container file, deflated.
+func writeAvro(w io.Writer, rows []exportRow) error {
+	ocf, err := goavro.NewOCFWriter(goavro.OCFConfig{W: w, Schema: exportSchema, CompressionName: goavro.CompressionDeflateLabel})
+	if err != nil {
+		return err
+	}
+	records := make([]interface{}, len(rows))
+	for i, row := range rows {
+		keywords := make([]interface{}, len(row.Keywords))
+		for j, keyword := range row.Keywords {
+			keywords[j] = map[string]interface{}{"text": keyword.Text, "score": keyword.Score}
+		}
+		entities := make([]interface{}, len(row.Entities))
+		for j, entity := range row.Entities {
+			entities[j] = map[string]interface{}{"text": entity.Text, "label": entity.Label}
+		}
+		failed := make([]interface{}, len(row.FailedAnalyses))
+		for j, name := range row.FailedAnalyses {
+			failed[j] = name
+		}
+		// goavro takes the branch of an optional field's union by name
+		record := map[string]interface{}{
+			"id": row.ID, "status": row.Status,
+			"keywords": keywords, "entities": entities, "failed_analyses": failed,
+			"error": nil, "language": nil, "language_probability": nil, "token_count": nil, "sentence_count": nil,
+		}
+		if row.Error != nil {
+			record["error"] = goavro.Union("string", *row.Error)
+		}
+		if row.Language != nil {
+			record["language"] = goavro.Union("string", *row.Language)
+		}
+		if row.LanguageProbability != nil {
+			record["language_probability"] = goavro.Union("double", *row.LanguageProbability)
+		}
+		if row.TokenCount != nil {
+			record["token_count"] = goavro.Union("long", *row.TokenCount)
+		}
+		if row.SentenceCount != nil {
+			record["sentence_count"] = goavro.Union("long", *row.SentenceCount)
+		}
+		records[i] = record
+	}
+
+	return ocf.Append(records)
+}
+
+// writeParquet writes rows as a Parquet file, Snappy compressed.
+func writeParquet(w io.Writer, rows []exportRow) error {
+	pw, err := writer.NewParquetWriterFromWriter(w, new(exportRow), 1)
+	if err != nil {
+		return err
+	}
+	for _, row := range rows {
+		if err := pw.Write(row); err != nil {
+			return err
+		}
+	}
+
+	return pw.WriteStop()
+}
+
+// exportBatch writes the results of a batch as an Avro or Parquet file, for
+// data lakes to query as they are.
+func exportBatch(c echo.Context, format string, results []batchResult) error {
+	rows := make([]exportRow, len(results))
+	for i, result := range results {
+		rows[i] = exportRowOf(result)
+	}
+	var buf bytes.Buffer
+	write := writeAvro
+	if format == mimeParquet {
+		write = writeParquet
+	}
+	if err := write(&buf, rows); err != nil {
+		return internalError(err)
+	}
+
+	return c.Blob(http.StatusOK, format, buf.Bytes())
+}
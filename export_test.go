@@ -0,0 +1,136 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/linkedin/goavro/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/xitongsys/parquet-go-source/buffer"
+	"github.com/xitongsys/parquet-go/reader"
+)
+
+func exportResults() []batchResult {
+	return []batchResult{
+		{ID: "a", Status: "ok", Analyses: map[string]analysisResult{
+			"language": {Status: http.StatusOK, Result: json.RawMessage(`{"language":"en","probability":0.99}`)},
+			"keywords": {Status: http.StatusOK, Result: json.RawMessage(`[{"text":"nobel prize","score":4.5},"curie"]`)},
+			"entities": {Status: http.StatusOK, Result: json.RawMessage(`[{"text":"Curie","label":"PERSON"}]`)},
+			"tokens":   {Status: http.StatusOK, Result: json.RawMessage(`["Curie","won"]`)},
+			"summary":  {Status: http.StatusBadGateway, Error: newAPIError(http.StatusBadGateway, codeUpstreamError, "down")},
+		}},
+		{ID: "b", Status: "error", Error: newAPIError(http.StatusBadRequest, codeValidationFailed, "text is required")},
+	}
+}
+
+func TestExportFormat(t *testing.T) {
+	assert.Equal(t, mimeAvro, exportFormat("application/avro"))
+	assert.Equal(t, mimeParquet, exportFormat("application/vnd.apache.parquet, application/json;q=0.5"))
+	assert.Empty(t, exportFormat("application/json"))
+	assert.Empty(t, exportFormat(""))
+}
+
+func TestExportRowOf(t *testing.T) {
+	rows := []exportRow{exportRowOf(exportResults()[0]), exportRowOf(exportResults()[1])}
+	if assert.NotNil(t, rows[0].Language) && assert.NotNil(t, rows[0].LanguageProbability) {
+		assert.Equal(t, "en", *rows[0].Language)
+		assert.Equal(t, 0.99, *rows[0].LanguageProbability)
+	}
+	assert.Equal(t, []*exportKeyword{{"nobel prize", 4.5}, {"curie", 0}}, rows[0].Keywords)
+	assert.Equal(t, []*exportEntity{{"Curie", "PERSON"}}, rows[0].Entities)
+	if assert.NotNil(t, rows[0].TokenCount) {
+		assert.Equal(t, int64(2), *rows[0].TokenCount)
+	}
+	assert.Nil(t, rows[0].SentenceCount, "analyses not asked for are null")
+	assert.Equal(t, []string{"summary"}, rows[0].FailedAnalyses)
+	if assert.NotNil(t, rows[1].Error) {
+		assert.Equal(t, "text is required", *rows[1].Error)
+	}
+	assert.Empty(t, rows[1].Keywords)
+}
+
+func TestWriteAvro(t *testing.T) {
+	var buf bytes.Buffer
+	rows := []exportRow{exportRowOf(exportResults()[0]), exportRowOf(exportResults()[1])}
+	if !assert.NoError(t, writeAvro(&buf, rows)) {
+		return
+	}
+	ocf, err := goavro.NewOCFReader(&buf)
+	if !assert.NoError(t, err) {
+		return
+	}
+	var records []map[string]interface{}
+	for ocf.Scan() {
+		record, err := ocf.Read()
+		if assert.NoError(t, err) {
+			records = append(records, record.(map[string]interface{}))
+		}
+	}
+	if assert.Len(t, records, 2) {
+		assert.Equal(t, "a", records[0]["id"])
+		assert.Equal(t, map[string]interface{}{"string": "en"}, records[0]["language"])
+		assert.Equal(t, map[string]interface{}{"long": int64(2)}, records[0]["token_count"])
+		assert.Nil(t, records[0]["sentence_count"])
+		assert.Equal(t, []interface{}{"summary"}, records[0]["failed_analyses"])
+		assert.Equal(t, map[string]interface{}{"string": "text is required"}, records[1]["error"])
+	}
+}
+
+func TestWriteParquet(t *testing.T) {
+	var buf bytes.Buffer
+	rows := []exportRow{exportRowOf(exportResults()[0]), exportRowOf(exportResults()[1])}
+	if !assert.NoError(t, writeParquet(&buf, rows)) {
+		return
+	}
+	pr, err := reader.NewParquetReader(buffer.NewBufferFileFromBytes(buf.Bytes()), new(exportRow), 1)
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer pr.ReadStop()
+	read := make([]exportRow, pr.GetNumRows())
+	if assert.NoError(t, pr.Read(&read)) && assert.Len(t, read, 2) {
+		assert.Equal(t, "a", read[0].ID)
+		assert.Equal(t, rows[0].Keywords, read[0].Keywords)
+		assert.Equal(t, rows[0].Entities, read[0].Entities)
+		if assert.NotNil(t, read[0].TokenCount) {
+			assert.Equal(t, int64(2), *read[0].TokenCount)
+		}
+		assert.Nil(t, read[0].SentenceCount)
+		if assert.NotNil(t, read[1].Error) {
+			assert.Equal(t, "text is required", *read[1].Error)
+		}
+	}
+}
+
+func TestPostBatchAvro(t *testing.T) {
+	upstream := newBatchUpstream(t)
+	defer upstream.Close()
+	defer func(url string) { urlRake = url }(urlRake)
+	urlRake = upstream.URL
+
+	body := `{"documents": [{"id": "a", "text": "one"}, {"id": "b", "text": "two"}]}`
+	req := httptest.NewRequest(http.MethodPost, "/batch?analyses=keywords", strings.NewReader(body))
+	req.Header.Set(echo.HeaderAccept, mimeAvro)
+	w := httptest.NewRecorder()
+	c := e.NewContext(req, w)
+
+	if assert.NoError(t, postBatch(c)) {
+		assert.Equal(t, mimeAvro, w.Header().Get(echo.HeaderContentType))
+		ocf, err := goavro.NewOCFReader(w.Body)
+		if assert.NoError(t, err) {
+			var ids []interface{}
+			for ocf.Scan() {
+				record, err := ocf.Read()
+				if assert.NoError(t, err) {
+					ids = append(ids, record.(map[string]interface{})["id"])
+				}
+			}
+			assert.Equal(t, []interface{}{"a", "b"}, ids)
+		}
+	}
+}
@@ -0,0 +1,281 @@
+// author: Gary A. Stafford
+// site: https://programmaticponderings.com
+// license: MIT License
+// purpose: NLP microservices: nlp-client multi-region upstream failover
+// modified: 2026-10-14
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+const headerServedRegion = "X-Served-Region"
+
+var (
+	// semicolon-separated upstream=region:url secondaries, e.g.
+	// "rake=us-west-2:https://rake.us-west-2.example.com;prose=us-west-2:https://prose.us-west-2.example.com"
+	failoverUpstreams     = getEnv("FAILOVER_UPSTREAMS", "")
+	failoverPrimaryRegion = getEnv("FAILOVER_PRIMARY_REGION", "primary")
+	// consecutive failures of the primary that fail an upstream over
+	failoverThreshold     = getEnv("FAILOVER_THRESHOLD", "5")
+	failoverCheckInterval = getEnv("FAILOVER_CHECK_INTERVAL", "10s")
+	upstreamRegions       = loadUpstreamRegions()
+)
+
+// regionalUpstream is an upstream served from a primary and a secondary
+// region, and which of them currently serves it.
+type regionalUpstream struct {
+	name            string
+	primary         *url.URL
+	primaryRegion   string
+	secondary       *url.URL
+	secondaryRegion string
+
+	mu         sync.Mutex
+	failures   int
+	failedOver bool
+}
+
+// active returns the URL and region currently serving the upstream.
+func (u *regionalUpstream) active() (*url.URL, string, bool) {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+
+	if u.failedOver {
+		return u.secondary, u.secondaryRegion, true
+	}
+
+	return u.primary, u.primaryRegion, false
+}
+
+// observe records a call to the primary, and reports whether it was the
+// failure that fails the upstream over.
+func (u *regionalUpstream) observe(ok bool, threshold int) bool {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+
+	if ok {
+		u.failures = 0
+		return false
+	}
+	u.failures++
+	if u.failedOver || u.failures < threshold {
+		return false
+	}
+	u.failedOver = true
+
+	return true
+}
+
+func (u *regionalUpstream) failBack() {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+
+	u.failedOver = false
+	u.failures = 0
+}
+
+// parseUpstreamRegions parses FAILOVER_UPSTREAMS against the primary URLs
+// of the named upstreams, keyed by primary host.
+func parseUpstreamRegions(config, primaryRegion string, named map[string]string) (map[string]*regionalUpstream, error) {
+	regions := map[string]*regionalUpstream{}
+	for _, entry := range strings.Split(config, ";") {
+		if strings.TrimSpace(entry) == "" {
+			continue
+		}
+		parts := strings.SplitN(entry, "=", 2)
+		name := strings.TrimSpace(parts[0])
+		primaryURL, ok := named[name]
+		if len(parts) != 2 || !ok {
+			return nil, fmt.Errorf("invalid failover upstream %q", entry)
+		}
+		target := strings.SplitN(strings.TrimSpace(parts[1]), ":", 2)
+		if len(target) != 2 || target[0] == "" {
+			return nil, fmt.Errorf("invalid failover region %q, want region:url", parts[1])
+		}
+		primary, err := url.Parse(primaryURL)
+		if err != nil {
+			return nil, err
+		}
+		secondary, err := url.Parse(target[1])
+		if err != nil || secondary.Scheme == "" || secondary.Host == "" {
+			return nil, fmt.Errorf("invalid failover url %q", target[1])
+		}
+		regions[primary.Host] = &regionalUpstream{
+			name:            name,
+			primary:         primary,
+			primaryRegion:   primaryRegion,
+			secondary:       secondary,
+			secondaryRegion: target[0],
+		}
+	}
+
+	return regions, nil
+}
+
+func loadUpstreamRegions() map[string]*regionalUpstream {
+	regions, err := parseUpstreamRegions(failoverUpstreams, failoverPrimaryRegion, namedUpstreams())
+	if err != nil {
+		e.Logger.Errorf("ignoring FAILOVER_UPSTREAMS: %v", err)
+		return nil
+	}
+
+	return regions
+}
+
+// secondaryUpstreamName returns the upstream a secondary region's host serves, if any.
+func secondaryUpstreamName(host string) string {
+	for _, u := range upstreamRegions {
+		if u.secondary.Host == host {
+			return u.name
+		}
+	}
+
+	return ""
+}
+
+// servedRegion returns the region currently serving an upstream URL, if it has regions.
+func servedRegion(rawURL string) string {
+	target, err := url.Parse(rawURL)
+	if err != nil {
+		return ""
+	}
+	u, ok := upstreamRegions[target.Host]
+	if !ok {
+		return ""
+	}
+	_, region, _ := u.active()
+
+	return region
+}
+
+// failoverTransport sends the calls to a regional upstream to whichever
+// region serves it. Once the primary has failed threshold calls in a row,
+// with an error or a 5xx, the upstream fails over to its secondary until
+// the primary's /health endpoint answers again. The call that fails it over
+// is sent again to the secondary when it is safe to repeat; see replayable.
+type failoverTransport struct {
+	next      http.RoundTripper
+	upstreams map[string]*regionalUpstream
+	threshold int
+	interval  time.Duration
+	timeout   time.Duration
+}
+
+func (t *failoverTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	u, ok := t.upstreams[req.URL.Host]
+	if !ok {
+		return t.next.RoundTrip(req)
+	}
+	target, region, secondary := u.active()
+	if secondary {
+		req = onRegion(req, u, target)
+	}
+
+	resp, err := t.next.RoundTrip(req)
+	// a caller giving up says nothing about the primary's health
+	if !secondary && req.Context().Err() == nil && u.observe(err == nil && resp.StatusCode < http.StatusInternalServerError, t.threshold) {
+		e.Logger.Warnf("upstream %s failed over from %s to %s", u.name, u.primaryRegion, u.secondaryRegion)
+		metrics.Gauge("upstream_failed_over", 1, map[string]string{"upstream": u.name})
+		go t.awaitPrimary(u)
+
+		if replayable(req, u.name) {
+			retry := onRegion(req, u, u.secondary)
+			if req.GetBody != nil {
+				if retry.Body, err = req.GetBody(); err != nil {
+					return nil, err
+				}
+			}
+			if resp != nil {
+				resp.Body.Close()
+			}
+			resp, err = t.next.RoundTrip(retry)
+			region = u.secondaryRegion
+		}
+	}
+	if resp != nil {
+		resp.Header.Set(headerServedRegion, region)
+	}
+
+	return resp, err
+}
+
+// onRegion returns a copy of a call to an upstream's primary sent to target instead.
+func onRegion(req *http.Request, u *regionalUpstream, target *url.URL) *http.Request {
+	req = req.Clone(req.Context())
+	req.URL.Scheme = target.Scheme
+	req.URL.Host = target.Host
+	req.URL.Path = target.Path + strings.TrimPrefix(req.URL.Path, u.primary.Path)
+	req.Host = target.Host
+
+	return req
+}
+
+// replayable reports whether a call may be sent again: its body can be read
+// again, and it is a read, or an analysis, which has no side effects. Writes
+// to the record store are not, as the primary may have stored the record
+// before failing.
+func replayable(req *http.Request, upstream string) bool {
+	if req.Body != nil && req.Body != http.NoBody && req.GetBody == nil {
+		return false
+	}
+	switch req.Method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions:
+		return true
+	}
+
+	return upstream != "dynamo"
+}
+
+// awaitPrimary fails an upstream back to its primary once its health check passes.
+func (t *failoverTransport) awaitPrimary(u *regionalUpstream) {
+	ticker := time.NewTicker(t.interval)
+	defer ticker.Stop()
+	for range ticker.C {
+		if t.primaryHealthy(u) {
+			u.failBack()
+			e.Logger.Warnf("upstream %s failed back to %s", u.name, u.primaryRegion)
+			metrics.Gauge("upstream_failed_over", 0, map[string]string{"upstream": u.name})
+			return
+		}
+	}
+}
+
+func (t *failoverTransport) primaryHealthy(u *regionalUpstream) bool {
+	ctx, cancel := context.WithTimeout(context.Background(), t.timeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, strings.TrimSuffix(u.primary.String(), "/")+"/health", nil)
+	if err != nil {
+		return false
+	}
+	req.Header.Set("X-API-Key", apiKeys.Primary())
+	resp, err := t.next.RoundTrip(req)
+	if err != nil {
+		return false
+	}
+	defer resp.Body.Close()
+
+	return resp.StatusCode >= 200 && resp.StatusCode <= 299
+}
+
+func withFailover(next http.RoundTripper) http.RoundTripper {
+	if len(upstreamRegions) == 0 {
+		return next
+	}
+
+	return &failoverTransport{
+		next:      next,
+		upstreams: upstreamRegions,
+		threshold: envInt(failoverThreshold, 5),
+		interval:  envDuration(failoverCheckInterval, 10*time.Second),
+		timeout:   envDuration(healthCheckTimeout, 5*time.Second),
+	}
+}
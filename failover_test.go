@@ -0,0 +1,89 @@
+package main
+
+import (
+	"bytes"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseUpstreamRegions(t *testing.T) {
+	named := map[string]string{"rake": "http://rake.east:8081", "prose": "http://prose.east:8082"}
+
+	regions, err := parseUpstreamRegions("rake=us-west-2:http://rake.west:8081", "us-east-1", named)
+	if assert.NoError(t, err) && assert.Contains(t, regions, "rake.east:8081") {
+		u := regions["rake.east:8081"]
+		assert.Equal(t, "rake", u.name)
+		assert.Equal(t, "us-east-1", u.primaryRegion)
+		assert.Equal(t, "us-west-2", u.secondaryRegion)
+		assert.Equal(t, "rake.west:8081", u.secondary.Host)
+	}
+
+	for _, config := range []string{"spell=us-west-2:http://spell.west", "rake=http://rake.west", "rake=us-west-2:", "rake"} {
+		_, err := parseUpstreamRegions(config, "us-east-1", named)
+		assert.Error(t, err, config)
+	}
+}
+
+func TestReplayable(t *testing.T) {
+	analysis, _ := http.NewRequest(http.MethodPost, "http://rake/keywords", bytes.NewReader([]byte(`{"text": "a"}`)))
+	assert.True(t, replayable(analysis, "rake"))
+	assert.False(t, replayable(analysis, "dynamo"), "a record may already be stored")
+	streamed, _ := http.NewRequest(http.MethodPost, "http://rake/keywords", ioutil.NopCloser(strings.NewReader("a")))
+	assert.False(t, replayable(streamed, "rake"), "a body that cannot be read again")
+	read, _ := http.NewRequest(http.MethodGet, "http://dynamo/record/7", nil)
+	assert.True(t, replayable(read, "dynamo"))
+}
+
+func TestFailoverTransport(t *testing.T) {
+	var primaryHealthy int32
+	primary := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/health" && atomic.LoadInt32(&primaryHealthy) == 1 {
+			w.WriteHeader(http.StatusOK)
+			return
+		}
+		w.WriteHeader(http.StatusBadGateway)
+	}))
+	defer primary.Close()
+	secondary := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer secondary.Close()
+
+	regions, err := parseUpstreamRegions("rake=us-west-2:"+secondary.URL, "us-east-1", map[string]string{"rake": primary.URL})
+	if !assert.NoError(t, err) {
+		return
+	}
+	transport := &failoverTransport{next: http.DefaultTransport, upstreams: regions, threshold: 2, interval: 10 * time.Millisecond, timeout: time.Second}
+	client := &http.Client{Transport: transport}
+	call := func() *http.Response {
+		resp, err := client.Get(primary.URL + "/keywords")
+		if !assert.NoError(t, err) {
+			t.FailNow()
+		}
+		resp.Body.Close()
+		return resp
+	}
+
+	resp := call()
+	assert.Equal(t, http.StatusBadGateway, resp.StatusCode)
+	assert.Equal(t, "us-east-1", resp.Header.Get(headerServedRegion))
+	resp = call()
+	assert.Equal(t, http.StatusOK, resp.StatusCode, "the call that fails the upstream over is retried on the secondary")
+	assert.Equal(t, "us-west-2", resp.Header.Get(headerServedRegion))
+	resp = call()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, "us-west-2", resp.Header.Get(headerServedRegion))
+
+	atomic.StoreInt32(&primaryHealthy, 1)
+	assert.Eventually(t, func() bool {
+		_, region, _ := regions[primary.Listener.Addr().String()].active()
+		return region == "us-east-1"
+	}, time.Second, 10*time.Millisecond)
+}
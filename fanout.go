@@ -0,0 +1,159 @@
+// author: Gary A. Stafford
+// site: https://programmaticponderings.com
+// license: MIT License
+// purpose: NLP microservices: nlp-client concurrent analyses and fan-out budget
+// modified: 2026-10-14
+
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/labstack/echo/v4"
+)
+
+var (
+	// analyses of an /analyze request run at once, by default and at most;
+	// callers may ask for fewer with ?concurrency=, 1 to run them in turn
+	analyzeConcurrency = getEnv("ANALYZE_CONCURRENCY", "4")
+	// analysis calls in flight across every request at once; 0 for no limit
+	analyzeFanoutBudget = getEnv("ANALYZE_FANOUT_BUDGET", "64")
+	// how long an analysis waits for the fan-out budget before failing
+	analyzeFanoutWait = getEnv("ANALYZE_FANOUT_WAIT", "1s")
+
+	errFanoutBudget = errors.New("analysis fan-out budget exhausted")
+
+	fanoutOnce   sync.Once
+	sharedFanout *fanoutBudget
+)
+
+// fanoutBudget caps the analysis calls all in-flight requests may make at once.
+type fanoutBudget struct {
+	slots chan struct{}
+	wait  time.Duration
+}
+
+// newFanoutBudget returns a budget of limit calls, or nil for no limit.
+func newFanoutBudget(limit int, wait time.Duration) *fanoutBudget {
+	if limit <= 0 {
+		return nil
+	}
+
+	return &fanoutBudget{slots: make(chan struct{}, limit), wait: wait}
+}
+
+// fanout returns the gateway's budget, sized by ANALYZE_FANOUT_BUDGET.
+func fanout() *fanoutBudget {
+	fanoutOnce.Do(func() {
+		sharedFanout = newFanoutBudget(envInt(analyzeFanoutBudget, 64), envDuration(analyzeFanoutWait, time.Second))
+	})
+
+	return sharedFanout
+}
+
+// acquire takes a call from the budget, waiting for one to be released for
+// up to the budget's wait or the deadline of ctx.
+func (b *fanoutBudget) acquire(ctx context.Context) *apiError {
+	if b == nil {
+		return nil
+	}
+	select {
+	case b.slots <- struct{}{}:
+		return nil
+	default:
+	}
+	timer := time.NewTimer(b.wait)
+	defer timer.Stop()
+	select {
+	case b.slots <- struct{}{}:
+		return nil
+	case <-ctx.Done():
+		return deadlineExceededError()
+	case <-timer.C:
+		metrics.Count("fanout_rejected_total", 1, nil)
+		return newAPIError(http.StatusServiceUnavailable, codeOverloaded, errFanoutBudget.Error())
+	}
+}
+
+func (b *fanoutBudget) release() {
+	if b != nil {
+		<-b.slots
+	}
+}
+
+// parseConcurrency returns the ?concurrency= of an /analyze request, or
+// ANALYZE_CONCURRENCY, which it may not exceed.
+func parseConcurrency(c echo.Context) (int, error) {
+	maxConcurrency := envInt(analyzeConcurrency, 4)
+	value := c.QueryParam("concurrency")
+	if value == "" {
+		return maxConcurrency, nil
+	}
+	concurrency, err := strconv.Atoi(value)
+	if err != nil || concurrency < 1 || concurrency > maxConcurrency {
+		return 0, fmt.Errorf("concurrency must be between 1 and %d", maxConcurrency)
+	}
+
+	return concurrency, nil
+}
+
+// runAnalysesConcurrently runs the analyses of text, up to concurrency at
+// once, recording failures per analysis. The analyses not yet started share
+// the remaining deadline a round of concurrency at a time, and every call
+// takes its turn from the gateway's fan-out budget.
+func runAnalysesConcurrently(ctx context.Context, text string, analyses []string, key string, concurrency int) map[string]analysisResult {
+	if concurrency > len(analyses) {
+		concurrency = len(analyses)
+	}
+	results := make(map[string]analysisResult, len(analyses))
+	var mu sync.Mutex
+	next := 0
+	var wg sync.WaitGroup
+	for w := 0; w < concurrency; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				mu.Lock()
+				if next == len(analyses) {
+					mu.Unlock()
+					return
+				}
+				i := next
+				next++
+				mu.Unlock()
+
+				name := analyses[i]
+				result := runBudgetedAnalysis(ctx, name, text, key, (len(analyses)-i+concurrency-1)/concurrency)
+				mu.Lock()
+				results[name] = result
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+
+	return results
+}
+
+// runBudgetedAnalysis runs an analysis with its share of the deadline of ctx, one of rounds, and a call from the fan-out budget.
+func runBudgetedAnalysis(ctx context.Context, name, text, key string, rounds int) analysisResult {
+	stepCtx, cancel, err := stepContext(ctx, rounds)
+	if err != nil {
+		return failedAnalysis(deadlineExceededError())
+	}
+	defer cancel()
+	budget := fanout()
+	if err := budget.acquire(stepCtx); err != nil {
+		return failedAnalysis(err)
+	}
+	defer budget.release()
+
+	return runAnalysis(stepCtx, name, text, key)
+}
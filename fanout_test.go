@@ -0,0 +1,114 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseConcurrency(t *testing.T) {
+	defer func(concurrency string) { analyzeConcurrency = concurrency }(analyzeConcurrency)
+	analyzeConcurrency = "3"
+	parse := func(target string) (int, error) {
+		return parseConcurrency(e.NewContext(httptest.NewRequest(http.MethodPost, target, nil), httptest.NewRecorder()))
+	}
+
+	concurrency, err := parse("/analyze")
+	if assert.NoError(t, err) {
+		assert.Equal(t, 3, concurrency)
+	}
+	concurrency, err = parse("/analyze?concurrency=1")
+	if assert.NoError(t, err) {
+		assert.Equal(t, 1, concurrency)
+	}
+	for _, value := range []string{"0", "4", "all"} {
+		_, err = parse("/analyze?concurrency=" + value)
+		assert.EqualError(t, err, "concurrency must be between 1 and 3", value)
+	}
+}
+
+func TestFanoutBudget(t *testing.T) {
+	budget := newFanoutBudget(1, 10*time.Millisecond)
+	assert.Nil(t, budget.acquire(context.Background()))
+	err := budget.acquire(context.Background())
+	if assert.NotNil(t, err) {
+		assert.Equal(t, http.StatusServiceUnavailable, err.Status)
+		assert.Equal(t, codeOverloaded, err.Code)
+	}
+	budget.release()
+	assert.Nil(t, budget.acquire(context.Background()), "a released call can be taken again")
+
+	assert.Nil(t, newFanoutBudget(0, time.Second), "a budget of 0 is no limit")
+	var unlimited *fanoutBudget
+	assert.Nil(t, unlimited.acquire(context.Background()))
+	unlimited.release()
+}
+
+// newConcurrencyUpstream answers every analysis after a pause, counting the
+// calls in flight at once.
+func newConcurrencyUpstream() (*httptest.Server, func() int) {
+	var mu sync.Mutex
+	inFlight, peak := 0, 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		inFlight++
+		if inFlight > peak {
+			peak = inFlight
+		}
+		mu.Unlock()
+		time.Sleep(20 * time.Millisecond)
+		mu.Lock()
+		inFlight--
+		mu.Unlock()
+		w.Header().Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		_, _ = w.Write([]byte(`[]`))
+	}))
+
+	return server, func() int {
+		mu.Lock()
+		defer mu.Unlock()
+		return peak
+	}
+}
+
+func TestPostAnalyzeConcurrency(t *testing.T) {
+	for _, tc := range []struct {
+		query string
+		peak  int
+	}{{"", 3}, {"&concurrency=1", 1}} {
+		upstream, peak := newConcurrencyUpstream()
+		defer func(rake, prose string) { urlRake, urlProse = rake, prose }(urlRake, urlProse)
+		urlRake, urlProse = upstream.URL, upstream.URL
+
+		req := httptest.NewRequest(http.MethodPost, "/analyze?analyses=keywords,tokens,entities"+tc.query, strings.NewReader(`{"text": "The Nobel Prize"}`))
+		w := httptest.NewRecorder()
+		if assert.NoError(t, postAnalyze(e.NewContext(req, w))) {
+			assert.Equal(t, http.StatusOK, w.Code)
+			assert.Equal(t, tc.peak, peak(), tc.query)
+		}
+		upstream.Close()
+	}
+}
+
+func TestRunAnalysesFanoutBudget(t *testing.T) {
+	upstream, peak := newConcurrencyUpstream()
+	defer upstream.Close()
+	defer func(rake, prose string) { urlRake, urlProse = rake, prose }(urlRake, urlProse)
+	urlRake, urlProse = upstream.URL, upstream.URL
+	defer func(budget *fanoutBudget) { sharedFanout = budget }(fanout())
+	sharedFanout = newFanoutBudget(2, time.Second)
+
+	results := runAnalysesConcurrently(context.Background(), "text", []string{"keywords", "tokens", "entities", "sentences"}, "", 4)
+	assert.Len(t, results, 4)
+	for name, result := range results {
+		assert.Nil(t, result.Error, name)
+	}
+	assert.Equal(t, 2, peak(), "the budget holds back the analyses the request would run at once")
+}
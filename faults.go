@@ -0,0 +1,227 @@
+// author: Gary A. Stafford
+// site: https://programmaticponderings.com
+// license: MIT License
+// purpose: NLP microservices: nlp-client fault injection
+// modified: 2026-10-14
+
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"math/rand"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/labstack/echo/v4"
+)
+
+// wraps the upstream transport so faults can be injected from the admin
+// routes; never enable in production
+var faultInjection = getEnv("FAULT_INJECTION", "false")
+
+var (
+	errInjectedFault = fmt.Errorf("injected fault")
+	faults           = newFaultRules()
+)
+
+// faultRule injects faults into a percentage of the calls to one upstream.
+type faultRule struct {
+	Upstream string  `json:"upstream"`
+	Percent  float64 `json:"percent"`
+	// delay before the call is made, e.g. "2s"
+	Latency string `json:"latency,omitempty"`
+	// answer with this status instead of calling the upstream
+	Status int `json:"status,omitempty"`
+	// fail the call as if the connection dropped
+	Abort bool `json:"abort,omitempty"`
+	// cut the upstream's response body off halfway
+	Truncate bool `json:"truncate,omitempty"`
+	latency  time.Duration
+}
+
+type faultRules struct {
+	mu    sync.RWMutex
+	rules map[string]faultRule
+}
+
+func newFaultRules() *faultRules {
+	return &faultRules{rules: map[string]faultRule{}}
+}
+
+func (f *faultRules) get(upstream string) (faultRule, bool) {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+
+	rule, ok := f.rules[upstream]
+	return rule, ok
+}
+
+func (f *faultRules) set(rule faultRule) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.rules[rule.Upstream] = rule
+}
+
+func (f *faultRules) remove(upstream string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	delete(f.rules, upstream)
+}
+
+func (f *faultRules) list() []faultRule {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+
+	rules := make([]faultRule, 0, len(f.rules))
+	for _, rule := range f.rules {
+		rules = append(rules, rule)
+	}
+	sort.Slice(rules, func(i, j int) bool { return rules[i].Upstream < rules[j].Upstream })
+
+	return rules
+}
+
+// upstreamName returns the name of the upstream served from host.
+func upstreamName(host string) string {
+	for name, upstream := range namedUpstreams() {
+		if u, err := url.Parse(upstream); err == nil && u.Host == host {
+			return name
+		}
+	}
+
+	return secondaryUpstreamName(host)
+}
+
+// faultTransport applies the fault rule of the upstream a request is sent to.
+type faultTransport struct {
+	next  http.RoundTripper
+	rules *faultRules
+}
+
+func (t *faultTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	rule, ok := t.rules.get(upstreamName(req.URL.Host))
+	if !ok || rand.Float64()*100 >= rule.Percent {
+		return t.next.RoundTrip(req)
+	}
+
+	if rule.latency > 0 {
+		select {
+		case <-time.After(rule.latency):
+		case <-req.Context().Done():
+			return nil, req.Context().Err()
+		}
+	}
+	if rule.Abort {
+		return nil, errInjectedFault
+	}
+	if rule.Status != 0 {
+		if req.Body != nil {
+			req.Body.Close()
+		}
+		return &http.Response{
+			Status:     fmt.Sprintf("%d %s", rule.Status, http.StatusText(rule.Status)),
+			StatusCode: rule.Status,
+			Proto:      "HTTP/1.1",
+			ProtoMajor: 1,
+			ProtoMinor: 1,
+			Header:     http.Header{echo.HeaderContentType: []string{echo.MIMEApplicationJSON}},
+			Body:       ioutil.NopCloser(strings.NewReader(`{"error": "injected fault"}`)),
+			Request:    req,
+		}, nil
+	}
+
+	resp, err := t.next.RoundTrip(req)
+	if err != nil || !rule.Truncate {
+		return resp, err
+	}
+	data, err := ioutil.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	resp.Body = ioutil.NopCloser(io.MultiReader(bytes.NewReader(data[:len(data)/2]), unexpectedEOF{}))
+	resp.ContentLength = -1
+
+	return resp, nil
+}
+
+// unexpectedEOF ends a truncated body the way a dropped connection would.
+type unexpectedEOF struct{}
+
+func (unexpectedEOF) Read([]byte) (int, error) {
+	return 0, io.ErrUnexpectedEOF
+}
+
+func withFaultInjection(next http.RoundTripper) http.RoundTripper {
+	if !envBool(faultInjection) {
+		return next
+	}
+	e.Logger.Warn("FAULT_INJECTION is enabled")
+
+	return &faultTransport{next: next, rules: faults}
+}
+
+func faultsEnabled() error {
+	if !envBool(faultInjection) {
+		return newAPIError(http.StatusForbidden, codeForbidden, "fault injection is disabled")
+	}
+
+	return nil
+}
+
+func getFaults(c echo.Context) error {
+	if err := faultsEnabled(); err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusOK, faults.list())
+}
+
+// putFault sets the fault rule of one upstream, replacing any it had.
+func putFault(c echo.Context) error {
+	if err := faultsEnabled(); err != nil {
+		return err
+	}
+	var rule faultRule
+	if err := c.Bind(&rule); err != nil {
+		return validationError("invalid fault rule")
+	}
+	rule.Upstream = c.Param("upstream")
+	if _, ok := namedUpstreams()[rule.Upstream]; !ok {
+		return newAPIError(http.StatusNotFound, codeNotFound, fmt.Sprintf("unknown upstream: %s", rule.Upstream))
+	}
+	if rule.Percent <= 0 || rule.Percent > 100 {
+		return validationError("percent must be between 0 and 100")
+	}
+	if rule.Latency != "" {
+		d, err := time.ParseDuration(rule.Latency)
+		if err != nil || d < 0 {
+			return validationError(fmt.Sprintf("invalid latency: %s", rule.Latency))
+		}
+		rule.latency = d
+	}
+	if rule.Status != 0 && (rule.Status < 100 || rule.Status > 599) {
+		return validationError(fmt.Sprintf("invalid status: %d", rule.Status))
+	}
+	faults.set(rule)
+
+	return c.JSON(http.StatusOK, rule)
+}
+
+func deleteFault(c echo.Context) error {
+	if err := faultsEnabled(); err != nil {
+		return err
+	}
+	faults.remove(c.Param("upstream"))
+
+	return c.NoContent(http.StatusNoContent)
+}
@@ -0,0 +1,97 @@
+package main
+
+import (
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+)
+
+func newFaultTestUpstream(t *testing.T) (*httptest.Server, *faultTransport) {
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`["marie curie"]`))
+	}))
+	return upstream, &faultTransport{next: http.DefaultTransport, rules: newFaultRules()}
+}
+
+func TestFaultTransport(t *testing.T) {
+	upstream, transport := newFaultTestUpstream(t)
+	defer upstream.Close()
+	defer func(url string) { urlRake = url }(urlRake)
+	urlRake = upstream.URL
+	client := &http.Client{Transport: transport}
+
+	// no rule, no fault
+	resp, err := client.Get(upstream.URL + "/keywords")
+	if assert.NoError(t, err) {
+		body, _ := ioutil.ReadAll(resp.Body)
+		assert.Equal(t, `["marie curie"]`, string(body))
+	}
+
+	transport.rules.set(faultRule{Upstream: "rake", Percent: 100, Status: http.StatusServiceUnavailable})
+	resp, err = client.Get(upstream.URL + "/keywords")
+	if assert.NoError(t, err) {
+		assert.Equal(t, http.StatusServiceUnavailable, resp.StatusCode)
+	}
+
+	transport.rules.set(faultRule{Upstream: "rake", Percent: 100, Abort: true})
+	_, err = client.Get(upstream.URL + "/keywords")
+	assert.Error(t, err)
+
+	transport.rules.set(faultRule{Upstream: "rake", Percent: 100, Truncate: true})
+	resp, err = client.Get(upstream.URL + "/keywords")
+	if assert.NoError(t, err) {
+		body, err := ioutil.ReadAll(resp.Body)
+		assert.Equal(t, io.ErrUnexpectedEOF, err)
+		assert.Equal(t, `["marie`, string(body))
+	}
+}
+
+func TestUpstreamName(t *testing.T) {
+	defer func(url string) { urlLang = url }(urlLang)
+	urlLang = "http://lang-app:8083"
+
+	assert.Equal(t, "lang", upstreamName("lang-app:8083"))
+	assert.Equal(t, "", upstreamName("example.com"))
+}
+
+func TestPutFault(t *testing.T) {
+	defer func(enabled string) { faultInjection = enabled }(faultInjection)
+	defer faults.remove("prose")
+
+	put := func(upstream, body string) (*httptest.ResponseRecorder, error) {
+		req := httptest.NewRequest(http.MethodPut, "/admin/faults/"+upstream, strings.NewReader(body))
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		w := httptest.NewRecorder()
+		c := e.NewContext(req, w)
+		c.SetParamNames("upstream")
+		c.SetParamValues(upstream)
+		return w, putFault(c)
+	}
+
+	faultInjection = "false"
+	_, err := put("prose", `{"percent": 10}`)
+	if assert.Error(t, err) {
+		assert.Equal(t, http.StatusForbidden, err.(*apiError).Status)
+	}
+
+	faultInjection = "true"
+	if _, err := put("prose", `{"percent": 10, "latency": "2s"}`); assert.NoError(t, err) {
+		rule, ok := faults.get("prose")
+		assert.True(t, ok)
+		assert.Equal(t, 10.0, rule.Percent)
+		assert.Equal(t, "2s", rule.latency.String())
+	}
+
+	_, err = put("prose", `{"percent": 0}`)
+	assert.Error(t, err)
+	_, err = put("nowhere", `{"percent": 10}`)
+	if assert.Error(t, err) {
+		assert.Equal(t, http.StatusNotFound, err.(*apiError).Status)
+	}
+}
@@ -0,0 +1,130 @@
+// author: Gary A. Stafford
+// site: https://programmaticponderings.com
+// license: MIT License
+// purpose: NLP microservices: nlp-client feedback on analysis results
+// modified: 2026-10-14
+
+package main
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/labstack/echo/v4"
+)
+
+// feedback entries kept for export; the oldest are dropped once there are more
+var feedbackMaxEntries = getEnv("FEEDBACK_MAX_ENTRIES", "10000")
+
+var feedback = newFeedbackStore(envInt(feedbackMaxEntries, 10000))
+
+// feedbackEntry flags the items of an analysis result that were wrong, and
+// those it missed, for the request that returned it.
+type feedbackEntry struct {
+	ID        string `json:"id"`
+	RequestID string `json:"request_id"`
+	Analysis  string `json:"analysis"`
+	// the text analyzed, when the caller sends it, so the entry can be trained on
+	Text      string          `json:"text,omitempty"`
+	Incorrect []evaluatedItem `json:"incorrect,omitempty"`
+	Missing   []evaluatedItem `json:"missing,omitempty"`
+	Comment   string          `json:"comment,omitempty"`
+	// the key ID of the caller that sent the feedback
+	Submitter   string    `json:"submitter"`
+	SubmittedAt time.Time `json:"submitted_at"`
+}
+
+// feedbackStore keeps the feedback sent to a single instance in memory,
+// oldest first.
+type feedbackStore struct {
+	mu      sync.Mutex
+	entries []feedbackEntry
+	max     int
+}
+
+func newFeedbackStore(max int) *feedbackStore {
+	return &feedbackStore{max: max}
+}
+
+func (s *feedbackStore) add(entry feedbackEntry) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.entries = append(s.entries, entry)
+	if over := len(s.entries) - s.max; over > 0 {
+		s.entries = append([]feedbackEntry{}, s.entries[over:]...)
+	}
+}
+
+// since returns the entries submitted after t, for analysis if set.
+func (s *feedbackStore) since(t time.Time, analysis string) []feedbackEntry {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var entries []feedbackEntry
+	for _, entry := range s.entries {
+		if entry.SubmittedAt.After(t) && (analysis == "" || entry.Analysis == analysis) {
+			entries = append(entries, entry)
+		}
+	}
+
+	return entries
+}
+
+// postFeedback stores the corrections a caller sends for the entities or
+// keywords returned to one of its requests.
+func postFeedback(c echo.Context) error {
+	var entry feedbackEntry
+	if err := json.NewDecoder(c.Request().Body).Decode(&entry); err != nil {
+		return validationError(err.Error())
+	}
+	switch {
+	case entry.RequestID == "":
+		return validationError("request_id is required")
+	case !ensembleAnalyses[entry.Analysis]:
+		return validationError("analysis must be entities or keywords")
+	case len(entry.Incorrect) == 0 && len(entry.Missing) == 0:
+		return validationError("at least one incorrect or missing item is required")
+	}
+	id := make([]byte, 16)
+	if _, err := rand.Read(id); err != nil {
+		return internalError(err)
+	}
+	entry.ID = hex.EncodeToString(id)
+	entry.Submitter = keyID(c.Request().Header.Get("X-API-Key"))
+	entry.SubmittedAt = time.Now().UTC()
+	feedback.add(entry)
+	metrics.Count("feedback_received_total", 1, map[string]string{"analysis": entry.Analysis})
+
+	return c.JSON(http.StatusCreated, entry)
+}
+
+// getFeedbackExport writes the stored feedback as NDJSON, one entry a line,
+// for building retraining sets; since=RFC 3339 time exports only newer entries.
+func getFeedbackExport(c echo.Context) error {
+	var since time.Time
+	if value := c.QueryParam("since"); value != "" {
+		t, err := time.Parse(time.RFC3339, value)
+		if err != nil {
+			return validationError(fmt.Sprintf("invalid since: %s", value))
+		}
+		since = t
+	}
+
+	res := c.Response()
+	res.Header().Set(echo.HeaderContentType, mimeApplicationNDJSON)
+	res.WriteHeader(http.StatusOK)
+	enc := json.NewEncoder(res)
+	for _, entry := range feedback.since(since, c.QueryParam("analysis")) {
+		if err := enc.Encode(entry); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,79 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFeedbackStore(t *testing.T) {
+	store := newFeedbackStore(2)
+	start := time.Now()
+	for i, id := range []string{"a", "b", "c"} {
+		store.add(feedbackEntry{ID: id, Analysis: "entities", SubmittedAt: start.Add(time.Duration(i) * time.Second)})
+	}
+
+	entries := store.since(time.Time{}, "")
+	if assert.Len(t, entries, 2, "the oldest entry is dropped") {
+		assert.Equal(t, "b", entries[0].ID)
+	}
+	assert.Len(t, store.since(start.Add(time.Second), ""), 1)
+	assert.Empty(t, store.since(time.Time{}, "keywords"))
+}
+
+func TestPostFeedback(t *testing.T) {
+	defer func(store *feedbackStore) { feedback = store }(feedback)
+	feedback = newFeedbackStore(10)
+
+	post := func(body string) (*httptest.ResponseRecorder, error) {
+		req := httptest.NewRequest(http.MethodPost, "/feedback", strings.NewReader(body))
+		req.Header.Set("X-API-Key", "secret")
+		w := httptest.NewRecorder()
+		return w, postFeedback(e.NewContext(req, w))
+	}
+
+	w, err := post(`{"request_id": "r1", "analysis": "entities", "text": "Paris Hilton",
+		"incorrect": [{"text": "Paris", "label": "GPE"}], "missing": [{"text": "Paris Hilton", "label": "PERSON"}]}`)
+	if assert.NoError(t, err) {
+		assert.Equal(t, http.StatusCreated, w.Code)
+		var entry feedbackEntry
+		if assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &entry)) {
+			assert.NotEmpty(t, entry.ID)
+			assert.Equal(t, keyID("secret"), entry.Submitter)
+		}
+	}
+	for _, body := range []string{
+		`{"analysis": "entities", "missing": [{"text": "Paris"}]}`,
+		`{"request_id": "r1", "analysis": "language", "missing": [{"text": "en"}]}`,
+		`{"request_id": "r1", "analysis": "keywords"}`,
+	} {
+		_, err := post(body)
+		assert.Error(t, err, body)
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/admin/feedback/export", nil)
+	w = httptest.NewRecorder()
+	if assert.NoError(t, getFeedbackExport(e.NewContext(req, w))) {
+		assert.Equal(t, mimeApplicationNDJSON, w.Header().Get("Content-Type"))
+		lines := 0
+		scanner := bufio.NewScanner(w.Body)
+		for scanner.Scan() {
+			var entry feedbackEntry
+			if assert.NoError(t, json.Unmarshal(scanner.Bytes(), &entry)) {
+				assert.Equal(t, "r1", entry.RequestID)
+				assert.Equal(t, []evaluatedItem{{"Paris Hilton", "PERSON"}}, entry.Missing)
+			}
+			lines++
+		}
+		assert.Equal(t, 1, lines)
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/admin/feedback/export?since=yesterday", nil)
+	assert.Error(t, getFeedbackExport(e.NewContext(req, httptest.NewRecorder())))
+}
@@ -0,0 +1,479 @@
+// author: Gary A. Stafford
+// site: https://programmaticponderings.com
+// license: MIT License
+// purpose: NLP microservices: nlp-client RSS and Atom feed ingestion
+// modified: 2026-10-14
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"golang.org/x/net/html"
+)
+
+var (
+	// comma-separated RSS or Atom feeds to ingest; unset to ingest none
+	feedURLs         = getEnv("FEED_URLS", "")
+	feedPollInterval = getEnv("FEED_POLL_INTERVAL", "15m")
+	// how long polling one feed, with its new items, may take
+	feedTimeout = getEnv("FEED_TIMEOUT", "60s")
+	// analyses run on every new item
+	feedAnalyses = getEnv("FEED_ANALYSES", "keywords,entities,language")
+	// most new items of a feed ingested per poll, in the feed's order
+	feedMaxItems = getEnv("FEED_MAX_ITEMS", "20")
+	// largest feed or article fetched, in bytes
+	feedMaxBytes = getEnv("FEED_MAX_BYTES", "5242880")
+	// fetch the article an item links to for its text, rather than only the
+	// feed's summary, from the feed's own host or these hosts; * for any
+	feedFetchArticles = getEnv("FEED_FETCH_ARTICLES", "true")
+	feedArticleHosts  = getEnv("FEED_ARTICLE_HOSTS", "")
+	// store each item as a record with its analyses, and the purpose to store
+	// them for where RECORD_REQUIRE_PURPOSE asks for one
+	feedRecord        = getEnv("FEED_RECORD", "true")
+	feedRecordPurpose = getEnv("FEED_RECORD_PURPOSE", "")
+
+	errFeedTooLarge = errors.New("response too large")
+
+	feeds = newFeedPoller()
+)
+
+// feedSeenItems is how many item IDs of a feed are remembered as ingested.
+const feedSeenItems = 1000
+
+// feedContentTags hold the text of an article, in order of preference.
+var feedContentTags = []string{"article", "main", "body"}
+
+// feedSkippedTags are the parts of a page that are not its article.
+var feedSkippedTags = map[string]bool{
+	"script": true, "style": true, "noscript": true, "nav": true, "header": true, "footer": true, "aside": true, "form": true,
+}
+
+// feedItem is an RSS item or Atom entry.
+type feedItem struct {
+	ID        string    `json:"id"`
+	Title     string    `json:"title"`
+	Link      string    `json:"link,omitempty"`
+	Published time.Time `json:"published,omitempty"`
+	summary   string
+}
+
+type rssItem struct {
+	GUID        string `xml:"guid"`
+	Title       string `xml:"title"`
+	Link        string `xml:"link"`
+	Description string `xml:"description"`
+	Content     string `xml:"http://purl.org/rss/1.0/modules/content/ encoded"`
+	PubDate     string `xml:"pubDate"`
+	Date        string `xml:"http://purl.org/dc/elements/1.1/ date"`
+}
+
+type atomEntry struct {
+	ID    string `xml:"id"`
+	Title string `xml:"title"`
+	Links []struct {
+		Href string `xml:"href,attr"`
+		Rel  string `xml:"rel,attr"`
+	} `xml:"link"`
+	Summary   string `xml:"summary"`
+	Content   string `xml:"content"`
+	Published string `xml:"published"`
+	Updated   string `xml:"updated"`
+}
+
+// feedDocument is an RSS 2.0, RSS 1.0, or Atom feed.
+type feedDocument struct {
+	Channel struct {
+		Items []rssItem `xml:"item"`
+	} `xml:"channel"`
+	Items   []rssItem   `xml:"item"`
+	Entries []atomEntry `xml:"entry"`
+}
+
+// parseFeedTime parses the dates of RSS and Atom feeds, or returns the zero time.
+func parseFeedTime(value string) time.Time {
+	value = strings.TrimSpace(value)
+	for _, layout := range []string{time.RFC1123Z, time.RFC1123, time.RFC3339, "Mon, 2 Jan 2006 15:04:05 -0700", "Mon, 2 Jan 2006 15:04:05 MST"} {
+		if t, err := time.Parse(layout, value); err == nil {
+			return t
+		}
+	}
+
+	return time.Time{}
+}
+
+// parseFeed returns the items of an RSS or Atom feed, in the feed's order.
+func parseFeed(r io.Reader) ([]feedItem, error) {
+	dec := xml.NewDecoder(r)
+	dec.CharsetReader = charsetReader
+	var doc feedDocument
+	if err := dec.Decode(&doc); err != nil {
+		return nil, err
+	}
+
+	var items []feedItem
+	for _, item := range append(doc.Channel.Items, doc.Items...) {
+		summary := item.Content
+		if summary == "" {
+			summary = item.Description
+		}
+		published := item.PubDate
+		if published == "" {
+			published = item.Date
+		}
+		parsed := feedItem{ID: strings.TrimSpace(item.GUID), Title: strings.TrimSpace(item.Title), Link: strings.TrimSpace(item.Link),
+			Published: parseFeedTime(published), summary: summary}
+		if parsed.ID == "" {
+			parsed.ID = parsed.Link
+		}
+		items = append(items, parsed)
+	}
+	for _, entry := range doc.Entries {
+		parsed := feedItem{ID: strings.TrimSpace(entry.ID), Title: strings.TrimSpace(entry.Title), summary: entry.Content}
+		for _, link := range entry.Links {
+			if link.Rel == "" || link.Rel == "alternate" {
+				parsed.Link = strings.TrimSpace(link.Href)
+				break
+			}
+		}
+		if parsed.summary == "" {
+			parsed.summary = entry.Summary
+		}
+		if parsed.Published = parseFeedTime(entry.Published); parsed.Published.IsZero() {
+			parsed.Published = parseFeedTime(entry.Updated)
+		}
+		if parsed.ID == "" {
+			parsed.ID = parsed.Link
+		}
+		items = append(items, parsed)
+	}
+
+	return items, nil
+}
+
+// articleText returns the text of a page's article: its article element, or
+// else its main or body, without navigation, scripts, and the like.
+func articleText(page string) (string, error) {
+	doc, err := html.Parse(strings.NewReader(page))
+	if err != nil {
+		return "", err
+	}
+	var find func(n *html.Node, tag string) *html.Node
+	find = func(n *html.Node, tag string) *html.Node {
+		if n.Type == html.ElementNode && n.Data == tag {
+			return n
+		}
+		for child := n.FirstChild; child != nil; child = child.NextSibling {
+			if found := find(child, tag); found != nil {
+				return found
+			}
+		}
+		return nil
+	}
+	var b strings.Builder
+	var collect func(n *html.Node)
+	collect = func(n *html.Node) {
+		switch {
+		case n.Type == html.TextNode:
+			b.WriteString(n.Data)
+		case n.Type == html.ElementNode && feedSkippedTags[n.Data]:
+			return
+		}
+		for child := n.FirstChild; child != nil; child = child.NextSibling {
+			collect(child)
+		}
+		if n.Type == html.ElementNode {
+			// block boundaries keep the words of adjacent elements apart
+			b.WriteByte('\n')
+		}
+	}
+	for _, tag := range feedContentTags {
+		if n := find(doc, tag); n != nil {
+			collect(n)
+			break
+		}
+	}
+	lines := strings.Split(b.String(), "\n")
+	var kept []string
+	for _, line := range lines {
+		if line = strings.Join(strings.Fields(line), " "); line != "" {
+			kept = append(kept, line)
+		}
+	}
+
+	return strings.Join(kept, "\n"), nil
+}
+
+// fetchFeedURL returns the body of a feed or article, of up to FEED_MAX_BYTES,
+// following redirects only to the URLs allowed accepts, if it is set.
+func fetchFeedURL(ctx context.Context, target string, allowed func(u *url.URL) bool) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, target, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("User-Agent", "nlp-client/"+version)
+	client := &http.Client{CheckRedirect: func(req *http.Request, via []*http.Request) error {
+		if allowed != nil && !allowed(req.URL) {
+			return fmt.Errorf("redirect to %s is not allowed", req.URL.Host)
+		}
+		if len(via) >= 10 {
+			return errors.New("stopped after 10 redirects")
+		}
+		return nil
+	}}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("%s returned %s", target, resp.Status)
+	}
+	limit := int64(envInt(feedMaxBytes, 5242880))
+	body, err := ioutil.ReadAll(io.LimitReader(resp.Body, limit+1))
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(body)) > limit {
+		return nil, errFeedTooLarge
+	}
+
+	return body, nil
+}
+
+// articleAllowed reports whether an item's article may be fetched: from the
+// feed's own host, or one of FEED_ARTICLE_HOSTS, so feeds cannot point the
+// service at other addresses.
+func articleAllowed(feed, link string) bool {
+	u, err := url.Parse(link)
+	if err != nil || u.Scheme != "http" && u.Scheme != "https" {
+		return false
+	}
+	if f, err := url.Parse(feed); err == nil && strings.EqualFold(f.Hostname(), u.Hostname()) {
+		return true
+	}
+	for _, host := range splitList(feedArticleHosts) {
+		if host == "*" || strings.EqualFold(host, u.Hostname()) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// feedStatus is the state of a feed's ingestion.
+type feedStatus struct {
+	URL      string    `json:"url"`
+	LastPoll time.Time `json:"last_poll,omitempty"`
+	Error    string    `json:"error,omitempty"`
+	Ingested int       `json:"ingested"`
+	Failed   int       `json:"failed"`
+	seen     map[string]bool
+	order    []string
+}
+
+// remember marks an item ingested, forgetting the oldest beyond feedSeenItems.
+func (s *feedStatus) remember(id string) {
+	if s.seen[id] {
+		return
+	}
+	s.seen[id] = true
+	s.order = append(s.order, id)
+	if len(s.order) > feedSeenItems {
+		delete(s.seen, s.order[0])
+		s.order = s.order[1:]
+	}
+}
+
+// feedPoller ingests the new items of each feed.
+type feedPoller struct {
+	mu     sync.Mutex
+	status map[string]*feedStatus
+}
+
+func newFeedPoller() *feedPoller {
+	return &feedPoller{status: map[string]*feedStatus{}}
+}
+
+func (p *feedPoller) feed(url string) *feedStatus {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	s, ok := p.status[url]
+	if !ok {
+		s = &feedStatus{URL: url, seen: map[string]bool{}}
+		p.status[url] = s
+	}
+
+	return s
+}
+
+// snapshot returns the status of each of the feeds.
+func (p *feedPoller) snapshot(urls []string) []feedStatus {
+	statuses := []feedStatus{}
+	for _, url := range urls {
+		s := p.feed(url)
+		p.mu.Lock()
+		statuses = append(statuses, *s)
+		p.mu.Unlock()
+	}
+
+	return statuses
+}
+
+// poll ingests the items of a feed it has not ingested before, up to
+// FEED_MAX_ITEMS of them. The first poll of a feed skips items published
+// before the previous poll interval, as an instance running before this one
+// ingested those.
+func (p *feedPoller) poll(ctx context.Context, feedURL string, interval time.Duration) {
+	s := p.feed(feedURL)
+	first := s.LastPoll.IsZero()
+	ingested, failed, err := p.ingestFeed(ctx, s, first, interval)
+
+	p.mu.Lock()
+	s.LastPoll = time.Now().UTC()
+	s.Error = ""
+	if err != nil {
+		s.Error = err.Error()
+	}
+	s.Ingested += ingested
+	s.Failed += failed
+	p.mu.Unlock()
+
+	tags := map[string]string{"feed": feedURL}
+	metrics.Count("feed_items_total", float64(ingested), tags)
+	if failed > 0 || err != nil {
+		metrics.Count("feed_errors_total", 1, tags)
+	}
+}
+
+func (p *feedPoller) ingestFeed(ctx context.Context, s *feedStatus, first bool, interval time.Duration) (int, int, error) {
+	body, err := fetchFeedURL(ctx, s.URL, nil)
+	if err != nil {
+		return 0, 0, err
+	}
+	items, err := parseFeed(bytes.NewReader(body))
+	if err != nil {
+		return 0, 0, err
+	}
+	analyses, err := parseAnalyses(feedAnalyses)
+	if err != nil {
+		return 0, 0, fmt.Errorf("FEED_ANALYSES: %v", err)
+	}
+
+	var fresh []feedItem
+	cutoff := time.Now().Add(-interval)
+	p.mu.Lock()
+	for _, item := range items {
+		switch {
+		case item.ID == "" || s.seen[item.ID]:
+		case first && !item.Published.IsZero() && item.Published.Before(cutoff):
+			s.remember(item.ID)
+		default:
+			fresh = append(fresh, item)
+		}
+	}
+	p.mu.Unlock()
+	if max := envInt(feedMaxItems, 20); len(fresh) > max {
+		fresh = fresh[:max]
+	}
+
+	ingested, failed := 0, 0
+	for _, item := range fresh {
+		if err := ingestItem(ctx, s.URL, item, analyses); err != nil {
+			e.Logger.Errorf("feed %s item %s: %v", s.URL, item.ID, err)
+			failed++
+			continue
+		}
+		p.mu.Lock()
+		s.remember(item.ID)
+		p.mu.Unlock()
+		ingested++
+	}
+
+	return ingested, failed, nil
+}
+
+// feedItemText returns the text of an item: its title, and its article, when it
+// may be fetched, or else its summary.
+func feedItemText(ctx context.Context, feedURL string, item feedItem) string {
+	text := ""
+	if envBool(feedFetchArticles) && articleAllowed(feedURL, item.Link) {
+		allowed := func(u *url.URL) bool { return articleAllowed(feedURL, u.String()) }
+		if page, err := fetchFeedURL(ctx, item.Link, allowed); err != nil {
+			e.Logger.Warnf("feed %s article %s: %v", feedURL, item.Link, err)
+		} else if article, err := articleText(string(page)); err == nil {
+			text = article
+		}
+	}
+	if text == "" {
+		text, _ = stripHTML(item.summary)
+	}
+
+	return strings.TrimSpace(item.Title + "\n\n" + text)
+}
+
+// ingestItem analyzes an item and stores it as a record, as though the
+// service's own key had sent it.
+func ingestItem(ctx context.Context, feedURL string, item feedItem, analyses []string) error {
+	text := feedItemText(ctx, feedURL, item)
+	key := integrationKey("")
+	results := runAnalysesConcurrently(ctx, text, analyses, key, envInt(analyzeConcurrency, 4))
+	if analysesStatus(results) == analysesFailed {
+		return results[analyses[0]].Error
+	}
+	if !envBool(feedRecord) {
+		return nil
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, "/feeds", nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("X-API-Key", key)
+	fields := map[string]interface{}{"source": "feed", "feed": feedURL, "item": item, "analyses": results}
+	if feedRecordPurpose != "" {
+		fields["purpose"] = feedRecordPurpose
+	}
+
+	return storeRecord(e.NewContext(req, httptest.NewRecorder()), text, fields)
+}
+
+func startFeedPoller() {
+	urls := splitList(feedURLs)
+	if len(urls) == 0 {
+		return
+	}
+	interval := envDuration(feedPollInterval, 15*time.Minute)
+	timeout := envDuration(feedTimeout, time.Minute)
+	go func() {
+		for {
+			for _, url := range urls {
+				ctx, cancel := context.WithTimeout(context.Background(), timeout)
+				feeds.poll(ctx, url, interval)
+				cancel()
+			}
+			time.Sleep(interval)
+		}
+	}()
+}
+
+// getFeeds answers with the ingestion status of each configured feed.
+func getFeeds(c echo.Context) error {
+	return c.JSON(http.StatusOK, struct {
+		Feeds []feedStatus `json:"feeds"`
+	}{feeds.snapshot(splitList(feedURLs))})
+}
@@ -0,0 +1,120 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/garystafford/nlp-client/mockupstream"
+	"github.com/stretchr/testify/assert"
+)
+
+const rssFeed = `<?xml version="1.0" encoding="UTF-8"?>
+<rss version="2.0" xmlns:content="http://purl.org/rss/1.0/modules/content/">
+<channel>
+<item><guid>1</guid><title>Marie Curie</title><link>ARTICLE</link><description>Summary one</description></item>
+<item><guid>2</guid><title>Nobel Prize</title><link>https://example.com/2</link><content:encoded><![CDATA[<p>Content <b>two</b></p>]]></content:encoded><pubDate>Mon, 12 Oct 2020 09:00:00 +0000</pubDate></item>
+</channel>
+</rss>`
+
+const atomFeed = `<?xml version="1.0" encoding="UTF-8"?>
+<feed xmlns="http://www.w3.org/2005/Atom">
+<entry><id>urn:1</id><title>Marie Curie</title><link rel="alternate" href="https://example.com/1"/><summary>Summary</summary><updated>2026-10-12T09:00:00Z</updated></entry>
+</feed>`
+
+const articlePage = `<html><body><nav>Home | About</nav><article><h1>Marie Curie</h1><p>won the Nobel Prize.</p>` +
+	`<script>track()</script></article><footer>Copyright</footer></body></html>`
+
+func TestParseFeed(t *testing.T) {
+	items, err := parseFeed(strings.NewReader(rssFeed))
+	if assert.NoError(t, err) && assert.Len(t, items, 2) {
+		assert.Equal(t, "1", items[0].ID)
+		assert.Equal(t, "Summary one", items[0].summary)
+		assert.Equal(t, "<p>Content <b>two</b></p>", items[1].summary, "content is preferred to the description")
+		assert.Equal(t, time.Date(2020, 10, 12, 9, 0, 0, 0, time.UTC), items[1].Published.UTC())
+	}
+
+	items, err = parseFeed(strings.NewReader(atomFeed))
+	if assert.NoError(t, err) && assert.Len(t, items, 1) {
+		assert.Equal(t, "urn:1", items[0].ID)
+		assert.Equal(t, "https://example.com/1", items[0].Link)
+		assert.False(t, items[0].Published.IsZero(), "updated stands in for published")
+	}
+
+	_, err = parseFeed(strings.NewReader("not a feed"))
+	assert.Error(t, err)
+}
+
+func TestArticleText(t *testing.T) {
+	text, err := articleText(articlePage)
+	if assert.NoError(t, err) {
+		assert.Equal(t, "Marie Curie\nwon the Nobel Prize.", text)
+	}
+
+	text, err = articleText(`<html><body><header>Site</header><p>Only a body</p></body></html>`)
+	if assert.NoError(t, err) {
+		assert.Equal(t, "Only a body", text)
+	}
+}
+
+func TestArticleAllowed(t *testing.T) {
+	defer func(hosts string) { feedArticleHosts = hosts }(feedArticleHosts)
+	feedArticleHosts = "news.example.org"
+
+	assert.True(t, articleAllowed("https://example.com/feed", "https://example.com/1"))
+	assert.True(t, articleAllowed("https://example.com/feed", "https://news.example.org/1"))
+	assert.False(t, articleAllowed("https://example.com/feed", "http://169.254.169.254/latest/meta-data"))
+	assert.False(t, articleAllowed("https://example.com/feed", "file:///etc/passwd"))
+}
+
+func TestFeedPoll(t *testing.T) {
+	rake := mockupstream.NewRake()
+	defer rake.Close()
+	dynamo := mockupstream.NewDynamo()
+	defer dynamo.Close()
+	var site *httptest.Server
+	site = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/feed":
+			_, _ = w.Write([]byte(strings.Replace(rssFeed, "ARTICLE", site.URL+"/article", 1)))
+		case "/article":
+			_, _ = w.Write([]byte(articlePage))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer site.Close()
+	defer func(rakeURL, dynamoURL, analyses string) {
+		urlRake, urlDynamo, feedAnalyses = rakeURL, dynamoURL, analyses
+	}(urlRake, urlDynamo, feedAnalyses)
+	urlRake, urlDynamo, feedAnalyses = rake.URL, dynamo.URL, "keywords"
+
+	poller := newFeedPoller()
+	poller.poll(context.Background(), site.URL+"/feed", time.Hour)
+	status := poller.snapshot([]string{site.URL + "/feed"})[0]
+	assert.Empty(t, status.Error)
+	assert.Equal(t, 1, status.Ingested, "the first poll skips items older than the interval")
+
+	var records []map[string]interface{}
+	for _, req := range dynamo.Requests() {
+		var record map[string]interface{}
+		assert.NoError(t, json.Unmarshal([]byte(req.Body), &record))
+		records = append(records, record)
+	}
+	if assert.Len(t, records, 1) {
+		assert.Equal(t, "Marie Curie\n\nMarie Curie\nwon the Nobel Prize.", records[0]["text"], "the article is fetched for the text")
+		assert.Equal(t, "feed", records[0]["source"])
+		assert.Contains(t, records[0], "analyses")
+	}
+
+	poller.poll(context.Background(), site.URL+"/feed", time.Hour)
+	assert.Len(t, dynamo.Requests(), 1, "items are ingested once")
+	assert.Equal(t, 1, poller.snapshot([]string{site.URL + "/feed"})[0].Ingested)
+
+	poller.poll(context.Background(), site.URL+"/missing", time.Hour)
+	assert.Contains(t, poller.snapshot([]string{site.URL + "/missing"})[0].Error, "404")
+}
@@ -0,0 +1,185 @@
+// author: Gary A. Stafford
+// site: https://programmaticponderings.com
+// license: MIT License
+// purpose: NLP microservices: nlp-client result filters
+// modified: 2026-10-14
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+)
+
+// filteredRoutes answer with a JSON array the result filters apply to.
+var filteredRoutes = map[string]bool{"/tokens": true, "/entities": true, "/sentences": true, "/keywords": true}
+
+// filterParams are the query parameters of the result filters.
+var filterParams = []string{"min_score", "min_confidence", "dedupe", "lowercase", "limit", "punctuation", "split_contractions"}
+
+// resultFilter drops and rewrites the items of an analysis result, for
+// callers of upstreams that cannot do so themselves.
+type resultFilter struct {
+	minScore      *float64
+	minConfidence *float64
+	dedupe        bool
+	lowercase     bool
+	// zero for no limit
+	limit int
+	// tokens only: false drops punctuation, and true or false splits or
+	// joins contractions; nil leaves the tokenizer's
+	punctuation       *bool
+	splitContractions *bool
+}
+
+// parseResultFilter returns the filter a request asks for with the min_score,
+// min_confidence, dedupe, lowercase, limit, punctuation, and
+// split_contractions query parameters, or nil when the request asks for none.
+// On paged routes, limit is the page's.
+func parseResultFilter(c echo.Context) (*resultFilter, error) {
+	f := &resultFilter{}
+	var err error
+	for name, bound := range map[string]**float64{"min_score": &f.minScore, "min_confidence": &f.minConfidence} {
+		if value := c.QueryParam(name); value != "" {
+			number, err := strconv.ParseFloat(value, 64)
+			if err != nil {
+				return nil, fmt.Errorf("invalid %s: %s", name, value)
+			}
+			*bound = &number
+		}
+	}
+	for name, flag := range map[string]*bool{"dedupe": &f.dedupe, "lowercase": &f.lowercase} {
+		if value := c.QueryParam(name); value != "" {
+			if *flag, err = strconv.ParseBool(value); err != nil {
+				return nil, fmt.Errorf("invalid %s: %s", name, value)
+			}
+		}
+	}
+	for name, option := range map[string]**bool{"punctuation": &f.punctuation, "split_contractions": &f.splitContractions} {
+		if value := c.QueryParam(name); value != "" {
+			flag, err := strconv.ParseBool(value)
+			if err != nil {
+				return nil, fmt.Errorf("invalid %s: %s", name, value)
+			}
+			*option = &flag
+		}
+	}
+	if limit := c.QueryParam("limit"); limit != "" && !pagedRoutes[routePath(c)] {
+		maxLimit := envInt(pageMaxLimit, 1000)
+		if f.limit, err = strconv.Atoi(limit); err != nil || f.limit < 1 || f.limit > maxLimit {
+			return nil, fmt.Errorf("limit must be between 1 and %d", maxLimit)
+		}
+	}
+	if f.minScore == nil && f.minConfidence == nil && !f.dedupe && !f.lowercase && f.limit == 0 && f.punctuation == nil && f.splitContractions == nil {
+		return nil, nil
+	}
+
+	return f, nil
+}
+
+// itemText returns the text of a result item: the item itself, or its text
+// or candidate field.
+func itemText(item interface{}) (string, bool) {
+	switch item := item.(type) {
+	case string:
+		return item, true
+	case map[string]interface{}:
+		for _, field := range []string{"text", "candidate"} {
+			if text, ok := item[field].(string); ok {
+				return text, true
+			}
+		}
+	}
+
+	return "", false
+}
+
+// below reports whether an item has a numeric field under min; items without
+// the field are kept.
+func below(item interface{}, field string, min *float64) bool {
+	if min == nil {
+		return false
+	}
+	fields, ok := item.(map[string]interface{})
+	if !ok {
+		return false
+	}
+	value, ok := fields[field].(float64)
+
+	return ok && value < *min
+}
+
+// apply filters the result of an analysis route, in order: the tokenizer
+// options of tokens, by score and confidence, lowercasing, deduplication,
+// then the limit. Results of other routes are returned as they are.
+func (f *resultFilter) apply(route string, body []byte) ([]byte, error) {
+	if !filteredRoutes[route] {
+		return body, nil
+	}
+	if route == "/tokens" && (f.punctuation != nil || f.splitContractions != nil) {
+		var tokens []interface{}
+		if err := json.Unmarshal(body, &tokens); err != nil {
+			return nil, err
+		}
+		var err error
+		if body, err = json.Marshal(f.tokenize(tokens)); err != nil {
+			return nil, err
+		}
+	}
+	var items []json.RawMessage
+	if err := json.Unmarshal(body, &items); err != nil {
+		return nil, err
+	}
+
+	filtered := []json.RawMessage{}
+	seen := map[string]bool{}
+	for _, raw := range items {
+		var item interface{}
+		if err := json.Unmarshal(raw, &item); err != nil {
+			return nil, err
+		}
+		if below(item, "score", f.minScore) || below(item, "confidence", f.minConfidence) {
+			continue
+		}
+		text, ok := itemText(item)
+		if f.lowercase && ok && text != strings.ToLower(text) {
+			text = strings.ToLower(text)
+			switch fields := item.(type) {
+			case string:
+				item = text
+			case map[string]interface{}:
+				if _, ok := fields["text"].(string); ok {
+					fields["text"] = text
+				} else {
+					fields["candidate"] = text
+				}
+			}
+			var err error
+			if raw, err = json.Marshal(item); err != nil {
+				return nil, err
+			}
+		}
+		if f.dedupe && ok {
+			// entities of the same text with different labels are kept
+			key := text
+			if fields, isObject := item.(map[string]interface{}); isObject {
+				label, _ := fields["label"].(string)
+				key += "\x00" + label
+			}
+			if seen[key] {
+				continue
+			}
+			seen[key] = true
+		}
+		filtered = append(filtered, raw)
+		if len(filtered) == f.limit {
+			break
+		}
+	}
+
+	return json.Marshal(filtered)
+}
@@ -0,0 +1,91 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/garystafford/nlp-client/mockupstream"
+	"github.com/stretchr/testify/assert"
+)
+
+func requestFilter(target string) *resultFilter {
+	req := httptest.NewRequest(http.MethodPost, target, nil)
+	c := e.NewContext(req, httptest.NewRecorder())
+	c.SetPath(strings.SplitN(target, "?", 2)[0])
+	f, _ := parseResultFilter(c)
+	return f
+}
+
+func TestParseResultFilter(t *testing.T) {
+	assert.Nil(t, requestFilter("/keywords"))
+	assert.Nil(t, requestFilter("/tokens?limit=2"), "limit pages the paged routes")
+	f := requestFilter("/keywords?min_score=2.5&dedupe=true&limit=3")
+	if assert.NotNil(t, f) {
+		assert.Equal(t, 2.5, *f.minScore)
+		assert.True(t, f.dedupe)
+		assert.Equal(t, 3, f.limit)
+	}
+
+	for _, query := range []string{"min_score=high", "min_confidence=x", "dedupe=maybe", "limit=0"} {
+		req := httptest.NewRequest(http.MethodPost, "/keywords?"+query, nil)
+		c := e.NewContext(req, httptest.NewRecorder())
+		c.SetPath("/keywords")
+		_, err := parseResultFilter(c)
+		assert.Error(t, err, query)
+	}
+}
+
+func TestResultFilterApply(t *testing.T) {
+	keywords := []byte(`[{"candidate":"Nobel Prize","score":4},{"candidate":"nobel prize","score":4},{"candidate":"award","score":1},{"candidate":"Marie Curie","score":3.5}]`)
+	f := &resultFilter{minScore: new(float64), dedupe: true, lowercase: true}
+	*f.minScore = 2
+	body, err := f.apply("/keywords", keywords)
+	if assert.NoError(t, err) {
+		assert.JSONEq(t, `[{"candidate":"nobel prize","score":4},{"candidate":"marie curie","score":3.5}]`, string(body))
+	}
+
+	f = &resultFilter{limit: 1}
+	body, err = f.apply("/keywords", keywords)
+	if assert.NoError(t, err) {
+		assert.Equal(t, `[{"candidate":"Nobel Prize","score":4}]`, string(body))
+	}
+
+	entities := []byte(`[{"text":"Paris","label":"GPE"},{"text":"Paris","label":"PERSON"},{"text":"Paris","label":"GPE","confidence":0.2}]`)
+	f = &resultFilter{minConfidence: new(float64), dedupe: true}
+	*f.minConfidence = 0.5
+	body, err = f.apply("/entities", entities)
+	if assert.NoError(t, err) {
+		assert.JSONEq(t, `[{"text":"Paris","label":"GPE"},{"text":"Paris","label":"PERSON"}]`, string(body))
+	}
+
+	f = &resultFilter{lowercase: true, dedupe: true}
+	body, err = f.apply("/tokens", []byte(`["The","the","Prize"]`))
+	if assert.NoError(t, err) {
+		assert.Equal(t, `["the","prize"]`, string(body))
+	}
+
+	language := []byte(`{"language":"en","probability":0.99}`)
+	body, err = f.apply("/language", language)
+	if assert.NoError(t, err) {
+		assert.Equal(t, language, body)
+	}
+}
+
+func TestGetKeywordsFiltered(t *testing.T) {
+	rake := mockupstream.NewRake()
+	defer rake.Close()
+	defer func(url string) { urlRake = url }(urlRake)
+	urlRake = rake.URL
+	rake.SetFixture("/keywords", mockupstream.Fixture{Status: http.StatusOK,
+		Body: `[{"candidate":"nobel prize","score":4},{"candidate":"award","score":1}]`})
+
+	req := httptest.NewRequest(http.MethodPost, "/keywords?min_score=2", strings.NewReader(`{"text": "The Nobel Prize award"}`))
+	w := httptest.NewRecorder()
+	c := e.NewContext(req, w)
+	c.SetPath("/keywords")
+	if assert.NoError(t, getKeywords(c)) {
+		assert.JSONEq(t, `[{"candidate":"nobel prize","score":4}]`, w.Body.String())
+	}
+}
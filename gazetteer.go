@@ -0,0 +1,256 @@
+// author: Gary A. Stafford
+// site: https://programmaticponderings.com
+// license: MIT License
+// purpose: NLP microservices: nlp-client tenant gazetteers
+// modified: 2026-10-14
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"unicode"
+
+	"github.com/labstack/echo/v4"
+)
+
+// most terms one tenant's gazetteer may have
+var gazetteerMaxTerms = getEnv("GAZETTEER_MAX_TERMS", "100000")
+
+var gazetteers = newGazetteerStore()
+
+// gazetteerUpload is a tenant's term lists, by the entity label their terms are returned with.
+type gazetteerUpload struct {
+	Types         map[string][]string `json:"types"`
+	CaseSensitive bool                `json:"case_sensitive"`
+}
+
+// gazetteer matches the terms of an upload in a text with an Aho-Corasick
+// automaton, so a text is scanned once however many terms there are.
+type gazetteer struct {
+	upload gazetteerUpload
+	digest string
+	nodes  []acNode
+	// the label and length, in runes, of each term
+	labels  []string
+	lengths []int
+}
+
+type acNode struct {
+	next map[rune]int
+	fail int
+	// the terms that end at the node, including by its fail links
+	terms []int
+}
+
+// localMatch is an entity found in a text by the client rather than an
+// upstream, in characters, with the source that found it and, of overlapping
+// matches, the priority to keep it by.
+type localMatch struct {
+	start, end int
+	label      string
+	source     string
+	priority   int
+}
+
+func newGazetteer(upload gazetteerUpload) *gazetteer {
+	g := &gazetteer{upload: upload, nodes: []acNode{{next: map[rune]int{}}}}
+	labels := make([]string, 0, len(upload.Types))
+	for label := range upload.Types {
+		labels = append(labels, label)
+	}
+	sort.Strings(labels)
+	for _, label := range labels {
+		for _, term := range upload.Types[label] {
+			node := 0
+			for _, r := range g.fold(term) {
+				child, ok := g.nodes[node].next[r]
+				if !ok {
+					child = len(g.nodes)
+					g.nodes = append(g.nodes, acNode{next: map[rune]int{}})
+					g.nodes[node].next[r] = child
+				}
+				node = child
+			}
+			g.nodes[node].terms = append(g.nodes[node].terms, len(g.labels))
+			g.labels = append(g.labels, label)
+			g.lengths = append(g.lengths, len(g.fold(term)))
+		}
+	}
+
+	// fail links, breadth first, so each node's is set before its children's
+	queue := []int{}
+	for _, child := range g.nodes[0].next {
+		queue = append(queue, child)
+	}
+	for len(queue) > 0 {
+		node := queue[0]
+		queue = queue[1:]
+		for r, child := range g.nodes[node].next {
+			fail := g.nodes[node].fail
+			for fail > 0 && g.nodes[fail].next[r] == 0 {
+				fail = g.nodes[fail].fail
+			}
+			if next, ok := g.nodes[fail].next[r]; ok && next != child {
+				fail = next
+			} else {
+				fail = 0
+			}
+			g.nodes[child].fail = fail
+			g.nodes[child].terms = append(g.nodes[child].terms, g.nodes[fail].terms...)
+			queue = append(queue, child)
+		}
+	}
+
+	return g
+}
+
+func (g *gazetteer) fold(text string) []rune {
+	if g.upload.CaseSensitive {
+		return []rune(text)
+	}
+
+	return foldRunes(text)
+}
+
+// find returns the terms in text that are whole words, leftmost first and, of
+// those starting at the same place, longest, without overlaps.
+func (g *gazetteer) find(text string) []localMatch {
+	runes := g.fold(text)
+	boundary := func(i int) bool {
+		return i < 0 || i >= len(runes) || !unicode.IsLetter(runes[i]) && !unicode.IsDigit(runes[i])
+	}
+	var found []localMatch
+	node := 0
+	for i, r := range runes {
+		for node > 0 && g.nodes[node].next[r] == 0 {
+			node = g.nodes[node].fail
+		}
+		node = g.nodes[node].next[r]
+		for _, term := range g.nodes[node].terms {
+			start := i + 1 - g.lengths[term]
+			if boundary(start-1) && boundary(i+1) {
+				found = append(found, localMatch{start, i + 1, g.labels[term], "gazetteer", 0})
+			}
+		}
+	}
+	sort.SliceStable(found, func(i, j int) bool {
+		if found[i].start != found[j].start {
+			return found[i].start < found[j].start
+		}
+		return found[i].end > found[j].end
+	})
+
+	var matches []localMatch
+	for _, m := range found {
+		if len(matches) == 0 || m.start >= matches[len(matches)-1].end {
+			matches = append(matches, m)
+		}
+	}
+
+	return matches
+}
+
+// gazetteerStore keeps the gazetteers tenants upload to a single instance in memory.
+type gazetteerStore struct {
+	mu         sync.RWMutex
+	gazetteers map[string]*gazetteer
+}
+
+func newGazetteerStore() *gazetteerStore {
+	return &gazetteerStore{gazetteers: map[string]*gazetteer{}}
+}
+
+func (s *gazetteerStore) get(tenant string) *gazetteer {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return s.gazetteers[tenant]
+}
+
+func (s *gazetteerStore) set(tenant string, g *gazetteer) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if g == nil {
+		delete(s.gazetteers, tenant)
+		return
+	}
+	s.gazetteers[tenant] = g
+}
+
+// digest identifies the content of a tenant's gazetteer, for the ETags of
+// the entities it is merged into; it is empty without one.
+func (s *gazetteerStore) digest(tenant string) string {
+	if g := s.get(tenant); g != nil {
+		return g.digest
+	}
+
+	return ""
+}
+
+// putGazetteer replaces the gazetteer of the request's tenant.
+func putGazetteer(c echo.Context) error {
+	tenant, err := tenantOf(c)
+	if err != nil {
+		return err
+	}
+	var upload gazetteerUpload
+	if err := json.NewDecoder(c.Request().Body).Decode(&upload); err != nil {
+		return validationError(err.Error())
+	}
+	if upload.Types == nil {
+		upload.Types = map[string][]string{}
+	}
+	total := 0
+	entries := []string{fmt.Sprint(upload.CaseSensitive)}
+	for label, terms := range upload.Types {
+		if strings.TrimSpace(label) == "" {
+			return validationError("type names must not be empty")
+		}
+		for _, term := range terms {
+			if strings.TrimSpace(term) == "" {
+				return validationError(fmt.Sprintf("%s has an empty term", label))
+			}
+			entries = append(entries, label+"\x00"+term)
+		}
+		total += len(terms)
+	}
+	if max := envInt(gazetteerMaxTerms, 100000); total > max {
+		return newAPIError(http.StatusRequestEntityTooLarge, codePayloadTooLarge, fmt.Sprintf("more than %d gazetteer terms", max))
+	}
+	sort.Strings(entries)
+
+	g := newGazetteer(upload)
+	g.digest = hashHex(entries...)
+	gazetteers.set(tenant, g)
+
+	return c.JSON(http.StatusOK, upload)
+}
+
+func getGazetteer(c echo.Context) error {
+	tenant, err := tenantOf(c)
+	if err != nil {
+		return err
+	}
+	g := gazetteers.get(tenant)
+	if g == nil {
+		return newAPIError(http.StatusNotFound, codeNotFound, "no gazetteer is uploaded for the tenant")
+	}
+
+	return c.JSON(http.StatusOK, g.upload)
+}
+
+func deleteGazetteer(c echo.Context) error {
+	tenant, err := tenantOf(c)
+	if err != nil {
+		return err
+	}
+	gazetteers.set(tenant, nil)
+
+	return c.NoContent(http.StatusNoContent)
+}
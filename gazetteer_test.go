@@ -0,0 +1,84 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/garystafford/nlp-client/mockupstream"
+	"github.com/stretchr/testify/assert"
+)
+
+// uploadGazetteer puts a gazetteer for a tenant for the duration of a test.
+func uploadGazetteer(t *testing.T, tenant, body string) error {
+	t.Cleanup(func() { gazetteers.set(tenant, nil) })
+	req := httptest.NewRequest(http.MethodPut, "/gazetteer", strings.NewReader(body))
+	req.Header.Set(tenantHeader, tenant)
+	return putGazetteer(e.NewContext(req, httptest.NewRecorder()))
+}
+
+func TestGazetteerFind(t *testing.T) {
+	g := newGazetteer(gazetteerUpload{Types: map[string][]string{
+		"GPE":  {"New York", "New York City", "York"},
+		"DRUG": {"ibuprofen", "Advil"},
+	}})
+
+	text := "Ibuprofen sold in New York City, not ibuprofenol, in york."
+	assert.Equal(t, []localMatch{
+		{0, 9, "DRUG", "gazetteer", 0},
+		{18, 31, "GPE", "gazetteer", 0},
+		{53, 57, "GPE", "gazetteer", 0},
+	}, g.find(text), "the longest term wins, within whole words, regardless of case")
+
+	nested := newGazetteer(gazetteerUpload{Types: map[string][]string{"GPE": {"New Yorker", "York"}}})
+	assert.Equal(t, []localMatch{{4, 8, "GPE", "gazetteer", 0}}, nested.find("New York"), "terms inside the prefix of another are found")
+
+	cased := newGazetteer(gazetteerUpload{Types: map[string][]string{"PRODUCT": {"Go"}}, CaseSensitive: true})
+	assert.Len(t, cased.find("go to Go"), 1)
+}
+
+func TestGazetteerMerge(t *testing.T) {
+	assert.NoError(t, uploadGazetteer(t, "acme", `{"types": {"DRUG": ["ibuprofen"], "PRODUCT": ["Galaxy S24"]}}`))
+	text := "Marie took ibuprofen for her Galaxy S24."
+
+	v1, err := mergeLocalEntities("acme", "/entities", "", text, []byte(`[{"text":"Galaxy","label":"ORG"},{"text":"Marie","label":"PERSON"}]`))
+	if assert.NoError(t, err) {
+		assert.JSONEq(t, `[{"text":"Marie","label":"PERSON"},{"text":"ibuprofen","label":"DRUG","source":"gazetteer"},`+
+			`{"text":"Galaxy S24","label":"PRODUCT","source":"gazetteer"}]`, string(v1), "upstream entities a term overlaps are replaced")
+	}
+	v2, err := mergeLocalEntities("acme", "/entities", schemaV2, text, []byte(`[{"text":"Marie","label":"PERSON","offset":0,"length":5}]`))
+	if assert.NoError(t, err) {
+		assert.JSONEq(t, `[{"text":"Marie","label":"PERSON","offset":0,"length":5},`+
+			`{"text":"ibuprofen","label":"DRUG","offset":11,"length":9,"source":"gazetteer"},`+
+			`{"text":"Galaxy S24","label":"PRODUCT","offset":29,"length":10,"source":"gazetteer"}]`, string(v2))
+	}
+	unchanged, err := mergeLocalEntities("globex", "/entities", "", text, []byte(`[]`))
+	if assert.NoError(t, err) {
+		assert.Equal(t, `[]`, string(unchanged))
+	}
+}
+
+func TestPutGazetteer(t *testing.T) {
+	assert.Error(t, uploadGazetteer(t, "acme", `{"types": {"DRUG": [" "]}}`))
+	defer func(max string) { gazetteerMaxTerms = max }(gazetteerMaxTerms)
+	gazetteerMaxTerms = "1"
+	assert.Error(t, uploadGazetteer(t, "acme", `{"types": {"DRUG": ["ibuprofen", "aspirin"]}}`))
+}
+
+func TestGetEntitiesGazetteer(t *testing.T) {
+	assert.NoError(t, uploadGazetteer(t, "acme", `{"types": {"DRUG": ["ibuprofen"]}}`))
+	prose := mockupstream.NewProse()
+	defer prose.Close()
+	defer func(url string) { urlProse = url }(urlProse)
+	urlProse = prose.URL
+
+	req := httptest.NewRequest(http.MethodPost, "/entities", strings.NewReader(`{"text": "Marie Curie took ibuprofen"}`))
+	req.Header.Set(tenantHeader, "acme")
+	w := httptest.NewRecorder()
+	c := e.NewContext(req, w)
+	c.SetPath("/entities")
+	if assert.NoError(t, getEntities(c)) {
+		assert.JSONEq(t, `[{"text":"Marie Curie","label":"PERSON"},{"text":"ibuprofen","label":"DRUG","source":"gazetteer"}]`, w.Body.String())
+	}
+}
@@ -0,0 +1,279 @@
+// author: Gary A. Stafford
+// site: https://programmaticponderings.com
+// license: MIT License
+// purpose: NLP microservices: nlp-client geocoding of place entities
+// modified: 2026-10-14
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/labstack/echo/v4"
+)
+
+var (
+	// "nominatim" or "pelias" for the geocoder at GEOCODE_ENDPOINT; unset disables ?enrich=geo
+	geocodeBackend  = getEnv("GEOCODE_BACKEND", "")
+	urlGeocode      = upstreamURL(getEnv("GEOCODE_ENDPOINT", ""))
+	geocodeTimeout  = getEnv("GEOCODE_TIMEOUT", "2s")
+	geocodeCacheTTL = getEnv("GEOCODE_CACHE_TTL", "24h")
+	// comma-separated entity labels that name places
+	geocodeLabels = getEnv("GEOCODE_LABELS", "GPE,LOC,LOCATION,FAC")
+
+	geocodeOnce   sync.Once
+	geocodeEngine geocodeFunc
+	geocodeErr    error
+
+	geocodes = newGeocodeCache()
+)
+
+// enrichedRoutes answer with entities that ?enrich=geo resolves to places.
+var enrichedRoutes = map[string]bool{"/entities": true}
+
+// geoPlace is where a place entity was resolved to, with its canonical name.
+type geoPlace struct {
+	Lat  float64 `json:"lat"`
+	Lon  float64 `json:"lon"`
+	Name string  `json:"name"`
+}
+
+// geocodeFunc resolves a place name, returning nil when the geocoder knows no such place.
+type geocodeFunc func(ctx context.Context, place string) (*geoPlace, error)
+
+func loadGeocoder() (geocodeFunc, error) {
+	geocodeOnce.Do(func() {
+		geocodeEngine, geocodeErr = newGeocoder(geocodeBackend)
+	})
+
+	return geocodeEngine, geocodeErr
+}
+
+func newGeocoder(backend string) (geocodeFunc, error) {
+	if urlGeocode == "" {
+		return nil, fmt.Errorf("GEOCODE_ENDPOINT is required")
+	}
+	switch backend {
+	case "nominatim":
+		return nominatimGeocoder(urlGeocode), nil
+	case "pelias":
+		return peliasGeocoder(urlGeocode), nil
+	}
+
+	return nil, fmt.Errorf("unsupported GEOCODE_BACKEND: %q", backend)
+}
+
+// getGeocoder fetches a geocoder search and decodes its JSON response into result.
+func getGeocoder(ctx context.Context, endpoint string, result interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+	if err != nil {
+		return err
+	}
+	// public geocoders refuse requests without an identifying User-Agent
+	req.Header.Set("User-Agent", "nlp-client/"+version)
+	req.Header.Set(echo.HeaderAccept, echo.MIMEApplicationJSON)
+
+	resp, err := upstreamClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer func(Body io.ReadCloser) {
+		err := Body.Close()
+		if err != nil {
+			e.Logger.Error(err)
+		}
+	}(resp.Body)
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return newUpstreamStatusError(resp)
+	}
+
+	return json.NewDecoder(resp.Body).Decode(result)
+}
+
+// nominatimGeocoder searches an OpenStreetMap Nominatim server.
+func nominatimGeocoder(endpoint string) geocodeFunc {
+	return func(ctx context.Context, place string) (*geoPlace, error) {
+		var results []struct {
+			Lat         float64 `json:"lat,string"`
+			Lon         float64 `json:"lon,string"`
+			DisplayName string  `json:"display_name"`
+		}
+		query := url.Values{"q": {place}, "format": {"jsonv2"}, "limit": {"1"}}
+		if err := getGeocoder(ctx, endpoint+"/search?"+query.Encode(), &results); err != nil {
+			return nil, err
+		}
+		if len(results) == 0 {
+			return nil, nil
+		}
+		return &geoPlace{results[0].Lat, results[0].Lon, results[0].DisplayName}, nil
+	}
+}
+
+// peliasGeocoder searches a Pelias server, which answers with GeoJSON.
+func peliasGeocoder(endpoint string) geocodeFunc {
+	return func(ctx context.Context, place string) (*geoPlace, error) {
+		var result struct {
+			Features []struct {
+				Geometry struct {
+					Coordinates []float64 `json:"coordinates"`
+				} `json:"geometry"`
+				Properties struct {
+					Label string `json:"label"`
+				} `json:"properties"`
+			} `json:"features"`
+		}
+		query := url.Values{"text": {place}, "size": {"1"}}
+		if err := getGeocoder(ctx, endpoint+"/v1/search?"+query.Encode(), &result); err != nil {
+			return nil, err
+		}
+		if len(result.Features) == 0 || len(result.Features[0].Geometry.Coordinates) < 2 {
+			return nil, nil
+		}
+		feature := result.Features[0]
+		// GeoJSON coordinates are longitude first
+		return &geoPlace{feature.Geometry.Coordinates[1], feature.Geometry.Coordinates[0], feature.Properties.Label}, nil
+	}
+}
+
+type geocodeEntry struct {
+	place   *geoPlace
+	expires time.Time
+}
+
+// geocodeCache remembers what places resolved to, including those that did
+// not resolve, so the geocoder is asked about each place once per TTL.
+type geocodeCache struct {
+	mu      sync.Mutex
+	entries map[string]geocodeEntry
+}
+
+func newGeocodeCache() *geocodeCache {
+	return &geocodeCache{entries: map[string]geocodeEntry{}}
+}
+
+func (g *geocodeCache) get(place string) (*geoPlace, bool) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	entry, ok := g.entries[place]
+	if !ok || time.Now().After(entry.expires) {
+		return nil, false
+	}
+
+	return entry.place, true
+}
+
+func (g *geocodeCache) set(place string, resolved *geoPlace, ttl time.Duration) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	now := time.Now()
+	for key, entry := range g.entries {
+		if now.After(entry.expires) {
+			delete(g.entries, key)
+		}
+	}
+	g.entries[place] = geocodeEntry{resolved, now.Add(ttl)}
+}
+
+// geocodePlaces resolves each distinct place at once, with the cache.
+// Places the geocoder fails on are left unresolved, and counted.
+func geocodePlaces(ctx context.Context, places []string) (map[string]*geoPlace, error) {
+	geocode, err := loadGeocoder()
+	if err != nil {
+		return nil, err
+	}
+	timeout, ttl := envDuration(geocodeTimeout, 2*time.Second), envDuration(geocodeCacheTTL, 24*time.Hour)
+
+	resolved := map[string]*geoPlace{}
+	var pending []string
+	for _, place := range places {
+		if _, ok := resolved[place]; ok {
+			continue
+		}
+		cached, ok := geocodes.get(place)
+		resolved[place] = cached
+		if !ok {
+			pending = append(pending, place)
+		}
+	}
+
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	for _, place := range pending {
+		wg.Add(1)
+		go func(place string) {
+			defer wg.Done()
+			placeCtx, cancel := context.WithTimeout(ctx, timeout)
+			defer cancel()
+			found, err := geocode(placeCtx, place)
+			if err != nil {
+				metrics.Count("geocode_failures_total", 1, map[string]string{"backend": geocodeBackend})
+				e.Logger.Warnf("geocoding %q failed: %v", place, err)
+				return
+			}
+			geocodes.set(place, found, ttl)
+			mu.Lock()
+			resolved[place] = found
+			mu.Unlock()
+		}(place)
+	}
+	wg.Wait()
+
+	return resolved, nil
+}
+
+// enrichEntities adds the coordinates and canonical name of each place entity
+// of a result, v1 or v2, as its "geo".
+func enrichEntities(ctx context.Context, route string, body json.RawMessage) (json.RawMessage, error) {
+	if !enrichedRoutes[route] {
+		return body, nil
+	}
+	var items []json.RawMessage
+	if err := json.Unmarshal(body, &items); err != nil {
+		return nil, err
+	}
+	labels := map[string]bool{}
+	for _, label := range splitList(geocodeLabels) {
+		labels[label] = true
+	}
+	places := make([]string, len(items))
+	var distinct []string
+	for i, raw := range items {
+		var entity struct {
+			Text  string `json:"text"`
+			Label string `json:"label"`
+		}
+		if json.Unmarshal(raw, &entity) == nil && labels[entity.Label] && strings.TrimSpace(entity.Text) != "" {
+			places[i] = entity.Text
+			distinct = append(distinct, entity.Text)
+		}
+	}
+	if len(distinct) == 0 {
+		return body, nil
+	}
+	resolved, err := geocodePlaces(ctx, distinct)
+	if err != nil {
+		return nil, err
+	}
+
+	for i, place := range places {
+		found := resolved[place]
+		if found == nil {
+			continue
+		}
+		if items[i], err = setItemFields(items[i], itemField{"geo", found}); err != nil {
+			return nil, err
+		}
+	}
+
+	return json.Marshal(items)
+}
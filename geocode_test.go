@@ -0,0 +1,108 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/garystafford/nlp-client/mockupstream"
+	"github.com/stretchr/testify/assert"
+)
+
+// useGeocoder points geocoding at a fake backend for the duration of a test.
+func useGeocoder(t *testing.T, backend string, handler http.HandlerFunc) *int32 {
+	var calls int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		handler(w, r)
+	}))
+	b, u := geocodeBackend, urlGeocode
+	geocodeBackend, urlGeocode = backend, server.URL
+	geocodeOnce, geocodes = sync.Once{}, newGeocodeCache()
+	t.Cleanup(func() {
+		server.Close()
+		geocodeBackend, urlGeocode = b, u
+		geocodeOnce, geocodes = sync.Once{}, newGeocodeCache()
+	})
+	return &calls
+}
+
+func nominatim(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Query().Get("q") != "Warsaw" {
+		w.Write([]byte(`[]`))
+		return
+	}
+	w.Write([]byte(`[{"lat": "52.2319581", "lon": "21.0067249", "display_name": "Warszawa, Polska"}]`))
+}
+
+func TestPeliasGeocoder(t *testing.T) {
+	useGeocoder(t, "pelias", func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/v1/search", r.URL.Path)
+		w.Write([]byte(`{"features": [{"geometry": {"coordinates": [21.0067, 52.2319]}, "properties": {"label": "Warsaw, Poland"}}]}`))
+	})
+
+	geocode, err := loadGeocoder()
+	if assert.NoError(t, err) {
+		place, err := geocode(context.Background(), "Warsaw")
+		assert.NoError(t, err)
+		assert.Equal(t, &geoPlace{52.2319, 21.0067, "Warsaw, Poland"}, place)
+	}
+}
+
+func TestEnrichEntities(t *testing.T) {
+	calls := useGeocoder(t, "nominatim", nominatim)
+	body := `[{"text":"Marie Curie","label":"PERSON"},{"text":"Warsaw","label":"GPE"},` +
+		`{"text":"Atlantis","label":"LOC"},{"text":"Warsaw","label":"GPE","offset":40,"length":6}]`
+
+	enriched, err := enrichEntities(context.Background(), "/entities", []byte(body))
+	if assert.NoError(t, err) {
+		warsaw := `"geo":{"lat":52.2319581,"lon":21.0067249,"name":"Warszawa, Polska"}`
+		assert.JSONEq(t, `[{"text":"Marie Curie","label":"PERSON"},{"text":"Warsaw","label":"GPE",`+warsaw+`},`+
+			`{"text":"Atlantis","label":"LOC"},{"text":"Warsaw","label":"GPE","offset":40,"length":6,`+warsaw+`}]`, string(enriched))
+	}
+	assert.Equal(t, int32(2), atomic.LoadInt32(calls), "each distinct place is geocoded once")
+
+	_, err = enrichEntities(context.Background(), "/entities", []byte(body))
+	assert.NoError(t, err)
+	assert.Equal(t, int32(2), atomic.LoadInt32(calls), "places that did and did not resolve are cached")
+}
+
+func TestEnrichEntitiesGeocoderFailure(t *testing.T) {
+	useGeocoder(t, "nominatim", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusTooManyRequests)
+	})
+	body := `[{"text":"Warsaw","label":"GPE"}]`
+
+	enriched, err := enrichEntities(context.Background(), "/entities", []byte(body))
+	if assert.NoError(t, err) {
+		assert.JSONEq(t, body, string(enriched), "a failing geocoder leaves the place unresolved")
+	}
+}
+
+func TestGetEntitiesEnriched(t *testing.T) {
+	useGeocoder(t, "nominatim", nominatim)
+	prose := mockupstream.NewProse()
+	defer prose.Close()
+	defer func(url string) { urlProse = url }(urlProse)
+	urlProse = prose.URL
+	prose.SetFixture("/entities", mockupstream.Fixture{Status: http.StatusOK, Body: `[{"text":"Warsaw","label":"GPE"}]`})
+
+	get := func(target string) (*httptest.ResponseRecorder, error) {
+		req := httptest.NewRequest(http.MethodPost, target, strings.NewReader(`{"text": "Born in Warsaw"}`))
+		w := httptest.NewRecorder()
+		c := e.NewContext(req, w)
+		c.SetPath("/entities")
+		return w, getEntities(c)
+	}
+
+	w, err := get("/entities?enrich=geo")
+	if assert.NoError(t, err) {
+		assert.Contains(t, w.Body.String(), `"name":"Warszawa, Polska"`)
+	}
+	_, err = get("/entities?enrich=weather")
+	assert.Error(t, err)
+}
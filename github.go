@@ -0,0 +1,280 @@
+// author: Gary A. Stafford
+// site: https://programmaticponderings.com
+// license: MIT License
+// purpose: NLP microservices: nlp-client GitHub issue and pull request webhook
+// modified: 2026-10-14
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/labstack/echo/v4"
+)
+
+var (
+	// secret of the webhook posting issue and pull_request events to
+	// /integrations/github; unset to refuse every request to it
+	githubWebhookSecret = newSecret(getEnv("GITHUB_WEBHOOK_SECRET", ""))
+	// API key webhook requests are made, and their quota counted, as; the
+	// first of the service's keys when unset
+	githubAPIKey = newSecret(getEnv("GITHUB_API_KEY", ""))
+	// token allowed to write the repositories' issues, for comments and labels
+	githubToken  = newSecret(getEnv("GITHUB_TOKEN", ""))
+	githubAPIURL = getEnv("GITHUB_API_URL", "https://api.github.com")
+	// how long each call to the GitHub API may take
+	githubTimeout = getEnv("GITHUB_TIMEOUT", "10s")
+	// store each analysis as a record, with the issue it is of
+	githubRecord = getEnv("GITHUB_RECORD", "true")
+	// comment a summary on opened issues and pull requests, and label them
+	// with their language and sentiment, with GITHUB_TOKEN
+	githubComment = getEnv("GITHUB_COMMENT", "false")
+	githubLabels  = getEnv("GITHUB_LABELS", "false")
+
+	githubClient = &http.Client{Timeout: envDuration(githubTimeout, 10*time.Second)}
+)
+
+// githubAnalyses are run on the title and body of every issue and pull request.
+var githubAnalyses = []string{"keywords", "language"}
+
+// githubActions are the actions of issues and pull requests that are analyzed.
+var githubActions = map[string]bool{"opened": true, "edited": true, "reopened": true}
+
+// githubMaxKeywords is the most keywords a summary comment lists.
+const githubMaxKeywords = 10
+
+// githubIssue is the part of an issue, or pull request, a webhook analyzes.
+type githubIssue struct {
+	Number  int    `json:"number"`
+	Title   string `json:"title"`
+	Body    string `json:"body"`
+	HTMLURL string `json:"html_url"`
+}
+
+type githubEvent struct {
+	Action      string       `json:"action"`
+	Issue       *githubIssue `json:"issue"`
+	PullRequest *githubIssue `json:"pull_request"`
+	Repository  struct {
+		FullName string `json:"full_name"`
+	} `json:"repository"`
+	Sender struct {
+		Type string `json:"type"`
+	} `json:"sender"`
+}
+
+// githubAnalysis is the analysis of an issue or pull request.
+type githubAnalysis struct {
+	Repository     string   `json:"repository"`
+	Number         int      `json:"number"`
+	Kind           string   `json:"kind"`
+	URL            string   `json:"url"`
+	Keywords       []string `json:"keywords"`
+	Language       string   `json:"language"`
+	Sentiment      string   `json:"sentiment,omitempty"`
+	SentimentScore *float64 `json:"sentiment_score,omitempty"`
+}
+
+func isGitHubRoute(r route) bool {
+	return unversioned(r.path) == "/integrations/github"
+}
+
+// githubSignature returns the X-Hub-Signature-256 of a webhook body.
+func githubSignature(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// verifyGitHub accepts a request only if it is signed with the webhook's
+// secret, and then makes it with the GitHub API key, as verifySlack does.
+func verifyGitHub() echo.MiddlewareFunc {
+	// the body is read before the route's own limits apply
+	bodyLimit := earlyBodyLimit()
+
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return bodyLimit(func(c echo.Context) error {
+			if githubWebhookSecret.Get() == "" {
+				return newAPIError(http.StatusNotFound, codeNotFound, "the GitHub integration is not configured")
+			}
+			req := c.Request()
+			body, err := ioutil.ReadAll(req.Body)
+			if err != nil {
+				return err
+			}
+			req.Body = ioutil.NopCloser(bytes.NewReader(body))
+			if !hmac.Equal([]byte(req.Header.Get("X-Hub-Signature-256")), []byte(githubSignature(githubWebhookSecret.Get(), body))) {
+				return newAPIError(http.StatusUnauthorized, codeUnauthorized, "invalid GitHub signature")
+			}
+			req.Header.Set("X-API-Key", integrationKey(githubAPIKey.Get()))
+
+			return next(c)
+		})
+	}
+}
+
+// githubAPI sends a request to the GitHub API with GITHUB_TOKEN.
+func githubAPI(ctx context.Context, method, path string, payload interface{}) error {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, method, strings.TrimSuffix(githubAPIURL, "/")+path, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set(echo.HeaderAuthorization, "Bearer "+githubToken.Get())
+	req.Header.Set(echo.HeaderAccept, "application/vnd.github+json")
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	resp, err := githubClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return newUpstreamStatusError(resp)
+	}
+	_, err = io.Copy(ioutil.Discard, resp.Body)
+
+	return err
+}
+
+// githubSummary returns the Markdown of a summary comment. Keywords are set
+// as code, so none of them mention users or link issues.
+func githubSummary(analysis *githubAnalysis) string {
+	var b strings.Builder
+	b.WriteString("**Text analysis**\n\n")
+	fmt.Fprintf(&b, "- Language: %s\n", analysis.Language)
+	if analysis.SentimentScore != nil {
+		fmt.Fprintf(&b, "- Sentiment: %s (%.2f)\n", analysis.Sentiment, *analysis.SentimentScore)
+	}
+	keywords := analysis.Keywords
+	if len(keywords) > githubMaxKeywords {
+		keywords = keywords[:githubMaxKeywords]
+	}
+	if len(keywords) > 0 {
+		quoted := make([]string, len(keywords))
+		for i, keyword := range keywords {
+			quoted[i] = "`" + strings.ReplaceAll(keyword, "`", "") + "`"
+		}
+		fmt.Fprintf(&b, "- Keywords: %s\n", strings.Join(quoted, ", "))
+	}
+
+	return b.String()
+}
+
+// githubLabelNames returns the labels of an analysis: its language and sentiment.
+func githubLabelNames(analysis *githubAnalysis) []string {
+	labels := []string{}
+	if analysis.Language != "" {
+		labels = append(labels, "lang:"+analysis.Language)
+	}
+	if analysis.Sentiment != "" {
+		labels = append(labels, "sentiment:"+analysis.Sentiment)
+	}
+
+	return labels
+}
+
+// analyzeIssue runs the GitHub analyses, and sentiment if it is configured,
+// on the title and body of an issue.
+func analyzeIssue(c echo.Context, text string, analysis *githubAnalysis) error {
+	ctx, key := c.Request().Context(), c.Request().Header.Get("X-API-Key")
+	results := runAnalysesConcurrently(ctx, text, githubAnalyses, key, envInt(analyzeConcurrency, 4))
+	for _, name := range githubAnalyses {
+		if results[name].Error != nil {
+			return results[name].Error
+		}
+	}
+	keywords, err := flattenResult("keywords", results["keywords"].Result)
+	if err != nil {
+		return internalError(err)
+	}
+	analysis.Keywords = keywords.([]string)
+	language, err := flattenResult("language", results["language"].Result)
+	if err != nil {
+		return internalError(err)
+	}
+	analysis.Language = language.([]simpleLanguage)[0].Language
+	if urlSentiment != "" {
+		sentiment, err := scoreSentiment(ctx, sentimentRequest{Text: text}, key)
+		if err != nil {
+			return err
+		}
+		analysis.Sentiment, analysis.SentimentScore = sentiment.Sentiment, &sentiment.Score
+	}
+
+	return nil
+}
+
+// postGitHub analyzes the title and body of the issues and pull requests a
+// webhook sends as they are opened, edited, or reopened, stores the analysis
+// as a record, and, as configured, comments a summary on new ones and labels
+// them. Other events, and those caused by bots, are acknowledged and ignored.
+func postGitHub(c echo.Context) error {
+	kind := c.Request().Header.Get("X-GitHub-Event")
+	if kind == "ping" {
+		return c.NoContent(http.StatusNoContent)
+	}
+	var event githubEvent
+	if err := json.NewDecoder(c.Request().Body).Decode(&event); err != nil {
+		return validationError(err.Error())
+	}
+	issue := event.Issue
+	if kind == "pull_request" {
+		issue = event.PullRequest
+	}
+	if kind != "issues" && kind != "pull_request" || issue == nil || !githubActions[event.Action] || event.Sender.Type == "Bot" {
+		return c.JSON(http.StatusAccepted, map[string]string{"status": "ignored"})
+	}
+
+	text := strings.TrimSpace(issue.Title + "\n\n" + issue.Body)
+	analysis := &githubAnalysis{Repository: event.Repository.FullName, Number: issue.Number, Kind: kind, URL: issue.HTMLURL}
+	if err := analyzeIssue(c, text, analysis); err != nil {
+		return err
+	}
+	recorded := false
+	if envBool(githubRecord) {
+		if err := storeRecord(c, text, map[string]interface{}{"source": "github", "github": analysis}); err != nil {
+			return err
+		}
+		recorded = true
+	}
+	ctx := c.Request().Context()
+	path := fmt.Sprintf("/repos/%s/issues/%d", event.Repository.FullName, issue.Number)
+	labeled := false
+	if envBool(githubLabels) && githubToken.Get() != "" {
+		if labels := githubLabelNames(analysis); len(labels) > 0 {
+			if err := githubAPI(ctx, http.MethodPost, path+"/labels", map[string][]string{"labels": labels}); err != nil {
+				return upstreamError(err)
+			}
+			labeled = true
+		}
+	}
+	commented := false
+	if envBool(githubComment) && githubToken.Get() != "" && event.Action == "opened" {
+		if err := githubAPI(ctx, http.MethodPost, path+"/comments", map[string]string{"body": githubSummary(analysis)}); err != nil {
+			return upstreamError(err)
+		}
+		commented = true
+	}
+
+	return c.JSON(http.StatusOK, struct {
+		*githubAnalysis
+		Recorded  bool `json:"recorded"`
+		Labeled   bool `json:"labeled"`
+		Commented bool `json:"commented"`
+	}{analysis, recorded, labeled, commented})
+}
@@ -0,0 +1,117 @@
+package main
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/garystafford/nlp-client/mockupstream"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+)
+
+const githubIssueEvent = `{"action":"opened","issue":{"number":7,"title":"Marie Curie","body":"The Nobel Prize",` +
+	`"html_url":"https://github.com/octo/repo/issues/7"},"repository":{"full_name":"octo/repo"},"sender":{"type":"User"}}`
+
+// githubRequest returns a webhook request of event signed with secret.
+func githubRequest(secret, event, body string) *http.Request {
+	req := httptest.NewRequest(http.MethodPost, "/integrations/github", strings.NewReader(body))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	req.Header.Set("X-GitHub-Event", event)
+	req.Header.Set("X-Hub-Signature-256", githubSignature(secret, []byte(body)))
+
+	return req
+}
+
+func TestVerifyGitHub(t *testing.T) {
+	defer func(webhookSecret, key *secret) { githubWebhookSecret, githubAPIKey = webhookSecret, key }(githubWebhookSecret, githubAPIKey)
+	githubWebhookSecret, githubAPIKey = newSecret("webhook-secret"), newSecret("github-key")
+
+	var key string
+	next := func(c echo.Context) error {
+		key = c.Request().Header.Get("X-API-Key")
+		return nil
+	}
+	if assert.NoError(t, verifyGitHub()(next)(e.NewContext(githubRequest("webhook-secret", "ping", "{}"), httptest.NewRecorder()))) {
+		assert.Equal(t, "github-key", key)
+	}
+
+	err := verifyGitHub()(next)(e.NewContext(githubRequest("other-secret", "ping", "{}"), httptest.NewRecorder()))
+	if assert.Error(t, err) {
+		assert.Equal(t, http.StatusUnauthorized, err.(*apiError).Status)
+	}
+
+	githubWebhookSecret.Set("")
+	err = verifyGitHub()(next)(e.NewContext(githubRequest("", "ping", "{}"), httptest.NewRecorder()))
+	if assert.Error(t, err, "the integration is off without a secret") {
+		assert.Equal(t, http.StatusNotFound, err.(*apiError).Status)
+	}
+}
+
+func TestPostGitHub(t *testing.T) {
+	rake := mockupstream.NewRake()
+	defer rake.Close()
+	lang := mockupstream.NewLang()
+	defer lang.Close()
+	dynamo := mockupstream.NewDynamo()
+	defer dynamo.Close()
+	calls := map[string]string{}
+	api := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		calls[r.URL.Path] = string(body)
+		assert.Equal(t, "Bearer token", r.Header.Get(echo.HeaderAuthorization))
+		w.WriteHeader(http.StatusCreated)
+	}))
+	defer api.Close()
+	defer func(rakeURL, langURL, dynamoURL, sentimentURL string) {
+		urlRake, urlLang, urlDynamo, urlSentiment = rakeURL, langURL, dynamoURL, sentimentURL
+	}(urlRake, urlLang, urlDynamo, urlSentiment)
+	urlRake, urlLang, urlDynamo, urlSentiment = rake.URL, lang.URL, dynamo.URL, ""
+	defer func(token *secret, apiURL, comment, labels string) {
+		githubToken, githubAPIURL, githubComment, githubLabels = token, apiURL, comment, labels
+	}(githubToken, githubAPIURL, githubComment, githubLabels)
+	githubToken, githubAPIURL, githubComment, githubLabels = newSecret("token"), api.URL, "true", "true"
+
+	w := httptest.NewRecorder()
+	if assert.NoError(t, postGitHub(e.NewContext(githubRequest("", "issues", githubIssueEvent), w))) {
+		assert.Equal(t, http.StatusOK, w.Code)
+		var got map[string]interface{}
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &got))
+		assert.Equal(t, "octo/repo", got["repository"])
+		assert.Equal(t, "en", got["language"])
+		assert.Equal(t, []interface{}{"nobel prize", "marie curie"}, got["keywords"])
+		assert.Equal(t, true, got["recorded"])
+		assert.Equal(t, true, got["labeled"])
+		assert.Equal(t, true, got["commented"])
+	}
+	assert.JSONEq(t, `{"labels":["lang:en"]}`, calls["/repos/octo/repo/issues/7/labels"])
+	assert.Contains(t, calls["/repos/octo/repo/issues/7/comments"], "`nobel prize`, `marie curie`")
+}
+
+func TestPostGitHubIgnored(t *testing.T) {
+	for _, tt := range []struct{ event, body string }{
+		{"push", `{}`},
+		{"issues", `{"action":"closed","issue":{"number":7}}`},
+		{"issues", `{"action":"opened","issue":{"number":7},"sender":{"type":"Bot"}}`},
+	} {
+		w := httptest.NewRecorder()
+		if assert.NoError(t, postGitHub(e.NewContext(githubRequest("", tt.event, tt.body), w)), tt.body) {
+			assert.Equal(t, http.StatusAccepted, w.Code, tt.body)
+		}
+	}
+
+	w := httptest.NewRecorder()
+	if assert.NoError(t, postGitHub(e.NewContext(githubRequest("", "ping", `{"zen":"Keep it logically awesome."}`), w))) {
+		assert.Equal(t, http.StatusNoContent, w.Code)
+	}
+}
+
+func TestGitHubSummary(t *testing.T) {
+	score := -0.5
+	summary := githubSummary(&githubAnalysis{Language: "en", Sentiment: "negative", SentimentScore: &score, Keywords: []string{"@octocat", "a`b"}})
+	assert.Contains(t, summary, "- Sentiment: negative (-0.50)")
+	assert.Contains(t, summary, "- Keywords: `@octocat`, `ab`", "keywords cannot mention users or break out of code")
+}
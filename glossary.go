@@ -0,0 +1,229 @@
+// author: Gary A. Stafford
+// site: https://programmaticponderings.com
+// license: MIT License
+// purpose: NLP microservices: nlp-client tenant glossaries
+// modified: 2026-10-14
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/labstack/echo/v4"
+)
+
+// most acronyms and terms one tenant's glossary may have
+var glossaryMaxEntries = getEnv("GLOSSARY_MAX_ENTRIES", "10000")
+
+var glossaries = newGlossaryStore()
+
+// glossaryRoutes answer with items ?enrich=glossary matches against the glossary.
+var glossaryRoutes = map[string]bool{"/tokens": true, "/entities": true}
+
+// glossary maps a tenant's acronyms, matched exactly, to their expansions,
+// and its terms, matched regardless of case, to their canonical forms.
+type glossary struct {
+	Acronyms map[string]string `json:"acronyms"`
+	Terms    map[string]string `json:"terms"`
+	terms    map[string]string
+	digest   string
+}
+
+// glossaryMatch annotates an item that matches a glossary entry.
+type glossaryMatch struct {
+	Kind      string `json:"kind"`
+	Canonical string `json:"canonical"`
+}
+
+// glossaryToken is a token that is a string, as an object to annotate.
+type glossaryToken struct {
+	Text     string         `json:"text"`
+	Glossary *glossaryMatch `json:"glossary,omitempty"`
+}
+
+func (g *glossary) match(text string) (glossaryMatch, bool) {
+	if expansion, ok := g.Acronyms[text]; ok {
+		return glossaryMatch{"acronym", expansion}, true
+	}
+	if canonical, ok := g.terms[strings.ToLower(text)]; ok {
+		return glossaryMatch{"term", canonical}, true
+	}
+
+	return glossaryMatch{}, false
+}
+
+// glossaryStore keeps the glossaries tenants upload to a single instance in memory.
+type glossaryStore struct {
+	mu         sync.RWMutex
+	glossaries map[string]*glossary
+}
+
+func newGlossaryStore() *glossaryStore {
+	return &glossaryStore{glossaries: map[string]*glossary{}}
+}
+
+func (s *glossaryStore) get(tenant string) *glossary {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return s.glossaries[tenant]
+}
+
+func (s *glossaryStore) set(tenant string, g *glossary) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if g == nil {
+		delete(s.glossaries, tenant)
+		return
+	}
+	s.glossaries[tenant] = g
+}
+
+// digest identifies the content of a tenant's glossary, for the ETags of
+// results it enriches; it is empty without one.
+func (s *glossaryStore) digest(tenant string) string {
+	if g := s.get(tenant); g != nil {
+		return g.digest
+	}
+
+	return ""
+}
+
+// apply annotates the items of a result of route that match the tenant's
+// glossary with their "glossary" match or, to expand, replaces their text with
+// the canonical form, keeping the text found as "original". Tokens that are
+// strings are replaced when expanding, and become {"text"} objects to be
+// annotated.
+func (s *glossaryStore) apply(tenant, route string, body json.RawMessage, expand bool) (json.RawMessage, error) {
+	g := s.get(tenant)
+	if g == nil || !glossaryRoutes[route] {
+		return body, nil
+	}
+	var items []json.RawMessage
+	if err := json.Unmarshal(body, &items); err != nil {
+		return nil, err
+	}
+	for i, raw := range items {
+		var text string
+		if json.Unmarshal(raw, &text) == nil {
+			match, ok := g.match(text)
+			var item interface{} = glossaryToken{Text: text}
+			switch {
+			case expand && !ok:
+				continue
+			case expand:
+				item = match.Canonical
+			case ok:
+				item = glossaryToken{text, &match}
+			}
+			encoded, err := json.Marshal(item)
+			if err != nil {
+				return nil, err
+			}
+			items[i] = encoded
+			continue
+		}
+
+		var object struct {
+			Text string `json:"text"`
+		}
+		if json.Unmarshal(raw, &object) != nil {
+			continue
+		}
+		match, ok := g.match(object.Text)
+		if !ok {
+			continue
+		}
+		var err error
+		if expand {
+			items[i], err = setItemFields(raw, itemField{"text", match.Canonical}, itemField{"original", object.Text})
+		} else {
+			items[i], err = setItemFields(raw, itemField{"glossary", match})
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return json.Marshal(items)
+}
+
+// tenantOf returns the tenant a glossary request is for, which it must name.
+func tenantOf(c echo.Context) (string, error) {
+	tenant := c.Request().Header.Get(tenantHeader)
+	if tenant == "" {
+		return "", validationError(fmt.Sprintf("%s is required", tenantHeader))
+	}
+
+	return tenant, nil
+}
+
+// putGlossary replaces the glossary of the request's tenant.
+func putGlossary(c echo.Context) error {
+	tenant, err := tenantOf(c)
+	if err != nil {
+		return err
+	}
+	g := &glossary{}
+	if err := json.NewDecoder(c.Request().Body).Decode(g); err != nil {
+		return validationError(err.Error())
+	}
+	if g.Acronyms == nil {
+		g.Acronyms = map[string]string{}
+	}
+	if g.Terms == nil {
+		g.Terms = map[string]string{}
+	}
+	if max := envInt(glossaryMaxEntries, 10000); len(g.Acronyms)+len(g.Terms) > max {
+		return newAPIError(http.StatusRequestEntityTooLarge, codePayloadTooLarge, fmt.Sprintf("more than %d glossary entries", max))
+	}
+
+	// the entries in a stable order, for the digest
+	var entries []string
+	g.terms = map[string]string{}
+	for kind, values := range map[string]map[string]string{"acronym": g.Acronyms, "term": g.Terms} {
+		for key, value := range values {
+			if strings.TrimSpace(key) == "" || strings.TrimSpace(value) == "" {
+				return validationError(fmt.Sprintf("%s entries need a name and a value", kind))
+			}
+			if kind == "term" {
+				g.terms[strings.ToLower(key)] = value
+			}
+			entries = append(entries, kind+"\x00"+key+"\x00"+value)
+		}
+	}
+	sort.Strings(entries)
+	g.digest = hashHex(entries...)
+	glossaries.set(tenant, g)
+
+	return c.JSON(http.StatusOK, g)
+}
+
+func getGlossary(c echo.Context) error {
+	tenant, err := tenantOf(c)
+	if err != nil {
+		return err
+	}
+	g := glossaries.get(tenant)
+	if g == nil {
+		return newAPIError(http.StatusNotFound, codeNotFound, "no glossary is uploaded for the tenant")
+	}
+
+	return c.JSON(http.StatusOK, g)
+}
+
+func deleteGlossary(c echo.Context) error {
+	tenant, err := tenantOf(c)
+	if err != nil {
+		return err
+	}
+	glossaries.set(tenant, nil)
+
+	return c.NoContent(http.StatusNoContent)
+}
@@ -0,0 +1,88 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/garystafford/nlp-client/mockupstream"
+	"github.com/stretchr/testify/assert"
+)
+
+// uploadGlossary puts a glossary for a tenant for the duration of a test.
+func uploadGlossary(t *testing.T, tenant, body string) (*httptest.ResponseRecorder, error) {
+	t.Cleanup(func() { glossaries.set(tenant, nil) })
+	req := httptest.NewRequest(http.MethodPut, "/glossary", strings.NewReader(body))
+	if tenant != "" {
+		req.Header.Set(tenantHeader, tenant)
+	}
+	w := httptest.NewRecorder()
+	return w, putGlossary(e.NewContext(req, w))
+}
+
+func TestPutGlossary(t *testing.T) {
+	w, err := uploadGlossary(t, "acme", `{"acronyms": {"NLP": "natural language processing"}, "terms": {"E-Mail": "email"}}`)
+	if assert.NoError(t, err) {
+		assert.JSONEq(t, `{"acronyms": {"NLP": "natural language processing"}, "terms": {"E-Mail": "email"}}`, w.Body.String())
+	}
+	digest := glossaries.digest("acme")
+	assert.NotEmpty(t, digest)
+	_, err = uploadGlossary(t, "acme", `{"acronyms": {"NLP": "neuro-linguistic programming"}}`)
+	assert.NoError(t, err)
+	assert.NotEqual(t, digest, glossaries.digest("acme"), "a new glossary changes the ETags of its results")
+
+	_, err = uploadGlossary(t, "", `{"acronyms": {"NLP": "natural language processing"}}`)
+	assert.Error(t, err, "the tenant is required")
+	_, err = uploadGlossary(t, "acme", `{"terms": {"email": " "}}`)
+	assert.Error(t, err)
+	defer func(max string) { glossaryMaxEntries = max }(glossaryMaxEntries)
+	glossaryMaxEntries = "1"
+	_, err = uploadGlossary(t, "acme", `{"acronyms": {"NLP": "natural language processing", "NER": "named entity recognition"}}`)
+	assert.Error(t, err)
+}
+
+func TestGlossaryApply(t *testing.T) {
+	_, err := uploadGlossary(t, "acme", `{"acronyms": {"NLP": "natural language processing"}, "terms": {"e-mail": "email"}}`)
+	assert.NoError(t, err)
+
+	tokens := []byte(`["NLP","by","E-mail","nlp"]`)
+	annotated, err := glossaries.apply("acme", "/tokens", tokens, false)
+	if assert.NoError(t, err) {
+		assert.JSONEq(t, `[{"text":"NLP","glossary":{"kind":"acronym","canonical":"natural language processing"}},`+
+			`{"text":"by"},{"text":"E-mail","glossary":{"kind":"term","canonical":"email"}},{"text":"nlp"}]`, string(annotated))
+	}
+	expanded, err := glossaries.apply("acme", "/tokens", tokens, true)
+	if assert.NoError(t, err) {
+		assert.JSONEq(t, `["natural language processing","by","email","nlp"]`, string(expanded), "acronyms match exactly")
+	}
+
+	entities := []byte(`[{"text":"NLP","label":"ORG","offset":0,"length":3}]`)
+	expanded, err = glossaries.apply("acme", "/entities", entities, true)
+	if assert.NoError(t, err) {
+		assert.Equal(t, `[{"text":"natural language processing","label":"ORG","offset":0,"length":3,"original":"NLP"}]`, string(expanded))
+	}
+	unchanged, err := glossaries.apply("globex", "/entities", entities, true)
+	if assert.NoError(t, err) {
+		assert.Equal(t, string(entities), string(unchanged), "a tenant without a glossary is not enriched")
+	}
+}
+
+func TestGetEntitiesGlossary(t *testing.T) {
+	_, err := uploadGlossary(t, "acme", `{"acronyms": {"NYC": "New York City"}}`)
+	assert.NoError(t, err)
+	prose := mockupstream.NewProse()
+	defer prose.Close()
+	defer func(url string) { urlProse = url }(urlProse)
+	urlProse = prose.URL
+	prose.SetFixture("/entities", mockupstream.Fixture{Status: http.StatusOK, Body: `[{"text":"NYC","label":"GPE"}]`})
+
+	req := httptest.NewRequest(http.MethodPost, "/entities?enrich=glossary", strings.NewReader(`{"text": "Moved to NYC"}`))
+	req.Header.Set(tenantHeader, "acme")
+	w := httptest.NewRecorder()
+	c := e.NewContext(req, w)
+	c.SetPath("/entities")
+	if assert.NoError(t, getEntities(c)) {
+		assert.JSONEq(t, `[{"text":"NYC","label":"GPE","glossary":{"kind":"acronym","canonical":"New York City"}}]`, w.Body.String())
+	}
+}
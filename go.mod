@@ -3,8 +3,28 @@ module github.com/garystafford/nlp-client
 go 1.16
 
 require (
+	github.com/aws/aws-lambda-go v1.24.0
+	github.com/aws/aws-sdk-go-v2 v1.9.2
+	github.com/aws/aws-sdk-go-v2/config v1.8.2
+	github.com/aws/aws-sdk-go-v2/credentials v1.4.2
+	github.com/aws/aws-sdk-go-v2/service/dynamodb v1.5.2
+	github.com/aws/aws-sdk-go-v2/service/kms v1.6.0
+	github.com/aws/aws-sdk-go-v2/service/s3 v1.16.0
+	github.com/aws/aws-sdk-go-v2/service/secretsmanager v1.6.0
+	github.com/aws/aws-sdk-go-v2/service/sqs v1.9.1
+	github.com/aws/aws-sdk-go-v2/service/ssm v1.11.0
+	github.com/aws/aws-sdk-go-v2/service/textract v1.4.0
+	github.com/aws/aws-sdk-go-v2/service/transcribe v1.8.0
+	github.com/go-redis/redis/v8 v8.11.0
 	github.com/labstack/echo/v4 v4.3.0
 	github.com/labstack/gommon v0.3.0
+	github.com/linkedin/goavro/v2 v2.11.1
+	github.com/prometheus/client_golang v1.11.0
 	github.com/stretchr/testify v1.7.0
+	github.com/xitongsys/parquet-go v1.6.2
+	github.com/xitongsys/parquet-go-source v0.0.0-20220315005136-aec0fe3e777c
+	go.opentelemetry.io/otel v1.0.1
 	golang.org/x/net v0.0.0-20210614182718-04defd469f4e
+	golang.org/x/oauth2 v0.0.0-20210819190943-2bc19b11175f
+	golang.org/x/text v0.3.6
 )
@@ -0,0 +1,88 @@
+// author: Gary A. Stafford
+// site: https://programmaticponderings.com
+// license: MIT License
+// purpose: NLP microservices: nlp-client header propagation to upstreams
+// modified: 2026-10-14
+
+package main
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+)
+
+var (
+	// comma-separated inbound headers passed on to every upstream call
+	forwardHeaderNames = getEnv("FORWARD_HEADERS", "X-Tenant-ID,Accept-Language,X-Feature-Flags")
+	// comma-separated headers never passed on, even when listed in FORWARD_HEADERS
+	stripHeaderNames = getEnv("STRIP_HEADERS", "Authorization,Proxy-Authorization,Cookie")
+)
+
+// proxyHeaders are the inbound headers the passthrough proxy needs to relay the body as is.
+var proxyHeaders = []string{
+	echo.HeaderContentType,
+	echo.HeaderContentEncoding,
+	echo.HeaderContentLength,
+	echo.HeaderAccept,
+	echo.HeaderAcceptEncoding,
+	"X-API-Key",
+}
+
+type forwardedHeadersKey struct{}
+
+// allowedHeaders returns the allowlisted headers of an inbound request, less the stripped ones.
+func allowedHeaders(inbound http.Header) http.Header {
+	allowed := http.Header{}
+	for _, name := range splitList(forwardHeaderNames) {
+		if values := inbound.Values(name); len(values) > 0 {
+			allowed[http.CanonicalHeaderKey(name)] = values
+		}
+	}
+	stripHeaders(allowed)
+
+	return allowed
+}
+
+func stripHeaders(header http.Header) {
+	for _, name := range splitList(stripHeaderNames) {
+		header.Del(name)
+	}
+}
+
+// forwardHeaders carries the allowlisted headers of the inbound request on
+// its context, so every upstream call made for the request passes them on.
+func forwardHeaders() echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			req := c.Request()
+			ctx := context.WithValue(req.Context(), forwardedHeadersKey{}, allowedHeaders(req.Header))
+			c.SetRequest(req.WithContext(ctx))
+
+			return next(c)
+		}
+	}
+}
+
+// setForwardedHeaders passes the forwarded headers of an upstream request's context on to the upstream.
+func setForwardedHeaders(req *http.Request) {
+	forwarded, _ := req.Context().Value(forwardedHeadersKey{}).(http.Header)
+	for name, values := range forwarded {
+		req.Header[name] = values
+	}
+	stripHeaders(req.Header)
+}
+
+// setProxyHeaders replaces the inbound headers a proxied request starts with
+// by those the upstream needs and the forwarded ones.
+func setProxyHeaders(req *http.Request) {
+	inbound := req.Header
+	req.Header = http.Header{}
+	for _, name := range proxyHeaders {
+		if values := inbound.Values(name); len(values) > 0 {
+			req.Header[http.CanonicalHeaderKey(name)] = values
+		}
+	}
+	setForwardedHeaders(req)
+}
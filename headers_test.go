@@ -0,0 +1,71 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAllowedHeaders(t *testing.T) {
+	defer func(forward string) { forwardHeaderNames = forward }(forwardHeaderNames)
+	forwardHeaderNames = "x-tenant-id, Accept-Language, Authorization"
+
+	inbound := http.Header{}
+	inbound.Set("X-Tenant-ID", "acme")
+	inbound.Add("Accept-Language", "en-US")
+	inbound.Add("Accept-Language", "fr")
+	inbound.Set("Authorization", "Bearer secret")
+	inbound.Set("X-Other", "dropped")
+
+	assert.Equal(t, http.Header{
+		"X-Tenant-Id":     {"acme"},
+		"Accept-Language": {"en-US", "fr"},
+	}, allowedHeaders(inbound))
+}
+
+func TestForwardHeaders(t *testing.T) {
+	req := httptest.NewRequest(http.MethodPost, "/keywords", nil)
+	req.Header.Set("X-Tenant-ID", "acme")
+	req.Header.Set("Cookie", "session=secret")
+
+	var upstream *http.Request
+	handler := forwardHeaders()(func(c echo.Context) error {
+		upstream, _ = http.NewRequestWithContext(c.Request().Context(), http.MethodPost, "http://upstream/keywords", nil)
+		setForwardedHeaders(upstream)
+		return nil
+	})
+	if assert.NoError(t, handler(e.NewContext(req, httptest.NewRecorder()))) {
+		assert.Equal(t, "acme", upstream.Header.Get("X-Tenant-ID"))
+		assert.Empty(t, upstream.Header.Get("Cookie"))
+	}
+}
+
+func TestProxyPassHeaders(t *testing.T) {
+	var received http.Header
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received = r.Header
+	}))
+	defer upstream.Close()
+	target, _ := url.Parse(upstream.URL + "/keywords")
+
+	req := httptest.NewRequest(http.MethodPost, "/keywords", strings.NewReader(`{"text": "passthrough"}`))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	req.Header.Set("X-API-Key", "ChangeMe")
+	req.Header.Set("X-Tenant-ID", "acme")
+	req.Header.Set("Authorization", "Bearer secret")
+	req.Header.Set("X-Internal", "dropped")
+
+	handler := forwardHeaders()(func(c echo.Context) error { return proxyPass(c, target) })
+	if assert.NoError(t, handler(e.NewContext(req, httptest.NewRecorder()))) {
+		assert.Equal(t, echo.MIMEApplicationJSON, received.Get(echo.HeaderContentType))
+		assert.Equal(t, "ChangeMe", received.Get("X-API-Key"))
+		assert.Equal(t, "acme", received.Get("X-Tenant-ID"))
+		assert.Empty(t, received.Get("Authorization"))
+		assert.Empty(t, received.Get("X-Internal"))
+	}
+}
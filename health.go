@@ -0,0 +1,211 @@
+// author: Gary A. Stafford
+// site: https://programmaticponderings.com
+// license: MIT License
+// purpose: NLP microservices: nlp-client upstream health notifications
+// modified: 2026-10-14
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/labstack/echo/v4"
+)
+
+var (
+	// Slack-compatible incoming webhook notified of upstream health transitions; unset to disable
+	healthWebhookURL    = getEnv("HEALTH_WEBHOOK_URL", "")
+	healthCheckInterval = getEnv("HEALTH_CHECK_INTERVAL", "30s")
+	healthCheckTimeout  = getEnv("HEALTH_CHECK_TIMEOUT", "5s")
+	// how long posting to a webhook may take, so a hung receiver cannot stall the notifier
+	webhookTimeout = getEnv("WEBHOOK_TIMEOUT", "10s")
+
+	webhookClient = &http.Client{Timeout: envDuration(webhookTimeout, 10*time.Second)}
+)
+
+// healthEvent is posted to the webhook. Slack renders the text and ignores
+// the other fields, which are there for other receivers.
+type healthEvent struct {
+	Text     string    `json:"text"`
+	Event    string    `json:"event"`
+	Upstream string    `json:"upstream"`
+	Healthy  bool      `json:"healthy"`
+	Reason   string    `json:"reason,omitempty"`
+	Time     time.Time `json:"time"`
+}
+
+// healthMonitor probes each upstream's /health endpoint and notifies on
+// every transition between healthy and unhealthy. Upstreams are assumed
+// healthy at startup, so one that is down from the start is reported.
+type healthMonitor struct {
+	mu        sync.Mutex
+	upstreams func() map[string]string
+	healthy   map[string]bool
+	notify    func(ctx context.Context, event healthEvent) error
+	timeout   time.Duration
+}
+
+func newHealthMonitor(upstreams func() map[string]string, notify func(ctx context.Context, event healthEvent) error, timeout time.Duration) *healthMonitor {
+	return &healthMonitor{upstreams: upstreams, healthy: map[string]bool{}, notify: notify, timeout: timeout}
+}
+
+// namedUpstreams maps each upstream's name, as used by /health/:app, to its URL.
+func namedUpstreams() map[string]string {
+	upstreams := map[string]string{"rake": urlRake, "prose": urlProse, "lang": urlLang, "dynamo": urlDynamo}
+	// optional upstreams are only known once configured
+	if urlModerate != "" {
+		upstreams["moderate"] = urlModerate
+	}
+	if urlSpellcheck != "" {
+		upstreams["spellcheck"] = urlSpellcheck
+	}
+	if urlOCR != "" {
+		upstreams["ocr"] = urlOCR
+	}
+	if urlTranscribe != "" {
+		upstreams["transcribe"] = urlTranscribe
+	}
+	if urlTopics != "" {
+		upstreams["topics"] = urlTopics
+	}
+	if urlSegmenter != "" {
+		upstreams["segmenter"] = urlSegmenter
+	}
+	if urlSentiment != "" {
+		upstreams["sentiment"] = urlSentiment
+	}
+	if urlEmotions != "" {
+		upstreams["emotions"] = urlEmotions
+	}
+	if urlIntent != "" {
+		upstreams["intent"] = urlIntent
+	}
+	for analysis, providers := range ensembles {
+		for _, provider := range providers {
+			upstreams["ensemble-"+analysis+"-"+provider.name] = provider.url
+		}
+	}
+	for region, target := range residency.targets {
+		upstreams["dynamo-"+region] = target
+	}
+
+	return upstreams
+}
+
+func (m *healthMonitor) run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		m.check(ctx)
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (m *healthMonitor) check(ctx context.Context) {
+	upstreams := m.upstreams()
+	names := make([]string, 0, len(upstreams))
+	for name := range upstreams {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		err := m.probe(ctx, upstreams[name])
+		m.record(ctx, name, err == nil, err)
+	}
+}
+
+func (m *healthMonitor) probe(ctx context.Context, url string) error {
+	ctx, cancel := context.WithTimeout(ctx, m.timeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url+"/health", nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("X-API-Key", apiKeys.Primary())
+	resp, err := upstreamClient.Do(req)
+	if err != nil {
+		return upstreamError(err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return upstreamError(newUpstreamStatusError(resp))
+	}
+
+	return nil
+}
+
+// record notes the latest state of an upstream, notifying when it changes.
+func (m *healthMonitor) record(ctx context.Context, name string, healthy bool, cause error) {
+	m.mu.Lock()
+	was, seen := m.healthy[name]
+	m.healthy[name] = healthy
+	m.mu.Unlock()
+
+	if (!seen && healthy) || (seen && was == healthy) {
+		return
+	}
+
+	event := healthEvent{Event: "upstream_unhealthy", Upstream: name, Healthy: healthy, Time: time.Now().UTC()}
+	event.Text = fmt.Sprintf("nlp-client: upstream %s is unhealthy", name)
+	if cause != nil {
+		event.Reason = cause.Error()
+		event.Text += ": " + event.Reason
+	}
+	if healthy {
+		event.Event = "upstream_healthy"
+		event.Text = fmt.Sprintf("nlp-client: upstream %s has recovered", name)
+	}
+	if err := m.notify(ctx, event); err != nil {
+		e.Logger.Errorf("health webhook failed: %v", err)
+	}
+}
+
+func postWebhook(url string) func(ctx context.Context, event healthEvent) error {
+	return func(ctx context.Context, event healthEvent) error {
+		return sendWebhook(ctx, url, event)
+	}
+}
+
+// sendWebhook posts event to a webhook as JSON.
+func sendWebhook(ctx context.Context, url string, event interface{}) error {
+	body, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	resp, err := webhookClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("webhook returned %s", resp.Status)
+	}
+
+	return nil
+}
+
+func startHealthMonitor() {
+	if healthWebhookURL == "" {
+		return
+	}
+	monitor := newHealthMonitor(namedUpstreams, postWebhook(healthWebhookURL), envDuration(healthCheckTimeout, 5*time.Second))
+	go monitor.run(context.Background(), envDuration(healthCheckInterval, 30*time.Second))
+}
@@ -0,0 +1,72 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHealthMonitorTransitions(t *testing.T) {
+	var events []healthEvent
+	m := newHealthMonitor(nil, func(ctx context.Context, event healthEvent) error {
+		events = append(events, event)
+		return nil
+	}, time.Second)
+	ctx := context.Background()
+
+	m.record(ctx, "rake", true, nil)
+	m.record(ctx, "rake", true, nil)
+	m.record(ctx, "rake", false, errors.New("UPSTREAM_UNAVAILABLE: upstream service unavailable"))
+	m.record(ctx, "rake", false, nil)
+	m.record(ctx, "rake", true, nil)
+	// down from the start
+	m.record(ctx, "lang", false, nil)
+
+	if assert.Len(t, events, 3) {
+		assert.Equal(t, "upstream_unhealthy", events[0].Event)
+		assert.Equal(t, "nlp-client: upstream rake is unhealthy: UPSTREAM_UNAVAILABLE: upstream service unavailable", events[0].Text)
+		assert.Equal(t, "upstream_healthy", events[1].Event)
+		assert.True(t, events[1].Healthy)
+		assert.Equal(t, "lang", events[2].Upstream)
+	}
+}
+
+func TestHealthMonitorCheck(t *testing.T) {
+	var healthy int32 = 1
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/health", r.URL.Path)
+		if atomic.LoadInt32(&healthy) == 0 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}))
+	defer upstream.Close()
+
+	var received []healthEvent
+	webhook := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var event healthEvent
+		assert.NoError(t, json.NewDecoder(r.Body).Decode(&event))
+		received = append(received, event)
+	}))
+	defer webhook.Close()
+
+	m := newHealthMonitor(func() map[string]string {
+		return map[string]string{"rake": upstream.URL}
+	}, postWebhook(webhook.URL), time.Second)
+
+	m.check(context.Background())
+	atomic.StoreInt32(&healthy, 0)
+	m.check(context.Background())
+
+	if assert.Len(t, received, 1) {
+		assert.Equal(t, "rake", received[0].Upstream)
+		assert.False(t, received[0].Healthy)
+		assert.NotEmpty(t, received[0].Reason)
+	}
+}
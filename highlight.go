@@ -0,0 +1,124 @@
+// author: Gary A. Stafford
+// site: https://programmaticponderings.com
+// license: MIT License
+// purpose: NLP microservices: nlp-client keyword and entity highlighting
+// modified: 2026-10-14
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"html"
+	"regexp"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// highlightClassChars are left out of the CSS class of an entity label.
+var highlightClassChars = regexp.MustCompile(`[^a-z0-9-]+`)
+
+// highlightMark is a span of the text to mark, in characters.
+type highlightMark struct {
+	start, end int
+	// the entity label, empty for a keyword
+	label string
+}
+
+// itemTexts returns the texts of the items of a result.
+func itemTexts(body json.RawMessage) []string {
+	var items []interface{}
+	_ = json.Unmarshal(body, &items)
+	var texts []string
+	for _, item := range items {
+		if text, ok := itemText(item); ok {
+			texts = append(texts, text)
+		}
+	}
+
+	return texts
+}
+
+// occurrences finds every whole-word occurrence of needle in text, ignoring case.
+func occurrences(text []rune, item string, label string) []highlightMark {
+	needle := foldRunes(item)
+	if len(needle) == 0 {
+		return nil
+	}
+	boundary := func(i int) bool {
+		return i < 0 || i >= len(text) || !unicode.IsLetter(text[i]) && !unicode.IsDigit(text[i])
+	}
+	var marks []highlightMark
+	for i := indexRunes(text, needle, 0); i >= 0; i = indexRunes(text, needle, i+1) {
+		if boundary(i-1) && boundary(i+len(needle)) {
+			marks = append(marks, highlightMark{i, i + len(needle), label})
+		}
+	}
+
+	return marks
+}
+
+// highlightHTML returns text as HTML, with every occurrence of the keywords
+// and entities of results in <mark> tags. Of overlapping marks, entities win
+// over keywords, then the longest, then the first.
+func highlightHTML(text string, results map[string]analysisResult) string {
+	folded := foldRunes(text)
+	var marks []highlightMark
+	if result := results["entities"]; result.Error == nil {
+		var entities []v2Item
+		_ = json.Unmarshal(result.Result, &entities)
+		for _, entity := range entities {
+			label := entity.Label
+			if label == "" {
+				label = "entity"
+			}
+			marks = append(marks, occurrences(folded, entity.Text, label)...)
+		}
+	}
+	if result := results["keywords"]; result.Error == nil {
+		for _, keyword := range itemTexts(result.Result) {
+			marks = append(marks, occurrences(folded, keyword, "")...)
+		}
+	}
+	sort.SliceStable(marks, func(i, j int) bool {
+		a, b := marks[i], marks[j]
+		if (a.label != "") != (b.label != "") {
+			return a.label != ""
+		}
+		if a.end-a.start != b.end-b.start {
+			return a.end-a.start > b.end-b.start
+		}
+		return a.start < b.start
+	})
+	var kept []highlightMark
+	for _, m := range marks {
+		overlaps := false
+		for _, k := range kept {
+			overlaps = overlaps || m.start < k.end && k.start < m.end
+		}
+		if !overlaps {
+			kept = append(kept, m)
+		}
+	}
+	sort.Slice(kept, func(i, j int) bool { return kept[i].start < kept[j].start })
+
+	runes := []rune(text)
+	var b strings.Builder
+	last := 0
+	for _, m := range kept {
+		b.WriteString(html.EscapeString(string(runes[last:m.start])))
+		if m.label == "" {
+			b.WriteString(`<mark class="keyword">`)
+		} else {
+			class := highlightClassChars.ReplaceAllString(strings.ToLower(m.label), "-")
+			fmt.Fprintf(&b, `<mark class="entity entity-%s" data-label="%s">`, class, html.EscapeString(m.label))
+		}
+		b.WriteString(html.EscapeString(string(runes[m.start:m.end])))
+		b.WriteString("</mark>")
+		last = m.end
+	}
+	b.WriteString(html.EscapeString(string(runes[last:])))
+
+	return b.String()
+}
@@ -0,0 +1,42 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/garystafford/nlp-client/mockupstream"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHighlightHTML(t *testing.T) {
+	results := map[string]analysisResult{
+		"entities": {Status: http.StatusOK, Result: []byte(`[{"text":"Marie Curie","label":"PERSON"},{"text":"Nobel Prize","label":"WORK_OF_ART"}]`)},
+		"keywords": {Status: http.StatusOK, Result: []byte(`[{"candidate":"nobel prize","score":4},{"candidate":"prize","score":1},{"candidate":"radium","score":1}]`)},
+	}
+	assert.Equal(t, `<mark class="entity entity-person" data-label="PERSON">Marie Curie</mark> &amp; `+
+		`<mark class="keyword">radium</mark>: <mark class="entity entity-work-of-art" data-label="WORK_OF_ART">Nobel Prize</mark> `+
+		`&lt;1903&gt;, a second <mark class="keyword">prize</mark>, not Radiumville`,
+		highlightHTML("Marie Curie & radium: Nobel Prize <1903>, a second prize, not Radiumville", results),
+		"entities win over keywords, and only whole words are marked")
+}
+
+func TestPostAnalyzeHTML(t *testing.T) {
+	rake, prose := mockupstream.NewRake(), mockupstream.NewProse()
+	defer rake.Close()
+	defer prose.Close()
+	defer func(r, p string) { urlRake, urlProse = r, p }(urlRake, urlProse)
+	urlRake, urlProse = rake.URL, prose.URL
+	prose.SetFixture("/entities", mockupstream.Fixture{Status: http.StatusOK, Body: `[{"text":"Marie Curie","label":"PERSON"}]`})
+
+	req := httptest.NewRequest(http.MethodPost, "/analyze?analyses=keywords,entities&format=html",
+		strings.NewReader(`{"text": "Marie Curie won the Nobel Prize."}`))
+	w := httptest.NewRecorder()
+	if assert.NoError(t, postAnalyze(e.NewContext(req, w))) {
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Contains(t, w.Header().Get("Content-Type"), "text/html")
+		assert.Equal(t, `<mark class="entity entity-person" data-label="PERSON">Marie Curie</mark> won the `+
+			`<mark class="keyword">Nobel Prize</mark>.`, w.Body.String())
+	}
+}
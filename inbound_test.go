@@ -0,0 +1,221 @@
+package main
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"io/ioutil"
+	"math/big"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/garystafford/nlp-client/mockupstream"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+)
+
+const (
+	inboundTopic   = "arn:aws:sns:us-east-1:123456789012:inbound"
+	inboundMessage = "From: Marie Curie <marie@example.com>\r\nTo: support@example.com\r\nSubject: Nobel Prize\r\n\r\n" +
+		"The Nobel Prize is regarded as the most prestigious award.\r\n"
+)
+
+const snsTestCertURL = "https://sns.us-east-1.amazonaws.com/SimpleNotificationService-test.pem"
+
+var snsTestKey *rsa.PrivateKey
+
+// signSNS signs a delivery as SNS does, with a test certificate served, for
+// the rest of a test, from snsTestCertURL.
+func signSNS(t *testing.T, m snsMessage) snsMessage {
+	if snsTestKey == nil {
+		key, err := rsa.GenerateKey(rand.Reader, 2048)
+		if !assert.NoError(t, err) {
+			t.FailNow()
+		}
+		snsTestKey = key
+	}
+	template := &x509.Certificate{SerialNumber: big.NewInt(1), NotBefore: time.Now().Add(-time.Hour), NotAfter: time.Now().Add(time.Hour)}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &snsTestKey.PublicKey, snsTestKey)
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	cert, _ := x509.ParseCertificate(der)
+	snsCertsMu.Lock()
+	snsCerts[snsTestCertURL] = cert
+	snsCertsMu.Unlock()
+	t.Cleanup(func() {
+		snsCertsMu.Lock()
+		delete(snsCerts, snsTestCertURL)
+		snsCertsMu.Unlock()
+	})
+
+	m.SignatureVersion, m.SigningCertURL = "2", snsTestCertURL
+	digest := sha256.Sum256([]byte(m.signedString()))
+	signature, err := rsa.SignPKCS1v15(rand.Reader, snsTestKey, crypto.SHA256, digest[:])
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	m.Signature = base64.StdEncoding.EncodeToString(signature)
+
+	return m
+}
+
+// withInbound points the analyses at mock upstreams, forwarding to forward,
+// for the rest of a test.
+func withInbound(t *testing.T, forward string) {
+	rake := mockupstream.NewRake()
+	dynamo := mockupstream.NewDynamo()
+	t.Cleanup(rake.Close)
+	t.Cleanup(dynamo.Close)
+	rakeURL, dynamoURL, analyses, topics, forwardURL := urlRake, urlDynamo, inboundAnalyses, inboundSESTopics, inboundForwardURL
+	t.Cleanup(func() {
+		urlRake, urlDynamo, inboundAnalyses, inboundSESTopics, inboundForwardURL = rakeURL, dynamoURL, analyses, topics, forwardURL
+	})
+	urlRake, urlDynamo, inboundAnalyses, inboundSESTopics, inboundForwardURL = rake.URL, dynamo.URL, "keywords", inboundTopic, forward
+}
+
+// multipartForm returns a multipart body of fields and its content type.
+func multipartForm(fields map[string]string) (*bytes.Buffer, string) {
+	var body bytes.Buffer
+	w := multipart.NewWriter(&body)
+	for name, value := range fields {
+		_ = w.WriteField(name, value)
+	}
+	_ = w.Close()
+
+	return &body, w.FormDataContentType()
+}
+
+func TestVerifyInbound(t *testing.T) {
+	defer func(password, key *secret) { inboundPassword, inboundAPIKey = password, key }(inboundPassword, inboundAPIKey)
+	inboundPassword, inboundAPIKey = newSecret(""), newSecret("inbound-key")
+
+	var key string
+	next := func(c echo.Context) error {
+		key = c.Request().Header.Get("X-API-Key")
+		return nil
+	}
+	err := verifyInbound()(next)(e.NewContext(httptest.NewRequest(http.MethodPost, "/integrations/ses", nil), httptest.NewRecorder()))
+	if assert.Error(t, err, "inbound email is off without a password") {
+		assert.Equal(t, http.StatusNotFound, err.(*apiError).Status)
+	}
+
+	inboundPassword.Set("secret")
+	req := httptest.NewRequest(http.MethodPost, "/integrations/ses", nil)
+	w := httptest.NewRecorder()
+	err = verifyInbound()(next)(e.NewContext(req, w))
+	if assert.Error(t, err, "requests without credentials are challenged") {
+		assert.Equal(t, http.StatusUnauthorized, err.(*echo.HTTPError).Code)
+		assert.Contains(t, w.Header().Get(echo.HeaderWWWAuthenticate), `realm="inbound"`)
+	}
+
+	req = httptest.NewRequest(http.MethodPost, "/integrations/ses", nil)
+	req.SetBasicAuth(inboundUsername, "secret")
+	if assert.NoError(t, verifyInbound()(next)(e.NewContext(req, httptest.NewRecorder()))) {
+		assert.Equal(t, "inbound-key", key)
+	}
+}
+
+func TestPostSendGrid(t *testing.T) {
+	forwarded := make(chan string, 2)
+	forward := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		forwarded <- string(body)
+	}))
+	defer forward.Close()
+	withInbound(t, forward.URL)
+
+	for _, fields := range []map[string]string{
+		{"from": "Marie Curie <marie@example.com>", "subject": "Nobel Prize", "text": "The Nobel Prize.\n\n> quoted"},
+		{"email": inboundMessage},
+	} {
+		body, contentType := multipartForm(fields)
+		req := httptest.NewRequest(http.MethodPost, "/integrations/sendgrid", body)
+		req.Header.Set(echo.HeaderContentType, contentType)
+		w := httptest.NewRecorder()
+		if assert.NoError(t, postSendGrid(e.NewContext(req, w))) {
+			assert.Equal(t, http.StatusOK, w.Code)
+			var got struct {
+				inboundAnalysis
+				Recorded  bool `json:"recorded"`
+				Forwarded bool `json:"forwarded"`
+			}
+			assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &got))
+			assert.Equal(t, "sendgrid", got.Source)
+			assert.Equal(t, "Nobel Prize", got.Subject)
+			assert.NotContains(t, got.From, "marie@example.com", "addresses are redacted")
+			assert.Equal(t, analysesOK, got.Sections["body"].Status)
+			assert.NotContains(t, got.Sections["body"].Text, "quoted")
+			assert.True(t, got.Recorded)
+			assert.True(t, got.Forwarded)
+			assert.Contains(t, <-forwarded, `"source":"sendgrid"`)
+		}
+	}
+}
+
+func TestPostSES(t *testing.T) {
+	withInbound(t, "")
+
+	notification, _ := json.Marshal(map[string]interface{}{
+		"notificationType": "Received",
+		"receipt":          map[string]interface{}{"action": map[string]string{"type": "SNS", "encoding": "BASE64"}},
+		"content":          base64.StdEncoding.EncodeToString([]byte(inboundMessage)),
+	})
+	delivery, _ := json.Marshal(signSNS(t, snsMessage{Type: "Notification", MessageID: "1", TopicArn: inboundTopic, Message: string(notification), Timestamp: "2026-10-14T12:00:00.000Z"}))
+	req := httptest.NewRequest(http.MethodPost, "/integrations/ses", bytes.NewReader(delivery))
+	req.Header.Set(echo.HeaderContentType, "text/plain; charset=UTF-8")
+	w := httptest.NewRecorder()
+	if assert.NoError(t, postSES(e.NewContext(req, w))) {
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Contains(t, w.Body.String(), `"source":"ses"`)
+		assert.Contains(t, w.Body.String(), `"subject":"Nobel Prize"`)
+	}
+
+	forged := signSNS(t, snsMessage{Type: "Notification", TopicArn: inboundTopic, Message: `{"notificationType":"Received"}`})
+	forged.Message = string(notification)
+	foreignCert := signSNS(t, snsMessage{Type: "Notification", TopicArn: inboundTopic, Message: string(notification)})
+	foreignCert.SigningCertURL = "https://example.com/SimpleNotificationService-test.pem"
+	tests := []struct {
+		name     string
+		delivery snsMessage
+		status   int
+	}{
+		{"other topic", signSNS(t, snsMessage{Type: "Notification", TopicArn: "arn:aws:sns:us-east-1:123456789012:other"}), http.StatusForbidden},
+		{"unsigned", snsMessage{Type: "Notification", TopicArn: inboundTopic, Message: string(notification)}, http.StatusUnauthorized},
+		{"forged", forged, http.StatusUnauthorized},
+		{"foreign SigningCertURL", foreignCert, http.StatusUnauthorized},
+		{"foreign SubscribeURL", signSNS(t, snsMessage{Type: "SubscriptionConfirmation", TopicArn: inboundTopic, SubscribeURL: "https://example.com/confirm"}), http.StatusBadRequest},
+		{"no content", signSNS(t, snsMessage{Type: "Notification", TopicArn: inboundTopic, Message: `{"notificationType":"Received"}`}), http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		body, _ := json.Marshal(tt.delivery)
+		req := httptest.NewRequest(http.MethodPost, "/integrations/ses", bytes.NewReader(body))
+		err := postSES(e.NewContext(req, httptest.NewRecorder()))
+		if assert.Error(t, err, tt.name) {
+			assert.Equal(t, tt.status, err.(*apiError).Status, tt.name)
+		}
+	}
+}
+
+func TestSNSSignedString(t *testing.T) {
+	m := snsMessage{Type: "Notification", MessageID: "1", TopicArn: inboundTopic, Subject: "Hi", Message: "body", Timestamp: "T"}
+	assert.Equal(t, "Message\nbody\nMessageId\n1\nSubject\nHi\nTimestamp\nT\nTopicArn\n"+inboundTopic+"\nType\nNotification\n", m.signedString())
+
+	m = snsMessage{Type: "SubscriptionConfirmation", MessageID: "1", TopicArn: inboundTopic, Message: "body", Timestamp: "T", Token: "tok", SubscribeURL: "U"}
+	assert.Equal(t, "Message\nbody\nMessageId\n1\nSubscribeURL\nU\nTimestamp\nT\nToken\ntok\nTopicArn\n"+inboundTopic+"\nType\nSubscriptionConfirmation\n", m.signedString())
+}
+
+func TestSNSHost(t *testing.T) {
+	assert.True(t, snsHost.MatchString("sns.us-east-1.amazonaws.com"))
+	assert.True(t, snsHost.MatchString("sns.cn-north-1.amazonaws.com.cn"))
+	assert.False(t, snsHost.MatchString("sns.us-east-1.amazonaws.com.example.com"))
+	assert.False(t, snsHost.MatchString("evil-sns.us-east-1.amazonaws.com"))
+}
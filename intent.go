@@ -0,0 +1,265 @@
+// author: Gary A. Stafford
+// site: https://programmaticponderings.com
+// license: MIT License
+// purpose: NLP microservices: nlp-client intent detection and slot extraction
+// modified: 2026-10-14
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/labstack/echo/v4"
+)
+
+var (
+	// classification backend answering POST /intent with a score per label; unset disables /intent
+	urlIntent = upstreamURL(getEnv("INTENT_ENDPOINT", ""))
+	// comma-separated intents of tenants that have not uploaded their own
+	intentLabels = getEnv("INTENT_LABELS", "")
+	// lowest score an intent is detected at
+	intentThreshold = getEnv("INTENT_THRESHOLD", "0.5")
+	// most intents one tenant may upload
+	intentMaxIntents = getEnv("INTENT_MAX_INTENTS", "200")
+)
+
+var intents = newIntentStore()
+
+// intentDef is an intent a tenant's texts are classified into, with its
+// slots, by slot name, and the entity label that fills each.
+type intentDef struct {
+	Name  string            `json:"name"`
+	Slots map[string]string `json:"slots,omitempty"`
+}
+
+type intentSet struct {
+	Intents []intentDef `json:"intents"`
+}
+
+type intentScore struct {
+	Label string  `json:"label"`
+	Score float64 `json:"score"`
+}
+
+// slot is an entity of the text that fills a slot of the detected intent.
+type slot struct {
+	Name  string `json:"name"`
+	Text  string `json:"text"`
+	Label string `json:"label"`
+	span
+}
+
+type intentResult struct {
+	// the intent scored highest, empty when none reaches INTENT_THRESHOLD
+	Intent  string        `json:"intent"`
+	Score   float64       `json:"score"`
+	Intents []intentScore `json:"intents"`
+	Slots   []slot        `json:"slots"`
+}
+
+func intentNotConfigured() *apiError {
+	return newAPIError(http.StatusNotFound, codeNotFound, "intent detection is not configured")
+}
+
+// intentStore keeps the intents tenants upload to a single instance in memory.
+type intentStore struct {
+	mu   sync.RWMutex
+	sets map[string]*intentSet
+}
+
+func newIntentStore() *intentStore {
+	return &intentStore{sets: map[string]*intentSet{}}
+}
+
+func (s *intentStore) get(tenant string) *intentSet {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return s.sets[tenant]
+}
+
+func (s *intentStore) set(tenant string, set *intentSet) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if set == nil {
+		delete(s.sets, tenant)
+		return
+	}
+	s.sets[tenant] = set
+}
+
+// intentsFor returns the intents of a tenant, or else those of INTENT_LABELS, without slots.
+func intentsFor(tenant string) []intentDef {
+	if set := intents.get(tenant); set != nil {
+		return set.Intents
+	}
+	var defs []intentDef
+	for _, label := range splitList(intentLabels) {
+		defs = append(defs, intentDef{Name: label})
+	}
+
+	return defs
+}
+
+// fillSlots fills each slot of an intent with the first entity, in the order
+// of the text, of its label; slots without one are left out.
+func fillSlots(def intentDef, text string, entities []v2Item) []slot {
+	loc := &locator{text: foldRunes(text)}
+	for i := range entities {
+		if entities[i].Offset == nil {
+			entities[i].span = loc.locate(entities[i].Text)
+		}
+	}
+	sort.SliceStable(entities, func(i, j int) bool {
+		a, b := entities[i].Offset, entities[j].Offset
+		return a != nil && (b == nil || *a < *b)
+	})
+
+	names := make([]string, 0, len(def.Slots))
+	for name := range def.Slots {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	slots := []slot{}
+	for _, name := range names {
+		for _, entity := range entities {
+			if entity.Label == def.Slots[name] {
+				slots = append(slots, slot{name, entity.Text, entity.Label, entity.span})
+				break
+			}
+		}
+	}
+	sort.SliceStable(slots, func(i, j int) bool {
+		a, b := slots[i].Offset, slots[j].Offset
+		return a != nil && (b == nil || *a < *b)
+	})
+
+	return slots
+}
+
+// postIntent classifies a text into the intents of the request's tenant with
+// the backend and fills the slots of the detected intent with the entities
+// of the text, merged with those the tenant's gazetteer and patterns find.
+func postIntent(c echo.Context) error {
+	if urlIntent == "" {
+		return intentNotConfigured()
+	}
+	var body struct {
+		Text string `json:"text"`
+	}
+	if err := json.NewDecoder(c.Request().Body).Decode(&body); err != nil {
+		return validationError(err.Error())
+	}
+	req := c.Request()
+	tenant := req.Header.Get(tenantHeader)
+	defs := intentsFor(tenant)
+	if len(defs) == 0 {
+		return validationError("no intents are configured for the tenant")
+	}
+	labels := make([]string, len(defs))
+	for i, def := range defs {
+		labels[i] = def.Name
+	}
+
+	ctx, key := req.Context(), req.Header.Get("X-API-Key")
+	answer, err := postUpstreamJSON(ctx, urlIntent+"/intent", struct {
+		Text   string   `json:"text"`
+		Labels []string `json:"labels"`
+	}{body.Text, labels}, key)
+	if err != nil {
+		return upstreamError(err)
+	}
+	var scores []intentScore
+	if err := json.Unmarshal(answer, &scores); err != nil {
+		return upstreamError(err)
+	}
+	sort.SliceStable(scores, func(i, j int) bool { return scores[i].Score > scores[j].Score })
+
+	result := intentResult{Intents: scores, Slots: []slot{}}
+	if len(scores) == 0 || scores[0].Score < envFloat(intentThreshold, 0.5) {
+		return c.JSON(http.StatusOK, result)
+	}
+	result.Intent, result.Score = scores[0].Label, scores[0].Score
+	for _, def := range defs {
+		if def.Name != result.Intent || len(def.Slots) == 0 {
+			continue
+		}
+		entities := runAnalysis(ctx, "entities", body.Text, key)
+		if entities.Error != nil {
+			return entities.Error
+		}
+		merged, err := mergeLocalEntities(tenant, "/entities", schemaV1, body.Text, entities.Result)
+		if err != nil {
+			return internalError(err)
+		}
+		var items []v2Item
+		if err := json.Unmarshal(merged, &items); err != nil {
+			return internalError(err)
+		}
+		result.Slots = fillSlots(def, body.Text, items)
+	}
+
+	return c.JSON(http.StatusOK, result)
+}
+
+// putIntents replaces the intents of the request's tenant.
+func putIntents(c echo.Context) error {
+	tenant, err := tenantOf(c)
+	if err != nil {
+		return err
+	}
+	set := &intentSet{}
+	if err := json.NewDecoder(c.Request().Body).Decode(set); err != nil {
+		return validationError(err.Error())
+	}
+	if max := envInt(intentMaxIntents, 200); len(set.Intents) > max {
+		return newAPIError(http.StatusRequestEntityTooLarge, codePayloadTooLarge, fmt.Sprintf("more than %d intents", max))
+	}
+	seen := map[string]bool{}
+	for i, def := range set.Intents {
+		if strings.TrimSpace(def.Name) == "" {
+			return validationError(fmt.Sprintf("intent %d has no name", i))
+		}
+		if seen[def.Name] {
+			return validationError(fmt.Sprintf("intent %s is listed twice", def.Name))
+		}
+		seen[def.Name] = true
+		for name, label := range def.Slots {
+			if strings.TrimSpace(name) == "" || strings.TrimSpace(label) == "" {
+				return validationError(fmt.Sprintf("intent %s has a slot without a name or label", def.Name))
+			}
+		}
+	}
+	intents.set(tenant, set)
+
+	return c.JSON(http.StatusOK, set)
+}
+
+func getIntents(c echo.Context) error {
+	tenant, err := tenantOf(c)
+	if err != nil {
+		return err
+	}
+	set := intents.get(tenant)
+	if set == nil {
+		return newAPIError(http.StatusNotFound, codeNotFound, "no intents are uploaded for the tenant")
+	}
+
+	return c.JSON(http.StatusOK, set)
+}
+
+func deleteIntents(c echo.Context) error {
+	tenant, err := tenantOf(c)
+	if err != nil {
+		return err
+	}
+	intents.set(tenant, nil)
+
+	return c.NoContent(http.StatusNoContent)
+}
@@ -0,0 +1,103 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/garystafford/nlp-client/mockupstream"
+	"github.com/stretchr/testify/assert"
+)
+
+// uploadIntents puts the intents of a tenant for the duration of a test.
+func uploadIntents(t *testing.T, tenant, body string) (*httptest.ResponseRecorder, error) {
+	t.Cleanup(func() { intents.set(tenant, nil) })
+	req := httptest.NewRequest(http.MethodPut, "/intents", strings.NewReader(body))
+	if tenant != "" {
+		req.Header.Set(tenantHeader, tenant)
+	}
+	w := httptest.NewRecorder()
+	return w, putIntents(e.NewContext(req, w))
+}
+
+func TestPutIntents(t *testing.T) {
+	_, err := uploadIntents(t, "acme", `{"intents": [{"name": "book_flight", "slots": {"destination": "GPE"}}, {"name": "cancel"}]}`)
+	if assert.NoError(t, err) {
+		assert.Len(t, intentsFor("acme"), 2)
+	}
+	_, err = uploadIntents(t, "", `{"intents": [{"name": "cancel"}]}`)
+	assert.Error(t, err, "the tenant is required")
+	_, err = uploadIntents(t, "acme", `{"intents": [{"name": "cancel"}, {"name": "cancel"}]}`)
+	assert.Error(t, err)
+	_, err = uploadIntents(t, "acme", `{"intents": [{"name": "book_flight", "slots": {"destination": ""}}]}`)
+	assert.Error(t, err)
+
+	defer func(labels string) { intentLabels = labels }(intentLabels)
+	intentLabels = "greet, goodbye"
+	assert.Equal(t, []intentDef{{Name: "greet"}, {Name: "goodbye"}}, intentsFor("globex"), "tenants without intents get INTENT_LABELS")
+}
+
+func TestFillSlots(t *testing.T) {
+	text := "Fly from Boston to Paris on Friday"
+	def := intentDef{Name: "book_flight", Slots: map[string]string{"origin": "GPE", "date": "DATE", "seat": "SEAT"}}
+	slots := fillSlots(def, text, []v2Item{{Text: "Friday", Label: "DATE"}, {Text: "Boston", Label: "GPE"}, {Text: "Paris", Label: "GPE"}})
+	if assert.Len(t, slots, 2, "slots without an entity are left out") {
+		assert.Equal(t, "origin", slots[0].Name)
+		assert.Equal(t, "Boston", slots[0].Text, "a slot takes the first entity of its label")
+		assert.Equal(t, 9, *slots[0].Offset)
+		assert.Equal(t, "date", slots[1].Name)
+	}
+}
+
+func TestPostIntent(t *testing.T) {
+	post := func(tenant string) (*httptest.ResponseRecorder, error) {
+		req := httptest.NewRequest(http.MethodPost, "/intent", strings.NewReader(`{"text": "Book me a flight to Paris"}`))
+		req.Header.Set(tenantHeader, tenant)
+		w := httptest.NewRecorder()
+		return w, postIntent(e.NewContext(req, w))
+	}
+	_, err := post("acme")
+	assert.Equal(t, intentNotConfigured(), err)
+
+	var labels []string
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body struct{ Labels []string }
+		_ = json.NewDecoder(r.Body).Decode(&body)
+		labels = body.Labels
+		_, _ = w.Write([]byte(`[{"label": "cancel", "score": 0.1}, {"label": "book_flight", "score": 0.85}]`))
+	}))
+	defer backend.Close()
+	defer func(url string) { urlIntent = url }(urlIntent)
+	urlIntent = backend.URL
+	prose := mockupstream.NewProse()
+	defer prose.Close()
+	defer func(url string) { urlProse = url }(urlProse)
+	urlProse = prose.URL
+	prose.SetFixture("/entities", mockupstream.Fixture{Status: http.StatusOK, Body: `[{"text":"Paris","label":"GPE"}]`})
+
+	_, err = post("acme")
+	assert.Error(t, err, "a tenant needs intents")
+	_, err = uploadIntents(t, "acme", `{"intents": [{"name": "book_flight", "slots": {"destination": "GPE"}}, {"name": "cancel"}]}`)
+	assert.NoError(t, err)
+
+	w, err := post("acme")
+	if assert.NoError(t, err) {
+		assert.Equal(t, []string{"book_flight", "cancel"}, labels)
+		assert.JSONEq(t, `{"intent": "book_flight", "score": 0.85,
+			"intents": [{"label": "book_flight", "score": 0.85}, {"label": "cancel", "score": 0.1}],
+			"slots": [{"name": "destination", "text": "Paris", "label": "GPE", "offset": 20, "length": 5}]}`, w.Body.String())
+	}
+
+	defer func(threshold string) { intentThreshold = threshold }(intentThreshold)
+	intentThreshold = "0.9"
+	w, err = post("acme")
+	if assert.NoError(t, err) {
+		var result intentResult
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &result))
+		assert.Empty(t, result.Intent)
+		assert.Empty(t, result.Slots)
+		assert.Len(t, prose.Requests(), 1, "slots are only filled for a detected intent")
+	}
+}
@@ -0,0 +1,186 @@
+// author: Gary A. Stafford
+// site: https://programmaticponderings.com
+// license: MIT License
+// purpose: NLP microservices: nlp-client NIF and UIMA CAS XMI output
+// modified: 2026-10-14
+
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"unicode/utf16"
+
+	"github.com/labstack/echo/v4"
+)
+
+// prefix of the IRIs of NIF documents, as <prefix>doc:<hash>, and of entity labels, as <prefix>label:<label>
+var nifBaseURI = getEnv("NIF_BASE_URI", "urn:nlp-client:")
+
+const (
+	formatNIF = "nif"
+	formatXMI = "xmi"
+)
+
+// interchangeRoutes are the routes whose results can be encoded for other
+// NLP toolchains with ?format=nif or ?format=xmi.
+var interchangeRoutes = map[string]bool{"/tokens": true, "/entities": true}
+
+var interchangeContentTypes = map[string]string{
+	formatNIF: "text/turtle; charset=UTF-8",
+	formatXMI: "application/vnd.xmi+xml; charset=UTF-8",
+}
+
+// parseInterchange returns the ?format= a single-analysis route is asked
+// for: empty for JSON, or nif or xmi on the routes that have them.
+func parseInterchange(format, route string) (string, error) {
+	switch format {
+	case "", formatJSON:
+		return "", nil
+	case formatNIF, formatXMI:
+		if interchangeRoutes[route] {
+			return format, nil
+		}
+		return "", fmt.Errorf("format %s is only for tokens and entities", format)
+	}
+
+	return "", fmt.Errorf("unknown format %q, want json, nif, or xmi", format)
+}
+
+// annotation is an item of a result placed in its text, in characters.
+type annotation struct {
+	start, end int
+	text       string
+	label      string
+}
+
+// annotationsOf places the items of a v1 or v2 result in text; items not
+// found in it are left out.
+func annotationsOf(text string, body json.RawMessage) ([]annotation, error) {
+	var items []json.RawMessage
+	if err := json.Unmarshal(body, &items); err != nil {
+		return nil, err
+	}
+	loc := &locator{text: foldRunes(text)}
+	var annotations []annotation
+	for _, raw := range items {
+		var item v2Item
+		if err := json.Unmarshal(raw, &item.Text); err != nil {
+			if err := json.Unmarshal(raw, &item); err != nil {
+				return nil, err
+			}
+		}
+		s := item.span
+		if s.Offset == nil {
+			s = loc.locate(item.Text)
+		}
+		if s.Offset == nil {
+			continue
+		}
+		annotations = append(annotations, annotation{*s.Offset, *s.Offset + s.Length, item.Text, item.Label})
+	}
+
+	return annotations, nil
+}
+
+// turtleString quotes a Turtle string literal.
+func turtleString(s string) string {
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`, "\r", `\r`, "\t", `\t`).Replace(s) + `"`
+}
+
+// renderNIF encodes the annotations of text as NLP Interchange Format 2.0 RDF,
+// in Turtle: the text is a nif:Context, and each token a nif:Word or entity a
+// nif:Phrase, with its label as an itsrdf:taClassRef.
+func renderNIF(route, text string, annotations []annotation) string {
+	doc := nifBaseURI + "doc:" + hashHex(text)[:16]
+	iri := func(start, end int) string { return fmt.Sprintf("<%s#char=%d,%d>", doc, start, end) }
+	length := len([]rune(text))
+
+	var b strings.Builder
+	b.WriteString("@prefix nif: <http://persistence.uni-leipzig.org/nlp2rdf/ontologies/nif-core#> .\n")
+	b.WriteString("@prefix itsrdf: <http://www.w3.org/2005/11/its/rdf#> .\n")
+	b.WriteString("@prefix xsd: <http://www.w3.org/2001/XMLSchema#> .\n\n")
+	fmt.Fprintf(&b, "%s a nif:Context, nif:String, nif:RFC5147String ;\n", iri(0, length))
+	fmt.Fprintf(&b, "    nif:isString %s ;\n", turtleString(text))
+	fmt.Fprintf(&b, "    nif:beginIndex \"0\"^^xsd:nonNegativeInteger ;\n")
+	fmt.Fprintf(&b, "    nif:endIndex \"%d\"^^xsd:nonNegativeInteger .\n", length)
+	kind := "nif:Phrase"
+	if route == "/tokens" {
+		kind = "nif:Word"
+	}
+	for _, a := range annotations {
+		fmt.Fprintf(&b, "\n%s a nif:String, nif:RFC5147String, %s ;\n", iri(a.start, a.end), kind)
+		fmt.Fprintf(&b, "    nif:referenceContext %s ;\n", iri(0, length))
+		fmt.Fprintf(&b, "    nif:anchorOf %s ;\n", turtleString(a.text))
+		fmt.Fprintf(&b, "    nif:beginIndex \"%d\"^^xsd:nonNegativeInteger ;\n", a.start)
+		if a.label == "" {
+			fmt.Fprintf(&b, "    nif:endIndex \"%d\"^^xsd:nonNegativeInteger .\n", a.end)
+			continue
+		}
+		fmt.Fprintf(&b, "    nif:endIndex \"%d\"^^xsd:nonNegativeInteger ;\n", a.end)
+		fmt.Fprintf(&b, "    itsrdf:taClassRef <%slabel:%s> .\n", nifBaseURI, url.PathEscape(a.label))
+	}
+
+	return b.String()
+}
+
+// xmlAttr escapes an XML attribute value.
+func xmlAttr(s string) string {
+	var b bytes.Buffer
+	_ = xml.EscapeText(&b, []byte(s))
+	return b.String()
+}
+
+// renderXMI encodes the annotations of text as a UIMA CAS in XMI, with the
+// text as the sofa of the initial view and each token an nlpclient.Token, or
+// entity an nlpclient.Entity with its label. UIMA offsets count UTF-16 code
+// units, as Java strings do, rather than characters.
+func renderXMI(route, text string, annotations []annotation) string {
+	runes := []rune(text)
+	// units[i] is the UTF-16 offset of character i
+	units := make([]int, len(runes)+1)
+	for i, r := range runes {
+		units[i+1] = units[i] + len(utf16.Encode([]rune{r}))
+	}
+
+	var b strings.Builder
+	b.WriteString(`<?xml version="1.0" encoding="UTF-8"?>` + "\n")
+	b.WriteString(`<xmi:XMI xmlns:xmi="http://www.omg.org/XMI" xmlns:cas="http:///uima/cas.ecore" ` +
+		`xmlns:tcas="http:///uima/tcas.ecore" xmlns:nlpclient="http:///nlpclient.ecore" xmi:version="2.0">` + "\n")
+	b.WriteString(`  <cas:NULL xmi:id="0"/>` + "\n")
+	fmt.Fprintf(&b, `  <cas:Sofa xmi:id="1" sofaNum="1" sofaID="_InitialView" mimeType="text" sofaString="%s"/>`+"\n", xmlAttr(text))
+	fmt.Fprintf(&b, `  <tcas:DocumentAnnotation xmi:id="2" sofa="1" begin="0" end="%d" language="x-unspecified"/>`+"\n", units[len(runes)])
+	members := []string{"2"}
+	for i, a := range annotations {
+		id := fmt.Sprint(i + 3)
+		members = append(members, id)
+		if route == "/tokens" {
+			fmt.Fprintf(&b, `  <nlpclient:Token xmi:id="%s" sofa="1" begin="%d" end="%d"/>`+"\n", id, units[a.start], units[a.end])
+			continue
+		}
+		fmt.Fprintf(&b, `  <nlpclient:Entity xmi:id="%s" sofa="1" begin="%d" end="%d" label="%s"/>`+"\n", id, units[a.start], units[a.end], xmlAttr(a.label))
+	}
+	fmt.Fprintf(&b, `  <cas:View sofa="1" members="%s"/>`+"\n", strings.Join(members, " "))
+	b.WriteString("</xmi:XMI>\n")
+
+	return b.String()
+}
+
+// respondInterchange writes a tokens or entities result in the format asked for.
+func respondInterchange(c echo.Context, format, route, text string, body json.RawMessage) error {
+	annotations, err := annotationsOf(text, body)
+	if err != nil {
+		return internalError(err)
+	}
+	encoded := renderNIF(route, text, annotations)
+	if format == formatXMI {
+		encoded = renderXMI(route, text, annotations)
+	}
+
+	return c.Blob(http.StatusOK, interchangeContentTypes[format], []byte(encoded))
+}
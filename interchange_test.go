@@ -0,0 +1,82 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/garystafford/nlp-client/mockupstream"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseInterchange(t *testing.T) {
+	format, err := parseInterchange("nif", "/entities")
+	if assert.NoError(t, err) {
+		assert.Equal(t, formatNIF, format)
+	}
+	format, err = parseInterchange("json", "/keywords")
+	if assert.NoError(t, err) {
+		assert.Empty(t, format)
+	}
+	_, err = parseInterchange("xmi", "/keywords")
+	assert.Error(t, err)
+	_, err = parseInterchange("rdf", "/entities")
+	assert.Error(t, err)
+}
+
+func TestAnnotationsOf(t *testing.T) {
+	annotations, err := annotationsOf("to be or not to be", []byte(`["to","be",{"text":"or","offset":6,"length":2},"missing","to"]`))
+	if assert.NoError(t, err) {
+		assert.Equal(t, []annotation{{0, 2, "to", ""}, {3, 5, "be", ""}, {6, 8, "or", ""}, {13, 15, "to", ""}}, annotations,
+			"repeated items resolve to their own occurrences, and items not in the text are left out")
+	}
+}
+
+func TestRenderNIF(t *testing.T) {
+	defer func(base string) { nifBaseURI = base }(nifBaseURI)
+	nifBaseURI = "http://example.com/"
+	text := `Curie said "hi"`
+	doc := "<http://example.com/doc:" + hashHex(text)[:16]
+	assert.Equal(t, `@prefix nif: <http://persistence.uni-leipzig.org/nlp2rdf/ontologies/nif-core#> .
+@prefix itsrdf: <http://www.w3.org/2005/11/its/rdf#> .
+@prefix xsd: <http://www.w3.org/2001/XMLSchema#> .
+
+`+doc+`#char=0,15> a nif:Context, nif:String, nif:RFC5147String ;
+    nif:isString "Curie said \"hi\"" ;
+    nif:beginIndex "0"^^xsd:nonNegativeInteger ;
+    nif:endIndex "15"^^xsd:nonNegativeInteger .
+
+`+doc+`#char=0,5> a nif:String, nif:RFC5147String, nif:Phrase ;
+    nif:referenceContext `+doc+`#char=0,15> ;
+    nif:anchorOf "Curie" ;
+    nif:beginIndex "0"^^xsd:nonNegativeInteger ;
+    nif:endIndex "5"^^xsd:nonNegativeInteger ;
+    itsrdf:taClassRef <http://example.com/label:PERSON> .
+`, renderNIF("/entities", text, []annotation{{0, 5, "Curie", "PERSON"}}))
+}
+
+func TestRenderXMI(t *testing.T) {
+	xmi := renderXMI("/entities", "😀 Curie & co", []annotation{{2, 7, "Curie", "PERSON"}})
+	assert.Contains(t, xmi, `sofaString="😀 Curie &amp; co"`)
+	assert.Contains(t, xmi, `<tcas:DocumentAnnotation xmi:id="2" sofa="1" begin="0" end="13" language="x-unspecified"/>`)
+	assert.Contains(t, xmi, `<nlpclient:Entity xmi:id="3" sofa="1" begin="3" end="8" label="PERSON"/>`, "offsets count UTF-16 code units")
+	assert.Contains(t, xmi, `<cas:View sofa="1" members="2 3"/>`)
+}
+
+func TestGetTokensXMI(t *testing.T) {
+	prose := mockupstream.NewProse()
+	defer prose.Close()
+	defer func(url string) { urlProse = url }(urlProse)
+	urlProse = prose.URL
+	prose.SetFixture("/tokens", mockupstream.Fixture{Status: http.StatusOK, Body: `["Nobel","Prize"]`})
+
+	req := httptest.NewRequest(http.MethodPost, "/tokens?format=xmi", strings.NewReader(`{"text": "Nobel Prize"}`))
+	w := httptest.NewRecorder()
+	c := e.NewContext(req, w)
+	c.SetPath("/tokens")
+	if assert.NoError(t, getTokens(c)) {
+		assert.Equal(t, interchangeContentTypes[formatXMI], w.Header().Get("Content-Type"))
+		assert.Contains(t, w.Body.String(), `<nlpclient:Token xmi:id="4" sofa="1" begin="6" end="11"/>`)
+	}
+}
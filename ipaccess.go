@@ -0,0 +1,159 @@
+// author: Gary A. Stafford
+// site: https://programmaticponderings.com
+// license: MIT License
+// purpose: NLP microservices: nlp-client IP access control and client IP extraction
+// modified: 2026-10-14
+
+package main
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+)
+
+var (
+	// comma-separated CIDRs or addresses; when set, only matching clients are served
+	ipAllowlist = getEnv("IP_ALLOWLIST", "")
+	// comma-separated CIDRs or addresses that are always refused
+	ipDenylist = getEnv("IP_DENYLIST", "")
+	// comma-separated CIDRs of proxies whose X-Forwarded-For entries are trusted
+	trustedProxies = getEnv("TRUSTED_PROXIES", "")
+	// number of proxies in front of the client, e.g. 2 behind CloudFront and an ALB
+	trustedProxyHops = getEnv("TRUSTED_PROXY_HOPS", "0")
+
+	ipAccess = ipAccessConfig{}
+)
+
+type ipAccessConfig struct {
+	allow   []*net.IPNet
+	deny    []*net.IPNet
+	trusted []*net.IPNet
+	hops    int
+}
+
+func parseCIDRs(value string) ([]*net.IPNet, error) {
+	var nets []*net.IPNet
+	for _, entry := range splitList(value) {
+		if !strings.Contains(entry, "/") {
+			ip := net.ParseIP(entry)
+			if ip == nil {
+				return nil, fmt.Errorf("invalid address: %s", entry)
+			}
+			bits := 8 * net.IPv6len
+			if ip.To4() != nil {
+				ip, bits = ip.To4(), 8*net.IPv4len
+			}
+			nets = append(nets, &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)})
+			continue
+		}
+		_, ipNet, err := net.ParseCIDR(entry)
+		if err != nil {
+			return nil, err
+		}
+		nets = append(nets, ipNet)
+	}
+
+	return nets, nil
+}
+
+func containsIP(nets []*net.IPNet, ip net.IP) bool {
+	for _, ipNet := range nets {
+		if ipNet.Contains(ip) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// loadIPAccess parses the access lists and trusted proxies, and installs the
+// client IP extractor every middleware and handler reads c.RealIP() through.
+func loadIPAccess() error {
+	var cfg ipAccessConfig
+	var err error
+	if cfg.allow, err = parseCIDRs(ipAllowlist); err != nil {
+		return fmt.Errorf("IP_ALLOWLIST: %w", err)
+	}
+	if cfg.deny, err = parseCIDRs(ipDenylist); err != nil {
+		return fmt.Errorf("IP_DENYLIST: %w", err)
+	}
+	if cfg.trusted, err = parseCIDRs(trustedProxies); err != nil {
+		return fmt.Errorf("TRUSTED_PROXIES: %w", err)
+	}
+	if cfg.hops = envInt(trustedProxyHops, 0); cfg.hops < 0 {
+		return fmt.Errorf("invalid TRUSTED_PROXY_HOPS: %s", trustedProxyHops)
+	}
+
+	ipAccess = cfg
+	e.IPExtractor = cfg.clientIP
+
+	return nil
+}
+
+// clientIP walks the X-Forwarded-For chain from the nearest hop, skipping
+// the configured number of proxy hops and any trusted proxy, and returns the
+// first address that was not added by a proxy of ours. Entries further left
+// are supplied by the client and never trusted.
+func (cfg ipAccessConfig) clientIP(req *http.Request) string {
+	remote, _, err := net.SplitHostPort(req.RemoteAddr)
+	if err != nil {
+		remote = req.RemoteAddr
+	}
+	// requests that did not arrive over a connection, such as Lambda events
+	// without a source IP, have no remote address to start the chain
+	var chain []string
+	if remote != "" {
+		chain = append(chain, remote)
+	}
+	forwarded := strings.Split(strings.Join(req.Header.Values(echo.HeaderXForwardedFor), ","), ",")
+	for i := len(forwarded) - 1; i >= 0; i-- {
+		if hop := strings.TrimSpace(forwarded[i]); hop != "" {
+			chain = append(chain, hop)
+		}
+	}
+
+	for i, hop := range chain {
+		ip := net.ParseIP(hop)
+		if ip == nil {
+			// an unparseable entry cannot be a proxy of ours; stop at the last good hop
+			if i == 0 {
+				return hop
+			}
+			return chain[i-1]
+		}
+		if i < cfg.hops || containsIP(cfg.trusted, ip) {
+			continue
+		}
+		return hop
+	}
+
+	if len(chain) == 0 {
+		return ""
+	}
+
+	return chain[len(chain)-1]
+}
+
+// ipAccessControl refuses clients on the denylist, and those missing from
+// the allowlist when one is configured, with 403.
+func ipAccessControl() echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			if len(ipAccess.allow) == 0 && len(ipAccess.deny) == 0 {
+				return next(c)
+			}
+			ip := net.ParseIP(c.RealIP())
+			if ip == nil ||
+				containsIP(ipAccess.deny, ip) ||
+				(len(ipAccess.allow) > 0 && !containsIP(ipAccess.allow, ip)) {
+				return newAPIError(http.StatusForbidden, codeForbidden, "client address not allowed")
+			}
+
+			return next(c)
+		}
+	}
+}
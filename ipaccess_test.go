@@ -0,0 +1,80 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseCIDRs(t *testing.T) {
+	nets, err := parseCIDRs("10.0.0.0/8, 203.0.113.7, 2001:db8::/32")
+	if assert.NoError(t, err) {
+		assert.Equal(t, []string{"10.0.0.0/8", "203.0.113.7/32", "2001:db8::/32"},
+			[]string{nets[0].String(), nets[1].String(), nets[2].String()})
+	}
+
+	_, err = parseCIDRs("10.0.0.0/33")
+	assert.Error(t, err)
+	_, err = parseCIDRs("localhost")
+	assert.Error(t, err)
+}
+
+func TestClientIP(t *testing.T) {
+	trusted, _ := parseCIDRs("10.0.0.0/8")
+	tests := []struct {
+		name      string
+		cfg       ipAccessConfig
+		forwarded string
+		expected  string
+	}{
+		{"no trusted proxies", ipAccessConfig{}, "198.51.100.1", "10.0.0.5"},
+		{"one hop", ipAccessConfig{hops: 1}, "192.0.2.9, 198.51.100.1", "198.51.100.1"},
+		{"two hops", ipAccessConfig{hops: 2}, "192.0.2.9, 198.51.100.1, 130.176.0.1", "198.51.100.1"},
+		{"trusted proxy networks", ipAccessConfig{trusted: trusted}, "198.51.100.1, 10.1.2.3", "198.51.100.1"},
+		{"all hops trusted", ipAccessConfig{hops: 5}, "198.51.100.1", "198.51.100.1"},
+		{"unparseable hop", ipAccessConfig{hops: 3}, "unknown, 198.51.100.1", "198.51.100.1"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/health", nil)
+			req.RemoteAddr = "10.0.0.5:41234"
+			req.Header.Set(echo.HeaderXForwardedFor, tt.forwarded)
+
+			assert.Equal(t, tt.expected, tt.cfg.clientIP(req))
+		})
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/health", nil)
+	req.RemoteAddr = ""
+	req.Header.Set(echo.HeaderXForwardedFor, "203.0.113.9")
+	assert.Equal(t, "203.0.113.9", ipAccessConfig{hops: 1}.clientIP(req), "a missing remote address is skipped")
+}
+
+func TestIPAccessControl(t *testing.T) {
+	defer func(cfg ipAccessConfig) { ipAccess = cfg }(ipAccess)
+	allow, _ := parseCIDRs("192.0.2.0/24")
+	deny, _ := parseCIDRs("192.0.2.66")
+	ipAccess = ipAccessConfig{allow: allow, deny: deny}
+
+	handler := ipAccessControl()(func(c echo.Context) error { return c.NoContent(http.StatusOK) })
+	for addr, status := range map[string]int{
+		"192.0.2.10:1234":   http.StatusOK,
+		"192.0.2.66:1234":   http.StatusForbidden,
+		"198.51.100.1:1234": http.StatusForbidden,
+	} {
+		req := httptest.NewRequest(http.MethodGet, "/keywords", nil)
+		req.RemoteAddr = addr
+		w := httptest.NewRecorder()
+		err := handler(e.NewContext(req, w))
+		if status == http.StatusOK {
+			assert.NoError(t, err, addr)
+			continue
+		}
+		if assert.Error(t, err, addr) {
+			assert.Equal(t, status, err.(*apiError).Status, addr)
+		}
+	}
+}
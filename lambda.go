@@ -0,0 +1,224 @@
+// author: Gary A. Stafford
+// site: https://programmaticponderings.com
+// license: MIT License
+// purpose: NLP microservices: nlp-client AWS Lambda adapter
+// modified: 2026-10-14
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/labstack/echo/v4"
+)
+
+// run the handlers as a Lambda function behind API Gateway or an ALB instead of a server
+var lambdaMode = getEnv("LAMBDA_MODE", "false")
+
+// newLambdaHandler adapts API Gateway REST (v1), HTTP API (v2), and ALB target
+// group events to requests served by h.
+func newLambdaHandler(h http.Handler) func(context.Context, json.RawMessage) (interface{}, error) {
+	return func(ctx context.Context, event json.RawMessage) (interface{}, error) {
+		var probe struct {
+			Version        string `json:"version"`
+			RequestContext struct {
+				ELB *events.ELBContext `json:"elb"`
+			} `json:"requestContext"`
+		}
+		if err := json.Unmarshal(event, &probe); err != nil {
+			return nil, err
+		}
+
+		switch {
+		case probe.Version == "2.0":
+			var req events.APIGatewayV2HTTPRequest
+			if err := json.Unmarshal(event, &req); err != nil {
+				return nil, err
+			}
+			header := singleValueHeader(req.Headers)
+			if len(req.Cookies) > 0 {
+				header.Set("Cookie", strings.Join(req.Cookies, "; "))
+			}
+			r, err := newLambdaRequest(ctx, req.RequestContext.HTTP.Method, req.RawPath, req.RawQueryString,
+				header, req.Body, req.IsBase64Encoded, req.RequestContext.HTTP.SourceIP)
+			if err != nil {
+				return nil, err
+			}
+			w := serveLambda(h, r)
+			body, encoded := lambdaBody(w)
+
+			return events.APIGatewayV2HTTPResponse{
+				StatusCode:      w.Code,
+				Headers:         joinedHeader(w.Header()),
+				Body:            body,
+				IsBase64Encoded: encoded,
+			}, nil
+		case probe.RequestContext.ELB != nil:
+			var req events.ALBTargetGroupRequest
+			if err := json.Unmarshal(event, &req); err != nil {
+				return nil, err
+			}
+			header := http.Header(req.MultiValueHeaders)
+			if header == nil {
+				header = singleValueHeader(req.Headers)
+			}
+			r, err := newLambdaRequest(ctx, req.HTTPMethod, req.Path,
+				encodeQuery(req.QueryStringParameters, req.MultiValueQueryStringParameters, true),
+				header, req.Body, req.IsBase64Encoded, "")
+			if err != nil {
+				return nil, err
+			}
+			w := serveLambda(h, r)
+			body, encoded := lambdaBody(w)
+			resp := events.ALBTargetGroupResponse{
+				StatusCode:        w.Code,
+				StatusDescription: fmt.Sprintf("%d %s", w.Code, http.StatusText(w.Code)),
+				Body:              body,
+				IsBase64Encoded:   encoded,
+			}
+			// the target group only accepts multi-value headers when it sends them
+			if req.MultiValueHeaders != nil {
+				resp.MultiValueHeaders = w.Header()
+			} else {
+				resp.Headers = joinedHeader(w.Header())
+			}
+
+			return resp, nil
+		default:
+			var req events.APIGatewayProxyRequest
+			if err := json.Unmarshal(event, &req); err != nil {
+				return nil, err
+			}
+			header := http.Header(req.MultiValueHeaders)
+			if header == nil {
+				header = singleValueHeader(req.Headers)
+			}
+			r, err := newLambdaRequest(ctx, req.HTTPMethod, req.Path,
+				encodeQuery(req.QueryStringParameters, req.MultiValueQueryStringParameters, false),
+				header, req.Body, req.IsBase64Encoded, req.RequestContext.Identity.SourceIP)
+			if err != nil {
+				return nil, err
+			}
+			w := serveLambda(h, r)
+			body, encoded := lambdaBody(w)
+
+			return events.APIGatewayProxyResponse{
+				StatusCode:        w.Code,
+				MultiValueHeaders: w.Header(),
+				Body:              body,
+				IsBase64Encoded:   encoded,
+			}, nil
+		}
+	}
+}
+
+func newLambdaRequest(ctx context.Context, method, path, query string, header http.Header,
+	body string, encoded bool, sourceIP string) (*http.Request, error) {
+	var payload io.Reader = strings.NewReader(body)
+	if encoded {
+		decoded, err := base64.StdEncoding.DecodeString(body)
+		if err != nil {
+			return nil, err
+		}
+		payload = bytes.NewReader(decoded)
+	}
+
+	target := path
+	if query != "" {
+		target += "?" + query
+	}
+	r, err := http.NewRequestWithContext(ctx, method, target, payload)
+	if err != nil {
+		return nil, err
+	}
+	r.RequestURI = target
+	// canonicalize header names, which the events deliver lower-cased
+	for name, values := range header {
+		for _, value := range values {
+			r.Header.Add(name, value)
+		}
+	}
+	r.Host = r.Header.Get("Host")
+	// API Gateway is the peer; ALB events carry no source IP, only X-Forwarded-For
+	if sourceIP != "" {
+		r.RemoteAddr = net.JoinHostPort(sourceIP, "0")
+	}
+
+	return r, nil
+}
+
+func serveLambda(h http.Handler, r *http.Request) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+
+	return w
+}
+
+// lambdaBody returns text responses as-is and anything else base64 encoded.
+func lambdaBody(w *httptest.ResponseRecorder) (string, bool) {
+	contentType := w.Header().Get(echo.HeaderContentType)
+	if contentType == "" || strings.HasPrefix(contentType, "text/") ||
+		strings.Contains(contentType, "json") || strings.Contains(contentType, "xml") {
+		return w.Body.String(), false
+	}
+
+	return base64.StdEncoding.EncodeToString(w.Body.Bytes()), true
+}
+
+func singleValueHeader(headers map[string]string) http.Header {
+	header := make(http.Header, len(headers))
+	for name, value := range headers {
+		header.Set(name, value)
+	}
+
+	return header
+}
+
+func joinedHeader(header http.Header) map[string]string {
+	joined := make(map[string]string, len(header))
+	for name, values := range header {
+		joined[name] = strings.Join(values, ",")
+	}
+
+	return joined
+}
+
+// encodeQuery rebuilds the query string; ALB delivers the parameters still URL-encoded.
+func encodeQuery(single map[string]string, multi map[string][]string, escaped bool) string {
+	values := url.Values{}
+	if multi != nil {
+		for name, list := range multi {
+			for _, value := range list {
+				values.Add(unescapeQuery(name, escaped), unescapeQuery(value, escaped))
+			}
+		}
+	} else {
+		for name, value := range single {
+			values.Set(unescapeQuery(name, escaped), unescapeQuery(value, escaped))
+		}
+	}
+
+	return values.Encode()
+}
+
+func unescapeQuery(value string, escaped bool) string {
+	if !escaped {
+		return value
+	}
+	if unescaped, err := url.QueryUnescape(value); err == nil {
+		return unescaped
+	}
+
+	return value
+}
@@ -0,0 +1,112 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+)
+
+func newLambdaEcho() *echo.Echo {
+	app := echo.New()
+	app.POST("/keywords", func(c echo.Context) error {
+		var body struct {
+			Text string `json:"text"`
+		}
+		if err := c.Bind(&body); err != nil {
+			return err
+		}
+		return c.JSON(http.StatusOK, map[string]string{
+			"text":  body.Text,
+			"key":   c.Request().Header.Get("X-API-Key"),
+			"limit": c.QueryParam("limit"),
+		})
+	})
+
+	return app
+}
+
+func TestLambdaHandlerRESTAPI(t *testing.T) {
+	event := `{"httpMethod": "POST", "path": "/keywords",
+		"headers": {"content-type": "application/json", "x-api-key": "secret"},
+		"queryStringParameters": {"limit": "5"},
+		"requestContext": {"stage": "prod"},
+		"body": "{\"text\": \"hello\"}"}`
+
+	resp, err := newLambdaHandler(newLambdaEcho())(context.Background(), json.RawMessage(event))
+	if assert.NoError(t, err) {
+		proxy := resp.(events.APIGatewayProxyResponse)
+		assert.Equal(t, http.StatusOK, proxy.StatusCode)
+		assert.False(t, proxy.IsBase64Encoded)
+		assert.JSONEq(t, `{"text": "hello", "key": "secret", "limit": "5"}`, proxy.Body)
+	}
+}
+
+func TestLambdaHandlerHTTPAPI(t *testing.T) {
+	event := `{"version": "2.0", "rawPath": "/keywords", "rawQueryString": "limit=3",
+		"headers": {"content-type": "application/json", "x-api-key": "secret"},
+		"requestContext": {"http": {"method": "POST"}},
+		"body": "eyJ0ZXh0IjogImhlbGxvIn0=", "isBase64Encoded": true}`
+
+	resp, err := newLambdaHandler(newLambdaEcho())(context.Background(), json.RawMessage(event))
+	if assert.NoError(t, err) {
+		proxy := resp.(events.APIGatewayV2HTTPResponse)
+		assert.Equal(t, http.StatusOK, proxy.StatusCode)
+		assert.JSONEq(t, `{"text": "hello", "key": "secret", "limit": "3"}`, proxy.Body)
+	}
+}
+
+func TestLambdaHandlerClientIP(t *testing.T) {
+	allow, _ := parseCIDRs("203.0.113.0/24")
+	defer func(cfg ipAccessConfig) { ipAccess = cfg }(ipAccess)
+	ipAccess = ipAccessConfig{allow: allow, hops: 1}
+	app := echo.New()
+	app.IPExtractor = ipAccess.clientIP
+	app.GET("/ip", func(c echo.Context) error { return c.String(http.StatusOK, c.RealIP()) }, ipAccessControl())
+
+	for _, event := range []string{
+		`{"httpMethod": "GET", "path": "/ip", "headers": {"x-forwarded-for": "203.0.113.9"},
+			"requestContext": {"identity": {"sourceIp": "203.0.113.9"}}}`,
+		`{"version": "2.0", "rawPath": "/ip", "headers": {"x-forwarded-for": "203.0.113.9"},
+			"requestContext": {"http": {"method": "GET", "sourceIp": "203.0.113.9"}}}`,
+		`{"httpMethod": "GET", "path": "/ip", "headers": {"x-forwarded-for": "203.0.113.9"},
+			"requestContext": {"elb": {"targetGroupArn": "arn:aws:elasticloadbalancing:us-east-1:111222333444:targetgroup/nlp"}}}`,
+	} {
+		resp, err := newLambdaHandler(app)(context.Background(), json.RawMessage(event))
+		if !assert.NoError(t, err) {
+			continue
+		}
+		var status int
+		var body string
+		switch r := resp.(type) {
+		case events.APIGatewayProxyResponse:
+			status, body = r.StatusCode, r.Body
+		case events.APIGatewayV2HTTPResponse:
+			status, body = r.StatusCode, r.Body
+		case events.ALBTargetGroupResponse:
+			status, body = r.StatusCode, r.Body
+		}
+		assert.Equal(t, http.StatusOK, status, event)
+		assert.Equal(t, "203.0.113.9", body, event)
+	}
+}
+
+func TestLambdaHandlerALB(t *testing.T) {
+	event := `{"httpMethod": "POST", "path": "/keywords",
+		"headers": {"content-type": "application/json", "x-api-key": "secret"},
+		"queryStringParameters": {"limit": "1%202"},
+		"requestContext": {"elb": {"targetGroupArn": "arn:aws:elasticloadbalancing:us-east-1:111222333444:targetgroup/nlp"}},
+		"body": "{\"text\": \"hello\"}"}`
+
+	resp, err := newLambdaHandler(newLambdaEcho())(context.Background(), json.RawMessage(event))
+	if assert.NoError(t, err) {
+		alb := resp.(events.ALBTargetGroupResponse)
+		assert.Equal(t, "200 OK", alb.StatusDescription)
+		assert.Nil(t, alb.MultiValueHeaders)
+		assert.JSONEq(t, `{"text": "hello", "key": "secret", "limit": "1 2"}`, alb.Body)
+	}
+}
@@ -0,0 +1,128 @@
+// author: Gary A. Stafford
+// site: https://programmaticponderings.com
+// license: MIT License
+// purpose: NLP microservices: nlp-client per-route body size and timeout limits
+// modified: 2026-10-14
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/labstack/echo/v4/middleware"
+)
+
+var (
+	// both unlimited unless set
+	maxBodySize    = getEnv("MAX_BODY_SIZE", "")
+	requestTimeout = getEnv("REQUEST_TIMEOUT", "0")
+	// e.g. "/language=4K:2s;/entities=20M:120s"; either half may be left empty
+	routeLimits = getEnv("ROUTE_LIMITS", "")
+)
+
+type routeLimit struct {
+	bodySize string
+	timeout  time.Duration
+}
+
+// parseRouteLimits returns the per-route overrides, falling back to the global
+// values for whichever half of an entry is left empty.
+func parseRouteLimits(config string, fallback routeLimit) (map[string]routeLimit, error) {
+	limits := map[string]routeLimit{}
+	for _, entry := range strings.Split(config, ";") {
+		if strings.TrimSpace(entry) == "" {
+			continue
+		}
+		parts := strings.SplitN(entry, "=", 2)
+		values := []string{""}
+		if len(parts) == 2 {
+			values = strings.SplitN(parts[1], ":", 2)
+		}
+		if len(parts) != 2 || len(values) != 2 {
+			return nil, fmt.Errorf("invalid route limit: %s", entry)
+		}
+
+		limit := fallback
+		if size := strings.TrimSpace(values[0]); size != "" {
+			limit.bodySize = size
+		}
+		if timeout := strings.TrimSpace(values[1]); timeout != "" {
+			d, err := time.ParseDuration(timeout)
+			if err != nil {
+				return nil, fmt.Errorf("invalid route limit: %s", entry)
+			}
+			limit.timeout = d
+		}
+		limits[strings.TrimSpace(parts[0])] = limit
+	}
+
+	return limits, nil
+}
+
+// newLimits enforces the body size and timeout of the matched route. The
+// timeout is set as the request context deadline, which every upstream call
+// made for the request inherits.
+func newLimits(limits map[string]routeLimit, fallback routeLimit) echo.MiddlewareFunc {
+	bodyLimits := map[string]echo.MiddlewareFunc{}
+	for path, limit := range limits {
+		if limit.bodySize != "" {
+			bodyLimits[path] = middleware.BodyLimit(limit.bodySize)
+		}
+	}
+	var fallbackBodyLimit echo.MiddlewareFunc
+	if fallback.bodySize != "" {
+		fallbackBodyLimit = middleware.BodyLimit(fallback.bodySize)
+	}
+
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			limit, ok := limits[routePath(c)]
+			if !ok {
+				limit = fallback
+			}
+			if limit.timeout > 0 {
+				ctx, cancel := context.WithTimeout(c.Request().Context(), limit.timeout)
+				defer cancel()
+				c.SetRequest(c.Request().WithContext(ctx))
+			}
+
+			bodyLimit, ok := bodyLimits[routePath(c)]
+			if !ok {
+				bodyLimit = fallbackBodyLimit
+			}
+			if bodyLimit != nil {
+				return bodyLimit(next)(c)
+			}
+
+			return next(c)
+		}
+	}
+}
+
+// earlyBodySize caps the bodies read whole before the limits stage, by
+// signature checks and form parsing, when MAX_BODY_SIZE is unset.
+const earlyBodySize = "10M"
+
+// earlyBodyLimit is the body limit of middleware that reads the body before
+// the route's own limits apply.
+func earlyBodyLimit() echo.MiddlewareFunc {
+	if maxBodySize != "" {
+		return middleware.BodyLimit(maxBodySize)
+	}
+	return middleware.BodyLimit(earlyBodySize)
+}
+
+func routeLimitsMiddleware() echo.MiddlewareFunc {
+	fallback := routeLimit{bodySize: maxBodySize, timeout: envDuration(requestTimeout, 0)}
+	limits, err := parseRouteLimits(routeLimits, fallback)
+	if err != nil {
+		e.Logger.Errorf("ignoring ROUTE_LIMITS: %v", err)
+		limits = map[string]routeLimit{}
+	}
+
+	return newLimits(limits, fallback)
+}
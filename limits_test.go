@@ -0,0 +1,75 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseRouteLimits(t *testing.T) {
+	fallback := routeLimit{bodySize: "10M", timeout: time.Minute}
+	limits, err := parseRouteLimits("/language=4K:2s; /entities=:2m;/batch=1G:", fallback)
+	if assert.NoError(t, err) {
+		assert.Equal(t, map[string]routeLimit{
+			"/language": {"4K", 2 * time.Second},
+			"/entities": {"10M", 2 * time.Minute},
+			"/batch":    {"1G", time.Minute},
+		}, limits)
+	}
+
+	_, err = parseRouteLimits("/language=4K", fallback)
+	assert.EqualError(t, err, "invalid route limit: /language=4K")
+	_, err = parseRouteLimits("/language=4K:soon", fallback)
+	assert.Error(t, err)
+}
+
+func TestLimits(t *testing.T) {
+	limits := map[string]routeLimit{"/language": {"4", 10 * time.Millisecond}}
+	mw := newLimits(limits, routeLimit{bodySize: "1K"})
+
+	handler := func(c echo.Context) error {
+		deadline, ok := c.Request().Context().Deadline()
+		if ok {
+			assert.WithinDuration(t, time.Now().Add(10*time.Millisecond), deadline, 10*time.Millisecond)
+		}
+		return c.NoContent(http.StatusOK)
+	}
+
+	req := httptest.NewRequest(http.MethodPost, "/language", strings.NewReader("too large"))
+	c := e.NewContext(req, httptest.NewRecorder())
+	c.SetPath("/language")
+	err := mw(handler)(c)
+	if assert.Error(t, err) {
+		assert.Equal(t, http.StatusRequestEntityTooLarge, err.(*echo.HTTPError).Code)
+	}
+
+	req = httptest.NewRequest(http.MethodPost, "/keywords", strings.NewReader("small"))
+	w := httptest.NewRecorder()
+	c = e.NewContext(req, w)
+	c.SetPath("/keywords")
+	if assert.NoError(t, mw(handler)(c)) {
+		assert.Equal(t, http.StatusOK, w.Code)
+	}
+}
+
+func TestLimitsUnset(t *testing.T) {
+	mw := newLimits(map[string]routeLimit{}, routeLimit{bodySize: maxBodySize, timeout: envDuration(requestTimeout, 0)})
+
+	req := httptest.NewRequest(http.MethodPost, "/batch", strings.NewReader(strings.Repeat("x", 11<<20)))
+	w := httptest.NewRecorder()
+	c := e.NewContext(req, w)
+	c.SetPath("/batch")
+	handler := func(c echo.Context) error {
+		_, ok := c.Request().Context().Deadline()
+		assert.False(t, ok, "no timeout unless REQUEST_TIMEOUT is set")
+		return c.NoContent(http.StatusOK)
+	}
+	if assert.NoError(t, mw(handler)(c), "no body size limit unless MAX_BODY_SIZE is set") {
+		assert.Equal(t, http.StatusOK, w.Code)
+	}
+}
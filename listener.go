@@ -0,0 +1,137 @@
+// author: Gary A. Stafford
+// site: https://programmaticponderings.com
+// license: MIT License
+// purpose: NLP microservices: nlp-client listeners
+// modified: 2026-10-14
+
+package main
+
+import (
+	"crypto/tls"
+	"errors"
+	"flag"
+	"fmt"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// systemd passes activated sockets starting at file descriptor 3
+const listenFdsStart = 3
+
+var (
+	serverHost       = getEnv("NLP_CLIENT_HOST", "")
+	serverTLSPort    = getEnv("NLP_CLIENT_TLS_PORT", "")
+	tlsCertFile      = getEnv("TLS_CERT_FILE", "")
+	tlsKeyFile       = getEnv("TLS_KEY_FILE", "")
+	socketActivation = getEnv("SOCKET_ACTIVATION", "false")
+)
+
+// parseFlags lets command-line flags override the listener environment variables.
+func parseFlags(args []string) error {
+	fs := flag.NewFlagSet("nlp-client", flag.ContinueOnError)
+	fs.StringVar(&serverHost, "host", serverHost, "bind address (NLP_CLIENT_HOST)")
+	fs.StringVar(&serverPort, "port", serverPort, "HTTP port, host:port, or unix:// socket; empty disables HTTP (NLP_CLIENT_PORT)")
+	fs.StringVar(&serverTLSPort, "tls-port", serverTLSPort, "HTTPS port or host:port; empty disables HTTPS (NLP_CLIENT_TLS_PORT)")
+	fs.StringVar(&tlsCertFile, "tls-cert", tlsCertFile, "TLS certificate file (TLS_CERT_FILE)")
+	fs.StringVar(&tlsKeyFile, "tls-key", tlsKeyFile, "TLS private key file (TLS_KEY_FILE)")
+	fs.StringVar(&socketActivation, "socket-activation", socketActivation, "use systemd-activated sockets (SOCKET_ACTIVATION)")
+
+	return fs.Parse(args)
+}
+
+// listenAddress joins host and port, leaving full addresses and sockets untouched.
+func listenAddress(host, port string) string {
+	if port == "" || strings.HasPrefix(port, unixScheme) || strings.Contains(port, ":") {
+		return port
+	}
+
+	return net.JoinHostPort(host, port)
+}
+
+// activationListeners returns the sockets passed by systemd socket activation:
+// the first is served as HTTP and the second, if present, as HTTPS.
+func activationListeners() ([]net.Listener, error) {
+	pid, err := strconv.Atoi(os.Getenv("LISTEN_PID"))
+	if err != nil || pid != os.Getpid() {
+		return nil, errors.New("no sockets passed by systemd for this process")
+	}
+	count, err := strconv.Atoi(os.Getenv("LISTEN_FDS"))
+	if err != nil || count < 1 {
+		return nil, errors.New("no sockets passed by systemd for this process")
+	}
+	_ = os.Unsetenv("LISTEN_PID")
+	_ = os.Unsetenv("LISTEN_FDS")
+
+	listeners := make([]net.Listener, 0, count)
+	for fd := listenFdsStart; fd < listenFdsStart+count; fd++ {
+		f := os.NewFile(uintptr(fd), fmt.Sprintf("LISTEN_FD_%d", fd))
+		l, err := net.FileListener(f)
+		if err != nil {
+			return nil, err
+		}
+		_ = f.Close()
+		listeners = append(listeners, l)
+	}
+
+	return listeners, nil
+}
+
+// activationTLSConfig is the TLS config of a socket-activated HTTPS listener.
+// It offers HTTP/2, as e.StartTLS does, since a listener passed in is served
+// without the ALPN protocols StartTLS would set.
+func activationTLSConfig(cert tls.Certificate) *tls.Config {
+	return &tls.Config{
+		Certificates: []tls.Certificate{cert},
+		NextProtos:   []string{"h2", "http/1.1"},
+	}
+}
+
+// startServers starts the HTTP and HTTPS listeners that are configured, and
+// returns when the first of them stops.
+func startServers() error {
+	httpAddr := listenAddress(serverHost, serverPort)
+	tlsAddr := listenAddress(serverHost, serverTLSPort)
+
+	if envBool(socketActivation) {
+		listeners, err := activationListeners()
+		if err != nil {
+			return err
+		}
+		e.Listener = listeners[0]
+		if len(listeners) > 1 {
+			cert, err := tls.LoadX509KeyPair(tlsCertFile, tlsKeyFile)
+			if err != nil {
+				return err
+			}
+			e.TLSServer.TLSConfig = activationTLSConfig(cert)
+			e.TLSListener = tls.NewListener(listeners[1], e.TLSServer.TLSConfig)
+		}
+	} else if strings.HasPrefix(httpAddr, unixScheme) {
+		l, err := listenUnix(httpAddr)
+		if err != nil {
+			return err
+		}
+		e.Listener = l
+	}
+
+	errs := make(chan error, 2)
+	servers := 0
+	if e.Listener != nil || httpAddr != "" {
+		servers++
+		go func() { errs <- e.Start(httpAddr) }()
+	}
+	if e.TLSListener != nil {
+		servers++
+		go func() { errs <- e.StartServer(e.TLSServer) }()
+	} else if tlsAddr != "" {
+		servers++
+		go func() { errs <- e.StartTLS(tlsAddr, tlsCertFile, tlsKeyFile) }()
+	}
+	if servers == 0 {
+		return errors.New("no HTTP or HTTPS listener configured")
+	}
+
+	return <-errs
+}
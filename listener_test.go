@@ -0,0 +1,40 @@
+package main
+
+import (
+	"crypto/tls"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestListenAddress(t *testing.T) {
+	assert.Equal(t, ":8080", listenAddress("", ":8080"))
+	assert.Equal(t, ":8080", listenAddress("", "8080"))
+	assert.Equal(t, "127.0.0.1:8443", listenAddress("127.0.0.1", "8443"))
+	assert.Equal(t, "unix:///tmp/nlp.sock", listenAddress("127.0.0.1", "unix:///tmp/nlp.sock"))
+	assert.Equal(t, "", listenAddress("127.0.0.1", ""))
+}
+
+func TestParseFlags(t *testing.T) {
+	defer func(host, port, tlsPort string) {
+		serverHost, serverPort, serverTLSPort = host, port, tlsPort
+	}(serverHost, serverPort, serverTLSPort)
+
+	err := parseFlags([]string{"-host", "0.0.0.0", "-port", "9090", "-tls-port", "9443"})
+	if assert.NoError(t, err) {
+		assert.Equal(t, "0.0.0.0", serverHost)
+		assert.Equal(t, "9090", serverPort)
+		assert.Equal(t, "9443", serverTLSPort)
+	}
+}
+
+func TestActivationListenersNotActivated(t *testing.T) {
+	_, err := activationListeners()
+	assert.Error(t, err)
+}
+
+func TestActivationTLSConfig(t *testing.T) {
+	cfg := activationTLSConfig(tls.Certificate{})
+	assert.Len(t, cfg.Certificates, 1)
+	assert.Equal(t, []string{"h2", "http/1.1"}, cfg.NextProtos, "HTTP/2 is offered as it is by e.StartTLS")
+}
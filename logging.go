@@ -0,0 +1,184 @@
+// author: Gary A. Stafford
+// site: https://programmaticponderings.com
+// license: MIT License
+// purpose: NLP microservices: nlp-client runtime log level and body sampling
+// modified: 2026-10-14
+
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"math/rand"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/labstack/echo/v4"
+	"github.com/labstack/gommon/log"
+)
+
+var (
+	// fraction of request bodies logged, from 0 (off) to 1 (every request)
+	bodyLogSampleRate = getEnv("BODY_LOG_SAMPLE_RATE", "0")
+	bodyLogMaxBytes   = getEnv("BODY_LOG_MAX_BYTES", "1024")
+	bodyLogging       = newBodyLogConfig(parseSampleRate(bodyLogSampleRate), envInt(bodyLogMaxBytes, 1024))
+)
+
+var logLevels = map[string]log.Lvl{
+	"DEBUG": log.DEBUG,
+	"INFO":  log.INFO,
+	"WARN":  log.WARN,
+	"ERROR": log.ERROR,
+	"OFF":   log.OFF,
+}
+
+// piiPatterns are redacted from logged request bodies.
+var piiPatterns = []*regexp.Regexp{
+	regexp.MustCompile(`[A-Za-z0-9._%+-]+@[A-Za-z0-9.-]+\.[A-Za-z]{2,}`),
+	regexp.MustCompile(`\b\d{3}-\d{2}-\d{4}\b`),
+	regexp.MustCompile(`\b(?:\d[ -]?){12,18}\d\b`),
+	regexp.MustCompile(`(?:\+\d{1,3}[ .-]?)?\(?\b\d{3}\)?[ .-]?\d{3}[ .-]?\d{4}\b`),
+}
+
+// bodyLogConfig holds the body sampling settings, which may be changed while the service is running.
+type bodyLogConfig struct {
+	mu       sync.RWMutex
+	rate     float64
+	maxBytes int
+}
+
+func newBodyLogConfig(rate float64, maxBytes int) *bodyLogConfig {
+	return &bodyLogConfig{rate: rate, maxBytes: maxBytes}
+}
+
+func (b *bodyLogConfig) get() (float64, int) {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	return b.rate, b.maxBytes
+}
+
+func (b *bodyLogConfig) set(rate float64, maxBytes int) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.rate, b.maxBytes = rate, maxBytes
+}
+
+func parseSampleRate(value string) float64 {
+	rate, err := strconv.ParseFloat(value, 64)
+	if err != nil || rate < 0 || rate > 1 {
+		return 0
+	}
+
+	return rate
+}
+
+func redactPII(text string) string {
+	for _, pattern := range piiPatterns {
+		text = pattern.ReplaceAllString(text, "[REDACTED]")
+	}
+
+	return text
+}
+
+// bodyLog logs a sample of request bodies, truncated and redacted. Only the
+// logged prefix is buffered; the rest of the body streams through as before.
+func bodyLog() echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			rate, maxBytes := bodyLogging.get()
+			req := c.Request()
+			if rate <= 0 || req.Body == nil || rand.Float64() >= rate {
+				return next(c)
+			}
+
+			prefix, err := ioutil.ReadAll(io.LimitReader(req.Body, int64(maxBytes)+1))
+			if err != nil {
+				return err
+			}
+			req.Body = struct {
+				io.Reader
+				io.Closer
+			}{io.MultiReader(bytes.NewReader(prefix), req.Body), req.Body}
+
+			truncated := len(prefix) > maxBytes
+			if truncated {
+				prefix = prefix[:maxBytes]
+			}
+			e.Logger.Infoj(log.JSON{
+				"message":    "request body sample",
+				"method":     req.Method,
+				"path":       c.Path(),
+				"request_id": c.Response().Header().Get(echo.HeaderXRequestID),
+				"body":       redactPII(string(prefix)),
+				"truncated":  truncated,
+			})
+
+			return next(c)
+		}
+	}
+}
+
+type loggingSettings struct {
+	Level          string   `json:"level"`
+	BodySampleRate *float64 `json:"body_sample_rate,omitempty"`
+	BodyMaxBytes   *int     `json:"body_max_bytes,omitempty"`
+}
+
+func currentLoggingSettings() loggingSettings {
+	rate, maxBytes := bodyLogging.get()
+	level := strconv.Itoa(int(e.Logger.Level()))
+	for name, lvl := range logLevels {
+		if lvl == e.Logger.Level() {
+			level = name
+		}
+	}
+
+	return loggingSettings{Level: level, BodySampleRate: &rate, BodyMaxBytes: &maxBytes}
+}
+
+func getLogging(c echo.Context) error {
+	return c.JSON(http.StatusOK, currentLoggingSettings())
+}
+
+// putLogging changes the log level and body sampling without a restart;
+// fields left out of the request keep their current values.
+func putLogging(c echo.Context) error {
+	var settings loggingSettings
+	if err := c.Bind(&settings); err != nil {
+		return validationError("invalid logging settings")
+	}
+
+	level := e.Logger.Level()
+	if settings.Level != "" {
+		lvl, ok := logLevels[strings.ToUpper(settings.Level)]
+		if !ok {
+			return validationError(fmt.Sprintf("unknown log level: %s", settings.Level))
+		}
+		level = lvl
+	}
+	rate, maxBytes := bodyLogging.get()
+	if settings.BodySampleRate != nil {
+		if *settings.BodySampleRate < 0 || *settings.BodySampleRate > 1 {
+			return validationError("body_sample_rate must be between 0 and 1")
+		}
+		rate = *settings.BodySampleRate
+	}
+	if settings.BodyMaxBytes != nil {
+		if *settings.BodyMaxBytes < 1 {
+			return validationError("body_max_bytes must be positive")
+		}
+		maxBytes = *settings.BodyMaxBytes
+	}
+
+	e.Logger.SetLevel(level)
+	bodyLogging.set(rate, maxBytes)
+
+	return c.JSON(http.StatusOK, currentLoggingSettings())
+}
@@ -0,0 +1,70 @@
+package main
+
+import (
+	"bytes"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRedactPII(t *testing.T) {
+	redacted := redactPII("mail marie@curie.fr or call 555-123-4567, card 4111 1111 1111 1111, ssn 123-45-6789")
+	assert.Equal(t, "mail [REDACTED] or call [REDACTED], card [REDACTED], ssn [REDACTED]", redacted)
+	assert.Equal(t, "Marie Curie won in 1903", redactPII("Marie Curie won in 1903"))
+}
+
+func TestParseSampleRate(t *testing.T) {
+	assert.Equal(t, 0.01, parseSampleRate("0.01"))
+	assert.Equal(t, 0.0, parseSampleRate("2"))
+	assert.Equal(t, 0.0, parseSampleRate("often"))
+}
+
+func TestBodyLog(t *testing.T) {
+	defer func(rate float64, maxBytes int) { bodyLogging.set(rate, maxBytes) }(bodyLogging.get())
+	defer e.Logger.SetOutput(e.Logger.Output())
+	logged := new(bytes.Buffer)
+	e.Logger.SetOutput(logged)
+	bodyLogging.set(1, 16)
+
+	body := `{"text": "write to marie@curie.fr"}`
+	req := httptest.NewRequest(http.MethodPost, "/keywords", strings.NewReader(body))
+	var received []byte
+	handler := bodyLog()(func(c echo.Context) error {
+		received, _ = ioutil.ReadAll(c.Request().Body)
+		return nil
+	})
+
+	if assert.NoError(t, handler(e.NewContext(req, httptest.NewRecorder()))) {
+		// the handler still sees the whole body
+		assert.Equal(t, body, string(received))
+		assert.Contains(t, logged.String(), `"truncated":true`)
+		assert.Contains(t, logged.String(), `{\"text\": \"write `)
+	}
+}
+
+func TestPutLogging(t *testing.T) {
+	defer func(rate float64, maxBytes int) { bodyLogging.set(rate, maxBytes) }(bodyLogging.get())
+	defer e.Logger.SetLevel(e.Logger.Level())
+
+	req := httptest.NewRequest(http.MethodPut, "/admin/logging", strings.NewReader(`{"level": "warn", "body_sample_rate": 0.25}`))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	w := httptest.NewRecorder()
+
+	if assert.NoError(t, putLogging(e.NewContext(req, w))) {
+		rate, _ := bodyLogging.get()
+		assert.Equal(t, 0.25, rate)
+		assert.Contains(t, w.Body.String(), `"level":"WARN"`)
+	}
+
+	req = httptest.NewRequest(http.MethodPut, "/admin/logging", strings.NewReader(`{"level": "loud"}`))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	err := putLogging(e.NewContext(req, httptest.NewRecorder()))
+	if assert.Error(t, err) {
+		assert.Equal(t, http.StatusBadRequest, err.(*apiError).Status)
+	}
+}
@@ -7,26 +7,30 @@
 package main
 
 import (
+	"bytes"
+	"encoding/json"
+	"fmt"
 	"io"
 	"io/ioutil"
 	"net/http"
 	"os"
 	"strconv"
 	"strings"
+	"time"
+	"unicode/utf8"
 
+	"github.com/aws/aws-lambda-go/lambda"
 	"github.com/labstack/echo/v4"
-	"github.com/labstack/echo/v4/middleware"
 	"github.com/labstack/gommon/log"
-	"golang.org/x/net/context"
 )
 
 var (
 	logLevel   = getEnv("LOG_LEVEL", "1") // DEBUG
 	serverPort = getEnv("NLP_CLIENT_PORT", ":8080")
-	urlRake    = getEnv("RAKE_ENDPOINT", "http://localhost:8081")
-	urlProse   = getEnv("PROSE_ENDPOINT", "http://localhost:8082")
-	urlLang    = getEnv("LANG_ENDPOINT", "http://localhost:8083")
-	urlDynamo  = getEnv("DYNAMO_ENDPOINT", "http://localhost:8084")
+	urlRake    = upstreamURL(getEnv("RAKE_ENDPOINT", "http://localhost:8081"))
+	urlProse   = upstreamURL(getEnv("PROSE_ENDPOINT", "http://localhost:8082"))
+	urlLang    = upstreamURL(getEnv("LANG_ENDPOINT", "http://localhost:8083"))
+	urlDynamo  = upstreamURL(getEnv("DYNAMO_ENDPOINT", "http://localhost:8084"))
 	apiKey     = getEnv("API_KEY", "ChangeMe")
 	e          = echo.New()
 )
@@ -39,10 +43,6 @@ func getEnv(key, fallback string) string {
 	return fallback
 }
 
-func getRoutes(c echo.Context) error {
-	return c.JSON(http.StatusOK, e.Routes())
-}
-
 func getHealth(c echo.Context) error {
 	healthStatus := struct {
 		Status string `json:"status"`
@@ -51,21 +51,12 @@ func getHealth(c echo.Context) error {
 }
 
 func getHealthUpstream(c echo.Context) error {
-	var urlHealth = ""
-	switch c.Param("app") {
-	case "rake":
-		urlHealth = urlRake
-	case "prose":
-		urlHealth = urlProse
-	case "lang":
-		urlHealth = urlLang
-	case "dynamo":
-		urlHealth = urlDynamo
-	default:
+	urlHealth, ok := namedUpstreams()[c.Param("app")]
+	if !ok {
 		return echo.NewHTTPError(http.StatusMethodNotAllowed)
 	}
 
-	ctx := context.Background()
+	ctx := c.Request().Context()
 	req, err := http.NewRequestWithContext(ctx, http.MethodGet, urlHealth+"/health", c.Request().Body)
 
 	return serviceResponse(err, req, c)
@@ -76,51 +67,128 @@ func getError(c echo.Context) error {
 }
 
 func getKeywords(c echo.Context) error {
-	ctx := context.Background()
+	ctx := c.Request().Context()
 	req, err := http.NewRequestWithContext(ctx, http.MethodPost, urlRake+"/keywords", c.Request().Body)
 
 	return serviceResponse(err, req, c)
 }
 
-func getTokens(c echo.Context) error {
-	ctx := context.Background()
-	req, err := http.NewRequestWithContext(ctx, http.MethodPost, urlProse+"/tokens", c.Request().Body)
-
-	return serviceResponse(err, req, c)
-}
-
 func getEntities(c echo.Context) error {
-	ctx := context.Background()
+	ctx := c.Request().Context()
 	req, err := http.NewRequestWithContext(ctx, http.MethodPost, urlProse+"/entities", c.Request().Body)
 
 	return serviceResponse(err, req, c)
 }
 
 func getSentences(c echo.Context) error {
-	ctx := context.Background()
+	ctx := c.Request().Context()
 	req, err := http.NewRequestWithContext(ctx, http.MethodPost, urlProse+"/sentences", c.Request().Body)
 
 	return serviceResponse(err, req, c)
 }
 
 func getLanguage(c echo.Context) error {
-	ctx := context.Background()
+	ctx := c.Request().Context()
 	req, err := http.NewRequestWithContext(ctx, http.MethodPost, urlLang+"/language", c.Request().Body)
 
 	return serviceResponse(err, req, c)
 }
 
 func putDynamo(c echo.Context) error {
-	ctx := context.Background()
-	req, err := http.NewRequestWithContext(ctx, http.MethodPost, urlDynamo+"/record", c.Request().Body)
+	target, _, err := recordTarget(c.Request().Header)
+	if err != nil {
+		return err
+	}
+	if err := processRecord(c); err != nil {
+		return err
+	}
+	ctx := c.Request().Context()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, target+"/record", c.Request().Body)
 
 	return serviceResponse(err, req, c)
 }
 
 func serviceResponse(err error, req *http.Request, c echo.Context) error {
+	path := routePath(c)
+	version, err := schemaVersion(c)
+	if err != nil {
+		return validationError(err.Error())
+	}
+	// v2 responses always carry their metadata
+	meta := wantsMeta(c) || version == schemaV2
+	paged, err := parsePage(c)
+	if err != nil {
+		return validationError(err.Error())
+	}
+	filter, err := parseResultFilter(c)
+	if err != nil {
+		return validationError(err.Error())
+	}
+	if !filteredRoutes[path] {
+		filter = nil
+	}
+	enrich, err := parseEnrich(c)
+	if err != nil {
+		return validationError(err.Error())
+	}
+	if !enrich.applies(path) {
+		enrich = nil
+	}
+	format, err := parseInterchange(c.QueryParam("format"), path)
+	if err != nil {
+		return validationError(err.Error())
+	}
+	shape, err := templateFor(c, path)
+	if err != nil {
+		return err
+	}
+	provider := providerFor(path)
+	// the cache is keyed by text alone, so it only holds prose-app's tokens
+	segmented := c.Get(segmentedContextKey) != nil
+	if segmented {
+		provider = "segmenter"
+	}
+	cached := analysisCache != nil && cachedRoutes[path] && !segmented && c.Get(cacheContextKey) != nil
+	_, calibrated := calibrations[provider]
+	review := reviewed(path)
+	local := localEntities(c.Request().Header.Get(tenantHeader), path)
+	if isPassthrough(path) && !hasTransforms(path) && !meta && paged == nil && filter == nil && enrich == nil && !calibrated && !review && !local && !cached && format == "" && shape == nil {
+		return proxyPass(c, req.URL)
+	}
+	if len(requestTransformsFor(path)) > 0 && !textTransformed(req.Context()) {
+		body, err := transformRequestBody(path, req.Body)
+		if err != nil {
+			return validationError(err.Error())
+		}
+		req.Body = ioutil.NopCloser(bytes.NewReader(body))
+		req.ContentLength = int64(len(body))
+	}
+
+	var text string
+	if meta || cached || review || local || enrich != nil || format != "" || shape != nil {
+		if text, err = requestText(req); err != nil {
+			return validationError(err.Error())
+		}
+	}
+	if cached {
+		etag := analysisETag(c, path, version, text)
+		c.Response().Header().Set(headerETag, etag)
+		if notModified(c.Request(), etag) {
+			return c.NoContent(http.StatusNotModified)
+		}
+		if entry, ok := analysisCache.get(c.Request().Context(), cacheKey(path, text)); ok {
+			upstream := newUpstreamMeta("", provider, time.Now(), entry.status)
+			upstream.Cached = true
+			return respondResult(c, path, version, text, filter, enrich, paged, meta, entry.body, upstream)
+		}
+	}
+
 	req.Header.Set("X-API-Key", c.Request().Header.Get("X-API-Key"))
-	client := &http.Client{}
-	resp, err := client.Do(req)
+	setDeadlineHeader(req)
+	setBaggageHeader(req)
+	setForwardedHeaders(req)
+	start := time.Now()
+	resp, err := upstreamClient.Do(req)
 	if resp != nil {
 		defer func(Body io.ReadCloser) {
 			err := Body.Close()
@@ -130,50 +198,135 @@ func serviceResponse(err error, req *http.Request, c echo.Context) error {
 		}(resp.Body)
 	}
 	if err != nil {
-		return echo.NewHTTPError(http.StatusInternalServerError, err)
+		return upstreamError(err)
+	}
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return upstreamError(newUpstreamStatusError(resp))
+	}
+	if region := resp.Header.Get(headerServedRegion); region != "" {
+		c.Response().Header().Set(headerServedRegion, region)
+	}
+
+	if len(routeTransforms(responseTransformRoutes, path)) > 0 || meta || paged != nil || filter != nil || enrich != nil || calibrated || review || local || cached || format != "" || shape != nil {
+		body, err := readTransformedResponse(path, resp.Body)
+		if err != nil {
+			return internalError(err)
+		}
+		if cached {
+			analysisCache.set(c.Request().Context(), cacheKey(path, text), body, resp.StatusCode)
+		}
+		upstream := newUpstreamMeta("", provider, start, resp.StatusCode)
+		upstream.Region = resp.Header.Get(headerServedRegion)
+		return respondResult(c, path, version, text, filter, enrich, paged, meta, body, upstream)
 	}
 
-	body, err := ioutil.ReadAll(resp.Body)
+	// stream the upstream response through rather than buffering it
+	return c.Stream(http.StatusOK, echo.MIMEApplicationJSONCharsetUTF8, resp.Body)
+}
+
+// respondResult writes an upstream result with the filters, and in the
+// schema, page, and envelope, a request asks for.
+func respondResult(c echo.Context, path, version, text string, filter *resultFilter, enrich *enrichment, paged *page, meta bool, body []byte, upstream *upstreamMeta) error {
+	checkAlerts(c.Request().Header, map[string]json.RawMessage{strings.TrimPrefix(path, "/"): body})
+	calibrated, calibration, err := calibrate(path, body)
 	if err != nil {
-		return echo.NewHTTPError(http.StatusInternalServerError, err)
+		return internalError(err)
+	}
+	body = calibrated
+	queueReview(c, path, text, body)
+	if filter != nil {
+		if body, err = filter.apply(path, body); err != nil {
+			return internalError(err)
+		}
+	}
+	if version == schemaV2 {
+		if body, err = normalizeResult(path, text, body); err != nil {
+			return internalError(err)
+		}
+	}
+	if body, err = mergeLocalEntities(c.Request().Header.Get(tenantHeader), path, version, text, body); err != nil {
+		return internalError(err)
+	}
+	if enrich != nil {
+		if body, err = enrich.apply(c.Request().Context(), path, text, body); err != nil {
+			return internalError(err)
+		}
+	}
+	if paged != nil {
+		if body, err = paged.apply(c, body); err != nil {
+			return internalError(err)
+		}
+	}
+	if format := c.QueryParam("format"); format == formatNIF || format == formatXMI {
+		return respondInterchange(c, format, path, text, body)
+	}
+	if shape, _ := templateFor(c, path); shape != nil {
+		var result interface{}
+		if err := json.Unmarshal(body, &result); err != nil {
+			return internalError(err)
+		}
+		return respondTemplate(c, http.StatusOK, shape, result)
+	}
+	if meta {
+		upstream.Transforms = requestTransformsFor(path)
+		upstream.Calibration = calibration
+		length := utf8.RuneCountInString(text)
+		return c.JSON(http.StatusOK, metaEnvelope{body, &responseMeta{length, []upstreamMeta{*upstream}}})
 	}
 
 	return c.JSONBlob(http.StatusOK, body)
 }
 
 func run() error {
+	if err := loadSecrets(); err != nil {
+		return err
+	}
+	if err := loadPlugins(); err != nil {
+		return err
+	}
+	if err := loadIPAccess(); err != nil {
+		return err
+	}
+	if err := loadResidency(); err != nil {
+		return err
+	}
+	if err := loadAnonymize(); err != nil {
+		return err
+	}
+	startHealthMonitor()
+	startProber()
+	startFeedPoller()
+	startS3Ingest()
+
+	e.HTTPErrorHandler = httpErrorHandler
+
 	// Middleware
-	e.Use(middleware.Logger())
-	e.Use(middleware.Recover())
-
-	e.Use(middleware.KeyAuthWithConfig(middleware.KeyAuthConfig{
-		KeyLookup: "header:X-API-Key",
-		Skipper: func(c echo.Context) bool {
-			if strings.HasPrefix(c.Request().RequestURI, "/health") {
-				return true
-			}
-			return false
-		},
-		Validator: func(key string, c echo.Context) (bool, error) {
-			e.Logger.Debugf("API_KEY: %v", apiKey)
-			return key == apiKey, nil
-		},
-	}))
+	global, err := buildMiddleware(globalMiddleware)
+	if err != nil {
+		return err
+	}
+	e.Use(global...)
+
+	chains := map[string][]echo.MiddlewareFunc{}
+	for group, names := range groupMiddleware {
+		if chains[group], err = buildMiddleware(names); err != nil {
+			return err
+		}
+	}
 
 	// Routes
-	e.GET("/health", getHealth)
-	e.GET("/health/:app", getHealthUpstream)
-	e.GET("/error", getError)
-	e.GET("/routes", getRoutes)
-	e.POST("/keywords", getKeywords)
-	e.POST("/tokens", getTokens)
-	e.POST("/entities", getEntities)
-	e.POST("/sentences", getSentences)
-	e.POST("/language", getLanguage)
-	e.POST("/record", putDynamo)
+	for _, m := range registry() {
+		for _, r := range m.paths() {
+			e.Add(r.method, r.path, r.handler, routeMiddleware(r, chains)...)
+		}
+	}
 
 	// Start server
-	return e.Start(serverPort)
+	if envBool(lambdaMode) {
+		lambda.Start(newLambdaHandler(e))
+		return nil
+	}
+	return startServers()
 }
 
 func init() {
@@ -182,6 +335,16 @@ func init() {
 }
 
 func main() {
+	if len(os.Args) > 1 && os.Args[1] == "replay" {
+		if err := runReplay(os.Args[2:], os.Stdout); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		return
+	}
+	if err := parseFlags(os.Args[1:]); err != nil {
+		os.Exit(2)
+	}
 	if err := run(); err != nil {
 		e.Logger.Fatal(err)
 		os.Exit(1)
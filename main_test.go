@@ -7,8 +7,10 @@ import (
 	"net/http"
 	"net/http/httptest"
 	"os"
-	"sort"
+	"strings"
 	"testing"
+
+	"github.com/garystafford/nlp-client/mockupstream"
 )
 
 func TestGetEnvSet(t *testing.T) {
@@ -84,66 +86,26 @@ func TestGetHealth(t *testing.T) {
 func TestGetRoutes(t *testing.T) {
 	req := httptest.NewRequest(http.MethodGet, "/routes", nil)
 	w := httptest.NewRecorder()
-	e.GET("/health", getHealth)
-	e.GET("/health/:app", getHealthUpstream)
-	e.GET("/error", getError)
-	e.GET("/routes", getRoutes)
-	e.POST("/keywords", getKeywords)
-	e.POST("/tokens", getTokens)
-	e.POST("/entities", getEntities)
-	e.POST("/sentences", getSentences)
-	e.POST("/language", getLanguage)
-	e.POST("/record", putDynamo)
 	c := e.NewContext(req, w)
-	err := getRoutes(c)
-	if err != nil {
-		t.Error(err)
-	}
-	res := w.Result()
-	defer func(Body io.ReadCloser) {
-		err := Body.Close()
-		if err != nil {
-			t.Error(err)
-		}
-	}(res.Body)
-	data, err := io.ReadAll(res.Body)
-	if err != nil {
-		t.Error(err)
-	}
-
-	type Route struct {
-		Method string `json:"method"`
-		Path   string `json:"path"`
-		Name   string `json:"name"`
-	}
-
-	prefix := "github.com/garystafford/nlp-client"
-	expectedStatus := []Route{
-		{"GET", "/health", prefix + ".getHealth"},
-		{"GET", "/health/:app", prefix + ".getHealthUpstream"},
-		{"GET", "/error", prefix + ".getError"},
-		{"GET", "/routes", prefix + ".getRoutes"},
-		{"POST", "/keywords", prefix + ".getKeywords"},
-		{"POST", "/tokens", prefix + ".getTokens"},
-		{"POST", "/entities", prefix + ".getEntities"},
-		{"POST", "/sentences", prefix + ".getSentences"},
-		{"POST", "/language", prefix + ".getLanguage"},
-		{"POST", "/record", prefix + ".putDynamo"},
-	}
-	var responseBody []Route
-
-	err = json.Unmarshal(data, &responseBody)
-	if err != nil {
-		t.Error(err)
-	}
-
-	// sort both arrays of Route structs so they are in identical order
-	sort.Slice(expectedStatus, func(i, j int) bool { return expectedStatus[i].Path < expectedStatus[j].Path })
-	sort.Slice(responseBody, func(i, j int) bool { return responseBody[i].Path < responseBody[j].Path })
 
 	if assert.NoError(t, getRoutes(c)) {
 		assert.Equal(t, http.StatusOK, w.Code)
-		assert.Equal(t, &expectedStatus, &responseBody)
+		var routes []routeInfo
+		if assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &routes)) {
+			byPath := map[string]routeInfo{}
+			for _, r := range routes {
+				byPath[r.Method+" "+r.Path] = r
+			}
+			prefix := "github.com/garystafford/nlp-client"
+			assert.Equal(t, routeInfo{Method: "GET", Path: "/health", Name: prefix + ".getHealth"}, byPath["GET /health"])
+			assert.Equal(t, routeInfo{
+				Method: "POST", Path: "/v1/keywords", Name: prefix + ".getKeywords", Version: "v1",
+				Aliases: []routeAlias{{Path: "/keywords"}},
+			}, byPath["POST /v1/keywords"])
+			assert.Equal(t, routeInfo{Method: "POST", Path: "/v2/keywords", Name: prefix + ".getKeywords", Version: "v2"}, byPath["POST /v2/keywords"])
+			assert.NotContains(t, byPath, "POST /keywords")
+			assert.NotContains(t, byPath, "POST /v2/record")
+		}
 	}
 }
 
@@ -159,14 +121,86 @@ func TestGetHealthUpstreamRake(t *testing.T) {
 	}
 }
 
+func TestGetHealthUpstream(t *testing.T) {
+	rake := mockupstream.NewRake()
+	defer rake.Close()
+	defer func(url string) { urlRake = url }(urlRake)
+	urlRake = rake.URL
+
+	req := httptest.NewRequest(http.MethodGet, "/health/rake", nil)
+	w := httptest.NewRecorder()
+	c := e.NewContext(req, w)
+	c.SetParamNames("app")
+	c.SetParamValues("rake")
+
+	if assert.NoError(t, getHealthUpstream(c)) {
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.JSONEq(t, `{"status":"Up"}`, w.Body.String())
+	}
+}
+
 func TestGetKeywords(t *testing.T) {
-	req := httptest.NewRequest(http.MethodGet, "/keywords", nil)
+	rake := mockupstream.NewRake()
+	defer rake.Close()
+	defer func(url string) { urlRake = url }(urlRake)
+	urlRake = rake.URL
+
+	req := httptest.NewRequest(http.MethodPost, "/keywords", strings.NewReader(`{"text": "Marie Curie"}`))
+	w := httptest.NewRecorder()
+	c := e.NewContext(req, w)
+
+	if assert.NoError(t, getKeywords(c)) {
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.JSONEq(t, mockupstream.RakeFixtures["/keywords"].Body, w.Body.String())
+		if requests := rake.Requests(); assert.Len(t, requests, 1) {
+			assert.Equal(t, `{"text": "Marie Curie"}`, requests[0].Body)
+		}
+	}
+}
+
+func TestGetKeywordsUpstreamError(t *testing.T) {
+	rake := mockupstream.NewRake()
+	defer rake.Close()
+	defer func(url string) { urlRake = url }(urlRake)
+	urlRake = rake.URL
+	rake.SetFixture("/keywords", mockupstream.Fixture{Status: http.StatusInternalServerError})
+
+	req := httptest.NewRequest(http.MethodPost, "/keywords", nil)
 	w := httptest.NewRecorder()
 	c := e.NewContext(req, w)
-	res := w.Result()
-	res.Body.Close()
 
-	expected := `code=500, message=Post "http://localhost:8081/keywords": dial tcp [::1]:8081: connect: connection refused`
+	expected := `UPSTREAM_ERROR: upstream service returned an error`
 	if assert.EqualError(t, getKeywords(c), expected) {
 	}
 }
+
+func TestGetKeywordsUpstreamUnavailable(t *testing.T) {
+	rake := mockupstream.NewRake()
+	defer func(url string) { urlRake = url }(urlRake)
+	urlRake = rake.URL
+	// nothing is listening once the mock is closed
+	rake.Close()
+
+	req := httptest.NewRequest(http.MethodPost, "/keywords", nil)
+	w := httptest.NewRecorder()
+	c := e.NewContext(req, w)
+
+	expected := `UPSTREAM_UNAVAILABLE: upstream service unavailable`
+	if assert.EqualError(t, getKeywords(c), expected) {
+	}
+}
+
+func TestGetLanguage(t *testing.T) {
+	lang := mockupstream.NewLang()
+	defer lang.Close()
+	defer func(url string) { urlLang = url }(urlLang)
+	urlLang = lang.URL
+
+	req := httptest.NewRequest(http.MethodPost, "/language", strings.NewReader(`{"text": "bonjour"}`))
+	w := httptest.NewRecorder()
+	c := e.NewContext(req, w)
+
+	if assert.NoError(t, getLanguage(c)) {
+		assert.JSONEq(t, mockupstream.LangFixtures["/language"].Body, w.Body.String())
+	}
+}
@@ -0,0 +1,118 @@
+// author: Gary A. Stafford
+// site: https://programmaticponderings.com
+// license: MIT License
+// purpose: NLP microservices: nlp-client memory budget
+// modified: 2026-10-14
+
+package main
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+	"sync"
+
+	"github.com/labstack/echo/v4"
+)
+
+var (
+	memoryBudgetBytes = getEnv("MEMORY_BUDGET_BYTES", "268435456") // 256 MiB
+	errMemoryBudget   = errors.New("memory budget exhausted")
+)
+
+// memoryBudget caps the number of body bytes all in-flight requests may hold at once.
+type memoryBudget struct {
+	mu    sync.Mutex
+	limit int64
+	inUse int64
+	peak  int64
+}
+
+func newMemoryBudget(limit int64) *memoryBudget {
+	return &memoryBudget{limit: limit}
+}
+
+func (b *memoryBudget) acquire(n int64) bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.limit > 0 && b.inUse+n > b.limit {
+		metrics.Count("memory_rejected_total", 1, nil)
+		return false
+	}
+	b.inUse += n
+	if b.inUse > b.peak {
+		b.peak = b.inUse
+		metrics.Gauge("memory_peak_bytes", float64(b.peak), nil)
+	}
+	metrics.Gauge("memory_in_use_bytes", float64(b.inUse), nil)
+
+	return true
+}
+
+func (b *memoryBudget) release(n int64) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.inUse -= n
+	metrics.Gauge("memory_in_use_bytes", float64(b.inUse), nil)
+}
+
+// budgetedReader reserves budget as a body of unknown length is read.
+type budgetedReader struct {
+	io.ReadCloser
+	budget   *memoryBudget
+	reserved int64
+}
+
+func (r *budgetedReader) Read(p []byte) (int, error) {
+	n, err := r.ReadCloser.Read(p)
+	if n > 0 {
+		if !r.budget.acquire(int64(n)) {
+			return 0, errMemoryBudget
+		}
+		r.reserved += int64(n)
+	}
+
+	return n, err
+}
+
+func (r *budgetedReader) release() {
+	r.budget.release(r.reserved)
+	r.reserved = 0
+}
+
+// memoryLimit reserves each request body against the budget for the life of
+// the request, rejecting requests with 503 once the budget is exhausted.
+func memoryLimit(budget *memoryBudget) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			req := c.Request()
+			if req.ContentLength > 0 {
+				if !budget.acquire(req.ContentLength) {
+					return newAPIError(http.StatusServiceUnavailable, codeOverloaded, errMemoryBudget.Error())
+				}
+				defer budget.release(req.ContentLength)
+
+				return next(c)
+			}
+
+			body := &budgetedReader{ReadCloser: req.Body, budget: budget}
+			defer body.release()
+			req.Body = body
+
+			return next(c)
+		}
+	}
+}
+
+func parseMemoryBudget(value string) int64 {
+	limit, err := strconv.ParseInt(value, 10, 64)
+	if err != nil {
+		e.Logger.Warnf("invalid MEMORY_BUDGET_BYTES %q, budget disabled", value)
+		return 0
+	}
+
+	return limit
+}
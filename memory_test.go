@@ -0,0 +1,51 @@
+package main
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMemoryBudget(t *testing.T) {
+	budget := newMemoryBudget(10)
+
+	assert.True(t, budget.acquire(6))
+	assert.False(t, budget.acquire(5))
+	budget.release(6)
+	assert.True(t, budget.acquire(10))
+	assert.Equal(t, int64(10), budget.peak)
+}
+
+func TestMemoryLimitRejects(t *testing.T) {
+	budget := newMemoryBudget(4)
+	req := httptest.NewRequest(http.MethodPost, "/keywords", strings.NewReader("too large"))
+	w := httptest.NewRecorder()
+	c := e.NewContext(req, w)
+
+	err := memoryLimit(budget)(func(c echo.Context) error {
+		return c.NoContent(http.StatusOK)
+	})(c)
+	if assert.Error(t, err) {
+		assert.Equal(t, http.StatusServiceUnavailable, err.(*apiError).Status)
+	}
+}
+
+func TestMemoryLimitUnknownLength(t *testing.T) {
+	budget := newMemoryBudget(4)
+	req := httptest.NewRequest(http.MethodPost, "/keywords", strings.NewReader("too large"))
+	req.ContentLength = -1
+	w := httptest.NewRecorder()
+	c := e.NewContext(req, w)
+
+	err := memoryLimit(budget)(func(c echo.Context) error {
+		_, err := ioutil.ReadAll(c.Request().Body)
+		return err
+	})(c)
+	assert.Equal(t, errMemoryBudget, err)
+	assert.Equal(t, int64(0), budget.inUse)
+}
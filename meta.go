@@ -0,0 +1,134 @@
+// author: Gary A. Stafford
+// site: https://programmaticponderings.com
+// license: MIT License
+// purpose: NLP microservices: nlp-client response metadata
+// modified: 2026-10-14
+
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"time"
+	"unicode/utf8"
+
+	"github.com/labstack/echo/v4"
+)
+
+const headerIncludeMeta = "X-Include-Meta"
+
+// upstreamMeta describes one upstream call made for a response.
+type upstreamMeta struct {
+	Analysis  string  `json:"analysis,omitempty"`
+	Provider  string  `json:"provider"`
+	LatencyMS float64 `json:"latency_ms"`
+	Status    int     `json:"status,omitempty"`
+	Cached    bool    `json:"cached"`
+	// the region that served the call, for upstreams with failover regions
+	Region string `json:"region,omitempty"`
+	// the request transforms applied to the text before it was sent
+	Transforms []string `json:"transforms,omitempty"`
+	// the confidence calibration applied to the result
+	Calibration string `json:"calibration,omitempty"`
+}
+
+// responseMeta is the "_meta" section returned to callers sending X-Include-Meta: true.
+type responseMeta struct {
+	TextLength int            `json:"text_length"`
+	Upstreams  []upstreamMeta `json:"upstreams"`
+}
+
+// metaEnvelope wraps the upstream response of a single-analysis route.
+type metaEnvelope struct {
+	Result json.RawMessage `json:"result"`
+	Meta   *responseMeta   `json:"_meta"`
+}
+
+func wantsMeta(c echo.Context) bool {
+	include, _ := strconv.ParseBool(c.Request().Header.Get(headerIncludeMeta))
+	return include
+}
+
+// providerFor returns the upstream service answering a route or analysis.
+func providerFor(name string) string {
+	switch name {
+	case "keywords", "/keywords":
+		return "rake-app"
+	case "tokens", "entities", "sentences", "/tokens", "/entities", "/sentences":
+		return "prose-app"
+	case "language", "/language":
+		return "lang-app"
+	case "/record":
+		return "dynamo-app"
+	case "/moderate":
+		return "moderation"
+	case "/spellcheck":
+		return "spellcheck"
+	}
+
+	return ""
+}
+
+func newUpstreamMeta(analysis, provider string, start time.Time, status int) *upstreamMeta {
+	return &upstreamMeta{
+		Analysis:  analysis,
+		Provider:  provider,
+		LatencyMS: float64(time.Since(start)) / float64(time.Millisecond),
+		Status:    status,
+	}
+}
+
+// upstreamStatus returns the status an upstream answered a failed call with, if it answered at all.
+func upstreamStatus(err error) int {
+	var statusErr *upstreamStatusError
+	if errors.As(err, &statusErr) {
+		return statusErr.status
+	}
+
+	return 0
+}
+
+// textLength counts the characters of the text of a request body, leaving the body to be read again.
+func textLength(req *http.Request) (int, error) {
+	text, err := requestText(req)
+	return utf8.RuneCountInString(text), err
+}
+
+// requestText returns the text of a request body, leaving the body to be read again.
+func requestText(req *http.Request) (string, error) {
+	if req.Body == nil || req.Body == http.NoBody {
+		return "", nil
+	}
+	body, err := ioutil.ReadAll(req.Body)
+	if err != nil {
+		return "", err
+	}
+	req.Body = ioutil.NopCloser(bytes.NewReader(body))
+
+	var payload struct {
+		Text string `json:"text"`
+	}
+	if len(bytes.TrimSpace(body)) > 0 {
+		if err := json.Unmarshal(body, &payload); err != nil {
+			return "", err
+		}
+	}
+
+	return payload.Text, nil
+}
+
+// analysesMeta collects the upstream calls made for the analyses of one text, in request order.
+func analysesMeta(text string, analyses []string, results map[string]analysisResult) *responseMeta {
+	meta := &responseMeta{TextLength: utf8.RuneCountInString(text), Upstreams: []upstreamMeta{}}
+	for _, name := range analyses {
+		if upstream := results[name].meta; upstream != nil {
+			meta.Upstreams = append(meta.Upstreams, *upstream)
+		}
+	}
+
+	return meta
+}
@@ -0,0 +1,119 @@
+package main
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTextLength(t *testing.T) {
+	req := httptest.NewRequest(http.MethodPost, "/keywords", strings.NewReader(`{"text": "Marie Curie née"}`))
+	length, err := textLength(req)
+	if assert.NoError(t, err) {
+		assert.Equal(t, 15, length)
+	}
+	// the body is left to be forwarded
+	body, _ := ioutil.ReadAll(req.Body)
+	assert.Equal(t, `{"text": "Marie Curie née"}`, string(body))
+
+	_, err = textLength(httptest.NewRequest(http.MethodPost, "/keywords", strings.NewReader(`not json`)))
+	assert.Error(t, err)
+}
+
+func TestServiceResponseMeta(t *testing.T) {
+	upstream := newBatchUpstream(t)
+	defer upstream.Close()
+
+	req := httptest.NewRequest(http.MethodPost, "/keywords", strings.NewReader(`{"text": "curie"}`))
+	req.Header.Set(headerIncludeMeta, "true")
+	w := httptest.NewRecorder()
+	c := e.NewContext(req, w)
+	c.SetPath("/keywords")
+
+	out, err := http.NewRequest(http.MethodPost, upstream.URL, req.Body)
+	if assert.NoError(t, err) && assert.NoError(t, serviceResponse(nil, out, c)) {
+		var envelope struct {
+			Result []string     `json:"result"`
+			Meta   responseMeta `json:"_meta"`
+		}
+		if assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &envelope)) {
+			assert.Equal(t, []string{"CURIE"}, envelope.Result)
+			assert.Equal(t, 5, envelope.Meta.TextLength)
+			if assert.Len(t, envelope.Meta.Upstreams, 1) {
+				assert.Equal(t, "rake-app", envelope.Meta.Upstreams[0].Provider)
+				assert.Equal(t, http.StatusOK, envelope.Meta.Upstreams[0].Status)
+			}
+		}
+	}
+}
+
+func TestPostAnalyzeMeta(t *testing.T) {
+	upstream := newBatchUpstream(t)
+	defer upstream.Close()
+	defer func(rake, lang string) { urlRake, urlLang = rake, lang }(urlRake, urlLang)
+	urlRake = upstream.URL
+	urlLang = upstream.URL
+
+	req := httptest.NewRequest(http.MethodPost, "/analyze?analyses=keywords,language", strings.NewReader(`{"text": "curie"}`))
+	req.Header.Set(headerIncludeMeta, "1")
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	w := httptest.NewRecorder()
+
+	if assert.NoError(t, postAnalyze(e.NewContext(req, w))) {
+		var body struct {
+			Meta responseMeta `json:"_meta"`
+		}
+		if assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &body)) && assert.Len(t, body.Meta.Upstreams, 2) {
+			assert.Equal(t, "keywords", body.Meta.Upstreams[0].Analysis)
+			assert.Equal(t, "rake-app", body.Meta.Upstreams[0].Provider)
+			assert.Equal(t, "language", body.Meta.Upstreams[1].Analysis)
+			assert.Equal(t, "lang-app", body.Meta.Upstreams[1].Provider)
+		}
+	}
+}
+
+func TestPostAnalyzeWithoutMeta(t *testing.T) {
+	upstream := newBatchUpstream(t)
+	defer upstream.Close()
+	defer func(url string) { urlRake = url }(urlRake)
+	urlRake = upstream.URL
+
+	req := httptest.NewRequest(http.MethodPost, "/analyze?analyses=keywords", strings.NewReader(`{"text": "curie"}`))
+	w := httptest.NewRecorder()
+
+	if assert.NoError(t, postAnalyze(e.NewContext(req, w))) {
+		assert.NotContains(t, w.Body.String(), "_meta")
+	}
+}
+
+func TestPostAnalyzeMetaTransforms(t *testing.T) {
+	upstream := newBatchUpstream(t)
+	defer upstream.Close()
+	defer func(url, sanitize string) { urlRake, sanitizeTransforms = url, sanitize }(urlRake, sanitizeTransforms)
+	urlRake = upstream.URL
+	sanitizeTransforms = "decode_entities,collapse_whitespace"
+
+	req := httptest.NewRequest(http.MethodPost, "/analyze?analyses=keywords", strings.NewReader(`{"text": "curie  &amp;  joliot"}`))
+	req.Header.Set(headerIncludeMeta, "true")
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	w := httptest.NewRecorder()
+
+	if assert.NoError(t, postAnalyze(e.NewContext(req, w))) {
+		var body struct {
+			Analyses map[string]struct {
+				Result []string `json:"result"`
+			} `json:"analyses"`
+			Meta responseMeta `json:"_meta"`
+		}
+		if assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &body)) && assert.Len(t, body.Meta.Upstreams, 1) {
+			assert.Equal(t, []string{"CURIE & JOLIOT"}, body.Analyses["keywords"].Result)
+			assert.Equal(t, []string{"decode_entities", "collapse_whitespace"}, body.Meta.Upstreams[0].Transforms)
+		}
+	}
+}
@@ -0,0 +1,252 @@
+// author: Gary A. Stafford
+// site: https://programmaticponderings.com
+// license: MIT License
+// purpose: NLP microservices: nlp-client metrics
+// modified: 2026-10-14
+
+package main
+
+import (
+	"fmt"
+	"net"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+var (
+	// comma-separated: prometheus, statsd
+	metricsDrivers = getEnv("METRICS_DRIVERS", "prometheus")
+	statsdAddr     = getEnv("STATSD_ADDR", "127.0.0.1:8125")
+	statsdPrefix   = getEnv("STATSD_PREFIX", "nlp_client.")
+	metrics        = newMetrics(metricsDrivers)
+)
+
+// metricHelp describes every metric the client emits, for the drivers that publish help text.
+var metricHelp = map[string]string{
+	"memory_in_use_bytes":           "Bytes of request and response bodies currently reserved against the memory budget.",
+	"memory_peak_bytes":             "Highest number of bytes reserved against the memory budget since startup.",
+	"memory_rejected_total":         "Requests rejected because the memory budget was exhausted.",
+	"requests_total":                "Requests served, by route, method, and status.",
+	"deprecated_requests_total":     "Requests served by deprecated routes, by route.",
+	"request_duration_seconds":      "Time taken to serve requests, by route, method, and status.",
+	"slo_burn_rate":                 "Rate at which each route is spending its error budget, by objective and window.",
+	"redis_errors_total":            "Redis failures that switched the client to its local state.",
+	"alerts_matched_total":          "Analysis results that matched an alerting rule, by rule.",
+	"reviews_queued_total":          "Low-confidence results queued for review, by analysis.",
+	"reviews_dropped_total":         "Low-confidence results not queued because the review queue was full, by analysis.",
+	"feedback_received_total":       "Feedback entries received on analysis results, by analysis.",
+	"geocode_failures_total":        "Place entities left unresolved because the geocoder failed, by backend.",
+	"upstream_in_flight":            "Calls in flight to each upstream.",
+	"upstream_queue_depth":          "Calls waiting for each upstream.",
+	"upstream_queue_wait_seconds":   "Time calls waited for their upstream, by upstream.",
+	"upstream_queue_rejected_total": "Calls rejected because their upstream's queue was full, by upstream.",
+	"upstream_failed_over":          "Whether each upstream with failover regions is served from its secondary region.",
+	"probe_success":                 "Whether the latest synthetic probe of each analysis succeeded.",
+	"probe_duration_seconds":        "Time taken by the synthetic probes, by analysis.",
+}
+
+// metricsSink receives every counter, gauge, and histogram observation.
+type metricsSink interface {
+	Count(name string, delta float64, tags map[string]string)
+	Gauge(name string, value float64, tags map[string]string)
+	Observe(name string, value float64, tags map[string]string)
+}
+
+// multiSink fans each metric out to every configured driver.
+type multiSink []metricsSink
+
+func (m multiSink) Count(name string, delta float64, tags map[string]string) {
+	for _, sink := range m {
+		sink.Count(name, delta, tags)
+	}
+}
+
+func (m multiSink) Gauge(name string, value float64, tags map[string]string) {
+	for _, sink := range m {
+		sink.Gauge(name, value, tags)
+	}
+}
+
+func (m multiSink) Observe(name string, value float64, tags map[string]string) {
+	for _, sink := range m {
+		sink.Observe(name, value, tags)
+	}
+}
+
+func newMetrics(drivers string) multiSink {
+	var sinks multiSink
+	for _, driver := range strings.Split(drivers, ",") {
+		switch driver = strings.TrimSpace(driver); driver {
+		case "":
+		case "prometheus":
+			sinks = append(sinks, newPrometheusSink(prometheus.DefaultRegisterer))
+		case "statsd":
+			sink, err := newStatsdSink(statsdAddr, statsdPrefix)
+			if err != nil {
+				e.Logger.Errorf("statsd metrics disabled: %v", err)
+				continue
+			}
+			sinks = append(sinks, sink)
+		default:
+			e.Logger.Errorf("unknown metrics driver: %s", driver)
+		}
+	}
+
+	return sinks
+}
+
+func tagNames(tags map[string]string) []string {
+	names := make([]string, 0, len(tags))
+	for name := range tags {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	return names
+}
+
+// prometheusSink registers each metric on first use, labelled by its tag names.
+type prometheusSink struct {
+	mu         sync.Mutex
+	registerer prometheus.Registerer
+	counters   map[string]*prometheus.CounterVec
+	gauges     map[string]*prometheus.GaugeVec
+	histograms map[string]*prometheus.HistogramVec
+}
+
+func newPrometheusSink(registerer prometheus.Registerer) *prometheusSink {
+	return &prometheusSink{
+		registerer: registerer,
+		counters:   map[string]*prometheus.CounterVec{},
+		gauges:     map[string]*prometheus.GaugeVec{},
+		histograms: map[string]*prometheus.HistogramVec{},
+	}
+}
+
+func (p *prometheusSink) Count(name string, delta float64, tags map[string]string) {
+	p.mu.Lock()
+	vec, ok := p.counters[name]
+	if !ok {
+		vec = prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "nlp_client_" + name, Help: metricHelp[name],
+		}, tagNames(tags))
+		p.registerer.MustRegister(vec)
+		p.counters[name] = vec
+	}
+	p.mu.Unlock()
+
+	vec.With(tags).Add(delta)
+}
+
+func (p *prometheusSink) Gauge(name string, value float64, tags map[string]string) {
+	p.mu.Lock()
+	vec, ok := p.gauges[name]
+	if !ok {
+		vec = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "nlp_client_" + name, Help: metricHelp[name],
+		}, tagNames(tags))
+		p.registerer.MustRegister(vec)
+		p.gauges[name] = vec
+	}
+	p.mu.Unlock()
+
+	vec.With(tags).Set(value)
+}
+
+func (p *prometheusSink) Observe(name string, value float64, tags map[string]string) {
+	p.mu.Lock()
+	vec, ok := p.histograms[name]
+	if !ok {
+		vec = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name: "nlp_client_" + name, Help: metricHelp[name], Buckets: prometheus.DefBuckets,
+		}, tagNames(tags))
+		p.registerer.MustRegister(vec)
+		p.histograms[name] = vec
+	}
+	p.mu.Unlock()
+
+	vec.With(tags).Observe(value)
+}
+
+// statsdSink sends each metric as a DogStatsD datagram, which plain statsd
+// servers without tag support still accept as the untagged metric.
+type statsdSink struct {
+	conn   net.Conn
+	prefix string
+}
+
+func newStatsdSink(addr, prefix string) (*statsdSink, error) {
+	conn, err := net.Dial("udp", addr)
+	if err != nil {
+		return nil, err
+	}
+
+	return &statsdSink{conn: conn, prefix: prefix}, nil
+}
+
+func (s *statsdSink) Count(name string, delta float64, tags map[string]string) {
+	s.send(name, delta, "c", tags)
+}
+
+func (s *statsdSink) Gauge(name string, value float64, tags map[string]string) {
+	s.send(name, value, "g", tags)
+}
+
+func (s *statsdSink) Observe(name string, value float64, tags map[string]string) {
+	s.send(name, value, "h", tags)
+}
+
+func (s *statsdSink) send(name string, value float64, kind string, tags map[string]string) {
+	line := fmt.Sprintf("%s%s:%s|%s", s.prefix, name, strconv.FormatFloat(value, 'f', -1, 64), kind)
+	if len(tags) > 0 {
+		pairs := make([]string, 0, len(tags))
+		for _, tag := range tagNames(tags) {
+			pairs = append(pairs, tag+":"+tags[tag])
+		}
+		line += "|#" + strings.Join(pairs, ",")
+	}
+	// metrics are best effort; a missing agent must never fail a request
+	_, _ = s.conn.Write([]byte(line))
+}
+
+// requestMetrics counts and times every request, by route, method, and status.
+func requestMetrics() echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			start := time.Now()
+			err := next(c)
+
+			tags := map[string]string{
+				"route":  c.Path(),
+				"method": c.Request().Method,
+				"status": strconv.Itoa(responseStatus(c, err)),
+			}
+			metrics.Count("requests_total", 1, tags)
+			metrics.Observe("request_duration_seconds", time.Since(start).Seconds(), tags)
+
+			return err
+		}
+	}
+}
+
+// responseStatus returns the status a request is answered with, including
+// errors the error handler has yet to render.
+func responseStatus(c echo.Context, err error) int {
+	if err != nil && !c.Response().Committed {
+		return toAPIError(err).Status
+	}
+
+	return c.Response().Status
+}
+
+func getMetrics(c echo.Context) error {
+	promhttp.Handler().ServeHTTP(c.Response(), c.Request())
+	return nil
+}
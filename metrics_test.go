@@ -0,0 +1,82 @@
+package main
+
+import (
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPrometheusSink(t *testing.T) {
+	reg := prometheus.NewRegistry()
+	sink := newPrometheusSink(reg)
+
+	sink.Count("requests_total", 1, map[string]string{"route": "/keywords"})
+	sink.Count("requests_total", 2, map[string]string{"route": "/keywords"})
+	sink.Gauge("memory_in_use_bytes", 42, nil)
+	sink.Observe("request_duration_seconds", 0.2, map[string]string{"route": "/keywords"})
+
+	assert.Equal(t, 3.0, testutil.ToFloat64(sink.counters["requests_total"].WithLabelValues("/keywords")))
+	assert.Equal(t, 42.0, testutil.ToFloat64(sink.gauges["memory_in_use_bytes"].WithLabelValues()))
+	count, err := testutil.GatherAndCount(reg, "nlp_client_request_duration_seconds")
+	if assert.NoError(t, err) {
+		assert.Equal(t, 1, count)
+	}
+}
+
+func TestStatsdSink(t *testing.T) {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer conn.Close()
+
+	sink, err := newStatsdSink(conn.LocalAddr().String(), "nlp_client.")
+	if !assert.NoError(t, err) {
+		return
+	}
+	read := func() string {
+		buf := make([]byte, 512)
+		_ = conn.SetReadDeadline(time.Now().Add(time.Second))
+		n, _, _ := conn.ReadFrom(buf)
+		return string(buf[:n])
+	}
+
+	sink.Count("requests_total", 1, map[string]string{"route": "/keywords", "method": "POST"})
+	assert.Equal(t, "nlp_client.requests_total:1|c|#method:POST,route:/keywords", read())
+	sink.Gauge("memory_in_use_bytes", 1024, nil)
+	assert.Equal(t, "nlp_client.memory_in_use_bytes:1024|g", read())
+	sink.Observe("request_duration_seconds", 0.125, nil)
+	assert.Equal(t, "nlp_client.request_duration_seconds:0.125|h", read())
+}
+
+type recordingSink struct {
+	counts map[string]map[string]string
+}
+
+func (r *recordingSink) Count(name string, delta float64, tags map[string]string) {
+	r.counts[name] = tags
+}
+func (r *recordingSink) Gauge(name string, value float64, tags map[string]string)   {}
+func (r *recordingSink) Observe(name string, value float64, tags map[string]string) {}
+
+func TestRequestMetrics(t *testing.T) {
+	defer func(m multiSink) { metrics = m }(metrics)
+	sink := &recordingSink{counts: map[string]map[string]string{}}
+	metrics = multiSink{sink}
+
+	handler := requestMetrics()(func(c echo.Context) error {
+		return validationError("bad")
+	})
+	c := e.NewContext(httptest.NewRequest(http.MethodPost, "/keywords", nil), httptest.NewRecorder())
+	c.SetPath("/keywords")
+
+	assert.Error(t, handler(c))
+	assert.Equal(t, map[string]string{"route": "/keywords", "method": "POST", "status": "400"}, sink.counts["requests_total"])
+}
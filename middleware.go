@@ -0,0 +1,92 @@
+// author: Gary A. Stafford
+// site: https://programmaticponderings.com
+// license: MIT License
+// purpose: NLP microservices: nlp-client middleware chains
+// modified: 2026-10-14
+
+package main
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/labstack/echo/v4"
+	"github.com/labstack/echo/v4/middleware"
+)
+
+const (
+	groupPublic = "public"
+	groupAPI    = "api"
+	groupAdmin  = "admin"
+)
+
+var (
+	// ordered, comma-separated middleware names; MIDDLEWARE wraps every request,
+	// including unmatched routes, and each group's chain runs after it
+	globalMiddleware = getEnv("MIDDLEWARE", "request_id,logger,access_log,metrics,encoding,recover,ip_access")
+	groupMiddleware  = map[string]string{
+		groupPublic: getEnv("MIDDLEWARE_PUBLIC", ""),
+		groupAPI:    getEnv("MIDDLEWARE_API", "slo,limits,deadline,baggage,headers,memory_limit,auth,quota,cache,body_log,capture"),
+		groupAdmin:  getEnv("MIDDLEWARE_ADMIN", "admin_auth"),
+	}
+
+	budgetOnce   sync.Once
+	sharedBudget *memoryBudget
+)
+
+// middlewares maps configurable middleware names to their constructors.
+var middlewares = map[string]func() echo.MiddlewareFunc{
+	"request_id": middleware.RequestID,
+	"logger":     middleware.Logger,
+	"access_log": accessLogMiddleware,
+	"metrics":    requestMetrics,
+	"capture":    captureMiddleware,
+	"slo":        sloMiddleware,
+	"recover":    middleware.Recover,
+	"ip_access":  ipAccessControl,
+	"encoding":   responseEncoding,
+	"memory_limit": func() echo.MiddlewareFunc {
+		// every chain reserves against the same, global budget
+		budgetOnce.Do(func() {
+			sharedBudget = newMemoryBudget(parseMemoryBudget(memoryBudgetBytes))
+		})
+		return memoryLimit(sharedBudget)
+	},
+	"limits":     routeLimitsMiddleware,
+	"deadline":   requestDeadline,
+	"baggage":    extractBaggage,
+	"headers":    forwardHeaders,
+	"auth":       apiKeyAuth,
+	"quota":      quotaMiddleware,
+	"cache":      cacheMiddleware,
+	"admin_auth": adminAuth,
+	"body_log":   bodyLog,
+}
+
+func apiKeyAuth() echo.MiddlewareFunc {
+	return middleware.KeyAuthWithConfig(middleware.KeyAuthConfig{
+		KeyLookup: "header:X-API-Key",
+		Validator: func(key string, c echo.Context) (bool, error) {
+			return apiKeys.Matches(key), nil
+		},
+	})
+}
+
+// buildMiddleware returns the chain for a comma-separated list of middleware names, in order.
+func buildMiddleware(names string) ([]echo.MiddlewareFunc, error) {
+	var chain []echo.MiddlewareFunc
+	for _, name := range strings.Split(names, ",") {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+		newMiddleware, ok := middlewares[name]
+		if !ok {
+			return nil, fmt.Errorf("unknown middleware: %s", name)
+		}
+		chain = append(chain, newMiddleware())
+	}
+
+	return chain, nil
+}
@@ -0,0 +1,56 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBuildMiddleware(t *testing.T) {
+	chain, err := buildMiddleware("logger, recover,auth")
+	if assert.NoError(t, err) {
+		assert.Len(t, chain, 3)
+	}
+
+	_, err = buildMiddleware("recover,tracing")
+	assert.EqualError(t, err, "unknown middleware: tracing")
+
+	chain, err = buildMiddleware("")
+	if assert.NoError(t, err) {
+		assert.Empty(t, chain)
+	}
+}
+
+func TestGroupMiddlewareAuth(t *testing.T) {
+	defer func(keys string) { apiKeys.Set(keys) }(apiKeys.Get())
+	apiKeys.Set("secret")
+
+	app := echo.New()
+	auth, err := buildMiddleware("auth")
+	if !assert.NoError(t, err) {
+		return
+	}
+	app.Add(http.MethodGet, "/health", getHealth)
+	app.Add(http.MethodGet, "/error", getError, auth...)
+
+	for _, tc := range []struct {
+		path, key string
+		code      int
+	}{
+		{"/health", "", http.StatusOK},
+		{"/error", "", http.StatusBadRequest},
+		{"/error", "wrong", http.StatusUnauthorized},
+		{"/error", "secret", http.StatusInternalServerError},
+	} {
+		req := httptest.NewRequest(http.MethodGet, tc.path, nil)
+		if tc.key != "" {
+			req.Header.Set("X-API-Key", tc.key)
+		}
+		w := httptest.NewRecorder()
+		app.ServeHTTP(w, req)
+		assert.Equal(t, tc.code, w.Code, tc.path+" "+tc.key)
+	}
+}
@@ -0,0 +1,123 @@
+// author: Gary A. Stafford
+// site: https://programmaticponderings.com
+// license: MIT License
+// purpose: NLP microservices: mock rake, prose, lang, and dynamo upstreams for tests
+// modified: 2026-10-14
+
+// Package mockupstream emulates the HTTP APIs of the rake-app, prose-app,
+// lang-app, and dynamo-app upstreams with configurable fixtures, so the
+// client can be tested without the real services.
+package mockupstream
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+)
+
+// Fixture is the canned response served for one path.
+type Fixture struct {
+	Status int
+	Body   string
+}
+
+// Request is a request received by a mock upstream.
+type Request struct {
+	Method string
+	Path   string
+	Header http.Header
+	Body   string
+}
+
+// Default fixtures for each upstream, shaped like the real services' responses.
+var (
+	RakeFixtures = map[string]Fixture{
+		"/health":   {http.StatusOK, `{"status":"Up"}`},
+		"/keywords": {http.StatusOK, `[{"candidate":"nobel prize","score":4},{"candidate":"marie curie","score":4}]`},
+	}
+	ProseFixtures = map[string]Fixture{
+		"/health":    {http.StatusOK, `{"status":"Up"}`},
+		"/tokens":    {http.StatusOK, `["The","Nobel","Prize","is","regarded"]`},
+		"/entities":  {http.StatusOK, `[{"text":"Marie Curie","label":"PERSON"}]`},
+		"/sentences": {http.StatusOK, `["The Nobel Prize is regarded as the most prestigious award in the World."]`},
+	}
+	LangFixtures = map[string]Fixture{
+		"/health":   {http.StatusOK, `{"status":"Up"}`},
+		"/language": {http.StatusOK, `{"language":"en","probability":0.99}`},
+	}
+	DynamoFixtures = map[string]Fixture{
+		"/health": {http.StatusOK, `{"status":"Up"}`},
+		"/record": {http.StatusOK, `{"status":"ok"}`},
+	}
+)
+
+// Server is a running mock upstream. Paths without a fixture answer 404.
+type Server struct {
+	*httptest.Server
+	// APIKey, when set, must be sent as X-API-Key or the request is refused with 401
+	APIKey string
+
+	mu       sync.Mutex
+	fixtures map[string]Fixture
+	requests []Request
+}
+
+// New starts a mock upstream serving a copy of fixtures.
+func New(fixtures map[string]Fixture) *Server {
+	s := &Server{fixtures: map[string]Fixture{}}
+	for path, fixture := range fixtures {
+		s.fixtures[path] = fixture
+	}
+	s.Server = httptest.NewServer(http.HandlerFunc(s.serve))
+
+	return s
+}
+
+// NewRake starts a mock rake-app.
+func NewRake() *Server { return New(RakeFixtures) }
+
+// NewProse starts a mock prose-app.
+func NewProse() *Server { return New(ProseFixtures) }
+
+// NewLang starts a mock lang-app.
+func NewLang() *Server { return New(LangFixtures) }
+
+// NewDynamo starts a mock dynamo-app.
+func NewDynamo() *Server { return New(DynamoFixtures) }
+
+// SetFixture replaces the response served for path.
+func (s *Server) SetFixture(path string, fixture Fixture) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.fixtures[path] = fixture
+}
+
+// Requests returns every request received so far, in order.
+func (s *Server) Requests() []Request {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return append([]Request(nil), s.requests...)
+}
+
+func (s *Server) serve(w http.ResponseWriter, r *http.Request) {
+	body, _ := ioutil.ReadAll(r.Body)
+
+	s.mu.Lock()
+	s.requests = append(s.requests, Request{r.Method, r.URL.Path, r.Header.Clone(), string(body)})
+	fixture, ok := s.fixtures[r.URL.Path]
+	s.mu.Unlock()
+
+	switch {
+	case s.APIKey != "" && r.Header.Get("X-API-Key") != s.APIKey:
+		fixture = Fixture{http.StatusUnauthorized, `{"message":"invalid key"}`}
+	case !ok:
+		fixture = Fixture{http.StatusNotFound, `{"message":"Not Found"}`}
+	}
+
+	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+	w.WriteHeader(fixture.Status)
+	_, _ = w.Write([]byte(fixture.Body))
+}
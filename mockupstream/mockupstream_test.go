@@ -0,0 +1,57 @@
+package mockupstream
+
+import (
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestServer(t *testing.T) {
+	s := NewLang()
+	defer s.Close()
+
+	resp, err := http.Post(s.URL+"/language", "application/json", strings.NewReader(`{"text":"bonjour"}`))
+	if assert.NoError(t, err) {
+		body, _ := ioutil.ReadAll(resp.Body)
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		assert.Equal(t, LangFixtures["/language"].Body, string(body))
+	}
+
+	s.SetFixture("/language", Fixture{http.StatusServiceUnavailable, `{"message":"model unavailable"}`})
+	resp, err = http.Post(s.URL+"/language", "application/json", nil)
+	if assert.NoError(t, err) {
+		assert.Equal(t, http.StatusServiceUnavailable, resp.StatusCode)
+	}
+
+	resp, err = http.Get(s.URL + "/unknown")
+	if assert.NoError(t, err) {
+		assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+	}
+
+	requests := s.Requests()
+	if assert.Len(t, requests, 3) {
+		assert.Equal(t, `{"text":"bonjour"}`, requests[0].Body)
+		assert.Equal(t, "/unknown", requests[2].Path)
+	}
+}
+
+func TestServerAPIKey(t *testing.T) {
+	s := NewRake()
+	defer s.Close()
+	s.APIKey = "secret"
+
+	req, _ := http.NewRequest(http.MethodPost, s.URL+"/keywords", nil)
+	resp, err := http.DefaultClient.Do(req)
+	if assert.NoError(t, err) {
+		assert.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+	}
+
+	req.Header.Set("X-API-Key", "secret")
+	resp, err = http.DefaultClient.Do(req)
+	if assert.NoError(t, err) {
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+	}
+}
@@ -0,0 +1,70 @@
+// author: Gary A. Stafford
+// site: https://programmaticponderings.com
+// license: MIT License
+// purpose: NLP microservices: nlp-client toxicity moderation
+// modified: 2026-10-14
+
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"github.com/labstack/echo/v4"
+)
+
+var (
+	// moderation backend answering POST /moderate with {"toxicity": 0.0-1.0, ...}; unset disables /moderate
+	urlModerate = upstreamURL(getEnv("MODERATE_ENDPOINT", ""))
+	// "off", "flag" to mark toxic texts sent to /record, or "block" to refuse them
+	moderateRecord    = getEnv("MODERATE_RECORD", "off")
+	moderateThreshold = getEnv("MODERATE_THRESHOLD", "0.8")
+)
+
+func moderationNotConfigured() *apiError {
+	return newAPIError(http.StatusNotFound, codeNotFound, "moderation is not configured")
+}
+
+func postModerate(c echo.Context) error {
+	if urlModerate == "" {
+		return moderationNotConfigured()
+	}
+	ctx := c.Request().Context()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, urlModerate+"/moderate", c.Request().Body)
+
+	return serviceResponse(err, req, c)
+}
+
+// moderation is the part of the backend's verdict the /record gate acts on.
+type moderation struct {
+	Toxicity float64 `json:"toxicity"`
+	Flagged  bool    `json:"flagged"`
+}
+
+// screenRecord scores the text of a /record request before it is stored.
+// Texts at or above MODERATE_THRESHOLD are refused with 422 in "block" mode,
+// or stored with a "moderation" field in "flag" mode; every other text is
+// stored with its score.
+func screenRecord(c echo.Context, r *record) error {
+	threshold, err := strconv.ParseFloat(moderateThreshold, 64)
+	if err != nil {
+		return internalError(err)
+	}
+
+	req := c.Request()
+	body, err := postUpstream(req.Context(), urlModerate+"/moderate", r.text, req.Header.Get("X-API-Key"))
+	if err != nil {
+		return upstreamError(err)
+	}
+	var verdict moderation
+	if err := json.Unmarshal(body, &verdict); err != nil {
+		return upstreamError(err)
+	}
+	verdict.Flagged = verdict.Toxicity >= threshold
+	if verdict.Flagged && moderateRecord == "block" {
+		return newAPIError(http.StatusUnprocessableEntity, codeContentRejected, "text exceeds the toxicity threshold")
+	}
+
+	return r.set("moderation", verdict)
+}
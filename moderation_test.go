@@ -0,0 +1,102 @@
+package main
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// newModerationUpstreams serves a moderation backend scoring texts containing
+// "awful" as toxic, and a dynamo-app recording the bodies it stores.
+func newModerationUpstreams(t *testing.T, records *[]map[string]interface{}) (*httptest.Server, *httptest.Server) {
+	moderate := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		if strings.Contains(string(body), "awful") {
+			_, _ = w.Write([]byte(`{"toxicity": 0.97, "labels": ["insult"]}`))
+			return
+		}
+		_, _ = w.Write([]byte(`{"toxicity": 0.02, "labels": []}`))
+	}))
+	dynamo := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var record map[string]interface{}
+		if err := json.NewDecoder(r.Body).Decode(&record); err != nil {
+			t.Error(err)
+		}
+		*records = append(*records, record)
+		w.WriteHeader(http.StatusCreated)
+	}))
+
+	return moderate, dynamo
+}
+
+func TestPostModerate(t *testing.T) {
+	var records []map[string]interface{}
+	moderate, dynamo := newModerationUpstreams(t, &records)
+	defer moderate.Close()
+	defer dynamo.Close()
+	defer func(url string) { urlModerate = url }(urlModerate)
+
+	urlModerate = ""
+	req := httptest.NewRequest(http.MethodPost, "/moderate", strings.NewReader(`{"text": "awful"}`))
+	err := postModerate(e.NewContext(req, httptest.NewRecorder()))
+	if assert.Error(t, err) {
+		assert.Equal(t, http.StatusNotFound, err.(*apiError).Status)
+	}
+
+	urlModerate = moderate.URL
+	req = httptest.NewRequest(http.MethodPost, "/moderate", strings.NewReader(`{"text": "awful"}`))
+	w := httptest.NewRecorder()
+	c := e.NewContext(req, w)
+	c.SetPath("/moderate")
+	if assert.NoError(t, postModerate(c)) {
+		assert.JSONEq(t, `{"toxicity": 0.97, "labels": ["insult"]}`, w.Body.String())
+	}
+}
+
+func TestPutDynamoModeration(t *testing.T) {
+	var records []map[string]interface{}
+	moderate, dynamo := newModerationUpstreams(t, &records)
+	defer moderate.Close()
+	defer dynamo.Close()
+	defer func(moderateURL, dynamoURL, mode string) {
+		urlModerate, urlDynamo, moderateRecord = moderateURL, dynamoURL, mode
+	}(urlModerate, urlDynamo, moderateRecord)
+	urlModerate = moderate.URL
+	urlDynamo = dynamo.URL
+
+	record := func(text string) error {
+		req := httptest.NewRequest(http.MethodPost, "/record", strings.NewReader(`{"text": "`+text+`", "id": 7}`))
+		c := e.NewContext(req, httptest.NewRecorder())
+		c.SetPath("/record")
+		return putDynamo(c)
+	}
+
+	moderateRecord = "flag"
+	assert.NoError(t, record("lovely"))
+	assert.NoError(t, record("awful"))
+	if assert.Len(t, records, 2) {
+		assert.Equal(t, map[string]interface{}{"toxicity": 0.02, "flagged": false}, records[0]["moderation"])
+		assert.Equal(t, map[string]interface{}{"toxicity": 0.97, "flagged": true}, records[1]["moderation"])
+		assert.Equal(t, float64(7), records[1]["id"])
+	}
+
+	moderateRecord = "block"
+	err := record("awful")
+	if assert.Error(t, err) {
+		assert.Equal(t, http.StatusUnprocessableEntity, err.(*apiError).Status)
+		assert.Equal(t, codeContentRejected, err.(*apiError).Code)
+	}
+	assert.NoError(t, record("lovely"))
+	assert.Len(t, records, 3)
+
+	moderateRecord = "off"
+	assert.NoError(t, record("awful"))
+	if assert.Len(t, records, 4) {
+		assert.NotContains(t, records[3], "moderation")
+	}
+}
@@ -0,0 +1,168 @@
+// author: Gary A. Stafford
+// site: https://programmaticponderings.com
+// license: MIT License
+// purpose: NLP microservices: nlp-client snake_case and camelCase JSON field names
+// modified: 2026-10-14
+
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+	"unicode"
+
+	"github.com/labstack/echo/v4"
+)
+
+// the casing of response JSON keys, snake_case or camelCase; unset to answer
+// with keys as they are, which for this service's own fields is snake_case
+var jsonNaming = getEnv("JSON_NAMING", "")
+
+const (
+	namingSnake = "snake_case"
+	namingCamel = "camelCase"
+
+	namingContextKey = "json_naming"
+)
+
+var (
+	// keys are renamed only when they look like field names, so data keys,
+	// such as WORK_OF_ART entity labels or language codes, are left alone
+	snakeKey = regexp.MustCompile(`^_*[a-z][a-z0-9]*(_[a-z0-9]+)+$`)
+	camelKey = regexp.MustCompile(`^_*[a-z][a-z0-9]*([A-Z][a-z0-9]*)+$`)
+)
+
+// parseNaming returns the casing a ?naming= or JSON_NAMING asks for, or empty to keep keys as they are.
+func parseNaming(naming string) (string, error) {
+	switch naming {
+	case "", namingSnake, namingCamel:
+		return naming, nil
+	}
+
+	return "", fmt.Errorf("unknown naming %q, want snake_case or camelCase", naming)
+}
+
+// responseNaming returns the casing of the keys of a request's response.
+func responseNaming(c echo.Context) string {
+	if naming, ok := c.Get(namingContextKey).(string); ok {
+		return naming
+	}
+
+	return jsonNaming
+}
+
+// toCamel renames a snake_case key, as in language_probability to languageProbability.
+func toCamel(key string) string {
+	if !snakeKey.MatchString(key) {
+		return key
+	}
+	lead := len(key) - len(strings.TrimLeft(key, "_"))
+	parts := strings.Split(key[lead:], "_")
+	for i := 1; i < len(parts); i++ {
+		parts[i] = strings.ToUpper(parts[i][:1]) + parts[i][1:]
+	}
+
+	return key[:lead] + strings.Join(parts, "")
+}
+
+// toSnake renames a camelCase key, as in languageProbability to language_probability.
+func toSnake(key string) string {
+	if !camelKey.MatchString(key) {
+		return key
+	}
+	var b strings.Builder
+	for _, r := range key {
+		if unicode.IsUpper(r) {
+			b.WriteByte('_')
+			r = unicode.ToLower(r)
+		}
+		b.WriteRune(r)
+	}
+
+	return b.String()
+}
+
+// renameKeys rewrites the object keys of a JSON document with rename,
+// keeping their order and every value as it is.
+func renameKeys(body []byte, rename func(string) string) ([]byte, error) {
+	dec := json.NewDecoder(bytes.NewReader(body))
+	dec.UseNumber()
+	type level struct {
+		object bool
+		// whether an object's next token is a key
+		key bool
+		n   int
+	}
+	var stack []level
+	var b bytes.Buffer
+	separate := func() {
+		if len(stack) == 0 {
+			return
+		}
+		top := &stack[len(stack)-1]
+		if top.object && !top.key {
+			b.WriteByte(':')
+		} else if top.n > 0 {
+			b.WriteByte(',')
+		}
+	}
+	done := func() {
+		if len(stack) == 0 {
+			return
+		}
+		top := &stack[len(stack)-1]
+		if top.object {
+			top.key = !top.key
+			if top.key {
+				top.n++
+			}
+			return
+		}
+		top.n++
+	}
+
+	for {
+		token, err := dec.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		if delim, ok := token.(json.Delim); ok && (delim == '}' || delim == ']') {
+			b.WriteRune(rune(delim))
+			stack = stack[:len(stack)-1]
+			done()
+			continue
+		}
+		separate()
+		switch value := token.(type) {
+		case json.Delim:
+			b.WriteRune(rune(value))
+			stack = append(stack, level{object: value == '{', key: true})
+			continue
+		case string:
+			if top := len(stack) - 1; top >= 0 && stack[top].object && stack[top].key {
+				value = rename(value)
+			}
+			encoded, _ := json.Marshal(value)
+			b.Write(encoded)
+		case json.Number:
+			b.WriteString(value.String())
+		case bool:
+			fmt.Fprint(&b, value)
+		case nil:
+			b.WriteString("null")
+		}
+		done()
+	}
+	if len(stack) > 0 {
+		return nil, io.ErrUnexpectedEOF
+	}
+
+	return b.Bytes(), nil
+}
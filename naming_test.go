@@ -0,0 +1,45 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseNaming(t *testing.T) {
+	naming, err := parseNaming("camelCase")
+	if assert.NoError(t, err) {
+		assert.Equal(t, namingCamel, naming)
+	}
+	naming, err = parseNaming("")
+	if assert.NoError(t, err) {
+		assert.Empty(t, naming)
+	}
+	_, err = parseNaming("kebab-case")
+	assert.EqualError(t, err, `unknown naming "kebab-case", want snake_case or camelCase`)
+}
+
+func TestToCamel(t *testing.T) {
+	assert.Equal(t, "languageProbability", toCamel("language_probability"))
+	assert.Equal(t, "page2", toCamel("page_2"))
+	assert.Equal(t, "_meta", toCamel("_meta"))
+	assert.Equal(t, "WORK_OF_ART", toCamel("WORK_OF_ART"), "data keys are left alone")
+	assert.Equal(t, "text", toCamel("text"))
+}
+
+func TestToSnake(t *testing.T) {
+	assert.Equal(t, "language_probability", toSnake("languageProbability"))
+	assert.Equal(t, "request_id", toSnake("request_id"))
+	assert.Equal(t, "PERSON", toSnake("PERSON"), "data keys are left alone")
+	assert.Equal(t, "New York", toSnake("New York"))
+}
+
+func TestRenameKeys(t *testing.T) {
+	body, err := renameKeys([]byte(`{"request_id":"a","_meta":{"input_length":12},"items":[{"text":"a_b","score":1.50},null,true],"WORK_OF_ART":2}`), toCamel)
+	if assert.NoError(t, err) {
+		assert.Equal(t, `{"requestId":"a","_meta":{"inputLength":12},"items":[{"text":"a_b","score":1.50},null,true],"WORK_OF_ART":2}`, string(body),
+			"keys keep their order, and values and numbers are left as they are")
+	}
+	_, err = renameKeys([]byte(`{"a":`), toCamel)
+	assert.Error(t, err)
+}
@@ -0,0 +1,148 @@
+// author: Gary A. Stafford
+// site: https://programmaticponderings.com
+// license: MIT License
+// purpose: NLP microservices: nlp-client OAuth2 client credentials for upstreams
+// modified: 2026-10-14
+
+package main
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/oauth2"
+	"golang.org/x/oauth2/clientcredentials"
+)
+
+var (
+	// comma-separated upstream names sent a bearer token, e.g. "prose,lang"
+	oauth2Upstreams     = getEnv("OAUTH2_UPSTREAMS", "")
+	oauth2TokenURL      = getEnv("OAUTH2_TOKEN_URL", "")
+	oauth2ClientID      = getEnv("OAUTH2_CLIENT_ID", "")
+	oauth2ClientSecret  = newSecret(getEnv("OAUTH2_CLIENT_SECRET", ""))
+	oauth2Scopes        = getEnv("OAUTH2_SCOPES", "") // comma-separated
+	oauth2RefreshBefore = getEnv("OAUTH2_REFRESH_BEFORE", "60s")
+)
+
+// cachedTokenSource caches a token and refreshes it in the background once
+// it is within refreshBefore of expiring, so requests rarely wait on the
+// token endpoint; only a missing or expired token is fetched inline.
+type cachedTokenSource struct {
+	mu            sync.Mutex
+	source        oauth2.TokenSource
+	token         *oauth2.Token
+	refreshing    bool
+	refreshBefore time.Duration
+	now           func() time.Time
+}
+
+func newCachedTokenSource(source oauth2.TokenSource, refreshBefore time.Duration) *cachedTokenSource {
+	return &cachedTokenSource{source: source, refreshBefore: refreshBefore, now: time.Now}
+}
+
+func (s *cachedTokenSource) Token() (*oauth2.Token, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.now()
+	if s.token == nil || (!s.token.Expiry.IsZero() && !now.Before(s.token.Expiry)) {
+		token, err := s.source.Token()
+		if err != nil {
+			return nil, err
+		}
+		s.token = token
+		return token, nil
+	}
+	if !s.token.Expiry.IsZero() && now.Add(s.refreshBefore).After(s.token.Expiry) && !s.refreshing {
+		s.refreshing = true
+		go s.refresh()
+	}
+
+	return s.token, nil
+}
+
+func (s *cachedTokenSource) refresh() {
+	token, err := s.source.Token()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.refreshing = false
+	if err != nil {
+		e.Logger.Errorf("OAuth2 token refresh failed: %v", err)
+		return
+	}
+	s.token = token
+}
+
+// oauth2Transport adds a bearer token to the requests sent to the configured upstreams.
+type oauth2Transport struct {
+	next      http.RoundTripper
+	upstreams map[string]bool
+	source    oauth2.TokenSource
+}
+
+func (t *oauth2Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if !t.upstreams[upstreamName(req.URL.Host)] {
+		return t.next.RoundTrip(req)
+	}
+	token, err := t.source.Token()
+	if err != nil {
+		if req.Body != nil {
+			req.Body.Close()
+		}
+		return nil, err
+	}
+
+	authorized := req.Clone(req.Context())
+	token.SetAuthHeader(authorized)
+
+	return t.next.RoundTrip(authorized)
+}
+
+func splitList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+
+	return items
+}
+
+// clientCredentials fetches every token with the client secret current at
+// the time, so a rotated OAUTH2_CLIENT_SECRET is used from the next refresh.
+type clientCredentials struct {
+	cfg clientcredentials.Config
+}
+
+func (s clientCredentials) Token() (*oauth2.Token, error) {
+	cfg := s.cfg
+	cfg.ClientSecret = oauth2ClientSecret.Get()
+
+	return cfg.Token(context.Background())
+}
+
+func withOAuth2(next http.RoundTripper) http.RoundTripper {
+	names := splitList(oauth2Upstreams)
+	if len(names) == 0 {
+		return next
+	}
+	upstreams := map[string]bool{}
+	for _, name := range names {
+		upstreams[name] = true
+	}
+
+	cfg := clientcredentials.Config{
+		ClientID: oauth2ClientID,
+		TokenURL: oauth2TokenURL,
+		Scopes:   splitList(oauth2Scopes),
+	}
+	// the token endpoint is called with the default client, not through the upstream transport
+	source := newCachedTokenSource(clientCredentials{cfg}, envDuration(oauth2RefreshBefore, time.Minute))
+
+	return &oauth2Transport{next: next, upstreams: upstreams, source: source}
+}
@@ -0,0 +1,117 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/oauth2"
+	"golang.org/x/oauth2/clientcredentials"
+)
+
+type countingTokenSource struct {
+	calls  int32
+	expiry time.Time
+}
+
+func (s *countingTokenSource) Token() (*oauth2.Token, error) {
+	n := atomic.AddInt32(&s.calls, 1)
+	return &oauth2.Token{AccessToken: string(rune('a' + n - 1)), Expiry: s.expiry}, nil
+}
+
+func TestCachedTokenSource(t *testing.T) {
+	now := time.Date(2026, 10, 14, 12, 0, 0, 0, time.UTC)
+	upstream := &countingTokenSource{expiry: now.Add(10 * time.Minute)}
+	source := newCachedTokenSource(upstream, time.Minute)
+	source.now = func() time.Time { return now }
+
+	token, err := source.Token()
+	if assert.NoError(t, err) {
+		assert.Equal(t, "a", token.AccessToken)
+	}
+	token, _ = source.Token()
+	assert.Equal(t, "a", token.AccessToken)
+	assert.Equal(t, int32(1), atomic.LoadInt32(&upstream.calls))
+
+	// close to expiry: the cached token is returned while a new one is fetched
+	source.now = func() time.Time { return now.Add(9*time.Minute + 30*time.Second) }
+	token, _ = source.Token()
+	assert.Equal(t, "a", token.AccessToken)
+	assert.Eventually(t, func() bool { return atomic.LoadInt32(&upstream.calls) == 2 }, time.Second, time.Millisecond)
+
+	// expired: fetched inline
+	source.now = func() time.Time { return now.Add(time.Hour) }
+	token, _ = source.Token()
+	assert.Equal(t, "c", token.AccessToken)
+}
+
+type failingTokenSource struct{}
+
+func (failingTokenSource) Token() (*oauth2.Token, error) {
+	return nil, errors.New("token endpoint unavailable")
+}
+
+func TestClientCredentials(t *testing.T) {
+	var secrets []string
+	tokens := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, secret, _ := r.BasicAuth()
+		secrets = append(secrets, secret)
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"access_token": "t0k3n", "token_type": "Bearer", "expires_in": 3600}`))
+	}))
+	defer tokens.Close()
+	defer func(value string) { oauth2ClientSecret.Set(value) }(oauth2ClientSecret.Get())
+
+	source := clientCredentials{clientcredentials.Config{ClientID: "nlp-client", TokenURL: tokens.URL}}
+	oauth2ClientSecret.Set("first")
+	_, err := source.Token()
+	assert.NoError(t, err)
+	oauth2ClientSecret.Set("second")
+	_, err = source.Token()
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"first", "second"}, secrets, "each token is fetched with the current secret")
+}
+
+func TestOAuth2Transport(t *testing.T) {
+	tokens := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.NoError(t, r.ParseForm())
+		assert.Equal(t, "client_credentials", r.Form.Get("grant_type"))
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"access_token": "t0k3n", "token_type": "Bearer", "expires_in": 3600}`))
+	}))
+	defer tokens.Close()
+
+	var authorization []string
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		authorization = append(authorization, r.Header.Get("Authorization"))
+	}))
+	defer upstream.Close()
+	defer func(rake, lang string) { urlRake, urlLang = rake, lang }(urlRake, urlLang)
+	urlRake = upstream.URL
+	urlLang = "http://lang-app:8083"
+
+	cfg := clientcredentials.Config{ClientID: "nlp-client", ClientSecret: "secret", TokenURL: tokens.URL}
+	transport := &oauth2Transport{
+		next:      http.DefaultTransport,
+		upstreams: map[string]bool{"rake": true},
+		source:    newCachedTokenSource(cfg.TokenSource(context.Background()), time.Minute),
+	}
+
+	req, _ := http.NewRequest(http.MethodGet, upstream.URL+"/health", nil)
+	resp, err := transport.RoundTrip(req)
+	if assert.NoError(t, err) {
+		resp.Body.Close()
+		assert.Equal(t, []string{"Bearer t0k3n"}, authorization)
+		// the caller's request is left untouched
+		assert.Empty(t, req.Header.Get("Authorization"))
+	}
+
+	transport.source = failingTokenSource{}
+	_, err = transport.RoundTrip(req)
+	assert.Error(t, err)
+}
@@ -0,0 +1,193 @@
+// author: Gary A. Stafford
+// site: https://programmaticponderings.com
+// license: MIT License
+// purpose: NLP microservices: nlp-client OCR ingestion
+// modified: 2026-10-14
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"mime"
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/textract"
+	"github.com/aws/aws-sdk-go-v2/service/textract/types"
+	"github.com/labstack/echo/v4"
+)
+
+var (
+	// "tesseract" for the OCR service at OCR_ENDPOINT, "textract" for AWS Textract; unset disables /analyze/image
+	ocrBackend = getEnv("OCR_BACKEND", "")
+	// OCR service answering POST /ocr, given the image as the body, with {"text": "..."}
+	urlOCR = upstreamURL(getEnv("OCR_ENDPOINT", ""))
+
+	ocrOnce   sync.Once
+	ocrEngine ocrFunc
+	ocrErr    error
+)
+
+// imageFormats maps the accepted image types to their formats.
+var imageFormats = map[string]string{"image/png": "png", "image/jpeg": "jpeg", "image/tiff": "tiff"}
+
+// ocrFunc returns the text found in an image.
+type ocrFunc func(ctx context.Context, image []byte, contentType string) (string, error)
+
+func loadOCR() (ocrFunc, error) {
+	ocrOnce.Do(func() {
+		ocrEngine, ocrErr = newOCR(ocrBackend)
+	})
+
+	return ocrEngine, ocrErr
+}
+
+func newOCR(backend string) (ocrFunc, error) {
+	switch backend {
+	case "tesseract":
+		if urlOCR == "" {
+			return nil, fmt.Errorf("OCR_ENDPOINT is required by the tesseract backend")
+		}
+		return tesseractOCR(urlOCR), nil
+	case "textract":
+		cfg, err := config.LoadDefaultConfig(context.Background())
+		if err != nil {
+			return nil, err
+		}
+		return textractOCR(textract.NewFromConfig(cfg)), nil
+	}
+
+	return nil, fmt.Errorf("unsupported OCR_BACKEND: %q", backend)
+}
+
+func tesseractOCR(endpoint string) ocrFunc {
+	return func(ctx context.Context, image []byte, contentType string) (string, error) {
+		req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint+"/ocr", bytes.NewReader(image))
+		if err != nil {
+			return "", err
+		}
+		req.Header.Set(echo.HeaderContentType, contentType)
+		req.Header.Set("X-API-Key", apiKeys.Primary())
+		setDeadlineHeader(req)
+		setBaggageHeader(req)
+		setForwardedHeaders(req)
+
+		resp, err := upstreamClient.Do(req)
+		if err != nil {
+			return "", err
+		}
+		defer func(Body io.ReadCloser) {
+			err := Body.Close()
+			if err != nil {
+				e.Logger.Error(err)
+			}
+		}(resp.Body)
+		if resp.StatusCode < 200 || resp.StatusCode > 299 {
+			return "", newUpstreamStatusError(resp)
+		}
+
+		var result struct {
+			Text string `json:"text"`
+		}
+		if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+			return "", err
+		}
+		return result.Text, nil
+	}
+}
+
+// textractAPI is the part of the Textract client the OCR backend uses.
+type textractAPI interface {
+	DetectDocumentText(ctx context.Context, params *textract.DetectDocumentTextInput, optFns ...func(*textract.Options)) (*textract.DetectDocumentTextOutput, error)
+}
+
+// textractOCR returns the lines Textract detects, one per line of text.
+func textractOCR(client textractAPI) ocrFunc {
+	return func(ctx context.Context, image []byte, _ string) (string, error) {
+		out, err := client.DetectDocumentText(ctx, &textract.DetectDocumentTextInput{
+			Document: &types.Document{Bytes: image},
+		})
+		if err != nil {
+			return "", err
+		}
+
+		var lines []string
+		for _, block := range out.Blocks {
+			if block.BlockType == types.BlockTypeLine && block.Text != nil {
+				lines = append(lines, *block.Text)
+			}
+		}
+		return strings.Join(lines, "\n"), nil
+	}
+}
+
+// readUpload returns an uploaded file, sent either as the request body or as
+// the given field of a multipart form, and its content type, which must be
+// one of formats.
+func readUpload(c echo.Context, field string, formats map[string]string) ([]byte, string, error) {
+	var body io.Reader = c.Request().Body
+	contentType, _, _ := mime.ParseMediaType(c.Request().Header.Get(echo.HeaderContentType))
+	if contentType == echo.MIMEMultipartForm {
+		file, err := c.FormFile(field)
+		if err != nil {
+			return nil, "", err
+		}
+		f, err := file.Open()
+		if err != nil {
+			return nil, "", err
+		}
+		defer f.Close()
+		body = f
+		contentType, _, _ = mime.ParseMediaType(file.Header.Get(echo.HeaderContentType))
+	}
+
+	data, err := ioutil.ReadAll(body)
+	if err != nil {
+		return nil, "", err
+	}
+	if _, ok := formats[contentType]; !ok {
+		contentType, _, _ = mime.ParseMediaType(http.DetectContentType(data))
+	}
+	if _, ok := formats[contentType]; !ok {
+		return nil, "", fmt.Errorf("unsupported %s type: %s", field, contentType)
+	}
+
+	return data, contentType, nil
+}
+
+// postAnalyzeImage extracts the text of an uploaded image with the OCR
+// backend, then runs the requested analyses on it as /analyze would.
+func postAnalyzeImage(c echo.Context) error {
+	analyses, err := parseAnalyses(c.QueryParam("analyses"))
+	if err != nil {
+		return validationError(err.Error())
+	}
+	if ocrBackend == "" {
+		return newAPIError(http.StatusNotFound, codeNotFound, "OCR is not configured")
+	}
+	ocr, err := loadOCR()
+	if err != nil {
+		return internalError(err)
+	}
+
+	image, contentType, err := readUpload(c, "image", imageFormats)
+	if err != nil {
+		return validationError(err.Error())
+	}
+	text, err := ocr(c.Request().Context(), image, contentType)
+	if err != nil {
+		return upstreamError(err)
+	}
+	if strings.TrimSpace(text) == "" {
+		return newAPIError(http.StatusUnprocessableEntity, codeValidationFailed, "no text found in image")
+	}
+
+	return respondAnalyses(c, text, analyses, text)
+}
@@ -0,0 +1,120 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/textract"
+	"github.com/aws/aws-sdk-go-v2/service/textract/types"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+)
+
+// a PNG signature is enough for content sniffing
+var pngImage = []byte("\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR")
+
+type fakeTextract struct {
+	blocks []types.Block
+}
+
+func (f *fakeTextract) DetectDocumentText(_ context.Context, params *textract.DetectDocumentTextInput, _ ...func(*textract.Options)) (*textract.DetectDocumentTextOutput, error) {
+	return &textract.DetectDocumentTextOutput{Blocks: f.blocks}, nil
+}
+
+func TestTextractOCR(t *testing.T) {
+	client := &fakeTextract{blocks: []types.Block{
+		{BlockType: types.BlockTypePage},
+		{BlockType: types.BlockTypeLine, Text: aws.String("Marie Curie")},
+		{BlockType: types.BlockTypeWord, Text: aws.String("Marie")},
+		{BlockType: types.BlockTypeLine, Text: aws.String("Nobel Prize 1903")},
+	}}
+
+	text, err := textractOCR(client)(context.Background(), pngImage, "image/png")
+	if assert.NoError(t, err) {
+		assert.Equal(t, "Marie Curie\nNobel Prize 1903", text)
+	}
+}
+
+func TestReadUpload(t *testing.T) {
+	req := httptest.NewRequest(http.MethodPost, "/analyze/image", bytes.NewReader(pngImage))
+	image, contentType, err := readUpload(e.NewContext(req, httptest.NewRecorder()), "image", imageFormats)
+	if assert.NoError(t, err) {
+		assert.Equal(t, pngImage, image)
+		assert.Equal(t, "image/png", contentType)
+	}
+
+	var form bytes.Buffer
+	w := multipart.NewWriter(&form)
+	part, _ := w.CreateFormFile("image", "scan.png")
+	_, _ = part.Write(pngImage)
+	_ = w.Close()
+	req = httptest.NewRequest(http.MethodPost, "/analyze/image", &form)
+	req.Header.Set(echo.HeaderContentType, w.FormDataContentType())
+	image, contentType, err = readUpload(e.NewContext(req, httptest.NewRecorder()), "image", imageFormats)
+	if assert.NoError(t, err) {
+		assert.Equal(t, pngImage, image)
+		assert.Equal(t, "image/png", contentType)
+	}
+
+	req = httptest.NewRequest(http.MethodPost, "/analyze/image", strings.NewReader("plain text"))
+	_, _, err = readUpload(e.NewContext(req, httptest.NewRecorder()), "image", imageFormats)
+	assert.Error(t, err)
+}
+
+func TestPostAnalyzeImage(t *testing.T) {
+	ocrServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		assert.Equal(t, "/ocr", r.URL.Path)
+		assert.Equal(t, "image/png", r.Header.Get(echo.HeaderContentType))
+		assert.Equal(t, pngImage, body)
+		_, _ = w.Write([]byte(`{"text": "curie"}`))
+	}))
+	defer ocrServer.Close()
+	upstream := newBatchUpstream(t)
+	defer upstream.Close()
+	defer func(backend, ocrURL, rake string) {
+		ocrBackend, urlOCR, urlRake = backend, ocrURL, rake
+		ocrOnce = sync.Once{}
+	}(ocrBackend, urlOCR, urlRake)
+	ocrBackend = "tesseract"
+	urlOCR = ocrServer.URL
+	urlRake = upstream.URL
+	ocrOnce = sync.Once{}
+
+	req := httptest.NewRequest(http.MethodPost, "/analyze/image?analyses=keywords", bytes.NewReader(pngImage))
+	req.Header.Set(echo.HeaderContentType, "image/png")
+	w := httptest.NewRecorder()
+
+	if assert.NoError(t, postAnalyzeImage(e.NewContext(req, w))) {
+		var body struct {
+			Text     string `json:"text"`
+			Analyses map[string]struct {
+				Result []string `json:"result"`
+			} `json:"analyses"`
+		}
+		if assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &body)) {
+			assert.Equal(t, "curie", body.Text)
+			assert.Equal(t, []string{"CURIE"}, body.Analyses["keywords"].Result)
+		}
+	}
+}
+
+func TestPostAnalyzeImageNotConfigured(t *testing.T) {
+	defer func(backend string) { ocrBackend = backend }(ocrBackend)
+	ocrBackend = ""
+
+	req := httptest.NewRequest(http.MethodPost, "/analyze/image?analyses=keywords", bytes.NewReader(pngImage))
+	err := postAnalyzeImage(e.NewContext(req, httptest.NewRecorder()))
+	if assert.Error(t, err) {
+		assert.Equal(t, http.StatusNotFound, err.(*apiError).Status)
+	}
+}
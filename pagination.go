@@ -0,0 +1,101 @@
+// author: Gary A. Stafford
+// site: https://programmaticponderings.com
+// license: MIT License
+// purpose: NLP microservices: nlp-client result pagination
+// modified: 2026-10-14
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+)
+
+const headerTotalCount = "X-Total-Count"
+
+// largest page a caller may ask for
+var pageMaxLimit = getEnv("PAGE_MAX_LIMIT", "1000")
+
+// pagedRoutes answer with a JSON array that may be paged with offset and limit.
+var pagedRoutes = map[string]bool{"/tokens": true, "/entities": true}
+
+type page struct {
+	offset int
+	limit  int
+}
+
+// parsePage returns the page a request asks for with the offset and limit
+// query parameters, or nil when the request is not paged.
+func parsePage(c echo.Context) (*page, error) {
+	offset, limit := c.QueryParam("offset"), c.QueryParam("limit")
+	if !pagedRoutes[routePath(c)] || (offset == "" && limit == "") {
+		return nil, nil
+	}
+
+	maxLimit := envInt(pageMaxLimit, 1000)
+	p := &page{limit: maxLimit}
+	var err error
+	if offset != "" {
+		if p.offset, err = strconv.Atoi(offset); err != nil || p.offset < 0 {
+			return nil, fmt.Errorf("invalid offset: %s", offset)
+		}
+	}
+	if limit != "" {
+		if p.limit, err = strconv.Atoi(limit); err != nil || p.limit < 1 || p.limit > maxLimit {
+			return nil, fmt.Errorf("limit must be between 1 and %d", maxLimit)
+		}
+	}
+
+	return p, nil
+}
+
+// apply returns the page of a JSON array body, and reports the array's length
+// in X-Total-Count and the neighbouring pages in a Link header.
+func (p *page) apply(c echo.Context, body []byte) ([]byte, error) {
+	var items []json.RawMessage
+	if err := json.Unmarshal(body, &items); err != nil {
+		return nil, err
+	}
+
+	total := len(items)
+	start, end := p.offset, p.offset+p.limit
+	if start > total {
+		start = total
+	}
+	if end > total {
+		end = total
+	}
+
+	header := c.Response().Header()
+	header.Set(headerTotalCount, strconv.Itoa(total))
+	var links []string
+	if end < total {
+		links = append(links, fmt.Sprintf(`<%s>; rel="next"`, p.link(c, end)))
+	}
+	if start > 0 {
+		prev := start - p.limit
+		if prev < 0 {
+			prev = 0
+		}
+		links = append(links, fmt.Sprintf(`<%s>; rel="prev"`, p.link(c, prev)))
+	}
+	if len(links) > 0 {
+		header.Set("Link", strings.Join(links, ", "))
+	}
+
+	return json.Marshal(append([]json.RawMessage{}, items[start:end]...))
+}
+
+func (p *page) link(c echo.Context, offset int) string {
+	u := *c.Request().URL
+	query := u.Query()
+	query.Set("offset", strconv.Itoa(offset))
+	query.Set("limit", strconv.Itoa(p.limit))
+	u.RawQuery = query.Encode()
+
+	return u.RequestURI()
+}
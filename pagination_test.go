@@ -0,0 +1,70 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/garystafford/nlp-client/mockupstream"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParsePage(t *testing.T) {
+	tests := []struct {
+		path     string
+		query    string
+		expected *page
+		invalid  bool
+	}{
+		{"/tokens", "", nil, false},
+		{"/keywords", "offset=2", nil, false},
+		{"/tokens", "offset=2", &page{2, 1000}, false},
+		{"/entities", "limit=10", &page{0, 10}, false},
+		{"/tokens", "offset=-1", nil, true},
+		{"/tokens", "limit=0", nil, true},
+		{"/tokens", "limit=1001", nil, true},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest(http.MethodPost, tt.path+"?"+tt.query, nil)
+		c := e.NewContext(req, httptest.NewRecorder())
+		c.SetPath(tt.path)
+
+		p, err := parsePage(c)
+		if tt.invalid {
+			assert.Error(t, err, tt.query)
+			continue
+		}
+		if assert.NoError(t, err, tt.query) {
+			assert.Equal(t, tt.expected, p, tt.query)
+		}
+	}
+}
+
+func TestGetTokensPaged(t *testing.T) {
+	prose := mockupstream.NewProse()
+	defer prose.Close()
+	defer func(url string) { urlProse = url }(urlProse)
+	urlProse = prose.URL
+
+	req := httptest.NewRequest(http.MethodPost, "/tokens?offset=2&limit=2", strings.NewReader(`{"text": "The Nobel Prize is regarded"}`))
+	w := httptest.NewRecorder()
+	c := e.NewContext(req, w)
+	c.SetPath("/tokens")
+
+	if assert.NoError(t, getTokens(c)) {
+		assert.Equal(t, `["Prize","is"]`, w.Body.String())
+		assert.Equal(t, "5", w.Header().Get(headerTotalCount))
+		assert.Equal(t, `</tokens?limit=2&offset=4>; rel="next", </tokens?limit=2&offset=0>; rel="prev"`, w.Header().Get("Link"))
+	}
+
+	req = httptest.NewRequest(http.MethodPost, "/tokens?offset=4&limit=2", strings.NewReader(`{"text": "The Nobel Prize is regarded"}`))
+	w = httptest.NewRecorder()
+	c = e.NewContext(req, w)
+	c.SetPath("/tokens")
+
+	if assert.NoError(t, getTokens(c)) {
+		assert.Equal(t, `["regarded"]`, w.Body.String())
+		assert.Equal(t, `</tokens?limit=2&offset=2>; rel="prev"`, w.Header().Get("Link"))
+	}
+}
@@ -0,0 +1,232 @@
+// author: Gary A. Stafford
+// site: https://programmaticponderings.com
+// license: MIT License
+// purpose: NLP microservices: nlp-client tenant pattern entities
+// modified: 2026-10-14
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"net/http"
+	"regexp"
+	"strings"
+	"sync"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/labstack/echo/v4"
+)
+
+// most rules one tenant's patterns may have
+var patternMaxRules = getEnv("PATTERN_MAX_RULES", "100")
+
+var patterns = newPatternStore()
+
+// patternValidators check the checksums of identifiers, so a pattern's
+// matches that only look like one are not returned.
+var patternValidators = map[string]func(string) bool{"iban": validIBAN, "luhn": validLuhn}
+
+// patternRule returns the matches of a regular expression, or of its first
+// group when it has one, as entities with its label.
+type patternRule struct {
+	Label    string `json:"label"`
+	Pattern  string `json:"pattern"`
+	Priority int    `json:"priority,omitempty"`
+	// "iban" or "luhn", the checksum matches must pass
+	Validate string `json:"validate,omitempty"`
+	re       *regexp.Regexp
+}
+
+type patternSet struct {
+	Rules  []patternRule `json:"rules"`
+	digest string
+}
+
+// validIBAN checks the ISO 13616 mod-97 checksum of an IBAN, ignoring spaces.
+func validIBAN(value string) bool {
+	iban := strings.ToUpper(strings.ReplaceAll(value, " ", ""))
+	if len(iban) < 15 || len(iban) > 34 {
+		return false
+	}
+	var digits strings.Builder
+	for _, r := range iban[4:] + iban[:4] {
+		switch {
+		case r >= '0' && r <= '9':
+			digits.WriteRune(r)
+		case r >= 'A' && r <= 'Z':
+			fmt.Fprint(&digits, r-'A'+10)
+		default:
+			return false
+		}
+	}
+	n, _ := new(big.Int).SetString(digits.String(), 10)
+
+	return new(big.Int).Mod(n, big.NewInt(97)).Int64() == 1
+}
+
+// validLuhn checks the Luhn checksum of a number, such as a card number,
+// ignoring spaces and dashes.
+func validLuhn(value string) bool {
+	sum, count := 0, 0
+	for i := len(value) - 1; i >= 0; i-- {
+		c := value[i]
+		if c == ' ' || c == '-' {
+			continue
+		}
+		if c < '0' || c > '9' {
+			return false
+		}
+		digit := int(c - '0')
+		if count%2 == 1 {
+			if digit *= 2; digit > 9 {
+				digit -= 9
+			}
+		}
+		sum += digit
+		count++
+	}
+
+	return count > 1 && sum%10 == 0
+}
+
+// find returns the matches of every rule in text, in characters; matches
+// that begin or end inside a word are left out.
+func (p *patternSet) find(text string) []localMatch {
+	var found []localMatch
+	for _, rule := range p.Rules {
+		for _, loc := range rule.re.FindAllStringSubmatchIndex(text, -1) {
+			start, end := loc[0], loc[1]
+			if len(loc) > 2 && loc[2] >= 0 {
+				start, end = loc[2], loc[3]
+			}
+			if start == end {
+				continue
+			}
+			first, _ := utf8.DecodeRuneInString(text[start:end])
+			last, _ := utf8.DecodeLastRuneInString(text[start:end])
+			before, _ := utf8.DecodeLastRuneInString(text[:start])
+			after, _ := utf8.DecodeRuneInString(text[end:])
+			if start > 0 && wordRune(before) && wordRune(first) || end < len(text) && wordRune(after) && wordRune(last) {
+				continue
+			}
+			if validate := patternValidators[rule.Validate]; validate != nil && !validate(text[start:end]) {
+				continue
+			}
+			found = append(found, localMatch{
+				start:    utf8.RuneCountInString(text[:start]),
+				end:      utf8.RuneCountInString(text[:end]),
+				label:    rule.Label,
+				source:   "pattern",
+				priority: rule.Priority,
+			})
+		}
+	}
+
+	return found
+}
+
+func wordRune(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsDigit(r)
+}
+
+// patternStore keeps the patterns tenants upload to a single instance in memory.
+type patternStore struct {
+	mu   sync.RWMutex
+	sets map[string]*patternSet
+}
+
+func newPatternStore() *patternStore {
+	return &patternStore{sets: map[string]*patternSet{}}
+}
+
+func (s *patternStore) get(tenant string) *patternSet {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return s.sets[tenant]
+}
+
+func (s *patternStore) set(tenant string, p *patternSet) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if p == nil {
+		delete(s.sets, tenant)
+		return
+	}
+	s.sets[tenant] = p
+}
+
+// digest identifies the rules of a tenant's patterns, for the ETags of the
+// entities they are merged into; it is empty without any.
+func (s *patternStore) digest(tenant string) string {
+	if p := s.get(tenant); p != nil {
+		return p.digest
+	}
+
+	return ""
+}
+
+// putPatterns replaces the patterns of the request's tenant. Go regular
+// expressions run in time linear in the text, so a tenant's rule cannot stall
+// the requests of others.
+func putPatterns(c echo.Context) error {
+	tenant, err := tenantOf(c)
+	if err != nil {
+		return err
+	}
+	p := &patternSet{}
+	if err := json.NewDecoder(c.Request().Body).Decode(p); err != nil {
+		return validationError(err.Error())
+	}
+	if max := envInt(patternMaxRules, 100); len(p.Rules) > max {
+		return newAPIError(http.StatusRequestEntityTooLarge, codePayloadTooLarge, fmt.Sprintf("more than %d pattern rules", max))
+	}
+	entries := []string{}
+	for i := range p.Rules {
+		rule := &p.Rules[i]
+		if strings.TrimSpace(rule.Label) == "" {
+			return validationError(fmt.Sprintf("rule %d has no label", i))
+		}
+		if rule.re, err = regexp.Compile(rule.Pattern); err != nil {
+			return validationError(fmt.Sprintf("rule %d: %v", i, err))
+		}
+		if rule.re.MatchString("") {
+			return validationError(fmt.Sprintf("rule %d matches empty text", i))
+		}
+		if _, ok := patternValidators[rule.Validate]; rule.Validate != "" && !ok {
+			return validationError(fmt.Sprintf("rule %d: unknown validate %q, want iban or luhn", i, rule.Validate))
+		}
+		entries = append(entries, fmt.Sprintf("%s\x00%s\x00%d\x00%s", rule.Label, rule.Pattern, rule.Priority, rule.Validate))
+	}
+	p.digest = hashHex(entries...)
+	patterns.set(tenant, p)
+
+	return c.JSON(http.StatusOK, p)
+}
+
+func getPatterns(c echo.Context) error {
+	tenant, err := tenantOf(c)
+	if err != nil {
+		return err
+	}
+	p := patterns.get(tenant)
+	if p == nil {
+		return newAPIError(http.StatusNotFound, codeNotFound, "no patterns are uploaded for the tenant")
+	}
+
+	return c.JSON(http.StatusOK, p)
+}
+
+func deletePatterns(c echo.Context) error {
+	tenant, err := tenantOf(c)
+	if err != nil {
+		return err
+	}
+	patterns.set(tenant, nil)
+
+	return c.NoContent(http.StatusNoContent)
+}
@@ -0,0 +1,51 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// uploadPatterns puts patterns for a tenant for the duration of a test.
+func uploadPatterns(t *testing.T, tenant, body string) error {
+	t.Cleanup(func() { patterns.set(tenant, nil) })
+	req := httptest.NewRequest(http.MethodPut, "/patterns", strings.NewReader(body))
+	req.Header.Set(tenantHeader, tenant)
+	return putPatterns(e.NewContext(req, httptest.NewRecorder()))
+}
+
+func TestPatternValidators(t *testing.T) {
+	assert.True(t, validIBAN("GB82 WEST 1234 5698 7654 32"))
+	assert.True(t, validIBAN("DE89370400440532013000"))
+	assert.False(t, validIBAN("GB82 WEST 1234 5698 7654 33"))
+	assert.False(t, validIBAN("GB82"))
+	assert.True(t, validLuhn("4111-1111-1111-1111"))
+	assert.False(t, validLuhn("4111-1111-1111-1112"))
+}
+
+func TestPatternSetFind(t *testing.T) {
+	assert.NoError(t, uploadPatterns(t, "acme", `{"rules": [
+		{"label": "ORDER_ID", "pattern": "(?i)order #(\\d{5})"},
+		{"label": "TICKET", "pattern": "[A-Z]+-\\d+", "priority": 1},
+		{"label": "IBAN", "pattern": "[A-Z]{2}\\d{2}(?: ?[A-Z0-9]{4}){3,7}(?: ?[A-Z0-9]{1,3})?", "validate": "iban"}
+	]}`))
+	text := "Order #12345 and OPS-42, not XOPS-42x; pay GB82 WEST 1234 5698 7654 32, not GB82 WEST 1234 5698 7654 33."
+
+	assert.Equal(t, []localMatch{
+		{7, 12, "ORDER_ID", "pattern", 0},
+		{17, 23, "TICKET", "pattern", 1},
+		{43, 70, "IBAN", "pattern", 0},
+	}, patterns.get("acme").find(text), "the first group is the entity, within whole words, with a valid checksum")
+}
+
+func TestPutPatterns(t *testing.T) {
+	assert.Error(t, uploadPatterns(t, "acme", `{"rules": [{"label": "TICKET", "pattern": "[A-Z"}]}`))
+	assert.Error(t, uploadPatterns(t, "acme", `{"rules": [{"label": "TICKET", "pattern": "\\d*"}]}`), "a rule must not match empty text")
+	assert.Error(t, uploadPatterns(t, "acme", `{"rules": [{"label": "", "pattern": "\\d+"}]}`))
+	assert.Error(t, uploadPatterns(t, "acme", `{"rules": [{"label": "IBAN", "pattern": "\\d+", "validate": "crc"}]}`))
+	assert.NoError(t, uploadPatterns(t, "acme", `{"rules": [{"label": "TICKET", "pattern": "[A-Z]+-\\d+"}]}`))
+	assert.NotEmpty(t, patterns.digest("acme"))
+}
@@ -0,0 +1,56 @@
+//go:build plugins
+// +build plugins
+
+// author: Gary A. Stafford
+// site: https://programmaticponderings.com
+// license: MIT License
+// purpose: NLP microservices: nlp-client Go plugin loader
+// modified: 2026-10-14
+
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+	"plugin"
+)
+
+var pluginDir = getEnv("PLUGIN_DIR", "")
+
+// loadPlugins opens every *.so in PLUGIN_DIR and calls its Register function,
+//
+//	func Register(request func(string, func(string) (string, error)),
+//		response func(string, func([]byte) ([]byte, error)))
+//
+// which registers the plugin's named transforms.
+func loadPlugins() error {
+	if pluginDir == "" {
+		return nil
+	}
+	paths, err := filepath.Glob(filepath.Join(pluginDir, "*.so"))
+	if err != nil {
+		return err
+	}
+
+	for _, path := range paths {
+		p, err := plugin.Open(path)
+		if err != nil {
+			return err
+		}
+		sym, err := p.Lookup("Register")
+		if err != nil {
+			return err
+		}
+		register, ok := sym.(func(func(string, func(string) (string, error)), func(string, func([]byte) ([]byte, error))))
+		if !ok {
+			return fmt.Errorf("%s: Register has the wrong signature", path)
+		}
+		register(
+			func(name string, t func(string) (string, error)) { registerRequestTransform(name, t) },
+			func(name string, t func([]byte) ([]byte, error)) { registerResponseTransform(name, t) },
+		)
+		e.Logger.Infof("loaded plugin %s", path)
+	}
+
+	return nil
+}
@@ -0,0 +1,15 @@
+//go:build !plugins
+// +build !plugins
+
+// author: Gary A. Stafford
+// site: https://programmaticponderings.com
+// license: MIT License
+// purpose: NLP microservices: nlp-client Go plugin loader (disabled)
+// modified: 2026-10-14
+
+package main
+
+// loadPlugins is a no-op unless the binary is built with -tags plugins.
+func loadPlugins() error {
+	return nil
+}
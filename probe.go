@@ -0,0 +1,188 @@
+// author: Gary A. Stafford
+// site: https://programmaticponderings.com
+// license: MIT License
+// purpose: NLP microservices: nlp-client synthetic probes
+// modified: 2026-10-14
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/labstack/echo/v4"
+)
+
+var (
+	// how often canary texts are sent through every analysis; unset to disable
+	probeInterval = getEnv("PROBE_INTERVAL", "")
+	probeTimeout  = getEnv("PROBE_TIMEOUT", "10s")
+	probeText     = getEnv("PROBE_TEXT", "Marie Curie won the Nobel Prize in Physics in Paris in 1903.")
+	// body POSTed to /record, e.g. one addressed to a canary partition; unset to skip /record
+	probeRecordBody = getEnv("PROBE_RECORD_BODY", "")
+
+	prober = newProber()
+)
+
+var probeAnalyses = []string{"keywords", "tokens", "entities", "sentences", "language"}
+
+var errEmptyResult = errors.New("empty result")
+
+type probeResult struct {
+	Success   bool      `json:"success"`
+	LatencyMS float64   `json:"latency_ms"`
+	Error     string    `json:"error,omitempty"`
+	Time      time.Time `json:"time"`
+}
+
+// syntheticProber keeps the latest result of each probe.
+type syntheticProber struct {
+	mu      sync.RWMutex
+	results map[string]probeResult
+}
+
+func newProber() *syntheticProber {
+	return &syntheticProber{results: map[string]probeResult{}}
+}
+
+func (p *syntheticProber) record(name string, start time.Time, err error) {
+	result := probeResult{
+		Success:   err == nil,
+		LatencyMS: float64(time.Since(start)) / float64(time.Millisecond),
+		Time:      time.Now().UTC(),
+	}
+	if err != nil {
+		result.Error = err.Error()
+	}
+
+	p.mu.Lock()
+	p.results[name] = result
+	p.mu.Unlock()
+
+	success := 0.0
+	if result.Success {
+		success = 1
+	}
+	tags := map[string]string{"probe": name}
+	metrics.Gauge("probe_success", success, tags)
+	metrics.Observe("probe_duration_seconds", time.Since(start).Seconds(), tags)
+}
+
+func (p *syntheticProber) snapshot() (map[string]probeResult, bool) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	ready := true
+	results := make(map[string]probeResult, len(p.results))
+	for name, result := range p.results {
+		results[name] = result
+		ready = ready && result.Success
+	}
+
+	return results, ready
+}
+
+// probe runs every analysis end to end, failing those that answer with an
+// empty result, which is how a broken model often looks from the outside.
+func (p *syntheticProber) probe(ctx context.Context, timeout time.Duration) {
+	key := apiKeys.Primary()
+	for _, name := range probeAnalyses {
+		probeCtx, cancel := context.WithTimeout(ctx, timeout)
+		start := time.Now()
+		result := runAnalysis(probeCtx, name, probeText, key)
+		cancel()
+
+		var err error
+		switch {
+		case result.Error != nil:
+			err = result.Error
+		case isEmptyResult(result.Result):
+			err = errEmptyResult
+		}
+		p.record(name, start, err)
+	}
+
+	if probeRecordBody != "" {
+		probeCtx, cancel := context.WithTimeout(ctx, timeout)
+		start := time.Now()
+		p.record("record", start, probeRecord(probeCtx, key))
+		cancel()
+	}
+}
+
+func probeRecord(ctx context.Context, key string) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, urlDynamo+"/record", strings.NewReader(probeRecordBody))
+	if err != nil {
+		return err
+	}
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	req.Header.Set("X-API-Key", key)
+	resp, err := upstreamClient.Do(req)
+	if err != nil {
+		return upstreamError(err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return upstreamError(newUpstreamStatusError(resp))
+	}
+
+	return nil
+}
+
+func isEmptyResult(result json.RawMessage) bool {
+	switch string(bytes.TrimSpace(result)) {
+	case "", "null", "[]", "{}", `""`:
+		return true
+	}
+
+	return false
+}
+
+func startProber() {
+	if probeInterval == "" {
+		return
+	}
+	interval := envDuration(probeInterval, time.Minute)
+	timeout := envDuration(probeTimeout, 10*time.Second)
+	go func() {
+		for {
+			prober.probe(context.Background(), timeout)
+			time.Sleep(interval)
+		}
+	}()
+}
+
+// getReady answers 503 while any synthetic probe is failing.
+func getReady(c echo.Context) error {
+	results, ready := prober.snapshot()
+	names := make([]string, 0, len(results))
+	for name := range results {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var failing []string
+	for _, name := range names {
+		if !results[name].Success {
+			failing = append(failing, name)
+		}
+	}
+
+	status, code := "ready", http.StatusOK
+	if !ready {
+		status, code = "not ready", http.StatusServiceUnavailable
+	}
+
+	return c.JSON(code, struct {
+		Status  string                 `json:"status"`
+		Failing []string               `json:"failing,omitempty"`
+		Probes  map[string]probeResult `json:"probes"`
+	}{status, failing, results})
+}
@@ -0,0 +1,65 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIsEmptyResult(t *testing.T) {
+	for _, result := range []string{"", " null", "[]", "{}", `""`} {
+		assert.True(t, isEmptyResult(json.RawMessage(result)), result)
+	}
+	assert.False(t, isEmptyResult(json.RawMessage(`["curie"]`)))
+}
+
+func TestProbe(t *testing.T) {
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.URL.Path == "/keywords":
+			_, _ = w.Write([]byte(`["nobel prize"]`))
+		case strings.HasPrefix(r.URL.Path, "/language"):
+			_, _ = w.Write([]byte(`[]`))
+		default:
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+	}))
+	defer upstream.Close()
+	defer func(rake, prose, lang string) { urlRake, urlProse, urlLang = rake, prose, lang }(urlRake, urlProse, urlLang)
+	urlRake, urlProse, urlLang = upstream.URL, upstream.URL, upstream.URL
+
+	p := newProber()
+	p.probe(context.Background(), time.Second)
+
+	results, ready := p.snapshot()
+	assert.False(t, ready)
+	assert.True(t, results["keywords"].Success)
+	assert.Equal(t, errEmptyResult.Error(), results["language"].Error)
+	assert.False(t, results["entities"].Success)
+	_, probed := results["record"]
+	assert.False(t, probed)
+}
+
+func TestGetReady(t *testing.T) {
+	defer func(p *syntheticProber) { prober = p }(prober)
+	prober = newProber()
+
+	w := httptest.NewRecorder()
+	if assert.NoError(t, getReady(e.NewContext(httptest.NewRequest(http.MethodGet, "/ready", nil), w))) {
+		assert.Equal(t, http.StatusOK, w.Code)
+	}
+
+	prober.record("keywords", time.Now(), nil)
+	prober.record("language", time.Now(), errEmptyResult)
+	w = httptest.NewRecorder()
+	if assert.NoError(t, getReady(e.NewContext(httptest.NewRequest(http.MethodGet, "/ready", nil), w))) {
+		assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+		assert.Contains(t, w.Body.String(), `"failing":["language"]`)
+	}
+}
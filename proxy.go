@@ -0,0 +1,63 @@
+// author: Gary A. Stafford
+// site: https://programmaticponderings.com
+// license: MIT License
+// purpose: NLP microservices: nlp-client passthrough reverse proxy
+// modified: 2026-10-14
+
+package main
+
+import (
+	"net/http"
+	"net/http/httputil"
+	"net/url"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+)
+
+// comma-separated route paths, e.g. "/keywords,/language", served by the reverse proxy
+var passthroughRoutes = getEnv("PASSTHROUGH_ROUTES", "")
+
+func isPassthrough(path string) bool {
+	for _, route := range strings.Split(passthroughRoutes, ",") {
+		if strings.TrimSpace(route) == path && path != "" {
+			return true
+		}
+	}
+
+	return false
+}
+
+// proxyPass streams the inbound request to target and the upstream response
+// back to the caller byte for byte, without decoding either body.
+func proxyPass(c echo.Context, target *url.URL) error {
+	var proxyErr error
+	proxy := &httputil.ReverseProxy{
+		Transport: upstreamTransport,
+		Director: func(req *http.Request) {
+			req.URL.Scheme = target.Scheme
+			req.URL.Host = target.Host
+			req.URL.Path = target.Path
+			req.URL.RawPath = target.RawPath
+			req.Host = target.Host
+			setProxyHeaders(req)
+			setDeadlineHeader(req)
+			setBaggageHeader(req)
+		},
+		ModifyResponse: func(resp *http.Response) error {
+			if resp.StatusCode < 200 || resp.StatusCode > 299 {
+				return newUpstreamStatusError(resp)
+			}
+			return nil
+		},
+		ErrorHandler: func(w http.ResponseWriter, r *http.Request, err error) {
+			proxyErr = err
+		},
+	}
+	proxy.ServeHTTP(c.Response(), c.Request())
+	if proxyErr != nil {
+		return upstreamError(proxyErr)
+	}
+
+	return nil
+}
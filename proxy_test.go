@@ -0,0 +1,46 @@
+package main
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIsPassthrough(t *testing.T) {
+	defer func(routes string) { passthroughRoutes = routes }(passthroughRoutes)
+	passthroughRoutes = "/keywords, /language"
+
+	assert.True(t, isPassthrough("/keywords"))
+	assert.True(t, isPassthrough("/language"))
+	assert.False(t, isPassthrough("/entities"))
+	assert.False(t, isPassthrough(""))
+}
+
+func TestGetKeywordsPassthrough(t *testing.T) {
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		assert.Equal(t, "/keywords", r.URL.Path)
+		assert.Equal(t, "secret", r.Header.Get("X-API-Key"))
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write(body)
+	}))
+	defer upstream.Close()
+	defer func(url, routes string) { urlRake, passthroughRoutes = url, routes }(urlRake, passthroughRoutes)
+	urlRake = upstream.URL
+	passthroughRoutes = "/keywords"
+
+	req := httptest.NewRequest(http.MethodPost, "/keywords", strings.NewReader(`{"text":"passthrough"}`))
+	req.Header.Set("X-API-Key", "secret")
+	w := httptest.NewRecorder()
+	c := e.NewContext(req, w)
+	c.SetPath("/keywords")
+
+	if assert.NoError(t, getKeywords(c)) {
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, `{"text":"passthrough"}`, w.Body.String())
+	}
+}
@@ -0,0 +1,64 @@
+// author: Gary A. Stafford
+// site: https://programmaticponderings.com
+// license: MIT License
+// purpose: NLP microservices: nlp-client consent and purpose of records
+// modified: 2026-10-14
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+)
+
+var (
+	// "on" to refuse records stored without a purpose
+	recordRequirePurpose = getEnv("RECORD_REQUIRE_PURPOSE", "off")
+	// comma-separated purposes records may be stored for; unset allows any
+	recordPurposes = getEnv("RECORD_PURPOSES", "")
+)
+
+// purposeHeader names the purpose of records stored by routes other than
+// /record, whose bodies have no purpose field.
+const purposeHeader = "X-Record-Purpose"
+
+// checkPurpose validates the "purpose" and "consent" fields of a record,
+// filling in the purpose from the request's purposeHeader when the record has
+// none.
+func checkPurpose(c echo.Context, r *record) error {
+	var purpose string
+	if raw, ok := r.fields["purpose"]; ok {
+		if err := json.Unmarshal(raw, &purpose); err != nil {
+			return validationError("purpose must be a string")
+		}
+	}
+	if purpose == "" {
+		purpose = strings.TrimSpace(c.Request().Header.Get(purposeHeader))
+	}
+	if raw, ok := r.fields["consent"]; ok {
+		var consent bool
+		if err := json.Unmarshal(raw, &consent); err != nil {
+			return validationError("consent must be a boolean")
+		}
+	}
+
+	if purpose == "" {
+		if recordRequirePurpose == "on" {
+			return validationError("purpose is required")
+		}
+		return nil
+	}
+	allowed := splitList(recordPurposes)
+	known := len(allowed) == 0
+	for _, name := range allowed {
+		known = known || name == purpose
+	}
+	if !known {
+		return validationError(fmt.Sprintf("unknown purpose %q, want one of %s", purpose, strings.Join(allowed, ", ")))
+	}
+
+	return r.set("purpose", purpose)
+}
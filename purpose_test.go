@@ -0,0 +1,50 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPutDynamoPurpose(t *testing.T) {
+	var records []map[string]interface{}
+	_, dynamo := newModerationUpstreams(t, &records)
+	defer dynamo.Close()
+	defer func(url, require, purposes string) {
+		urlDynamo, recordRequirePurpose, recordPurposes = url, require, purposes
+	}(urlDynamo, recordRequirePurpose, recordPurposes)
+	urlDynamo = dynamo.URL
+	recordRequirePurpose, recordPurposes = "on", "analytics, support"
+
+	record := func(body, purpose string) error {
+		req := httptest.NewRequest(http.MethodPost, "/record", strings.NewReader(body))
+		if purpose != "" {
+			req.Header.Set(purposeHeader, purpose)
+		}
+		c := e.NewContext(req, httptest.NewRecorder())
+		c.SetPath("/record")
+		return putDynamo(c)
+	}
+
+	assert.NoError(t, record(`{"text": "Marie Curie", "purpose": "analytics", "consent": true}`, ""))
+	assert.NoError(t, record(`{"text": "Marie Curie"}`, "support"))
+	for _, body := range []string{
+		`{"text": "Marie Curie"}`,
+		`{"text": "Marie Curie", "purpose": "marketing"}`,
+		`{"text": "Marie Curie", "purpose": 7}`,
+		`{"text": "Marie Curie", "purpose": "analytics", "consent": "yes"}`,
+	} {
+		err := record(body, "")
+		if assert.Error(t, err, body) {
+			assert.Equal(t, http.StatusBadRequest, err.(*apiError).Status, body)
+		}
+	}
+	if assert.Len(t, records, 2, "refused records are not stored") {
+		assert.Equal(t, "analytics", records[0]["purpose"])
+		assert.Equal(t, true, records[0]["consent"])
+		assert.Equal(t, "support", records[1]["purpose"])
+	}
+}
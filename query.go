@@ -0,0 +1,102 @@
+// author: Gary A. Stafford
+// site: https://programmaticponderings.com
+// license: MIT License
+// purpose: NLP microservices: nlp-client GET analysis variants
+// modified: 2026-10-14
+
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/labstack/echo/v4"
+)
+
+var (
+	getMaxTextLength = getEnv("GET_MAX_TEXT_LENGTH", "2000")
+	// accept the API key as the api_key query parameter of the GET variants,
+	// for callers that cannot set headers; the key then shows in URLs and logs
+	getQueryAPIKey = getEnv("GET_QUERY_API_KEY", "false")
+)
+
+// queryRoutes are the analysis routes also served as GET, with their text in
+// the text query parameter.
+var queryRoutes = []string{"/keywords", "/tokens", "/entities", "/sentences", "/language"}
+
+// unversioned returns a route path without its version prefix.
+func unversioned(path string) string {
+	for _, version := range []string{schemaV1, schemaV2} {
+		if strings.HasPrefix(path, "/"+version+"/") {
+			return strings.TrimPrefix(path, "/"+version)
+		}
+	}
+
+	return path
+}
+
+// queryVariants returns the GET variants of the POST query routes.
+func queryVariants(routes []route) []route {
+	var variants []route
+	for _, r := range routes {
+		for _, path := range queryRoutes {
+			if r.method == http.MethodPost && r.path == path {
+				variants = append(variants, route{http.MethodGet, r.path, r.handler, r.group})
+			}
+		}
+	}
+
+	return variants
+}
+
+func isQueryVariant(r route) bool {
+	if r.method != http.MethodGet {
+		return false
+	}
+	for _, path := range queryRoutes {
+		if unversioned(r.path) == path {
+			return true
+		}
+	}
+
+	return false
+}
+
+// queryText turns the text query parameter of a GET variant into the JSON
+// body its POST handler expects, so the rest of the chain, from limits and
+// quotas to the cache, treats both alike.
+func queryText() echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			req := c.Request()
+			text := c.QueryParam("text")
+			if text == "" {
+				return validationError("missing text query parameter")
+			}
+			if max := envInt(getMaxTextLength, 2000); utf8.RuneCountInString(text) > max {
+				return newAPIError(http.StatusRequestURITooLong, codePayloadTooLarge,
+					fmt.Sprintf("text longer than %d characters, use POST", max))
+			}
+			if key := c.QueryParam("api_key"); key != "" && envBool(getQueryAPIKey) && req.Header.Get("X-API-Key") == "" {
+				req.Header.Set("X-API-Key", key)
+			}
+
+			body, err := json.Marshal(struct {
+				Text string `json:"text"`
+			}{text})
+			if err != nil {
+				return internalError(err)
+			}
+			req.Body = ioutil.NopCloser(bytes.NewReader(body))
+			req.ContentLength = int64(len(body))
+			req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+
+			return next(c)
+		}
+	}
+}
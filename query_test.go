@@ -0,0 +1,90 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/garystafford/nlp-client/mockupstream"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestQueryVariants(t *testing.T) {
+	variants := map[string]bool{}
+	for _, m := range registry() {
+		for _, r := range m.paths() {
+			if r.method == http.MethodGet {
+				variants[r.path] = isQueryVariant(r)
+			}
+		}
+	}
+
+	for _, path := range []string{"/keywords", "/v1/keywords", "/v2/keywords", "/language"} {
+		assert.True(t, variants[path], path)
+	}
+	assert.False(t, variants["/health"])
+	assert.NotContains(t, variants, "/record")
+}
+
+func TestGetKeywordsQuery(t *testing.T) {
+	rake := mockupstream.NewRake()
+	defer rake.Close()
+	defer func(url string) { urlRake = url }(urlRake)
+	urlRake = rake.URL
+
+	req := httptest.NewRequest(http.MethodGet, "/keywords?text="+url.QueryEscape("The Nobel Prize"), nil)
+	w := httptest.NewRecorder()
+	c := e.NewContext(req, w)
+	c.SetPath("/keywords")
+
+	if assert.NoError(t, queryText()(getKeywords)(c)) {
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Contains(t, w.Body.String(), `"candidate"`)
+	}
+}
+
+func TestQueryTextInvalid(t *testing.T) {
+	defer func(max string) { getMaxTextLength = max }(getMaxTextLength)
+	getMaxTextLength = "5"
+
+	tests := []struct {
+		query  string
+		status int
+	}{
+		{"", http.StatusBadRequest},
+		{"text=" + strings.Repeat("a", 6), http.StatusRequestURITooLong},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest(http.MethodGet, "/keywords?"+tt.query, nil)
+		c := e.NewContext(req, httptest.NewRecorder())
+
+		err := queryText()(func(c echo.Context) error { return nil })(c)
+		if assert.Error(t, err, tt.query) {
+			assert.Equal(t, tt.status, err.(*apiError).Status, tt.query)
+		}
+	}
+}
+
+func TestQueryTextAPIKey(t *testing.T) {
+	defer func(enabled string) { getQueryAPIKey = enabled }(getQueryAPIKey)
+
+	for _, enabled := range []string{"false", "true"} {
+		getQueryAPIKey = enabled
+		req := httptest.NewRequest(http.MethodGet, "/keywords?text=one&api_key=secret", nil)
+		c := e.NewContext(req, httptest.NewRecorder())
+
+		var key string
+		_ = queryText()(func(c echo.Context) error {
+			key = c.Request().Header.Get("X-API-Key")
+			return nil
+		})(c)
+		if enabled == "true" {
+			assert.Equal(t, "secret", key)
+		} else {
+			assert.Empty(t, key)
+		}
+	}
+}
@@ -0,0 +1,183 @@
+// author: Gary A. Stafford
+// site: https://programmaticponderings.com
+// license: MIT License
+// purpose: NLP microservices: nlp-client upstream request queues
+// modified: 2026-10-14
+
+package main
+
+import (
+	"container/list"
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+)
+
+var (
+	// calls in flight to each upstream; 0, the default, leaves them unbounded
+	upstreamConcurrency = getEnv("UPSTREAM_CONCURRENCY", "0")
+	// calls waiting for each upstream beyond which new calls are rejected
+	upstreamQueueSize    = getEnv("UPSTREAM_QUEUE_SIZE", "100")
+	errUpstreamQueueFull = errors.New("upstream queue full")
+	upstreamQueues       = newQueueSet(envInt(upstreamConcurrency, 0), envInt(upstreamQueueSize, 100))
+)
+
+// upstreamQueue admits up to limit calls to an upstream at once, and queues
+// up to backlog more in arrival order, so short bursts wait rather than fail.
+// With no limit, it admits every call and only counts those in flight.
+type upstreamQueue struct {
+	mu       sync.Mutex
+	name     string
+	limit    int
+	backlog  int
+	inFlight int
+	waiting  *list.List // of chan struct{}, closed when the call is admitted
+}
+
+func newUpstreamQueue(name string, limit, backlog int) *upstreamQueue {
+	return &upstreamQueue{name: name, limit: limit, backlog: backlog, waiting: list.New()}
+}
+
+func (q *upstreamQueue) tags() map[string]string {
+	return map[string]string{"upstream": q.name}
+}
+
+// acquire waits for a call's turn, failing at once if the backlog is full or
+// when ctx is done first.
+func (q *upstreamQueue) acquire(ctx context.Context) error {
+	q.mu.Lock()
+	if q.limit <= 0 || (q.inFlight < q.limit && q.waiting.Len() == 0) {
+		q.inFlight++
+		metrics.Gauge("upstream_in_flight", float64(q.inFlight), q.tags())
+		q.mu.Unlock()
+		return nil
+	}
+	if q.waiting.Len() >= q.backlog {
+		q.mu.Unlock()
+		metrics.Count("upstream_queue_rejected_total", 1, q.tags())
+		return errUpstreamQueueFull
+	}
+	admitted := make(chan struct{})
+	el := q.waiting.PushBack(admitted)
+	metrics.Gauge("upstream_queue_depth", float64(q.waiting.Len()), q.tags())
+	q.mu.Unlock()
+
+	start := time.Now()
+	select {
+	case <-admitted:
+		metrics.Observe("upstream_queue_wait_seconds", time.Since(start).Seconds(), q.tags())
+		return nil
+	case <-ctx.Done():
+	}
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	select {
+	case <-admitted:
+		// admitted as ctx was done; pass the turn on
+		q.releaseLocked()
+	default:
+		q.waiting.Remove(el)
+		metrics.Gauge("upstream_queue_depth", float64(q.waiting.Len()), q.tags())
+	}
+
+	return ctx.Err()
+}
+
+func (q *upstreamQueue) release() {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	q.releaseLocked()
+}
+
+// releaseLocked hands a finished call's slot to the longest waiting call, if any.
+func (q *upstreamQueue) releaseLocked() {
+	front := q.waiting.Front()
+	if front == nil {
+		q.inFlight--
+		metrics.Gauge("upstream_in_flight", float64(q.inFlight), q.tags())
+		return
+	}
+	q.waiting.Remove(front)
+	close(front.Value.(chan struct{}))
+	metrics.Gauge("upstream_queue_depth", float64(q.waiting.Len()), q.tags())
+}
+
+// releasingBody releases a call's slot once its response body is closed,
+// so streamed responses hold their slot until they finish.
+type releasingBody struct {
+	io.ReadCloser
+	once    sync.Once
+	release func()
+}
+
+func (b *releasingBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.once.Do(b.release)
+
+	return err
+}
+
+// queueSet holds the queue of each named upstream, created on first use.
+type queueSet struct {
+	mu      sync.Mutex
+	queues  map[string]*upstreamQueue
+	limit   int
+	backlog int
+}
+
+func newQueueSet(limit, backlog int) *queueSet {
+	return &queueSet{queues: map[string]*upstreamQueue{}, limit: limit, backlog: backlog}
+}
+
+func (s *queueSet) queue(name string) *upstreamQueue {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	q, ok := s.queues[name]
+	if !ok {
+		q = newUpstreamQueue(name, s.limit, s.backlog)
+		s.queues[name] = q
+	}
+
+	return q
+}
+
+// queueTransport queues the calls to each named upstream.
+type queueTransport struct {
+	next   http.RoundTripper
+	queues *queueSet
+}
+
+func (t *queueTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	name := upstreamName(req.URL.Host)
+	if name == "" {
+		return t.next.RoundTrip(req)
+	}
+	q := t.queues.queue(name)
+	if err := q.acquire(req.Context()); err != nil {
+		if req.Body != nil {
+			req.Body.Close()
+		}
+		return nil, err
+	}
+
+	resp, err := t.next.RoundTrip(req)
+	if err != nil {
+		q.release()
+		return nil, err
+	}
+	resp.Body = &releasingBody{ReadCloser: resp.Body, release: q.release}
+
+	return resp, nil
+}
+
+// withUpstreamQueue is always installed, so the calls in flight are counted
+// for autoscaling even when they are not capped.
+func withUpstreamQueue(next http.RoundTripper) http.RoundTripper {
+	return &queueTransport{next: next, queues: upstreamQueues}
+}
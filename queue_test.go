@@ -0,0 +1,97 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestUpstreamQueue(t *testing.T) {
+	q := newUpstreamQueue("rake", 1, 2)
+	ctx := context.Background()
+	assert.NoError(t, q.acquire(ctx))
+
+	order := make(chan int, 2)
+	for i := 1; i <= 2; i++ {
+		i := i
+		go func() {
+			if assert.NoError(t, q.acquire(ctx)) {
+				order <- i
+				q.release()
+			}
+		}()
+		// let each caller queue before the next
+		assert.Eventually(t, func() bool {
+			q.mu.Lock()
+			defer q.mu.Unlock()
+			return q.waiting.Len() == i
+		}, time.Second, time.Millisecond)
+	}
+
+	assert.True(t, errors.Is(q.acquire(ctx), errUpstreamQueueFull), "backlog is capped")
+
+	q.release()
+	assert.Equal(t, 1, <-order)
+	assert.Equal(t, 2, <-order)
+	assert.Eventually(t, func() bool {
+		q.mu.Lock()
+		defer q.mu.Unlock()
+		return q.inFlight == 0
+	}, time.Second, time.Millisecond)
+}
+
+func TestUpstreamQueueCancelled(t *testing.T) {
+	q := newUpstreamQueue("rake", 1, 1)
+	assert.NoError(t, q.acquire(context.Background()))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	assert.Equal(t, context.DeadlineExceeded, q.acquire(ctx))
+	assert.Equal(t, 0, q.waiting.Len())
+
+	q.release()
+	assert.Equal(t, 0, q.inFlight)
+}
+
+func TestQueueTransport(t *testing.T) {
+	release := make(chan struct{})
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer upstream.Close()
+	defer func(url string) { urlRake = url }(urlRake)
+	urlRake = upstream.URL
+
+	transport := &queueTransport{next: http.DefaultTransport, queues: newQueueSet(1, 0)}
+	client := &http.Client{Transport: transport}
+
+	done := make(chan error)
+	go func() {
+		resp, err := client.Get(upstream.URL + "/keywords")
+		if err == nil {
+			resp.Body.Close()
+		}
+		done <- err
+	}()
+	assert.Eventually(t, func() bool {
+		q := transport.queues.queue("rake")
+		q.mu.Lock()
+		defer q.mu.Unlock()
+		return q.inFlight == 1
+	}, time.Second, time.Millisecond)
+
+	_, err := client.Get(upstream.URL + "/keywords")
+	if assert.Error(t, err) {
+		assert.Equal(t, http.StatusServiceUnavailable, upstreamError(err).Status)
+	}
+
+	close(release)
+	assert.NoError(t, <-done)
+	assert.Equal(t, 0, transport.queues.queue("rake").inFlight)
+}
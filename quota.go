@@ -0,0 +1,428 @@
+// author: Gary A. Stafford
+// site: https://programmaticponderings.com
+// license: MIT License
+// purpose: NLP microservices: nlp-client per API key quotas
+// modified: 2026-10-14
+
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/labstack/echo/v4"
+)
+
+var (
+	// 0 leaves a quota unlimited
+	quotaDailyRequests     = getEnv("QUOTA_DAILY_REQUESTS", "0")
+	quotaDailyCharacters   = getEnv("QUOTA_DAILY_CHARACTERS", "0")
+	quotaMonthlyRequests   = getEnv("QUOTA_MONTHLY_REQUESTS", "0")
+	quotaMonthlyCharacters = getEnv("QUOTA_MONTHLY_CHARACTERS", "0")
+	// "memory", or "dynamodb://table" to keep the counters across restarts
+	quotaStoreURL = getEnv("QUOTA_STORE", "memory")
+)
+
+const (
+	headerQuotaLimit     = "X-Quota-Limit"
+	headerQuotaRemaining = "X-Quota-Remaining"
+	headerQuotaReset     = "X-Quota-Reset"
+)
+
+// quotaUsage is what one API key has used of a quota period.
+type quotaUsage struct {
+	Requests   int64
+	Characters int64
+}
+
+// quotaStore keeps the usage counters of every key and period.
+type quotaStore interface {
+	Usage(ctx context.Context, counter string) (quotaUsage, error)
+	// Add adds to a counter, which may be dropped once it expires
+	Add(ctx context.Context, counter string, usage quotaUsage, expires time.Time) error
+}
+
+// quotaPeriod is a calendar day or month, in UTC.
+type quotaPeriod struct {
+	name       string
+	requests   int64
+	characters int64
+}
+
+// bounds returns the id of the period now falls in and when it resets.
+func (p quotaPeriod) bounds(now time.Time) (string, time.Time) {
+	now = now.UTC()
+	if p.name == "monthly" {
+		start := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC)
+		return start.Format("2006-01"), start.AddDate(0, 1, 0)
+	}
+	start := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+
+	return start.Format("2006-01-02"), start.AddDate(0, 0, 1)
+}
+
+type quotaEnforcer struct {
+	store   quotaStore
+	periods []quotaPeriod
+	now     func() time.Time
+}
+
+func newQuotaEnforcer(store quotaStore) *quotaEnforcer {
+	q := &quotaEnforcer{store: store, now: time.Now}
+	for _, p := range []quotaPeriod{
+		{"daily", int64(envInt(quotaDailyRequests, 0)), int64(envInt(quotaDailyCharacters, 0))},
+		{"monthly", int64(envInt(quotaMonthlyRequests, 0)), int64(envInt(quotaMonthlyCharacters, 0))},
+	} {
+		if p.requests > 0 || p.characters > 0 {
+			q.periods = append(q.periods, p)
+		}
+	}
+
+	return q
+}
+
+// keyID identifies an API key in the store without persisting the key itself.
+func keyID(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:8])
+}
+
+// quotaState is the most constrained quota of a key, reported in the quota headers.
+type quotaState struct {
+	limit     int64
+	remaining int64
+	reset     time.Time
+}
+
+// check returns the state of the key's most constrained quota, and whether any quota is exhausted.
+func (q *quotaEnforcer) check(ctx context.Context, key string) (*quotaState, bool, error) {
+	now := q.now()
+	var tightest *quotaState
+	for _, p := range q.periods {
+		id, reset := p.bounds(now)
+		usage, err := q.store.Usage(ctx, keyID(key)+"/"+p.name+"/"+id)
+		if err != nil {
+			return nil, false, err
+		}
+		for _, quota := range []struct{ limit, used int64 }{
+			{p.requests, usage.Requests},
+			{p.characters, usage.Characters},
+		} {
+			if quota.limit <= 0 {
+				continue
+			}
+			state := &quotaState{limit: quota.limit, remaining: quota.limit - quota.used, reset: reset}
+			if state.remaining < 0 {
+				state.remaining = 0
+			}
+			if state.remaining == 0 {
+				return state, true, nil
+			}
+			if tightest == nil || state.remaining*tightest.limit < tightest.remaining*state.limit {
+				tightest = state
+			}
+		}
+	}
+
+	return tightest, false, nil
+}
+
+func (q *quotaEnforcer) charge(ctx context.Context, key string, usage quotaUsage) error {
+	now := q.now()
+	for _, p := range q.periods {
+		id, reset := p.bounds(now)
+		if err := q.store.Add(ctx, keyID(key)+"/"+p.name+"/"+id, usage, reset); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func setQuotaHeaders(header http.Header, state *quotaState, now time.Time) {
+	header.Set(headerQuotaLimit, strconv.FormatInt(state.limit, 10))
+	header.Set(headerQuotaRemaining, strconv.FormatInt(state.remaining, 10))
+	header.Set(headerQuotaReset, strconv.FormatInt(int64(state.reset.Sub(now).Seconds()), 10))
+}
+
+// characterCounter counts the UTF-8 characters read from a request body.
+type characterCounter struct {
+	io.ReadCloser
+	characters int64
+}
+
+func (r *characterCounter) Read(p []byte) (int, error) {
+	n, err := r.ReadCloser.Read(p)
+	for _, b := range p[:n] {
+		// every character has exactly one byte that is not a continuation byte
+		if b&0xC0 != 0x80 {
+			r.characters++
+		}
+	}
+
+	return n, err
+}
+
+// quotaMiddleware refuses keys that have exhausted a quota with 429, and
+// charges each request and the characters of its body once it completes.
+// Quotas are checked as of the start of a request, so the request that
+// crosses a limit is still served.
+func (q *quotaEnforcer) middleware(next echo.HandlerFunc) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		if len(q.periods) == 0 {
+			return next(c)
+		}
+		req := c.Request()
+		key := req.Header.Get("X-API-Key")
+
+		state, exhausted, err := q.check(req.Context(), key)
+		if err != nil {
+			// an unavailable store must not take the service down with it
+			e.Logger.Errorf("quota check failed: %v", err)
+			return next(c)
+		}
+		if state != nil {
+			setQuotaHeaders(c.Response().Header(), state, q.now())
+		}
+		if exhausted {
+			c.Response().Header().Set("Retry-After", c.Response().Header().Get(headerQuotaReset))
+			quotaErr := newAPIError(http.StatusTooManyRequests, codeQuotaExceeded, "quota exceeded")
+			quotaErr.Retryable = false
+			return quotaErr
+		}
+
+		body := &characterCounter{ReadCloser: req.Body}
+		req.Body = body
+		err = next(c)
+
+		// charge even when the caller has gone away, since the upstreams did the work
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if chargeErr := q.charge(ctx, key, quotaUsage{1, body.characters}); chargeErr != nil {
+			e.Logger.Errorf("quota charge failed: %v", chargeErr)
+		}
+
+		return err
+	}
+}
+
+func quotaMiddleware() echo.MiddlewareFunc {
+	store, err := newQuotaStore(quotaStoreURL)
+	if err != nil {
+		e.Logger.Errorf("quotas disabled: %v", err)
+		return func(next echo.HandlerFunc) echo.HandlerFunc { return next }
+	}
+
+	return newQuotaEnforcer(store).middleware
+}
+
+func newQuotaStore(value string) (quotaStore, error) {
+	switch value {
+	case "memory":
+		return newMemoryQuotaStore(), nil
+	case "redis":
+		backend := sharedRedisBackend()
+		if backend == nil {
+			return nil, errors.New("QUOTA_STORE is redis but REDIS_ADDRS is not set")
+		}
+		return &fallbackQuotaStore{remote: &redisQuotaStore{backend}, local: newMemoryQuotaStore()}, nil
+	}
+	u, err := url.Parse(value)
+	if err != nil {
+		return nil, err
+	}
+	if u.Scheme == "dynamodb" {
+		cfg, err := config.LoadDefaultConfig(context.Background())
+		if err != nil {
+			return nil, err
+		}
+		return &dynamoQuotaStore{client: dynamodb.NewFromConfig(cfg), table: u.Host}, nil
+	}
+
+	return nil, fmt.Errorf("unsupported QUOTA_STORE: %s", value)
+}
+
+// memoryQuotaStore keeps the counters of a single instance until it restarts.
+type memoryQuotaStore struct {
+	mu       sync.Mutex
+	counters map[string]quotaUsage
+	expires  map[string]time.Time
+	now      func() time.Time
+}
+
+func newMemoryQuotaStore() *memoryQuotaStore {
+	return &memoryQuotaStore{counters: map[string]quotaUsage{}, expires: map[string]time.Time{}, now: time.Now}
+}
+
+func (s *memoryQuotaStore) Usage(_ context.Context, counter string) (quotaUsage, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.counters[counter], nil
+}
+
+func (s *memoryQuotaStore) Add(_ context.Context, counter string, usage quotaUsage, expires time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.now()
+	for name, at := range s.expires {
+		if !now.Before(at) {
+			delete(s.counters, name)
+			delete(s.expires, name)
+		}
+	}
+	total := s.counters[counter]
+	total.Requests += usage.Requests
+	total.Characters += usage.Characters
+	s.counters[counter] = total
+	s.expires[counter] = expires
+
+	return nil
+}
+
+// dynamoAPI is the part of the DynamoDB client the quota store uses.
+type dynamoAPI interface {
+	GetItem(ctx context.Context, params *dynamodb.GetItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.GetItemOutput, error)
+	UpdateItem(ctx context.Context, params *dynamodb.UpdateItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.UpdateItemOutput, error)
+}
+
+// dynamoQuotaStore keeps the counters in a DynamoDB table keyed by the string
+// attribute "counter", shared by every instance. Enable TTL on "expires_at"
+// to have DynamoDB drop past periods.
+type dynamoQuotaStore struct {
+	client dynamoAPI
+	table  string
+}
+
+func (s *dynamoQuotaStore) Usage(ctx context.Context, counter string) (quotaUsage, error) {
+	out, err := s.client.GetItem(ctx, &dynamodb.GetItemInput{
+		TableName:      aws.String(s.table),
+		Key:            map[string]types.AttributeValue{"counter": &types.AttributeValueMemberS{Value: counter}},
+		ConsistentRead: aws.Bool(true),
+	})
+	if err != nil {
+		return quotaUsage{}, err
+	}
+
+	var usage quotaUsage
+	if usage.Requests, err = numberAttribute(out.Item, "requests"); err != nil {
+		return quotaUsage{}, err
+	}
+	if usage.Characters, err = numberAttribute(out.Item, "characters"); err != nil {
+		return quotaUsage{}, err
+	}
+
+	return usage, nil
+}
+
+func numberAttribute(item map[string]types.AttributeValue, name string) (int64, error) {
+	value, ok := item[name]
+	if !ok {
+		return 0, nil
+	}
+	n, ok := value.(*types.AttributeValueMemberN)
+	if !ok {
+		return 0, errors.New("quota attribute " + name + " is not a number")
+	}
+
+	return strconv.ParseInt(n.Value, 10, 64)
+}
+
+func (s *dynamoQuotaStore) Add(ctx context.Context, counter string, usage quotaUsage, expires time.Time) error {
+	// ADD is atomic, so concurrent instances never lose each other's updates
+	_, err := s.client.UpdateItem(ctx, &dynamodb.UpdateItemInput{
+		TableName:        aws.String(s.table),
+		Key:              map[string]types.AttributeValue{"counter": &types.AttributeValueMemberS{Value: counter}},
+		UpdateExpression: aws.String("ADD requests :requests, characters :characters SET expires_at = :expires"),
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":requests":   &types.AttributeValueMemberN{Value: strconv.FormatInt(usage.Requests, 10)},
+			":characters": &types.AttributeValueMemberN{Value: strconv.FormatInt(usage.Characters, 10)},
+			":expires":    &types.AttributeValueMemberN{Value: strconv.FormatInt(expires.Unix(), 10)},
+		},
+	})
+
+	return err
+}
+
+// redisQuotaAdd adds to both fields of a counter and sets its expiry in one step.
+const redisQuotaAdd = `redis.call("HINCRBY", KEYS[1], "requests", ARGV[1])
+redis.call("HINCRBY", KEYS[1], "characters", ARGV[2])
+redis.call("EXPIREAT", KEYS[1], ARGV[3])
+return 1`
+
+// redisQuotaStore keeps the counters as Redis hashes, shared by every instance.
+type redisQuotaStore struct {
+	backend *redisBackend
+}
+
+func (s *redisQuotaStore) Usage(ctx context.Context, counter string) (quotaUsage, error) {
+	var usage quotaUsage
+	err := s.backend.do(ctx, func(ctx context.Context) error {
+		values, err := s.backend.client.HMGet(ctx, s.backend.key("quota:"+counter), "requests", "characters").Result()
+		if err != nil {
+			return err
+		}
+		fields := []*int64{&usage.Requests, &usage.Characters}
+		for i, value := range values {
+			if value == nil {
+				continue
+			}
+			n, err := strconv.ParseInt(fmt.Sprint(value), 10, 64)
+			if err != nil {
+				return err
+			}
+			*fields[i] = n
+		}
+		return nil
+	})
+
+	return usage, err
+}
+
+func (s *redisQuotaStore) Add(ctx context.Context, counter string, usage quotaUsage, expires time.Time) error {
+	return s.backend.do(ctx, func(ctx context.Context) error {
+		keys := []string{s.backend.key("quota:" + counter)}
+		return s.backend.client.Eval(ctx, redisQuotaAdd, keys, usage.Requests, usage.Characters, expires.Unix()).Err()
+	})
+}
+
+// fallbackQuotaStore counts in a shared store, and in a local one that
+// answers in its place while the shared store is unavailable, so quotas
+// degrade to per-instance rather than off.
+type fallbackQuotaStore struct {
+	remote quotaStore
+	local  quotaStore
+}
+
+func (s *fallbackQuotaStore) Usage(ctx context.Context, counter string) (quotaUsage, error) {
+	usage, err := s.remote.Usage(ctx, counter)
+	if err != nil {
+		return s.local.Usage(ctx, counter)
+	}
+
+	return usage, nil
+}
+
+func (s *fallbackQuotaStore) Add(ctx context.Context, counter string, usage quotaUsage, expires time.Time) error {
+	if err := s.local.Add(ctx, counter, usage, expires); err != nil {
+		return err
+	}
+	// the local count stands in for the shared one while it is down
+	_ = s.remote.Add(ctx, counter, usage, expires)
+
+	return nil
+}
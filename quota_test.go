@@ -0,0 +1,131 @@
+package main
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestQuotaPeriodBounds(t *testing.T) {
+	now := time.Date(2026, 12, 31, 18, 30, 0, 0, time.UTC)
+
+	id, reset := quotaPeriod{name: "daily"}.bounds(now)
+	assert.Equal(t, "2026-12-31", id)
+	assert.Equal(t, time.Date(2027, 1, 1, 0, 0, 0, 0, time.UTC), reset)
+
+	id, reset = quotaPeriod{name: "monthly"}.bounds(now)
+	assert.Equal(t, "2026-12", id)
+	assert.Equal(t, time.Date(2027, 1, 1, 0, 0, 0, 0, time.UTC), reset)
+}
+
+func TestCharacterCounter(t *testing.T) {
+	body := &characterCounter{ReadCloser: ioutil.NopCloser(strings.NewReader(`{"text": "naïve café 日本"}`))}
+	_, _ = body.Read(make([]byte, 7))
+	_, _ = body.Read(make([]byte, 64))
+
+	assert.Equal(t, int64(len([]rune(`{"text": "naïve café 日本"}`))), body.characters)
+}
+
+func TestQuotaMiddleware(t *testing.T) {
+	now := time.Date(2026, 10, 14, 23, 0, 0, 0, time.UTC)
+	q := &quotaEnforcer{
+		store:   newMemoryQuotaStore(),
+		periods: []quotaPeriod{{name: "daily", requests: 2, characters: 100}},
+		now:     func() time.Time { return now },
+	}
+	handler := q.middleware(func(c echo.Context) error {
+		_, err := ioutil.ReadAll(c.Request().Body)
+		if err != nil {
+			return err
+		}
+		return c.NoContent(http.StatusOK)
+	})
+
+	serve := func(key, body string) (*httptest.ResponseRecorder, error) {
+		req := httptest.NewRequest(http.MethodPost, "/keywords", strings.NewReader(body))
+		req.Header.Set("X-API-Key", key)
+		w := httptest.NewRecorder()
+		return w, handler(e.NewContext(req, w))
+	}
+
+	w, err := serve("alpha", `{"text": "Hello"}`)
+	if assert.NoError(t, err) {
+		assert.Equal(t, "2", w.Header().Get(headerQuotaLimit))
+		assert.Equal(t, "2", w.Header().Get(headerQuotaRemaining))
+		assert.Equal(t, "3600", w.Header().Get(headerQuotaReset))
+	}
+	w, err = serve("alpha", `{"text": "Hello"}`)
+	if assert.NoError(t, err) {
+		assert.Equal(t, "1", w.Header().Get(headerQuotaRemaining))
+	}
+
+	w, err = serve("alpha", `{"text": "Hello"}`)
+	if assert.Error(t, err) {
+		ae := err.(*apiError)
+		assert.Equal(t, http.StatusTooManyRequests, ae.Status)
+		assert.Equal(t, codeQuotaExceeded, ae.Code)
+		assert.False(t, ae.Retryable)
+		assert.Equal(t, "0", w.Header().Get(headerQuotaRemaining))
+		assert.Equal(t, "3600", w.Header().Get("Retry-After"))
+	}
+
+	// keys are counted separately, and the characters quota applies too
+	_, err = serve("bravo", `{"text": "`+strings.Repeat("x", 120)+`"}`)
+	assert.NoError(t, err)
+	_, err = serve("bravo", `{"text": "again"}`)
+	if assert.Error(t, err) {
+		assert.Equal(t, codeQuotaExceeded, err.(*apiError).Code)
+	}
+
+	// the next day starts afresh
+	now = now.Add(2 * time.Hour)
+	_, err = serve("alpha", `{"text": "Hello"}`)
+	assert.NoError(t, err)
+}
+
+type fakeDynamo struct {
+	get    *dynamodb.GetItemInput
+	update *dynamodb.UpdateItemInput
+	item   map[string]types.AttributeValue
+}
+
+func (f *fakeDynamo) GetItem(_ context.Context, params *dynamodb.GetItemInput, _ ...func(*dynamodb.Options)) (*dynamodb.GetItemOutput, error) {
+	f.get = params
+	return &dynamodb.GetItemOutput{Item: f.item}, nil
+}
+
+func (f *fakeDynamo) UpdateItem(_ context.Context, params *dynamodb.UpdateItemInput, _ ...func(*dynamodb.Options)) (*dynamodb.UpdateItemOutput, error) {
+	f.update = params
+	return &dynamodb.UpdateItemOutput{}, nil
+}
+
+func TestDynamoQuotaStore(t *testing.T) {
+	client := &fakeDynamo{item: map[string]types.AttributeValue{
+		"counter":  &types.AttributeValueMemberS{Value: "k/daily/2026-10-14"},
+		"requests": &types.AttributeValueMemberN{Value: "42"},
+	}}
+	store := &dynamoQuotaStore{client: client, table: "NLPQuotas"}
+
+	usage, err := store.Usage(context.Background(), "k/daily/2026-10-14")
+	if assert.NoError(t, err) {
+		assert.Equal(t, quotaUsage{Requests: 42}, usage)
+		assert.Equal(t, "NLPQuotas", *client.get.TableName)
+	}
+
+	expires := time.Date(2026, 10, 15, 0, 0, 0, 0, time.UTC)
+	if assert.NoError(t, store.Add(context.Background(), "k/daily/2026-10-14", quotaUsage{1, 17}, expires)) {
+		values := client.update.ExpressionAttributeValues
+		assert.Equal(t, "1", values[":requests"].(*types.AttributeValueMemberN).Value)
+		assert.Equal(t, "17", values[":characters"].(*types.AttributeValueMemberN).Value)
+		assert.Equal(t, "1792022400", values[":expires"].(*types.AttributeValueMemberN).Value)
+	}
+}
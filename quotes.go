@@ -0,0 +1,225 @@
+// author: Gary A. Stafford
+// site: https://programmaticponderings.com
+// license: MIT License
+// purpose: NLP microservices: nlp-client quote extraction and attribution
+// modified: 2026-10-14
+
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+)
+
+var (
+	// fewest words a quoted span needs to be a quote rather than a scare quote or title
+	quoteMinWords = getEnv("QUOTE_MIN_WORDS", "3")
+	// comma-separated entity labels a quote may be attributed to
+	quoteSpeakerLabels = getEnv("QUOTE_SPEAKER_LABELS", "PERSON,ORG")
+)
+
+// quoteAnalyses are run on the text to attribute its quotes.
+var quoteAnalyses = []string{"entities", "sentences"}
+
+// quoteMarks pairs each opening quotation mark with its closing mark.
+var quoteMarks = map[rune]rune{'"': '"', '“': '”', '«': '»', '„': '“'}
+
+// speechWords mark a sentence as attributing a quote to someone.
+var speechWords = map[string]bool{
+	"said": true, "says": true, "say": true, "told": true, "tells": true, "asked": true, "asks": true,
+	"added": true, "adds": true, "stated": true, "states": true, "explained": true, "explains": true,
+	"wrote": true, "writes": true, "replied": true, "noted": true, "argued": true, "claimed": true,
+	"announced": true, "declared": true, "according": true,
+}
+
+// speakerPronouns refer a quote to the speaker of the quote before it.
+var speakerPronouns = map[string]bool{"he": true, "she": true, "they": true}
+
+// quote is a quoted span of the request text, in characters, without its
+// quotation marks, and who it is attributed to: an entity named in its
+// sentence, or the speaker of the quote before it, referred to by a pronoun.
+type quote struct {
+	Quote string `json:"quote"`
+	span
+	Speaker       string `json:"speaker,omitempty"`
+	SpeakerLabel  string `json:"speaker_label,omitempty"`
+	SpeakerOffset *int   `json:"speaker_offset,omitempty"`
+	// "entity" or "pronoun"; empty when the quote is not attributed
+	Attribution string `json:"attribution,omitempty"`
+}
+
+// mention is where an entity occurs in the text, in characters.
+type mention struct {
+	text, label string
+	start, end  int
+}
+
+// quotedSpans finds the spans between matching quotation marks in text, of
+// at least minWords words.
+func quotedSpans(text []rune, minWords int) []quote {
+	quotes := []quote{}
+	for i := 0; i < len(text); i++ {
+		closing, ok := quoteMarks[text[i]]
+		if !ok {
+			continue
+		}
+		end := i + 1
+		for end < len(text) && text[end] != closing {
+			end++
+		}
+		if end == len(text) {
+			break
+		}
+		inner := string(text[i+1 : end])
+		if len(compareWords(inner)) >= minWords {
+			start := i + 1
+			quotes = append(quotes, quote{Quote: inner, span: span{Offset: &start, Length: end - start}})
+		}
+		i = end
+	}
+
+	return quotes
+}
+
+// entityMentions finds every occurrence of the entities with a speaker label.
+func entityMentions(text []rune, entities []v2Item) []mention {
+	labels := map[string]bool{}
+	for _, label := range splitList(quoteSpeakerLabels) {
+		labels[label] = true
+	}
+	var mentions []mention
+	seen := map[string]bool{}
+	for _, entity := range entities {
+		key := strings.ToLower(entity.Text) + "\x00" + entity.Label
+		if !labels[entity.Label] || seen[key] {
+			continue
+		}
+		seen[key] = true
+		needle := foldRunes(entity.Text)
+		for i := indexRunes(text, needle, 0); i >= 0; i = indexRunes(text, needle, i+len(needle)) {
+			mentions = append(mentions, mention{entity.Text, entity.Label, i, i + len(needle)})
+		}
+	}
+
+	return mentions
+}
+
+// attributeQuotes attributes each quote to the speaker mention nearest it in
+// the sentences it spans, outside any quote, when those sentences have a
+// speech word; a pronoun instead of a mention refers to the last speaker.
+func attributeQuotes(text string, quotes []quote, sentences []string, entities []v2Item) {
+	folded := foldRunes(text)
+	// the character ranges of the sentences
+	var bounds [][2]int
+	loc := &locator{text: folded}
+	for _, sentence := range sentences {
+		if s := loc.locate(sentence); s.Offset != nil {
+			bounds = append(bounds, [2]int{*s.Offset, *s.Offset + s.Length})
+		}
+	}
+	quoted := func(at int) bool {
+		for _, q := range quotes {
+			if at >= *q.Offset-1 && at <= *q.Offset+q.Length {
+				return true
+			}
+		}
+		return false
+	}
+	mentions := entityMentions(folded, entities)
+
+	var last *quote
+	for i := range quotes {
+		q := &quotes[i]
+		start, end := *q.Offset, *q.Offset+q.Length
+		// the sentences the quote overlaps, or the quote alone
+		from, to := start, end
+		for _, b := range bounds {
+			if b[0] < end && b[1] > start {
+				if b[0] < from {
+					from = b[0]
+				}
+				if b[1] > to {
+					to = b[1]
+				}
+			}
+		}
+		// the words of those sentences outside any quote
+		var outside []rune
+		for at := from; at < to; at++ {
+			if quoted(at) {
+				outside = append(outside, ' ')
+				continue
+			}
+			outside = append(outside, folded[at])
+		}
+		speech, pronoun := false, false
+		for _, word := range compareWords(string(outside)) {
+			speech = speech || speechWords[word]
+			pronoun = pronoun || speakerPronouns[word]
+		}
+		if !speech {
+			continue
+		}
+
+		best, distance := -1, 0
+		for j, m := range mentions {
+			if m.start < from || m.end > to || quoted(m.start) {
+				continue
+			}
+			d := m.start - end
+			if m.end <= start {
+				d = start - m.end
+			}
+			if best < 0 || d < distance {
+				best, distance = j, d
+			}
+		}
+		switch {
+		case best >= 0:
+			m := mentions[best]
+			offset := m.start
+			q.Speaker, q.SpeakerLabel, q.SpeakerOffset, q.Attribution = m.text, m.label, &offset, "entity"
+		case pronoun && last != nil:
+			q.Speaker, q.SpeakerLabel, q.SpeakerOffset, q.Attribution = last.Speaker, last.SpeakerLabel, last.SpeakerOffset, "pronoun"
+		default:
+			continue
+		}
+		last = q
+	}
+}
+
+// postQuotes extracts the quotes of a text and attributes them to speakers,
+// with the entities and sentences the upstreams find in it.
+func postQuotes(c echo.Context) error {
+	var body struct {
+		Text string `json:"text"`
+	}
+	if err := json.NewDecoder(c.Request().Body).Decode(&body); err != nil {
+		return validationError(err.Error())
+	}
+
+	quotes := quotedSpans([]rune(body.Text), envInt(quoteMinWords, 3))
+	if len(quotes) == 0 {
+		return c.JSON(http.StatusOK, quotes)
+	}
+	results := runAnalyses(c.Request().Context(), body.Text, quoteAnalyses, c.Request().Header.Get("X-API-Key"))
+	for _, name := range quoteAnalyses {
+		if results[name].Error != nil {
+			return results[name].Error
+		}
+	}
+	var entities []v2Item
+	var sentences []string
+	if err := json.Unmarshal(results["entities"].Result, &entities); err != nil {
+		return internalError(err)
+	}
+	if err := json.Unmarshal(results["sentences"].Result, &sentences); err != nil {
+		return internalError(err)
+	}
+	attributeQuotes(body.Text, quotes, sentences, entities)
+
+	return c.JSON(http.StatusOK, quotes)
+}
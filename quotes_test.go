@@ -0,0 +1,76 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/garystafford/nlp-client/mockupstream"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestQuotedSpans(t *testing.T) {
+	quotes := quotedSpans([]rune(`The “so-called” prize. “One never notices what has been done,” she said. "Unclosed`), 3)
+	if assert.Len(t, quotes, 1) {
+		assert.Equal(t, "One never notices what has been done,", quotes[0].Quote)
+		assert.Equal(t, 24, *quotes[0].Offset)
+		assert.Equal(t, 37, quotes[0].Length)
+	}
+}
+
+func TestAttributeQuotes(t *testing.T) {
+	text := `"Nothing in life is to be feared," Marie Curie said. ` +
+		`"It is only to be understood," she added. ` +
+		`The Nobel Committee praised "her pioneering work on radium."`
+	sentences := []string{
+		`"Nothing in life is to be feared," Marie Curie said.`,
+		`"It is only to be understood," she added.`,
+		`The Nobel Committee praised "her pioneering work on radium."`,
+	}
+	entities := []v2Item{{Text: "Marie Curie", Label: "PERSON"}, {Text: "Nobel Committee", Label: "ORG"}}
+
+	quotes := quotedSpans([]rune(text), 3)
+	attributeQuotes(text, quotes, sentences, entities)
+	if assert.Len(t, quotes, 3) {
+		assert.Equal(t, "Marie Curie", quotes[0].Speaker)
+		assert.Equal(t, "entity", quotes[0].Attribution)
+		assert.Equal(t, 35, *quotes[0].SpeakerOffset)
+		assert.Equal(t, "Marie Curie", quotes[1].Speaker)
+		assert.Equal(t, "pronoun", quotes[1].Attribution)
+		assert.Empty(t, quotes[2].Speaker, "praised is not a speech word")
+	}
+}
+
+func TestPostQuotes(t *testing.T) {
+	prose := mockupstream.NewProse()
+	defer prose.Close()
+	defer func(p string) { urlProse = p }(urlProse)
+	urlProse = prose.URL
+	text := `Marie Curie said, "One never notices what has been done."`
+	prose.SetFixture("/sentences", mockupstream.Fixture{Status: http.StatusOK, Body: `["` + strings.ReplaceAll(text, `"`, `\"`) + `"]`})
+
+	post := func(text string) (*httptest.ResponseRecorder, error) {
+		body, _ := json.Marshal(map[string]string{"text": text})
+		req := httptest.NewRequest(http.MethodPost, "/quotes", strings.NewReader(string(body)))
+		w := httptest.NewRecorder()
+		return w, postQuotes(e.NewContext(req, w))
+	}
+
+	w, err := post(text)
+	if assert.NoError(t, err) {
+		var quotes []quote
+		if assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &quotes)) && assert.Len(t, quotes, 1) {
+			assert.Equal(t, "One never notices what has been done.", quotes[0].Quote)
+			assert.Equal(t, "Marie Curie", quotes[0].Speaker)
+			assert.Equal(t, "PERSON", quotes[0].SpeakerLabel)
+		}
+	}
+
+	w, err = post("Marie Curie discovered radium.")
+	if assert.NoError(t, err) {
+		assert.Equal(t, "[]\n", w.Body.String())
+		assert.Len(t, prose.Requests(), 2, "a text without quotes is not analyzed")
+	}
+}
@@ -0,0 +1,156 @@
+// author: Gary A. Stafford
+// site: https://programmaticponderings.com
+// license: MIT License
+// purpose: NLP microservices: nlp-client readability scoring
+// modified: 2026-10-14
+
+package main
+
+import (
+	"encoding/json"
+	"math"
+	"net/http"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/labstack/echo/v4"
+)
+
+var (
+	readabilitySentences = regexp.MustCompile(`[^.!?]+(?:[.!?]+|$)`)
+	readabilityWords     = regexp.MustCompile(`[\p{L}\p{N}]+(?:['’]\p{L}+)*`)
+	vowelGroups          = regexp.MustCompile(`[aeiouy]+`)
+)
+
+// beVerbs and irregularParticiples mark a passive clause, as in "was written".
+var beVerbs = map[string]bool{"am": true, "is": true, "are": true, "was": true, "were": true, "be": true, "been": true, "being": true}
+
+var irregularParticiples = map[string]bool{
+	"begun": true, "bought": true, "built": true, "brought": true, "caught": true, "chosen": true, "done": true,
+	"drawn": true, "driven": true, "eaten": true, "fallen": true, "felt": true, "found": true, "forgotten": true,
+	"given": true, "gone": true, "held": true, "hidden": true, "hit": true, "kept": true, "known": true, "laid": true,
+	"led": true, "left": true, "lost": true, "made": true, "meant": true, "met": true, "paid": true, "put": true,
+	"read": true, "run": true, "said": true, "seen": true, "sent": true, "set": true, "shown": true, "shut": true,
+	"sold": true, "spent": true, "spoken": true, "stolen": true, "taken": true, "taught": true, "told": true,
+	"thought": true, "thrown": true, "understood": true, "won": true, "worn": true, "written": true,
+}
+
+// readability scores how hard a text is to read. Flesch reading ease runs
+// from about 0, hard, to 100, easy; the grades are the years of US schooling
+// a reader needs.
+type readability struct {
+	Sentences          int     `json:"sentences"`
+	Words              int     `json:"words"`
+	Syllables          int     `json:"syllables"`
+	FleschReadingEase  float64 `json:"flesch_reading_ease"`
+	FleschKincaidGrade float64 `json:"flesch_kincaid_grade"`
+	SMOG               float64 `json:"smog"`
+	GunningFog         float64 `json:"gunning_fog"`
+	// share of the sentences with a passive clause
+	PassiveRatio float64 `json:"passive_ratio"`
+	// in characters
+	AvgWordLength float64 `json:"avg_word_length"`
+	// in words
+	AvgSentenceLength float64 `json:"avg_sentence_length"`
+}
+
+// syllables estimates the syllables of an English word by its vowel groups,
+// without a silent final "e" or "ed".
+func syllables(word string) int {
+	word = strings.ToLower(word)
+	if utf8.RuneCountInString(word) <= 3 {
+		return 1
+	}
+	switch {
+	case strings.HasSuffix(word, "ed") && !strings.HasSuffix(word, "ted") && !strings.HasSuffix(word, "ded"):
+		word = strings.TrimSuffix(word, "ed")
+	case strings.HasSuffix(word, "e") && !strings.HasSuffix(word, "le"):
+		word = strings.TrimSuffix(word, "e")
+	}
+	if n := len(vowelGroups.FindAllString(word, -1)); n > 0 {
+		return n
+	}
+
+	return 1
+}
+
+// passive reports whether a sentence's words have a form of "be" followed,
+// past an adverb at most, by a past participle.
+func passive(words []string) bool {
+	for i := 0; i+1 < len(words); i++ {
+		if !beVerbs[words[i]] {
+			continue
+		}
+		next := words[i+1]
+		if strings.HasSuffix(next, "ly") && i+2 < len(words) {
+			next = words[i+2]
+		}
+		if irregularParticiples[next] || len(next) > 4 && strings.HasSuffix(next, "ed") {
+			return true
+		}
+	}
+
+	return false
+}
+
+func round2(x float64) float64 {
+	return math.Round(x*100) / 100
+}
+
+// scoreReadability scores text, or returns nil when it has no words.
+func scoreReadability(text string) *readability {
+	r := &readability{}
+	letters, polysyllables, passives := 0, 0, 0
+	for _, sentence := range readabilitySentences.FindAllString(text, -1) {
+		words := readabilityWords.FindAllString(strings.ToLower(sentence), -1)
+		if len(words) == 0 {
+			continue
+		}
+		r.Sentences++
+		r.Words += len(words)
+		for _, word := range words {
+			n := syllables(word)
+			r.Syllables += n
+			if n >= 3 {
+				polysyllables++
+			}
+			letters += utf8.RuneCountInString(word)
+		}
+		if passive(words) {
+			passives++
+		}
+	}
+	if r.Words == 0 {
+		return nil
+	}
+
+	wordsPerSentence := float64(r.Words) / float64(r.Sentences)
+	syllablesPerWord := float64(r.Syllables) / float64(r.Words)
+	r.FleschReadingEase = round2(206.835 - 1.015*wordsPerSentence - 84.6*syllablesPerWord)
+	r.FleschKincaidGrade = round2(0.39*wordsPerSentence + 11.8*syllablesPerWord - 15.59)
+	r.SMOG = round2(1.043*math.Sqrt(float64(polysyllables)*30/float64(r.Sentences)) + 3.1291)
+	r.GunningFog = round2(0.4 * (wordsPerSentence + 100*float64(polysyllables)/float64(r.Words)))
+	r.PassiveRatio = round2(float64(passives) / float64(r.Sentences))
+	r.AvgWordLength = round2(float64(letters) / float64(r.Words))
+	r.AvgSentenceLength = round2(wordsPerSentence)
+
+	return r
+}
+
+// postReadability scores the readability of an English text locally, with
+// no upstream.
+func postReadability(c echo.Context) error {
+	var body struct {
+		Text string `json:"text"`
+	}
+	if err := json.NewDecoder(c.Request().Body).Decode(&body); err != nil {
+		return validationError(err.Error())
+	}
+	r := scoreReadability(body.Text)
+	if r == nil {
+		return validationError("text has no words")
+	}
+
+	return c.JSON(http.StatusOK, r)
+}
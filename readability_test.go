@@ -0,0 +1,46 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSyllables(t *testing.T) {
+	for word, want := range map[string]int{"cat": 1, "table": 2, "jumped": 1, "wanted": 2, "beautiful": 3, "readability": 5} {
+		assert.Equal(t, want, syllables(word), word)
+	}
+}
+
+func TestPassive(t *testing.T) {
+	assert.True(t, passive(strings.Fields("the report was written by the committee")))
+	assert.True(t, passive(strings.Fields("it is widely believed")))
+	assert.False(t, passive(strings.Fields("the committee wrote the report")))
+}
+
+func TestScoreReadability(t *testing.T) {
+	r := scoreReadability("The cat sat on the mat. The report was written by the committee.")
+	assert.Equal(t, &readability{
+		Sentences: 2, Words: 13, Syllables: 17,
+		FleschReadingEase: 89.61, FleschKincaidGrade: 2.38, SMOG: 7.17, GunningFog: 5.68,
+		PassiveRatio: 0.5, AvgWordLength: 3.85, AvgSentenceLength: 6.5,
+	}, r)
+	assert.Nil(t, scoreReadability(" ... "))
+}
+
+func TestPostReadability(t *testing.T) {
+	post := func(body string) (*httptest.ResponseRecorder, error) {
+		req := httptest.NewRequest(http.MethodPost, "/readability", strings.NewReader(body))
+		w := httptest.NewRecorder()
+		return w, postReadability(e.NewContext(req, w))
+	}
+	w, err := post(`{"text": "Short words help."}`)
+	if assert.NoError(t, err) {
+		assert.Contains(t, w.Body.String(), `"sentences":1`)
+	}
+	_, err = post(`{"text": ""}`)
+	assert.Error(t, err)
+}
@@ -0,0 +1,232 @@
+// author: Gary A. Stafford
+// site: https://programmaticponderings.com
+// license: MIT License
+// purpose: NLP microservices: nlp-client record processing and provenance
+// modified: 2026-10-14
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/labstack/echo/v4"
+)
+
+// "on" to store a "provenance" field with each record, naming the client
+// version, the pipeline that processed the record, and the providers that
+// contributed to it
+var recordProvenance = getEnv("RECORD_PROVENANCE", "off")
+
+// record is a /record request being processed before it is stored.
+type record struct {
+	fields map[string]json.RawMessage
+	text   string
+}
+
+type transformedKey struct{}
+
+// recordStage processes a record, when enabled, before it is stored.
+type recordStage struct {
+	enabled func() bool
+	apply   func(c echo.Context, r *record) error
+}
+
+// recordStages run in order over every /record request; the body of a
+// request no stage is enabled for is stored as it was sent. The purpose is
+// checked first, before any other stage runs for a record that is refused;
+// the request transforms next, so the later stages see the text as stored,
+// anonymization once nothing else needs the text, and encryption last, once
+// nothing needs the fields in plaintext.
+var recordStages = []recordStage{
+	{func() bool { return recordRequirePurpose == "on" || recordPurposes != "" }, checkPurpose},
+	{func() bool { return len(requestTransformsFor("/record")) > 0 }, transformRecord},
+	{func() bool { return moderateRecord != "off" && urlModerate != "" }, screenRecord},
+	{func() bool { return alertRecord == "on" && len(alerts) > 0 }, tagRecord},
+	{func() bool { return len(anonymize) > 0 }, anonymizeRecord},
+	{func() bool { return recordProvenance == "on" }, stampProvenance},
+	{func() bool { return recordEncryption != "off" }, encryptRecord},
+}
+
+// processRecord runs the enabled record stages over the body of a /record
+// request, replacing it with the processed record.
+func processRecord(c echo.Context) error {
+	stages := enabledStages()
+	if len(stages) == 0 {
+		return nil
+	}
+
+	req := c.Request()
+	r := &record{}
+	if err := json.NewDecoder(req.Body).Decode(&r.fields); err != nil {
+		return validationError(err.Error())
+	}
+	if raw, ok := r.fields["text"]; ok {
+		if err := json.Unmarshal(raw, &r.text); err != nil {
+			return validationError(err.Error())
+		}
+	}
+	if err := applyStages(c, r, stages); err != nil {
+		return err
+	}
+
+	body, err := json.Marshal(r.fields)
+	if err != nil {
+		return internalError(err)
+	}
+	req.Body = ioutil.NopCloser(bytes.NewReader(body))
+	req.ContentLength = int64(len(body))
+	c.SetRequest(req.WithContext(context.WithValue(req.Context(), transformedKey{}, true)))
+
+	return nil
+}
+
+func enabledStages() []recordStage {
+	var stages []recordStage
+	for _, stage := range recordStages {
+		if stage.enabled() {
+			stages = append(stages, stage)
+		}
+	}
+
+	return stages
+}
+
+func applyStages(c echo.Context, r *record, stages []recordStage) error {
+	for _, stage := range stages {
+		if err := stage.apply(c, r); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// storeRecord stores text, with any other fields, as a record on behalf of a
+// route other than /record, through the same stages, to the same record store.
+func storeRecord(c echo.Context, text string, fields map[string]interface{}) error {
+	target, _, err := recordTarget(c.Request().Header)
+	if err != nil {
+		return err
+	}
+	r := &record{fields: map[string]json.RawMessage{}, text: text}
+	for name, value := range fields {
+		if err := r.set(name, value); err != nil {
+			return err
+		}
+	}
+	if err := r.set("text", text); err != nil {
+		return err
+	}
+	if err := applyStages(c, r, enabledStages()); err != nil {
+		return err
+	}
+
+	key := c.Request().Header.Get("X-API-Key")
+	if _, err := postUpstreamJSON(c.Request().Context(), target+"/record", r.fields, key); err != nil {
+		return upstreamError(err)
+	}
+
+	return nil
+}
+
+// textTransformed reports whether the request transforms have already been
+// applied to the body of the request with ctx.
+func textTransformed(ctx context.Context) bool {
+	transformed, _ := ctx.Value(transformedKey{}).(bool)
+	return transformed
+}
+
+func transformRecord(c echo.Context, r *record) error {
+	text, err := transformText("/record", r.text)
+	if err != nil {
+		return validationError(err.Error())
+	}
+	r.text = text
+
+	return r.set("text", text)
+}
+
+// set stores a field of the record.
+func (r *record) set(name string, value interface{}) error {
+	raw, err := json.Marshal(value)
+	if err != nil {
+		return internalError(err)
+	}
+	r.fields[name] = raw
+
+	return nil
+}
+
+type provenance struct {
+	ClientVersion string `json:"client_version"`
+	// hash of the configuration of the record pipeline, which changes with any
+	// change to the stages run or how they are configured
+	Pipeline  string   `json:"pipeline"`
+	Providers []string `json:"providers"`
+	// the residency region of the record store written to, for tenants with one
+	Region string `json:"region,omitempty"`
+	// the ID of the request that stored the record, which feedback on it refers to
+	RequestID string `json:"request_id,omitempty"`
+	// the ID of the batch or corpus job, or the caller's own, the record was stored for
+	JobID      string    `json:"job_id,omitempty"`
+	RecordedAt time.Time `json:"recorded_at"`
+}
+
+// recordProviders returns the providers contributing fields to records.
+func recordProviders() []string {
+	providers := map[string]bool{"dynamo-app": true}
+	if moderateRecord != "off" && urlModerate != "" {
+		providers[providerFor("/moderate")] = true
+	}
+	if alertRecord == "on" {
+		for _, rule := range alerts {
+			providers[providerFor(rule.analysis)] = true
+		}
+	}
+	if len(anonymize) > 0 {
+		for _, analysis := range anonymizedAnalyses {
+			providers[providerFor(analysis)] = true
+		}
+	}
+	if recordEncryption == "kms" {
+		providers["kms"] = true
+	}
+	names := make([]string, 0, len(providers))
+	for name := range providers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	return names
+}
+
+func recordPipeline() string {
+	return hashHex(
+		version,
+		moderateRecord, moderateThreshold,
+		alertRecord, alertRules,
+		recordAnonymize, recordRequirePurpose, recordPurposes,
+		recordEncryption, recordKMSKeyID, recordEncryptedFields,
+		strings.Join(requestTransformsFor("/record"), ","),
+	)[:16]
+}
+
+func stampProvenance(c echo.Context, r *record) error {
+	_, region, _ := recordTarget(c.Request().Header)
+
+	return r.set("provenance", provenance{
+		ClientVersion: version,
+		Pipeline:      recordPipeline(),
+		Providers:     recordProviders(),
+		Region:        region,
+		RequestID:     requestID(c),
+		JobID:         jobID(c.Request().Context()),
+		RecordedAt:    time.Now().UTC(),
+	})
+}
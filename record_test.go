@@ -0,0 +1,73 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPutDynamoProvenance(t *testing.T) {
+	var records []map[string]interface{}
+	_, dynamo := newModerationUpstreams(t, &records)
+	defer dynamo.Close()
+	defer func(url, mode string) { urlDynamo, recordProvenance = url, mode }(urlDynamo, recordProvenance)
+	urlDynamo = dynamo.URL
+
+	record := func() {
+		req := httptest.NewRequest(http.MethodPost, "/record", strings.NewReader(`{"text": "Marie Curie", "id": 7}`))
+		req.Header.Set("Baggage", baggageJobID+"=job-7")
+		c := e.NewContext(req, httptest.NewRecorder())
+		assert.NoError(t, extractBaggage()(func(echo.Context) error { return nil })(c))
+		c.SetPath("/record")
+		assert.NoError(t, putDynamo(c))
+	}
+
+	recordProvenance = "off"
+	record()
+	recordProvenance = "on"
+	record()
+	if assert.Len(t, records, 2) {
+		assert.NotContains(t, records[0], "provenance")
+		stamped, ok := records[1]["provenance"].(map[string]interface{})
+		if assert.True(t, ok) {
+			assert.Equal(t, version, stamped["client_version"])
+			assert.Equal(t, recordPipeline(), stamped["pipeline"])
+			assert.Equal(t, []interface{}{"dynamo-app"}, stamped["providers"])
+			assert.Equal(t, "job-7", stamped["job_id"], "the job of the record's baggage")
+			assert.NotEmpty(t, stamped["recorded_at"])
+		}
+		assert.Equal(t, float64(7), records[1]["id"])
+	}
+}
+
+func TestRecordPipeline(t *testing.T) {
+	defer func(mode string) { moderateRecord = mode }(moderateRecord)
+	moderateRecord = "off"
+	before := recordPipeline()
+	moderateRecord = "flag"
+	assert.NotEqual(t, before, recordPipeline(), "configuring a stage changes the pipeline hash")
+}
+
+func TestPutDynamoTransformsOnce(t *testing.T) {
+	var records []map[string]interface{}
+	_, dynamo := newModerationUpstreams(t, &records)
+	defer dynamo.Close()
+	defer func(url, mode, routes string) {
+		urlDynamo, recordProvenance, requestTransformRoutes = url, mode, routes
+	}(urlDynamo, recordProvenance, requestTransformRoutes)
+	urlDynamo = dynamo.URL
+	recordProvenance = "on"
+	registerRequestTransform("exclaim", func(text string) (string, error) { return text + "!", nil })
+	requestTransformRoutes = "/record=exclaim"
+
+	req := httptest.NewRequest(http.MethodPost, "/record", strings.NewReader(`{"text": "Marie Curie"}`))
+	c := e.NewContext(req, httptest.NewRecorder())
+	c.SetPath("/record")
+	if assert.NoError(t, putDynamo(c)) && assert.Len(t, records, 1) {
+		assert.Equal(t, "Marie Curie!", records[0]["text"])
+	}
+}
@@ -0,0 +1,117 @@
+// author: Gary A. Stafford
+// site: https://programmaticponderings.com
+// license: MIT License
+// purpose: NLP microservices: nlp-client shared Redis integration
+// modified: 2026-10-14
+
+package main
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+var (
+	// comma-separated host:port addresses; one for a single server, several
+	// for a cluster, or the sentinels when REDIS_MASTER_NAME is set
+	redisAddrs      = getEnv("REDIS_ADDRS", "")
+	redisMasterName = getEnv("REDIS_MASTER_NAME", "")
+	redisPassword   = getEnv("REDIS_PASSWORD", "")
+	redisDB         = getEnv("REDIS_DB", "0")
+	redisTLS        = getEnv("REDIS_TLS", "false")
+	redisKeyPrefix  = getEnv("REDIS_KEY_PREFIX", "nlp-client:")
+	redisTimeout    = getEnv("REDIS_TIMEOUT", "200ms")
+	// how long to stay local-only after Redis fails before trying it again
+	redisRetryAfter = getEnv("REDIS_RETRY_AFTER", "30s")
+
+	errRedisDown = errors.New("redis unavailable")
+
+	redisOnce   sync.Once
+	sharedRedis *redisBackend
+)
+
+// redisAPI is the part of the Redis client used, so tests can fake it.
+type redisAPI interface {
+	Get(ctx context.Context, key string) *redis.StringCmd
+	Set(ctx context.Context, key string, value interface{}, expiration time.Duration) *redis.StatusCmd
+	HMGet(ctx context.Context, key string, fields ...string) *redis.SliceCmd
+	Eval(ctx context.Context, script string, keys []string, args ...interface{}) *redis.Cmd
+}
+
+// redisBackend is the Redis connection shared by the subsystems that keep
+// state across instances. After a failure it reports itself down for a
+// while, so each subsystem falls back to its local state rather than
+// waiting on a dead server with every request.
+type redisBackend struct {
+	client     redisAPI
+	prefix     string
+	timeout    time.Duration
+	retryAfter time.Duration
+	now        func() time.Time
+
+	mu        sync.Mutex
+	downUntil time.Time
+}
+
+func newRedisBackend(client redisAPI, prefix string, timeout, retryAfter time.Duration) *redisBackend {
+	return &redisBackend{client: client, prefix: prefix, timeout: timeout, retryAfter: retryAfter, now: time.Now}
+}
+
+// sharedRedisBackend returns the process's Redis backend, or nil when Redis is not configured.
+func sharedRedisBackend() *redisBackend {
+	redisOnce.Do(func() {
+		if redisAddrs == "" {
+			return
+		}
+		opts := &redis.UniversalOptions{
+			Addrs:        splitList(redisAddrs),
+			MasterName:   redisMasterName,
+			Password:     redisPassword,
+			DB:           envInt(redisDB, 0),
+			DialTimeout:  envDuration(redisTimeout, 200*time.Millisecond),
+			ReadTimeout:  envDuration(redisTimeout, 200*time.Millisecond),
+			WriteTimeout: envDuration(redisTimeout, 200*time.Millisecond),
+		}
+		if envBool(redisTLS) {
+			opts.TLSConfig = &tls.Config{MinVersion: tls.VersionTLS12}
+		}
+		sharedRedis = newRedisBackend(redis.NewUniversalClient(opts), redisKeyPrefix,
+			envDuration(redisTimeout, 200*time.Millisecond), envDuration(redisRetryAfter, 30*time.Second))
+	})
+
+	return sharedRedis
+}
+
+func (r *redisBackend) key(name string) string {
+	return r.prefix + name
+}
+
+// do runs a command unless Redis is down, marking it down if the command fails.
+func (r *redisBackend) do(ctx context.Context, command func(ctx context.Context) error) error {
+	r.mu.Lock()
+	down := r.now().Before(r.downUntil)
+	r.mu.Unlock()
+	if down {
+		return errRedisDown
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, r.timeout)
+	defer cancel()
+	err := command(ctx)
+	if err != nil && !errors.Is(err, redis.Nil) {
+		r.mu.Lock()
+		if !r.now().Before(r.downUntil) {
+			e.Logger.Errorf("redis unavailable, using local state for %s: %v", r.retryAfter, err)
+			metrics.Count("redis_errors_total", 1, nil)
+		}
+		r.downUntil = r.now().Add(r.retryAfter)
+		r.mu.Unlock()
+	}
+
+	return err
+}
@@ -0,0 +1,163 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeRedis keeps strings and hashes in memory, failing every command while down.
+type fakeRedis struct {
+	mu      sync.Mutex
+	strings map[string]string
+	hashes  map[string]map[string]int64
+	down    bool
+	calls   int
+}
+
+func newFakeRedis() *fakeRedis {
+	return &fakeRedis{strings: map[string]string{}, hashes: map[string]map[string]int64{}}
+}
+
+func (f *fakeRedis) fail() error {
+	f.calls++
+	if f.down {
+		return errors.New("connection refused")
+	}
+	return nil
+}
+
+func (f *fakeRedis) Get(_ context.Context, key string) *redis.StringCmd {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := f.fail(); err != nil {
+		return redis.NewStringResult("", err)
+	}
+	value, ok := f.strings[key]
+	if !ok {
+		return redis.NewStringResult("", redis.Nil)
+	}
+	return redis.NewStringResult(value, nil)
+}
+
+func (f *fakeRedis) Set(_ context.Context, key string, value interface{}, _ time.Duration) *redis.StatusCmd {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := f.fail(); err != nil {
+		return redis.NewStatusResult("", err)
+	}
+	f.strings[key] = fmt.Sprintf("%s", value)
+	return redis.NewStatusResult("OK", nil)
+}
+
+func (f *fakeRedis) HMGet(_ context.Context, key string, fields ...string) *redis.SliceCmd {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := f.fail(); err != nil {
+		return redis.NewSliceResult(nil, err)
+	}
+	values := make([]interface{}, len(fields))
+	for i, field := range fields {
+		if n, ok := f.hashes[key][field]; ok {
+			values[i] = fmt.Sprint(n)
+		}
+	}
+	return redis.NewSliceResult(values, nil)
+}
+
+// Eval runs redisQuotaAdd, the only script sent.
+func (f *fakeRedis) Eval(_ context.Context, _ string, keys []string, args ...interface{}) *redis.Cmd {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := f.fail(); err != nil {
+		return redis.NewCmdResult(nil, err)
+	}
+	hash, ok := f.hashes[keys[0]]
+	if !ok {
+		hash = map[string]int64{}
+		f.hashes[keys[0]] = hash
+	}
+	hash["requests"] += args[0].(int64)
+	hash["characters"] += args[1].(int64)
+	return redis.NewCmdResult(int64(1), nil)
+}
+
+func TestRedisBackendDegrades(t *testing.T) {
+	now := time.Date(2026, 10, 14, 12, 0, 0, 0, time.UTC)
+	fake := newFakeRedis()
+	backend := newRedisBackend(fake, "test:", time.Second, time.Minute)
+	backend.now = func() time.Time { return now }
+	get := func() error {
+		return backend.do(context.Background(), func(ctx context.Context) error {
+			return backend.client.Get(ctx, "missing").Err()
+		})
+	}
+
+	assert.Equal(t, redis.Nil, get(), "a miss is not a failure")
+	fake.down = true
+	assert.Error(t, get())
+	calls := fake.calls
+	assert.Equal(t, errRedisDown, get())
+	assert.Equal(t, calls, fake.calls, "no call is made while down")
+
+	fake.down = false
+	now = now.Add(time.Minute)
+	assert.Equal(t, redis.Nil, get())
+}
+
+func TestRedisQuotaStore(t *testing.T) {
+	store := &redisQuotaStore{newRedisBackend(newFakeRedis(), "test:", time.Second, time.Minute)}
+	ctx := context.Background()
+	expires := time.Now().Add(time.Hour)
+
+	assert.NoError(t, store.Add(ctx, "key/day/1", quotaUsage{1, 10}, expires))
+	assert.NoError(t, store.Add(ctx, "key/day/1", quotaUsage{1, 5}, expires))
+	usage, err := store.Usage(ctx, "key/day/1")
+	if assert.NoError(t, err) {
+		assert.Equal(t, quotaUsage{2, 15}, usage)
+	}
+}
+
+func TestFallbackQuotaStore(t *testing.T) {
+	fake := newFakeRedis()
+	remote := &redisQuotaStore{newRedisBackend(fake, "test:", time.Second, time.Minute)}
+	store := &fallbackQuotaStore{remote: remote, local: newMemoryQuotaStore()}
+	ctx := context.Background()
+	expires := time.Now().Add(time.Hour)
+
+	assert.NoError(t, store.Add(ctx, "counter", quotaUsage{1, 10}, expires))
+	fake.down = true
+	assert.NoError(t, store.Add(ctx, "counter", quotaUsage{1, 10}, expires))
+
+	usage, err := store.Usage(ctx, "counter")
+	if assert.NoError(t, err) {
+		assert.Equal(t, quotaUsage{2, 20}, usage, "the local count answers while redis is down")
+	}
+}
+
+func TestResponseCacheShared(t *testing.T) {
+	fake := newFakeRedis()
+	ctx := context.Background()
+	first, second := newResponseCache(time.Minute, 10), newResponseCache(time.Minute, 10)
+	first.remote = newRedisBackend(fake, "test:", time.Second, time.Minute)
+	second.remote = newRedisBackend(fake, "test:", time.Second, time.Minute)
+
+	first.set(ctx, "key", []byte(`["one"]`), 200)
+	entry, ok := second.get(ctx, "key")
+	if assert.True(t, ok) {
+		assert.Equal(t, `["one"]`, string(entry.body))
+		assert.Equal(t, 200, entry.status)
+	}
+
+	fake.down = true
+	_, ok = second.get(ctx, "other")
+	assert.False(t, ok)
+	_, ok = second.get(ctx, "key")
+	assert.True(t, ok, "entries fetched from redis are kept locally")
+}
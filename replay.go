@@ -0,0 +1,133 @@
+// author: Gary A. Stafford
+// site: https://programmaticponderings.com
+// license: MIT License
+// purpose: NLP microservices: nlp-client traffic replay
+// modified: 2026-10-14
+
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"reflect"
+	"strings"
+	"time"
+)
+
+// replayResult tallies a replay run.
+type replayResult struct {
+	replayed, matched, mismatched, skipped int
+}
+
+// runReplay re-sends captured exchanges to a target environment and reports
+// every response that differs from the captured one:
+//
+//	nlp-client replay -target https://staging.example.com -api-key $API_KEY capture.jsonl
+func runReplay(args []string, out io.Writer) error {
+	fs := flag.NewFlagSet("replay", flag.ContinueOnError)
+	target := fs.String("target", "http://localhost:8080", "base URL of the environment to replay against")
+	key := fs.String("api-key", apiKey, "API key sent with every replayed request")
+	timeout := fs.Duration("timeout", 30*time.Second, "longest a replayed request may take")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() == 0 {
+		return fmt.Errorf("replay: no capture files given")
+	}
+
+	var result replayResult
+	client := &http.Client{Timeout: *timeout}
+	for _, name := range fs.Args() {
+		if err := replayFile(name, strings.TrimRight(*target, "/"), *key, client, out, &result); err != nil {
+			return err
+		}
+	}
+	fmt.Fprintf(out, "replayed %d, matched %d, mismatched %d, skipped %d\n",
+		result.replayed, result.matched, result.mismatched, result.skipped)
+	if result.mismatched > 0 {
+		return fmt.Errorf("replay: %d responses differ", result.mismatched)
+	}
+
+	return nil
+}
+
+func replayFile(name, target, key string, client *http.Client, out io.Writer, result *replayResult) error {
+	f, err := os.Open(name)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	for scanner.Scan() {
+		var exchange capturedExchange
+		if err := json.Unmarshal(scanner.Bytes(), &exchange); err != nil {
+			return fmt.Errorf("replay: %s: %v", name, err)
+		}
+		if exchange.Truncated {
+			result.skipped++
+			continue
+		}
+
+		status, body, err := replayExchange(client, target, key, exchange)
+		result.replayed++
+		switch {
+		case err != nil:
+			result.mismatched++
+			fmt.Fprintf(out, "%s %s: %v\n", exchange.Method, exchange.URI, err)
+		case status != exchange.Status:
+			result.mismatched++
+			fmt.Fprintf(out, "%s %s: status %d, captured %d\n", exchange.Method, exchange.URI, status, exchange.Status)
+		case !sameJSON(redactPII(string(body)), exchange.Response):
+			result.mismatched++
+			fmt.Fprintf(out, "%s %s: response differs\n", exchange.Method, exchange.URI)
+		default:
+			result.matched++
+		}
+	}
+
+	return scanner.Err()
+}
+
+func replayExchange(client *http.Client, target, key string, exchange capturedExchange) (int, []byte, error) {
+	var body io.Reader
+	if exchange.Request != "" {
+		body = strings.NewReader(exchange.Request)
+	}
+	req, err := http.NewRequest(exchange.Method, target+exchange.URI, body)
+	if err != nil {
+		return 0, nil, err
+	}
+	for name, value := range exchange.Headers {
+		req.Header.Set(name, value)
+	}
+	req.Header.Set("X-API-Key", key)
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return 0, nil, err
+	}
+	defer resp.Body.Close()
+	data, err := ioutil.ReadAll(resp.Body)
+
+	return resp.StatusCode, data, err
+}
+
+// sameJSON compares two bodies as JSON values when both parse, ignoring
+// formatting and key order, and byte for byte otherwise.
+func sameJSON(a, b string) bool {
+	var va, vb interface{}
+	if json.Unmarshal([]byte(a), &va) == nil && json.Unmarshal([]byte(b), &vb) == nil {
+		return reflect.DeepEqual(va, vb)
+	}
+
+	return bytes.Equal(bytes.TrimSpace([]byte(a)), bytes.TrimSpace([]byte(b)))
+}
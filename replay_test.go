@@ -0,0 +1,50 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSameJSON(t *testing.T) {
+	assert.True(t, sameJSON(`{"a": 1, "b": [2]}`, `{"b":[2],"a":1}`))
+	assert.False(t, sameJSON(`{"a": 1}`, `{"a": 2}`))
+	assert.True(t, sameJSON("plain\n", "plain"))
+}
+
+func TestRunReplay(t *testing.T) {
+	target := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "replay-key", r.Header.Get("X-API-Key"))
+		body, _ := ioutil.ReadAll(r.Body)
+		if r.URL.Path == "/language" {
+			_, _ = w.Write([]byte(`{"language": "fr"}`))
+			return
+		}
+		_, _ = w.Write(body)
+	}))
+	defer target.Close()
+
+	var lines bytes.Buffer
+	enc := json.NewEncoder(&lines)
+	_ = enc.Encode(capturedExchange{Method: http.MethodPost, URI: "/keywords", Request: `{"text":"a"}`,
+		Status: http.StatusOK, Response: `{"text": "a"}`})
+	_ = enc.Encode(capturedExchange{Method: http.MethodPost, URI: "/language", Request: `{"text":"a"}`,
+		Status: http.StatusOK, Response: `{"language": "en"}`})
+	_ = enc.Encode(capturedExchange{Method: http.MethodPost, URI: "/keywords", Truncated: true})
+
+	path := filepath.Join(t.TempDir(), "capture.jsonl")
+	if !assert.NoError(t, ioutil.WriteFile(path, lines.Bytes(), 0644)) {
+		return
+	}
+
+	out := new(bytes.Buffer)
+	err := runReplay([]string{"-target", target.URL, "-api-key", "replay-key", path}, out)
+	assert.EqualError(t, err, "replay: 1 responses differ")
+	assert.Equal(t, "POST /language: response differs\nreplayed 2, matched 1, mismatched 1, skipped 1\n", out.String())
+}
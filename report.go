@@ -0,0 +1,204 @@
+// author: Gary A. Stafford
+// site: https://programmaticponderings.com
+// license: MIT License
+// purpose: NLP microservices: nlp-client Markdown and plain-text reports
+// modified: 2026-10-14
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"text/tabwriter"
+)
+
+// number of keywords listed in a report
+var reportTopKeywords = getEnv("REPORT_TOP_KEYWORDS", "10")
+
+const (
+	reportMarkdown = "markdown"
+	reportText     = "text"
+)
+
+var reportContentTypes = map[string]string{
+	reportMarkdown: "text/markdown; charset=UTF-8",
+	reportText:     "text/plain; charset=UTF-8",
+}
+
+// parseReportStyle returns the ?report= style a report is written in, Markdown by default.
+func parseReportStyle(style string) (string, error) {
+	switch style {
+	case "":
+		return reportMarkdown, nil
+	case reportMarkdown, reportText:
+		return style, nil
+	}
+
+	return "", fmt.Errorf("unknown report %q, want markdown or text", style)
+}
+
+// reportEntity is an entity of a report, with the times the result lists it.
+type reportEntity struct {
+	text, label string
+	mentions    int
+}
+
+// reportSummary is what a report says of the results of its analyses.
+type reportSummary struct {
+	language  string
+	counts    [][2]string
+	keywords  []string
+	entities  []reportEntity
+	failed    []string
+	analyzed  map[string]bool
+	truncated int
+}
+
+func summarizeReport(analyses []string, results map[string]analysisResult) reportSummary {
+	s := reportSummary{analyzed: map[string]bool{}}
+	for _, name := range analyses {
+		result := results[name]
+		if result.Error != nil {
+			s.failed = append(s.failed, fmt.Sprintf("%s: %s (%d)", name, result.Error.Message, result.Status))
+			continue
+		}
+		s.analyzed[name] = true
+		switch name {
+		case "language":
+			var language map[string]interface{}
+			_ = json.Unmarshal(result.Result, &language)
+			s.language, _ = language["language"].(string)
+			for _, field := range []string{"confidence", "probability"} {
+				if p, ok := language[field].(float64); ok {
+					s.language += fmt.Sprintf(" (%.2f)", p)
+					break
+				}
+			}
+		case "keywords":
+			var items []interface{}
+			_ = json.Unmarshal(result.Result, &items)
+			top := envInt(reportTopKeywords, 10)
+			for i, item := range items {
+				if i == top {
+					s.truncated = len(items) - top
+					break
+				}
+				text, _ := itemText(item)
+				if fields, ok := item.(map[string]interface{}); ok {
+					if score, ok := fields["score"].(float64); ok {
+						text += fmt.Sprintf(" (%g)", score)
+					}
+				}
+				s.keywords = append(s.keywords, text)
+			}
+		case "entities":
+			var items []v2Item
+			_ = json.Unmarshal(result.Result, &items)
+			index := map[string]int{}
+			for _, item := range items {
+				key := strings.ToLower(item.Text) + "\x00" + item.Label
+				if i, ok := index[key]; ok {
+					s.entities[i].mentions++
+					continue
+				}
+				index[key] = len(s.entities)
+				s.entities = append(s.entities, reportEntity{item.Text, item.Label, 1})
+			}
+		case "tokens", "sentences":
+			var items []json.RawMessage
+			_ = json.Unmarshal(result.Result, &items)
+			s.counts = append(s.counts, [2]string{strings.ToUpper(name[:1]) + name[1:], fmt.Sprint(len(items))})
+		}
+	}
+
+	return s
+}
+
+// markdownCell escapes the pipes of a Markdown table cell.
+func markdownCell(text string) string {
+	return strings.ReplaceAll(strings.ReplaceAll(text, "|", `\|`), "\n", " ")
+}
+
+// renderReport writes the results of the analyses of a request as a summary
+// for people to read, in Markdown or plain text: the language, counts, top
+// keywords, a table of entities, and the analyses that failed.
+func renderReport(style string, analyses []string, results map[string]analysisResult) string {
+	s := summarizeReport(analyses, results)
+	var b strings.Builder
+	markdown := style == reportMarkdown
+	heading := func(title string) {
+		if markdown {
+			fmt.Fprintf(&b, "\n## %s\n\n", title)
+		} else {
+			fmt.Fprintf(&b, "\n%s\n%s\n", title, strings.Repeat("-", len(title)))
+		}
+	}
+
+	if markdown {
+		b.WriteString("# Analysis Report\n")
+	} else {
+		b.WriteString("Analysis Report\n===============\n")
+	}
+	var facts [][2]string
+	if s.analyzed["language"] {
+		facts = append(facts, [2]string{"Language", s.language})
+	}
+	facts = append(facts, s.counts...)
+	if len(facts) > 0 {
+		b.WriteString("\n")
+		for _, fact := range facts {
+			if markdown {
+				fmt.Fprintf(&b, "- **%s:** %s\n", fact[0], fact[1])
+			} else {
+				fmt.Fprintf(&b, "%s: %s\n", fact[0], fact[1])
+			}
+		}
+	}
+
+	if s.analyzed["keywords"] {
+		heading("Top Keywords")
+		for i, keyword := range s.keywords {
+			fmt.Fprintf(&b, "%d. %s\n", i+1, keyword)
+		}
+		if len(s.keywords) == 0 {
+			b.WriteString("No keywords were found.\n")
+		}
+		if s.truncated > 0 {
+			fmt.Fprintf(&b, "\n%d more not shown.\n", s.truncated)
+		}
+	}
+
+	if s.analyzed["entities"] {
+		heading("Entities")
+		switch {
+		case len(s.entities) == 0:
+			b.WriteString("No entities were found.\n")
+		case markdown:
+			b.WriteString("| Entity | Label | Mentions |\n| --- | --- | --- |\n")
+			for _, entity := range s.entities {
+				fmt.Fprintf(&b, "| %s | %s | %d |\n", markdownCell(entity.text), markdownCell(entity.label), entity.mentions)
+			}
+		default:
+			w := tabwriter.NewWriter(&b, 0, 0, 2, ' ', 0)
+			fmt.Fprintln(w, "Entity\tLabel\tMentions")
+			for _, entity := range s.entities {
+				fmt.Fprintf(w, "%s\t%s\t%d\n", entity.text, entity.label, entity.mentions)
+			}
+			_ = w.Flush()
+		}
+	}
+
+	if len(s.failed) > 0 {
+		heading("Failed Analyses")
+		for _, failed := range s.failed {
+			if markdown {
+				fmt.Fprintf(&b, "- %s\n", failed)
+			} else {
+				fmt.Fprintf(&b, "%s\n", failed)
+			}
+		}
+	}
+
+	return b.String()
+}
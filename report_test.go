@@ -0,0 +1,94 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/garystafford/nlp-client/mockupstream"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseReportStyle(t *testing.T) {
+	style, err := parseReportStyle("")
+	if assert.NoError(t, err) {
+		assert.Equal(t, reportMarkdown, style)
+	}
+	_, err = parseReportStyle("pdf")
+	assert.Error(t, err)
+}
+
+func TestRenderReport(t *testing.T) {
+	analyses := []string{"language", "keywords", "entities", "sentences"}
+	results := map[string]analysisResult{
+		"language":  {Status: http.StatusOK, Result: []byte(`{"language":"en","probability":0.99}`)},
+		"keywords":  {Status: http.StatusOK, Result: []byte(`[{"candidate":"nobel prize","score":4},{"candidate":"award","score":1}]`)},
+		"entities":  {Status: http.StatusOK, Result: []byte(`[{"text":"Marie Curie","label":"PERSON"},{"text":"AT|T","label":"ORG"},{"text":"marie curie","label":"PERSON"}]`)},
+		"sentences": failedAnalysis(upstreamError(nil)),
+	}
+	defer func(top string) { reportTopKeywords = top }(reportTopKeywords)
+	reportTopKeywords = "1"
+
+	assert.Equal(t, `# Analysis Report
+
+- **Language:** en (0.99)
+
+## Top Keywords
+
+1. nobel prize (4)
+
+1 more not shown.
+
+## Entities
+
+| Entity | Label | Mentions |
+| --- | --- | --- |
+| Marie Curie | PERSON | 2 |
+| AT\|T | ORG | 1 |
+
+## Failed Analyses
+
+- sentences: upstream service unavailable (502)
+`, renderReport(reportMarkdown, analyses, results))
+
+	assert.Equal(t, `Analysis Report
+===============
+
+Language: en (0.99)
+
+Top Keywords
+------------
+1. nobel prize (4)
+
+1 more not shown.
+
+Entities
+--------
+Entity       Label   Mentions
+Marie Curie  PERSON  2
+AT|T         ORG     1
+
+Failed Analyses
+---------------
+sentences: upstream service unavailable (502)
+`, renderReport(reportText, analyses, results))
+}
+
+func TestPostAnalyzeReport(t *testing.T) {
+	prose := mockupstream.NewProse()
+	defer prose.Close()
+	defer func(url string) { urlProse = url }(urlProse)
+	urlProse = prose.URL
+
+	req := httptest.NewRequest(http.MethodPost, "/analyze?analyses=sentences&format=report&report=text",
+		strings.NewReader(`{"text": "The Nobel Prize is regarded as the most prestigious award in the World."}`))
+	w := httptest.NewRecorder()
+	if assert.NoError(t, postAnalyze(e.NewContext(req, w))) {
+		assert.Equal(t, "text/plain; charset=UTF-8", w.Header().Get("Content-Type"))
+		assert.Equal(t, "Analysis Report\n===============\n\nSentences: 1\n", w.Body.String())
+	}
+
+	req = httptest.NewRequest(http.MethodPost, "/analyze?analyses=sentences&format=pdf", strings.NewReader(`{"text": "Hi"}`))
+	assert.Error(t, postAnalyze(e.NewContext(req, httptest.NewRecorder())))
+}
@@ -0,0 +1,102 @@
+// author: Gary A. Stafford
+// site: https://programmaticponderings.com
+// license: MIT License
+// purpose: NLP microservices: nlp-client data residency of records
+// modified: 2026-10-14
+
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+var (
+	// semicolon-separated region=url record stores, each a dynamo-app, e.g.
+	// "eu=https://dynamo.eu-west-1.example.com;us=https://dynamo.us-east-1.example.com"
+	recordTargets = getEnv("RECORD_TARGETS", "")
+	// comma-separated tenant=region residencies, with * for every other tenant;
+	// the records of tenants without one are written to DYNAMO_ENDPOINT
+	tenantResidency = getEnv("TENANT_RESIDENCY", "")
+	// inbound header naming the tenant of a request
+	tenantHeader = getEnv("TENANT_HEADER", "X-Tenant-ID")
+
+	residency residencyConfig
+)
+
+// residencyConfig maps tenants to the region their records must stay in, and
+// regions to the record store in them.
+type residencyConfig struct {
+	targets map[string]string
+	tenants map[string]string
+}
+
+func parseResidency(targets, tenants string) (residencyConfig, error) {
+	cfg := residencyConfig{targets: map[string]string{}, tenants: map[string]string{}}
+	for _, entry := range strings.Split(targets, ";") {
+		if strings.TrimSpace(entry) == "" {
+			continue
+		}
+		parts := strings.SplitN(entry, "=", 2)
+		region := strings.TrimSpace(parts[0])
+		if len(parts) != 2 || region == "" {
+			return residencyConfig{}, fmt.Errorf("invalid record target %q, want region=url", entry)
+		}
+		target := strings.TrimSpace(parts[1])
+		if u, err := url.Parse(target); err != nil || u.Scheme == "" || u.Host == "" {
+			return residencyConfig{}, fmt.Errorf("invalid record target url %q", target)
+		}
+		cfg.targets[region] = upstreamURL(target)
+	}
+	for _, entry := range splitList(tenants) {
+		parts := strings.SplitN(entry, "=", 2)
+		if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+			return residencyConfig{}, fmt.Errorf("invalid tenant residency %q, want tenant=region", entry)
+		}
+		cfg.tenants[parts[0]] = parts[1]
+	}
+
+	return cfg, nil
+}
+
+// loadResidency parses the residency configuration. A residency that cannot
+// be parsed stops the client from starting rather than being ignored, so
+// records are never written outside their region.
+func loadResidency() error {
+	cfg, err := parseResidency(recordTargets, tenantResidency)
+	if err != nil {
+		return fmt.Errorf("RECORD_TARGETS or TENANT_RESIDENCY: %w", err)
+	}
+	residency = cfg
+
+	return nil
+}
+
+// region returns the region the records of a tenant must stay in, if any.
+func (r residencyConfig) region(tenant string) (string, bool) {
+	if region, ok := r.tenants[tenant]; ok {
+		return region, true
+	}
+	region, ok := r.tenants["*"]
+
+	return region, ok
+}
+
+// recordTarget returns the record store a request's record is written to,
+// refusing the write when the tenant's region has none.
+func recordTarget(header http.Header) (string, string, error) {
+	tenant := header.Get(tenantHeader)
+	region, ok := residency.region(tenant)
+	if !ok {
+		return urlDynamo, "", nil
+	}
+	target, ok := residency.targets[region]
+	if !ok {
+		return "", "", newAPIError(http.StatusUnprocessableEntity, codeResidencyViolation,
+			fmt.Sprintf("no record store is configured in region %s", region))
+	}
+
+	return target, region, nil
+}
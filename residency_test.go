@@ -0,0 +1,61 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseResidency(t *testing.T) {
+	cfg, err := parseResidency("eu=https://dynamo.eu.example.com; us=https://dynamo.us.example.com", "acme=eu,*=us")
+	if assert.NoError(t, err) {
+		assert.Equal(t, map[string]string{"eu": "https://dynamo.eu.example.com", "us": "https://dynamo.us.example.com"}, cfg.targets)
+		assert.Equal(t, map[string]string{"acme": "eu", "*": "us"}, cfg.tenants)
+	}
+
+	for _, targets := range []string{"eu", "=https://dynamo.eu.example.com", "eu=dynamo.eu.example.com"} {
+		_, err := parseResidency(targets, "")
+		assert.Error(t, err, targets)
+	}
+	_, err = parseResidency("", "acme")
+	assert.Error(t, err)
+}
+
+func TestPutDynamoResidency(t *testing.T) {
+	var defaultRecords, euRecords []map[string]interface{}
+	_, dynamo := newModerationUpstreams(t, &defaultRecords)
+	defer dynamo.Close()
+	_, dynamoEU := newModerationUpstreams(t, &euRecords)
+	defer dynamoEU.Close()
+	defer func(url string, cfg residencyConfig) { urlDynamo, residency = url, cfg }(urlDynamo, residency)
+	urlDynamo = dynamo.URL
+	var err error
+	residency, err = parseResidency("eu="+dynamoEU.URL, "acme=eu,initech=apac")
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	record := func(tenant string) error {
+		req := httptest.NewRequest(http.MethodPost, "/record", strings.NewReader(`{"text": "Marie Curie"}`))
+		req.Header.Set("X-Tenant-ID", tenant)
+		c := e.NewContext(req, httptest.NewRecorder())
+		c.SetPath("/record")
+		return putDynamo(c)
+	}
+
+	assert.NoError(t, record("acme"))
+	assert.NoError(t, record("globex"))
+	assert.Len(t, euRecords, 1, "records of EU tenants are written to the EU store")
+	assert.Len(t, defaultRecords, 1, "records of other tenants are written to DYNAMO_ENDPOINT")
+
+	err = record("initech")
+	if assert.Error(t, err) {
+		assert.Equal(t, http.StatusUnprocessableEntity, err.(*apiError).Status)
+		assert.Equal(t, codeResidencyViolation, err.(*apiError).Code)
+	}
+	assert.Len(t, euRecords, 1)
+	assert.Len(t, defaultRecords, 1)
+}
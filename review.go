@@ -0,0 +1,265 @@
+// author: Gary A. Stafford
+// site: https://programmaticponderings.com
+// license: MIT License
+// purpose: NLP microservices: nlp-client human review of low-confidence results
+// modified: 2026-10-14
+
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/labstack/echo/v4"
+)
+
+const (
+	headerReviewID       = "X-Review-ID"
+	headerReviewCallback = "X-Review-Callback"
+)
+
+var (
+	// results with a confidence below it are queued for review; unset reviews nothing
+	reviewThreshold = getEnv("REVIEW_CONFIDENCE_THRESHOLD", "")
+	// pending reviews kept; results found once there are this many are not queued
+	reviewMaxPending = getEnv("REVIEW_MAX_PENDING", "1000")
+	// comma-separated URL prefixes an X-Review-Callback may be under
+	reviewCallbackURLs   = getEnv("REVIEW_CALLBACK_URLS", "")
+	reviewWebhookTimeout = getEnv("REVIEW_WEBHOOK_TIMEOUT", "5s")
+
+	reviews      = newReviewQueue(envInt(reviewMaxPending, 1000))
+	reviewNotify = sendWebhook
+)
+
+// review is a result queued for a reviewer to correct.
+type review struct {
+	ID        string          `json:"id"`
+	RequestID string          `json:"request_id,omitempty"`
+	Analysis  string          `json:"analysis"`
+	Text      string          `json:"text"`
+	Result    json.RawMessage `json:"result"`
+	// the lowest confidence in the result
+	Confidence float64         `json:"confidence"`
+	Status     string          `json:"status"`
+	Correction json.RawMessage `json:"correction,omitempty"`
+	CreatedAt  time.Time       `json:"created_at"`
+	ResolvedAt *time.Time      `json:"resolved_at,omitempty"`
+	callback   string
+}
+
+// reviewEvent is posted to the callback of the request a review was queued by.
+type reviewEvent struct {
+	Event     string          `json:"event"`
+	ReviewID  string          `json:"review_id"`
+	RequestID string          `json:"request_id,omitempty"`
+	Analysis  string          `json:"analysis"`
+	Result    json.RawMessage `json:"result"`
+	Time      time.Time       `json:"time"`
+}
+
+// reviewQueue keeps the reviews of a single instance in memory, oldest first,
+// with resolved reviews dropped once there are more than max in all.
+type reviewQueue struct {
+	mu      sync.Mutex
+	reviews []*review
+	max     int
+}
+
+func newReviewQueue(max int) *reviewQueue {
+	return &reviewQueue{max: max}
+}
+
+func (q *reviewQueue) add(r *review) bool {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	pending := 0
+	for _, queued := range q.reviews {
+		if queued.Status == "pending" {
+			pending++
+		}
+	}
+	if pending >= q.max {
+		return false
+	}
+	q.reviews = append(q.reviews, r)
+	for i := 0; len(q.reviews) > q.max && i < len(q.reviews); {
+		if q.reviews[i].Status == "resolved" {
+			q.reviews = append(q.reviews[:i], q.reviews[i+1:]...)
+			continue
+		}
+		i++
+	}
+
+	return true
+}
+
+// list returns copies of the reviews with status, or of all of them.
+func (q *reviewQueue) list(status string) []review {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	listed := []review{}
+	for _, r := range q.reviews {
+		if status == "" || r.Status == status {
+			listed = append(listed, *r)
+		}
+	}
+
+	return listed
+}
+
+// resolve records the correction of a pending review.
+func (q *reviewQueue) resolve(id string, correction json.RawMessage) (review, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	for _, r := range q.reviews {
+		if r.ID != id {
+			continue
+		}
+		if r.Status != "pending" {
+			return review{}, newAPIError(http.StatusConflict, codeConflict, "review already resolved")
+		}
+		now := time.Now().UTC()
+		r.Status, r.Correction, r.ResolvedAt = "resolved", correction, &now
+		return *r, nil
+	}
+
+	return review{}, newAPIError(http.StatusNotFound, codeNotFound, "review not found")
+}
+
+// reviewed reports whether the results of route are checked for review.
+func reviewed(route string) bool {
+	_, ok := analysisEndpoint(strings.TrimPrefix(route, "/"))
+	return reviewThreshold != "" && ok
+}
+
+// lowestConfidence returns the lowest confidence of a result, or of its items.
+func lowestConfidence(body json.RawMessage) (float64, bool) {
+	var items []json.RawMessage
+	if json.Unmarshal(body, &items) != nil {
+		items = []json.RawMessage{body}
+	}
+	lowest, found := 0.0, false
+	for _, raw := range items {
+		var item struct {
+			Confidence *float64 `json:"confidence"`
+		}
+		if json.Unmarshal(raw, &item) != nil || item.Confidence == nil {
+			continue
+		}
+		if !found || *item.Confidence < lowest {
+			lowest, found = *item.Confidence, true
+		}
+	}
+
+	return lowest, found
+}
+
+// allowedCallback reports whether a callback is under a REVIEW_CALLBACK_URLS prefix.
+func allowedCallback(callback string) bool {
+	for _, prefix := range splitList(reviewCallbackURLs) {
+		if strings.HasPrefix(callback, prefix) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// queueReview queues the result of a route for review when its confidence is
+// below the threshold, naming the review in an X-Review-ID of the response.
+func queueReview(c echo.Context, route, text string, body json.RawMessage) {
+	if !reviewed(route) {
+		return
+	}
+	threshold, err := strconv.ParseFloat(reviewThreshold, 64)
+	if err != nil {
+		return
+	}
+	confidence, ok := lowestConfidence(body)
+	if !ok || confidence >= threshold {
+		return
+	}
+	id := make([]byte, 16)
+	if _, err := rand.Read(id); err != nil {
+		e.Logger.Errorf("not queueing review: %v", err)
+		return
+	}
+
+	analysis := strings.TrimPrefix(route, "/")
+	r := &review{
+		ID:         hex.EncodeToString(id),
+		RequestID:  requestID(c),
+		Analysis:   analysis,
+		Text:       text,
+		Result:     append(json.RawMessage{}, body...),
+		Confidence: confidence,
+		Status:     "pending",
+		CreatedAt:  time.Now().UTC(),
+	}
+	if callback := c.Request().Header.Get(headerReviewCallback); allowedCallback(callback) {
+		r.callback = callback
+	}
+	if !reviews.add(r) {
+		metrics.Count("reviews_dropped_total", 1, map[string]string{"analysis": analysis})
+		return
+	}
+	metrics.Count("reviews_queued_total", 1, map[string]string{"analysis": analysis})
+	c.Response().Header().Add(headerReviewID, r.ID)
+}
+
+func getReviews(c echo.Context) error {
+	return c.JSON(http.StatusOK, reviews.list(c.QueryParam("status")))
+}
+
+// postReview resolves a review with the reviewer's corrected result, which
+// replaces the cached result of the text, and notifies the callback of the
+// request that queued it, if any.
+func postReview(c echo.Context) error {
+	var body struct {
+		Result json.RawMessage `json:"result"`
+	}
+	if err := json.NewDecoder(c.Request().Body).Decode(&body); err != nil {
+		return validationError(err.Error())
+	}
+	if len(body.Result) == 0 {
+		return validationError("result is required")
+	}
+	r, err := reviews.resolve(c.Param("id"), body.Result)
+	if err != nil {
+		return err
+	}
+
+	route := "/" + r.Analysis
+	if analysisCache != nil && cachedRoutes[route] {
+		analysisCache.set(c.Request().Context(), cacheKey(route, r.Text), body.Result, http.StatusOK)
+	}
+	if r.callback != "" {
+		notify, event := reviewNotify, reviewEvent{
+			Event:     "review_resolved",
+			ReviewID:  r.ID,
+			RequestID: r.RequestID,
+			Analysis:  r.Analysis,
+			Result:    r.Correction,
+			Time:      *r.ResolvedAt,
+		}
+		go func() {
+			ctx, cancel := context.WithTimeout(context.Background(), envDuration(reviewWebhookTimeout, 5*time.Second))
+			defer cancel()
+			if err := notify(ctx, r.callback, event); err != nil {
+				e.Logger.Errorf("review callback failed: %v", err)
+			}
+		}()
+	}
+
+	return c.JSON(http.StatusOK, r)
+}
@@ -0,0 +1,95 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/garystafford/nlp-client/mockupstream"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLowestConfidence(t *testing.T) {
+	lowest, ok := lowestConfidence(json.RawMessage(`[{"text":"a","confidence":0.9},{"text":"b","confidence":0.2},{"text":"c"}]`))
+	assert.True(t, ok)
+	assert.Equal(t, 0.2, lowest)
+	lowest, ok = lowestConfidence(json.RawMessage(`{"language":"en","confidence":0.4}`))
+	assert.True(t, ok)
+	assert.Equal(t, 0.4, lowest)
+	_, ok = lowestConfidence(json.RawMessage(`["The","Nobel"]`))
+	assert.False(t, ok)
+}
+
+func TestReviewQueue(t *testing.T) {
+	q := newReviewQueue(2)
+	assert.True(t, q.add(&review{ID: "a", Status: "pending"}))
+	assert.True(t, q.add(&review{ID: "b", Status: "pending"}))
+	assert.False(t, q.add(&review{ID: "c", Status: "pending"}), "a full queue queues no more")
+
+	_, err := q.resolve("a", json.RawMessage(`[]`))
+	assert.NoError(t, err)
+	_, err = q.resolve("a", json.RawMessage(`[]`))
+	assert.Equal(t, http.StatusConflict, err.(*apiError).Status)
+	_, err = q.resolve("z", json.RawMessage(`[]`))
+	assert.Equal(t, http.StatusNotFound, err.(*apiError).Status)
+
+	assert.True(t, q.add(&review{ID: "c", Status: "pending"}), "resolved reviews make room")
+	assert.Len(t, q.list(""), 2)
+	assert.Len(t, q.list("pending"), 2)
+}
+
+func TestReviewLowConfidenceLanguage(t *testing.T) {
+	lang := mockupstream.NewLang()
+	defer lang.Close()
+	defer func(url, threshold, callbacks string, cals map[string]calibration, queue *reviewQueue) {
+		urlLang, reviewThreshold, reviewCallbackURLs, calibrations, reviews = url, threshold, callbacks, cals, queue
+	}(urlLang, reviewThreshold, reviewCallbackURLs, calibrations, reviews)
+	defer func(notify func(ctx context.Context, url string, event interface{}) error) { reviewNotify = notify }(reviewNotify)
+	urlLang = lang.URL
+	reviewThreshold, reviewCallbackURLs = "0.6", "https://hooks.example.com/"
+	calibrations, _ = parseCalibrations("lang-app.probability=0.98:0 1:1")
+	reviews = newReviewQueue(10)
+	notified := make(chan reviewEvent, 1)
+	reviewNotify = func(ctx context.Context, url string, event interface{}) error {
+		assert.Equal(t, "https://hooks.example.com/reviews", url)
+		notified <- event.(reviewEvent)
+		return nil
+	}
+
+	req := httptest.NewRequest(http.MethodPost, "/language", strings.NewReader(`{"text": "Bonjour"}`))
+	req.Header.Set(headerReviewCallback, "https://hooks.example.com/reviews")
+	w := httptest.NewRecorder()
+	c := e.NewContext(req, w)
+	c.SetPath("/language")
+	if !assert.NoError(t, getLanguage(c)) {
+		return
+	}
+	id := w.Header().Get(headerReviewID)
+	pending := reviews.list("pending")
+	if assert.Len(t, pending, 1) {
+		assert.Equal(t, id, pending[0].ID)
+		assert.Equal(t, "language", pending[0].Analysis)
+		assert.Equal(t, "Bonjour", pending[0].Text)
+		assert.InDelta(t, 0.5, pending[0].Confidence, 1e-9)
+	}
+
+	req = httptest.NewRequest(http.MethodPost, "/admin/reviews/"+id, strings.NewReader(`{"result": {"language": "fr"}}`))
+	w = httptest.NewRecorder()
+	c = e.NewContext(req, w)
+	c.SetParamNames("id")
+	c.SetParamValues(id)
+	if assert.NoError(t, postReview(c)) {
+		assert.Empty(t, reviews.list("pending"))
+		select {
+		case event := <-notified:
+			assert.Equal(t, "review_resolved", event.Event)
+			assert.JSONEq(t, `{"language": "fr"}`, string(event.Result))
+		case <-time.After(time.Second):
+			t.Fatal("the callback was not notified")
+		}
+	}
+}
@@ -0,0 +1,192 @@
+// author: Gary A. Stafford
+// site: https://programmaticponderings.com
+// license: MIT License
+// purpose: NLP microservices: nlp-client route registry
+// modified: 2026-10-14
+
+package main
+
+import (
+	"net/http"
+	"reflect"
+	"runtime"
+	"time"
+
+	"github.com/labstack/echo/v4"
+)
+
+type route struct {
+	method  string
+	path    string
+	handler echo.HandlerFunc
+	group   string
+}
+
+// apiRoutes lists every route and the middleware group that serves it, by its
+// unversioned path; registry mounts them under their versions.
+func apiRoutes() []route {
+	routes := []route{
+		{http.MethodGet, "/health", getHealth, groupPublic},
+		{http.MethodGet, "/health/:app", getHealthUpstream, groupPublic},
+		{http.MethodGet, "/metrics", getMetrics, groupPublic},
+		{http.MethodGet, "/version", getVersion, groupPublic},
+		{http.MethodGet, "/slo", getSLO, groupPublic},
+		{http.MethodGet, "/autoscaling", getAutoscaling, groupPublic},
+		{http.MethodGet, "/ready", getReady, groupPublic},
+		{http.MethodGet, "/error", getError, groupAPI},
+		{http.MethodGet, "/routes", getRoutes, groupAPI},
+		{http.MethodPost, "/keywords", getKeywords, groupAPI},
+		{http.MethodPost, "/tokens", getTokens, groupAPI},
+		{http.MethodPost, "/entities", getEntities, groupAPI},
+		{http.MethodPost, "/sentences", getSentences, groupAPI},
+		{http.MethodPost, "/language", getLanguage, groupAPI},
+		{http.MethodPost, "/record", putDynamo, groupAPI},
+		{http.MethodPost, "/moderate", postModerate, groupAPI},
+		{http.MethodPost, "/spellcheck", postSpellcheck, groupAPI},
+		{http.MethodPost, "/analyze", postAnalyze, groupAPI},
+		{http.MethodPost, "/analyze/image", postAnalyzeImage, groupAPI},
+		{http.MethodPost, "/analyze/audio", postAnalyzeAudio, groupAPI},
+		{http.MethodPost, "/analyze/email", postAnalyzeEmail, groupAPI},
+		{http.MethodPost, "/analyze/csv", postAnalyzeCSV, groupAPI},
+		{http.MethodPost, "/batch", postBatch, groupAPI},
+		{http.MethodPost, "/corpus/stats", postCorpusStats, groupAPI},
+		{http.MethodPost, "/topics", postTopics, groupAPI},
+		{http.MethodPost, "/ensemble/:analysis", postEnsemble, groupAPI},
+		{http.MethodPost, "/evaluate", postEvaluate, groupAPI},
+		{http.MethodPost, "/feedback", postFeedback, groupAPI},
+		{http.MethodPost, "/compare-docs", postCompareDocs, groupAPI},
+		{http.MethodPost, "/quotes", postQuotes, groupAPI},
+		{http.MethodPost, "/temporal", postTemporal, groupAPI},
+		{http.MethodPost, "/readability", postReadability, groupAPI},
+		{http.MethodPost, "/sentiment", postSentiment, groupAPI},
+		{http.MethodPost, "/emotions", postEmotions, groupAPI},
+		{http.MethodPost, "/intent", postIntent, groupAPI},
+		{http.MethodGet, "/intents", getIntents, groupAPI},
+		{http.MethodPut, "/intents", putIntents, groupAPI},
+		{http.MethodDelete, "/intents", deleteIntents, groupAPI},
+		{http.MethodGet, "/glossary", getGlossary, groupAPI},
+		{http.MethodPut, "/glossary", putGlossary, groupAPI},
+		{http.MethodDelete, "/glossary", deleteGlossary, groupAPI},
+		{http.MethodGet, "/gazetteer", getGazetteer, groupAPI},
+		{http.MethodPut, "/gazetteer", putGazetteer, groupAPI},
+		{http.MethodDelete, "/gazetteer", deleteGazetteer, groupAPI},
+		{http.MethodGet, "/patterns", getPatterns, groupAPI},
+		{http.MethodPut, "/patterns", putPatterns, groupAPI},
+		{http.MethodDelete, "/patterns", deletePatterns, groupAPI},
+		{http.MethodGet, "/templates", getTemplates, groupAPI},
+		{http.MethodPut, "/templates", putTemplates, groupAPI},
+		{http.MethodDelete, "/templates", deleteTemplates, groupAPI},
+		{http.MethodPost, "/sessions", postSession, groupAPI},
+		{http.MethodPost, "/sessions/:id/analyze", postSessionAnalyze, groupAPI},
+		{http.MethodDelete, "/sessions/:id", deleteSession, groupAPI},
+		{http.MethodGet, simplePath, getSimple, groupAPI},
+		{http.MethodPost, simplePath, getSimple, groupAPI},
+		{http.MethodPost, "/integrations/slack", postSlack, groupAPI},
+		{http.MethodPost, "/integrations/github", postGitHub, groupAPI},
+		{http.MethodPost, "/integrations/sendgrid", postSendGrid, groupAPI},
+		{http.MethodPost, "/integrations/ses", postSES, groupAPI},
+		{http.MethodGet, "/feeds", getFeeds, groupAPI},
+	}
+
+	return append(routes, queryVariants(routes)...)
+}
+
+// mount is a route as the registry serves it: under its version, if it has
+// one, and at the legacy paths that alias it.
+type mount struct {
+	route
+	version string
+	aliases []string
+}
+
+// paths returns the routes to serve a mount at: its own path, then its aliases.
+func (m mount) paths() []route {
+	routes := []route{m.route}
+	for _, alias := range m.aliases {
+		routes = append(routes, route{m.method, alias, m.handler, m.group})
+	}
+
+	return routes
+}
+
+// registry mounts the API routes under /v1, keeping their unprefixed paths as
+// aliases, and the v2 routes under /v2 as well. The public and admin routes
+// are operational rather than part of the API, and stay unversioned.
+func registry() []mount {
+	var mounts []mount
+	for _, r := range append(apiRoutes(), adminRoutes()...) {
+		if r.group != groupAPI {
+			mounts = append(mounts, mount{route: r})
+			continue
+		}
+		v1 := r
+		v1.path = "/" + schemaV1 + r.path
+		mounts = append(mounts, mount{v1, schemaV1, []string{r.path}})
+		for _, path := range v2Routes {
+			if r.path == path {
+				v2 := r
+				v2.path = "/" + schemaV2 + r.path
+				mounts = append(mounts, mount{route: v2, version: schemaV2})
+			}
+		}
+	}
+
+	return mounts
+}
+
+type deprecationInfo struct {
+	Since     time.Time  `json:"since"`
+	Sunset    *time.Time `json:"sunset,omitempty"`
+	Successor string     `json:"successor,omitempty"`
+}
+
+func deprecationOf(method, path string) *deprecationInfo {
+	d, ok := deprecations[routeKey(method, path)]
+	if !ok {
+		return nil
+	}
+	info := &deprecationInfo{Since: d.Since, Successor: d.Successor}
+	if !d.Sunset.IsZero() {
+		info.Sunset = &d.Sunset
+	}
+
+	return info
+}
+
+type routeAlias struct {
+	Path        string           `json:"path"`
+	Deprecation *deprecationInfo `json:"deprecation,omitempty"`
+}
+
+type routeInfo struct {
+	Method      string           `json:"method"`
+	Path        string           `json:"path"`
+	Name        string           `json:"name"`
+	Version     string           `json:"version,omitempty"`
+	Aliases     []routeAlias     `json:"aliases,omitempty"`
+	Deprecation *deprecationInfo `json:"deprecation,omitempty"`
+}
+
+func handlerName(h echo.HandlerFunc) string {
+	return runtime.FuncForPC(reflect.ValueOf(h).Pointer()).Name()
+}
+
+func getRoutes(c echo.Context) error {
+	mounts := registry()
+	routes := make([]routeInfo, 0, len(mounts))
+	for _, m := range mounts {
+		info := routeInfo{
+			Method:      m.method,
+			Path:        m.path,
+			Name:        handlerName(m.handler),
+			Version:     m.version,
+			Deprecation: deprecationOf(m.method, m.path),
+		}
+		for _, alias := range m.aliases {
+			info.Aliases = append(info.Aliases, routeAlias{alias, deprecationOf(m.method, alias)})
+		}
+		routes = append(routes, info)
+	}
+
+	return c.JSON(http.StatusOK, routes)
+}
@@ -0,0 +1,51 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRegistry(t *testing.T) {
+	paths := map[string]bool{}
+	for _, m := range registry() {
+		for _, r := range m.paths() {
+			paths[r.method+" "+r.path] = true
+		}
+	}
+
+	for _, path := range []string{"POST /v1/tokens", "POST /tokens", "POST /v2/tokens", "POST /v1/record", "POST /record", "GET /health", "GET /admin/logging"} {
+		assert.True(t, paths[path], path)
+	}
+	for _, path := range []string{"POST /v2/record", "GET /v1/health", "GET /v1/admin/logging"} {
+		assert.False(t, paths[path], path)
+	}
+}
+
+func TestGetRoutesDeprecatedAlias(t *testing.T) {
+	since := time.Date(2026, 10, 1, 0, 0, 0, 0, time.UTC)
+	defer func(d map[string]deprecation) { deprecations = d }(deprecations)
+	deprecations = map[string]deprecation{
+		routeKey(http.MethodPost, "/tokens"): {Since: since, Successor: "/v1/tokens"},
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/routes", nil)
+	w := httptest.NewRecorder()
+	c := e.NewContext(req, w)
+
+	if assert.NoError(t, getRoutes(c)) {
+		var routes []routeInfo
+		if assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &routes)) {
+			for _, r := range routes {
+				if r.Method == http.MethodPost && r.Path == "/v1/tokens" {
+					assert.Nil(t, r.Deprecation)
+					assert.Equal(t, []routeAlias{{"/tokens", &deprecationInfo{Since: since, Successor: "/v1/tokens"}}}, r.Aliases)
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,440 @@
+// author: Gary A. Stafford
+// site: https://programmaticponderings.com
+// license: MIT License
+// purpose: NLP microservices: nlp-client S3 event-driven ingestion
+// modified: 2026-10-14
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"mime"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"path"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/sqs"
+	sqstypes "github.com/aws/aws-sdk-go-v2/service/sqs/types"
+	"github.com/aws/aws-sdk-go-v2/service/textract"
+	"github.com/aws/aws-sdk-go-v2/service/textract/types"
+)
+
+var (
+	// SQS queue the bucket's ObjectCreated notifications are sent to, directly
+	// or through an SNS topic; unset to ingest no objects
+	s3IngestQueueURL = getEnv("S3_INGEST_QUEUE_URL", "")
+	// how long a receive waits for notifications, and how long one is hidden
+	// from other receivers, and may take to ingest, before it is redelivered
+	s3IngestWait              = getEnv("S3_INGEST_WAIT", "20s")
+	s3IngestVisibilityTimeout = getEnv("S3_INGEST_VISIBILITY_TIMEOUT", "5m")
+	// notifications received, and ingested at once, per receive, up to 10
+	s3IngestBatchSize = getEnv("S3_INGEST_BATCH_SIZE", "4")
+	// analyses run on every new object
+	s3IngestAnalyses = getEnv("S3_INGEST_ANALYSES", "keywords,entities,language")
+	// largest text object ingested, in bytes; PDFs are read by Textract in place
+	s3IngestMaxBytes = getEnv("S3_INGEST_MAX_BYTES", "5242880")
+	// how often a PDF's Textract job is checked on
+	s3IngestTextractInterval = getEnv("S3_INGEST_TEXTRACT_INTERVAL", "5s")
+	// store each object's text as a record with its analyses, and the purpose
+	// to store them for where RECORD_REQUIRE_PURPOSE asks for one
+	s3IngestRecord        = getEnv("S3_INGEST_RECORD", "true")
+	s3IngestRecordPurpose = getEnv("S3_INGEST_RECORD_PURPOSE", "")
+	// write each object's analyses, as <key>.json, under this prefix of the
+	// output bucket, the object's own bucket when unset; unset the prefix to
+	// write none
+	s3IngestOutputBucket = getEnv("S3_INGEST_OUTPUT_BUCKET", "")
+	s3IngestOutputPrefix = getEnv("S3_INGEST_OUTPUT_PREFIX", "")
+
+	errObjectTooLarge = errors.New("object too large")
+)
+
+// s3IngestMaxBatch is the most messages SQS returns per receive.
+const s3IngestMaxBatch = 10
+
+// s3IngestTextTypes are the content types, besides text/*, ingested as text.
+var s3IngestTextTypes = map[string]bool{"application/json": true, "application/xml": true, "application/x-ndjson": true}
+
+// s3IngestTextExtensions are the extensions of objects ingested as text
+// whatever their content type, which uploads often leave generic.
+var s3IngestTextExtensions = map[string]bool{".txt": true, ".md": true, ".csv": true, ".json": true}
+
+// s3Event is an S3 event notification, or the test event S3 sends when
+// notifications are configured.
+type s3Event struct {
+	Event   string `json:"Event"`
+	Records []struct {
+		EventName string `json:"eventName"`
+		S3        struct {
+			Bucket struct {
+				Name string `json:"name"`
+			} `json:"bucket"`
+			Object struct {
+				Key  string `json:"key"`
+				Size int64  `json:"size"`
+			} `json:"object"`
+		} `json:"s3"`
+	} `json:"Records"`
+}
+
+// s3Object is a new object of an S3 event.
+type s3Object struct {
+	Bucket string `json:"bucket"`
+	Key    string `json:"key"`
+	Size   int64  `json:"size"`
+}
+
+// s3IngestResult is the analysis of an object, as it is written to the output prefix.
+type s3IngestResult struct {
+	Source   string                    `json:"source"`
+	Bucket   string                    `json:"bucket"`
+	Key      string                    `json:"key"`
+	Format   string                    `json:"format"`
+	Text     string                    `json:"text"`
+	Analyses map[string]analysisResult `json:"analyses"`
+}
+
+// parseS3Event returns the new objects of a notification, as SQS delivers it
+// from S3 or SNS. Object keys arrive URL-encoded.
+func parseS3Event(body string) ([]s3Object, error) {
+	var delivery snsMessage
+	if err := json.Unmarshal([]byte(body), &delivery); err == nil && delivery.Type == "Notification" {
+		body = delivery.Message
+	}
+	var event s3Event
+	if err := json.Unmarshal([]byte(body), &event); err != nil {
+		return nil, err
+	}
+	if event.Event == "s3:TestEvent" {
+		return nil, nil
+	}
+
+	var objects []s3Object
+	for _, record := range event.Records {
+		if !strings.HasPrefix(record.EventName, "ObjectCreated:") {
+			continue
+		}
+		key, err := url.QueryUnescape(record.S3.Object.Key)
+		if err != nil {
+			return nil, err
+		}
+		objects = append(objects, s3Object{Bucket: record.S3.Bucket.Name, Key: key, Size: record.S3.Object.Size})
+	}
+
+	return objects, nil
+}
+
+// objectFormat returns how an object is read, "text", "html" or "pdf", by its
+// extension or else its content type, or "" if it is not ingested.
+func objectFormat(key, contentType string) string {
+	ext := strings.ToLower(path.Ext(key))
+	switch {
+	case ext == ".pdf":
+		return "pdf"
+	case ext == ".html", ext == ".htm":
+		return "html"
+	case s3IngestTextExtensions[ext]:
+		return "text"
+	}
+	mediaType, _, _ := mime.ParseMediaType(contentType)
+	switch {
+	case mediaType == "application/pdf":
+		return "pdf"
+	case mediaType == "text/html":
+		return "html"
+	case strings.HasPrefix(mediaType, "text/"), s3IngestTextTypes[mediaType]:
+		return "text"
+	}
+
+	return ""
+}
+
+// outputLocation returns where the analyses of an object are written, if they are.
+func outputLocation(obj s3Object) (string, string, bool) {
+	if s3IngestOutputPrefix == "" {
+		return "", "", false
+	}
+	bucket := s3IngestOutputBucket
+	if bucket == "" {
+		bucket = obj.Bucket
+	}
+
+	return bucket, s3IngestOutputPrefix + obj.Key + ".json", true
+}
+
+// sqsAPI is the part of the SQS client the ingester uses.
+type sqsAPI interface {
+	ReceiveMessage(ctx context.Context, params *sqs.ReceiveMessageInput, optFns ...func(*sqs.Options)) (*sqs.ReceiveMessageOutput, error)
+	DeleteMessage(ctx context.Context, params *sqs.DeleteMessageInput, optFns ...func(*sqs.Options)) (*sqs.DeleteMessageOutput, error)
+}
+
+// s3API is the part of the S3 client the ingester uses.
+type s3API interface {
+	GetObject(ctx context.Context, params *s3.GetObjectInput, optFns ...func(*s3.Options)) (*s3.GetObjectOutput, error)
+	PutObject(ctx context.Context, params *s3.PutObjectInput, optFns ...func(*s3.Options)) (*s3.PutObjectOutput, error)
+}
+
+// textractJobsAPI is the part of the Textract client reading PDFs uses.
+type textractJobsAPI interface {
+	StartDocumentTextDetection(ctx context.Context, params *textract.StartDocumentTextDetectionInput, optFns ...func(*textract.Options)) (*textract.StartDocumentTextDetectionOutput, error)
+	GetDocumentTextDetection(ctx context.Context, params *textract.GetDocumentTextDetectionInput, optFns ...func(*textract.Options)) (*textract.GetDocumentTextDetectionOutput, error)
+}
+
+// pdfTextFunc returns the text of a PDF object.
+type pdfTextFunc func(ctx context.Context, obj s3Object) (string, error)
+
+// textractPDF runs a Textract text detection job on a PDF in place, which,
+// unlike DetectDocumentText, reads every page, and returns the lines it
+// detects, one per line of text.
+func textractPDF(client textractJobsAPI, interval time.Duration) pdfTextFunc {
+	return func(ctx context.Context, obj s3Object) (string, error) {
+		job, err := client.StartDocumentTextDetection(ctx, &textract.StartDocumentTextDetectionInput{
+			DocumentLocation: &types.DocumentLocation{S3Object: &types.S3Object{Bucket: aws.String(obj.Bucket), Name: aws.String(obj.Key)}},
+		})
+		if err != nil {
+			return "", err
+		}
+
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		var lines []string
+		var next *string
+		for {
+			out, err := client.GetDocumentTextDetection(ctx, &textract.GetDocumentTextDetectionInput{JobId: job.JobId, NextToken: next})
+			if err != nil {
+				return "", err
+			}
+			switch out.JobStatus {
+			case types.JobStatusSucceeded, types.JobStatusPartialSuccess:
+				for _, block := range out.Blocks {
+					if block.BlockType == types.BlockTypeLine && block.Text != nil {
+						lines = append(lines, *block.Text)
+					}
+				}
+				if next = out.NextToken; next == nil {
+					return strings.Join(lines, "\n"), nil
+				}
+				continue
+			case types.JobStatusFailed:
+				return "", fmt.Errorf("text detection job %s failed: %s", aws.ToString(job.JobId), aws.ToString(out.StatusMessage))
+			}
+			select {
+			case <-ctx.Done():
+				return "", ctx.Err()
+			case <-ticker.C:
+			}
+		}
+	}
+}
+
+// s3Ingester analyzes the objects of the bucket notifications of a queue.
+type s3Ingester struct {
+	queue    sqsAPI
+	queueURL string
+	batch    int
+	objects  s3API
+	pdfText  pdfTextFunc
+}
+
+// receive ingests the objects of one batch of notifications, all at once, so
+// each is done within the visibility timeout it was received with, deleting
+// each notification once its objects are ingested; the others are redelivered
+// after the visibility timeout, and moved to the queue's dead-letter queue,
+// if it has one, after its maximum receives.
+func (g *s3Ingester) receive(ctx context.Context, wait, visibility time.Duration) error {
+	batch := g.batch
+	if batch < 1 {
+		batch = 1
+	} else if batch > s3IngestMaxBatch {
+		batch = s3IngestMaxBatch
+	}
+	out, err := g.queue.ReceiveMessage(ctx, &sqs.ReceiveMessageInput{
+		QueueUrl:            aws.String(g.queueURL),
+		MaxNumberOfMessages: int32(batch),
+		WaitTimeSeconds:     int32(wait / time.Second),
+		VisibilityTimeout:   int32(visibility / time.Second),
+	})
+	if err != nil {
+		return err
+	}
+
+	var wg sync.WaitGroup
+	for _, msg := range out.Messages {
+		wg.Add(1)
+		go func(msg sqstypes.Message) {
+			defer wg.Done()
+			msgCtx, cancel := context.WithTimeout(ctx, visibility)
+			err := g.handle(msgCtx, aws.ToString(msg.Body))
+			cancel()
+			if err != nil {
+				e.Logger.Errorf("s3 ingest message %s: %v", aws.ToString(msg.MessageId), err)
+				return
+			}
+			if _, err := g.queue.DeleteMessage(ctx, &sqs.DeleteMessageInput{QueueUrl: aws.String(g.queueURL), ReceiptHandle: msg.ReceiptHandle}); err != nil {
+				e.Logger.Errorf("s3 ingest message %s: %v", aws.ToString(msg.MessageId), err)
+			}
+		}(msg)
+	}
+	wg.Wait()
+
+	return nil
+}
+
+// handle ingests the new objects of a notification. A notification that is
+// not an S3 event is logged and dropped, as no redelivery will parse it.
+func (g *s3Ingester) handle(ctx context.Context, body string) error {
+	objects, err := parseS3Event(body)
+	if err != nil {
+		e.Logger.Warnf("s3 ingest: dropping a message that is not an S3 event: %v", err)
+		return nil
+	}
+	analyses, err := parseAnalyses(s3IngestAnalyses)
+	if err != nil {
+		return fmt.Errorf("S3_INGEST_ANALYSES: %v", err)
+	}
+
+	for _, obj := range objects {
+		tags := map[string]string{"bucket": obj.Bucket}
+		if err := g.ingest(ctx, obj, analyses); err != nil {
+			metrics.Count("s3_ingest_errors_total", 1, tags)
+			return fmt.Errorf("s3://%s/%s: %v", obj.Bucket, obj.Key, err)
+		}
+		metrics.Count("s3_ingest_objects_total", 1, tags)
+	}
+
+	return nil
+}
+
+// readObject returns the text of an object and how it was read, or "" for
+// both if it is not ingested.
+func (g *s3Ingester) readObject(ctx context.Context, obj s3Object) (string, string, error) {
+	out, err := g.objects.GetObject(ctx, &s3.GetObjectInput{Bucket: aws.String(obj.Bucket), Key: aws.String(obj.Key)})
+	if err != nil {
+		return "", "", err
+	}
+	defer out.Body.Close()
+
+	switch format := objectFormat(obj.Key, aws.ToString(out.ContentType)); format {
+	case "text", "html":
+		limit := int64(envInt(s3IngestMaxBytes, 5242880))
+		body, err := ioutil.ReadAll(io.LimitReader(out.Body, limit+1))
+		if err != nil {
+			return "", "", err
+		}
+		if int64(len(body)) > limit {
+			return "", "", errObjectTooLarge
+		}
+		text := string(body)
+		if format == "html" {
+			if text, err = stripHTML(text); err != nil {
+				return "", "", err
+			}
+		}
+		return text, format, nil
+	case "pdf":
+		if g.pdfText == nil {
+			return "", "", errors.New("reading PDFs requires Textract")
+		}
+		text, err := g.pdfText(ctx, obj)
+		return text, "pdf", err
+	}
+
+	return "", "", nil
+}
+
+// ingest analyzes an object, as though the service's own key had sent it, and
+// stores its analyses as a record and writes them to the output prefix, as
+// configured. Objects under the output prefix are skipped, as are those that
+// are neither text nor PDFs, too large, or empty.
+func (g *s3Ingester) ingest(ctx context.Context, obj s3Object, analyses []string) error {
+	if bucket, _, ok := outputLocation(obj); ok && bucket == obj.Bucket && strings.HasPrefix(obj.Key, s3IngestOutputPrefix) {
+		return nil
+	}
+	text, format, err := g.readObject(ctx, obj)
+	if err == errObjectTooLarge {
+		e.Logger.Warnf("s3 ingest: skipping s3://%s/%s: %v", obj.Bucket, obj.Key, err)
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if text = strings.TrimSpace(text); text == "" {
+		return nil
+	}
+
+	key := integrationKey("")
+	results := runAnalysesConcurrently(ctx, text, analyses, key, envInt(analyzeConcurrency, 4))
+	if analysesStatus(results) == analysesFailed {
+		return results[analyses[0]].Error
+	}
+	if envBool(s3IngestRecord) {
+		req, err := http.NewRequestWithContext(ctx, http.MethodPost, "/s3", nil)
+		if err != nil {
+			return err
+		}
+		req.Header.Set("X-API-Key", key)
+		fields := map[string]interface{}{"source": "s3", "bucket": obj.Bucket, "key": obj.Key, "format": format, "analyses": results}
+		if s3IngestRecordPurpose != "" {
+			fields["purpose"] = s3IngestRecordPurpose
+		}
+		if err := storeRecord(e.NewContext(req, httptest.NewRecorder()), text, fields); err != nil {
+			return err
+		}
+	}
+	if bucket, outputKey, ok := outputLocation(obj); ok {
+		body, err := json.Marshal(&s3IngestResult{Source: "s3", Bucket: obj.Bucket, Key: obj.Key, Format: format, Text: text, Analyses: results})
+		if err != nil {
+			return err
+		}
+		if _, err := g.objects.PutObject(ctx, &s3.PutObjectInput{
+			Bucket:      aws.String(bucket),
+			Key:         aws.String(outputKey),
+			Body:        bytes.NewReader(body),
+			ContentType: aws.String("application/json"),
+		}); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func startS3Ingest() {
+	if s3IngestQueueURL == "" {
+		return
+	}
+	cfg, err := config.LoadDefaultConfig(context.Background())
+	if err != nil {
+		e.Logger.Errorf("S3 ingestion is off: %v", err)
+		return
+	}
+	ingester := &s3Ingester{
+		queue:    sqs.NewFromConfig(cfg),
+		queueURL: s3IngestQueueURL,
+		batch:    envInt(s3IngestBatchSize, 4),
+		objects:  s3.NewFromConfig(cfg),
+		pdfText:  textractPDF(textract.NewFromConfig(cfg), envDuration(s3IngestTextractInterval, 5*time.Second)),
+	}
+	wait := envDuration(s3IngestWait, 20*time.Second)
+	visibility := envDuration(s3IngestVisibilityTimeout, 5*time.Minute)
+	go func() {
+		for {
+			if err := ingester.receive(context.Background(), wait, visibility); err != nil {
+				e.Logger.Errorf("s3 ingest: %v", err)
+				time.Sleep(wait)
+			}
+		}
+	}()
+}
@@ -0,0 +1,182 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/sqs"
+	sqstypes "github.com/aws/aws-sdk-go-v2/service/sqs/types"
+	"github.com/aws/aws-sdk-go-v2/service/textract"
+	"github.com/aws/aws-sdk-go-v2/service/textract/types"
+	"github.com/garystafford/nlp-client/mockupstream"
+	"github.com/stretchr/testify/assert"
+)
+
+const s3Notification = `{"Records":[{"eventName":"ObjectCreated:Put","s3":{"bucket":{"name":"docs"},` +
+	`"object":{"key":"reports/Marie+Curie.txt","size":24}}},{"eventName":"ObjectRemoved:Delete","s3":{"bucket":{"name":"docs"},` +
+	`"object":{"key":"old.txt"}}}]}`
+
+type fakeSQS struct {
+	mu       sync.Mutex
+	messages []sqstypes.Message
+	received []int32
+	deleted  []string
+}
+
+func (f *fakeSQS) ReceiveMessage(_ context.Context, params *sqs.ReceiveMessageInput, _ ...func(*sqs.Options)) (*sqs.ReceiveMessageOutput, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	n := int(params.MaxNumberOfMessages)
+	if n > len(f.messages) {
+		n = len(f.messages)
+	}
+	messages := f.messages[:n]
+	f.messages = f.messages[n:]
+	f.received = append(f.received, params.MaxNumberOfMessages)
+	return &sqs.ReceiveMessageOutput{Messages: messages}, nil
+}
+
+func (f *fakeSQS) DeleteMessage(_ context.Context, params *sqs.DeleteMessageInput, _ ...func(*sqs.Options)) (*sqs.DeleteMessageOutput, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.deleted = append(f.deleted, aws.ToString(params.ReceiptHandle))
+	return &sqs.DeleteMessageOutput{}, nil
+}
+
+type fakeS3 struct {
+	mu      sync.Mutex
+	objects map[string]string
+	types   map[string]string
+	put     map[string]string
+}
+
+func (f *fakeS3) GetObject(_ context.Context, params *s3.GetObjectInput, _ ...func(*s3.Options)) (*s3.GetObjectOutput, error) {
+	body, ok := f.objects[aws.ToString(params.Key)]
+	if !ok {
+		return nil, errors.New("NoSuchKey")
+	}
+	return &s3.GetObjectOutput{Body: ioutil.NopCloser(strings.NewReader(body)), ContentType: aws.String(f.types[aws.ToString(params.Key)])}, nil
+}
+
+func (f *fakeS3) PutObject(_ context.Context, params *s3.PutObjectInput, _ ...func(*s3.Options)) (*s3.PutObjectOutput, error) {
+	body, _ := ioutil.ReadAll(params.Body)
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.put[aws.ToString(params.Bucket)+"/"+aws.ToString(params.Key)] = string(body)
+	return &s3.PutObjectOutput{}, nil
+}
+
+type fakeTextractJobs struct {
+	pages [][]types.Block
+	gets  int
+}
+
+func (f *fakeTextractJobs) StartDocumentTextDetection(_ context.Context, params *textract.StartDocumentTextDetectionInput, _ ...func(*textract.Options)) (*textract.StartDocumentTextDetectionOutput, error) {
+	return &textract.StartDocumentTextDetectionOutput{JobId: aws.String("job-" + aws.ToString(params.DocumentLocation.S3Object.Name))}, nil
+}
+
+func (f *fakeTextractJobs) GetDocumentTextDetection(_ context.Context, params *textract.GetDocumentTextDetectionInput, _ ...func(*textract.Options)) (*textract.GetDocumentTextDetectionOutput, error) {
+	f.gets++
+	if f.gets == 1 {
+		return &textract.GetDocumentTextDetectionOutput{JobStatus: types.JobStatusInProgress}, nil
+	}
+	page := 0
+	if params.NextToken != nil {
+		page = 1
+	}
+	out := &textract.GetDocumentTextDetectionOutput{JobStatus: types.JobStatusSucceeded, Blocks: f.pages[page]}
+	if page+1 < len(f.pages) {
+		out.NextToken = aws.String("page-2")
+	}
+	return out, nil
+}
+
+func TestParseS3Event(t *testing.T) {
+	objects, err := parseS3Event(s3Notification)
+	if assert.NoError(t, err) {
+		assert.Equal(t, []s3Object{{Bucket: "docs", Key: "reports/Marie Curie.txt", Size: 24}}, objects, "only created objects, with their keys decoded")
+	}
+
+	delivery, _ := json.Marshal(snsMessage{Type: "Notification", TopicArn: inboundTopic, Message: s3Notification})
+	objects, err = parseS3Event(string(delivery))
+	if assert.NoError(t, err) {
+		assert.Len(t, objects, 1, "notifications are unwrapped from SNS")
+	}
+
+	objects, err = parseS3Event(`{"Service":"Amazon S3","Event":"s3:TestEvent","Bucket":"docs"}`)
+	assert.NoError(t, err)
+	assert.Empty(t, objects)
+
+	_, err = parseS3Event("not an event")
+	assert.Error(t, err)
+}
+
+func TestObjectFormat(t *testing.T) {
+	assert.Equal(t, "text", objectFormat("notes.txt", "binary/octet-stream"))
+	assert.Equal(t, "text", objectFormat("notes", "text/plain; charset=utf-8"))
+	assert.Equal(t, "html", objectFormat("page.htm", ""))
+	assert.Equal(t, "pdf", objectFormat("report.PDF", ""))
+	assert.Equal(t, "pdf", objectFormat("report", "application/pdf"))
+	assert.Equal(t, "", objectFormat("photo.png", "image/png"))
+}
+
+func TestTextractPDF(t *testing.T) {
+	client := &fakeTextractJobs{pages: [][]types.Block{
+		{{BlockType: types.BlockTypeLine, Text: aws.String("Marie Curie")}, {BlockType: types.BlockTypeWord, Text: aws.String("Marie")}},
+		{{BlockType: types.BlockTypeLine, Text: aws.String("won the Nobel Prize.")}},
+	}}
+	text, err := textractPDF(client, time.Millisecond)(context.Background(), s3Object{Bucket: "docs", Key: "report.pdf"})
+	if assert.NoError(t, err) {
+		assert.Equal(t, "Marie Curie\nwon the Nobel Prize.", text, "every page of the job's results is read")
+	}
+}
+
+func TestS3IngesterReceive(t *testing.T) {
+	rake := mockupstream.NewRake()
+	defer rake.Close()
+	dynamo := mockupstream.NewDynamo()
+	defer dynamo.Close()
+	defer func(rakeURL, dynamoURL, analyses, prefix string) {
+		urlRake, urlDynamo, s3IngestAnalyses, s3IngestOutputPrefix = rakeURL, dynamoURL, analyses, prefix
+	}(urlRake, urlDynamo, s3IngestAnalyses, s3IngestOutputPrefix)
+	urlRake, urlDynamo, s3IngestAnalyses, s3IngestOutputPrefix = rake.URL, dynamo.URL, "keywords", "analyses/"
+
+	queue := &fakeSQS{messages: []sqstypes.Message{
+		{MessageId: aws.String("1"), ReceiptHandle: aws.String("created"), Body: aws.String(s3Notification)},
+		{MessageId: aws.String("2"), ReceiptHandle: aws.String("output"), Body: aws.String(strings.Replace(s3Notification, "reports/Marie+Curie.txt", "analyses/reports/Marie+Curie.txt.json", 1))},
+		{MessageId: aws.String("3"), ReceiptHandle: aws.String("missing"), Body: aws.String(strings.Replace(s3Notification, "Marie+Curie", "missing", 1))},
+		{MessageId: aws.String("4"), ReceiptHandle: aws.String("garbage"), Body: aws.String("not an event")},
+	}}
+	objects := &fakeS3{
+		objects: map[string]string{"reports/Marie Curie.txt": "The Nobel Prize is regarded as the most prestigious award."},
+		types:   map[string]string{},
+		put:     map[string]string{},
+	}
+	ingester := &s3Ingester{queue: queue, queueURL: "https://sqs.us-east-1.amazonaws.com/123456789012/docs", batch: 20, objects: objects}
+	assert.NoError(t, ingester.receive(context.Background(), 0, time.Minute))
+	assert.Equal(t, []int32{s3IngestMaxBatch}, queue.received, "a receive asks for at most what SQS returns")
+	assert.ElementsMatch(t, []string{"created", "output", "garbage"}, queue.deleted, "failed objects are left for redelivery")
+
+	if assert.Len(t, dynamo.Requests(), 1) {
+		var record map[string]interface{}
+		assert.NoError(t, json.Unmarshal([]byte(dynamo.Requests()[0].Body), &record))
+		assert.Equal(t, "s3", record["source"])
+		assert.Equal(t, "reports/Marie Curie.txt", record["key"])
+		assert.Contains(t, record, "analyses")
+	}
+	var result s3IngestResult
+	if assert.Contains(t, objects.put, "docs/analyses/reports/Marie Curie.txt.json") {
+		assert.NoError(t, json.Unmarshal([]byte(objects.put["docs/analyses/reports/Marie Curie.txt.json"]), &result))
+		assert.Equal(t, "text", result.Format)
+		assert.Equal(t, analysesOK, analysesStatus(result.Analyses))
+	}
+	assert.Len(t, objects.put, 1, "the output prefix is not ingested again")
+}
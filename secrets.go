@@ -0,0 +1,273 @@
+// author: Gary A. Stafford
+// site: https://programmaticponderings.com
+// license: MIT License
+// purpose: NLP microservices: nlp-client secrets
+// modified: 2026-10-14
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/secretsmanager"
+	"github.com/aws/aws-sdk-go-v2/service/ssm"
+)
+
+var (
+	secretRefreshInterval = getEnv("SECRET_REFRESH_INTERVAL", "5m")
+	vaultAddr             = getEnv("VAULT_ADDR", "http://127.0.0.1:8200")
+	vaultToken            = getEnv("VAULT_TOKEN", "")
+	vaultTimeout          = getEnv("VAULT_TIMEOUT", "10s")
+	apiKeys               = newSecret(apiKey)
+
+	// secrets are read directly, not through the upstream transport's fault
+	// injection, failover, and request signing
+	vaultClient = &http.Client{Timeout: envDuration(vaultTimeout, 10*time.Second)}
+)
+
+// secret holds a value that may be rotated while the service is running.
+type secret struct {
+	mu    sync.RWMutex
+	value string
+}
+
+func newSecret(value string) *secret {
+	return &secret{value: value}
+}
+
+func (s *secret) Get() string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return s.value
+}
+
+func (s *secret) Set(value string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.value = value
+}
+
+// Matches reports whether key is one of the comma-separated values of the
+// secret, which allows an old and a new key to overlap during rotation.
+func (s *secret) Matches(key string) bool {
+	for _, value := range strings.Split(s.Get(), ",") {
+		if value = strings.TrimSpace(value); value != "" && value == key {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Primary returns the first of the comma-separated values of the secret, the
+// one to send while an old and a new key overlap.
+func (s *secret) Primary() string {
+	for _, value := range strings.Split(s.Get(), ",") {
+		if value = strings.TrimSpace(value); value != "" {
+			return value
+		}
+	}
+
+	return ""
+}
+
+type secretLoader func(ctx context.Context) (string, error)
+
+// newSecretLoader returns a loader for a file://, ssm://, secretsmanager://, or vault:// source.
+// An optional #field fragment selects one field of a JSON secret.
+func newSecretLoader(source string) (secretLoader, error) {
+	u, err := url.Parse(source)
+	if err != nil {
+		return nil, err
+	}
+	name := strings.TrimPrefix(u.Host+u.Path, "/")
+
+	var load secretLoader
+	switch u.Scheme {
+	case "file":
+		load = func(ctx context.Context) (string, error) {
+			data, err := ioutil.ReadFile(u.Path)
+			return strings.TrimSpace(string(data)), err
+		}
+	case "ssm":
+		// parameter names are absolute paths
+		name = "/" + name
+		load = func(ctx context.Context) (string, error) {
+			cfg, err := config.LoadDefaultConfig(ctx)
+			if err != nil {
+				return "", err
+			}
+			out, err := ssm.NewFromConfig(cfg).GetParameter(ctx, &ssm.GetParameterInput{
+				Name:           aws.String(name),
+				WithDecryption: true,
+			})
+			if err != nil {
+				return "", err
+			}
+			return aws.ToString(out.Parameter.Value), nil
+		}
+	case "secretsmanager":
+		load = func(ctx context.Context) (string, error) {
+			cfg, err := config.LoadDefaultConfig(ctx)
+			if err != nil {
+				return "", err
+			}
+			out, err := secretsmanager.NewFromConfig(cfg).GetSecretValue(ctx, &secretsmanager.GetSecretValueInput{
+				SecretId: aws.String(name),
+			})
+			if err != nil {
+				return "", err
+			}
+			return aws.ToString(out.SecretString), nil
+		}
+	case "vault":
+		load = func(ctx context.Context) (string, error) {
+			return readVault(ctx, name)
+		}
+	default:
+		return nil, fmt.Errorf("unsupported secret source: %s", source)
+	}
+
+	if u.Fragment == "" {
+		return load, nil
+	}
+
+	return func(ctx context.Context) (string, error) {
+		value, err := load(ctx)
+		if err != nil {
+			return "", err
+		}
+		return secretField(value, u.Fragment)
+	}, nil
+}
+
+// readVault reads a KV secret, unwrapping the extra data level of KV version 2.
+func readVault(ctx context.Context, path string) (string, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, strings.TrimSuffix(vaultAddr, "/")+"/v1/"+path, nil)
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("X-Vault-Token", vaultToken)
+
+	resp, err := vaultClient.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("vault returned %d for %s", resp.StatusCode, path)
+	}
+
+	var body struct {
+		Data map[string]json.RawMessage `json:"data"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return "", err
+	}
+	if nested, ok := body.Data["data"]; ok {
+		return string(nested), nil
+	}
+	data, err := json.Marshal(body.Data)
+
+	return string(data), err
+}
+
+func secretField(value, field string) (string, error) {
+	var fields map[string]interface{}
+	if err := json.Unmarshal([]byte(value), &fields); err != nil {
+		return "", fmt.Errorf("secret is not a JSON object: %v", err)
+	}
+	v, ok := fields[field]
+	if !ok {
+		return "", fmt.Errorf("secret has no field %q", field)
+	}
+
+	return fmt.Sprint(v), nil
+}
+
+// watchSecret loads the secret immediately and then refreshes it on every interval,
+// keeping the last good value whenever a refresh fails.
+func watchSecret(ctx context.Context, s *secret, load secretLoader, interval time.Duration) error {
+	value, err := load(ctx)
+	if err != nil {
+		return err
+	}
+	s.Set(value)
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				value, err := load(ctx)
+				if err != nil {
+					e.Logger.Errorf("secret refresh failed: %v", err)
+					continue
+				}
+				s.Set(value)
+			}
+		}
+	}()
+
+	return nil
+}
+
+type sourcedSecret struct {
+	name   string
+	secret *secret
+}
+
+// sourcedSecrets are the credentials that may be loaded from a store through
+// <name>_SOURCE, e.g. API_KEY_SOURCE=file:///var/run/secrets/api-key,
+// ssm:///nlp/api-key, secretsmanager://nlp#api_key, or
+// vault://secret/data/nlp#api_key; each falls back to <name> when its source
+// is unset. REDIS_PASSWORD and
+// RECORD_ENCRYPTION_KEY are read once at startup, and VAULT_TOKEN reads the
+// vault sources themselves, so none of them is here.
+var sourcedSecrets = []sourcedSecret{
+	{"API_KEY", apiKeys},
+	{"ADMIN_API_KEY", adminKeys},
+	{"OAUTH2_CLIENT_SECRET", oauth2ClientSecret},
+	{"SLACK_SIGNING_SECRET", slackSigningSecret},
+	{"SLACK_API_KEY", slackAPIKey},
+	{"GITHUB_WEBHOOK_SECRET", githubWebhookSecret},
+	{"GITHUB_API_KEY", githubAPIKey},
+	{"GITHUB_TOKEN", githubToken},
+	{"INBOUND_PASSWORD", inboundPassword},
+	{"INBOUND_API_KEY", inboundAPIKey},
+}
+
+// loadSecrets starts watching the source of every secret that has one configured.
+func loadSecrets() error {
+	interval := envDuration(secretRefreshInterval, 5*time.Minute)
+	for _, s := range sourcedSecrets {
+		source := getEnv(s.name+"_SOURCE", "")
+		if source == "" {
+			continue
+		}
+		load, err := newSecretLoader(source)
+		if err != nil {
+			return fmt.Errorf("%s_SOURCE: %v", s.name, err)
+		}
+		if err := watchSecret(context.Background(), s.secret, load, interval); err != nil {
+			return fmt.Errorf("%s_SOURCE: %v", s.name, err)
+		}
+	}
+
+	return nil
+}
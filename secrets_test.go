@@ -0,0 +1,90 @@
+package main
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSecretMatches(t *testing.T) {
+	s := newSecret("old-key, new-key")
+
+	assert.True(t, s.Matches("old-key"))
+	assert.True(t, s.Matches("new-key"))
+	assert.False(t, s.Matches(""))
+	assert.False(t, s.Matches("other-key"))
+}
+
+func TestSecretPrimary(t *testing.T) {
+	assert.Equal(t, "old-key", newSecret(" old-key, new-key").Primary(), "only the first key is sent during rotation")
+	assert.Equal(t, "new-key", newSecret(",new-key").Primary())
+	assert.Equal(t, "", newSecret("").Primary())
+}
+
+func TestWatchSecretFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "api-key")
+	if !assert.NoError(t, ioutil.WriteFile(path, []byte("first\n"), 0600)) {
+		return
+	}
+	load, err := newSecretLoader("file://" + path)
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	s := newSecret("")
+	if assert.NoError(t, watchSecret(ctx, s, load, 10*time.Millisecond)) {
+		assert.Equal(t, "first", s.Get())
+		assert.NoError(t, ioutil.WriteFile(path, []byte("second\n"), 0600))
+		assert.Eventually(t, func() bool { return s.Get() == "second" }, time.Second, 10*time.Millisecond)
+	}
+}
+
+func TestLoadSecrets(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "slack-signing-secret")
+	if !assert.NoError(t, ioutil.WriteFile(path, []byte("signing-secret\n"), 0600)) {
+		return
+	}
+	defer func(value string) { slackSigningSecret.Set(value) }(slackSigningSecret.Get())
+	defer os.Unsetenv("SLACK_SIGNING_SECRET_SOURCE")
+	assert.NoError(t, os.Setenv("SLACK_SIGNING_SECRET_SOURCE", "file://"+path))
+
+	if assert.NoError(t, loadSecrets()) {
+		assert.Equal(t, "signing-secret", slackSigningSecret.Get(), "every sourced secret is loaded, not only the API key")
+	}
+
+	assert.NoError(t, os.Setenv("SLACK_SIGNING_SECRET_SOURCE", "ftp://secrets/slack"))
+	assert.EqualError(t, loadSecrets(), "SLACK_SIGNING_SECRET_SOURCE: unsupported secret source: ftp://secrets/slack")
+}
+
+func TestSecretLoaderVault(t *testing.T) {
+	vault := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/v1/secret/data/nlp", r.URL.Path)
+		assert.Equal(t, "token", r.Header.Get("X-Vault-Token"))
+		_, _ = w.Write([]byte(`{"data": {"data": {"api_key": "from-vault"}, "metadata": {"version": 2}}}`))
+	}))
+	defer vault.Close()
+	defer func(addr, token string) { vaultAddr, vaultToken = addr, token }(vaultAddr, vaultToken)
+	vaultAddr, vaultToken = vault.URL, "token"
+
+	load, err := newSecretLoader("vault://secret/data/nlp#api_key")
+	if assert.NoError(t, err) {
+		value, err := load(context.Background())
+		if assert.NoError(t, err) {
+			assert.Equal(t, "from-vault", value)
+		}
+	}
+}
+
+func TestSecretLoaderUnsupported(t *testing.T) {
+	_, err := newSecretLoader("ftp://secrets/api-key")
+	assert.EqualError(t, err, "unsupported secret source: ftp://secrets/api-key")
+}
@@ -0,0 +1,215 @@
+// author: Gary A. Stafford
+// site: https://programmaticponderings.com
+// license: MIT License
+// purpose: NLP microservices: nlp-client sentence and aspect sentiment
+// modified: 2026-10-14
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/labstack/echo/v4"
+)
+
+var (
+	// sentiment backend answering POST /sentiment with {"score": -1.0-1.0, ...}; unset disables /sentiment
+	urlSentiment = upstreamURL(getEnv("SENTIMENT_ENDPOINT", ""))
+	// scores no further from 0 than this are neutral
+	sentimentNeutral = getEnv("SENTIMENT_NEUTRAL", "0.05")
+)
+
+// sentimentAnalyses are run on the text to score its sentences and entities.
+var sentimentAnalyses = []string{"sentences", "entities"}
+
+// sentimentRequest is a text and, besides its entities, the aspects to score
+// sentiment toward, such as "battery life".
+type sentimentRequest struct {
+	Text    string   `json:"text"`
+	Aspects []string `json:"aspects"`
+}
+
+// sentimentScore is the part of the backend's answer a sentence is scored by.
+type sentimentScore struct {
+	Score float64 `json:"score"`
+}
+
+type sentenceSentiment struct {
+	Text string `json:"text"`
+	span
+	Score     float64 `json:"score"`
+	Sentiment string  `json:"sentiment"`
+}
+
+// aspectSentiment is the sentiment toward an entity or requested aspect: the
+// mean score of the sentences it is mentioned in, once per mention.
+type aspectSentiment struct {
+	Text      string  `json:"text"`
+	Label     string  `json:"label,omitempty"`
+	Score     float64 `json:"score"`
+	Sentiment string  `json:"sentiment"`
+	Mentions  int     `json:"mentions"`
+	first     int
+}
+
+type sentimentResult struct {
+	Score     float64             `json:"score"`
+	Sentiment string              `json:"sentiment"`
+	Sentences []sentenceSentiment `json:"sentences"`
+	Aspects   []aspectSentiment   `json:"aspects"`
+}
+
+func sentimentNotConfigured() *apiError {
+	return newAPIError(http.StatusNotFound, codeNotFound, "sentiment is not configured")
+}
+
+// polarity names a score positive, negative, or, within SENTIMENT_NEUTRAL of 0, neutral.
+func polarity(score float64) string {
+	neutral := envFloat(sentimentNeutral, 0.05)
+	switch {
+	case score > neutral:
+		return "positive"
+	case score < -neutral:
+		return "negative"
+	}
+
+	return "neutral"
+}
+
+// scoreSentences scores each sentence with the backend at once, failing with
+// the first sentence the backend fails on.
+func scoreSentences(ctx context.Context, sentences []string, key string) ([]float64, error) {
+	scores := make([]float64, len(sentences))
+	errs := make([]error, len(sentences))
+	var wg sync.WaitGroup
+	for i, sentence := range sentences {
+		wg.Add(1)
+		go func(i int, sentence string) {
+			defer wg.Done()
+			body, err := postUpstream(ctx, urlSentiment+"/sentiment", sentence, key)
+			if err != nil {
+				errs[i] = err
+				return
+			}
+			var score sentimentScore
+			if errs[i] = json.Unmarshal(body, &score); errs[i] == nil {
+				scores[i] = score.Score
+			}
+		}(i, sentence)
+	}
+	wg.Wait()
+	for _, err := range errs {
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return scores, nil
+}
+
+// aspectSentiments scores each entity, and each requested aspect, found in
+// text by the sentences it is mentioned in, in the order they are first
+// mentioned; those the text does not mention are left out.
+func aspectSentiments(text string, sentences []sentenceSentiment, entities []v2Item, aspects []string) []aspectSentiment {
+	for _, aspect := range aspects {
+		entities = append(entities, v2Item{Text: aspect})
+	}
+	runes := foldRunes(text)
+	seen := map[string]bool{}
+	result := []aspectSentiment{}
+	for _, entity := range entities {
+		key := strings.ToLower(entity.Text) + "\x00" + entity.Label
+		needle := foldRunes(entity.Text)
+		if seen[key] || len(needle) == 0 {
+			continue
+		}
+		seen[key] = true
+		aspect := aspectSentiment{Text: entity.Text, Label: entity.Label, first: -1}
+		total := 0.0
+		for i := indexRunes(runes, needle, 0); i >= 0; i = indexRunes(runes, needle, i+len(needle)) {
+			for _, sentence := range sentences {
+				if sentence.Offset != nil && *sentence.Offset <= i && i < *sentence.Offset+sentence.Length {
+					if aspect.first < 0 {
+						aspect.first = i
+					}
+					total += sentence.Score
+					aspect.Mentions++
+					break
+				}
+			}
+		}
+		if aspect.Mentions == 0 {
+			continue
+		}
+		aspect.Score = total / float64(aspect.Mentions)
+		aspect.Sentiment = polarity(aspect.Score)
+		result = append(result, aspect)
+	}
+	sort.SliceStable(result, func(i, j int) bool { return result[i].first < result[j].first })
+
+	return result
+}
+
+// postSentiment scores the sentiment of each sentence of a text, of the text
+// as a whole, weighted by the length of its sentences, and toward each of its
+// entities and requested aspects.
+func postSentiment(c echo.Context) error {
+	if urlSentiment == "" {
+		return sentimentNotConfigured()
+	}
+	var body sentimentRequest
+	if err := json.NewDecoder(c.Request().Body).Decode(&body); err != nil {
+		return validationError(err.Error())
+	}
+	result, err := scoreSentiment(c.Request().Context(), body, c.Request().Header.Get("X-API-Key"))
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusOK, result)
+}
+
+// scoreSentiment scores the sentiment of a request's text, as postSentiment
+// answers it.
+func scoreSentiment(ctx context.Context, body sentimentRequest, key string) (*sentimentResult, error) {
+	results := runAnalyses(ctx, body.Text, sentimentAnalyses, key)
+	for _, name := range sentimentAnalyses {
+		if results[name].Error != nil {
+			return nil, results[name].Error
+		}
+	}
+	var sentences []string
+	var entities []v2Item
+	if err := json.Unmarshal(results["sentences"].Result, &sentences); err != nil {
+		return nil, internalError(err)
+	}
+	if err := json.Unmarshal(results["entities"].Result, &entities); err != nil {
+		return nil, internalError(err)
+	}
+	scores, err := scoreSentences(ctx, sentences, key)
+	if err != nil {
+		return nil, upstreamError(err)
+	}
+
+	result := &sentimentResult{Sentences: []sentenceSentiment{}}
+	loc := &locator{text: foldRunes(body.Text)}
+	total, length := 0.0, 0
+	for i, sentence := range sentences {
+		s := sentenceSentiment{Text: sentence, span: loc.locate(sentence), Score: scores[i], Sentiment: polarity(scores[i])}
+		result.Sentences = append(result.Sentences, s)
+		total += s.Score * float64(s.Length)
+		length += s.Length
+	}
+	if length > 0 {
+		result.Score = total / float64(length)
+	}
+	result.Sentiment = polarity(result.Score)
+	result.Aspects = aspectSentiments(body.Text, result.Sentences, entities, body.Aspects)
+
+	return result, nil
+}
@@ -0,0 +1,75 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/garystafford/nlp-client/mockupstream"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPolarity(t *testing.T) {
+	assert.Equal(t, "positive", polarity(0.5))
+	assert.Equal(t, "negative", polarity(-0.5))
+	assert.Equal(t, "neutral", polarity(0.05))
+}
+
+func TestAspectSentiments(t *testing.T) {
+	text := "Acme phones are great. The battery life of Acme phones is poor. Globex is fine."
+	offset := func(i int) *int { return &i }
+	sentences := []sentenceSentiment{
+		{Text: "Acme phones are great.", span: span{Offset: offset(0), Length: 22}, Score: 0.8},
+		{Text: "The battery life of Acme phones is poor.", span: span{Offset: offset(23), Length: 40}, Score: -0.4},
+	}
+	entities := []v2Item{{Text: "Acme", Label: "ORG"}, {Text: "acme", Label: "ORG"}, {Text: "Globex", Label: "ORG"}}
+	aspects := aspectSentiments(text, sentences, entities, []string{"battery life", "price"})
+	if assert.Len(t, aspects, 2, "duplicates, and aspects outside the scored sentences, are left out") {
+		assert.Equal(t, "Acme", aspects[0].Text)
+		assert.Equal(t, 2, aspects[0].Mentions)
+		assert.InDelta(t, 0.2, aspects[0].Score, 1e-9)
+		assert.Equal(t, "positive", aspects[0].Sentiment)
+		assert.Equal(t, aspectSentiment{Text: "battery life", Score: -0.4, Sentiment: "negative", Mentions: 1, first: 27}, aspects[1])
+	}
+}
+
+func TestPostSentiment(t *testing.T) {
+	w := httptest.NewRecorder()
+	err := postSentiment(e.NewContext(httptest.NewRequest(http.MethodPost, "/sentiment", strings.NewReader(`{"text": "Hi"}`)), w))
+	assert.Equal(t, sentimentNotConfigured(), err)
+
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body struct{ Text string }
+		_ = json.NewDecoder(r.Body).Decode(&body)
+		score := -0.6
+		if strings.Contains(body.Text, "love") {
+			score = 0.9
+		}
+		_ = json.NewEncoder(w).Encode(map[string]float64{"score": score})
+	}))
+	defer backend.Close()
+	defer func(url string) { urlSentiment = url }(urlSentiment)
+	urlSentiment = backend.URL
+	prose := mockupstream.NewProse()
+	defer prose.Close()
+	defer func(url string) { urlProse = url }(urlProse)
+	urlProse = prose.URL
+	prose.SetFixture("/sentences", mockupstream.Fixture{Status: http.StatusOK, Body: `["I love the screen.","The battery life is awful."]`})
+	prose.SetFixture("/entities", mockupstream.Fixture{Status: http.StatusOK, Body: `[]`})
+
+	req := httptest.NewRequest(http.MethodPost, "/sentiment",
+		strings.NewReader(`{"text": "I love the screen. The battery life is awful.", "aspects": ["battery life"]}`))
+	w = httptest.NewRecorder()
+	if assert.NoError(t, postSentiment(e.NewContext(req, w))) {
+		var result sentimentResult
+		if assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &result)) {
+			assert.InDelta(t, (18*0.9-26*0.6)/44, result.Score, 1e-9, "sentences are weighted by their length")
+			assert.Equal(t, "neutral", result.Sentiment)
+			offset := 19
+			assert.Equal(t, sentenceSentiment{Text: "The battery life is awful.", span: span{Offset: &offset, Length: 26}, Score: -0.6, Sentiment: "negative"}, result.Sentences[1])
+			assert.Equal(t, []aspectSentiment{{Text: "battery life", Score: -0.6, Sentiment: "negative", Mentions: 1}}, result.Aspects)
+		}
+	}
+}
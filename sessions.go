@@ -0,0 +1,262 @@
+// author: Gary A. Stafford
+// site: https://programmaticponderings.com
+// license: MIT License
+// purpose: NLP microservices: nlp-client conversational sessions
+// modified: 2026-10-14
+
+package main
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"github.com/labstack/echo/v4"
+)
+
+const turnSeparator = "\n\n"
+
+var (
+	sessionTTL = getEnv("SESSION_TTL", "30m")
+	// characters of prior turns sent as context with each turn
+	sessionContextChars = getEnv("SESSION_CONTEXT_CHARS", "10000")
+	sessions            = newSessionStore(envDuration(sessionTTL, 30*time.Minute), envInt(sessionContextChars, 10000))
+)
+
+// session is a conversation whose turns are analyzed in the context of the
+// ones before them. Only the most recent turns that fit the context are kept.
+type session struct {
+	id      string
+	owner   string
+	turns   []string
+	count   int
+	expires time.Time
+}
+
+// sessionStore keeps the sessions of a single instance in memory, each
+// expiring once it has gone unused for the TTL.
+type sessionStore struct {
+	mu           sync.Mutex
+	sessions     map[string]*session
+	ttl          time.Duration
+	contextChars int
+	now          func() time.Time
+}
+
+func newSessionStore(ttl time.Duration, contextChars int) *sessionStore {
+	return &sessionStore{sessions: map[string]*session{}, ttl: ttl, contextChars: contextChars, now: time.Now}
+}
+
+func (s *sessionStore) create(owner string) (*session, error) {
+	id := make([]byte, 16)
+	if _, err := rand.Read(id); err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.now()
+	for key, sess := range s.sessions {
+		if !now.Before(sess.expires) {
+			delete(s.sessions, key)
+		}
+	}
+	sess := &session{id: hex.EncodeToString(id), owner: owner, expires: now.Add(s.ttl)}
+	s.sessions[sess.id] = sess
+
+	return sess, nil
+}
+
+// get returns a live session of owner; other owners' sessions are not found.
+func (s *sessionStore) get(id, owner string) (*session, bool) {
+	sess, ok := s.sessions[id]
+	if !ok || sess.owner != owner || !s.now().Before(sess.expires) {
+		return nil, false
+	}
+
+	return sess, true
+}
+
+// context returns the prior turns of a session, as sent ahead of the next turn.
+func (s *sessionStore) context(id, owner string) (string, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	sess, ok := s.get(id, owner)
+	if !ok {
+		return "", false
+	}
+
+	return strings.Join(sess.turns, ""), true
+}
+
+// addTurn records a turn, dropping the oldest turns beyond the context, and
+// returns its number in the session.
+func (s *sessionStore) addTurn(id, owner, text string) (int, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	sess, ok := s.get(id, owner)
+	if !ok {
+		return 0, false
+	}
+	sess.turns = append(sess.turns, text+turnSeparator)
+	sess.count++
+	sess.expires = s.now().Add(s.ttl)
+
+	total := 0
+	for i := len(sess.turns) - 1; i >= 0; i-- {
+		total += utf8.RuneCountInString(sess.turns[i])
+		if total > s.contextChars {
+			sess.turns = sess.turns[i+1:]
+			break
+		}
+	}
+
+	return sess.count, true
+}
+
+func (s *sessionStore) delete(id, owner string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.get(id, owner); !ok {
+		return false
+	}
+	delete(s.sessions, id)
+
+	return true
+}
+
+func sessionOwner(c echo.Context) string {
+	return keyID(c.Request().Header.Get("X-API-Key"))
+}
+
+func sessionNotFound() *apiError {
+	return newAPIError(http.StatusNotFound, codeNotFound, "session not found")
+}
+
+// scopeToTurn keeps the items of a v2 result of the whole conversation that
+// fall in the turn starting at offset start, with offsets into the turn.
+func scopeToTurn(route string, body json.RawMessage, start int) (json.RawMessage, error) {
+	switch route {
+	case "/tokens", "/sentences", "/entities":
+		var items []v2Item
+		if err := json.Unmarshal(body, &items); err != nil {
+			return nil, err
+		}
+		scoped := []v2Item{}
+		for _, item := range items {
+			if item.Offset != nil && *item.Offset >= start {
+				offset := *item.Offset - start
+				item.Offset = &offset
+				scoped = append(scoped, item)
+			}
+		}
+		return json.Marshal(scoped)
+	case "/keywords":
+		var keywords []v2Keyword
+		if err := json.Unmarshal(body, &keywords); err != nil {
+			return nil, err
+		}
+		scoped := []v2Keyword{}
+		for _, keyword := range keywords {
+			offsets := []int{}
+			for _, offset := range keyword.Offsets {
+				if offset >= start {
+					offsets = append(offsets, offset-start)
+				}
+			}
+			if len(offsets) > 0 {
+				keyword.Offsets = offsets
+				scoped = append(scoped, keyword)
+			}
+		}
+		return json.Marshal(scoped)
+	}
+
+	return body, nil
+}
+
+func postSession(c echo.Context) error {
+	sess, err := sessions.create(sessionOwner(c))
+	if err != nil {
+		return internalError(err)
+	}
+
+	return c.JSON(http.StatusCreated, struct {
+		ID        string    `json:"id"`
+		ExpiresAt time.Time `json:"expires_at"`
+	}{sess.id, sess.expires.UTC()})
+}
+
+func deleteSession(c echo.Context) error {
+	if !sessions.delete(c.Param("id"), sessionOwner(c)) {
+		return sessionNotFound()
+	}
+
+	return c.NoContent(http.StatusNoContent)
+}
+
+// postSessionAnalyze analyzes a turn along with the prior turns of its
+// session, so the upstreams see the conversation so far, and answers with the
+// results found in the turn itself, in the v2 schema.
+func postSessionAnalyze(c echo.Context) error {
+	analyses, err := parseAnalyses(c.QueryParam("analyses"))
+	if err != nil {
+		return validationError(err.Error())
+	}
+	var body struct {
+		Text string `json:"text"`
+	}
+	if err := json.NewDecoder(c.Request().Body).Decode(&body); err != nil {
+		return validationError(err.Error())
+	}
+	id, owner := c.Param("id"), sessionOwner(c)
+	prior, ok := sessions.context(id, owner)
+	if !ok {
+		return sessionNotFound()
+	}
+
+	conversation := prior + body.Text
+	start := utf8.RuneCountInString(prior)
+	results := runAnalyses(c.Request().Context(), conversation, analyses, c.Request().Header.Get("X-API-Key"))
+	for name, result := range results {
+		if result.Error != nil {
+			continue
+		}
+		result.Result, err = normalizeResult("/"+name, conversation, result.Result)
+		if err == nil {
+			result.Result, err = scopeToTurn("/"+name, result.Result, start)
+		}
+		if err != nil {
+			results[name] = failedAnalysis(internalError(err))
+			continue
+		}
+		results[name] = result
+	}
+	turn, ok := sessions.addTurn(id, owner, body.Text)
+	if !ok {
+		return sessionNotFound()
+	}
+
+	status, code := analysesResponse(results, analyses)
+	var meta *responseMeta
+	if wantsMeta(c) {
+		meta = analysesMeta(conversation, analyses, results)
+	}
+
+	return c.JSON(code, struct {
+		Session  string                    `json:"session"`
+		Turn     int                       `json:"turn"`
+		Status   string                    `json:"status"`
+		Analyses map[string]analysisResult `json:"analyses"`
+		Meta     *responseMeta             `json:"_meta,omitempty"`
+	}{id, turn, status, results, meta})
+}
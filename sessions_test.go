@@ -0,0 +1,102 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSessionStore(t *testing.T) {
+	now := time.Date(2026, 10, 14, 12, 0, 0, 0, time.UTC)
+	store := newSessionStore(time.Minute, 12)
+	store.now = func() time.Time { return now }
+
+	sess, err := store.create("owner")
+	if !assert.NoError(t, err) {
+		return
+	}
+	_, ok := store.context(sess.id, "other")
+	assert.False(t, ok, "sessions are private to their owner")
+
+	for _, text := range []string{"one", "two", "three"} {
+		_, ok := store.addTurn(sess.id, "owner", text)
+		assert.True(t, ok)
+	}
+	prior, ok := store.context(sess.id, "owner")
+	assert.True(t, ok)
+	assert.Equal(t, "two\n\nthree\n\n", prior, "the oldest turns beyond the context are dropped")
+
+	now = now.Add(time.Minute)
+	_, ok = store.context(sess.id, "owner")
+	assert.False(t, ok, "sessions expire")
+}
+
+func TestScopeToTurn(t *testing.T) {
+	items := `[{"text":"Marie","offset":0,"length":5},{"text":"she","offset":20,"length":3},{"text":"lost","offset":null,"length":4}]`
+	scoped, err := scopeToTurn("/tokens", json.RawMessage(items), 18)
+	if assert.NoError(t, err) {
+		assert.JSONEq(t, `[{"text":"she","offset":2,"length":3}]`, string(scoped))
+	}
+
+	keywords := `[{"text":"prize","score":1,"offsets":[4,30]},{"text":"nobel","score":1,"offsets":[0]}]`
+	scoped, err = scopeToTurn("/keywords", json.RawMessage(keywords), 18)
+	if assert.NoError(t, err) {
+		assert.JSONEq(t, `[{"text":"prize","score":1,"offsets":[12]}]`, string(scoped))
+	}
+}
+
+func TestPostSessionAnalyze(t *testing.T) {
+	var sent []string
+	prose := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body struct {
+			Text string `json:"text"`
+		}
+		_ = json.NewDecoder(r.Body).Decode(&body)
+		sent = append(sent, body.Text)
+		w.Header().Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		_ = json.NewEncoder(w).Encode(strings.Fields(body.Text))
+	}))
+	defer prose.Close()
+	defer func(url string) { urlProse = url }(urlProse)
+	urlProse = prose.URL
+	defer func(store *sessionStore) { sessions = store }(sessions)
+	sessions = newSessionStore(time.Minute, 1000)
+
+	call := func(req *http.Request, handler echo.HandlerFunc, id string) *httptest.ResponseRecorder {
+		req.Header.Set("X-API-Key", "secret")
+		w := httptest.NewRecorder()
+		c := e.NewContext(req, w)
+		c.SetParamNames("id")
+		c.SetParamValues(id)
+		assert.NoError(t, handler(c))
+		return w
+	}
+
+	w := call(httptest.NewRequest(http.MethodPost, "/sessions", nil), postSession, "")
+	assert.Equal(t, http.StatusCreated, w.Code)
+	var created struct {
+		ID string `json:"id"`
+	}
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &created))
+
+	analyze := func(text string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/sessions/"+created.ID+"/analyze?analyses=tokens", strings.NewReader(`{"text": "`+text+`"}`))
+		return call(req, postSessionAnalyze, created.ID)
+	}
+	analyze("Marie Curie won")
+	w = analyze("She won again")
+
+	assert.Equal(t, []string{"Marie Curie won", "Marie Curie won\n\nShe won again"}, sent)
+	expected := `{"session":"` + created.ID + `","turn":2,"status":"ok","analyses":{"tokens":{"status":200,"result":[` +
+		`{"text":"She","offset":0,"length":3},{"text":"won","offset":4,"length":3},{"text":"again","offset":8,"length":5}]}}}`
+	assert.JSONEq(t, expected, w.Body.String())
+
+	w = call(httptest.NewRequest(http.MethodDelete, "/sessions/"+created.ID, nil), deleteSession, created.ID)
+	assert.Equal(t, http.StatusNoContent, w.Code)
+}
@@ -0,0 +1,133 @@
+// author: Gary A. Stafford
+// site: https://programmaticponderings.com
+// license: MIT License
+// purpose: NLP microservices: nlp-client SigV4 signing of upstream requests
+// modified: 2026-10-14
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	v4 "github.com/aws/aws-sdk-go-v2/aws/signer/v4"
+	"github.com/aws/aws-sdk-go-v2/config"
+)
+
+// upstreams to sign and how, e.g. "rake=execute-api:us-east-1;prose=execute-api";
+// the region defaults to the one of the task's AWS configuration
+var upstreamSigV4 = getEnv("UPSTREAM_SIGV4", "")
+
+type sigV4Scope struct {
+	service string
+	region  string
+}
+
+func parseSigV4(config string) (map[string]sigV4Scope, error) {
+	scopes := map[string]sigV4Scope{}
+	for _, entry := range strings.Split(config, ";") {
+		if strings.TrimSpace(entry) == "" {
+			continue
+		}
+		parts := strings.SplitN(entry, "=", 2)
+		if len(parts) != 2 {
+			return nil, fmt.Errorf("invalid SigV4 upstream: %s", entry)
+		}
+		values := strings.SplitN(parts[1], ":", 2)
+		scope := sigV4Scope{service: strings.TrimSpace(values[0])}
+		if len(values) == 2 {
+			scope.region = strings.TrimSpace(values[1])
+		}
+		if scope.service == "" {
+			return nil, fmt.Errorf("invalid SigV4 upstream: %s", entry)
+		}
+		scopes[strings.TrimSpace(parts[0])] = scope
+	}
+
+	return scopes, nil
+}
+
+// sigV4Transport signs the requests to the configured upstreams with the
+// credentials of the task role, or whichever the default chain finds.
+type sigV4Transport struct {
+	next   http.RoundTripper
+	scopes map[string]sigV4Scope
+	signer *v4.Signer
+
+	once   sync.Once
+	cfg    aws.Config
+	cfgErr error
+	load   func(ctx context.Context) (aws.Config, error)
+	now    func() time.Time
+}
+
+func (t *sigV4Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	scope, ok := t.scopes[upstreamName(req.URL.Host)]
+	if !ok {
+		return t.next.RoundTrip(req)
+	}
+
+	t.once.Do(func() {
+		t.cfg, t.cfgErr = t.load(context.Background())
+	})
+	if t.cfgErr != nil {
+		return nil, t.cfgErr
+	}
+	creds, err := t.cfg.Credentials.Retrieve(req.Context())
+	if err != nil {
+		return nil, err
+	}
+	region := scope.region
+	if region == "" {
+		region = t.cfg.Region
+	}
+
+	// the signature covers the payload, so the body is read before sending
+	var body []byte
+	if req.Body != nil {
+		if body, err = ioutil.ReadAll(req.Body); err != nil {
+			return nil, err
+		}
+		req.Body.Close()
+	}
+	signed := req.Clone(req.Context())
+	signed.Body = ioutil.NopCloser(bytes.NewReader(body))
+	signed.ContentLength = int64(len(body))
+	hash := sha256.Sum256(body)
+
+	if err := t.signer.SignHTTP(req.Context(), creds, signed, hex.EncodeToString(hash[:]), scope.service, region, t.now()); err != nil {
+		return nil, err
+	}
+
+	return t.next.RoundTrip(signed)
+}
+
+func withSigV4(next http.RoundTripper) http.RoundTripper {
+	scopes, err := parseSigV4(upstreamSigV4)
+	if err != nil {
+		e.Logger.Errorf("ignoring UPSTREAM_SIGV4: %v", err)
+		return next
+	}
+	if len(scopes) == 0 {
+		return next
+	}
+
+	return &sigV4Transport{
+		next:   next,
+		scopes: scopes,
+		signer: v4.NewSigner(),
+		load: func(ctx context.Context) (aws.Config, error) {
+			return config.LoadDefaultConfig(ctx)
+		},
+		now: time.Now,
+	}
+}
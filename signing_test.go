@@ -0,0 +1,69 @@
+package main
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	v4 "github.com/aws/aws-sdk-go-v2/aws/signer/v4"
+	"github.com/aws/aws-sdk-go-v2/credentials"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseSigV4(t *testing.T) {
+	scopes, err := parseSigV4("rake=execute-api:us-east-1; prose=execute-api")
+	if assert.NoError(t, err) {
+		assert.Equal(t, map[string]sigV4Scope{
+			"rake":  {"execute-api", "us-east-1"},
+			"prose": {"execute-api", ""},
+		}, scopes)
+	}
+
+	_, err = parseSigV4("rake")
+	assert.Error(t, err)
+	_, err = parseSigV4("rake=:us-east-1")
+	assert.Error(t, err)
+}
+
+func TestSigV4Transport(t *testing.T) {
+	var received *http.Request
+	var body string
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received = r
+		data, _ := ioutil.ReadAll(r.Body)
+		body = string(data)
+	}))
+	defer upstream.Close()
+	defer func(rake, prose string) { urlRake, urlProse = rake, prose }(urlRake, urlProse)
+	urlRake = upstream.URL
+	urlProse = "http://prose-app:8082"
+
+	transport := &sigV4Transport{
+		next:   http.DefaultTransport,
+		scopes: map[string]sigV4Scope{"rake": {"execute-api", ""}},
+		signer: v4.NewSigner(),
+		load: func(ctx context.Context) (aws.Config, error) {
+			return aws.Config{
+				Region:      "eu-west-1",
+				Credentials: credentials.NewStaticCredentialsProvider("AKIDEXAMPLE", "secret", ""),
+			}, nil
+		},
+		now: func() time.Time { return time.Date(2026, 10, 14, 12, 0, 0, 0, time.UTC) },
+	}
+
+	req, _ := http.NewRequest(http.MethodPost, upstream.URL+"/keywords", strings.NewReader(`{"text":"curie"}`))
+	resp, err := transport.RoundTrip(req)
+	if assert.NoError(t, err) {
+		resp.Body.Close()
+		auth := received.Header.Get("Authorization")
+		assert.True(t, strings.HasPrefix(auth,
+			"AWS4-HMAC-SHA256 Credential=AKIDEXAMPLE/20261014/eu-west-1/execute-api/aws4_request"), auth)
+		assert.Equal(t, "20261014T120000Z", received.Header.Get("X-Amz-Date"))
+		assert.Equal(t, `{"text":"curie"}`, body)
+	}
+}
@@ -0,0 +1,149 @@
+// author: Gary A. Stafford
+// site: https://programmaticponderings.com
+// license: MIT License
+// purpose: NLP microservices: nlp-client simplified routes for no-code platforms
+// modified: 2026-10-14
+
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+)
+
+const simplePath = "/simple/:analysis"
+
+// simpleEntity is an entity of a /simple/entities response.
+type simpleEntity struct {
+	Text  string `json:"text"`
+	Label string `json:"label"`
+}
+
+// simpleLanguage is the language of a /simple/language response.
+type simpleLanguage struct {
+	Language    string  `json:"language"`
+	Probability float64 `json:"probability"`
+}
+
+func isSimpleRoute(r route) bool {
+	return unversioned(r.path) == simplePath
+}
+
+// simpleText turns the text of a /simple request, sent as the text query
+// parameter, a form field, or JSON, into the JSON body of the analysis
+// routes, and takes the API key from the api_key query parameter when
+// GET_QUERY_API_KEY allows, as queryText does for the GET variants.
+func simpleText() echo.MiddlewareFunc {
+	// the form is read before the route's own limits apply
+	bodyLimit := earlyBodyLimit()
+
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return bodyLimit(func(c echo.Context) error {
+			req := c.Request()
+			var text string
+			if strings.HasPrefix(req.Header.Get(echo.HeaderContentType), echo.MIMEApplicationJSON) {
+				var err error
+				if text, err = requestText(req); err != nil {
+					return validationError(err.Error())
+				}
+			} else {
+				text = c.FormValue("text")
+			}
+			if strings.TrimSpace(text) == "" {
+				return validationError("missing text")
+			}
+			if key := c.QueryParam("api_key"); key != "" && envBool(getQueryAPIKey) && req.Header.Get("X-API-Key") == "" {
+				req.Header.Set("X-API-Key", key)
+			}
+
+			body, err := json.Marshal(struct {
+				Text string `json:"text"`
+			}{text})
+			if err != nil {
+				return internalError(err)
+			}
+			req.Body = ioutil.NopCloser(bytes.NewReader(body))
+			req.ContentLength = int64(len(body))
+			req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+
+			return next(c)
+		})
+	}
+}
+
+// flattenResult returns an analysis result as a flat array: the texts of
+// keywords, tokens, and sentences, the text and label of entities, or the
+// language alone.
+func flattenResult(name string, body []byte) (interface{}, error) {
+	switch name {
+	case "entities":
+		entities := []simpleEntity{}
+		if err := json.Unmarshal(body, &entities); err != nil {
+			return nil, err
+		}
+		return entities, nil
+	case "language":
+		var language simpleLanguage
+		if err := json.Unmarshal(body, &language); err != nil {
+			return nil, err
+		}
+		return []simpleLanguage{language}, nil
+	}
+	var items []interface{}
+	if err := json.Unmarshal(body, &items); err != nil {
+		return nil, err
+	}
+	texts := []string{}
+	for _, item := range items {
+		if text, ok := itemText(item); ok {
+			texts = append(texts, text)
+		}
+	}
+
+	return texts, nil
+}
+
+// getSimple runs one analysis and answers with its result flattened, for
+// automation platforms that map fields of flat arrays but not nested objects.
+func getSimple(c echo.Context) error {
+	name := c.Param("analysis")
+	switch name {
+	case "keywords", "tokens", "entities", "sentences", "language":
+	default:
+		return newAPIError(http.StatusNotFound, codeNotFound, fmt.Sprintf("unknown analysis: %s", name))
+	}
+	text, err := requestText(c.Request())
+	if err != nil {
+		return validationError(err.Error())
+	}
+	flat, err := flatAnalysis(c, name, text)
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusOK, flat)
+}
+
+// flatAnalysis runs an analysis of text for a request and returns its
+// result flattened.
+func flatAnalysis(c echo.Context, name, text string) (interface{}, error) {
+	result := runAnalysis(c.Request().Context(), name, text, c.Request().Header.Get("X-API-Key"))
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	if err := finishResult(c, name, text, schemaV1, nil, nil, &result); err != nil {
+		return nil, internalError(err)
+	}
+	flat, err := flattenResult(name, result.Result)
+	if err != nil {
+		return nil, internalError(err)
+	}
+
+	return flat, nil
+}
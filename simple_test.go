@@ -0,0 +1,112 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/garystafford/nlp-client/mockupstream"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSimpleRoutes(t *testing.T) {
+	simple := map[string]bool{}
+	for _, m := range registry() {
+		for _, r := range m.paths() {
+			simple[r.method+" "+r.path] = isSimpleRoute(r)
+		}
+	}
+
+	for _, key := range []string{"GET /simple/:analysis", "POST /simple/:analysis", "POST /v1/simple/:analysis"} {
+		assert.True(t, simple[key], key)
+	}
+	assert.False(t, simple["POST /keywords"])
+}
+
+func TestGetSimple(t *testing.T) {
+	rake := mockupstream.NewRake()
+	defer rake.Close()
+	prose := mockupstream.NewProse()
+	defer prose.Close()
+	lang := mockupstream.NewLang()
+	defer lang.Close()
+	defer func(rakeURL, proseURL, langURL string) { urlRake, urlProse, urlLang = rakeURL, proseURL, langURL }(urlRake, urlProse, urlLang)
+	urlRake, urlProse, urlLang = rake.URL, prose.URL, lang.URL
+
+	tests := []struct {
+		analysis string
+		want     string
+	}{
+		{"keywords", `["nobel prize","marie curie"]`},
+		{"tokens", `["The","Nobel","Prize","is","regarded"]`},
+		{"entities", `[{"text":"Marie Curie","label":"PERSON"}]`},
+		{"language", `[{"language":"en","probability":0.99}]`},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest(http.MethodGet, "/simple/"+tt.analysis+"?text="+url.QueryEscape("Marie Curie"), nil)
+		w := httptest.NewRecorder()
+		c := e.NewContext(req, w)
+		c.SetParamNames("analysis")
+		c.SetParamValues(tt.analysis)
+
+		if assert.NoError(t, simpleText()(getSimple)(c), tt.analysis) {
+			assert.Equal(t, http.StatusOK, w.Code, tt.analysis)
+			assert.JSONEq(t, tt.want, w.Body.String(), tt.analysis)
+		}
+	}
+}
+
+func TestSimpleText(t *testing.T) {
+	defer func(enabled string) { getQueryAPIKey = enabled }(getQueryAPIKey)
+	getQueryAPIKey = "true"
+
+	tests := []struct {
+		name        string
+		query       string
+		contentType string
+		body        string
+	}{
+		{"query", "?text=one&api_key=secret", "", ""},
+		{"form", "?api_key=secret", echo.MIMEApplicationForm, "text=one"},
+		{"json", "?api_key=secret", echo.MIMEApplicationJSON, `{"text":"one"}`},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest(http.MethodPost, "/simple/tokens"+tt.query, strings.NewReader(tt.body))
+		if tt.contentType != "" {
+			req.Header.Set(echo.HeaderContentType, tt.contentType)
+		}
+		c := e.NewContext(req, httptest.NewRecorder())
+
+		var text, key string
+		err := simpleText()(func(c echo.Context) error {
+			text, _ = requestText(c.Request())
+			key = c.Request().Header.Get("X-API-Key")
+			return nil
+		})(c)
+		if assert.NoError(t, err, tt.name) {
+			assert.Equal(t, "one", text, tt.name)
+			assert.Equal(t, "secret", key, tt.name)
+		}
+	}
+}
+
+func TestSimpleInvalid(t *testing.T) {
+	req := httptest.NewRequest(http.MethodGet, "/simple/tokens", nil)
+	c := e.NewContext(req, httptest.NewRecorder())
+	err := simpleText()(getSimple)(c)
+	if assert.Error(t, err) {
+		assert.Equal(t, http.StatusBadRequest, err.(*apiError).Status)
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/simple/record?text=one", nil)
+	c = e.NewContext(req, httptest.NewRecorder())
+	c.SetParamNames("analysis")
+	c.SetParamValues("record")
+	err = simpleText()(getSimple)(c)
+	if assert.Error(t, err) {
+		assert.Equal(t, http.StatusNotFound, err.(*apiError).Status)
+	}
+}
@@ -0,0 +1,204 @@
+// author: Gary A. Stafford
+// site: https://programmaticponderings.com
+// license: MIT License
+// purpose: NLP microservices: nlp-client Slack slash-command integration
+// modified: 2026-10-14
+
+package main
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/labstack/echo/v4"
+)
+
+var (
+	// signing secret of the Slack app whose slash command posts to
+	// /integrations/slack; unset to refuse every request to it
+	slackSigningSecret = newSecret(getEnv("SLACK_SIGNING_SECRET", ""))
+	// API key Slack requests are made, and their quota counted, as; the first
+	// of the service's keys when unset
+	slackAPIKey = newSecret(getEnv("SLACK_API_KEY", ""))
+	// ephemeral to answer only the user who ran the command, or in_channel
+	slackResponseType = getEnv("SLACK_RESPONSE_TYPE", "ephemeral")
+	// how far a request's timestamp may be from now, against replayed requests
+	slackMaxSkew = getEnv("SLACK_MAX_SKEW", "5m")
+)
+
+// slackMaxSectionLength is the most text Slack accepts in a section block.
+const slackMaxSectionLength = 3000
+
+var slackTitles = map[string]string{
+	"keywords": "Keywords", "tokens": "Tokens", "entities": "Entities", "sentences": "Sentences", "language": "Language",
+}
+
+const slackUsage = "Usage: `/nlp <keywords|tokens|entities|sentences|language> <text>`"
+
+// slackText is a text object of a Block Kit block.
+type slackText struct {
+	Type string `json:"type"`
+	Text string `json:"text"`
+}
+
+type slackBlock struct {
+	Type     string      `json:"type"`
+	Text     *slackText  `json:"text,omitempty"`
+	Elements []slackText `json:"elements,omitempty"`
+}
+
+// slackMessage is the response to a slash command.
+type slackMessage struct {
+	ResponseType string       `json:"response_type"`
+	Text         string       `json:"text"`
+	Blocks       []slackBlock `json:"blocks,omitempty"`
+}
+
+func isSlackRoute(r route) bool {
+	return unversioned(r.path) == "/integrations/slack"
+}
+
+// slackSignature returns the v0 signature of a request body sent at timestamp.
+func slackSignature(secret, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	fmt.Fprintf(mac, "v0:%s:", timestamp)
+	mac.Write(body)
+
+	return "v0=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// verifySlack accepts a request only if it is signed with the Slack app's
+// signing secret within SLACK_MAX_SKEW of now, and then makes it with the
+// Slack API key, which Slack cannot send, so the rest of the chain
+// authenticates and counts it as any other request.
+func verifySlack() echo.MiddlewareFunc {
+	// the body is read before the route's own limits apply
+	bodyLimit := earlyBodyLimit()
+
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return bodyLimit(func(c echo.Context) error {
+			if slackSigningSecret.Get() == "" {
+				return newAPIError(http.StatusNotFound, codeNotFound, "the Slack integration is not configured")
+			}
+			req := c.Request()
+			timestamp := req.Header.Get("X-Slack-Request-Timestamp")
+			seconds, err := strconv.ParseInt(timestamp, 10, 64)
+			if err != nil {
+				return newAPIError(http.StatusUnauthorized, codeUnauthorized, "missing Slack request timestamp")
+			}
+			skew := time.Since(time.Unix(seconds, 0))
+			if max := envDuration(slackMaxSkew, 5*time.Minute); skew > max || skew < -max {
+				return newAPIError(http.StatusUnauthorized, codeUnauthorized, "stale Slack request timestamp")
+			}
+			body, err := ioutil.ReadAll(req.Body)
+			if err != nil {
+				return err
+			}
+			req.Body = ioutil.NopCloser(bytes.NewReader(body))
+			if !hmac.Equal([]byte(req.Header.Get("X-Slack-Signature")), []byte(slackSignature(slackSigningSecret.Get(), timestamp, body))) {
+				return newAPIError(http.StatusUnauthorized, codeUnauthorized, "invalid Slack signature")
+			}
+
+			req.Header.Set("X-API-Key", integrationKey(slackAPIKey.Get()))
+
+			return next(c)
+		})
+	}
+}
+
+// integrationKey returns the API key an integration's requests are made as:
+// its own, or the first of the service's keys.
+func integrationKey(key string) string {
+	if key != "" {
+		return key
+	}
+
+	return apiKeys.Primary()
+}
+
+// escapeSlack escapes the characters Slack reads as markup in message text.
+func escapeSlack(text string) string {
+	return strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;").Replace(text)
+}
+
+// slackReply returns an ephemeral message of text alone, for usage and errors.
+func slackReply(text string) slackMessage {
+	return slackMessage{ResponseType: "ephemeral", Text: text}
+}
+
+// slackResult formats a flattened result as a message: a title, the result's
+// items a line each, truncated to fit a section, and their count.
+func slackResult(name string, flat interface{}) slackMessage {
+	var lines []string
+	switch items := flat.(type) {
+	case []string:
+		for _, item := range items {
+			lines = append(lines, "• "+escapeSlack(item))
+		}
+	case []simpleEntity:
+		for _, entity := range items {
+			lines = append(lines, fmt.Sprintf("• %s `%s`", escapeSlack(entity.Text), escapeSlack(entity.Label)))
+		}
+	case []simpleLanguage:
+		for _, language := range items {
+			lines = append(lines, fmt.Sprintf("• %s (%.2f)", escapeSlack(language.Language), language.Probability))
+		}
+	}
+	title := slackTitles[name]
+	if len(lines) == 0 {
+		return slackMessage{ResponseType: slackResponseType, Text: fmt.Sprintf("No %s found.", strings.ToLower(title))}
+	}
+	shown := lines
+	body := strings.Join(shown, "\n")
+	for len(body) > slackMaxSectionLength {
+		shown = shown[:len(shown)-1]
+		body = strings.Join(shown, "\n") + "\n…"
+	}
+
+	return slackMessage{
+		ResponseType: slackResponseType,
+		Text:         fmt.Sprintf("%s: %d found", title, len(lines)),
+		Blocks: []slackBlock{
+			{Type: "section", Text: &slackText{"mrkdwn", "*" + title + "*"}},
+			{Type: "section", Text: &slackText{"mrkdwn", body}},
+			{Type: "context", Elements: []slackText{{"mrkdwn", fmt.Sprintf("%d of %d shown", len(shown), len(lines))}}},
+		},
+	}
+}
+
+// postSlack runs the analysis a slash command names on the rest of its text
+// and answers with the result as Block Kit blocks. Slack shows only a generic
+// failure for other statuses, so usage and analysis errors are answered 200
+// with a message for the user.
+func postSlack(c echo.Context) error {
+	if c.FormValue("ssl_check") != "" {
+		return c.NoContent(http.StatusOK)
+	}
+	fields := strings.Fields(c.FormValue("text"))
+	if len(fields) < 2 {
+		return c.JSON(http.StatusOK, slackReply(slackUsage))
+	}
+	name := strings.ToLower(fields[0])
+	if _, ok := analysisEndpoint(name); !ok {
+		return c.JSON(http.StatusOK, slackReply(fmt.Sprintf("Unknown analysis `%s`. %s", escapeSlack(fields[0]), slackUsage)))
+	}
+	text := strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(c.FormValue("text")), fields[0]))
+	flat, err := flatAnalysis(c, name, text)
+	if err != nil {
+		message := err.Error()
+		if apiErr, ok := err.(*apiError); ok {
+			message = apiErr.Message
+		}
+		return c.JSON(http.StatusOK, slackReply(":warning: "+escapeSlack(message)))
+	}
+
+	return c.JSON(http.StatusOK, slackResult(name, flat))
+}
@@ -0,0 +1,123 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/garystafford/nlp-client/mockupstream"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+)
+
+// slackRequest returns a slash command request signed with secret at timestamp.
+func slackRequest(secret string, timestamp time.Time, text string) *http.Request {
+	body := url.Values{"command": {"/nlp"}, "text": {text}}.Encode()
+	req := httptest.NewRequest(http.MethodPost, "/integrations/slack", strings.NewReader(body))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationForm)
+	ts := strconv.FormatInt(timestamp.Unix(), 10)
+	req.Header.Set("X-Slack-Request-Timestamp", ts)
+	req.Header.Set("X-Slack-Signature", slackSignature(secret, ts, []byte(body)))
+
+	return req
+}
+
+func TestVerifySlack(t *testing.T) {
+	defer func(signingSecret, key *secret) { slackSigningSecret, slackAPIKey = signingSecret, key }(slackSigningSecret, slackAPIKey)
+	slackSigningSecret, slackAPIKey = newSecret("signing-secret"), newSecret("slack-key")
+
+	tests := []struct {
+		name   string
+		req    *http.Request
+		status int
+	}{
+		{"signed", slackRequest("signing-secret", time.Now(), "tokens one"), http.StatusOK},
+		{"wrong secret", slackRequest("other-secret", time.Now(), "tokens one"), http.StatusUnauthorized},
+		{"stale", slackRequest("signing-secret", time.Now().Add(-10*time.Minute), "tokens one"), http.StatusUnauthorized},
+	}
+	for _, tt := range tests {
+		var key string
+		err := verifySlack()(func(c echo.Context) error {
+			key = c.Request().Header.Get("X-API-Key")
+			assert.Equal(t, "tokens one", c.FormValue("text"), tt.name)
+			return nil
+		})(e.NewContext(tt.req, httptest.NewRecorder()))
+		if tt.status == http.StatusOK {
+			assert.NoError(t, err, tt.name)
+			assert.Equal(t, "slack-key", key, tt.name)
+		} else if assert.Error(t, err, tt.name) {
+			assert.Equal(t, tt.status, err.(*apiError).Status, tt.name)
+		}
+	}
+
+	slackSigningSecret.Set("")
+	err := verifySlack()(postSlack)(e.NewContext(slackRequest("", time.Now(), "tokens one"), httptest.NewRecorder()))
+	if assert.Error(t, err, "the integration is off without a signing secret") {
+		assert.Equal(t, http.StatusNotFound, err.(*apiError).Status)
+	}
+}
+
+func TestPostSlack(t *testing.T) {
+	prose := mockupstream.NewProse()
+	defer prose.Close()
+	defer func(url string) { urlProse = url }(urlProse)
+	urlProse = prose.URL
+
+	tests := []struct {
+		text string
+		want string
+	}{
+		{"entities Marie Curie won", "• Marie Curie `PERSON`"},
+		{"", "Usage:"},
+		{"summarize Marie Curie", "Unknown analysis `summarize`"},
+	}
+	for _, tt := range tests {
+		req := slackRequest("", time.Now(), tt.text)
+		w := httptest.NewRecorder()
+		if assert.NoError(t, postSlack(e.NewContext(req, w)), tt.text) {
+			assert.Equal(t, http.StatusOK, w.Code, tt.text)
+			assert.Contains(t, w.Body.String(), tt.want, tt.text)
+		}
+	}
+}
+
+func TestSlackResult(t *testing.T) {
+	var long []string
+	for i := 0; i < 500; i++ {
+		long = append(long, "token")
+	}
+	message := slackResult("tokens", long)
+	if assert.Len(t, message.Blocks, 3) {
+		assert.LessOrEqual(t, len(message.Blocks[1].Text.Text), slackMaxSectionLength)
+		assert.Contains(t, message.Blocks[2].Elements[0].Text, "of 500 shown")
+	}
+	assert.Equal(t, "Tokens: 500 found", message.Text)
+
+	message = slackResult("keywords", []string{"<!channel> & co"})
+	assert.Equal(t, "• &lt;!channel&gt; &amp; co", message.Blocks[1].Text.Text, "markup is escaped")
+
+	assert.Equal(t, "No entities found.", slackResult("entities", []simpleEntity{}).Text)
+}
+
+func TestResponseEncodingIntegrations(t *testing.T) {
+	defer func(naming string) { jsonNaming = naming }(jsonNaming)
+	jsonNaming = namingCamel
+	handler := responseEncoding()(func(c echo.Context) error {
+		return c.JSON(http.StatusOK, slackReply("hello"))
+	})
+
+	req := httptest.NewRequest(http.MethodPost, "/v1/integrations/slack", nil)
+	w := httptest.NewRecorder()
+	c := e.NewContext(req, w)
+	c.SetPath("/v1/integrations/slack")
+	if assert.NoError(t, handler(c)) {
+		var message map[string]interface{}
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &message))
+		assert.Contains(t, message, "response_type", "Slack's own field names are kept")
+	}
+}
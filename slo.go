@@ -0,0 +1,299 @@
+// author: Gary A. Stafford
+// site: https://programmaticponderings.com
+// license: MIT License
+// purpose: NLP microservices: nlp-client per-route SLO tracking
+// modified: 2026-10-14
+
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/labstack/echo/v4"
+)
+
+var (
+	sloAvailability  = getEnv("SLO_AVAILABILITY", "99.9") // percent of requests without a 5xx
+	sloLatency       = getEnv("SLO_LATENCY", "1s")        // threshold a request must finish within
+	sloLatencyTarget = getEnv("SLO_LATENCY_TARGET", "99") // percent of requests within the threshold
+	// e.g. "/language=99.95:200ms:99;/batch=99::95"; any part may be left empty
+	sloObjectives = getEnv("SLO_OBJECTIVES", "")
+	sloWindows    = getEnv("SLO_WINDOWS", "5m,1h,6h")
+	sloInterval   = getEnv("SLO_PUBLISH_INTERVAL", "15s")
+
+	sloOnce       sync.Once
+	sharedTracker *sloTracker
+)
+
+type sloObjective struct {
+	Availability  float64       `json:"availability"`
+	Latency       time.Duration `json:"-"`
+	LatencyTarget float64       `json:"latency_target"`
+}
+
+// sloBucket counts the requests of one minute.
+type sloBucket struct {
+	minute int64
+	total  int64
+	errors int64
+	slow   int64
+}
+
+type sloWindow struct {
+	Window            string  `json:"window"`
+	Requests          int64   `json:"requests"`
+	Availability      float64 `json:"availability"`
+	LatencyAttainment float64 `json:"latency_attainment"`
+	AvailabilityBurn  float64 `json:"availability_burn_rate"`
+	LatencyBurn       float64 `json:"latency_burn_rate"`
+}
+
+type sloRouteSummary struct {
+	Route     string       `json:"route"`
+	Objective sloObjective `json:"objective"`
+	Latency   string       `json:"latency"`
+	Windows   []sloWindow  `json:"windows"`
+}
+
+// sloTracker keeps per-minute request counts for each route, enough to cover
+// the longest window, and computes attainment and burn rates from them.
+type sloTracker struct {
+	mu         sync.Mutex
+	fallback   sloObjective
+	objectives map[string]sloObjective
+	windows    []time.Duration
+	buckets    map[string][]sloBucket
+	now        func() time.Time
+}
+
+func newSLOTracker(fallback sloObjective, objectives map[string]sloObjective, windows []time.Duration) *sloTracker {
+	return &sloTracker{
+		fallback:   fallback,
+		objectives: objectives,
+		windows:    windows,
+		buckets:    map[string][]sloBucket{},
+		now:        time.Now,
+	}
+}
+
+func (t *sloTracker) objective(route string) sloObjective {
+	if objective, ok := t.objectives[route]; ok {
+		return objective
+	}
+
+	return t.fallback
+}
+
+func (t *sloTracker) size() int64 {
+	longest := time.Minute
+	for _, window := range t.windows {
+		if window > longest {
+			longest = window
+		}
+	}
+
+	return int64(longest / time.Minute)
+}
+
+// record counts a request; 5xx responses count against availability, and
+// responses slower than the route's threshold against latency.
+func (t *sloTracker) record(route string, status int, latency time.Duration) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	size := t.size()
+	buckets, ok := t.buckets[route]
+	if !ok {
+		buckets = make([]sloBucket, size)
+		t.buckets[route] = buckets
+	}
+	minute := t.now().Unix() / 60
+	bucket := &buckets[minute%size]
+	if bucket.minute != minute {
+		*bucket = sloBucket{minute: minute}
+	}
+	bucket.total++
+	if status >= http.StatusInternalServerError {
+		bucket.errors++
+	}
+	if latency > t.objective(route).Latency {
+		bucket.slow++
+	}
+}
+
+func (t *sloTracker) summary() []sloRouteSummary {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	routes := make([]string, 0, len(t.buckets))
+	for route := range t.buckets {
+		routes = append(routes, route)
+	}
+	sort.Strings(routes)
+
+	minute := t.now().Unix() / 60
+	summaries := make([]sloRouteSummary, 0, len(routes))
+	for _, route := range routes {
+		objective := t.objective(route)
+		summary := sloRouteSummary{Route: route, Objective: objective, Latency: objective.Latency.String()}
+		for _, window := range t.windows {
+			var total, errors, slow int64
+			span := int64(window / time.Minute)
+			for _, bucket := range t.buckets[route] {
+				if bucket.total > 0 && minute-bucket.minute < span {
+					total, errors, slow = total+bucket.total, errors+bucket.errors, slow+bucket.slow
+				}
+			}
+			summary.Windows = append(summary.Windows, newSLOWindow(window, objective, total, errors, slow))
+		}
+		summaries = append(summaries, summary)
+	}
+
+	return summaries
+}
+
+// newSLOWindow computes attainment, and the burn rate: how many times faster
+// than the objective allows the error budget is being spent.
+func newSLOWindow(window time.Duration, objective sloObjective, total, errors, slow int64) sloWindow {
+	w := sloWindow{Window: window.String(), Requests: total, Availability: 100, LatencyAttainment: 100}
+	if total == 0 {
+		return w
+	}
+	errorRate := float64(errors) / float64(total)
+	slowRate := float64(slow) / float64(total)
+	w.Availability = 100 * (1 - errorRate)
+	w.LatencyAttainment = 100 * (1 - slowRate)
+	if budget := 1 - objective.Availability/100; budget > 0 {
+		w.AvailabilityBurn = errorRate / budget
+	}
+	if budget := 1 - objective.LatencyTarget/100; budget > 0 {
+		w.LatencyBurn = slowRate / budget
+	}
+
+	return w
+}
+
+// publish exports the burn rates as gauges through the configured metrics drivers.
+func (t *sloTracker) publish() {
+	for _, summary := range t.summary() {
+		for _, w := range summary.Windows {
+			metrics.Gauge("slo_burn_rate", w.AvailabilityBurn,
+				map[string]string{"route": summary.Route, "window": w.Window, "objective": "availability"})
+			metrics.Gauge("slo_burn_rate", w.LatencyBurn,
+				map[string]string{"route": summary.Route, "window": w.Window, "objective": "latency"})
+		}
+	}
+}
+
+func parseSLOObjectives(config string, fallback sloObjective) (map[string]sloObjective, error) {
+	objectives := map[string]sloObjective{}
+	for _, entry := range strings.Split(config, ";") {
+		if strings.TrimSpace(entry) == "" {
+			continue
+		}
+		parts := strings.SplitN(entry, "=", 2)
+		values := []string{""}
+		if len(parts) == 2 {
+			values = strings.Split(parts[1], ":")
+		}
+		if len(parts) != 2 || len(values) != 3 {
+			return nil, fmt.Errorf("invalid SLO objective: %s", entry)
+		}
+
+		objective := fallback
+		var err error
+		if v := strings.TrimSpace(values[0]); v != "" {
+			if objective.Availability, err = parsePercent(v); err != nil {
+				return nil, fmt.Errorf("invalid SLO objective: %s", entry)
+			}
+		}
+		if v := strings.TrimSpace(values[1]); v != "" {
+			if objective.Latency, err = time.ParseDuration(v); err != nil {
+				return nil, fmt.Errorf("invalid SLO objective: %s", entry)
+			}
+		}
+		if v := strings.TrimSpace(values[2]); v != "" {
+			if objective.LatencyTarget, err = parsePercent(v); err != nil {
+				return nil, fmt.Errorf("invalid SLO objective: %s", entry)
+			}
+		}
+		objectives[strings.TrimSpace(parts[0])] = objective
+	}
+
+	return objectives, nil
+}
+
+func parsePercent(value string) (float64, error) {
+	percent, err := strconv.ParseFloat(value, 64)
+	if err != nil || percent <= 0 || percent > 100 {
+		return 0, fmt.Errorf("invalid percentage: %s", value)
+	}
+
+	return percent, nil
+}
+
+func parseSLOWindows(value string) []time.Duration {
+	var windows []time.Duration
+	for _, window := range strings.Split(value, ",") {
+		d, err := time.ParseDuration(strings.TrimSpace(window))
+		if err != nil || d < time.Minute {
+			e.Logger.Warnf("ignoring SLO window %q", window)
+			continue
+		}
+		windows = append(windows, d)
+	}
+
+	return windows
+}
+
+func sloTrackerInstance() *sloTracker {
+	sloOnce.Do(func() {
+		availability, err := parsePercent(sloAvailability)
+		if err != nil {
+			availability = 99.9
+		}
+		target, err := parsePercent(sloLatencyTarget)
+		if err != nil {
+			target = 99
+		}
+		fallback := sloObjective{availability, envDuration(sloLatency, time.Second), target}
+		objectives, err := parseSLOObjectives(sloObjectives, fallback)
+		if err != nil {
+			e.Logger.Errorf("ignoring SLO_OBJECTIVES: %v", err)
+			objectives = map[string]sloObjective{}
+		}
+		sharedTracker = newSLOTracker(fallback, objectives, parseSLOWindows(sloWindows))
+		go func() {
+			for range time.Tick(envDuration(sloInterval, 15*time.Second)) {
+				sharedTracker.publish()
+			}
+		}()
+	})
+
+	return sharedTracker
+}
+
+// sloMiddleware records every request of the chain against its route's objectives.
+func sloMiddleware() echo.MiddlewareFunc {
+	tracker := sloTrackerInstance()
+
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			start := time.Now()
+			err := next(c)
+			tracker.record(routePath(c), responseStatus(c, err), time.Since(start))
+
+			return err
+		}
+	}
+}
+
+func getSLO(c echo.Context) error {
+	return c.JSON(http.StatusOK, sloTrackerInstance().summary())
+}
@@ -0,0 +1,71 @@
+package main
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseSLOObjectives(t *testing.T) {
+	fallback := sloObjective{99.9, time.Second, 99}
+	objectives, err := parseSLOObjectives("/language=99.95:200ms:;/batch=99::95", fallback)
+	if assert.NoError(t, err) {
+		assert.Equal(t, sloObjective{99.95, 200 * time.Millisecond, 99}, objectives["/language"])
+		assert.Equal(t, sloObjective{99, time.Second, 95}, objectives["/batch"])
+	}
+
+	for _, config := range []string{"/language", "/language=99.9", "/language=101::", "/language=:soon:"} {
+		_, err := parseSLOObjectives(config, fallback)
+		assert.Error(t, err, config)
+	}
+}
+
+func TestSLOTracker(t *testing.T) {
+	now := time.Date(2026, 10, 14, 12, 0, 0, 0, time.UTC)
+	tracker := newSLOTracker(sloObjective{99, 100 * time.Millisecond, 90}, nil,
+		[]time.Duration{5 * time.Minute, time.Hour})
+
+	// outside the 5m window, inside the 1h window
+	tracker.now = func() time.Time { return now.Add(-50 * time.Minute) }
+	for i := 0; i < 10; i++ {
+		tracker.record("/keywords", http.StatusBadGateway, time.Millisecond)
+	}
+	tracker.now = func() time.Time { return now }
+	for i := 0; i < 90; i++ {
+		tracker.record("/keywords", http.StatusOK, time.Millisecond)
+	}
+	for i := 0; i < 10; i++ {
+		tracker.record("/keywords", http.StatusOK, time.Second)
+	}
+
+	summary := tracker.summary()
+	if assert.Len(t, summary, 1) && assert.Len(t, summary[0].Windows, 2) {
+		short, long := summary[0].Windows[0], summary[0].Windows[1]
+		assert.Equal(t, int64(100), short.Requests)
+		assert.Equal(t, 100.0, short.Availability)
+		assert.Equal(t, 90.0, short.LatencyAttainment)
+		assert.InDelta(t, 1.0, short.LatencyBurn, 1e-9)
+
+		assert.Equal(t, int64(110), long.Requests)
+		// 10 errors in 110 requests against a 1% budget
+		assert.InDelta(t, 100*(1-10.0/110), long.Availability, 1e-9)
+		assert.InDelta(t, (10.0/110)/0.01, long.AvailabilityBurn, 1e-6)
+	}
+}
+
+func TestSLOTrackerWrapsBuckets(t *testing.T) {
+	now := time.Date(2026, 10, 14, 12, 0, 0, 0, time.UTC)
+	tracker := newSLOTracker(sloObjective{99, time.Second, 99}, nil, []time.Duration{5 * time.Minute})
+	tracker.now = func() time.Time { return now }
+	tracker.record("/keywords", http.StatusInternalServerError, time.Millisecond)
+
+	// the same bucket is reused five minutes later
+	tracker.now = func() time.Time { return now.Add(5 * time.Minute) }
+	tracker.record("/keywords", http.StatusOK, time.Millisecond)
+
+	window := tracker.summary()[0].Windows[0]
+	assert.Equal(t, int64(1), window.Requests)
+	assert.Equal(t, 0.0, window.AvailabilityBurn)
+}
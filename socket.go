@@ -0,0 +1,75 @@
+// author: Gary A. Stafford
+// site: https://programmaticponderings.com
+// license: MIT License
+// purpose: NLP microservices: nlp-client unix domain sockets
+// modified: 2026-10-14
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"os"
+	"strings"
+	"sync"
+)
+
+const unixScheme = "unix://"
+
+var (
+	unixSocketsMu sync.RWMutex
+	// unixSockets maps the placeholder host of a unix:// upstream to its socket path
+	unixSockets = map[string]string{}
+)
+
+// upstreamURL rewrites a unix:///path/to.sock endpoint into an http:// URL with a
+// placeholder host, which the upstream transport dials as the socket; any other
+// endpoint is returned unchanged.
+func upstreamURL(endpoint string) string {
+	if !strings.HasPrefix(endpoint, unixScheme) {
+		return endpoint
+	}
+
+	unixSocketsMu.Lock()
+	defer unixSocketsMu.Unlock()
+
+	host := fmt.Sprintf("unix-socket-%d", len(unixSockets))
+	unixSockets[host] = strings.TrimPrefix(endpoint, unixScheme)
+
+	return "http://" + host
+}
+
+func unixSocket(addr string) (string, bool) {
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		host = addr
+	}
+
+	unixSocketsMu.RLock()
+	defer unixSocketsMu.RUnlock()
+	path, ok := unixSockets[host]
+
+	return path, ok
+}
+
+// dialUpstream dials unix:// upstreams over their socket and everything else with dialer.
+func dialUpstream(dialer *net.Dialer) func(ctx context.Context, network, addr string) (net.Conn, error) {
+	return func(ctx context.Context, network, addr string) (net.Conn, error) {
+		if path, ok := unixSocket(addr); ok {
+			return dialer.DialContext(ctx, "unix", path)
+		}
+
+		return dialer.DialContext(ctx, network, addr)
+	}
+}
+
+// listenUnix listens on a unix:///path/to.sock address, removing a stale socket file first.
+func listenUnix(address string) (net.Listener, error) {
+	path := strings.TrimPrefix(address, unixScheme)
+	if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+
+	return net.Listen("unix", path)
+}
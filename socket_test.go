@@ -0,0 +1,40 @@
+package main
+
+import (
+	"io/ioutil"
+	"net/http"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestUpstreamURL(t *testing.T) {
+	assert.Equal(t, "http://localhost:8081", upstreamURL("http://localhost:8081"))
+
+	url := upstreamURL("unix:///var/run/rake.sock")
+	path, ok := unixSocket(url[len("http://"):] + ":80")
+	if assert.True(t, ok) {
+		assert.Equal(t, "/var/run/rake.sock", path)
+	}
+}
+
+func TestUnixSocketRoundTrip(t *testing.T) {
+	socket := unixScheme + filepath.Join(t.TempDir(), "rake.sock")
+	l, err := listenUnix(socket)
+	if !assert.NoError(t, err) {
+		return
+	}
+	server := &http.Server{Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(r.URL.Path))
+	})}
+	go func() { _ = server.Serve(l) }()
+	defer server.Close()
+
+	resp, err := upstreamClient.Get(upstreamURL(socket) + "/keywords")
+	if assert.NoError(t, err) {
+		body, _ := ioutil.ReadAll(resp.Body)
+		assert.NoError(t, resp.Body.Close())
+		assert.Equal(t, "/keywords", string(body))
+	}
+}
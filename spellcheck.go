@@ -0,0 +1,93 @@
+// author: Gary A. Stafford
+// site: https://programmaticponderings.com
+// license: MIT License
+// purpose: NLP microservices: nlp-client spell checking
+// modified: 2026-10-14
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"sort"
+	"time"
+
+	"github.com/labstack/echo/v4"
+)
+
+var (
+	// spell-check backend answering POST /spellcheck with corrections; unset disables /spellcheck
+	urlSpellcheck = upstreamURL(getEnv("SPELLCHECK_ENDPOINT", ""))
+	// how long the spellcheck transform waits for the backend
+	spellcheckTimeout = getEnv("SPELLCHECK_TIMEOUT", "5s")
+)
+
+// correction replaces length characters of a text, starting at the character offset.
+type correction struct {
+	Offset     int    `json:"offset"`
+	Length     int    `json:"length"`
+	Original   string `json:"original"`
+	Suggestion string `json:"suggestion"`
+}
+
+func init() {
+	registerRequestTransform("spellcheck", autoCorrect)
+}
+
+func postSpellcheck(c echo.Context) error {
+	if urlSpellcheck == "" {
+		return newAPIError(http.StatusNotFound, codeNotFound, "spell checking is not configured")
+	}
+	ctx := c.Request().Context()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, urlSpellcheck+"/spellcheck", c.Request().Body)
+
+	return serviceResponse(err, req, c)
+}
+
+// autoCorrect applies the backend's first suggestion for each misspelling,
+// so noisy text reaches keyword and entity extraction corrected.
+func autoCorrect(text string) (string, error) {
+	if urlSpellcheck == "" || text == "" {
+		return text, nil
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), envDuration(spellcheckTimeout, 5*time.Second))
+	defer cancel()
+
+	body, err := postUpstream(ctx, urlSpellcheck+"/spellcheck", text, apiKeys.Primary())
+	if err != nil {
+		return "", err
+	}
+	var result struct {
+		Corrections []correction `json:"corrections"`
+	}
+	if err := json.Unmarshal(body, &result); err != nil {
+		return "", err
+	}
+
+	return applyCorrections(text, result.Corrections), nil
+}
+
+// applyCorrections applies corrections from the end of the text backwards, so
+// earlier offsets stay valid. Corrections that overlap one already applied,
+// fall outside the text, or no longer match their original are skipped.
+func applyCorrections(text string, corrections []correction) string {
+	sort.SliceStable(corrections, func(i, j int) bool { return corrections[i].Offset > corrections[j].Offset })
+
+	runes := []rune(text)
+	end := len(runes)
+	for _, fix := range corrections {
+		if fix.Offset < 0 || fix.Length < 0 || fix.Offset+fix.Length > end {
+			continue
+		}
+		original := runes[fix.Offset : fix.Offset+fix.Length]
+		if fix.Original != "" && string(original) != fix.Original {
+			continue
+		}
+		corrected := append([]rune(fix.Suggestion), runes[fix.Offset+fix.Length:]...)
+		runes = append(runes[:fix.Offset], corrected...)
+		end = fix.Offset
+	}
+
+	return string(runes)
+}
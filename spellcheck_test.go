@@ -0,0 +1,50 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestApplyCorrections(t *testing.T) {
+	text := "Mrie Curie wsa a pionere"
+	corrections := []correction{
+		{Offset: 0, Length: 4, Original: "Mrie", Suggestion: "Marie"},
+		{Offset: 17, Length: 8, Original: "pionere", Suggestion: "pioneer"}, // runs past the end of the text
+		{Offset: 17, Length: 7, Original: "pionere", Suggestion: "pioneer"},
+		{Offset: 11, Length: 3, Original: "wsa", Suggestion: "was"},
+		{Offset: 11, Length: 2, Suggestion: "ignored"}, // overlaps "wsa"
+		{Offset: 40, Length: 2, Suggestion: "outside"},
+		{Offset: 5, Length: 5, Original: "Cuire", Suggestion: "Curie"}, // no longer matches
+	}
+
+	assert.Equal(t, "Marie Curie was a pioneer", applyCorrections(text, corrections))
+	// offsets count characters, not bytes
+	assert.Equal(t, "café au lait", applyCorrections("café ua lait", []correction{{Offset: 5, Length: 2, Suggestion: "au"}}))
+}
+
+func TestSpellcheckTransform(t *testing.T) {
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/spellcheck", r.URL.Path)
+		_, _ = w.Write([]byte(`{"corrections": [{"offset": 6, "length": 5, "original": "Cuire", "suggestion": "Curie"}]}`))
+	}))
+	defer upstream.Close()
+	defer func(url, routes string) { urlSpellcheck, requestTransformRoutes = url, routes }(urlSpellcheck, requestTransformRoutes)
+	urlSpellcheck = upstream.URL
+	requestTransformRoutes = "/keywords=spellcheck"
+
+	body, err := transformRequestBody("/keywords", strings.NewReader(`{"text": "Marie Cuire"}`))
+	if assert.NoError(t, err) {
+		assert.JSONEq(t, `{"text": "Marie Curie"}`, string(body))
+	}
+
+	// without a backend the text passes through unchanged
+	urlSpellcheck = ""
+	text, err := autoCorrect("Marie Cuire")
+	if assert.NoError(t, err) {
+		assert.Equal(t, "Marie Cuire", text)
+	}
+}
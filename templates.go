@@ -0,0 +1,267 @@
+// author: Gary A. Stafford
+// site: https://programmaticponderings.com
+// license: MIT License
+// purpose: NLP microservices: nlp-client tenant response templates
+// modified: 2026-10-14
+
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"mime"
+	"net/http"
+	"strings"
+	"sync"
+	"text/template"
+
+	"github.com/labstack/echo/v4"
+)
+
+var (
+	// most templates one tenant may have, across its routes
+	templateMaxTemplates = getEnv("TEMPLATE_MAX_TEMPLATES", "50")
+	// largest template source, and response a template may write, in bytes
+	templateMaxBytes       = getEnv("TEMPLATE_MAX_BYTES", "16384")
+	templateMaxOutputBytes = getEnv("TEMPLATE_MAX_OUTPUT_BYTES", "1048576")
+
+	errTemplateOutput = errors.New("template output too large")
+)
+
+var templates = newTemplateStore()
+
+// templateRoutes answer with ?template= shaped results.
+var templateRoutes = map[string]bool{
+	"/tokens": true, "/entities": true, "/sentences": true, "/keywords": true, "/language": true, "/analyze": true,
+}
+
+// scriptedMediaTypes are the content types browsers run scripts in.
+var scriptedMediaTypes = map[string]bool{"text/html": true, "application/xhtml+xml": true, "image/svg+xml": true, "text/xml": true, "application/xml": true}
+
+// templateFuncs are the functions templates may call, besides text/template's own.
+var templateFuncs = template.FuncMap{
+	// texts returns the texts of the items of a list result
+	"texts": func(items interface{}) []string {
+		list, _ := items.([]interface{})
+		texts := []string{}
+		for _, item := range list {
+			if text, ok := itemText(item); ok {
+				texts = append(texts, text)
+			}
+		}
+		return texts
+	},
+	"join":  func(sep string, values []string) string { return strings.Join(values, sep) },
+	"lower": strings.ToLower,
+	"upper": strings.ToUpper,
+	"trim":  strings.TrimSpace,
+	// csv quotes a field of a CSV row when it needs to be
+	"csv": func(value interface{}) string {
+		field := fmt.Sprint(value)
+		if strings.ContainsAny(field, ",\"\r\n") {
+			return `"` + strings.ReplaceAll(field, `"`, `""`) + `"`
+		}
+		return field
+	},
+	"json": func(value interface{}) (string, error) {
+		encoded, err := json.Marshal(value)
+		return string(encoded), err
+	},
+}
+
+// responseTemplate shapes the results of a route into a response of its own
+// content type, for consumers that cannot parse JSON.
+type responseTemplate struct {
+	Name        string `json:"name"`
+	Route       string `json:"route"`
+	Template    string `json:"template"`
+	ContentType string `json:"content_type,omitempty"`
+	parsed      *template.Template
+}
+
+type templateSet struct {
+	Templates []responseTemplate `json:"templates"`
+	digest    string
+}
+
+// find returns the template of route with the name, or nil.
+func (t *templateSet) find(route, name string) *responseTemplate {
+	for i := range t.Templates {
+		if t.Templates[i].Route == route && t.Templates[i].Name == name {
+			return &t.Templates[i]
+		}
+	}
+
+	return nil
+}
+
+// limitedWriter fails writes past its limit, so a template cannot write an
+// unbounded response.
+type limitedWriter struct {
+	bytes.Buffer
+	limit int
+}
+
+func (w *limitedWriter) Write(p []byte) (int, error) {
+	if w.Len()+len(p) > w.limit {
+		return 0, errTemplateOutput
+	}
+
+	return w.Buffer.Write(p)
+}
+
+// render executes the template over a result, decoded from JSON.
+func (t *responseTemplate) render(result interface{}) ([]byte, error) {
+	out := &limitedWriter{limit: envInt(templateMaxOutputBytes, 1048576)}
+	if err := t.parsed.Execute(out, result); err != nil {
+		return nil, err
+	}
+
+	return out.Bytes(), nil
+}
+
+type templateStore struct {
+	mu   sync.RWMutex
+	sets map[string]*templateSet
+}
+
+func newTemplateStore() *templateStore {
+	return &templateStore{sets: map[string]*templateSet{}}
+}
+
+func (s *templateStore) get(tenant string) *templateSet {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return s.sets[tenant]
+}
+
+func (s *templateStore) set(tenant string, t *templateSet) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if t == nil {
+		delete(s.sets, tenant)
+		return
+	}
+	s.sets[tenant] = t
+}
+
+// digest identifies a tenant's templates, for the ETags of the results they
+// shape; it is empty without any.
+func (s *templateStore) digest(tenant string) string {
+	if t := s.get(tenant); t != nil {
+		return t.digest
+	}
+
+	return ""
+}
+
+// templateFor returns the template the ?template= of a request to route
+// names, or nil when it names none.
+func templateFor(c echo.Context, route string) (*responseTemplate, error) {
+	name := c.QueryParam("template")
+	if name == "" {
+		return nil, nil
+	}
+	if !templateRoutes[route] {
+		return nil, validationError(fmt.Sprintf("%s results cannot be templated", route))
+	}
+	tenant, err := tenantOf(c)
+	if err != nil {
+		return nil, err
+	}
+	var t *responseTemplate
+	if set := templates.get(tenant); set != nil {
+		t = set.find(route, name)
+	}
+	if t == nil {
+		return nil, newAPIError(http.StatusNotFound, codeNotFound, fmt.Sprintf("no template %s for %s is uploaded for the tenant", name, route))
+	}
+
+	return t, nil
+}
+
+// respondTemplate writes a result shaped by a template.
+func respondTemplate(c echo.Context, code int, t *responseTemplate, result interface{}) error {
+	body, err := t.render(result)
+	if err != nil {
+		return validationError(fmt.Sprintf("template %s: %v", t.Name, err))
+	}
+
+	c.Response().Header().Set(echo.HeaderXContentTypeOptions, "nosniff")
+
+	return c.Blob(code, t.ContentType, body)
+}
+
+// putTemplates replaces the templates of the request's tenant.
+func putTemplates(c echo.Context) error {
+	tenant, err := tenantOf(c)
+	if err != nil {
+		return err
+	}
+	t := &templateSet{}
+	if err := json.NewDecoder(c.Request().Body).Decode(t); err != nil {
+		return validationError(err.Error())
+	}
+	if max := envInt(templateMaxTemplates, 50); len(t.Templates) > max {
+		return newAPIError(http.StatusRequestEntityTooLarge, codePayloadTooLarge, fmt.Sprintf("more than %d templates", max))
+	}
+	entries := []string{}
+	for i := range t.Templates {
+		tmpl := &t.Templates[i]
+		if strings.TrimSpace(tmpl.Name) == "" {
+			return validationError(fmt.Sprintf("template %d has no name", i))
+		}
+		if !templateRoutes[tmpl.Route] {
+			return validationError(fmt.Sprintf("template %d: %s results cannot be templated", i, tmpl.Route))
+		}
+		if t.find(tmpl.Route, tmpl.Name) != tmpl {
+			return validationError(fmt.Sprintf("template %d: %s is named twice for %s", i, tmpl.Name, tmpl.Route))
+		}
+		if max := envInt(templateMaxBytes, 16384); len(tmpl.Template) > max {
+			return newAPIError(http.StatusRequestEntityTooLarge, codePayloadTooLarge, fmt.Sprintf("template %d is over %d bytes", i, max))
+		}
+		if tmpl.parsed, err = template.New(tmpl.Name).Funcs(templateFuncs).Parse(tmpl.Template); err != nil {
+			return validationError(fmt.Sprintf("template %d: %v", i, err))
+		}
+		if tmpl.ContentType == "" {
+			tmpl.ContentType = echo.MIMETextPlainCharsetUTF8
+		}
+		// the results hold the caller's text unescaped, so are never served as pages or scripts
+		mediaType, _, err := mime.ParseMediaType(tmpl.ContentType)
+		if err != nil || scriptedMediaTypes[mediaType] || strings.Contains(mediaType, "javascript") {
+			return validationError(fmt.Sprintf("template %d: content type %s is not allowed", i, tmpl.ContentType))
+		}
+		entries = append(entries, strings.Join([]string{tmpl.Name, tmpl.Route, tmpl.Template, tmpl.ContentType}, "\x00"))
+	}
+	t.digest = hashHex(entries...)
+	templates.set(tenant, t)
+
+	return c.JSON(http.StatusOK, t)
+}
+
+func getTemplates(c echo.Context) error {
+	tenant, err := tenantOf(c)
+	if err != nil {
+		return err
+	}
+	t := templates.get(tenant)
+	if t == nil {
+		return newAPIError(http.StatusNotFound, codeNotFound, "no templates are uploaded for the tenant")
+	}
+
+	return c.JSON(http.StatusOK, t)
+}
+
+func deleteTemplates(c echo.Context) error {
+	tenant, err := tenantOf(c)
+	if err != nil {
+		return err
+	}
+	templates.set(tenant, nil)
+
+	return c.NoContent(http.StatusNoContent)
+}
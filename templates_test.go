@@ -0,0 +1,97 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/garystafford/nlp-client/mockupstream"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+)
+
+// uploadTemplates puts templates for a tenant for the duration of a test.
+func uploadTemplates(t *testing.T, tenant, body string) error {
+	t.Cleanup(func() { templates.set(tenant, nil) })
+	req := httptest.NewRequest(http.MethodPut, "/templates", strings.NewReader(body))
+	req.Header.Set(tenantHeader, tenant)
+	return putTemplates(e.NewContext(req, httptest.NewRecorder()))
+}
+
+func TestPutTemplates(t *testing.T) {
+	assert.Error(t, uploadTemplates(t, "acme", `{"templates": [{"name": "csv", "route": "/keywords", "template": "{{range"}]}`))
+	assert.Error(t, uploadTemplates(t, "acme", `{"templates": [{"name": "csv", "route": "/record", "template": "ok"}]}`))
+	assert.Error(t, uploadTemplates(t, "acme", `{"templates": [{"name": "", "route": "/keywords", "template": "ok"}]}`))
+	assert.Error(t, uploadTemplates(t, "acme", `{"templates": [{"name": "page", "route": "/keywords", "template": "ok", "content_type": "text/html"}]}`),
+		"results are never served as pages")
+	assert.Error(t, uploadTemplates(t, "acme", `{"templates": [{"name": "csv", "route": "/keywords", "template": "a"}, {"name": "csv", "route": "/keywords", "template": "b"}]}`))
+	assert.NoError(t, uploadTemplates(t, "acme", `{"templates": [{"name": "csv", "route": "/keywords", "template": "a"}, {"name": "csv", "route": "/entities", "template": "b"}]}`))
+	assert.Equal(t, echo.MIMETextPlainCharsetUTF8, templates.get("acme").find("/entities", "csv").ContentType)
+	assert.NotEmpty(t, templates.digest("acme"))
+}
+
+func TestTemplateRender(t *testing.T) {
+	assert.NoError(t, uploadTemplates(t, "acme", `{"templates": [
+		{"name": "row", "route": "/entities", "template": "{{range .}}{{csv .text}},{{lower .label}}\n{{end}}"},
+		{"name": "big", "route": "/tokens", "template": "{{range .}}{{.}}{{.}}{{end}}"}
+	]}`))
+	set := templates.get("acme")
+	body, err := set.find("/entities", "row").render([]interface{}{map[string]interface{}{"text": "Curie, Marie", "label": "PERSON"}})
+	if assert.NoError(t, err) {
+		assert.Equal(t, "\"Curie, Marie\",person\n", string(body))
+	}
+
+	defer func(max string) { templateMaxOutputBytes = max }(templateMaxOutputBytes)
+	templateMaxOutputBytes = "8"
+	_, err = set.find("/tokens", "big").render([]interface{}{"Nobel", "Prize"})
+	assert.Equal(t, errTemplateOutput, err)
+}
+
+func TestGetKeywordsTemplate(t *testing.T) {
+	rake := mockupstream.NewRake()
+	defer rake.Close()
+	defer func(url string) { urlRake = url }(urlRake)
+	urlRake = rake.URL
+	rake.SetFixture("/keywords", mockupstream.Fixture{Status: http.StatusOK, Body: `["nobel prize","marie curie"]`})
+	assert.NoError(t, uploadTemplates(t, "acme", `{"templates": [{"name": "list", "route": "/keywords", "template": "{{texts . | join \",\"}}", "content_type": "text/csv"}]}`))
+
+	req := httptest.NewRequest(http.MethodPost, "/keywords?template=list", strings.NewReader(`{"text": "Marie Curie won the Nobel Prize"}`))
+	req.Header.Set(tenantHeader, "acme")
+	w := httptest.NewRecorder()
+	c := e.NewContext(req, w)
+	c.SetPath("/keywords")
+	if assert.NoError(t, getKeywords(c)) {
+		assert.Equal(t, "text/csv", w.Header().Get(echo.HeaderContentType))
+		assert.Equal(t, "nosniff", w.Header().Get(echo.HeaderXContentTypeOptions))
+		assert.Equal(t, "nobel prize,marie curie", w.Body.String())
+	}
+
+	req = httptest.NewRequest(http.MethodPost, "/keywords?template=missing", strings.NewReader(`{"text": "Marie Curie"}`))
+	req.Header.Set(tenantHeader, "acme")
+	c = e.NewContext(req, httptest.NewRecorder())
+	c.SetPath("/keywords")
+	err := getKeywords(c)
+	if assert.Error(t, err) {
+		assert.Equal(t, http.StatusNotFound, err.(*apiError).Status)
+	}
+}
+
+func TestPostAnalyzeTemplate(t *testing.T) {
+	rake, lang := mockupstream.NewRake(), mockupstream.NewLang()
+	defer rake.Close()
+	defer lang.Close()
+	defer func(rakeURL, langURL string) { urlRake, urlLang = rakeURL, langURL }(urlRake, urlLang)
+	urlRake, urlLang = rake.URL, lang.URL
+	rake.SetFixture("/keywords", mockupstream.Fixture{Status: http.StatusOK, Body: `["nobel prize"]`})
+	lang.SetFixture("/language", mockupstream.Fixture{Status: http.StatusOK, Body: `{"language":"en","probability":0.99}`})
+	assert.NoError(t, uploadTemplates(t, "acme", `{"templates": [{"name": "line", "route": "/analyze", "template": "{{.language.language}}: {{texts .keywords | join \"; \"}}"}]}`))
+
+	req := httptest.NewRequest(http.MethodPost, "/analyze?analyses=keywords,language&template=line", strings.NewReader(`{"text": "The Nobel Prize"}`))
+	req.Header.Set(tenantHeader, "acme")
+	w := httptest.NewRecorder()
+	if assert.NoError(t, postAnalyze(e.NewContext(req, w))) {
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, "en: nobel prize", w.Body.String())
+	}
+}
@@ -0,0 +1,284 @@
+// author: Gary A. Stafford
+// site: https://programmaticponderings.com
+// license: MIT License
+// purpose: NLP microservices: nlp-client date, time, and numeric expressions
+// modified: 2026-10-14
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/labstack/echo/v4"
+)
+
+// "mdy" or "dmy", the order of numeric dates such as 10/04/2026
+var temporalDateOrder = getEnv("TEMPORAL_DATE_ORDER", "mdy")
+
+// temporalExpression is a date, time, duration, amount of money, or quantity
+// found in the request text, in characters, with its normalized value: ISO
+// 8601 for dates, times, and durations, and a decimal number with an ISO 4217
+// currency code or a unit for money and quantities.
+type temporalExpression struct {
+	Type string `json:"type"`
+	Text string `json:"text"`
+	span
+	Value string `json:"value"`
+	Unit  string `json:"unit,omitempty"`
+}
+
+// temporalRule matches expressions of a type and normalizes each match,
+// returning false for one that is not a valid expression after all.
+type temporalRule struct {
+	kind      string
+	pattern   *regexp.Regexp
+	normalize func(groups []string, reference time.Time) (string, string, bool)
+}
+
+const (
+	monthPattern = `(jan(?:uary)?|feb(?:ruary)?|mar(?:ch)?|apr(?:il)?|may|june?|july?|aug(?:ust)?|sept?(?:ember)?|oct(?:ober)?|nov(?:ember)?|dec(?:ember)?)\.?`
+	// a number with any thousands separators, such as 1,200.50
+	numberPattern = `(\d+(?:,\d{3})*(?:\.\d+)?)`
+)
+
+var (
+	numberWords = map[string]string{
+		"one": "1", "two": "2", "three": "3", "four": "4", "five": "5", "six": "6",
+		"seven": "7", "eight": "8", "nine": "9", "ten": "10", "eleven": "11", "twelve": "12",
+	}
+	durationUnits = map[string]string{
+		"second": "S", "minute": "M", "hour": "H", "day": "D", "week": "W", "month": "M", "year": "Y",
+	}
+	currencies = map[string]string{
+		"$": "USD", "us$": "USD", "€": "EUR", "£": "GBP", "¥": "JPY",
+		"dollar": "USD", "euro": "EUR", "pound": "GBP", "yen": "JPY",
+		"usd": "USD", "eur": "EUR", "gbp": "GBP", "jpy": "JPY",
+	}
+	scales = map[string]float64{"": 1, "thousand": 1e3, "million": 1e6, "billion": 1e9, "trillion": 1e12}
+	units  = map[string]string{
+		"%": "%", "percent": "%", "km": "km", "kilometer": "km", "kilometre": "km",
+		"m": "m", "meter": "m", "metre": "m", "cm": "cm", "mm": "mm",
+		"kg": "kg", "kilogram": "kg", "g": "g", "gram": "g", "lb": "lb", "lbs": "lb",
+		"mi": "mi", "mile": "mi", "ft": "ft", "feet": "ft", "foot": "ft", "inch": "in", "inches": "in",
+		"l": "L", "liter": "L", "litre": "L", "ml": "mL", "ton": "t", "tonne": "t", "°c": "°C", "°f": "°F",
+	}
+)
+
+// temporalRules are tried in order; of overlapping matches the longest is
+// kept, and of those as long the one found by the earlier rule.
+var temporalRules = []temporalRule{
+	{"date", regexp.MustCompile(`\b(\d{4})-(\d{2})-(\d{2})(?:[T ](\d{2}):(\d{2})(?::(\d{2}))?)?\b`), isoDate},
+	{"date", regexp.MustCompile(`(?i)\b` + monthPattern + `\s+(\d{1,2})(?:st|nd|rd|th)?,?\s+(\d{4})\b`), func(g []string, _ time.Time) (string, string, bool) {
+		return calendarDate(g[3], monthNumber(g[1]), g[2])
+	}},
+	{"date", regexp.MustCompile(`(?i)\b(\d{1,2})(?:st|nd|rd|th)?\s+(?:of\s+)?` + monthPattern + `,?\s+(\d{4})\b`), func(g []string, _ time.Time) (string, string, bool) {
+		return calendarDate(g[3], monthNumber(g[2]), g[1])
+	}},
+	{"date", regexp.MustCompile(`(?i)\b` + monthPattern + `\s+(\d{4})\b`), func(g []string, _ time.Time) (string, string, bool) {
+		return fmt.Sprintf("%s-%02d", g[2], monthNumber(g[1])), "", true
+	}},
+	{"date", regexp.MustCompile(`\b(\d{1,2})/(\d{1,2})/(\d{4})\b`), func(g []string, _ time.Time) (string, string, bool) {
+		month, day := g[1], g[2]
+		if temporalDateOrder == "dmy" {
+			month, day = day, month
+		}
+		n, _ := strconv.Atoi(month)
+		return calendarDate(g[3], n, day)
+	}},
+	{"date", regexp.MustCompile(`(?i)\b(today|yesterday|tomorrow)\b`), func(g []string, reference time.Time) (string, string, bool) {
+		days := map[string]int{"today": 0, "yesterday": -1, "tomorrow": 1}[strings.ToLower(g[1])]
+		return reference.AddDate(0, 0, days).Format("2006-01-02"), "", true
+	}},
+	{"time", regexp.MustCompile(`(?i)\b(\d{1,2})(?::(\d{2}))?\s*([ap])(?:\.m\.|m\b)`), func(g []string, _ time.Time) (string, string, bool) {
+		hour, _ := strconv.Atoi(g[1])
+		if hour < 1 || hour > 12 {
+			return "", "", false
+		}
+		hour %= 12
+		if strings.EqualFold(g[3], "p") {
+			hour += 12
+		}
+		return clockTime(hour, g[2])
+	}},
+	{"time", regexp.MustCompile(`\b([01]?\d|2[0-3]):([0-5]\d)\b`), func(g []string, _ time.Time) (string, string, bool) {
+		hour, _ := strconv.Atoi(g[1])
+		return clockTime(hour, g[2])
+	}},
+	{"time", regexp.MustCompile(`(?i)\b(noon|midnight)\b`), func(g []string, _ time.Time) (string, string, bool) {
+		if strings.EqualFold(g[1], "noon") {
+			return "12:00", "", true
+		}
+		return "00:00", "", true
+	}},
+	{"duration", regexp.MustCompile(`(?i)\b(\d+(?:\.\d+)?|one|two|three|four|five|six|seven|eight|nine|ten|eleven|twelve)\s+(second|minute|hour|day|week|month|year)s?\b`), func(g []string, _ time.Time) (string, string, bool) {
+		amount := strings.ToLower(g[1])
+		if word, ok := numberWords[amount]; ok {
+			amount = word
+		}
+		unit := strings.ToLower(g[2])
+		if unit == "second" || unit == "minute" || unit == "hour" {
+			return "PT" + amount + durationUnits[unit], "", true
+		}
+		return "P" + amount + durationUnits[unit], "", true
+	}},
+	{"money", regexp.MustCompile(`(?i)(US\$|\$|€|£|¥|\b(?:USD|EUR|GBP|JPY)\s?)` + numberPattern + `(?:\s+(thousand|million|billion|trillion)\b)?`), func(g []string, _ time.Time) (string, string, bool) {
+		return amountOf(g[2], g[3], currencies[strings.ToLower(strings.TrimSpace(g[1]))])
+	}},
+	{"money", regexp.MustCompile(`(?i)\b` + numberPattern + `(?:\s+(thousand|million|billion|trillion))?\s+(dollar|euro|pound|yen|USD|EUR|GBP|JPY)s?\b`), func(g []string, _ time.Time) (string, string, bool) {
+		return amountOf(g[1], g[2], currencies[strings.ToLower(g[3])])
+	}},
+	{"quantity", regexp.MustCompile(`(?i)\b` + numberPattern + `\s?(%|percent|kilomet(?:er|re)s?|met(?:er|re)s?|kilograms?|grams?|miles?|feet|foot|inch(?:es)?|lit(?:er|re)s?|tonnes?|tons?|km|cm|mm|kg|lbs?|mi|ft|ml|°[cf]|m|g|l)`), func(g []string, _ time.Time) (string, string, bool) {
+		unit := strings.ToLower(g[2])
+		if _, ok := units[unit]; !ok {
+			unit = strings.TrimSuffix(unit, "s")
+		}
+		return amountOf(g[1], "", units[unit])
+	}},
+}
+
+func monthNumber(name string) int {
+	prefix := strings.ToLower(name)[:3]
+	return strings.Index("janfebmaraprmayjunjulaugsepoctnovdec", prefix)/3 + 1
+}
+
+// calendarDate formats a date as ISO 8601, unless there is no such day.
+func calendarDate(year string, month int, day string) (string, string, bool) {
+	y, _ := strconv.Atoi(year)
+	d, _ := strconv.Atoi(day)
+	date := time.Date(y, time.Month(month), d, 0, 0, 0, 0, time.UTC)
+	if date.Day() != d || int(date.Month()) != month {
+		return "", "", false
+	}
+
+	return date.Format("2006-01-02"), "", true
+}
+
+func isoDate(g []string, _ time.Time) (string, string, bool) {
+	n, _ := strconv.Atoi(g[2])
+	date, unit, ok := calendarDate(g[1], n, g[3])
+	if !ok || g[4] == "" {
+		return date, unit, ok
+	}
+	seconds := g[6]
+	if seconds == "" {
+		seconds = "00"
+	}
+
+	return fmt.Sprintf("%sT%s:%s:%s", date, g[4], g[5], seconds), "", true
+}
+
+func clockTime(hour int, minutes string) (string, string, bool) {
+	if minutes == "" {
+		minutes = "00"
+	}
+
+	return fmt.Sprintf("%02d:%s", hour, minutes), "", true
+}
+
+// amountOf normalizes a number, with its thousands separators and any scale
+// word such as "million", to a plain decimal.
+func amountOf(number, scale, unit string) (string, string, bool) {
+	value, err := strconv.ParseFloat(strings.ReplaceAll(number, ",", ""), 64)
+	if err != nil || unit == "" {
+		return "", "", false
+	}
+
+	return strconv.FormatFloat(value*scales[strings.ToLower(scale)], 'f', -1, 64), unit, true
+}
+
+// extractTemporal finds the expressions of text with the embedded rules,
+// resolving relative dates such as "tomorrow" against reference.
+func extractTemporal(text string, reference time.Time) []temporalExpression {
+	type match struct {
+		start, end, rule int
+		expression       temporalExpression
+	}
+	var matches []match
+	for i, rule := range temporalRules {
+		for _, loc := range rule.pattern.FindAllStringSubmatchIndex(text, -1) {
+			start, end := loc[0], loc[1]
+			// a match ending inside a word, such as "5 magnets", is not a unit
+			if next, _ := utf8.DecodeRuneInString(text[end:]); end < len(text) && unicode.IsLetter(next) {
+				continue
+			}
+			groups := make([]string, len(loc)/2)
+			for g := range groups {
+				if loc[2*g] >= 0 {
+					groups[g] = text[loc[2*g]:loc[2*g+1]]
+				}
+			}
+			value, unit, ok := rule.normalize(groups, reference)
+			if !ok {
+				continue
+			}
+			offset := utf8.RuneCountInString(text[:start])
+			matches = append(matches, match{start, end, i, temporalExpression{
+				Type:  rule.kind,
+				Text:  text[start:end],
+				span:  span{Offset: &offset, Length: utf8.RuneCountInString(text[start:end])},
+				Value: value,
+				Unit:  unit,
+			}})
+		}
+	}
+	sort.SliceStable(matches, func(i, j int) bool {
+		if a, b := matches[i].end-matches[i].start, matches[j].end-matches[j].start; a != b {
+			return a > b
+		}
+		return matches[i].rule < matches[j].rule
+	})
+
+	var kept []match
+	for _, m := range matches {
+		overlaps := false
+		for _, k := range kept {
+			if m.start < k.end && k.start < m.end {
+				overlaps = true
+				break
+			}
+		}
+		if !overlaps {
+			kept = append(kept, m)
+		}
+	}
+	sort.Slice(kept, func(i, j int) bool { return kept[i].start < kept[j].start })
+
+	expressions := make([]temporalExpression, len(kept))
+	for i, k := range kept {
+		expressions[i] = k.expression
+	}
+
+	return expressions
+}
+
+// postTemporal extracts and normalizes the dates, times, durations, amounts of
+// money, and quantities of a text. Relative dates are resolved against the
+// reference date sent with it, or today.
+func postTemporal(c echo.Context) error {
+	var body struct {
+		Text      string `json:"text"`
+		Reference string `json:"reference"`
+	}
+	if err := json.NewDecoder(c.Request().Body).Decode(&body); err != nil {
+		return validationError(err.Error())
+	}
+	reference := time.Now().UTC()
+	if body.Reference != "" {
+		t, err := time.Parse("2006-01-02", body.Reference)
+		if err != nil {
+			return validationError(fmt.Sprintf("invalid reference date: %s", body.Reference))
+		}
+		reference = t
+	}
+
+	return c.JSON(http.StatusOK, extractTemporal(body.Text, reference))
+}
@@ -0,0 +1,70 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExtractTemporal(t *testing.T) {
+	reference := time.Date(2026, 10, 14, 0, 0, 0, 0, time.UTC)
+	tests := []struct {
+		text, kind, value, unit string
+	}{
+		{"on 2026-10-14T09:30 sharp", "date", "2026-10-14T09:30:00", ""},
+		{"on October 14th, 2026.", "date", "2026-10-14", ""},
+		{"on 14 Oct. 2026", "date", "2026-10-14", ""},
+		{"in May 2026", "date", "2026-05", ""},
+		{"on 10/04/2026", "date", "2026-10-04", ""},
+		{"due tomorrow", "date", "2026-10-15", ""},
+		{"at 3:30 p.m.", "time", "15:30", ""},
+		{"at 12 am", "time", "00:00", ""},
+		{"at 18:05", "time", "18:05", ""},
+		{"by noon", "time", "12:00", ""},
+		{"for two weeks", "duration", "P2W", ""},
+		{"after 90 minutes", "duration", "PT90M", ""},
+		{"costs $1,200.50", "money", "1200.5", "USD"},
+		{"won €2.5 million", "money", "2500000", "EUR"},
+		{"paid 30 pounds", "money", "30", "GBP"},
+		{"ran 5 km", "quantity", "5", "km"},
+		{"up 40%", "quantity", "40", "%"},
+		{"weighs 3 kilograms", "quantity", "3", "kg"},
+	}
+	for _, test := range tests {
+		found := extractTemporal(test.text, reference)
+		if assert.Len(t, found, 1, test.text) {
+			assert.Equal(t, test.kind, found[0].Type, test.text)
+			assert.Equal(t, test.value, found[0].Value, test.text)
+			assert.Equal(t, test.unit, found[0].Unit, test.text)
+		}
+	}
+
+	assert.Empty(t, extractTemporal("February 30, 2026 and 5 magnets in 1903", reference))
+
+	found := extractTemporal("Héléna left on May 4, 2026 at 9 am.", reference)
+	if assert.Len(t, found, 2) {
+		assert.Equal(t, "May 4, 2026", found[0].Text)
+		assert.Equal(t, 15, *found[0].Offset, "offsets are in characters")
+		assert.Equal(t, "9 am", found[1].Text)
+	}
+}
+
+func TestPostTemporal(t *testing.T) {
+	post := func(body string) (*httptest.ResponseRecorder, error) {
+		req := httptest.NewRequest(http.MethodPost, "/temporal", strings.NewReader(body))
+		w := httptest.NewRecorder()
+		return w, postTemporal(e.NewContext(req, w))
+	}
+
+	w, err := post(`{"text": "See you tomorrow.", "reference": "2026-12-31"}`)
+	if assert.NoError(t, err) {
+		assert.JSONEq(t, `[{"type": "date", "text": "tomorrow", "offset": 8, "length": 8, "value": "2027-01-01"}]`, w.Body.String())
+	}
+
+	_, err = post(`{"text": "See you tomorrow.", "reference": "31/12/2026"}`)
+	assert.Error(t, err)
+}
@@ -0,0 +1,193 @@
+// author: Gary A. Stafford
+// site: https://programmaticponderings.com
+// license: MIT License
+// purpose: NLP microservices: nlp-client locale-aware tokenization
+// modified: 2026-10-14
+
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+	"unicode"
+
+	"github.com/labstack/echo/v4"
+)
+
+var (
+	// word segmenter answering POST /tokens?lang=<code> for texts without
+	// spaces between words, with tokens as prose-app does; unset to send
+	// every text to prose-app
+	urlSegmenter = upstreamURL(getEnv("SEGMENTER_ENDPOINT", ""))
+	// languages whose /tokens requests go to the segmenter
+	segmenterLanguages = getEnv("SEGMENTER_LANGUAGES", "zh,ja,ko")
+)
+
+const segmentedContextKey = "segmented"
+
+// languageCode matches the BCP 47 tags a ?lang= may be, as in ja or zh-Hant.
+var languageCode = regexp.MustCompile(`^[A-Za-z]{2,3}(-[A-Za-z0-9]{1,8})*$`)
+
+// contractionSuffixes are the English clitics prose-app splits off of a
+// contraction, as in do n't or I 'm.
+var contractionSuffixes = []string{"n't", "'s", "'m", "'re", "'ve", "'ll", "'d"}
+
+// cjkLanguage returns the language of a text whose letters are mostly Han,
+// kana, or Hangul, by its script: ja for any kana, ko for Hangul, or zh, and
+// empty for any other text.
+func cjkLanguage(text string) string {
+	var letters, han, kana, hangul int
+	for _, r := range text {
+		switch {
+		case unicode.In(r, unicode.Hiragana, unicode.Katakana):
+			kana++
+		case unicode.Is(unicode.Hangul, r):
+			hangul++
+		case unicode.Is(unicode.Han, r):
+			han++
+		case unicode.IsLetter(r):
+			letters++
+		}
+	}
+	cjk := han + kana + hangul
+	switch {
+	case cjk == 0 || cjk < letters:
+		return ""
+	case kana > 0:
+		return "ja"
+	case hangul > han:
+		return "ko"
+	}
+
+	return "zh"
+}
+
+// segmenterLanguage returns the language a /tokens request is segmented in,
+// by its ?lang= or else by the text's script, or empty to tokenize it with
+// prose-app.
+func segmenterLanguage(lang, text string) string {
+	if lang == "" {
+		lang = cjkLanguage(text)
+	}
+	for _, segmented := range splitList(segmenterLanguages) {
+		if lang != "" && strings.EqualFold(strings.SplitN(lang, "-", 2)[0], segmented) {
+			return lang
+		}
+	}
+
+	return ""
+}
+
+// punctuationToken reports whether a token is only punctuation and symbols.
+func punctuationToken(token string) bool {
+	for _, r := range token {
+		if !unicode.IsPunct(r) && !unicode.IsSymbol(r) {
+			return false
+		}
+	}
+
+	return token != ""
+}
+
+// contractionSuffix returns the clitic a token ends with, if any, with the
+// curly apostrophe counted as a straight one.
+func contractionSuffix(token string) string {
+	runes := []rune(token)
+	for _, suffix := range contractionSuffixes {
+		n := len([]rune(suffix))
+		if n > len(runes) {
+			continue
+		}
+		tail := string(runes[len(runes)-n:])
+		if strings.ToLower(strings.ReplaceAll(tail, "’", "'")) == suffix {
+			return tail
+		}
+	}
+
+	return ""
+}
+
+// withText returns a token item with its text replaced, as a string for a
+// string item or as a copy of an object item.
+func withText(item interface{}, text string) interface{} {
+	fields, ok := item.(map[string]interface{})
+	if !ok {
+		return text
+	}
+	copied := map[string]interface{}{}
+	for name, value := range fields {
+		copied[name] = value
+	}
+	copied["text"] = text
+
+	return copied
+}
+
+// tokenize drops punctuation tokens and splits or joins contractions, as a
+// filter's punctuation and split_contractions options ask.
+func (f *resultFilter) tokenize(items []interface{}) []interface{} {
+	var tokens []interface{}
+	for _, item := range items {
+		text, ok := itemText(item)
+		if !ok {
+			tokens = append(tokens, item)
+			continue
+		}
+		if f.punctuation != nil && !*f.punctuation && punctuationToken(text) {
+			continue
+		}
+		if f.splitContractions == nil {
+			tokens = append(tokens, item)
+			continue
+		}
+		suffix := contractionSuffix(text)
+		switch {
+		case *f.splitContractions && suffix != "" && suffix != text:
+			tokens = append(tokens, withText(item, text[:len(text)-len(suffix)]), withText(item, suffix))
+		case !*f.splitContractions && suffix == text && len(tokens) > 0:
+			// a clitic on its own rejoins the token before it
+			if previous, ok := itemText(tokens[len(tokens)-1]); ok && !punctuationToken(previous) {
+				tokens[len(tokens)-1] = withText(tokens[len(tokens)-1], previous+text)
+				continue
+			}
+			tokens = append(tokens, item)
+		default:
+			tokens = append(tokens, item)
+		}
+	}
+	if tokens == nil {
+		tokens = []interface{}{}
+	}
+
+	return tokens
+}
+
+// getTokens tokenizes a text with the tokenizer of its language.
+func getTokens(c echo.Context) error {
+	ctx := c.Request().Context()
+	lang := c.QueryParam("lang")
+	if lang != "" && !languageCode.MatchString(lang) {
+		return validationError(fmt.Sprintf("invalid lang: %s", lang))
+	}
+	target := urlProse + "/tokens"
+	if urlSegmenter != "" {
+		var text string
+		if lang == "" {
+			// only the text's script tells which tokenizer it needs
+			var err error
+			if text, err = requestText(c.Request()); err != nil {
+				return validationError(err.Error())
+			}
+		}
+		if segmented := segmenterLanguage(lang, text); segmented != "" {
+			target = urlSegmenter + "/tokens?lang=" + url.QueryEscape(segmented)
+			c.Set(segmentedContextKey, true)
+		}
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, target, c.Request().Body)
+
+	return serviceResponse(err, req, c)
+}
@@ -0,0 +1,115 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/garystafford/nlp-client/mockupstream"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCJKLanguage(t *testing.T) {
+	assert.Equal(t, "ja", cjkLanguage("東京に行きます"))
+	assert.Equal(t, "zh", cjkLanguage("我们去北京"))
+	assert.Equal(t, "ko", cjkLanguage("서울에 갑니다"))
+	assert.Equal(t, "zh", cjkLanguage("用 Go 写"), "a few Latin letters do not outweigh the Han")
+	assert.Empty(t, cjkLanguage("The Nobel Prize"))
+	assert.Empty(t, cjkLanguage("Kyoto (京都) is a city in Japan"))
+}
+
+func TestSegmenterLanguage(t *testing.T) {
+	assert.Equal(t, "ja", segmenterLanguage("", "東京に行きます"))
+	assert.Equal(t, "zh-Hant", segmenterLanguage("zh-Hant", "Taipei"), "a ?lang= wins over the script")
+	assert.Empty(t, segmenterLanguage("en", "東京"))
+	assert.Empty(t, segmenterLanguage("", "The Nobel Prize"))
+}
+
+func TestContractionSuffix(t *testing.T) {
+	assert.Equal(t, "n't", contractionSuffix("don't"))
+	assert.Equal(t, "’m", contractionSuffix("I’m"))
+	assert.Equal(t, "'S", contractionSuffix("IT'S"))
+	assert.Equal(t, "'s", contractionSuffix("'s"))
+	assert.Empty(t, contractionSuffix("Curie"))
+}
+
+func TestParseResultFilterTokenizer(t *testing.T) {
+	f := requestFilter("/tokens?punctuation=false&split_contractions=true")
+	if assert.NotNil(t, f) {
+		assert.False(t, *f.punctuation)
+		assert.True(t, *f.splitContractions)
+	}
+	req := httptest.NewRequest(http.MethodPost, "/tokens?punctuation=some", nil)
+	c := e.NewContext(req, httptest.NewRecorder())
+	c.SetPath("/tokens")
+	_, err := parseResultFilter(c)
+	assert.EqualError(t, err, "invalid punctuation: some")
+}
+
+func TestResultFilterTokenize(t *testing.T) {
+	tokens := []byte(`["I","do","n't","know",",","she","’s","right","."]`)
+	no, yes := false, true
+
+	f := &resultFilter{punctuation: &no}
+	body, err := f.apply("/tokens", tokens)
+	if assert.NoError(t, err) {
+		assert.Equal(t, `["I","do","n't","know","she","’s","right"]`, string(body))
+	}
+
+	f = &resultFilter{splitContractions: &no}
+	body, err = f.apply("/tokens", tokens)
+	if assert.NoError(t, err) {
+		assert.Equal(t, `["I","don't","know",",","she’s","right","."]`, string(body))
+	}
+
+	f = &resultFilter{splitContractions: &yes}
+	body, err = f.apply("/tokens", []byte(`["I'm",{"text":"can't","tag":"MD"}]`))
+	if assert.NoError(t, err) {
+		assert.JSONEq(t, `["I","'m",{"text":"ca","tag":"MD"},{"text":"n't","tag":"MD"}]`, string(body))
+	}
+
+	body, err = f.apply("/keywords", []byte(`["don't"]`))
+	if assert.NoError(t, err) {
+		assert.Equal(t, `["don't"]`, string(body), "only tokens are retokenized")
+	}
+}
+
+func TestGetTokensSegmented(t *testing.T) {
+	var lang string
+	segmenter := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		lang = r.URL.Query().Get("lang")
+		w.Header().Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		_, _ = w.Write([]byte(`["東京","に","行きます","。"]`))
+	}))
+	defer segmenter.Close()
+	prose := mockupstream.NewProse()
+	defer prose.Close()
+	defer func(segmenter, prose string) { urlSegmenter, urlProse = segmenter, prose }(urlSegmenter, urlProse)
+	urlSegmenter, urlProse = segmenter.URL, prose.URL
+	prose.SetFixture("/tokens", mockupstream.Fixture{Status: http.StatusOK, Body: `["The","Nobel","Prize","."]`})
+
+	req := httptest.NewRequest(http.MethodPost, "/tokens?punctuation=false", strings.NewReader(`{"text": "東京に行きます。"}`))
+	w := httptest.NewRecorder()
+	c := e.NewContext(req, w)
+	c.SetPath("/tokens")
+	if assert.NoError(t, getTokens(c)) {
+		assert.Equal(t, "ja", lang)
+		assert.Equal(t, `["東京","に","行きます"]`, w.Body.String())
+	}
+
+	req = httptest.NewRequest(http.MethodPost, "/tokens?punctuation=false", strings.NewReader(`{"text": "The Nobel Prize."}`))
+	w = httptest.NewRecorder()
+	c = e.NewContext(req, w)
+	c.SetPath("/tokens")
+	if assert.NoError(t, getTokens(c)) {
+		assert.Equal(t, `["The","Nobel","Prize"]`, w.Body.String(), "other texts go to prose-app")
+	}
+
+	req = httptest.NewRequest(http.MethodPost, "/tokens?lang=en_US", strings.NewReader(`{"text": "The Nobel Prize."}`))
+	err := getTokens(e.NewContext(req, httptest.NewRecorder()))
+	if assert.Error(t, err) {
+		assert.Equal(t, http.StatusBadRequest, err.(*apiError).Status)
+	}
+}
@@ -0,0 +1,289 @@
+// author: Gary A. Stafford
+// site: https://programmaticponderings.com
+// license: MIT License
+// purpose: NLP microservices: nlp-client topic modeling
+// modified: 2026-10-14
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"math/rand"
+	"net/http"
+	"sort"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/labstack/echo/v4"
+)
+
+var (
+	// topic-modeling backend answering POST /topics; unset models topics in process
+	urlTopics = upstreamURL(getEnv("TOPICS_ENDPOINT", ""))
+	// bounds on the in-process model, which is sized for small document sets
+	topicsMaxDocuments  = getEnv("TOPICS_MAX_DOCUMENTS", "500")
+	topicsMaxVocabulary = getEnv("TOPICS_MAX_VOCABULARY", "1000")
+	topicsIterations    = getEnv("TOPICS_ITERATIONS", "200")
+)
+
+// topicStopwords are the common English words left out of the vocabulary;
+// words under three letters are always left out.
+var topicStopwords = map[string]bool{
+	"about": true, "after": true, "all": true, "also": true, "and": true, "any": true, "are": true, "because": true,
+	"been": true, "before": true, "being": true, "but": true, "can": true, "could": true, "did": true, "does": true,
+	"each": true, "for": true, "from": true, "had": true, "has": true, "have": true, "her": true, "here": true,
+	"his": true, "how": true, "into": true, "its": true, "just": true, "more": true, "most": true, "not": true,
+	"now": true, "only": true, "other": true, "our": true, "out": true, "over": true, "she": true, "should": true,
+	"some": true, "such": true, "than": true, "that": true, "the": true, "their": true, "them": true, "then": true,
+	"there": true, "these": true, "they": true, "this": true, "those": true, "through": true, "very": true,
+	"was": true, "were": true, "what": true, "when": true, "where": true, "which": true, "while": true, "who": true,
+	"will": true, "with": true, "would": true, "you": true, "your": true,
+}
+
+type topicsRequest struct {
+	Documents []batchDocument `json:"documents"`
+	Topics    int             `json:"topics"`
+	Terms     int             `json:"terms"`
+}
+
+type topicTerm struct {
+	Term   string  `json:"term"`
+	Weight float64 `json:"weight"`
+}
+
+type topic struct {
+	ID    int         `json:"id"`
+	Terms []topicTerm `json:"terms"`
+}
+
+type documentTopics struct {
+	ID      string    `json:"id"`
+	Weights []float64 `json:"weights"`
+}
+
+type topicModel struct {
+	Topics    []topic          `json:"topics"`
+	Documents []documentTopics `json:"documents"`
+}
+
+// topicTerms splits text into lowercased words, leaving out stopwords and short words.
+func topicTerms(text string) []string {
+	var terms []string
+	for _, word := range strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	}) {
+		if utf8.RuneCountInString(word) >= 3 && !topicStopwords[word] {
+			terms = append(terms, word)
+		}
+	}
+
+	return terms
+}
+
+// termMatrix weighs the terms of each document by TF-IDF, with each row
+// scaled to unit length, over the maxTerms terms found in the most documents.
+func termMatrix(docs []batchDocument, maxTerms int) ([][]float64, []string) {
+	counts := make([]map[string]int, len(docs))
+	df := map[string]int{}
+	for i, doc := range docs {
+		counts[i] = map[string]int{}
+		for _, term := range topicTerms(doc.Text) {
+			if counts[i][term] == 0 {
+				df[term]++
+			}
+			counts[i][term]++
+		}
+	}
+
+	vocabulary := make([]string, 0, len(df))
+	for term := range df {
+		vocabulary = append(vocabulary, term)
+	}
+	sort.Slice(vocabulary, func(i, j int) bool {
+		if df[vocabulary[i]] != df[vocabulary[j]] {
+			return df[vocabulary[i]] > df[vocabulary[j]]
+		}
+		return vocabulary[i] < vocabulary[j]
+	})
+	if len(vocabulary) > maxTerms {
+		vocabulary = vocabulary[:maxTerms]
+	}
+
+	matrix := make([][]float64, len(docs))
+	for i := range docs {
+		matrix[i] = make([]float64, len(vocabulary))
+		norm := 0.0
+		for j, term := range vocabulary {
+			if n := counts[i][term]; n > 0 {
+				matrix[i][j] = float64(n) * (math.Log(float64(1+len(docs))/float64(1+df[term])) + 1)
+				norm += matrix[i][j] * matrix[i][j]
+			}
+		}
+		if norm > 0 {
+			norm = math.Sqrt(norm)
+			for j := range matrix[i] {
+				matrix[i][j] /= norm
+			}
+		}
+	}
+
+	return matrix, vocabulary
+}
+
+// factorize approximates the documents-by-terms matrix v as w×h, with w
+// documents by k topics and h k topics by terms, by non-negative matrix
+// factorization with multiplicative updates. It starts from a fixed seed,
+// so the same documents always give the same topics.
+func factorize(v [][]float64, k, iterations int) ([][]float64, [][]float64) {
+	const epsilon = 1e-9
+	docs, terms := len(v), 0
+	if docs > 0 {
+		terms = len(v[0])
+	}
+	rng := rand.New(rand.NewSource(1))
+	w, h := make([][]float64, docs), make([][]float64, k)
+	for i := range w {
+		w[i] = make([]float64, k)
+		for t := range w[i] {
+			w[i][t] = rng.Float64()
+		}
+	}
+	for t := range h {
+		h[t] = make([]float64, terms)
+		for j := range h[t] {
+			h[t][j] = rng.Float64()
+		}
+	}
+
+	for n := 0; n < iterations; n++ {
+		// h = h ⊙ (wᵀv) ⊘ (wᵀwh)
+		wtw := make([][]float64, k)
+		for a := range wtw {
+			wtw[a] = make([]float64, k)
+			for b := range wtw[a] {
+				for i := range w {
+					wtw[a][b] += w[i][a] * w[i][b]
+				}
+			}
+		}
+		for t := range h {
+			for j := range h[t] {
+				numerator, denominator := 0.0, 0.0
+				for i := range v {
+					numerator += w[i][t] * v[i][j]
+				}
+				for b := range h {
+					denominator += wtw[t][b] * h[b][j]
+				}
+				h[t][j] *= numerator / (denominator + epsilon)
+			}
+		}
+
+		// w = w ⊙ (vhᵀ) ⊘ (whhᵀ)
+		hht := make([][]float64, k)
+		for a := range hht {
+			hht[a] = make([]float64, k)
+			for b := range hht[a] {
+				for j := 0; j < terms; j++ {
+					hht[a][b] += h[a][j] * h[b][j]
+				}
+			}
+		}
+		for i := range w {
+			for t := range w[i] {
+				numerator, denominator := 0.0, 0.0
+				for j := 0; j < terms; j++ {
+					numerator += v[i][j] * h[t][j]
+				}
+				for b := range hht {
+					denominator += w[i][b] * hht[b][t]
+				}
+				w[i][t] *= numerator / (denominator + epsilon)
+			}
+		}
+	}
+
+	return w, h
+}
+
+// proportions scales weights to sum to one, rounded for the response.
+func proportions(weights []float64) []float64 {
+	total := 0.0
+	for _, weight := range weights {
+		total += weight
+	}
+	scaled := make([]float64, len(weights))
+	for i, weight := range weights {
+		if total > 0 {
+			scaled[i] = math.Round(weight/total*1e4) / 1e4
+		}
+	}
+
+	return scaled
+}
+
+// modelTopics finds k topics in docs, each with its top terms, and the
+// weight of each topic in each document.
+func modelTopics(docs []batchDocument, k, terms, maxTerms, iterations int) topicModel {
+	matrix, vocabulary := termMatrix(docs, maxTerms)
+	w, h := factorize(matrix, k, iterations)
+
+	model := topicModel{Topics: make([]topic, k), Documents: make([]documentTopics, len(docs))}
+	for t := range h {
+		weights := proportions(h[t])
+		order := make([]int, len(weights))
+		for j := range order {
+			order[j] = j
+		}
+		sort.SliceStable(order, func(a, b int) bool { return weights[order[a]] > weights[order[b]] })
+		model.Topics[t] = topic{ID: t, Terms: []topicTerm{}}
+		for _, j := range order {
+			if len(model.Topics[t].Terms) == terms || weights[j] == 0 {
+				break
+			}
+			model.Topics[t].Terms = append(model.Topics[t].Terms, topicTerm{vocabulary[j], weights[j]})
+		}
+	}
+	for i, doc := range docs {
+		model.Documents[i] = documentTopics{ID: doc.ID, Weights: proportions(w[i])}
+	}
+
+	return model
+}
+
+// postTopics models the topics of a set of documents, with the topic-modeling
+// backend when one is configured, or else in process.
+func postTopics(c echo.Context) error {
+	if urlTopics != "" {
+		req, err := http.NewRequestWithContext(c.Request().Context(), http.MethodPost, urlTopics+"/topics", c.Request().Body)
+		return serviceResponse(err, req, c)
+	}
+
+	var request topicsRequest
+	if err := json.NewDecoder(c.Request().Body).Decode(&request); err != nil {
+		return validationError(err.Error())
+	}
+	if request.Topics == 0 {
+		request.Topics = 5
+	}
+	if request.Terms == 0 {
+		request.Terms = 10
+	}
+	switch max := envInt(topicsMaxDocuments, 500); {
+	case len(request.Documents) == 0:
+		return validationError("documents must not be empty")
+	case len(request.Documents) > max:
+		return newAPIError(http.StatusRequestEntityTooLarge, codePayloadTooLarge,
+			fmt.Sprintf("more than %d documents, configure a topic-modeling backend", max))
+	case request.Topics < 1 || request.Topics > len(request.Documents):
+		return validationError("topics must be between 1 and the number of documents")
+	case request.Terms < 1:
+		return validationError("terms must be positive")
+	}
+
+	return c.JSON(http.StatusOK, modelTopics(request.Documents, request.Topics, request.Terms,
+		envInt(topicsMaxVocabulary, 1000), envInt(topicsIterations, 200)))
+}
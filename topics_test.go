@@ -0,0 +1,85 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTopicTerms(t *testing.T) {
+	assert.Equal(t, []string{"marie", "curie", "discovered", "radium", "1898"},
+		topicTerms("Marie Curie discovered radium in 1898, and it was..."))
+}
+
+func TestModelTopics(t *testing.T) {
+	docs := []batchDocument{
+		{ID: "a", Text: "Radium and polonium are radioactive elements."},
+		{ID: "b", Text: "Curie isolated radium; polonium decays quickly."},
+		{ID: "c", Text: "The striker scored a goal in the final match."},
+		{ID: "d", Text: "A late goal decided the match for the striker."},
+	}
+	model := modelTopics(docs, 2, 3, 1000, 200)
+
+	if assert.Len(t, model.Topics, 2) && assert.Len(t, model.Documents, 4) {
+		dominant := func(i int) int {
+			if model.Documents[i].Weights[0] > model.Documents[i].Weights[1] {
+				return 0
+			}
+			return 1
+		}
+		assert.Equal(t, dominant(0), dominant(1))
+		assert.Equal(t, dominant(2), dominant(3))
+		assert.NotEqual(t, dominant(0), dominant(2))
+
+		var science []string
+		for _, term := range model.Topics[dominant(0)].Terms {
+			science = append(science, term.Term)
+		}
+		assert.Subset(t, []string{"radium", "polonium", "radioactive", "elements", "curie", "isolated", "decays", "quickly"}, science)
+	}
+	assert.Equal(t, model, modelTopics(docs, 2, 3, 1000, 200), "topics are deterministic")
+}
+
+func TestPostTopics(t *testing.T) {
+	body := `{"documents": [{"id": "a", "text": "radium polonium"}, {"id": "b", "text": "goal striker"}], "topics": 2, "terms": 2}`
+	req := httptest.NewRequest(http.MethodPost, "/topics", strings.NewReader(body))
+	w := httptest.NewRecorder()
+
+	if assert.NoError(t, postTopics(e.NewContext(req, w))) {
+		assert.Equal(t, http.StatusOK, w.Code)
+		var model topicModel
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &model))
+		assert.Len(t, model.Topics, 2)
+		assert.Equal(t, "a", model.Documents[0].ID)
+	}
+
+	req = httptest.NewRequest(http.MethodPost, "/topics", strings.NewReader(`{"documents": [{"id": "a", "text": "radium"}], "topics": 2}`))
+	err := postTopics(e.NewContext(req, httptest.NewRecorder()))
+	if assert.Error(t, err) {
+		assert.Equal(t, codeValidationFailed, err.(*apiError).Code)
+	}
+}
+
+func TestPostTopicsBackend(t *testing.T) {
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/topics", r.URL.Path)
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"topics":[],"documents":[]}`))
+	}))
+	defer backend.Close()
+	defer func(url string) { urlTopics = url }(urlTopics)
+	urlTopics = backend.URL
+
+	req := httptest.NewRequest(http.MethodPost, "/topics", strings.NewReader(`{"documents": []}`))
+	w := httptest.NewRecorder()
+	c := e.NewContext(req, w)
+	c.SetPath("/topics")
+
+	if assert.NoError(t, postTopics(c)) {
+		assert.JSONEq(t, `{"topics":[],"documents":[]}`, w.Body.String())
+	}
+}
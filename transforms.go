@@ -0,0 +1,251 @@
+// author: Gary A. Stafford
+// site: https://programmaticponderings.com
+// license: MIT License
+// purpose: NLP microservices: nlp-client request and response transforms
+// modified: 2026-10-14
+
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"strings"
+	"sync"
+	"unicode"
+
+	"golang.org/x/net/html"
+	"golang.org/x/text/unicode/norm"
+)
+
+// requestTransform rewrites the text of an analysis request before it is forwarded upstream.
+type requestTransform func(text string) (string, error)
+
+// responseTransform rewrites an upstream response body before it is returned to the caller.
+type responseTransform func(body []byte) ([]byte, error)
+
+var (
+	// e.g. "/keywords=strip_html,collapse_whitespace;/entities=strip_html"
+	requestTransformRoutes  = getEnv("REQUEST_TRANSFORMS", "")
+	responseTransformRoutes = getEnv("RESPONSE_TRANSFORMS", "")
+	// comma-separated request transforms applied to the text of every analysis
+	// route before its own, e.g. "nfc,strip_control,decode_entities"
+	sanitizeTransforms = getEnv("SANITIZE", "")
+
+	transformsMu       sync.RWMutex
+	requestTransforms  = map[string]requestTransform{}
+	responseTransforms = map[string]responseTransform{}
+)
+
+func init() {
+	registerRequestTransform("trim", func(text string) (string, error) {
+		return strings.TrimSpace(text), nil
+	})
+	registerRequestTransform("lowercase", func(text string) (string, error) {
+		return strings.ToLower(text), nil
+	})
+	registerRequestTransform("collapse_whitespace", func(text string) (string, error) {
+		return strings.Join(strings.Fields(text), " "), nil
+	})
+	registerRequestTransform("strip_html", stripHTML)
+	registerRequestTransform("nfc", func(text string) (string, error) {
+		return norm.NFC.String(text), nil
+	})
+	registerRequestTransform("strip_control", func(text string) (string, error) {
+		return strings.Map(func(r rune) rune {
+			if unicode.IsControl(r) && !unicode.IsSpace(r) {
+				return -1
+			}
+			return r
+		}, text), nil
+	})
+	registerRequestTransform("decode_entities", func(text string) (string, error) {
+		return html.UnescapeString(text), nil
+	})
+	registerRequestTransform("strip_emoji", func(text string) (string, error) {
+		return strings.Map(func(r rune) rune {
+			if unicode.Is(emoji, r) {
+				return -1
+			}
+			return r
+		}, text), nil
+	})
+	registerResponseTransform("compact", func(body []byte) ([]byte, error) {
+		var buf bytes.Buffer
+		err := json.Compact(&buf, body)
+		return buf.Bytes(), err
+	})
+}
+
+// registerRequestTransform makes a request transform available to REQUEST_TRANSFORMS.
+func registerRequestTransform(name string, t requestTransform) {
+	transformsMu.Lock()
+	defer transformsMu.Unlock()
+
+	requestTransforms[name] = t
+}
+
+// registerResponseTransform makes a response transform available to RESPONSE_TRANSFORMS.
+func registerResponseTransform(name string, t responseTransform) {
+	transformsMu.Lock()
+	defer transformsMu.Unlock()
+
+	responseTransforms[name] = t
+}
+
+// routeTransforms returns the transform names configured for route in a
+// "route=name,name;route=name" list.
+func routeTransforms(config, route string) []string {
+	for _, entry := range strings.Split(config, ";") {
+		parts := strings.SplitN(entry, "=", 2)
+		if len(parts) != 2 || strings.TrimSpace(parts[0]) != route {
+			continue
+		}
+		var names []string
+		for _, name := range strings.Split(parts[1], ",") {
+			if name = strings.TrimSpace(name); name != "" {
+				names = append(names, name)
+			}
+		}
+		return names
+	}
+
+	return nil
+}
+
+// requestTransformsFor returns the request transforms applied to the text of
+// route: the SANITIZE pipeline for analysis routes, then the route's own.
+func requestTransformsFor(route string) []string {
+	var names []string
+	if _, ok := analysisEndpoint(strings.TrimPrefix(route, "/")); ok {
+		names = splitList(sanitizeTransforms)
+	}
+
+	return append(names, routeTransforms(requestTransformRoutes, route)...)
+}
+
+func hasTransforms(route string) bool {
+	return len(requestTransformsFor(route)) > 0 ||
+		len(routeTransforms(responseTransformRoutes, route)) > 0
+}
+
+func transformText(route, text string) (string, error) {
+	transformsMu.RLock()
+	defer transformsMu.RUnlock()
+
+	for _, name := range requestTransformsFor(route) {
+		t, ok := requestTransforms[name]
+		if !ok {
+			return "", fmt.Errorf("unknown request transform: %s", name)
+		}
+		var err error
+		if text, err = t(text); err != nil {
+			return "", err
+		}
+	}
+
+	return text, nil
+}
+
+// transformRequestBody applies the request transforms of route to the text
+// field of a JSON request body, leaving any other fields untouched.
+func transformRequestBody(route string, body io.Reader) ([]byte, error) {
+	var fields map[string]json.RawMessage
+	if err := json.NewDecoder(body).Decode(&fields); err != nil {
+		return nil, err
+	}
+
+	var text string
+	if raw, ok := fields["text"]; ok {
+		if err := json.Unmarshal(raw, &text); err != nil {
+			return nil, err
+		}
+	}
+	text, err := transformText(route, text)
+	if err != nil {
+		return nil, err
+	}
+	if fields["text"], err = json.Marshal(text); err != nil {
+		return nil, err
+	}
+
+	return json.Marshal(fields)
+}
+
+func transformResponse(route string, body []byte) ([]byte, error) {
+	transformsMu.RLock()
+	defer transformsMu.RUnlock()
+
+	for _, name := range routeTransforms(responseTransformRoutes, route) {
+		t, ok := responseTransforms[name]
+		if !ok {
+			return nil, fmt.Errorf("unknown response transform: %s", name)
+		}
+		var err error
+		if body, err = t(body); err != nil {
+			return nil, err
+		}
+	}
+
+	return body, nil
+}
+
+func readTransformedResponse(route string, body io.Reader) ([]byte, error) {
+	data, err := ioutil.ReadAll(body)
+	if err != nil {
+		return nil, err
+	}
+
+	return transformResponse(route, data)
+}
+
+// stripHTML returns the text content of an HTML fragment, dropping scripts and styles.
+func stripHTML(text string) (string, error) {
+	var out strings.Builder
+	z := html.NewTokenizer(strings.NewReader(text))
+	skip := 0
+	for {
+		switch z.Next() {
+		case html.ErrorToken:
+			if z.Err() == io.EOF {
+				return strings.TrimSpace(out.String()), nil
+			}
+			return "", z.Err()
+		case html.StartTagToken:
+			if name, _ := z.TagName(); isRawTextTag(name) {
+				skip++
+			}
+			out.WriteByte(' ')
+		case html.EndTagToken:
+			if name, _ := z.TagName(); isRawTextTag(name) && skip > 0 {
+				skip--
+			}
+			out.WriteByte(' ')
+		case html.TextToken:
+			if skip == 0 {
+				out.Write(z.Text())
+			}
+		}
+	}
+}
+
+func isRawTextTag(name []byte) bool {
+	return string(name) == "script" || string(name) == "style"
+}
+
+// emoji covers the pictographic blocks along with the joiners, variation
+// selectors, and tags that combine them into sequences.
+var emoji = &unicode.RangeTable{
+	R16: []unicode.Range16{
+		{Lo: 0x200d, Hi: 0x200d, Stride: 1},
+		{Lo: 0x2600, Hi: 0x27bf, Stride: 1},
+		{Lo: 0x2b00, Hi: 0x2bff, Stride: 1},
+		{Lo: 0xfe0e, Hi: 0xfe0f, Stride: 1},
+	},
+	R32: []unicode.Range32{
+		{Lo: 0x1f000, Hi: 0x1faff, Stride: 1},
+		{Lo: 0xe0020, Hi: 0xe007f, Stride: 1},
+	},
+}
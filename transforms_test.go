@@ -0,0 +1,94 @@
+package main
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRouteTransforms(t *testing.T) {
+	config := "/keywords=strip_html, trim;/entities=lowercase"
+
+	assert.Equal(t, []string{"strip_html", "trim"}, routeTransforms(config, "/keywords"))
+	assert.Equal(t, []string{"lowercase"}, routeTransforms(config, "/entities"))
+	assert.Nil(t, routeTransforms(config, "/tokens"))
+}
+
+func TestStripHTML(t *testing.T) {
+	text, err := stripHTML(`<p>Marie <b>Curie</b></p><script>alert("x")</script><style>p {}</style>won &amp; won`)
+	if assert.NoError(t, err) {
+		assert.Equal(t, "Marie Curie won & won", strings.Join(strings.Fields(text), " "))
+	}
+}
+
+func TestTransformRequestBody(t *testing.T) {
+	defer func(routes string) { requestTransformRoutes = routes }(requestTransformRoutes)
+	requestTransformRoutes = "/keywords=strip_html,collapse_whitespace"
+
+	body, err := transformRequestBody("/keywords", strings.NewReader(`{"text": "<p>Albert   Einstein</p>", "id": 7}`))
+	if assert.NoError(t, err) {
+		assert.JSONEq(t, `{"text": "Albert Einstein", "id": 7}`, string(body))
+	}
+}
+
+func TestGetKeywordsTransforms(t *testing.T) {
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		_, _ = w.Write([]byte("[\n  " + string(body) + "\n]"))
+	}))
+	defer upstream.Close()
+	defer func(url, request, response string) {
+		urlRake, requestTransformRoutes, responseTransformRoutes = url, request, response
+	}(urlRake, requestTransformRoutes, responseTransformRoutes)
+	urlRake = upstream.URL
+	requestTransformRoutes = "/keywords=strip_html"
+	responseTransformRoutes = "/keywords=compact"
+
+	req := httptest.NewRequest(http.MethodPost, "/keywords", strings.NewReader(`{"text":"<i>Nobel</i>"}`))
+	w := httptest.NewRecorder()
+	c := e.NewContext(req, w)
+	c.SetPath("/keywords")
+
+	if assert.NoError(t, getKeywords(c)) {
+		assert.Equal(t, `[{"text":"Nobel"}]`, w.Body.String())
+	}
+}
+
+func TestSanitizeTransforms(t *testing.T) {
+	tests := []struct {
+		transform string
+		text      string
+		expected  string
+	}{
+		{"nfc", "Café", "Café"},
+		{"strip_control", "Marie\x00 Curie\x1b\n\tPhysicist", "Marie Curie\n\tPhysicist"},
+		{"decode_entities", "Curie &amp; Joliot &#8212; &lt;1903&gt;", "Curie & Joliot — <1903>"},
+		{"strip_emoji", "Nobel \U0001F3C6\U0001F3FD prize ❤️ \U0001F469‍\U0001F52C", "Nobel  prize  "},
+	}
+	for _, tt := range tests {
+		t.Run(tt.transform, func(t *testing.T) {
+			text, err := requestTransforms[tt.transform](tt.text)
+			if assert.NoError(t, err) {
+				assert.Equal(t, tt.expected, text)
+			}
+		})
+	}
+}
+
+func TestRequestTransformsFor(t *testing.T) {
+	defer func(sanitize, routes string) {
+		sanitizeTransforms, requestTransformRoutes = sanitize, routes
+	}(sanitizeTransforms, requestTransformRoutes)
+	sanitizeTransforms = "nfc, strip_control"
+	requestTransformRoutes = "/keywords=strip_html;/record=trim"
+
+	assert.Equal(t, []string{"nfc", "strip_control", "strip_html"}, requestTransformsFor("/keywords"))
+	assert.Equal(t, []string{"nfc", "strip_control"}, requestTransformsFor("/language"))
+	// the pipeline only sanitizes analysis routes
+	assert.Equal(t, []string{"trim"}, requestTransformsFor("/record"))
+	assert.Empty(t, requestTransformsFor("/health/:app"))
+}
@@ -0,0 +1,126 @@
+// author: Gary A. Stafford
+// site: https://programmaticponderings.com
+// license: MIT License
+// purpose: NLP microservices: nlp-client upstream transport
+// modified: 2026-10-14
+
+package main
+
+import (
+	"context"
+	"crypto/tls"
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+
+	"golang.org/x/net/http2"
+)
+
+var (
+	upstreamHTTP2             = getEnv("UPSTREAM_HTTP2", "true")
+	upstreamH2C               = getEnv("UPSTREAM_H2C", "false")
+	upstreamMaxIdleConns      = getEnv("UPSTREAM_MAX_IDLE_CONNS", "100")
+	upstreamMaxIdlePerHost    = getEnv("UPSTREAM_MAX_IDLE_CONNS_PER_HOST", "10")
+	upstreamMaxConnsPerHost   = getEnv("UPSTREAM_MAX_CONNS_PER_HOST", "0")
+	upstreamIdleConnTimeout   = getEnv("UPSTREAM_IDLE_CONN_TIMEOUT", "90s")
+	upstreamStrictMaxStreams  = getEnv("UPSTREAM_H2_STRICT_MAX_CONCURRENT_STREAMS", "false")
+	upstreamH2ReadIdleTimeout = getEnv("UPSTREAM_H2_READ_IDLE_TIMEOUT", "30s")
+	upstreamH2PingTimeout     = getEnv("UPSTREAM_H2_PING_TIMEOUT", "15s")
+	upstreamTransport         = withUpstreamQueue(withFailover(withFaultInjection(withOAuth2(withSigV4(newUpstreamTransport())))))
+	upstreamClient            = &http.Client{Transport: upstreamTransport}
+)
+
+func envBool(value string) bool {
+	b, _ := strconv.ParseBool(value)
+	return b
+}
+
+func envInt(value string, fallback int) int {
+	i, err := strconv.Atoi(value)
+	if err != nil {
+		return fallback
+	}
+
+	return i
+}
+
+func envFloat(value string, fallback float64) float64 {
+	f, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		return fallback
+	}
+
+	return f
+}
+
+func envDuration(value string, fallback time.Duration) time.Duration {
+	d, err := time.ParseDuration(value)
+	if err != nil {
+		return fallback
+	}
+
+	return d
+}
+
+// newUpstreamTransport builds the single, shared transport used for every
+// upstream call, so connections are kept alive and reused across requests.
+func newUpstreamTransport() http.RoundTripper {
+	dialer := &net.Dialer{Timeout: 30 * time.Second, KeepAlive: 30 * time.Second}
+	t1 := &http.Transport{
+		Proxy:                 http.ProxyFromEnvironment,
+		DialContext:           dialUpstream(dialer),
+		MaxIdleConns:          envInt(upstreamMaxIdleConns, 100),
+		MaxIdleConnsPerHost:   envInt(upstreamMaxIdlePerHost, 10),
+		MaxConnsPerHost:       envInt(upstreamMaxConnsPerHost, 0),
+		IdleConnTimeout:       envDuration(upstreamIdleConnTimeout, 90*time.Second),
+		TLSHandshakeTimeout:   10 * time.Second,
+		ExpectContinueTimeout: 1 * time.Second,
+	}
+	if !envBool(upstreamHTTP2) {
+		return t1
+	}
+
+	// HTTP/2 over TLS is negotiated with ALPN; plain-text upstreams stay on
+	// HTTP/1.1 unless h2c (prior knowledge) is explicitly enabled
+	t2, err := http2.ConfigureTransports(t1)
+	if err != nil {
+		e.Logger.Warnf("HTTP/2 disabled for upstreams: %v", err)
+		return t1
+	}
+	configureH2(t2)
+	if !envBool(upstreamH2C) {
+		return t1
+	}
+
+	h2c := &http2.Transport{
+		AllowHTTP: true,
+		DialTLS: func(network, addr string, _ *tls.Config) (net.Conn, error) {
+			return dialUpstream(dialer)(context.Background(), network, addr)
+		},
+	}
+	configureH2(h2c)
+
+	return &h2cTransport{h2c: h2c, tls: t1}
+}
+
+func configureH2(t *http2.Transport) {
+	t.StrictMaxConcurrentStreams = envBool(upstreamStrictMaxStreams)
+	t.ReadIdleTimeout = envDuration(upstreamH2ReadIdleTimeout, 30*time.Second)
+	t.PingTimeout = envDuration(upstreamH2PingTimeout, 15*time.Second)
+}
+
+// h2cTransport sends plain-text requests over HTTP/2 with prior knowledge and
+// everything else through the regular transport.
+type h2cTransport struct {
+	h2c *http2.Transport
+	tls *http.Transport
+}
+
+func (t *h2cTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.URL.Scheme == "http" {
+		return t.h2c.RoundTrip(req)
+	}
+
+	return t.tls.RoundTrip(req)
+}
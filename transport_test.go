@@ -0,0 +1,47 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/net/http2"
+	"golang.org/x/net/http2/h2c"
+)
+
+func TestEnvHelpers(t *testing.T) {
+	assert.True(t, envBool("true"))
+	assert.False(t, envBool("nope"))
+	assert.Equal(t, 7, envInt("7", 1))
+	assert.Equal(t, 1, envInt("seven", 1))
+	assert.Equal(t, 2*time.Second, envDuration("2s", time.Second))
+	assert.Equal(t, time.Second, envDuration("", time.Second))
+}
+
+func TestNewUpstreamTransportHTTP1(t *testing.T) {
+	defer func(value string) { upstreamHTTP2 = value }(upstreamHTTP2)
+	upstreamHTTP2 = "false"
+
+	_, ok := newUpstreamTransport().(*http.Transport)
+	assert.True(t, ok)
+}
+
+func TestNewUpstreamTransportH2C(t *testing.T) {
+	defer func(value string) { upstreamH2C = value }(upstreamH2C)
+	upstreamH2C = "true"
+
+	upstream := httptest.NewServer(h2c.NewHandler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, 2, r.ProtoMajor)
+		w.WriteHeader(http.StatusNoContent)
+	}), &http2.Server{}))
+	defer upstream.Close()
+
+	client := &http.Client{Transport: newUpstreamTransport()}
+	resp, err := client.Get(upstream.URL)
+	if assert.NoError(t, err) {
+		assert.Equal(t, http.StatusNoContent, resp.StatusCode)
+		assert.NoError(t, resp.Body.Close())
+	}
+}
@@ -0,0 +1,183 @@
+// author: Gary A. Stafford
+// site: https://programmaticponderings.com
+// license: MIT License
+// purpose: NLP microservices: nlp-client response schema versions
+// modified: 2026-10-14
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"unicode"
+
+	"github.com/labstack/echo/v4"
+)
+
+const (
+	headerAcceptVersion  = "Accept-Version"
+	headerContentVersion = "Content-Version"
+
+	schemaV1 = "v1"
+	schemaV2 = "v2"
+)
+
+// v2Routes are also served under /v2, and answer in the v2 schema there or
+// when asked for with Accept-Version: v2.
+var v2Routes = []string{"/keywords", "/tokens", "/entities", "/sentences", "/language", "/analyze"}
+
+// routePath returns the route of a request without its version prefix, so
+// each version and alias of a route shares its configuration.
+func routePath(c echo.Context) string {
+	return unversioned(c.Path())
+}
+
+// schemaVersion returns the response schema a request asks for, by path
+// prefix or, on the unprefixed aliases, Accept-Version header, and sets it as
+// the Content-Version.
+func schemaVersion(c echo.Context) (string, error) {
+	version := schemaV1
+	switch path := c.Path(); {
+	case strings.HasPrefix(path, "/"+schemaV1+"/"):
+	case strings.HasPrefix(path, "/"+schemaV2+"/"):
+		version = schemaV2
+	default:
+		switch strings.TrimPrefix(strings.ToLower(strings.TrimSpace(c.Request().Header.Get(headerAcceptVersion))), "v") {
+		case "", "1":
+		case "2":
+			version = schemaV2
+		default:
+			return "", fmt.Errorf("unsupported %s: %s", headerAcceptVersion, c.Request().Header.Get(headerAcceptVersion))
+		}
+	}
+	c.Response().Header().Set(headerContentVersion, version)
+
+	return version, nil
+}
+
+// span is where a token, sentence, or entity was found in the request text,
+// in characters. Offset is null when the upstream's text cannot be found.
+type span struct {
+	Offset *int `json:"offset"`
+	Length int  `json:"length"`
+}
+
+type v2Item struct {
+	Text  string `json:"text"`
+	Label string `json:"label,omitempty"`
+	span
+	Confidence *float64 `json:"confidence,omitempty"`
+}
+
+type v2Keyword struct {
+	Text       string   `json:"text"`
+	Score      float64  `json:"score"`
+	Confidence *float64 `json:"confidence,omitempty"`
+	Offsets    []int    `json:"offsets"`
+}
+
+// foldRunes lowercases text rune by rune, so offsets into the result are offsets into text.
+func foldRunes(text string) []rune {
+	runes := []rune(text)
+	for i, r := range runes {
+		runes[i] = unicode.ToLower(r)
+	}
+
+	return runes
+}
+
+func indexRunes(haystack, needle []rune, from int) int {
+	if len(needle) == 0 {
+		return -1
+	}
+	for i := from; i+len(needle) <= len(haystack); i++ {
+		match := true
+		for j, r := range needle {
+			if haystack[i+j] != r {
+				match = false
+				break
+			}
+		}
+		if match {
+			return i
+		}
+	}
+
+	return -1
+}
+
+// locator finds successive items in a text, each after the one before, so
+// repeated tokens resolve to their own occurrences.
+type locator struct {
+	text   []rune
+	cursor int
+}
+
+func (l *locator) locate(item string) span {
+	needle := foldRunes(item)
+	s := span{Length: len(needle)}
+	i := indexRunes(l.text, needle, l.cursor)
+	if i < 0 {
+		i = indexRunes(l.text, needle, 0)
+	}
+	if i >= 0 {
+		s.Offset = &i
+		l.cursor = i + len(needle)
+	}
+
+	return s
+}
+
+// normalizeResult rewrites an upstream result in the v2 schema: tokens,
+// sentences, and entities become objects with their character offsets in
+// text, and keywords list every offset they occur at.
+func normalizeResult(route, text string, body json.RawMessage) (json.RawMessage, error) {
+	l := &locator{text: foldRunes(text)}
+	switch route {
+	case "/tokens", "/sentences":
+		var items []string
+		if err := json.Unmarshal(body, &items); err != nil {
+			return nil, err
+		}
+		normalized := make([]v2Item, len(items))
+		for i, item := range items {
+			normalized[i] = v2Item{Text: item, span: l.locate(item)}
+		}
+		return json.Marshal(normalized)
+	case "/entities":
+		var items []struct {
+			Text       string   `json:"text"`
+			Label      string   `json:"label"`
+			Confidence *float64 `json:"confidence"`
+		}
+		if err := json.Unmarshal(body, &items); err != nil {
+			return nil, err
+		}
+		normalized := make([]v2Item, len(items))
+		for i, item := range items {
+			normalized[i] = v2Item{Text: item.Text, Label: item.Label, span: l.locate(item.Text), Confidence: item.Confidence}
+		}
+		return json.Marshal(normalized)
+	case "/keywords":
+		var items []struct {
+			Candidate  string   `json:"candidate"`
+			Score      float64  `json:"score"`
+			Confidence *float64 `json:"confidence"`
+		}
+		if err := json.Unmarshal(body, &items); err != nil {
+			return nil, err
+		}
+		normalized := make([]v2Keyword, len(items))
+		for i, item := range items {
+			normalized[i] = v2Keyword{Text: item.Candidate, Score: item.Score, Confidence: item.Confidence, Offsets: []int{}}
+			needle := foldRunes(item.Candidate)
+			for at := indexRunes(l.text, needle, 0); at >= 0; at = indexRunes(l.text, needle, at+len(needle)) {
+				normalized[i].Offsets = append(normalized[i].Offsets, at)
+			}
+		}
+		return json.Marshal(normalized)
+	}
+
+	return body, nil
+}
@@ -0,0 +1,95 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/garystafford/nlp-client/mockupstream"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNormalizeResult(t *testing.T) {
+	text := "The cat saw the Cat"
+	tests := []struct {
+		route    string
+		body     string
+		expected string
+	}{
+		{"/tokens", `["The","cat","saw","the","dog"]`,
+			`[{"text":"The","offset":0,"length":3},{"text":"cat","offset":4,"length":3},{"text":"saw","offset":8,"length":3},` +
+				`{"text":"the","offset":12,"length":3},{"text":"dog","offset":null,"length":3}]`},
+		{"/entities", `[{"text":"Cat","label":"ANIMAL"}]`, `[{"text":"Cat","label":"ANIMAL","offset":4,"length":3}]`},
+		{"/keywords", `[{"candidate":"cat","score":2.5}]`, `[{"text":"cat","score":2.5,"offsets":[4,16]}]`},
+		{"/language", `{"language":"en","probability":0.99}`, `{"language":"en","probability":0.99}`},
+	}
+	for _, tt := range tests {
+		normalized, err := normalizeResult(tt.route, text, json.RawMessage(tt.body))
+		if assert.NoError(t, err, tt.route) {
+			assert.JSONEq(t, tt.expected, string(normalized), tt.route)
+		}
+	}
+}
+
+func TestSchemaVersion(t *testing.T) {
+	tests := []struct {
+		path     string
+		header   string
+		expected string
+		invalid  bool
+	}{
+		{"/tokens", "", schemaV1, false},
+		{"/tokens", "2", schemaV2, false},
+		{"/tokens", "V2", schemaV2, false},
+		{"/tokens", "v1", schemaV1, false},
+		{"/v2/tokens", "", schemaV2, false},
+		{"/v1/tokens", "v2", schemaV1, false},
+		{"/tokens", "v3", "", true},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest(http.MethodPost, tt.path, nil)
+		if tt.header != "" {
+			req.Header.Set(headerAcceptVersion, tt.header)
+		}
+		w := httptest.NewRecorder()
+		c := e.NewContext(req, w)
+		c.SetPath(tt.path)
+
+		version, err := schemaVersion(c)
+		if tt.invalid {
+			assert.Error(t, err, tt.header)
+			continue
+		}
+		if assert.NoError(t, err, tt.header) {
+			assert.Equal(t, tt.expected, version, tt.path+" "+tt.header)
+			assert.Equal(t, tt.expected, w.Header().Get(headerContentVersion))
+		}
+	}
+}
+
+func TestGetTokensV2(t *testing.T) {
+	prose := mockupstream.NewProse()
+	defer prose.Close()
+	defer func(url string) { urlProse = url }(urlProse)
+	urlProse = prose.URL
+
+	req := httptest.NewRequest(http.MethodPost, "/v2/tokens", strings.NewReader(`{"text": "The Nobel Prize is regarded"}`))
+	w := httptest.NewRecorder()
+	c := e.NewContext(req, w)
+	c.SetPath("/v2/tokens")
+
+	if assert.NoError(t, getTokens(c)) {
+		var body metaEnvelope
+		if assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &body)) {
+			assert.JSONEq(t, `[{"text":"The","offset":0,"length":3},{"text":"Nobel","offset":4,"length":5},`+
+				`{"text":"Prize","offset":10,"length":5},{"text":"is","offset":16,"length":2},{"text":"regarded","offset":19,"length":8}]`,
+				string(body.Result))
+			if assert.NotNil(t, body.Meta) {
+				assert.Equal(t, 27, body.Meta.TextLength)
+			}
+		}
+		assert.Equal(t, schemaV2, w.Header().Get(headerContentVersion))
+	}
+}